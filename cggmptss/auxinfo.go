@@ -0,0 +1,476 @@
+package cggmptss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/dlnproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/facproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/modproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskAuxInfo is the task name reported in errors from AuxInfo.
+const TaskAuxInfo = "cggmp-aux-info"
+
+// AuxInfo tracks the generation of the auxiliary information of a key and the refresh of its
+// shares (CGGMP21 Fig. 6). Each party publishes a new Paillier key and ring-Pedersen
+// parameters, and shares a random polynomial of constant term 0: adding these shares to the
+// current ones gives new shares of the same key, which makes the old shares useless to an
+// attacker.
+//
+// All the parties of the key must take part, with the same threshold as at keygen. As with
+// Keygen, broadcast messages are always followed by an echo round.
+type AuxInfo struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	ssid   []byte
+	key    *ecdsatss.Key // current key, in the order of the parties of params
+
+	// round 1
+	preParams ecdsatss.LocalPreParams
+	shares    []*big.Int // refresh shares of each party
+	deCommit  cmts.HashDeCommitment
+	kgcs      []cmts.HashCommitment
+
+	// round 3
+	rid    *big.Int
+	polyGs [][]*crypto.ECPoint // commitments to the coefficients of degree 1 to t of each party
+
+	data *ecdsatss.Key
+
+	Done chan *ecdsatss.Key
+	Err  chan error
+}
+
+// NewAuxInfo creates a new AuxInfo for key and executes round 1. The Paillier key and
+// ring-Pedersen parameters are generated unless given in optionalPreParams, which must then
+// include the values needed for the proofs (see LocalPreParams.ValidateWithProof).
+func NewAuxInfo(ctx context.Context, key *ecdsatss.Key, params *tss.Parameters, optionalPreParams ...ecdsatss.LocalPreParams) (*AuxInfo, error) {
+	if key.MoreKs != nil {
+		return nil, ecdsatss.ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ecdsatss.ErrHierarchicalKey
+	}
+	if len(key.Ks) != params.PartyCount() {
+		return nil, fmt.Errorf("all %d parties of the key must take part, got %d", len(key.Ks), params.PartyCount())
+	}
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	partyCount := params.PartyCount()
+	ai := &AuxInfo{
+		ctx:    ctx,
+		params: params,
+		key:    key,
+		kgcs:   make([]cmts.HashCommitment, partyCount),
+		polyGs: make([][]*crypto.ECPoint, partyCount),
+		data:   ecdsatss.NewKey(partyCount),
+		Done:   make(chan *ecdsatss.Key, 1),
+		Err:    make(chan error, 1),
+	}
+	if len(optionalPreParams) > 0 {
+		ai.preParams = optionalPreParams[0]
+	}
+	ai.broker = tss.NewSessionBroker(params.Broker())
+	ai.stop = context.AfterFunc(ctx, func() { ai.fail(ctx.Err()) })
+	if err := ai.round1(); err != nil {
+		ai.release()
+		return nil, err
+	}
+	return ai, nil
+}
+
+func (ai *AuxInfo) round1() error {
+	Pi := ai.params.PartyID()
+	i := Pi.Index
+	ec := ai.params.EC()
+	q := ec.Params().N
+
+	values, err := crypto.FlattenECPoints(ai.key.BigXj)
+	if err != nil {
+		return err
+	}
+	ai.ssid = getSSID(ai.params, TaskAuxInfo, values...)
+
+	// 1. Paillier key and ring-Pedersen parameters
+	if ai.preParams.Validate() && !ai.preParams.ValidateWithProof() {
+		return errors.New("`optionalPreParams` failed to validate; it might have been generated with an older version of tss-lib")
+	} else if !ai.preParams.ValidateWithProof() {
+		ctx, cancel := context.WithTimeout(ai.ctx, ai.params.SafePrimeGenTimeout())
+		defer cancel()
+		preParams, err := (&ecdsatss.LocalPreGenerator{Context: ctx, Rand: ai.params.Rand(), Concurrency: ai.params.Concurrency()}).Generate()
+		if err != nil {
+			return errors.New("pre-params generation failed")
+		}
+		ai.preParams = *preParams
+	}
+	pp := ai.preParams
+	dlnProof1, err := dlnproof.NewDLNProof(pp.H1i, pp.H2i, pp.Alpha, pp.P, pp.Q, pp.NTildei, ai.params.Rand()).Serialize()
+	if err != nil {
+		return err
+	}
+	dlnProof2, err := dlnproof.NewDLNProof(pp.H2i, pp.H1i, pp.Beta, pp.P, pp.Q, pp.NTildei, ai.params.Rand()).Serialize()
+	if err != nil {
+		return err
+	}
+
+	// 2. random polynomial f of degree t with f(0) = 0, and the shares f(kj)
+	threshold := ai.params.Threshold()
+	coefs := make([]*big.Int, threshold)
+	polyG := make([]*crypto.ECPoint, threshold)
+	for c := range coefs {
+		coefs[c] = common.GetRandomPositiveInt(ai.params.Rand(), q)
+		polyG[c] = baseMult(ec, coefs[c])
+		if polyG[c] == nil {
+			return errors.New("refresh polynomial has a zero coefficient")
+		}
+	}
+	modQ := common.ModInt(q)
+	ai.shares = make([]*big.Int, len(ai.key.Ks))
+	for j, kj := range ai.key.Ks {
+		share, z := big.NewInt(0), big.NewInt(1)
+		for _, a := range coefs {
+			z = modQ.Mul(z, kj)
+			share = modQ.Add(share, modQ.Mul(a, z))
+		}
+		ai.shares[j] = share
+	}
+
+	// 3. commit to the polynomial and rid_i
+	rid := common.MustGetRandomInt(ai.params.Rand(), cmts.HashLength)
+	pGFlat, err := crypto.FlattenECPoints(polyG)
+	if err != nil {
+		return err
+	}
+	cmt := cmts.NewHashCommitment(ai.params.Rand(), append(pGFlat, rid)...)
+	ai.deCommit = cmt.D
+	ai.polyGs[i] = polyG
+
+	ai.data.Ks = ai.key.Ks
+	ai.data.ShareID = ai.key.ShareID
+	ai.data.ECDSAPub = ai.key.ECDSAPub
+	ai.data.LocalPreParams = pp
+	ai.data.PaillierPKs[i] = &pp.PaillierSK.PublicKey
+	ai.data.NTildej[i] = pp.NTildei
+	ai.data.H1j[i], ai.data.H2j[i] = pp.H1i, pp.H2i
+
+	otherIds := ai.params.Parties().IDs().Exclude(Pi)
+	msg := &auxInfoRound1msg{
+		Commitment: cmt.C.Bytes(),
+		PaillierN:  pp.PaillierSK.N.Bytes(),
+		NTilde:     pp.NTildei.Bytes(),
+		H1:         pp.H1i.Bytes(),
+		H2:         pp.H2i.Bytes(),
+		DlnProof1:  dlnProof1,
+		DlnProof2:  dlnProof2,
+	}
+	for _, Pj := range otherIds {
+		ai.broker.Receive(tss.JsonWrap(ai.params.MsgType("cggmp:auxinfo:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[auxInfoRound1msg](ai.params.MsgType("cggmp:auxinfo:round1"), otherIds, ai.round2, ai.timeout(1), ai.echo(1, "cggmp:auxinfo:round1", otherIds))
+	ai.broker.Connect(ai.params.MsgType("cggmp:auxinfo:round1"), rcv)
+	return nil
+}
+
+// round2 checks the Paillier keys and ring-Pedersen parameters of the other parties, and sends
+// the de-commitment.
+func (ai *AuxInfo) round2(otherIds []*tss.PartyID, r1msgs []*auxInfoRound1msg) {
+	if ai.ctx.Err() != nil {
+		ai.fail(ai.ctx.Err())
+		return
+	}
+	Pi := ai.params.PartyID()
+
+	var errs []error
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, r1msg := range r1msgs {
+		Pj := otherIds[n]
+		paillierPK := &paillier.PublicKey{N: new(big.Int).SetBytes(r1msg.PaillierN)}
+		if paillierPK.N.BitLen() < 2048 {
+			errs = append(errs, ai.wrapError(2, fmt.Errorf("paillier modulus bit length %d < 2048", paillierPK.N.BitLen()), Pj))
+			continue
+		}
+		NTildej := new(big.Int).SetBytes(r1msg.NTilde)
+		if NTildej.BitLen() < 2048 {
+			errs = append(errs, ai.wrapError(2, fmt.Errorf("NTilde bit length %d < 2048", NTildej.BitLen()), Pj))
+			continue
+		}
+		H1j, H2j := new(big.Int).SetBytes(r1msg.H1), new(big.Int).SetBytes(r1msg.H2)
+		if H1j.Cmp(H2j) == 0 {
+			errs = append(errs, ai.wrapError(2, errors.New("H1j == H2j"), Pj))
+			continue
+		}
+
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			dlnPf1, err := dlnproof.UnmarshalDLNProof(r1msgs[n].DlnProof1)
+			if err != nil || !dlnPf1.Verify(H1j, H2j, NTildej) {
+				failed[n] = true
+				return
+			}
+			dlnPf2, err := dlnproof.UnmarshalDLNProof(r1msgs[n].DlnProof2)
+			if err != nil || !dlnPf2.Verify(H2j, H1j, NTildej) {
+				failed[n] = true
+			}
+		}(n)
+
+		ai.data.PaillierPKs[Pj.Index] = paillierPK
+		ai.data.NTildej[Pj.Index] = NTildej
+		ai.data.H1j[Pj.Index], ai.data.H2j[Pj.Index] = H1j, H2j
+		ai.kgcs[Pj.Index] = new(big.Int).SetBytes(r1msg.Commitment)
+	}
+	wg.Wait()
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		if failed[n] {
+			culprits = append(culprits, Pj)
+		}
+	}
+	if len(culprits) > 0 {
+		errs = append(errs, ai.wrapError(2, errors.New("DLN proof verification failed"), culprits...))
+	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		ai.fail(err)
+		return
+	}
+
+	r2msg := &auxInfoRound2msg{DeCommitment: common.BigIntsToBytes(ai.deCommit)}
+	for _, Pj := range otherIds {
+		ai.broker.Receive(tss.JsonWrap(ai.params.MsgType("cggmp:auxinfo:round2"), r2msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[auxInfoRound2msg](ai.params.MsgType("cggmp:auxinfo:round2"), otherIds, ai.round3, ai.timeout(2), ai.echo(2, "cggmp:auxinfo:round2", otherIds))
+	ai.broker.Connect(ai.params.MsgType("cggmp:auxinfo:round2"), rcv)
+}
+
+// round3 checks the de-commitments, and sends the encrypted refresh shares along with the
+// proofs about the new Paillier key.
+func (ai *AuxInfo) round3(otherIds []*tss.PartyID, r2msgs []*auxInfoRound2msg) {
+	if ai.ctx.Err() != nil {
+		ai.fail(ai.ctx.Err())
+		return
+	}
+	Pi := ai.params.PartyID()
+	ec := ai.params.EC()
+	threshold := ai.params.Threshold()
+
+	var culprits []*tss.PartyID
+	rid := new(big.Int).Set(ai.deCommit[len(ai.deCommit)-1])
+	for n, Pj := range otherIds {
+		cmtDeCmt := cmts.HashCommitDecommit{C: ai.kgcs[Pj.Index], D: cmts.NewHashDeCommitmentFromBytes(r2msgs[n].DeCommitment)}
+		ok, secrets := cmtDeCmt.DeCommit()
+		if !ok || len(secrets) != 2*threshold+1 {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		polyG, err := crypto.UnFlattenECPoints(ec, secrets[:len(secrets)-1])
+		if err != nil {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		ai.polyGs[Pj.Index] = polyG
+		rid.Xor(rid, secrets[len(secrets)-1])
+	}
+	if len(culprits) > 0 {
+		ai.fail(ai.wrapError(3, errors.New("decommitment verification failed"), culprits...))
+		return
+	}
+	ai.rid = rid
+
+	session := ai.session(Pi.Index)
+	sk := ai.preParams.PaillierSK
+	for _, Pj := range otherIds {
+		C, err := ai.data.PaillierPKs[Pj.Index].Encrypt(ai.params.Rand(), ai.shares[Pj.Index])
+		if err != nil {
+			ai.fail(ai.wrapError(3, err))
+			return
+		}
+		var facProofBzs [][]byte
+		if !ai.params.NoProofFac() {
+			fp, err := facproof.NewProof(session, ec, sk.N, ai.data.NTildej[Pj.Index], ai.data.H1j[Pj.Index], ai.data.H2j[Pj.Index], sk.P, sk.Q, ai.params.Rand())
+			if err != nil {
+				ai.fail(ai.wrapError(3, fmt.Errorf("failed to generate fac proof for party %s: %w", Pj, err)))
+				return
+			}
+			bzs := fp.Bytes()
+			facProofBzs = bzs[:]
+		}
+		r3msg1 := &auxInfoRound3msg1{Share: C.Bytes(), FacProof: facProofBzs}
+		ai.broker.Receive(tss.JsonWrapPrivate(ai.params.MsgType("cggmp:auxinfo:round3-1"), r3msg1, Pi, Pj))
+	}
+
+	var modProofBzs [][]byte
+	if !ai.params.NoProofMod() {
+		mp, err := modproof.NewProof(session, sk.N, sk.P, sk.Q, ai.params.Rand())
+		if err != nil {
+			ai.fail(ai.wrapError(3, fmt.Errorf("failed to generate mod proof: %w", err)))
+			return
+		}
+		bzs := mp.Bytes()
+		modProofBzs = bzs[:]
+	}
+	r3msg2 := &auxInfoRound3msg2{ModProof: modProofBzs}
+	for _, Pj := range otherIds {
+		ai.broker.Receive(tss.JsonWrap(ai.params.MsgType("cggmp:auxinfo:round3-2"), r3msg2, Pi, Pj))
+	}
+
+	connectPair(ai.broker, ai.params, "cggmp:auxinfo:round3", otherIds, ai.round4, ai.timeout(3), ai.echo(3, "cggmp:auxinfo:round3-2", otherIds))
+}
+
+// round4 verifies the proofs and refresh shares of the other parties, and completes the
+// refresh.
+func (ai *AuxInfo) round4(otherIds []*tss.PartyID, r3msgs1 []*auxInfoRound3msg1, r3msgs2 []*auxInfoRound3msg2) {
+	if ai.ctx.Err() != nil {
+		ai.fail(ai.ctx.Err())
+		return
+	}
+	Pi := ai.params.PartyID()
+	i := Pi.Index
+	ec := ai.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	shares := make([]*big.Int, len(otherIds))
+	errs := make([]error, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		wg.Add(1)
+		go func(n int, Pj *tss.PartyID) {
+			defer wg.Done()
+			session := ai.session(Pj.Index)
+			Nj := ai.data.PaillierPKs[Pj.Index].N
+			if !ai.params.NoProofMod() {
+				mp, err := modproof.NewProofFromBytes(r3msgs2[n].ModProof)
+				if err != nil || !mp.Verify(session, Nj) {
+					errs[n] = ai.wrapError(4, errors.New("mod proof verification failed"), Pj)
+					return
+				}
+			}
+			if !ai.params.NoProofFac() {
+				fp, err := facproof.NewProofFromBytes(r3msgs1[n].FacProof)
+				if err != nil || !fp.Verify(session, ec, Nj, ai.preParams.NTildei, ai.preParams.H1i, ai.preParams.H2i) {
+					errs[n] = ai.wrapError(4, errors.New("fac proof verification failed"), Pj)
+					return
+				}
+			}
+			share, err := ai.preParams.PaillierSK.Decrypt(new(big.Int).SetBytes(r3msgs1[n].Share))
+			if err != nil || share.Cmp(q) >= 0 || !ai.verifyShare(share, ai.polyGs[Pj.Index], ai.key.Ks[i]) {
+				errs[n] = ai.wrapError(4, errors.New("refresh share verification failed"), Pj)
+				return
+			}
+			shares[n] = share
+		}(n, Pj)
+	}
+	wg.Wait()
+	if err := tss.JoinErrors(errs...); err != nil {
+		ai.fail(err)
+		return
+	}
+
+	// new xi = xi + sum of the refresh shares
+	xi := modQ.Add(ai.key.Xi, ai.shares[i])
+	for _, share := range shares {
+		xi = modQ.Add(xi, share)
+	}
+	ai.data.Xi = xi
+
+	// new Xj = Xj + sum of the refresh polynomials evaluated at kj
+	for j, kj := range ai.key.Ks {
+		terms := []*crypto.ECPoint{ai.key.BigXj[j]}
+		for _, polyG := range ai.polyGs {
+			z := big.NewInt(1)
+			for _, A := range polyG {
+				z = modQ.Mul(z, kj)
+				terms = append(terms, scalarMult(A, z))
+			}
+		}
+		BigXj, err := addPoints(terms...)
+		if err != nil || BigXj == nil {
+			ai.fail(ai.wrapError(4, fmt.Errorf("failed computing BigXj for party %d", j)))
+			return
+		}
+		ai.data.BigXj[j] = BigXj
+	}
+	if !pointsEqual(baseMult(ec, xi), ai.data.BigXj[i]) {
+		ai.fail(ai.wrapError(4, errors.New("refreshed share does not match its public point")))
+		return
+	}
+
+	ai.release()
+	ai.Done <- ai.data
+}
+
+// verifyShare returns true if share·G is the evaluation at k of the polynomial committed to
+// by polyG.
+func (ai *AuxInfo) verifyShare(share *big.Int, polyG []*crypto.ECPoint, k *big.Int) bool {
+	ec := ai.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+	var terms []*crypto.ECPoint
+	z := big.NewInt(1)
+	for _, A := range polyG {
+		z = modQ.Mul(z, k)
+		terms = append(terms, scalarMult(A, z))
+	}
+	expected, err := addPoints(terms...)
+	if err != nil {
+		return false
+	}
+	return pointsEqual(baseMult(ec, share), expected)
+}
+
+// session returns the session of the proofs of the party of index j, bound to rid.
+func (ai *AuxInfo) session(j int) []byte {
+	return common.AppendBigIntToBytesSlice(common.AppendBigIntToBytesSlice(ai.ssid, ai.rid), big.NewInt(int64(j)))
+}
+
+// fail reports err on Err and releases the receivers registered by this aux info.
+func (ai *AuxInfo) fail(err error) {
+	ai.release()
+	select {
+	case ai.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this aux info from the broker.
+func (ai *AuxInfo) release() {
+	ai.stop()
+	ai.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (ai *AuxInfo) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(ai.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		ai.fail(ai.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking that the broadcast messages of the given type were the
+// same for all of peers. It is used whether echo broadcast is enabled or not.
+func (ai *AuxInfo) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	return tss.WithEcho(ai.broker, ai.params.MsgType(typ+":echo"), ai.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		ai.fail(ai.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (ai *AuxInfo) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskAuxInfo, round, ai.params.PartyID(), culprits...)
+}
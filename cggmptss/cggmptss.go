@@ -0,0 +1,147 @@
+// Package cggmptss implements the threshold ECDSA protocol of Canetti, Gennaro, Goldfeder,
+// Makriyannis and Peled (CGGMP21, "UC Non-Interactive, Proactive, Threshold ECDSA with
+// Identifiable Aborts").
+//
+// The protocol works on the same ecdsatss.Key as the GG18 based ecdsatss package:
+//
+//   - NewKeygen generates the shares of a new key, without any Paillier key;
+//   - NewAuxInfo gives every party a fresh Paillier key and ring-Pedersen parameters, and
+//     refreshes the shares. It runs once after keygen, and can be run again at any time to
+//     proactively refresh a key, including one made by ecdsatss;
+//   - NewPresigning runs the rounds of signing that do not depend on the message;
+//   - SignWithPresignature signs a message in a single round with a presignature.
+//
+// Every message carries zero-knowledge proofs, so that a party deviating from the protocol
+// is reported as a culprit of the tss.Error ending it.
+package cggmptss
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var zero = big.NewInt(0)
+
+// getSSID returns the session identifier of the proofs of task, binding the curve, the
+// parties, the given public values and the session id of params.
+func getSSID(params *tss.Parameters, task string, values ...*big.Int) []byte {
+	ec := params.EC()
+	ssidList := []*big.Int{ec.Params().P, ec.Params().N, ec.Params().B, ec.Params().Gx, ec.Params().Gy}
+	ssidList = append(ssidList, params.Parties().IDs().Keys()...)
+	ssidList = append(ssidList, values...)
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(task)))
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(params.SessionID())))
+	return common.SHA512_256i(ssidList...).Bytes()
+}
+
+// keyValues returns the public values of key hashed in the ssid of presigning and signing.
+func keyValues(key *ecdsatss.Key) ([]*big.Int, error) {
+	values, err := crypto.FlattenECPoints(key.BigXj)
+	if err != nil {
+		return nil, err
+	}
+	for _, pk := range key.PaillierPKs {
+		values = append(values, pk.N)
+	}
+	values = append(values, key.NTildej...)
+	values = append(values, key.H1j...)
+	values = append(values, key.H2j...)
+	return values, nil
+}
+
+// hasAuxInfo returns true if key holds the Paillier keys and ring-Pedersen parameters of all
+// its parties, as set by NewAuxInfo.
+func hasAuxInfo(key *ecdsatss.Key) bool {
+	if !key.LocalPreParams.Validate() {
+		return false
+	}
+	for j := range key.Ks {
+		if key.PaillierPKs[j] == nil || key.NTildej[j] == nil || key.H1j[j] == nil || key.H2j[j] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// scalarMult returns k·p, or nil (the point at infinity) when k is 0 modulo the curve order.
+func scalarMult(p *crypto.ECPoint, k *big.Int) *crypto.ECPoint {
+	k = new(big.Int).Mod(k, p.Curve().Params().N)
+	if k.Sign() == 0 {
+		return nil
+	}
+	return p.ScalarMult(k)
+}
+
+// baseMult returns k·G, or nil (the point at infinity) when k is 0 modulo the curve order.
+func baseMult(ec elliptic.Curve, k *big.Int) *crypto.ECPoint {
+	k = new(big.Int).Mod(k, ec.Params().N)
+	if k.Sign() == 0 {
+		return nil
+	}
+	return crypto.ScalarBaseMult(ec, k)
+}
+
+// addPoints returns the sum of points, nil standing for the point at infinity.
+func addPoints(points ...*crypto.ECPoint) (*crypto.ECPoint, error) {
+	var sum *crypto.ECPoint
+	for _, p := range points {
+		switch {
+		case p == nil:
+		case sum == nil:
+			sum = p
+		case sum.X().Cmp(p.X()) == 0 && sum.Y().Cmp(p.Y()) != 0:
+			// p = -sum
+			sum = nil
+		default:
+			var err error
+			if sum, err = sum.Add(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sum, nil
+}
+
+// pointsEqual compares two points, nil standing for the point at infinity.
+func pointsEqual(p1, p2 *crypto.ECPoint) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == nil && p2 == nil
+	}
+	return p1.Equals(p2)
+}
+
+// pointFromBytes returns the point of coordinates x and y.
+func pointFromBytes(params *tss.Parameters, x, y []byte) (*crypto.ECPoint, error) {
+	return crypto.NewECPoint(params.EC(), new(big.Int).SetBytes(x), new(big.Int).SetBytes(y))
+}
+
+// connectPair connects the receivers of a round made of a private message sent to each party,
+// of type typ-1, and of a broadcast message, of type typ-2. next is called once both messages were
+// received from all the parties of from.
+func connectPair[P, B any](broker *tss.SessionBroker, params *tss.Parameters, typ string, from []*tss.PartyID, next func(from []*tss.PartyID, p2p []*P, bcast []*B), timeout, echo tss.ExpectOption) {
+	var (
+		pending = int32(2)
+		p2p     []*P
+		bcast   []*B
+	)
+	onP2P := func(_ []*tss.PartyID, msgs []*P) {
+		p2p = msgs
+		if atomic.AddInt32(&pending, -1) == 0 {
+			next(from, p2p, bcast)
+		}
+	}
+	onBcast := func(_ []*tss.PartyID, msgs []*B) {
+		bcast = msgs
+		if atomic.AddInt32(&pending, -1) == 0 {
+			next(from, p2p, bcast)
+		}
+	}
+	broker.Connect(params.MsgType(typ+"-1"), tss.NewJsonExpect[P](params.MsgType(typ+"-1"), from, onP2P, timeout, tss.WithPrivate()))
+	broker.Connect(params.MsgType(typ+"-2"), tss.NewJsonExpect[B](params.MsgType(typ+"-2"), from, onBcast, timeout, echo))
+}
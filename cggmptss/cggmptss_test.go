@@ -0,0 +1,641 @@
+package cggmptss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	mrand "math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// hubBroker routes messages between parties in a test network.
+// When the protocol calls broker.Receive(msg), it routes the message:
+//   - If msg.From matches this party (outbound): route to destination party's broker
+//   - If msg.From does NOT match this party (inbound): dispatch to local handler
+//
+// If no handler is registered yet for an inbound message, it is queued and
+// delivered as soon as Connect() registers a handler for that type.
+type hubBroker struct {
+	partyIdx int
+	hub      *testHub
+	handlers map[string]tss.MessageReceiver
+	pending  map[string][]*tss.JsonMessage // messages buffered before handler registered
+	mu       sync.Mutex
+}
+
+type testHub struct {
+	brokers []*hubBroker
+}
+
+func newTestHub(n int) *testHub {
+	h := &testHub{
+		brokers: make([]*hubBroker, n),
+	}
+	for i := 0; i < n; i++ {
+		h.brokers[i] = &hubBroker{
+			partyIdx: i,
+			hub:      h,
+			handlers: make(map[string]tss.MessageReceiver),
+			pending:  make(map[string][]*tss.JsonMessage),
+		}
+	}
+	return h
+}
+
+func (b *hubBroker) Connect(typ string, dest tss.MessageReceiver) {
+	b.mu.Lock()
+	b.handlers[typ] = dest
+	// drain any pending messages for this type
+	queued := b.pending[typ]
+	delete(b.pending, typ)
+	b.mu.Unlock()
+
+	for _, msg := range queued {
+		if err := dest.Receive(msg); err != nil {
+			fmt.Printf("hubBroker: error delivering queued message type %s to party %d: %v\n", typ, b.partyIdx, err)
+		}
+	}
+}
+
+func (b *hubBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
+func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx {
+		// outbound from this party: route to destination
+		if msg.To != nil {
+			// P2P: route to specific party
+			return b.hub.brokers[msg.To.Index].Receive(msg)
+		}
+		// broadcast to all other parties
+		for j, broker := range b.hub.brokers {
+			if j == b.partyIdx {
+				continue
+			}
+			if err := broker.Receive(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// inbound to this party: dispatch to handler or queue
+	b.mu.Lock()
+	handler, ok := b.handlers[msg.Type]
+	if !ok {
+		// no handler yet; buffer the message
+		b.pending[msg.Type] = append(b.pending[msg.Type], msg)
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+	return handler.Receive(msg)
+}
+
+// tamperBroker lets a test corrupt the messages of the given type sent by a party. The echoes
+// of these messages are left untouched.
+type tamperBroker struct {
+	*hubBroker
+	typ    string
+	tamper func(msg *tss.JsonMessage) *tss.JsonMessage
+}
+
+func (b *tamperBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx && strings.HasPrefix(msg.Type, b.typ) && !strings.HasPrefix(msg.Type, b.typ+":echo") {
+		msg = b.tamper(msg)
+	}
+	return b.hubBroker.Receive(msg)
+}
+
+// loadTestKeys loads the ecdsatss keys of the fixtures, which hold aux info.
+func loadTestKeys(t *testing.T, qty int) ([]*ecdsatss.Key, tss.SortedPartyIDs) {
+	keys := make([]*ecdsatss.Key, qty)
+	ids := make(tss.UnSortedPartyIDs, qty)
+	for i := range qty {
+		buf, err := os.ReadFile(fmt.Sprintf("../test/_ecdsa_fixtures/keygen_data_%d.json", i))
+		require.NoError(t, err)
+		var key *ecdsatss.Key
+		require.NoError(t, json.Unmarshal(buf, &key))
+		for _, bigXj := range key.BigXj {
+			bigXj.SetCurve(tss.S256())
+		}
+		key.ECDSAPub.SetCurve(tss.S256())
+		keys[i] = key
+		ids[i] = tss.NewPartyID(fmt.Sprintf("%d", i+1), fmt.Sprintf("P[%d]", i+1), key.ShareID)
+	}
+	return keys, tss.SortPartyIDs(ids)
+}
+
+// keyFor returns the key of keys held by the party p.
+func keyFor(t *testing.T, keys []*ecdsatss.Key, p *tss.PartyID) *ecdsatss.Key {
+	for _, key := range keys {
+		if key.ShareID.Cmp(p.KeyInt()) == 0 {
+			return key
+		}
+	}
+	t.Fatalf("no key for party %s", p)
+	return nil
+}
+
+func runKeygen(t *testing.T, pIDs tss.SortedPartyIDs, threshold int) []*ecdsatss.Key {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	keygens := make([]*Keygen, partyCount)
+	for i := range partyCount {
+		params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	keys := make([]*ecdsatss.Key, partyCount)
+	for i, kg := range keygens {
+		select {
+		case keys[i] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("party %d keygen error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d keygen timed out", i)
+		}
+	}
+	return keys
+}
+
+func runPresigning(t *testing.T, keys []*ecdsatss.Key, pIDs tss.SortedPartyIDs, threshold int) []*PreSignature {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	presignings := make([]*Presigning, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		ps, err := NewPresigning(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		presignings[i] = ps
+	}
+	presigs := make([]*PreSignature, partyCount)
+	for i, ps := range presignings {
+		select {
+		case presigs[i] = <-ps.Done:
+		case err := <-ps.Err:
+			t.Fatalf("party %d presigning error: %v", i, err)
+		case <-time.After(120 * time.Second):
+			t.Fatalf("party %d presigning timed out", i)
+		}
+	}
+	return presigs
+}
+
+func runSigning(t *testing.T, keys []*ecdsatss.Key, presigs []*PreSignature, msg *big.Int, pIDs tss.SortedPartyIDs, threshold int) []*ecdsatss.SignatureData {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	signings := make([]*Signing, partyCount)
+	used := make([][]byte, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		s, err := SignWithPresignature(context.Background(), keyFor(t, keys, p), presigs[i], msg, params, func(id []byte) error {
+			used[i] = id
+			return nil
+		})
+		require.NoError(t, err)
+		signings[i] = s
+	}
+	for i := range used {
+		assert.Equal(t, presigs[i].ID, used[i], "party %d should mark its presignature used", i)
+	}
+	sigs := make([]*ecdsatss.SignatureData, partyCount)
+	for i, s := range signings {
+		select {
+		case sigs[i] = <-s.Done:
+		case err := <-s.Err:
+			t.Fatalf("party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+	return sigs
+}
+
+func TestKeygen(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+
+	q := tss.S256().Params().N
+	x := big.NewInt(0)
+	for i, key := range keys {
+		assert.True(t, key.ECDSAPub.Equals(keys[0].ECDSAPub))
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[i]))
+		assert.False(t, hasAuxInfo(key))
+		lambda, err := vss.LagrangeCoefficient(q, key.Ks, i, big.NewInt(0))
+		require.NoError(t, err)
+		x.Add(x, new(big.Int).Mul(lambda, key.Xi))
+	}
+	assert.True(t, crypto.ScalarBaseMult(tss.S256(), x.Mod(x, q)).Equals(keys[0].ECDSAPub))
+}
+
+func TestKeygenAuxInfoAndSign(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+
+	_, err := NewPresigning(context.Background(), keys[0], tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], partyCount, threshold))
+	require.Error(t, err, "presigning needs aux info")
+
+	// aux info, with the pre-params of the fixtures to save the safe primes generation
+	fixtures, _ := loadTestKeys(t, partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	auxInfos := make([]*AuxInfo, partyCount)
+	for i := range partyCount {
+		params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		ai, err := NewAuxInfo(context.Background(), keys[i], params, fixtures[i].LocalPreParams)
+		require.NoError(t, err)
+		auxInfos[i] = ai
+	}
+	refreshed := make([]*ecdsatss.Key, partyCount)
+	for i, ai := range auxInfos {
+		select {
+		case refreshed[i] = <-ai.Done:
+		case err := <-ai.Err:
+			t.Fatalf("party %d aux info error: %v", i, err)
+		case <-time.After(120 * time.Second):
+			t.Fatalf("party %d aux info timed out", i)
+		}
+	}
+	for i, key := range refreshed {
+		assert.True(t, hasAuxInfo(key))
+		assert.True(t, key.ECDSAPub.Equals(keys[i].ECDSAPub))
+		assert.NotEqual(t, keys[i].Xi, key.Xi, "share should be refreshed")
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[i]))
+		for j := range key.BigXj {
+			assert.True(t, key.BigXj[j].Equals(refreshed[0].BigXj[j]))
+		}
+	}
+
+	// presign and sign with 2 of the 3 parties
+	signers := pIDs[1:]
+	signerIDs := tss.SortPartyIDs(tss.UnSortedPartyIDs{
+		tss.NewPartyID(signers[0].Id, signers[0].Moniker, signers[0].KeyInt()),
+		tss.NewPartyID(signers[1].Id, signers[1].Moniker, signers[1].KeyInt()),
+	})
+	presigs := runPresigning(t, refreshed, signerIDs, threshold)
+	msg := big.NewInt(42)
+	sigs := runSigning(t, refreshed, presigs, msg, signerIDs, threshold)
+
+	pk := ecdsa.PublicKey{Curve: tss.S256(), X: refreshed[0].ECDSAPub.X(), Y: refreshed[0].ECDSAPub.Y()}
+	for _, sig := range sigs {
+		assert.Equal(t, sigs[0].Signature, sig.Signature)
+		assert.True(t, ecdsa.Verify(&pk, msg.Bytes(), new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+	}
+}
+
+func TestPresignAndSignEcdsatssKeys(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	presigs := runPresigning(t, keys, pIDs, threshold)
+
+	// presignatures can be persisted
+	for i, presig := range presigs {
+		assert.Equal(t, presigs[0].ID, presig.ID)
+		assert.True(t, presigs[0].R.Equals(presig.R))
+		buf, err := json.Marshal(presig)
+		require.NoError(t, err)
+		var restored *PreSignature
+		require.NoError(t, json.Unmarshal(buf, &restored))
+		restored.R.SetCurve(tss.S256())
+		for j := range restored.BigR {
+			restored.BigR[j].SetCurve(tss.S256())
+			restored.BigS[j].SetCurve(tss.S256())
+		}
+		presigs[i] = restored
+	}
+
+	msg := new(big.Int).SetBytes([]byte("cggmp presignature"))
+	sigs := runSigning(t, keys, presigs, msg, pIDs, threshold)
+	pk := ecdsa.PublicKey{Curve: tss.S256(), X: keys[0].ECDSAPub.X(), Y: keys[0].ECDSAPub.Y()}
+	for _, sig := range sigs {
+		assert.Equal(t, sigs[0].Signature, sig.Signature)
+		assert.True(t, ecdsa.Verify(&pk, msg.Bytes(), new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+	}
+
+	// a presignature signs only once
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], signerCount, threshold)
+	params.SetBroker(newTestHub(signerCount).brokers[0])
+	_, err := SignWithPresignature(context.Background(), keyFor(t, keys, pIDs[0]), presigs[0], msg, params, markNothing)
+	assert.ErrorIs(t, err, ErrPresignatureUsed)
+}
+
+// markNothing is a markUsed callback for presignatures that are never stored.
+func markNothing([]byte) error { return nil }
+
+func TestSignWithPresignatureMarkUsedFails(t *testing.T) {
+	const signerCount = 3
+	keys, pIDs := loadTestKeys(t, signerCount)
+
+	R := crypto.ScalarBaseMult(tss.S256(), big.NewInt(1))
+	presig := &PreSignature{
+		ID:   []byte("presig"),
+		Ks:   pIDs.Keys(),
+		K:    big.NewInt(1),
+		Chi:  big.NewInt(1),
+		R:    R,
+		BigR: []*crypto.ECPoint{R, R, R},
+		BigS: []*crypto.ECPoint{R, R, R},
+	}
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], signerCount, 2)
+	hub := newTestHub(signerCount)
+	params.SetBroker(hub.brokers[0])
+
+	errStorage := errors.New("storage unavailable")
+	_, err := SignWithPresignature(context.Background(), keyFor(t, keys, pIDs[0]), presig, big.NewInt(1), params, func([]byte) error {
+		return errStorage
+	})
+	assert.ErrorIs(t, err, errStorage)
+	// nothing was sent, and the presignature can still be used
+	hub.brokers[1].mu.Lock()
+	assert.Empty(t, hub.brokers[1].pending)
+	hub.brokers[1].mu.Unlock()
+	assert.NotNil(t, presig.K)
+
+	_, err = SignWithPresignature(context.Background(), keyFor(t, keys, pIDs[0]), presig, big.NewInt(1), params, nil)
+	assert.Error(t, err)
+	assert.NotNil(t, presig.K)
+}
+
+// runBadPresigning runs presigning with party 2 corrupting its round 3 broadcast with tamper,
+// and checks that every party reports it as the culprit of round 5. tamper also gets the
+// presigning of party 2, so that it can keep the corrupted values: party 2 then enters the
+// identification round as well, as a malicious party would to avoid being reported as missing.
+func runBadPresigning(t *testing.T, tamper func(p *Presigning, msg *presignRound3msg2)) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(signerCount)
+
+	// party 2 starts first, so that its presigning is set when it reaches round 3
+	presignings := make([]*Presigning, signerCount)
+	for i := signerCount - 1; i >= 0; i-- {
+		p := pIDs[i]
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		if i == 2 {
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "cggmp:presign:round3-2", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				bad := *msg.Data.(*presignRound3msg2)
+				tamper(presignings[2], &bad)
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		ps, err := NewPresigning(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		presignings[i] = ps
+	}
+
+	// party 2 finds the proofs of the others valid, and reports no culprit
+	for i, ps := range presignings {
+		select {
+		case <-ps.Done:
+			t.Fatalf("party %d should reject the presignature", i)
+		case err := <-ps.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskPresigning, tssErr.Task())
+			assert.Equal(t, 5, tssErr.Round())
+			if i == 2 {
+				assert.Empty(t, tssErr.Culprits())
+				continue
+			}
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(120 * time.Second):
+			t.Fatalf("party %d presigning timed out", i)
+		}
+	}
+}
+
+func TestPresigningIdentifiesBadDelta(t *testing.T) {
+	runBadPresigning(t, func(p *Presigning, msg *presignRound3msg2) {
+		p.deltas[2] = new(big.Int).Add(new(big.Int).SetBytes(msg.Delta), big.NewInt(1))
+		msg.Delta = p.deltas[2].Bytes()
+	})
+}
+
+func TestPresigningIdentifiesBadChi(t *testing.T) {
+	runBadPresigning(t, func(p *Presigning, msg *presignRound3msg2) {
+		// S_2 + G instead of S_2
+		ec := tss.S256()
+		bigS, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(msg.SX), new(big.Int).SetBytes(msg.SY))
+		require.NoError(t, err)
+		bigS, err = bigS.Add(crypto.ScalarBaseMult(ec, big.NewInt(1)))
+		require.NoError(t, err)
+		p.bigSs[2] = bigS
+		msg.SX, msg.SY = bigS.X().Bytes(), bigS.Y().Bytes()
+	})
+}
+
+func TestPresigningAlwaysEchoes(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(signerCount)
+
+	presignings := make([]*Presigning, signerCount)
+	for i, p := range pIDs {
+		// echo broadcast is not enabled in params
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		if i == 0 {
+			// party 0 sends a wrong delta_0 to party 2 only
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "cggmp:presign:round3-2", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				if msg.To.Index != 2 {
+					return msg
+				}
+				bad := *msg.Data.(*presignRound3msg2)
+				bad.Delta = new(big.Int).Add(new(big.Int).SetBytes(bad.Delta), big.NewInt(1)).Bytes()
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		ps, err := NewPresigning(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		presignings[i] = ps
+	}
+
+	for i, ps := range presignings[1:] {
+		select {
+		case <-ps.Done:
+			t.Fatalf("party %d should detect the equivocation", i+1)
+		case err := <-ps.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.ErrorIs(t, err, tss.ErrEquivocation)
+			assert.Equal(t, 3, tssErr.Round())
+		case <-time.After(120 * time.Second):
+			t.Fatalf("party %d presigning timed out", i+1)
+		}
+	}
+}
+
+func TestPresigningSeededRandIsRepeatable(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	// a seeded source gives the same presignature whatever order the conversions run in
+	run := func() []*PreSignature {
+		hub := newTestHub(signerCount)
+		presignings := make([]*Presigning, signerCount)
+		for i, p := range pIDs {
+			params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+			params.SetRand(mrand.NewChaCha8([32]byte{byte(i + 1)}))
+			params.SetBroker(hub.brokers[i])
+			ps, err := NewPresigning(context.Background(), keyFor(t, keys, p), params)
+			require.NoError(t, err)
+			presignings[i] = ps
+		}
+		presigs := make([]*PreSignature, signerCount)
+		for i, ps := range presignings {
+			select {
+			case presigs[i] = <-ps.Done:
+			case err := <-ps.Err:
+				t.Fatalf("party %d presigning error: %v", i, err)
+			case <-time.After(120 * time.Second):
+				t.Fatalf("party %d presigning timed out", i)
+			}
+		}
+		return presigs
+	}
+
+	first, second := run(), run()
+	for i := range first {
+		assert.Equal(t, first[i].ID, second[i].ID, "party %d", i)
+		assert.Equal(t, first[i].K, second[i].K, "party %d", i)
+		assert.Equal(t, first[i].Chi, second[i].Chi, "party %d", i)
+	}
+}
+
+func TestSigningIdentifiesBadSigma(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	presigs := runPresigning(t, keys, pIDs, threshold)
+
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(signerCount)
+	signings := make([]*Signing, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		if i == 2 {
+			// party 2 broadcasts a wrong sigma_2
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "cggmp:sign:round1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				bad := *msg.Data.(*signMsg)
+				bad.Sigma = new(big.Int).Add(new(big.Int).SetBytes(bad.Sigma), big.NewInt(1)).Bytes()
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		s, err := SignWithPresignature(context.Background(), keyFor(t, keys, p), presigs[i], big.NewInt(42), params, markNothing)
+		require.NoError(t, err)
+		signings[i] = s
+	}
+
+	for i, s := range signings[:2] {
+		select {
+		case <-s.Done:
+			t.Fatalf("party %d should reject the signature", i)
+		case err := <-s.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskSigning, tssErr.Task())
+			assert.Equal(t, 2, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+}
+
+func TestSigningWithAnotherPresignatureHasNoCulprit(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+	keys, pIDs := loadTestKeys(t, signerCount)
+	presigs := runPresigning(t, keys, pIDs, threshold)
+	// party 2 may have been given another presignature: its sender cannot be told apart
+	other := runPresigning(t, keys, pIDs, threshold)
+
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(signerCount)
+	signings := make([]*Signing, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		presig := presigs[i]
+		if i == 2 {
+			presig = other[i]
+		}
+		s, err := SignWithPresignature(context.Background(), keyFor(t, keys, p), presig, big.NewInt(42), params, markNothing)
+		require.NoError(t, err)
+		signings[i] = s
+	}
+
+	for i, s := range signings[:2] {
+		select {
+		case <-s.Done:
+			t.Fatalf("party %d should reject the signature", i)
+		case err := <-s.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, 2, tssErr.Round())
+			assert.Empty(t, tssErr.Culprits())
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+}
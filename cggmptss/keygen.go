@@ -0,0 +1,309 @@
+package cggmptss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/schnorr"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskKeygen is the task name reported in errors from Keygen.
+const TaskKeygen = "cggmp-keygen"
+
+// Keygen tracks the generation of a threshold ECDSA key (CGGMP21 Fig. 5, with Feldman VSS
+// shares). Each party commits to its VSS polynomial and to a random rid_i, then reveals them
+// along with the shares of the others, and proves knowledge of its secret with a Schnorr proof
+// bound to rid, the XOR of all rid_j.
+//
+// The resulting Key has no Paillier key: NewAuxInfo must be run before presigning.
+//
+// Broadcast messages are always followed by an echo round, whatever the setting of
+// params.EchoBroadcast, as the protocol assumes that all the parties receive the same
+// commitments.
+type Keygen struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	ssid   []byte
+
+	// round 1
+	ui       *big.Int
+	vs       vss.Vs
+	shares   vss.Shares
+	deCommit cmts.HashDeCommitment
+	kgcs     []cmts.HashCommitment
+
+	// round 3
+	rid    *big.Int
+	polyGs []vss.Vs // VSS commitments of each party
+
+	data *ecdsatss.Key
+
+	Done chan *ecdsatss.Key
+	Err  chan error
+}
+
+// NewKeygen creates a new Keygen and executes round 1 of the key generation protocol.
+func NewKeygen(ctx context.Context, params *tss.Parameters) (*Keygen, error) {
+	if params.Weights() != nil {
+		return nil, ecdsatss.ErrWeightedKey
+	}
+	if params.Ranks() != nil {
+		return nil, ecdsatss.ErrHierarchicalKey
+	}
+	partyCount := params.PartyCount()
+	kg := &Keygen{
+		ctx:    ctx,
+		params: params,
+		kgcs:   make([]cmts.HashCommitment, partyCount),
+		polyGs: make([]vss.Vs, partyCount),
+		data:   ecdsatss.NewKey(partyCount),
+		Done:   make(chan *ecdsatss.Key, 1),
+		Err:    make(chan error, 1),
+	}
+	kg.broker = tss.NewSessionBroker(params.Broker())
+	kg.stop = context.AfterFunc(ctx, func() { kg.fail(ctx.Err()) })
+	if err := kg.round1(); err != nil {
+		kg.release()
+		return nil, err
+	}
+	return kg, nil
+}
+
+func (kg *Keygen) round1() error {
+	Pi := kg.params.PartyID()
+	ec := kg.params.EC()
+	ids := kg.params.Parties().IDs().Keys()
+	kg.ssid = getSSID(kg.params, TaskKeygen)
+
+	// 1. sample the secret ui and its VSS shares
+	ui := common.GetRandomPositiveInt(kg.params.PartialKeyRand(), ec.Params().N)
+	vs, shares, err := vss.Create(ec, kg.params.Threshold(), ui, ids, kg.params.Rand())
+	if err != nil {
+		return err
+	}
+
+	// 2. commit to the VSS polynomial and rid_i
+	rid := common.MustGetRandomInt(kg.params.Rand(), cmts.HashLength)
+	pGFlat, err := crypto.FlattenECPoints(vs)
+	if err != nil {
+		return err
+	}
+	cmt := cmts.NewHashCommitment(kg.params.Rand(), append(pGFlat, rid)...)
+
+	kg.ui = ui
+	kg.vs = vs
+	kg.shares = shares
+	kg.deCommit = cmt.D
+	kg.data.Ks = ids
+	kg.data.ShareID = ids[Pi.Index]
+
+	otherIds := kg.params.Parties().IDs().Exclude(Pi)
+	msg := &keygenRound1msg{Commitment: cmt.C.Bytes()}
+	for _, Pj := range otherIds {
+		kg.broker.Receive(tss.JsonWrap(kg.params.MsgType("cggmp:keygen:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[keygenRound1msg](kg.params.MsgType("cggmp:keygen:round1"), otherIds, kg.round2, kg.timeout(1), kg.echo(1, "cggmp:keygen:round1", otherIds))
+	kg.broker.Connect(kg.params.MsgType("cggmp:keygen:round1"), rcv)
+	return nil
+}
+
+// round2 stores the commitments of the other parties, and sends the shares and de-commitment.
+func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
+	if kg.ctx.Err() != nil {
+		kg.fail(kg.ctx.Err())
+		return
+	}
+	Pi := kg.params.PartyID()
+
+	for n, r1msg := range r1msgs {
+		kg.kgcs[otherIds[n].Index] = new(big.Int).SetBytes(r1msg.Commitment)
+	}
+
+	for _, Pj := range otherIds {
+		r2msg1 := &keygenRound2msg1{Share: kg.shares[Pj.Index].Share.Bytes()}
+		kg.broker.Receive(tss.JsonWrapPrivate(kg.params.MsgType("cggmp:keygen:round2-1"), r2msg1, Pi, Pj))
+	}
+	r2msg2 := &keygenRound2msg2{DeCommitment: common.BigIntsToBytes(kg.deCommit)}
+	for _, Pj := range otherIds {
+		kg.broker.Receive(tss.JsonWrap(kg.params.MsgType("cggmp:keygen:round2-2"), r2msg2, Pi, Pj))
+	}
+
+	connectPair(kg.broker, kg.params, "cggmp:keygen:round2", otherIds, kg.round3, kg.timeout(2), kg.echo(2, "cggmp:keygen:round2-2", otherIds))
+}
+
+// round3 checks the de-commitments and shares, computes the key share, and proves knowledge of
+// ui.
+func (kg *Keygen) round3(otherIds []*tss.PartyID, r2msgs1 []*keygenRound2msg1, r2msgs2 []*keygenRound2msg2) {
+	if kg.ctx.Err() != nil {
+		kg.fail(kg.ctx.Err())
+		return
+	}
+	Pi := kg.params.PartyID()
+	ec := kg.params.EC()
+	threshold := kg.params.Threshold()
+
+	var errs []error
+	rid := new(big.Int).Set(kg.deCommit[len(kg.deCommit)-1])
+	xi := new(big.Int).Set(kg.shares[Pi.Index].Share)
+	for n, Pj := range otherIds {
+		cmtDeCmt := cmts.HashCommitDecommit{C: kg.kgcs[Pj.Index], D: cmts.NewHashDeCommitmentFromBytes(r2msgs2[n].DeCommitment)}
+		ok, secrets := cmtDeCmt.DeCommit()
+		if !ok || len(secrets) != 2*(threshold+1)+1 {
+			errs = append(errs, kg.wrapError(3, errors.New("decommitment verification failed"), Pj))
+			continue
+		}
+		PjVs, err := crypto.UnFlattenECPoints(ec, secrets[:len(secrets)-1])
+		if err != nil {
+			errs = append(errs, kg.wrapError(3, fmt.Errorf("unflatten EC points failed: %w", err), Pj))
+			continue
+		}
+		share := vss.Share{
+			Threshold: threshold,
+			ID:        Pi.KeyInt(),
+			Share:     new(big.Int).SetBytes(r2msgs1[n].Share),
+		}
+		if !share.Verify(ec, threshold, PjVs) {
+			errs = append(errs, kg.wrapError(3, errors.New("VSS share verification failed"), Pj))
+			continue
+		}
+		kg.polyGs[Pj.Index] = PjVs
+		rid.Xor(rid, secrets[len(secrets)-1])
+		xi.Add(xi, share.Share)
+	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		kg.fail(err)
+		return
+	}
+	kg.polyGs[Pi.Index] = kg.vs
+	kg.rid = rid
+	kg.data.Xi = xi.Mod(xi, ec.Params().N)
+
+	// Xj = sum of the VSS commitments evaluated at kj
+	modQ := common.ModInt(ec.Params().N)
+	for j, kj := range kg.data.Ks {
+		var terms []*crypto.ECPoint
+		for _, vs := range kg.polyGs {
+			z := big.NewInt(1)
+			for c := range vs {
+				terms = append(terms, scalarMult(vs[c], z))
+				z = modQ.Mul(z, kj)
+			}
+		}
+		BigXj, err := addPoints(terms...)
+		if err != nil || BigXj == nil {
+			kg.fail(kg.wrapError(3, fmt.Errorf("failed computing BigXj for party %d", j)))
+			return
+		}
+		kg.data.BigXj[j] = BigXj
+	}
+	var v0s []*crypto.ECPoint
+	for _, vs := range kg.polyGs {
+		v0s = append(v0s, vs[0])
+	}
+	ecdsaPub, err := addPoints(v0s...)
+	if err != nil || ecdsaPub == nil {
+		kg.fail(kg.wrapError(3, errors.New("failed computing the public key")))
+		return
+	}
+	kg.data.ECDSAPub = ecdsaPub
+
+	// prove knowledge of ui, bound to rid
+	proof, err := schnorr.NewZKProof(kg.session(Pi.Index), kg.ui, kg.vs[0], kg.params.Rand())
+	if err != nil {
+		kg.fail(kg.wrapError(3, err))
+		return
+	}
+	kg.ui = zero
+
+	r3msg := &keygenRound3msg{
+		ProofAlphaX: proof.Alpha.X().Bytes(),
+		ProofAlphaY: proof.Alpha.Y().Bytes(),
+		ProofT:      proof.T.Bytes(),
+	}
+	for _, Pj := range otherIds {
+		kg.broker.Receive(tss.JsonWrap(kg.params.MsgType("cggmp:keygen:round3"), r3msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[keygenRound3msg](kg.params.MsgType("cggmp:keygen:round3"), otherIds, kg.round4, kg.timeout(3), kg.echo(3, "cggmp:keygen:round3", otherIds))
+	kg.broker.Connect(kg.params.MsgType("cggmp:keygen:round3"), rcv)
+}
+
+// round4 verifies the Schnorr proofs of the other parties and completes keygen.
+func (kg *Keygen) round4(otherIds []*tss.PartyID, r3msgs []*keygenRound3msg) {
+	if kg.ctx.Err() != nil {
+		kg.fail(kg.ctx.Err())
+		return
+	}
+
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		alpha, err := pointFromBytes(kg.params, r3msgs[n].ProofAlphaX, r3msgs[n].ProofAlphaY)
+		if err != nil {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		proof := &schnorr.ZKProof{Alpha: alpha, T: new(big.Int).SetBytes(r3msgs[n].ProofT)}
+		if !proof.Verify(kg.session(Pj.Index), kg.polyGs[Pj.Index][0]) {
+			culprits = append(culprits, Pj)
+		}
+	}
+	if len(culprits) > 0 {
+		kg.fail(kg.wrapError(4, errors.New("schnorr proof verification failed"), culprits...))
+		return
+	}
+
+	kg.release()
+	kg.Done <- kg.data
+}
+
+// session returns the session of the proofs of the party of index j, bound to rid.
+func (kg *Keygen) session(j int) []byte {
+	return common.AppendBigIntToBytesSlice(common.AppendBigIntToBytesSlice(kg.ssid, kg.rid), big.NewInt(int64(j)))
+}
+
+// fail reports err on Err and releases the receivers registered by this keygen.
+func (kg *Keygen) fail(err error) {
+	kg.release()
+	select {
+	case kg.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this keygen from the broker.
+func (kg *Keygen) release() {
+	kg.stop()
+	kg.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (kg *Keygen) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(kg.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking that the broadcast messages of the given type were the
+// same for all of peers. It is used whether echo broadcast is enabled or not.
+func (kg *Keygen) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	return tss.WithEcho(kg.broker, kg.params.MsgType(typ+":echo"), kg.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (kg *Keygen) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskKeygen, round, kg.params.PartyID(), culprits...)
+}
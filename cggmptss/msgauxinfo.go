@@ -0,0 +1,34 @@
+package cggmptss
+
+// messages for aux info
+
+// auxInfoRound1msg is a broadcast message containing the commitment to the refresh polynomial
+// and rid_i, along with the new Paillier key and ring-Pedersen parameters of the sender.
+type auxInfoRound1msg struct {
+	Commitment []byte   `json:"commitment"`
+	PaillierN  []byte   `json:"paillier_n"`
+	NTilde     []byte   `json:"ntilde"`
+	H1         []byte   `json:"h1"`
+	H2         []byte   `json:"h2"`
+	DlnProof1  [][]byte `json:"dln_proof_1"`
+	DlnProof2  [][]byte `json:"dln_proof_2"`
+}
+
+// auxInfoRound2msg is a broadcast message containing the de-commitment of round 1.
+type auxInfoRound2msg struct {
+	DeCommitment [][]byte `json:"de_commitment"`
+}
+
+// auxInfoRound3msg1 is a P2P message containing the refresh share of the recipient, encrypted
+// with its new Paillier key, and the proof that the Paillier modulus of the sender has no
+// small factor.
+type auxInfoRound3msg1 struct {
+	Share    []byte   `json:"share"`
+	FacProof [][]byte `json:"fac_proof"`
+}
+
+// auxInfoRound3msg2 is a broadcast message containing the proof that the Paillier modulus of
+// the sender is a Paillier-Blum modulus.
+type auxInfoRound3msg2 struct {
+	ModProof [][]byte `json:"mod_proof"`
+}
@@ -0,0 +1,27 @@
+package cggmptss
+
+// messages for keygen
+
+// keygenRound1msg is a broadcast message containing the commitment to the VSS polynomial and
+// the random identifier rid_i.
+type keygenRound1msg struct {
+	Commitment []byte `json:"commitment"`
+}
+
+// keygenRound2msg1 is a P2P message containing the VSS share of the recipient.
+type keygenRound2msg1 struct {
+	Share []byte `json:"share"`
+}
+
+// keygenRound2msg2 is a broadcast message containing the de-commitment of round 1.
+type keygenRound2msg2 struct {
+	DeCommitment [][]byte `json:"de_commitment"`
+}
+
+// keygenRound3msg is a broadcast message containing the Schnorr proof of the secret ui,
+// bound to the common rid.
+type keygenRound3msg struct {
+	ProofAlphaX []byte `json:"proof_alpha_x"`
+	ProofAlphaY []byte `json:"proof_alpha_y"`
+	ProofT      []byte `json:"proof_t"`
+}
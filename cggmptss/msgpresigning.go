@@ -0,0 +1,86 @@
+package cggmptss
+
+// messages for presigning and signing
+
+// presignRound1msg1 is a P2P message containing the proof that K encrypts a small value, made
+// with the ring-Pedersen parameters of the recipient.
+type presignRound1msg1 struct {
+	EncProof [][]byte `json:"enc_proof"`
+}
+
+// presignRound1msg2 is a broadcast message containing the encryptions of k_i and gamma_i.
+type presignRound1msg2 struct {
+	K []byte `json:"k"`
+	G []byte `json:"g"`
+}
+
+// presignRound2msg1 is a P2P message containing the proofs of the MtA conversions sent to the
+// recipient and of the encryption of gamma_i.
+type presignRound2msg1 struct {
+	AffgProof    [][]byte `json:"affg_proof"`
+	AffgHatProof [][]byte `json:"affg_hat_proof"`
+	LogstarProof [][]byte `json:"logstar_proof"`
+}
+
+// presignRound2msg2 is a broadcast message containing Gamma_i and the ciphertexts of the MtA
+// conversions with each party, indexed by party. They are broadcast so that every party can
+// check the decryption proofs if the protocol has to identify a culprit.
+type presignRound2msg2 struct {
+	GammaX []byte   `json:"gamma_x"`
+	GammaY []byte   `json:"gamma_y"`
+	D      [][]byte `json:"d"`
+	F      [][]byte `json:"f"`
+	DHat   [][]byte `json:"d_hat"`
+	FHat   [][]byte `json:"f_hat"`
+}
+
+// presignRound3msg1 is a P2P message containing the proof that Delta_i = k_i·Gamma.
+type presignRound3msg1 struct {
+	LogstarProof [][]byte `json:"logstar_proof"`
+}
+
+// presignRound3msg2 is a broadcast message containing delta_i, Delta_i = k_i·Gamma and
+// S_i = chi_i·Gamma.
+type presignRound3msg2 struct {
+	Delta  []byte `json:"delta"`
+	DeltaX []byte `json:"delta_x"`
+	DeltaY []byte `json:"delta_y"`
+	SX     []byte `json:"s_x"`
+	SY     []byte `json:"s_y"`
+}
+
+// presignRound4msg1 is a P2P message, only sent when delta is inconsistent, containing the
+// proof that delta_i is the decryption modulo q of the MtA values of the sender.
+type presignRound4msg1 struct {
+	DecProof [][]byte `json:"dec_proof"`
+}
+
+// presignRound4msg2 is a broadcast message, only sent when delta is inconsistent, containing
+// H_i, an encryption of k_i·gamma_i, and its proof.
+type presignRound4msg2 struct {
+	H        []byte   `json:"h"`
+	MulProof [][]byte `json:"mul_proof"`
+}
+
+// presignChiMsg1 is a P2P message, only sent when chi is inconsistent, containing the proof
+// that H_i is an encryption of k_i·w_i, and the proof that sigma_i is the decryption modulo q
+// of the encryption of k_i·m + r·chi_i computed from the MtA values of the sender.
+type presignChiMsg1 struct {
+	AffgProof [][]byte `json:"affg_proof"`
+	DecProof  [][]byte `json:"dec_proof"`
+}
+
+// presignChiMsg2 is a broadcast message, only sent when chi is inconsistent, containing H_i,
+// the encryption Y_i of zero its proof is made with, and sigma_i = k_i·m + r·chi_i for the
+// identification message m.
+type presignChiMsg2 struct {
+	H     []byte `json:"h"`
+	Y     []byte `json:"y"`
+	Sigma []byte `json:"sigma"`
+}
+
+// signMsg is a broadcast message containing the partial signature made with a presignature.
+type signMsg struct {
+	ID    []byte `json:"id"`
+	Sigma []byte `json:"sigma"`
+}
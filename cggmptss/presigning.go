@@ -0,0 +1,926 @@
+package cggmptss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/affgproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/decproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/encproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/logstarproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/mulproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskPresigning is the task name reported in errors from Presigning.
+const TaskPresigning = "cggmp-presigning"
+
+// ErrPresignatureUsed is returned by SignWithPresignature when the presignature was already
+// used to sign.
+var ErrPresignatureUsed = errors.New("presignature was already used")
+
+// PreSignature holds the result of presigning: the nonce point R, the local shares of k and
+// k·x, and the points used to check the partial signature of each party. It can be persisted
+// as json and used by SignWithPresignature to produce exactly one signature, with the same
+// committee.
+//
+// A PreSignature is as sensitive as a key share: signing two messages with the same
+// presignature reveals the key. SignWithPresignature clears it before use, once its markUsed
+// callback recorded the use, for instance by deleting the stored copies.
+type PreSignature struct {
+	ID   []byte            // identifies the presignature, the same for all the parties
+	Ks   []*big.Int        // keys of the parties of the committee, in order
+	K    *big.Int          // ki
+	Chi  *big.Int          // chi_i, share of k·x
+	R    *crypto.ECPoint   // R = g^(1/k)
+	BigR []*crypto.ECPoint // kj·R for each party
+	BigS []*crypto.ECPoint // chi_j·R for each party
+
+	lock sync.Mutex
+}
+
+// Presigning tracks the presigning protocol of CGGMP21 (Fig. 7 and 8), which runs the rounds
+// of signing that do not depend on the message. The MtA conversions are made with Paillier
+// encryption and checked with zero-knowledge proofs made for the ring-Pedersen parameters of
+// each verifier.
+//
+// When the shares of delta = k·gamma do not add up, every party proves that its share is the
+// decryption of its MtA values, and the parties whose proof fails are reported as culprits
+// of round 5. When the points S_j = chi_j·Gamma do not add up to delta·X, every party reveals
+// its partial signature sigma_j of an identification message, and proves that it is the
+// decryption of its MtA values: the parties whose S_j does not match sigma_j, or whose proof
+// fails, are reported as culprits of round 5 as well. A party finding the proofs of all the
+// others valid, as the party at fault does, fails without culprits.
+//
+// Broadcast messages are always followed by an echo round, whatever the setting of
+// params.EchoBroadcast: the identification of the culprits relies on all the parties having
+// received the same values.
+type Presigning struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	ssid   []byte
+	key    *ecdsatss.Key // key, in the order of the committee
+
+	// round 1
+	k, gamma, w *big.Int
+	rhoK, nuG   *big.Int          // randomness of K_i and G_i
+	bigWs       []*crypto.ECPoint // lambda_j·Xj for each party
+	bigKs       []*big.Int        // K_j for each party
+	bigGs       []*big.Int        // G_j for each party
+
+	// round 2
+	betas, betaHats []*big.Int
+	bigGammas       []*crypto.ECPoint
+	ds, fs          [][]*big.Int // ds[j][l] = D_{l,j}, fs[j][l] = F_{l,j}, as sent by party j
+	dHats, fHats    [][]*big.Int
+
+	// round 3
+	bigGamma *crypto.ECPoint
+	delta    *big.Int // delta_i
+	chi      *big.Int
+	deltas   []*big.Int
+	bigDs    []*crypto.ECPoint // Delta_j for each party
+	bigSs    []*crypto.ECPoint // S_j for each party
+
+	// identification of the culprits of an inconsistent chi
+	m, r *big.Int // identification message, and r = R.x
+
+	Done chan *PreSignature
+	Err  chan error
+}
+
+// NewPresigning creates a new Presigning with key, for the committee of the parties of
+// params, and executes round 1. The key must hold the auxiliary information of NewAuxInfo.
+func NewPresigning(ctx context.Context, key *ecdsatss.Key, params *tss.Parameters) (*Presigning, error) {
+	if key.MoreKs != nil {
+		return nil, ecdsatss.ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ecdsatss.ErrHierarchicalKey
+	}
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	if !hasAuxInfo(key) {
+		return nil, errors.New("key has no aux info, NewAuxInfo must be run first")
+	}
+	if params.PartyCount() <= params.Threshold() {
+		return nil, fmt.Errorf("presigning needs more than %d parties, got %d", params.Threshold(), params.PartyCount())
+	}
+	partyCount := params.PartyCount()
+	p := &Presigning{
+		ctx:       ctx,
+		params:    params,
+		key:       key,
+		bigWs:     make([]*crypto.ECPoint, partyCount),
+		bigKs:     make([]*big.Int, partyCount),
+		bigGs:     make([]*big.Int, partyCount),
+		betas:     make([]*big.Int, partyCount),
+		betaHats:  make([]*big.Int, partyCount),
+		bigGammas: make([]*crypto.ECPoint, partyCount),
+		ds:        make([][]*big.Int, partyCount),
+		fs:        make([][]*big.Int, partyCount),
+		dHats:     make([][]*big.Int, partyCount),
+		fHats:     make([][]*big.Int, partyCount),
+		deltas:    make([]*big.Int, partyCount),
+		bigDs:     make([]*crypto.ECPoint, partyCount),
+		bigSs:     make([]*crypto.ECPoint, partyCount),
+		Done:      make(chan *PreSignature, 1),
+		Err:       make(chan error, 1),
+	}
+	p.broker = tss.NewSessionBroker(params.Broker())
+	p.stop = context.AfterFunc(ctx, func() { p.fail(ctx.Err()) })
+	if err := p.round1(); err != nil {
+		p.release()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Presigning) round1() error {
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	values, err := keyValues(p.key)
+	if err != nil {
+		return err
+	}
+	p.ssid = getSSID(p.params, TaskPresigning, values...)
+
+	// w_i = lambda_i·x_i and W_j = lambda_j·X_j, so that the w_i add up to x
+	for j := range p.key.Ks {
+		lambda, err := vss.LagrangeCoefficient(q, p.key.Ks, j, big.NewInt(0))
+		if err != nil {
+			return err
+		}
+		if j == i {
+			p.w = modQ.Mul(lambda, p.key.Xi)
+		}
+		if p.bigWs[j] = scalarMult(p.key.BigXj[j], lambda); p.bigWs[j] == nil {
+			return fmt.Errorf("public share of party %d is invalid", j)
+		}
+	}
+
+	// 1. sample k_i and gamma_i, and encrypt them
+	pk := p.key.PaillierPKs[i]
+	p.k = common.GetRandomPositiveInt(p.params.Rand(), q)
+	p.gamma = common.GetRandomPositiveInt(p.params.Rand(), q)
+	K, rhoK, err := pk.EncryptAndReturnRandomness(p.params.Rand(), p.k)
+	if err != nil {
+		return err
+	}
+	G, nuG, err := pk.EncryptAndReturnRandomness(p.params.Rand(), p.gamma)
+	if err != nil {
+		return err
+	}
+	p.rhoK, p.nuG = rhoK, nuG
+	p.bigKs[i], p.bigGs[i] = K, G
+
+	// 2. prove to each party that K_i encrypts a small value
+	otherIds := p.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range otherIds {
+		j := Pj.Index
+		proof, err := encproof.NewProof(p.session(i), ec, pk, K, p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j], p.k, rhoK, p.params.Rand())
+		if err != nil {
+			return err
+		}
+		bzs := proof.Bytes()
+		r1msg1 := &presignRound1msg1{EncProof: bzs[:]}
+		p.broker.Receive(tss.JsonWrapPrivate(p.params.MsgType("cggmp:presign:round1-1"), r1msg1, Pi, Pj))
+	}
+	r1msg2 := &presignRound1msg2{K: K.Bytes(), G: G.Bytes()}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("cggmp:presign:round1-2"), r1msg2, Pi, Pj))
+	}
+
+	connectPair(p.broker, p.params, "cggmp:presign:round1", otherIds, p.round2, p.timeout(1), p.echo(1, "cggmp:presign:round1-2", otherIds))
+	return nil
+}
+
+// round2 verifies the encryptions of k_j, and runs the MtA conversions of gamma_i and w_i with
+// each party.
+func (p *Presigning) round2(otherIds []*tss.PartyID, r1msgs1 []*presignRound1msg1, r1msgs2 []*presignRound1msg2) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.params.EC()
+	rnd := p.params.Rand()
+
+	// 1. verify the proofs of K_j
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		pkj := p.key.PaillierPKs[j]
+		p.bigKs[j] = new(big.Int).SetBytes(r1msgs2[n].K)
+		p.bigGs[j] = new(big.Int).SetBytes(r1msgs2[n].G)
+		if !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), p.bigKs[j]) || !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), p.bigGs[j]) {
+			failed[n] = true
+			continue
+		}
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			proof, err := encproof.NewProofFromBytes(r1msgs1[n].EncProof)
+			if err != nil || !proof.Verify(p.session(j), ec, pkj, p.bigKs[j], p.key.NTildei, p.key.H1i, p.key.H2i) {
+				failed[n] = true
+			}
+		}(n, j)
+	}
+	wg.Wait()
+	if culprits := culpritsOf(otherIds, failed); len(culprits) > 0 {
+		p.fail(p.wrapError(2, errors.New("enc proof verification failed"), culprits...))
+		return
+	}
+
+	// 2. Gamma_i, and the MtA conversions with each party
+	bigGamma := baseMult(ec, p.gamma)
+	if bigGamma == nil {
+		p.fail(p.wrapError(2, errors.New("gamma_i is zero")))
+		return
+	}
+	p.bigGammas[i] = bigGamma
+	partyCount := p.params.PartyCount()
+	ds, fs := make([]*big.Int, partyCount), make([]*big.Int, partyCount)
+	dHats, fHats := make([]*big.Int, partyCount), make([]*big.Int, partyCount)
+	r2msgs1 := make([]*presignRound2msg1, len(otherIds))
+	errs := make([]error, len(otherIds))
+	g := crypto.ScalarBaseMult(ec, big.NewInt(1))
+	twoLPrime := new(big.Int).Lsh(big.NewInt(1), uint(5*ec.Params().N.BitLen()))
+	// each party's conversions draw from their own stream, so that they make the same proofs
+	// whatever order they run in
+	streams := make([]io.Reader, len(otherIds))
+	for n := range streams {
+		var err error
+		if streams[n], err = tss.RandStream(rnd); err != nil {
+			p.fail(p.wrapError(2, err))
+			return
+		}
+	}
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		p.betas[j] = common.GetRandomPositiveInt(rnd, twoLPrime)
+		p.betaHats[j] = common.GetRandomPositiveInt(rnd, twoLPrime)
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			var err error
+			var r2msg1 presignRound2msg1
+			session := p.session(i)
+			NTildej, H1j, H2j := p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j]
+
+			var affg *affgproof.ProofAffg
+			ds[j], fs[j], affg, err = p.mta(session, j, p.gamma, p.betas[j], bigGamma, streams[n])
+			if err != nil {
+				errs[n] = err
+				return
+			}
+			affgBzs := affg.Bytes()
+			r2msg1.AffgProof = affgBzs[:]
+
+			dHats[j], fHats[j], affg, err = p.mta(session, j, p.w, p.betaHats[j], p.bigWs[i], streams[n])
+			if err != nil {
+				errs[n] = err
+				return
+			}
+			affgHatBzs := affg.Bytes()
+			r2msg1.AffgHatProof = affgHatBzs[:]
+
+			logstar, err := logstarproof.NewProof(session, ec, p.key.PaillierPKs[i], p.bigGs[i], bigGamma, g, NTildej, H1j, H2j, p.gamma, p.nuG, streams[n])
+			if err != nil {
+				errs[n] = err
+				return
+			}
+			logstarBzs := logstar.Bytes()
+			r2msg1.LogstarProof = logstarBzs[:]
+			r2msgs1[n] = &r2msg1
+		}(n, j)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		p.fail(p.wrapError(2, err))
+		return
+	}
+	p.ds[i], p.fs[i], p.dHats[i], p.fHats[i] = ds, fs, dHats, fHats
+
+	for n, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrapPrivate(p.params.MsgType("cggmp:presign:round2-1"), r2msgs1[n], Pi, Pj))
+	}
+	r2msg2 := &presignRound2msg2{
+		GammaX: bigGamma.X().Bytes(),
+		GammaY: bigGamma.Y().Bytes(),
+		D:      intsToBytes(ds),
+		F:      intsToBytes(fs),
+		DHat:   intsToBytes(dHats),
+		FHat:   intsToBytes(fHats),
+	}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("cggmp:presign:round2-2"), r2msg2, Pi, Pj))
+	}
+
+	connectPair(p.broker, p.params, "cggmp:presign:round2", otherIds, p.round3, p.timeout(2), p.echo(2, "cggmp:presign:round2-2", otherIds))
+}
+
+// mta returns D = K_j^x·Enc_j(beta) and F = Enc_i(beta), along with the proof for party j that
+// they were computed with the discrete logarithm x of X. It draws its randomness from rnd.
+func (p *Presigning) mta(session []byte, j int, x, beta *big.Int, X *crypto.ECPoint, rnd io.Reader) (D, F *big.Int, proof *affgproof.ProofAffg, err error) {
+	i := p.params.PartyID().Index
+	pki, pkj := p.key.PaillierPKs[i], p.key.PaillierPKs[j]
+	encBeta, rho, err := pkj.EncryptAndReturnRandomness(rnd, beta)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kx, err := pkj.HomoMult(x, p.bigKs[j])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if D, err = pkj.HomoAdd(kx, encBeta); err != nil {
+		return nil, nil, nil, err
+	}
+	F, rhoy, err := pki.EncryptAndReturnRandomness(rnd, beta)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	proof, err = affgproof.NewProof(session, p.params.EC(), pkj, pki, p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j], p.bigKs[j], D, F, X, x, beta, rho, rhoy, rnd)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return D, F, proof, nil
+}
+
+// round3 verifies the MtA conversions of the other parties, and computes the shares of delta
+// and chi.
+func (p *Presigning) round3(otherIds []*tss.PartyID, r2msgs1 []*presignRound2msg1, r2msgs2 []*presignRound2msg2) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+	partyCount := p.params.PartyCount()
+	pki := p.key.PaillierPKs[i]
+	g := crypto.ScalarBaseMult(ec, big.NewInt(1))
+
+	// 1. verify the MtA conversions sent to this party and the proofs of G_j
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		r2msg2 := r2msgs2[n]
+		bigGamma, err := pointFromBytes(p.params, r2msg2.GammaX, r2msg2.GammaY)
+		if err != nil || len(r2msg2.D) != partyCount || len(r2msg2.F) != partyCount || len(r2msg2.DHat) != partyCount || len(r2msg2.FHat) != partyCount {
+			failed[n] = true
+			continue
+		}
+		p.bigGammas[j] = bigGamma
+		p.ds[j], p.fs[j] = bytesToInts(r2msg2.D), bytesToInts(r2msg2.F)
+		p.dHats[j], p.fHats[j] = bytesToInts(r2msg2.DHat), bytesToInts(r2msg2.FHat)
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			session := p.session(j)
+			pkj := p.key.PaillierPKs[j]
+			r2msg1 := r2msgs1[n]
+			affg, err := affgproof.NewProofFromBytes(ec, r2msg1.AffgProof)
+			if err != nil || !affg.Verify(session, ec, pki, pkj, p.key.NTildei, p.key.H1i, p.key.H2i, p.bigKs[i], p.ds[j][i], p.fs[j][i], p.bigGammas[j]) {
+				failed[n] = true
+				return
+			}
+			affgHat, err := affgproof.NewProofFromBytes(ec, r2msg1.AffgHatProof)
+			if err != nil || !affgHat.Verify(session, ec, pki, pkj, p.key.NTildei, p.key.H1i, p.key.H2i, p.bigKs[i], p.dHats[j][i], p.fHats[j][i], p.bigWs[j]) {
+				failed[n] = true
+				return
+			}
+			logstar, err := logstarproof.NewProofFromBytes(ec, r2msg1.LogstarProof)
+			if err != nil || !logstar.Verify(session, ec, pkj, p.bigGs[j], p.bigGammas[j], g, p.key.NTildei, p.key.H1i, p.key.H2i) {
+				failed[n] = true
+			}
+		}(n, j)
+	}
+	wg.Wait()
+	if culprits := culpritsOf(otherIds, failed); len(culprits) > 0 {
+		p.fail(p.wrapError(3, errors.New("MtA proof verification failed"), culprits...))
+		return
+	}
+
+	// 2. Gamma, Delta_i = k_i·Gamma, and the shares of delta = k·gamma and chi = k·x
+	bigGamma, err := addPoints(p.bigGammas...)
+	if err != nil || bigGamma == nil {
+		p.fail(p.wrapError(3, errors.New("failed computing Gamma")))
+		return
+	}
+	p.bigGamma = bigGamma
+	sk := p.key.PaillierSK
+	delta := modQ.Mul(p.gamma, p.k)
+	chi := modQ.Mul(p.w, p.k)
+	for _, Pj := range otherIds {
+		j := Pj.Index
+		alpha, err := sk.Decrypt(p.ds[j][i])
+		if err != nil {
+			p.fail(p.wrapError(3, err, Pj))
+			return
+		}
+		alphaHat, err := sk.Decrypt(p.dHats[j][i])
+		if err != nil {
+			p.fail(p.wrapError(3, err, Pj))
+			return
+		}
+		delta = modQ.Add(delta, modQ.Sub(alpha, p.betas[j]))
+		chi = modQ.Add(chi, modQ.Sub(alphaHat, p.betaHats[j]))
+	}
+	p.delta, p.chi = delta, chi
+	p.deltas[i] = delta
+	p.bigDs[i] = scalarMult(bigGamma, p.k)
+	p.bigSs[i] = scalarMult(bigGamma, chi)
+	if p.bigDs[i] == nil || p.bigSs[i] == nil {
+		p.fail(p.wrapError(3, errors.New("failed computing Delta_i")))
+		return
+	}
+
+	for _, Pj := range otherIds {
+		j := Pj.Index
+		proof, err := logstarproof.NewProof(p.session(i), ec, pki, p.bigKs[i], p.bigDs[i], bigGamma, p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j], p.k, p.rhoK, p.params.Rand())
+		if err != nil {
+			p.fail(p.wrapError(3, err))
+			return
+		}
+		bzs := proof.Bytes()
+		r3msg1 := &presignRound3msg1{LogstarProof: bzs[:]}
+		p.broker.Receive(tss.JsonWrapPrivate(p.params.MsgType("cggmp:presign:round3-1"), r3msg1, Pi, Pj))
+	}
+	r3msg2 := &presignRound3msg2{
+		Delta:  delta.Bytes(),
+		DeltaX: p.bigDs[i].X().Bytes(),
+		DeltaY: p.bigDs[i].Y().Bytes(),
+		SX:     p.bigSs[i].X().Bytes(),
+		SY:     p.bigSs[i].Y().Bytes(),
+	}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("cggmp:presign:round3-2"), r3msg2, Pi, Pj))
+	}
+
+	connectPair(p.broker, p.params, "cggmp:presign:round3", otherIds, p.round4, p.timeout(3), p.echo(3, "cggmp:presign:round3-2", otherIds))
+}
+
+// round4 verifies Delta_j, checks delta and completes presigning, or starts the identification
+// of the culprits when delta is inconsistent.
+func (p *Presigning) round4(otherIds []*tss.PartyID, r3msgs1 []*presignRound3msg1, r3msgs2 []*presignRound3msg2) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+	i := p.params.PartyID().Index
+	ec := p.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// 1. verify Delta_j = k_j·Gamma
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		r3msg2 := r3msgs2[n]
+		bigD, err := pointFromBytes(p.params, r3msg2.DeltaX, r3msg2.DeltaY)
+		if err != nil {
+			failed[n] = true
+			continue
+		}
+		bigS, err := pointFromBytes(p.params, r3msg2.SX, r3msg2.SY)
+		if err != nil {
+			failed[n] = true
+			continue
+		}
+		p.deltas[j] = new(big.Int).SetBytes(r3msg2.Delta)
+		p.bigDs[j], p.bigSs[j] = bigD, bigS
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			proof, err := logstarproof.NewProofFromBytes(ec, r3msgs1[n].LogstarProof)
+			if err != nil || !proof.Verify(p.session(j), ec, p.key.PaillierPKs[j], p.bigKs[j], p.bigDs[j], p.bigGamma, p.key.NTildei, p.key.H1i, p.key.H2i) {
+				failed[n] = true
+			}
+		}(n, j)
+	}
+	wg.Wait()
+	if culprits := culpritsOf(otherIds, failed); len(culprits) > 0 {
+		p.fail(p.wrapError(4, errors.New("logstar proof verification failed"), culprits...))
+		return
+	}
+
+	// 2. delta·G = sum of Delta_j, otherwise identify the parties that sent a wrong delta_j
+	delta := big.NewInt(0)
+	for _, deltaj := range p.deltas {
+		delta = modQ.Add(delta, deltaj)
+	}
+	sumD, err := addPoints(p.bigDs...)
+	if err != nil || delta.Sign() == 0 || !pointsEqual(baseMult(ec, delta), sumD) {
+		p.blame(otherIds)
+		return
+	}
+
+	// 3. sum of S_j = chi·Gamma = delta·X, otherwise identify the parties that sent a wrong S_j
+	sumS, err := addPoints(p.bigSs...)
+	if err != nil || !pointsEqual(sumS, scalarMult(p.key.ECDSAPub, delta)) {
+		p.blameChi(otherIds, delta)
+		return
+	}
+
+	// 4. R = delta^-1·Gamma, and the points to check each partial signature
+	deltaInv := modQ.ModInverse(delta)
+	R := scalarMult(p.bigGamma, deltaInv)
+	presig := &PreSignature{
+		ID:   common.SHA512_256(p.ssid, R.X().Bytes(), R.Y().Bytes()),
+		Ks:   p.key.Ks,
+		K:    p.k,
+		Chi:  p.chi,
+		R:    R,
+		BigR: make([]*crypto.ECPoint, len(p.bigDs)),
+		BigS: make([]*crypto.ECPoint, len(p.bigSs)),
+	}
+	for j := range p.bigDs {
+		presig.BigR[j] = scalarMult(p.bigDs[j], deltaInv)
+		presig.BigS[j] = scalarMult(p.bigSs[j], deltaInv)
+	}
+	p.k, p.gamma, p.w, p.chi = zero, zero, zero, zero
+	p.betas[i], p.betaHats[i] = nil, nil
+
+	p.release()
+	p.Done <- presig
+}
+
+// blame sends H_i, an encryption of k_i·gamma_i, and proves to each party that delta_i is the
+// decryption modulo q of H_i and the MtA values of this party.
+func (p *Presigning) blame(otherIds []*tss.PartyID) {
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.params.EC()
+	q := ec.Params().N
+	pki := p.key.PaillierPKs[i]
+	session := p.session(i)
+
+	// H_i = G_i^k_i·rho^N, an encryption of k_i·gamma_i
+	rho := common.GetRandomPositiveRelativelyPrimeInt(p.params.Rand(), pki.N)
+	modNSquare := common.ModInt(pki.NSquare())
+	H := modNSquare.Mul(modNSquare.Exp(p.bigGs[i], p.k), modNSquare.Exp(rho, pki.N))
+	mul, err := mulproof.NewProof(session, ec, pki, p.bigKs[i], p.bigGs[i], H, p.k, rho, p.rhoK, p.params.Rand())
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+
+	C, err := p.shareCiphertext(i, H, p.ds, p.fs)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	y, rhoC, err := p.key.PaillierSK.DecryptAndReturnRandomness(C)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	x := new(big.Int).Mod(new(big.Int).Add(p.delta, p.deltaOffset()), q)
+	for _, Pj := range otherIds {
+		j := Pj.Index
+		proof, err := decproof.NewProof(session, ec, pki, C, x, p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j], y, rhoC, p.params.Rand())
+		if err != nil {
+			p.fail(p.wrapError(4, err))
+			return
+		}
+		bzs := proof.Bytes()
+		r4msg1 := &presignRound4msg1{DecProof: bzs[:]}
+		p.broker.Receive(tss.JsonWrapPrivate(p.params.MsgType("cggmp:presign:round4-1"), r4msg1, Pi, Pj))
+	}
+	mulBzs := mul.Bytes()
+	r4msg2 := &presignRound4msg2{H: H.Bytes(), MulProof: mulBzs[:]}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("cggmp:presign:round4-2"), r4msg2, Pi, Pj))
+	}
+
+	connectPair(p.broker, p.params, "cggmp:presign:round4", otherIds, p.round5, p.timeout(4), p.echo(4, "cggmp:presign:round4-2", otherIds))
+}
+
+// round5 verifies the proofs of delta_j, and fails reporting the parties whose proofs are
+// invalid.
+func (p *Presigning) round5(otherIds []*tss.PartyID, r4msgs1 []*presignRound4msg1, r4msgs2 []*presignRound4msg2) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+	ec := p.params.EC()
+	q := ec.Params().N
+
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			session := p.session(j)
+			pkj := p.key.PaillierPKs[j]
+			H := new(big.Int).SetBytes(r4msgs2[n].H)
+			mul, err := mulproof.NewProofFromBytes(r4msgs2[n].MulProof)
+			if err != nil || !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), H) || !mul.Verify(session, ec, pkj, p.bigKs[j], p.bigGs[j], H) {
+				failed[n] = true
+				return
+			}
+			C, err := p.shareCiphertext(j, H, p.ds, p.fs)
+			if err != nil {
+				failed[n] = true
+				return
+			}
+			x := new(big.Int).Mod(new(big.Int).Add(p.deltas[j], p.deltaOffset()), q)
+			dec, err := decproof.NewProofFromBytes(r4msgs1[n].DecProof)
+			if err != nil || !dec.Verify(session, ec, pkj, C, x, p.key.NTildei, p.key.H1i, p.key.H2i) {
+				failed[n] = true
+			}
+		}(n, Pj.Index)
+	}
+	wg.Wait()
+	p.fail(p.wrapError(5, errors.New("delta verification failed"), culpritsOf(otherIds, failed)...))
+}
+
+// blameChi sends H_i, an encryption of k_i·w_i, and the partial signature
+// sigma_i = k_i·m + r·chi_i of an identification message m, and proves to each party that
+// sigma_i is the decryption modulo q of K_i^m·C_i^r, where C_i is the encryption of chi_i
+// computed from H_i and the MtA values of this party. As when signing, sigma_i does not reveal
+// chi_i.
+func (p *Presigning) blameChi(otherIds []*tss.PartyID, delta *big.Int) {
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+	pki := p.key.PaillierPKs[i]
+	session := p.session(i)
+
+	// m is derived from the ssid, and r from R = delta^-1·Gamma
+	R := scalarMult(p.bigGamma, modQ.ModInverse(delta))
+	if R == nil {
+		p.fail(p.wrapError(4, errors.New("failed computing R")))
+		return
+	}
+	p.m = new(big.Int).Mod(new(big.Int).SetBytes(common.SHA512_256(p.ssid, []byte("chi"))), q)
+	p.r = new(big.Int).Mod(R.X(), q)
+
+	// H_i = K_i^w_i·Enc_i(0), proven with an affg proof for y = 0 encrypted as Y_i under the
+	// same key, so that H_i·Y_i^-1 is an encryption of k_i·w_i
+	encZero, rho, err := pki.EncryptAndReturnRandomness(p.params.Rand(), zero)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	kw, err := pki.HomoMult(p.w, p.bigKs[i])
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	H, err := pki.HomoAdd(kw, encZero)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	Y, rhoy, err := pki.EncryptAndReturnRandomness(p.params.Rand(), zero)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+
+	C, err := p.sigmaCiphertext(i, H, Y)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	y, rhoC, err := p.key.PaillierSK.DecryptAndReturnRandomness(C)
+	if err != nil {
+		p.fail(p.wrapError(4, err))
+		return
+	}
+	sigma := modQ.Add(modQ.Mul(p.k, p.m), modQ.Mul(p.r, p.chi))
+	x := modQ.Add(sigma, modQ.Mul(p.r, p.deltaOffset()))
+	for _, Pj := range otherIds {
+		j := Pj.Index
+		NTildej, H1j, H2j := p.key.NTildej[j], p.key.H1j[j], p.key.H2j[j]
+		affg, err := affgproof.NewProof(session, ec, pki, pki, NTildej, H1j, H2j, p.bigKs[i], H, Y, p.bigWs[i], p.w, zero, rho, rhoy, p.params.Rand())
+		if err != nil {
+			p.fail(p.wrapError(4, err))
+			return
+		}
+		dec, err := decproof.NewProof(session, ec, pki, C, x, NTildej, H1j, H2j, y, rhoC, p.params.Rand())
+		if err != nil {
+			p.fail(p.wrapError(4, err))
+			return
+		}
+		affgBzs, decBzs := affg.Bytes(), dec.Bytes()
+		msg1 := &presignChiMsg1{AffgProof: affgBzs[:], DecProof: decBzs[:]}
+		p.broker.Receive(tss.JsonWrapPrivate(p.params.MsgType("cggmp:presign:round4-chi-1"), msg1, Pi, Pj))
+	}
+	msg2 := &presignChiMsg2{H: H.Bytes(), Y: Y.Bytes(), Sigma: sigma.Bytes()}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("cggmp:presign:round4-chi-2"), msg2, Pi, Pj))
+	}
+
+	connectPair(p.broker, p.params, "cggmp:presign:round4-chi", otherIds, p.round5Chi, p.timeout(4), p.echo(4, "cggmp:presign:round4-chi-2", otherIds))
+}
+
+// round5Chi verifies the partial signatures sigma_j of the identification message and their
+// proofs, and fails reporting the parties whose S_j does not match sigma_j or whose proofs are
+// invalid.
+func (p *Presigning) round5Chi(otherIds []*tss.PartyID, msgs1 []*presignChiMsg1, msgs2 []*presignChiMsg2) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+	ec := p.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		wg.Add(1)
+		go func(n, j int) {
+			defer wg.Done()
+			session := p.session(j)
+			pkj := p.key.PaillierPKs[j]
+			H := new(big.Int).SetBytes(msgs2[n].H)
+			Y := new(big.Int).SetBytes(msgs2[n].Y)
+			sigma := new(big.Int).SetBytes(msgs2[n].Sigma)
+			if sigma.Cmp(q) >= 0 || !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), H) || !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), Y) {
+				failed[n] = true
+				return
+			}
+
+			// sigma_j·Gamma = m·Delta_j + r·S_j
+			expected, err := addPoints(scalarMult(p.bigDs[j], p.m), scalarMult(p.bigSs[j], p.r))
+			if err != nil || !pointsEqual(scalarMult(p.bigGamma, sigma), expected) {
+				failed[n] = true
+				return
+			}
+
+			affg, err := affgproof.NewProofFromBytes(ec, msgs1[n].AffgProof)
+			if err != nil || !affg.Verify(session, ec, pkj, pkj, p.key.NTildei, p.key.H1i, p.key.H2i, p.bigKs[j], H, Y, p.bigWs[j]) {
+				failed[n] = true
+				return
+			}
+			C, err := p.sigmaCiphertext(j, H, Y)
+			if err != nil {
+				failed[n] = true
+				return
+			}
+			x := modQ.Add(sigma, modQ.Mul(p.r, p.deltaOffset()))
+			dec, err := decproof.NewProofFromBytes(msgs1[n].DecProof)
+			if err != nil || !dec.Verify(session, ec, pkj, C, x, p.key.NTildei, p.key.H1i, p.key.H2i) {
+				failed[n] = true
+			}
+		}(n, Pj.Index)
+	}
+	wg.Wait()
+	p.fail(p.wrapError(5, errors.New("chi verification failed"), culpritsOf(otherIds, failed)...))
+}
+
+// sigmaCiphertext returns the encryption under the key of party j of k_j·m + r·(chi_j + T),
+// computed from K_j, H_j·Y_j^-1 and the MtA values of the conversions of w with party j.
+func (p *Presigning) sigmaCiphertext(j int, H, Y *big.Int) (*big.Int, error) {
+	pkj := p.key.PaillierPKs[j]
+	modNSquare := common.ModInt(pkj.NSquare())
+	C, err := p.shareCiphertext(j, modNSquare.Mul(H, modNSquare.ModInverse(Y)), p.dHats, p.fHats)
+	if err != nil {
+		return nil, err
+	}
+	return modNSquare.Mul(modNSquare.Exp(p.bigKs[j], p.m), modNSquare.Exp(C, p.r)), nil
+}
+
+// shareCiphertext returns the encryption under the key of party j of its share plus T,
+// computed from H_j, an encryption of the product of its own values, and the ciphertexts of
+// its MtA conversions: H_j·prod(D_{l,j})·prod(F_{j,l})^-1·Enc(T). With ds and fs, the share is
+// delta_j, and with dHats and fHats it is chi_j. The offset T keeps the plaintext positive.
+func (p *Presigning) shareCiphertext(j int, H *big.Int, ds, fs [][]*big.Int) (*big.Int, error) {
+	pkj := p.key.PaillierPKs[j]
+	modNSquare := common.ModInt(pkj.NSquare())
+	C := modNSquare.Mul(H, modNSquare.Exp(pkj.Gamma(), p.deltaOffset()))
+	for l := range p.key.Ks {
+		if l == j {
+			continue
+		}
+		D, F := ds[l][j], fs[j][l]
+		if !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), D) || !common.IsNumberInMultiplicativeGroup(pkj.NSquare(), F) {
+			return nil, fmt.Errorf("invalid MtA ciphertext between parties %d and %d", j, l)
+		}
+		C = modNSquare.Mul(C, modNSquare.Mul(D, modNSquare.ModInverse(F)))
+	}
+	return C, nil
+}
+
+// deltaOffset returns T = (n-1)·2^l', the bound of the sum of the betas of a party.
+func (p *Presigning) deltaOffset() *big.Int {
+	T := new(big.Int).Lsh(big.NewInt(1), uint(5*p.params.EC().Params().N.BitLen()))
+	return T.Mul(T, big.NewInt(int64(p.params.PartyCount()-1)))
+}
+
+// take returns ki and chi_i once markUsed recorded that the presignature is used, and clears
+// them so that the presignature cannot be used again.
+func (presig *PreSignature) take(markUsed func(id []byte) error) (k, chi *big.Int, err error) {
+	presig.lock.Lock()
+	defer presig.lock.Unlock()
+	if presig.K == nil || presig.Chi == nil {
+		return nil, nil, ErrPresignatureUsed
+	}
+	if err := markUsed(presig.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to mark the presignature as used: %w", err)
+	}
+	k, chi = presig.K, presig.Chi
+	presig.K, presig.Chi = nil, nil
+	return k, chi, nil
+}
+
+// culpritsOf returns the parties of ids for which failed is true.
+func culpritsOf(ids []*tss.PartyID, failed []bool) []*tss.PartyID {
+	var culprits []*tss.PartyID
+	for n, id := range ids {
+		if failed[n] {
+			culprits = append(culprits, id)
+		}
+	}
+	return culprits
+}
+
+// intsToBytes returns the bytes of each value of ints, nil values giving empty slices.
+func intsToBytes(ints []*big.Int) [][]byte {
+	bzs := make([][]byte, len(ints))
+	for j, v := range ints {
+		if v != nil {
+			bzs[j] = v.Bytes()
+		}
+	}
+	return bzs
+}
+
+// bytesToInts is the reverse of intsToBytes.
+func bytesToInts(bzs [][]byte) []*big.Int {
+	ints := make([]*big.Int, len(bzs))
+	for j, bz := range bzs {
+		ints[j] = new(big.Int).SetBytes(bz)
+	}
+	return ints
+}
+
+// session returns the session of the proofs of the party of index j.
+func (p *Presigning) session(j int) []byte {
+	return common.AppendBigIntToBytesSlice(p.ssid, big.NewInt(int64(j)))
+}
+
+// fail reports err on Err and releases the receivers registered by this presigning.
+func (p *Presigning) fail(err error) {
+	p.release()
+	select {
+	case p.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this presigning from the broker.
+func (p *Presigning) release() {
+	p.stop()
+	p.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (p *Presigning) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(p.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		p.fail(p.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking that the broadcast messages of the given type were the
+// same for all of peers. It is used whether echo broadcast is enabled or not.
+func (p *Presigning) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	return tss.WithEcho(p.broker, p.params.MsgType(typ+":echo"), p.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		p.fail(p.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (p *Presigning) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskPresigning, round, p.params.PartyID(), culprits...)
+}
@@ -0,0 +1,217 @@
+package cggmptss
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskSigning is the task name reported in errors from Signing.
+const TaskSigning = "cggmp-signing"
+
+// Signing tracks the signing of a message with a presignature (CGGMP21 Fig. 8, output).
+type Signing struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	key    *ecdsatss.Key
+	presig *PreSignature
+
+	m, r, sigma *big.Int
+
+	Done chan *ecdsatss.SignatureData
+	Err  chan error
+}
+
+// SignWithPresignature signs msg, a hashed message, with a presignature made by
+// NewPresigning, in a single round exchanging the partial signatures. The parties of params
+// must be the committee of the presignature.
+//
+// markUsed is called with the ID of the presignature before anything derived from it is
+// computed, and must durably record that it is used, for instance by deleting its stored
+// copies: a presignature restored from storage and used again would reveal the key. If
+// markUsed fails, nothing is sent and the presignature can be used again. Otherwise the
+// presignature is consumed even if signing fails afterwards.
+//
+// Each partial signature sigma_j is checked against the points of the presignature, so that a
+// party sending an invalid one is reported as a culprit of round 2. Partial signatures made
+// with another presignature end the signing without culprits.
+func SignWithPresignature(ctx context.Context, key *ecdsatss.Key, presig *PreSignature, msg *big.Int, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	ec := params.EC()
+	q := ec.Params().N
+	if msg == nil || msg.Cmp(q) >= 0 {
+		return nil, errors.New("hashed message is not valid")
+	}
+	if markUsed == nil {
+		return nil, errors.New("a presignature can only be used with a markUsed callback")
+	}
+	ids := params.Parties().IDs()
+	if len(ids) != len(presig.Ks) || len(presig.BigR) != len(ids) || len(presig.BigS) != len(ids) {
+		return nil, fmt.Errorf("presignature was made by %d parties, not %d", len(presig.Ks), len(ids))
+	}
+	for j, id := range ids {
+		if id.KeyInt().Cmp(presig.Ks[j]) != 0 {
+			return nil, fmt.Errorf("party %s is not part of the presignature committee", id)
+		}
+	}
+	if presig.R == nil {
+		return nil, errors.New("presignature has no R")
+	}
+	k, chi, err := presig.take(markUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// sigma_i = k_i·m + r·chi_i
+	modQ := common.ModInt(q)
+	r := new(big.Int).Mod(presig.R.X(), q)
+	s := &Signing{
+		ctx:    ctx,
+		params: params,
+		key:    key,
+		presig: presig,
+		m:      msg,
+		r:      r,
+		sigma:  modQ.Add(modQ.Mul(k, msg), modQ.Mul(r, chi)),
+		Done:   make(chan *ecdsatss.SignatureData, 1),
+		Err:    make(chan error, 1),
+	}
+	s.broker = tss.NewSessionBroker(params.Broker())
+	s.stop = context.AfterFunc(ctx, func() { s.fail(ctx.Err()) })
+
+	Pi := params.PartyID()
+	otherIds := ids.Exclude(Pi)
+	r1msg := &signMsg{ID: presig.ID, Sigma: s.sigma.Bytes()}
+	for _, Pj := range otherIds {
+		s.broker.Receive(tss.JsonWrap(params.MsgType("cggmp:sign:round1"), r1msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[signMsg](params.MsgType("cggmp:sign:round1"), otherIds, s.round2, s.timeout(1), s.echo(1, "cggmp:sign:round1", otherIds))
+	s.broker.Connect(params.MsgType("cggmp:sign:round1"), rcv)
+	return s, nil
+}
+
+// round2 checks the partial signatures of the other parties and combines them.
+func (s *Signing) round2(otherIds []*tss.PartyID, msgs []*signMsg) {
+	if s.ctx.Err() != nil {
+		s.fail(s.ctx.Err())
+		return
+	}
+	ec := s.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// a partial signature sent with another ID was made with another presignature, which the
+	// sender may have been given, so it is not attributable to it
+	for _, msg := range msgs {
+		if !bytes.Equal(msg.ID, s.presig.ID) {
+			s.fail(s.wrapError(2, errors.New("partial signatures were made with different presignatures")))
+			return
+		}
+	}
+
+	// sigma_j·R = m·(k_j·R) + r·(chi_j·R)
+	var culprits []*tss.PartyID
+	sum := new(big.Int).Set(s.sigma)
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		sigma := new(big.Int).SetBytes(msgs[n].Sigma)
+		if sigma.Cmp(q) >= 0 {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		expected, err := addPoints(scalarMult(s.presig.BigR[j], s.m), scalarMult(s.presig.BigS[j], s.r))
+		if err != nil || !pointsEqual(scalarMult(s.presig.R, sigma), expected) {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		sum = modQ.Add(sum, sigma)
+	}
+	if len(culprits) > 0 {
+		s.fail(s.wrapError(2, errors.New("partial signature verification failed"), culprits...))
+		return
+	}
+
+	// recovery byte and low-S normalization, as done by ecdsatss
+	R := s.presig.R
+	recid := 0
+	if R.X().Cmp(q) >= 0 {
+		recid = 2
+	}
+	if R.Y().Bit(0) != 0 {
+		recid |= 1
+	}
+	if !s.params.KeepHighS() && sum.Cmp(new(big.Int).Rsh(q, 1)) > 0 {
+		sum.Sub(q, sum)
+		recid ^= 1
+	}
+
+	bitSizeInBytes := (ec.Params().BitSize + 7) / 8
+	rBytes := common.PadToLengthBytesInPlace(s.r.Bytes(), bitSizeInBytes)
+	sBytes := common.PadToLengthBytesInPlace(sum.Bytes(), bitSizeInBytes)
+	curve, _ := tss.GetCurveName(ec)
+	sigData := &ecdsatss.SignatureData{
+		R:         rBytes,
+		S:         sBytes,
+		Signature: append(append([]byte{}, rBytes...), sBytes...),
+		Recovery:  byte(recid),
+		M:         s.m.Bytes(),
+		Curve:     curve,
+	}
+
+	pk := ecdsa.PublicKey{Curve: ec, X: s.key.ECDSAPub.X(), Y: s.key.ECDSAPub.Y()}
+	if !ecdsa.Verify(&pk, sigData.M, s.r, sum) {
+		s.fail(s.wrapError(2, errors.New("signature verification failed")))
+		return
+	}
+
+	s.release()
+	s.Done <- sigData
+}
+
+// fail reports err on Err and releases the receivers registered by this signing.
+func (s *Signing) fail(err error) {
+	s.release()
+	select {
+	case s.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this signing from the broker.
+func (s *Signing) release() {
+	s.stop()
+	s.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (s *Signing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(s.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (s *Signing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !s.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(s.broker, s.params.MsgType(typ+":echo"), s.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (s *Signing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskSigning, round, s.params.PartyID(), culprits...)
+}
@@ -0,0 +1,256 @@
+// Package affgproof implements the Paillier affine operation with group commitment in range
+// proof of CGGMP21 (Fig. 15): a proof that D = C^x * Enc0(y; rho) was computed with a small x
+// committed to as X = g^x, and a small y encrypted under another key as Y = Enc1(y; rhoy).
+package affgproof
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+)
+
+const (
+	// ProofAffgBytesParts is the number of byte parts in a serialized ProofAffg.
+	ProofAffgBytesParts = 14
+)
+
+type (
+	// ProofAffg is a zero-knowledge proof that D = C^x * Enc0(y; rho), Y = Enc1(y; rhoy) and
+	// X = g^x, with x < 2^l and y < 2^l', where l is the bit length of the curve order and
+	// l' = 5l.
+	ProofAffg struct {
+		A                                     *big.Int
+		Bx                                    *crypto.ECPoint
+		By, E, S, F, T, Z1, Z2, Z3, Z4, W, Wy *big.Int
+	}
+)
+
+var one = big.NewInt(1)
+
+// NewProof implements proofaffg. C and D are ciphertexts under pk0, Y under pk1.
+func NewProof(Session []byte, ec elliptic.Curve, pk0, pk1 *paillier.PublicKey, NCap, s, t, C, D, Y *big.Int, X *crypto.ECPoint, x, y, rho, rhoy *big.Int, rand io.Reader) (*ProofAffg, error) {
+	if ec == nil || pk0 == nil || pk1 == nil || NCap == nil || s == nil || t == nil || C == nil || D == nil || Y == nil || X == nil ||
+		x == nil || y == nil || rho == nil || rhoy == nil {
+		return nil, errors.New("ProveAffg constructor received nil value(s)")
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	lPrime := 5 * l
+	epsilon := 2 * l
+	twoLEps := new(big.Int).Lsh(one, uint(l+epsilon))
+	twoLPrimeEps := new(big.Int).Lsh(one, uint(lPrime+epsilon))
+	twoLNCap := new(big.Int).Lsh(NCap, uint(l))
+	twoLEpsNCap := new(big.Int).Lsh(NCap, uint(l+epsilon))
+
+	// Fig 15.1 sample
+	alpha := common.GetRandomPositiveInt(rand, twoLEps)
+	beta := common.GetRandomPositiveInt(rand, twoLPrimeEps)
+	r := common.GetRandomPositiveRelativelyPrimeInt(rand, pk0.N)
+	ry := common.GetRandomPositiveRelativelyPrimeInt(rand, pk1.N)
+	gamma := common.GetRandomPositiveInt(rand, twoLEpsNCap)
+	m := common.GetRandomPositiveInt(rand, twoLNCap)
+	delta := common.GetRandomPositiveInt(rand, twoLEpsNCap)
+	mu := common.GetRandomPositiveInt(rand, twoLNCap)
+
+	// Fig 15.1 compute
+	modNCap := common.ModInt(NCap)
+	modN0Square := common.ModInt(pk0.NSquare())
+	modN1Square := common.ModInt(pk1.NSquare())
+	A := modN0Square.Mul(modN0Square.Exp(C, alpha), modN0Square.Exp(pk0.Gamma(), beta))
+	A = modN0Square.Mul(A, modN0Square.Exp(r, pk0.N))
+	Bx := crypto.ScalarBaseMult(ec, new(big.Int).Mod(alpha, q))
+	By := modN1Square.Mul(modN1Square.Exp(pk1.Gamma(), beta), modN1Square.Exp(ry, pk1.N))
+	E := modNCap.Mul(modNCap.Exp(s, alpha), modNCap.Exp(t, gamma))
+	S := modNCap.Mul(modNCap.Exp(s, x), modNCap.Exp(t, m))
+	F := modNCap.Mul(modNCap.Exp(s, beta), modNCap.Exp(t, delta))
+	T := modNCap.Mul(modNCap.Exp(s, y), modNCap.Exp(t, mu))
+
+	// Fig 15.2 e
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk0.N, pk1.N, NCap, s, t, C, D, Y, X.X(), X.Y(), A, Bx.X(), Bx.Y(), By, E, S, F, T)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 15.3
+	z1 := new(big.Int).Mul(e, x)
+	z1 = new(big.Int).Add(z1, alpha)
+
+	z2 := new(big.Int).Mul(e, y)
+	z2 = new(big.Int).Add(z2, beta)
+
+	z3 := new(big.Int).Mul(e, m)
+	z3 = new(big.Int).Add(z3, gamma)
+
+	z4 := new(big.Int).Mul(e, mu)
+	z4 = new(big.Int).Add(z4, delta)
+
+	w := common.ModInt(pk0.N).Exp(rho, e)
+	w = common.ModInt(pk0.N).Mul(w, r)
+
+	wy := common.ModInt(pk1.N).Exp(rhoy, e)
+	wy = common.ModInt(pk1.N).Mul(wy, ry)
+
+	return &ProofAffg{A: A, Bx: Bx, By: By, E: E, S: S, F: F, T: T, Z1: z1, Z2: z2, Z3: z3, Z4: z4, W: w, Wy: wy}, nil
+}
+
+// NewProofFromBytes reconstructs a ProofAffg from a slice of byte slices.
+func NewProofFromBytes(ec elliptic.Curve, bzs [][]byte) (*ProofAffg, error) {
+	if !common.NonEmptyMultiBytes(bzs, ProofAffgBytesParts) {
+		return nil, fmt.Errorf("expected %d byte parts to construct ProofAffg", ProofAffgBytesParts)
+	}
+	Bx, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(bzs[1]), new(big.Int).SetBytes(bzs[2]))
+	if err != nil {
+		return nil, err
+	}
+	return &ProofAffg{
+		A:  new(big.Int).SetBytes(bzs[0]),
+		Bx: Bx,
+		By: new(big.Int).SetBytes(bzs[3]),
+		E:  new(big.Int).SetBytes(bzs[4]),
+		S:  new(big.Int).SetBytes(bzs[5]),
+		F:  new(big.Int).SetBytes(bzs[6]),
+		T:  new(big.Int).SetBytes(bzs[7]),
+		Z1: new(big.Int).SetBytes(bzs[8]),
+		Z2: new(big.Int).SetBytes(bzs[9]),
+		Z3: new(big.Int).SetBytes(bzs[10]),
+		Z4: new(big.Int).SetBytes(bzs[11]),
+		W:  new(big.Int).SetBytes(bzs[12]),
+		Wy: new(big.Int).SetBytes(bzs[13]),
+	}, nil
+}
+
+// Verify checks whether the affine operation proof is valid for the given statement.
+func (pf *ProofAffg) Verify(Session []byte, ec elliptic.Curve, pk0, pk1 *paillier.PublicKey, NCap, s, t, C, D, Y *big.Int, X *crypto.ECPoint) bool {
+	if pf == nil || !pf.ValidateBasic() || ec == nil || pk0 == nil || pk1 == nil || NCap == nil || s == nil || t == nil ||
+		C == nil || D == nil || Y == nil || X == nil || !X.ValidateBasic() {
+		return false
+	}
+	if pk0.N.Sign() != 1 || pk1.N.Sign() != 1 || NCap.Sign() != 1 {
+		return false
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	lPrime := 5 * l
+	epsilon := 2 * l
+
+	// Fig 15. Range Check
+	if !common.IsInInterval(pf.Z1, new(big.Int).Lsh(one, uint(l+epsilon+1))) {
+		return false
+	}
+	if !common.IsInInterval(pf.Z2, new(big.Int).Lsh(one, uint(lPrime+epsilon+1))) {
+		return false
+	}
+	if !common.IsNumberInMultiplicativeGroup(pk0.N, pf.W) || !common.IsNumberInMultiplicativeGroup(pk1.N, pf.Wy) {
+		return false
+	}
+
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk0.N, pk1.N, NCap, s, t, C, D, Y, X.X(), X.Y(), pf.A, pf.Bx.X(), pf.Bx.Y(), pf.By, pf.E, pf.S, pf.F, pf.T)
+		e = common.RejectionSample(q, eHash)
+	}
+	if e.Sign() == 0 {
+		return false
+	}
+
+	// Fig 15. Equality Check
+	{
+		modN0Square := common.ModInt(pk0.NSquare())
+		LHS := modN0Square.Mul(modN0Square.Exp(C, pf.Z1), modN0Square.Exp(pk0.Gamma(), pf.Z2))
+		LHS = modN0Square.Mul(LHS, modN0Square.Exp(pf.W, pk0.N))
+		RHS := modN0Square.Mul(pf.A, modN0Square.Exp(D, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		z1ModQ := new(big.Int).Mod(pf.Z1, q)
+		if z1ModQ.Sign() == 0 {
+			return false
+		}
+		LHS := crypto.ScalarBaseMult(ec, z1ModQ)
+		RHS, err := pf.Bx.Add(X.ScalarMult(e))
+		if err != nil || !LHS.Equals(RHS) {
+			return false
+		}
+	}
+
+	{
+		modN1Square := common.ModInt(pk1.NSquare())
+		LHS := modN1Square.Mul(modN1Square.Exp(pk1.Gamma(), pf.Z2), modN1Square.Exp(pf.Wy, pk1.N))
+		RHS := modN1Square.Mul(pf.By, modN1Square.Exp(Y, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	modNCap := common.ModInt(NCap)
+	{
+		LHS := modNCap.Mul(modNCap.Exp(s, pf.Z1), modNCap.Exp(t, pf.Z3))
+		RHS := modNCap.Mul(pf.E, modNCap.Exp(pf.S, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		LHS := modNCap.Mul(modNCap.Exp(s, pf.Z2), modNCap.Exp(t, pf.Z4))
+		RHS := modNCap.Mul(pf.F, modNCap.Exp(pf.T, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateBasic checks that all fields of the proof are non-nil.
+func (pf *ProofAffg) ValidateBasic() bool {
+	return pf.A != nil &&
+		pf.Bx != nil &&
+		pf.By != nil &&
+		pf.E != nil &&
+		pf.S != nil &&
+		pf.F != nil &&
+		pf.T != nil &&
+		pf.Z1 != nil &&
+		pf.Z2 != nil &&
+		pf.Z3 != nil &&
+		pf.Z4 != nil &&
+		pf.W != nil &&
+		pf.Wy != nil
+}
+
+// Bytes serializes the proof into a fixed-size array of byte slices.
+func (pf *ProofAffg) Bytes() [ProofAffgBytesParts][]byte {
+	return [...][]byte{
+		pf.A.Bytes(),
+		pf.Bx.X().Bytes(),
+		pf.Bx.Y().Bytes(),
+		pf.By.Bytes(),
+		pf.E.Bytes(),
+		pf.S.Bytes(),
+		pf.F.Bytes(),
+		pf.T.Bytes(),
+		pf.Z1.Bytes(),
+		pf.Z2.Bytes(),
+		pf.Z3.Bytes(),
+		pf.Z4.Bytes(),
+		pf.W.Bytes(),
+		pf.Wy.Bytes(),
+	}
+}
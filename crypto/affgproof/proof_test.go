@@ -0,0 +1,59 @@
+package affgproof_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/affgproof"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsa/keygen"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var Session = []byte("session")
+
+func TestAffg(test *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	keys, _, err := keygen.LoadKeygenTestFixtures(3)
+	require.NoError(test, err)
+	sk0 := keys[0].PaillierSK
+	pk0, pk1 := &sk0.PublicKey, &keys[1].PaillierSK.PublicKey
+	NCap, s, t := keys[2].NTildei, keys[2].H1i, keys[2].H2i
+
+	// D = C^x * Enc0(y), Y = Enc1(y), X = g^x
+	c := common.GetRandomPositiveInt(rand.Reader, q)
+	C, err := pk0.Encrypt(rand.Reader, c)
+	require.NoError(test, err)
+	x := common.GetRandomPositiveInt(rand.Reader, q)
+	y := common.GetRandomPositiveInt(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(5*q.BitLen())))
+	X := crypto.ScalarBaseMult(ec, x)
+	encY, rho, err := pk0.EncryptAndReturnRandomness(rand.Reader, y)
+	require.NoError(test, err)
+	D, err := pk0.HomoMult(x, C)
+	require.NoError(test, err)
+	D, err = pk0.HomoAdd(D, encY)
+	require.NoError(test, err)
+	Y, rhoy, err := pk1.EncryptAndReturnRandomness(rand.Reader, y)
+	require.NoError(test, err)
+
+	plain, err := sk0.Decrypt(D)
+	require.NoError(test, err)
+	assert.Equal(test, 0, plain.Cmp(new(big.Int).Add(new(big.Int).Mul(x, c), y)))
+
+	proof, err := NewProof(Session, ec, pk0, pk1, NCap, s, t, C, D, Y, X, x, y, rho, rhoy, rand.Reader)
+	require.NoError(test, err)
+
+	proofBzs := proof.Bytes()
+	proof, err = NewProofFromBytes(ec, proofBzs[:])
+	require.NoError(test, err)
+	assert.True(test, proof.Verify(Session, ec, pk0, pk1, NCap, s, t, C, D, Y, X), "proof must verify")
+
+	assert.False(test, proof.Verify(Session, ec, pk0, pk1, NCap, s, t, C, D, Y, X.ScalarMult(x)))
+	assert.False(test, proof.Verify(Session, ec, pk0, pk1, NCap, s, t, C, encY, Y, X))
+}
@@ -123,7 +123,10 @@ func NewExtendedKeyFromString(key string, curve elliptic.Curve) (*ExtendedKey, e
 			Y:     pk.Y(),
 		}
 	} else {
-		px, py := elliptic.Unmarshal(curve, keyData)
+		px, py := elliptic.UnmarshalCompressed(curve, keyData)
+		if px == nil {
+			return nil, errors.New("invalid extended key: bad public key")
+		}
 		pubKey = ecdsa.PublicKey{
 			Curve: curve,
 			X:     px,
@@ -7,6 +7,9 @@
 package ckd_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"testing"
 
 	"github.com/KarpelesLab/secp256k1"
@@ -130,3 +133,27 @@ tests:
 		}
 	}
 }
+
+func TestExtendedKeyP256(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extKey := &ExtendedKey{
+		PublicKey: priv.PublicKey,
+		ChainCode: make([]byte, 32),
+		ParentFP:  make([]byte, 4),
+	}
+	_, child, err := DeriveChildKey(1, extKey, elliptic.P256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := NewExtendedKeyFromString(child.String(), elliptic.P256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.X.Cmp(child.X) != 0 || parsed.Y.Cmp(child.Y) != 0 {
+		t.Errorf("parsed public key does not match the serialized one")
+	}
+}
@@ -0,0 +1,168 @@
+// Package decproof implements the Paillier decryption modulo q proof of CGGMP21 (Fig. 30): a
+// proof that the plaintext y of a ciphertext C is equal to x modulo the curve order, made
+// with the ring-Pedersen parameters (NCap, s, t) of the verifier.
+package decproof
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+)
+
+const (
+	// ProofDecBytesParts is the number of byte parts in a serialized ProofDec.
+	ProofDecBytesParts = 7
+)
+
+type (
+	// ProofDec is a zero-knowledge proof that C = Enc(y; rho) with y = x mod q.
+	ProofDec struct {
+		S, T, A, Gamma, Z1, Z2, W *big.Int
+	}
+)
+
+// NewProof implements proofdec. y is the plaintext of C, in [0, N0).
+func NewProof(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, C, x, NCap, s, t, y, rho *big.Int, rand io.Reader) (*ProofDec, error) {
+	if ec == nil || pk == nil || C == nil || x == nil || NCap == nil || s == nil || t == nil || y == nil || rho == nil {
+		return nil, errors.New("ProveDec constructor received nil value(s)")
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	epsilon := 2 * l
+	// y may be as large as N0, so alpha is sampled to hide e*y
+	twoLEpsN0 := new(big.Int).Lsh(pk.N, uint(l+epsilon))
+	twoLNCap := new(big.Int).Lsh(NCap, uint(l))
+	twoLEpsNCap := new(big.Int).Lsh(NCap, uint(l+epsilon))
+
+	// Fig 30.1 sample
+	alpha := common.GetRandomPositiveInt(rand, twoLEpsN0)
+	mu := common.GetRandomPositiveInt(rand, twoLNCap)
+	nu := common.GetRandomPositiveInt(rand, twoLEpsNCap)
+	r := common.GetRandomPositiveRelativelyPrimeInt(rand, pk.N)
+
+	// Fig 30.1 compute
+	modNCap := common.ModInt(NCap)
+	modNSquare := common.ModInt(pk.NSquare())
+	S := modNCap.Mul(modNCap.Exp(s, y), modNCap.Exp(t, mu))
+	T := modNCap.Mul(modNCap.Exp(s, alpha), modNCap.Exp(t, nu))
+	A := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), alpha), modNSquare.Exp(r, pk.N))
+	gamma := new(big.Int).Mod(alpha, q)
+
+	// Fig 30.2 e
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, C, x, NCap, s, t, S, T, A, gamma)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 30.3
+	z1 := new(big.Int).Mul(e, y)
+	z1 = new(big.Int).Add(z1, alpha)
+
+	z2 := new(big.Int).Mul(e, mu)
+	z2 = new(big.Int).Add(z2, nu)
+
+	w := common.ModInt(pk.N).Exp(rho, e)
+	w = common.ModInt(pk.N).Mul(w, r)
+
+	return &ProofDec{S: S, T: T, A: A, Gamma: gamma, Z1: z1, Z2: z2, W: w}, nil
+}
+
+// NewProofFromBytes reconstructs a ProofDec from a slice of byte slices.
+func NewProofFromBytes(bzs [][]byte) (*ProofDec, error) {
+	if !common.NonEmptyMultiBytes(bzs, ProofDecBytesParts) {
+		return nil, fmt.Errorf("expected %d byte parts to construct ProofDec", ProofDecBytesParts)
+	}
+	return &ProofDec{
+		S:     new(big.Int).SetBytes(bzs[0]),
+		T:     new(big.Int).SetBytes(bzs[1]),
+		A:     new(big.Int).SetBytes(bzs[2]),
+		Gamma: new(big.Int).SetBytes(bzs[3]),
+		Z1:    new(big.Int).SetBytes(bzs[4]),
+		Z2:    new(big.Int).SetBytes(bzs[5]),
+		W:     new(big.Int).SetBytes(bzs[6]),
+	}, nil
+}
+
+// Verify checks whether the decryption proof is valid for the ciphertext C and x.
+func (pf *ProofDec) Verify(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, C, x, NCap, s, t *big.Int) bool {
+	if pf == nil || !pf.ValidateBasic() || ec == nil || pk == nil || C == nil || x == nil || NCap == nil || s == nil || t == nil {
+		return false
+	}
+	if pk.N.Sign() != 1 || NCap.Sign() != 1 {
+		return false
+	}
+	if !common.IsNumberInMultiplicativeGroup(pk.N, pf.W) {
+		return false
+	}
+
+	q := ec.Params().N
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, C, x, NCap, s, t, pf.S, pf.T, pf.A, pf.Gamma)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 30. Equality Check
+	{
+		modNSquare := common.ModInt(pk.NSquare())
+		LHS := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), pf.Z1), modNSquare.Exp(pf.W, pk.N))
+		RHS := modNSquare.Mul(pf.A, modNSquare.Exp(C, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		modQ := common.ModInt(q)
+		LHS := new(big.Int).Mod(pf.Z1, q)
+		RHS := modQ.Add(pf.Gamma, modQ.Mul(e, x))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		modNCap := common.ModInt(NCap)
+		LHS := modNCap.Mul(modNCap.Exp(s, pf.Z1), modNCap.Exp(t, pf.Z2))
+		RHS := modNCap.Mul(pf.T, modNCap.Exp(pf.S, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateBasic checks that all fields of the proof are non-nil.
+func (pf *ProofDec) ValidateBasic() bool {
+	return pf.S != nil &&
+		pf.T != nil &&
+		pf.A != nil &&
+		pf.Gamma != nil &&
+		pf.Z1 != nil &&
+		pf.Z2 != nil &&
+		pf.W != nil
+}
+
+// Bytes serializes the proof into a fixed-size array of byte slices.
+func (pf *ProofDec) Bytes() [ProofDecBytesParts][]byte {
+	return [...][]byte{
+		pf.S.Bytes(),
+		pf.T.Bytes(),
+		pf.A.Bytes(),
+		pf.Gamma.Bytes(),
+		pf.Z1.Bytes(),
+		pf.Z2.Bytes(),
+		pf.W.Bytes(),
+	}
+}
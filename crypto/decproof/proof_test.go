@@ -0,0 +1,42 @@
+package decproof_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/decproof"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsa/keygen"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var Session = []byte("session")
+
+func TestDec(test *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	keys, _, err := keygen.LoadKeygenTestFixtures(2)
+	require.NoError(test, err)
+	pk := &keys[0].PaillierSK.PublicKey
+	NCap, s, t := keys[1].NTildei, keys[1].H1i, keys[1].H2i
+
+	// the plaintext may be much larger than q
+	y := common.GetRandomPositiveInt(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 1500))
+	x := new(big.Int).Mod(y, q)
+	C, rho, err := pk.EncryptAndReturnRandomness(rand.Reader, y)
+	require.NoError(test, err)
+
+	proof, err := NewProof(Session, ec, pk, C, x, NCap, s, t, y, rho, rand.Reader)
+	require.NoError(test, err)
+
+	proofBzs := proof.Bytes()
+	proof, err = NewProofFromBytes(proofBzs[:])
+	require.NoError(test, err)
+	assert.True(test, proof.Verify(Session, ec, pk, C, x, NCap, s, t), "proof must verify")
+
+	assert.False(test, proof.Verify(Session, ec, pk, C, new(big.Int).Add(x, big.NewInt(1)), NCap, s, t))
+}
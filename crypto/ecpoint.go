@@ -259,7 +259,10 @@ func (p *ECPoint) GobDecode(buf []byte) error {
 	if err := Y.GobDecode(y); err != nil {
 		return err
 	}
-	p.curve = tss.EC()
+	if p.curve == nil {
+		// gob does not carry the curve: keep the one of the point decoded into, if any
+		p.curve = tss.EC()
+	}
 	p.coords = [2]*big.Int{X, Y}
 	if !p.IsOnCurve() {
 		return errors.New("ECPoint.UnmarshalJSON: the point is not on the elliptic curve")
@@ -302,8 +305,8 @@ func (p *ECPoint) UnmarshalJSON(payload []byte) error {
 			return fmt.Errorf("cannot find curve named with %s in curve registry, please call tss.RegisterCurve(name, curve) to register it first", aux.Curve)
 		}
 		p.curve = ec
-	} else {
-		// forward compatible, use global ec as default value
+	} else if p.curve == nil {
+		// forward compatible, use the curve of the point decoded into or global ec as default value
 		p.curve = tss.EC()
 	}
 
@@ -0,0 +1,164 @@
+// Package encproof implements the Paillier encryption in range proof of CGGMP21 (Fig. 14):
+// a proof that the plaintext k of a ciphertext K is small, made with the ring-Pedersen
+// parameters (NCap, s, t) of the verifier.
+package encproof
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+)
+
+const (
+	// ProofEncBytesParts is the number of byte parts in a serialized ProofEnc.
+	ProofEncBytesParts = 6
+)
+
+type (
+	// ProofEnc is a zero-knowledge proof that K = Enc(k; rho) with k < 2^l, where l is the
+	// bit length of the curve order.
+	ProofEnc struct {
+		S, A, C, Z1, Z2, Z3 *big.Int
+	}
+)
+
+var one = big.NewInt(1)
+
+// NewProof implements proofenc
+func NewProof(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, K, NCap, s, t, k, rho *big.Int, rand io.Reader) (*ProofEnc, error) {
+	if ec == nil || pk == nil || K == nil || NCap == nil || s == nil || t == nil || k == nil || rho == nil {
+		return nil, errors.New("ProveEnc constructor received nil value(s)")
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	epsilon := 2 * l
+	twoLEps := new(big.Int).Lsh(one, uint(l+epsilon))
+	twoLNCap := new(big.Int).Lsh(NCap, uint(l))
+	twoLEpsNCap := new(big.Int).Lsh(NCap, uint(l+epsilon))
+
+	// Fig 14.1 sample
+	alpha := common.GetRandomPositiveInt(rand, twoLEps)
+	mu := common.GetRandomPositiveInt(rand, twoLNCap)
+	r := common.GetRandomPositiveRelativelyPrimeInt(rand, pk.N)
+	gamma := common.GetRandomPositiveInt(rand, twoLEpsNCap)
+
+	// Fig 14.1 compute
+	modNCap := common.ModInt(NCap)
+	modNSquare := common.ModInt(pk.NSquare())
+	S := modNCap.Mul(modNCap.Exp(s, k), modNCap.Exp(t, mu))
+	A := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), alpha), modNSquare.Exp(r, pk.N))
+	C := modNCap.Mul(modNCap.Exp(s, alpha), modNCap.Exp(t, gamma))
+
+	// Fig 14.2 e
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, K, NCap, s, t, S, A, C)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 14.3
+	z1 := new(big.Int).Mul(e, k)
+	z1 = new(big.Int).Add(z1, alpha)
+
+	z2 := common.ModInt(pk.N).Exp(rho, e)
+	z2 = common.ModInt(pk.N).Mul(z2, r)
+
+	z3 := new(big.Int).Mul(e, mu)
+	z3 = new(big.Int).Add(z3, gamma)
+
+	return &ProofEnc{S: S, A: A, C: C, Z1: z1, Z2: z2, Z3: z3}, nil
+}
+
+// NewProofFromBytes reconstructs a ProofEnc from a slice of byte slices.
+func NewProofFromBytes(bzs [][]byte) (*ProofEnc, error) {
+	if !common.NonEmptyMultiBytes(bzs, ProofEncBytesParts) {
+		return nil, fmt.Errorf("expected %d byte parts to construct ProofEnc", ProofEncBytesParts)
+	}
+	return &ProofEnc{
+		S:  new(big.Int).SetBytes(bzs[0]),
+		A:  new(big.Int).SetBytes(bzs[1]),
+		C:  new(big.Int).SetBytes(bzs[2]),
+		Z1: new(big.Int).SetBytes(bzs[3]),
+		Z2: new(big.Int).SetBytes(bzs[4]),
+		Z3: new(big.Int).SetBytes(bzs[5]),
+	}, nil
+}
+
+// Verify checks whether the encryption proof is valid for the ciphertext K.
+func (pf *ProofEnc) Verify(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, K, NCap, s, t *big.Int) bool {
+	if pf == nil || !pf.ValidateBasic() || ec == nil || pk == nil || K == nil || NCap == nil || s == nil || t == nil {
+		return false
+	}
+	if pk.N.Sign() != 1 || NCap.Sign() != 1 {
+		return false
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	epsilon := 2 * l
+
+	// Fig 14. Range Check
+	if !common.IsInInterval(pf.Z1, new(big.Int).Lsh(one, uint(l+epsilon+1))) {
+		return false
+	}
+	if !common.IsNumberInMultiplicativeGroup(pk.N, pf.Z2) {
+		return false
+	}
+
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, K, NCap, s, t, pf.S, pf.A, pf.C)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 14. Equality Check
+	{
+		modNSquare := common.ModInt(pk.NSquare())
+		LHS := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), pf.Z1), modNSquare.Exp(pf.Z2, pk.N))
+		RHS := modNSquare.Mul(pf.A, modNSquare.Exp(K, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		modNCap := common.ModInt(NCap)
+		LHS := modNCap.Mul(modNCap.Exp(s, pf.Z1), modNCap.Exp(t, pf.Z3))
+		RHS := modNCap.Mul(pf.C, modNCap.Exp(pf.S, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateBasic checks that all fields of the proof are non-nil.
+func (pf *ProofEnc) ValidateBasic() bool {
+	return pf.S != nil &&
+		pf.A != nil &&
+		pf.C != nil &&
+		pf.Z1 != nil &&
+		pf.Z2 != nil &&
+		pf.Z3 != nil
+}
+
+// Bytes serializes the proof into a fixed-size array of byte slices.
+func (pf *ProofEnc) Bytes() [ProofEncBytesParts][]byte {
+	return [...][]byte{
+		pf.S.Bytes(),
+		pf.A.Bytes(),
+		pf.C.Bytes(),
+		pf.Z1.Bytes(),
+		pf.Z2.Bytes(),
+		pf.Z3.Bytes(),
+	}
+}
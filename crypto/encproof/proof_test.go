@@ -0,0 +1,50 @@
+package encproof_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/encproof"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsa/keygen"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var Session = []byte("session")
+
+func TestEnc(test *testing.T) {
+	ec := tss.EC()
+	keys, _, err := keygen.LoadKeygenTestFixtures(2)
+	require.NoError(test, err)
+	pk := &keys[0].PaillierSK.PublicKey
+	NCap, s, t := keys[1].NTildei, keys[1].H1i, keys[1].H2i
+
+	k := common.GetRandomPositiveInt(rand.Reader, ec.Params().N)
+	K, rho, err := pk.EncryptAndReturnRandomness(rand.Reader, k)
+	require.NoError(test, err)
+
+	proof, err := NewProof(Session, ec, pk, K, NCap, s, t, k, rho, rand.Reader)
+	require.NoError(test, err)
+
+	proofBzs := proof.Bytes()
+	proof, err = NewProofFromBytes(proofBzs[:])
+	require.NoError(test, err)
+	assert.True(test, proof.Verify(Session, ec, pk, K, NCap, s, t), "proof must verify")
+
+	assert.False(test, proof.Verify([]byte("other session"), ec, pk, K, NCap, s, t))
+	K2, err := pk.Encrypt(rand.Reader, k)
+	require.NoError(test, err)
+	assert.False(test, proof.Verify(Session, ec, pk, K2, NCap, s, t))
+
+	// a plaintext out of range cannot be proven
+	large := new(big.Int).Lsh(big.NewInt(1), 1024)
+	K, rho, err = pk.EncryptAndReturnRandomness(rand.Reader, large)
+	require.NoError(test, err)
+	proof, err = NewProof(Session, ec, pk, K, NCap, s, t, large, rho, rand.Reader)
+	require.NoError(test, err)
+	assert.False(test, proof.Verify(Session, ec, pk, K, NCap, s, t))
+}
@@ -0,0 +1,193 @@
+// Package logstarproof implements the knowledge of exponent vs Paillier encryption proof of
+// CGGMP21 (Fig. 25): a proof that the plaintext x of a ciphertext C is small and is the
+// discrete logarithm of X in base B.
+package logstarproof
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+)
+
+const (
+	// ProofLogstarBytesParts is the number of byte parts in a serialized ProofLogstar.
+	ProofLogstarBytesParts = 8
+)
+
+type (
+	// ProofLogstar is a zero-knowledge proof that C = Enc(x; rho) and X = B^x, with x < 2^l,
+	// where l is the bit length of the curve order.
+	ProofLogstar struct {
+		S, A       *big.Int
+		Y          *crypto.ECPoint
+		D          *big.Int
+		Z1, Z2, Z3 *big.Int
+	}
+)
+
+var one = big.NewInt(1)
+
+// NewProof implements prooflogstar
+func NewProof(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, C *big.Int, X, B *crypto.ECPoint, NCap, s, t, x, rho *big.Int, rand io.Reader) (*ProofLogstar, error) {
+	if ec == nil || pk == nil || C == nil || X == nil || B == nil || NCap == nil || s == nil || t == nil || x == nil || rho == nil {
+		return nil, errors.New("ProveLogstar constructor received nil value(s)")
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	epsilon := 2 * l
+	twoLEps := new(big.Int).Lsh(one, uint(l+epsilon))
+	twoLNCap := new(big.Int).Lsh(NCap, uint(l))
+	twoLEpsNCap := new(big.Int).Lsh(NCap, uint(l+epsilon))
+
+	// Fig 25.1 sample
+	alpha := common.GetRandomPositiveInt(rand, twoLEps)
+	mu := common.GetRandomPositiveInt(rand, twoLNCap)
+	r := common.GetRandomPositiveRelativelyPrimeInt(rand, pk.N)
+	gamma := common.GetRandomPositiveInt(rand, twoLEpsNCap)
+
+	// Fig 25.1 compute
+	modNCap := common.ModInt(NCap)
+	modNSquare := common.ModInt(pk.NSquare())
+	S := modNCap.Mul(modNCap.Exp(s, x), modNCap.Exp(t, mu))
+	A := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), alpha), modNSquare.Exp(r, pk.N))
+	Y := B.ScalarMult(new(big.Int).Mod(alpha, q))
+	D := modNCap.Mul(modNCap.Exp(s, alpha), modNCap.Exp(t, gamma))
+
+	// Fig 25.2 e
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, C, X.X(), X.Y(), B.X(), B.Y(), NCap, s, t, S, A, Y.X(), Y.Y(), D)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 25.3
+	z1 := new(big.Int).Mul(e, x)
+	z1 = new(big.Int).Add(z1, alpha)
+
+	z2 := common.ModInt(pk.N).Exp(rho, e)
+	z2 = common.ModInt(pk.N).Mul(z2, r)
+
+	z3 := new(big.Int).Mul(e, mu)
+	z3 = new(big.Int).Add(z3, gamma)
+
+	return &ProofLogstar{S: S, A: A, Y: Y, D: D, Z1: z1, Z2: z2, Z3: z3}, nil
+}
+
+// NewProofFromBytes reconstructs a ProofLogstar from a slice of byte slices.
+func NewProofFromBytes(ec elliptic.Curve, bzs [][]byte) (*ProofLogstar, error) {
+	if !common.NonEmptyMultiBytes(bzs, ProofLogstarBytesParts) {
+		return nil, fmt.Errorf("expected %d byte parts to construct ProofLogstar", ProofLogstarBytesParts)
+	}
+	Y, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(bzs[2]), new(big.Int).SetBytes(bzs[3]))
+	if err != nil {
+		return nil, err
+	}
+	return &ProofLogstar{
+		S:  new(big.Int).SetBytes(bzs[0]),
+		A:  new(big.Int).SetBytes(bzs[1]),
+		Y:  Y,
+		D:  new(big.Int).SetBytes(bzs[4]),
+		Z1: new(big.Int).SetBytes(bzs[5]),
+		Z2: new(big.Int).SetBytes(bzs[6]),
+		Z3: new(big.Int).SetBytes(bzs[7]),
+	}, nil
+}
+
+// Verify checks whether the proof is valid for the ciphertext C and X = B^x.
+func (pf *ProofLogstar) Verify(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, C *big.Int, X, B *crypto.ECPoint, NCap, s, t *big.Int) bool {
+	if pf == nil || !pf.ValidateBasic() || ec == nil || pk == nil || C == nil || X == nil || !X.ValidateBasic() ||
+		B == nil || !B.ValidateBasic() || NCap == nil || s == nil || t == nil {
+		return false
+	}
+	if pk.N.Sign() != 1 || NCap.Sign() != 1 {
+		return false
+	}
+
+	q := ec.Params().N
+	l := q.BitLen()
+	epsilon := 2 * l
+
+	// Fig 25. Range Check
+	if !common.IsInInterval(pf.Z1, new(big.Int).Lsh(one, uint(l+epsilon+1))) {
+		return false
+	}
+	if !common.IsNumberInMultiplicativeGroup(pk.N, pf.Z2) {
+		return false
+	}
+
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, C, X.X(), X.Y(), B.X(), B.Y(), NCap, s, t, pf.S, pf.A, pf.Y.X(), pf.Y.Y(), pf.D)
+		e = common.RejectionSample(q, eHash)
+	}
+	if e.Sign() == 0 {
+		return false
+	}
+
+	// Fig 25. Equality Check
+	{
+		modNSquare := common.ModInt(pk.NSquare())
+		LHS := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), pf.Z1), modNSquare.Exp(pf.Z2, pk.N))
+		RHS := modNSquare.Mul(pf.A, modNSquare.Exp(C, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		z1ModQ := new(big.Int).Mod(pf.Z1, q)
+		if z1ModQ.Sign() == 0 {
+			return false
+		}
+		LHS := B.ScalarMult(z1ModQ)
+		RHS, err := pf.Y.Add(X.ScalarMult(e))
+		if err != nil || !LHS.Equals(RHS) {
+			return false
+		}
+	}
+
+	{
+		modNCap := common.ModInt(NCap)
+		LHS := modNCap.Mul(modNCap.Exp(s, pf.Z1), modNCap.Exp(t, pf.Z3))
+		RHS := modNCap.Mul(pf.D, modNCap.Exp(pf.S, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateBasic checks that all fields of the proof are non-nil.
+func (pf *ProofLogstar) ValidateBasic() bool {
+	return pf.S != nil &&
+		pf.A != nil &&
+		pf.Y != nil &&
+		pf.D != nil &&
+		pf.Z1 != nil &&
+		pf.Z2 != nil &&
+		pf.Z3 != nil
+}
+
+// Bytes serializes the proof into a fixed-size array of byte slices.
+func (pf *ProofLogstar) Bytes() [ProofLogstarBytesParts][]byte {
+	return [...][]byte{
+		pf.S.Bytes(),
+		pf.A.Bytes(),
+		pf.Y.X().Bytes(),
+		pf.Y.Y().Bytes(),
+		pf.D.Bytes(),
+		pf.Z1.Bytes(),
+		pf.Z2.Bytes(),
+		pf.Z3.Bytes(),
+	}
+}
@@ -0,0 +1,43 @@
+package logstarproof_test
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/logstarproof"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsa/keygen"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var Session = []byte("session")
+
+func TestLogstar(test *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	keys, _, err := keygen.LoadKeygenTestFixtures(2)
+	require.NoError(test, err)
+	pk := &keys[0].PaillierSK.PublicKey
+	NCap, s, t := keys[1].NTildei, keys[1].H1i, keys[1].H2i
+
+	B := crypto.ScalarBaseMult(ec, common.GetRandomPositiveInt(rand.Reader, q))
+	x := common.GetRandomPositiveInt(rand.Reader, q)
+	X := B.ScalarMult(x)
+	C, rho, err := pk.EncryptAndReturnRandomness(rand.Reader, x)
+	require.NoError(test, err)
+
+	proof, err := NewProof(Session, ec, pk, C, X, B, NCap, s, t, x, rho, rand.Reader)
+	require.NoError(test, err)
+
+	proofBzs := proof.Bytes()
+	proof, err = NewProofFromBytes(ec, proofBzs[:])
+	require.NoError(test, err)
+	assert.True(test, proof.Verify(Session, ec, pk, C, X, B, NCap, s, t), "proof must verify")
+
+	assert.False(test, proof.Verify(Session, ec, pk, C, X, crypto.ScalarBaseMult(ec, x), NCap, s, t))
+	assert.False(test, proof.Verify(Session, ec, pk, C, X.ScalarMult(x), B, NCap, s, t))
+}
@@ -0,0 +1,139 @@
+// Package mulproof implements the Paillier multiplication proof of CGGMP21 (Fig. 29): a proof
+// that C = Y^x * rho^N, where x is the plaintext of X = Enc(x; rhox).
+package mulproof
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+)
+
+const (
+	// ProofMulBytesParts is the number of byte parts in a serialized ProofMul.
+	ProofMulBytesParts = 5
+)
+
+type (
+	// ProofMul is a zero-knowledge proof that C encrypts the product of the plaintexts of X
+	// and Y.
+	ProofMul struct {
+		A, B, Z, U, V *big.Int
+	}
+)
+
+// NewProof implements proofmul
+func NewProof(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, X, Y, C, x, rho, rhox *big.Int, rand io.Reader) (*ProofMul, error) {
+	if ec == nil || pk == nil || X == nil || Y == nil || C == nil || x == nil || rho == nil || rhox == nil {
+		return nil, errors.New("ProveMul constructor received nil value(s)")
+	}
+
+	q := ec.Params().N
+
+	// Fig 29.1 sample
+	alpha := common.GetRandomPositiveInt(rand, pk.N)
+	r := common.GetRandomPositiveRelativelyPrimeInt(rand, pk.N)
+	s := common.GetRandomPositiveRelativelyPrimeInt(rand, pk.N)
+
+	// Fig 29.1 compute
+	modNSquare := common.ModInt(pk.NSquare())
+	A := modNSquare.Mul(modNSquare.Exp(Y, alpha), modNSquare.Exp(r, pk.N))
+	B := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), alpha), modNSquare.Exp(s, pk.N))
+
+	// Fig 29.2 e
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, X, Y, C, A, B)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 29.3
+	z := new(big.Int).Mul(e, x)
+	z = new(big.Int).Add(z, alpha)
+
+	modN := common.ModInt(pk.N)
+	u := modN.Mul(modN.Exp(rho, e), r)
+	v := modN.Mul(modN.Exp(rhox, e), s)
+
+	return &ProofMul{A: A, B: B, Z: z, U: u, V: v}, nil
+}
+
+// NewProofFromBytes reconstructs a ProofMul from a slice of byte slices.
+func NewProofFromBytes(bzs [][]byte) (*ProofMul, error) {
+	if !common.NonEmptyMultiBytes(bzs, ProofMulBytesParts) {
+		return nil, fmt.Errorf("expected %d byte parts to construct ProofMul", ProofMulBytesParts)
+	}
+	return &ProofMul{
+		A: new(big.Int).SetBytes(bzs[0]),
+		B: new(big.Int).SetBytes(bzs[1]),
+		Z: new(big.Int).SetBytes(bzs[2]),
+		U: new(big.Int).SetBytes(bzs[3]),
+		V: new(big.Int).SetBytes(bzs[4]),
+	}, nil
+}
+
+// Verify checks whether the multiplication proof is valid for the ciphertexts X, Y and C.
+func (pf *ProofMul) Verify(Session []byte, ec elliptic.Curve, pk *paillier.PublicKey, X, Y, C *big.Int) bool {
+	if pf == nil || !pf.ValidateBasic() || ec == nil || pk == nil || X == nil || Y == nil || C == nil {
+		return false
+	}
+	if pk.N.Sign() != 1 {
+		return false
+	}
+	if !common.IsNumberInMultiplicativeGroup(pk.N, pf.U) || !common.IsNumberInMultiplicativeGroup(pk.N, pf.V) {
+		return false
+	}
+
+	q := ec.Params().N
+	var e *big.Int
+	{
+		eHash := common.SHA512_256i_TAGGED(Session, pk.N, X, Y, C, pf.A, pf.B)
+		e = common.RejectionSample(q, eHash)
+	}
+
+	// Fig 29. Equality Check
+	modNSquare := common.ModInt(pk.NSquare())
+	{
+		LHS := modNSquare.Mul(modNSquare.Exp(Y, pf.Z), modNSquare.Exp(pf.U, pk.N))
+		RHS := modNSquare.Mul(pf.A, modNSquare.Exp(C, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	{
+		LHS := modNSquare.Mul(modNSquare.Exp(pk.Gamma(), pf.Z), modNSquare.Exp(pf.V, pk.N))
+		RHS := modNSquare.Mul(pf.B, modNSquare.Exp(X, e))
+
+		if LHS.Cmp(RHS) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateBasic checks that all fields of the proof are non-nil.
+func (pf *ProofMul) ValidateBasic() bool {
+	return pf.A != nil &&
+		pf.B != nil &&
+		pf.Z != nil &&
+		pf.U != nil &&
+		pf.V != nil
+}
+
+// Bytes serializes the proof into a fixed-size array of byte slices.
+func (pf *ProofMul) Bytes() [ProofMulBytesParts][]byte {
+	return [...][]byte{
+		pf.A.Bytes(),
+		pf.B.Bytes(),
+		pf.Z.Bytes(),
+		pf.U.Bytes(),
+		pf.V.Bytes(),
+	}
+}
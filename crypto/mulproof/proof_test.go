@@ -0,0 +1,53 @@
+package mulproof_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/mulproof"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsa/keygen"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var Session = []byte("session")
+
+func TestMul(test *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	keys, _, err := keygen.LoadKeygenTestFixtures(1)
+	require.NoError(test, err)
+	sk := keys[0].PaillierSK
+	pk := &sk.PublicKey
+
+	x := common.GetRandomPositiveInt(rand.Reader, q)
+	y := common.GetRandomPositiveInt(rand.Reader, q)
+	X, rhox, err := pk.EncryptAndReturnRandomness(rand.Reader, x)
+	require.NoError(test, err)
+	Y, err := pk.Encrypt(rand.Reader, y)
+	require.NoError(test, err)
+	C, err := pk.HomoMult(x, Y)
+	require.NoError(test, err)
+	rho := common.GetRandomPositiveRelativelyPrimeInt(rand.Reader, pk.N)
+	C = common.ModInt(pk.NSquare()).Mul(C, new(big.Int).Exp(rho, pk.N, pk.NSquare()))
+
+	xy, err := sk.Decrypt(C)
+	require.NoError(test, err)
+	assert.Equal(test, 0, xy.Cmp(new(big.Int).Mul(x, y)))
+
+	proof, err := NewProof(Session, ec, pk, X, Y, C, x, rho, rhox, rand.Reader)
+	require.NoError(test, err)
+
+	proofBzs := proof.Bytes()
+	proof, err = NewProofFromBytes(proofBzs[:])
+	require.NoError(test, err)
+	assert.True(test, proof.Verify(Session, ec, pk, X, Y, C), "proof must verify")
+
+	C2, err := pk.HomoAdd(C, X)
+	require.NoError(test, err)
+	assert.False(test, proof.Verify(Session, ec, pk, X, Y, C2))
+}
@@ -219,6 +219,21 @@ func (privateKey *PrivateKey) Decrypt(c *big.Int) (m *big.Int, err error) {
 	return
 }
 
+// DecryptAndReturnRandomness decrypts the ciphertext c and also returns the randomness x used
+// for its encryption, such that c = Gamma^m * x^N mod N2.
+func (privateKey *PrivateKey) DecryptAndReturnRandomness(c *big.Int) (m, x *big.Int, err error) {
+	if m, err = privateKey.Decrypt(c); err != nil {
+		return nil, nil, err
+	}
+	// c = x^N mod N, so x = c^(N^-1 mod phi(N)) mod N
+	nInv := new(big.Int).ModInverse(privateKey.N, privateKey.PhiN)
+	if nInv == nil {
+		return nil, nil, errors.New("modular inverse of N does not exist")
+	}
+	x = new(big.Int).Exp(new(big.Int).Mod(c, privateKey.N), nInv, privateKey.N)
+	return
+}
+
 // ----- //
 
 // Proof is an implementation of Gennaro, R., Micciancio, D., Rabin, T.:
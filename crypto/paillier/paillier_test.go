@@ -76,6 +76,17 @@ func TestEncryptDecrypt(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDecryptAndReturnRandomness(t *testing.T) {
+	setUp(t)
+	exp := big.NewInt(100)
+	cypher, x, err := privateKey.EncryptAndReturnRandomness(rand.Reader, exp)
+	assert.NoError(t, err)
+	ret, retX, err := privateKey.DecryptAndReturnRandomness(cypher)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, exp.Cmp(ret))
+	assert.Equal(t, 0, x.Cmp(retX))
+}
+
 func TestHomoMul(t *testing.T) {
 	setUp(t)
 	three, err := privateKey.Encrypt(rand.Reader, big.NewInt(3))
@@ -23,10 +23,12 @@ import (
 
 type (
 	// Share represents a single Shamir secret share with its threshold, party ID, and share value.
+	// Shares of a hierarchical sharing, see CreateHierarchical, also have a rank.
 	Share struct {
 		Threshold int
 		ID,       // xi
 		Share *big.Int // Sigma i
+		Rank int // derivative of the polynomial evaluated at ID, 0 for a Shamir share
 	}
 
 	// Vs is a slice of EC points representing the Feldman VSS commitments (v0..vt).
@@ -101,6 +103,16 @@ func (share *Share) Verify(ec elliptic.Curve, threshold int, vs Vs) bool {
 	if share.Threshold != threshold || vs == nil || len(vs) != threshold+1 {
 		return false
 	}
+	if share.Rank != 0 {
+		if share.Rank < 0 || share.Rank > threshold {
+			return false
+		}
+		for _, v := range vs {
+			v.SetCurve(ec)
+		}
+		v, err := vs.PublicShare(share.ID, share.Rank)
+		return err == nil && crypto.ScalarBaseMult(ec, share.Share).Equals(v)
+	}
 	var err error
 	modQ := common.ModInt(ec.Params().N)
 	v, t := vs[0], one // YRO : we need to have our accumulator outside of the loop
@@ -126,6 +138,9 @@ func (shares Shares) ReConstruct(ec elliptic.Curve) (secret *big.Int, err error)
 	if shares[0].Threshold > len(shares) {
 		return nil, ErrNumSharesBelowThreshold
 	}
+	if shares.hierarchical() {
+		return shares.reConstructHierarchical(ec)
+	}
 	modN := common.ModInt(ec.Params().N)
 
 	// x coords
@@ -157,6 +172,101 @@ func (shares Shares) ReConstruct(ec elliptic.Curve) (secret *big.Int, err error)
 	return secret, nil
 }
 
+// LagrangeCoefficient returns the Lagrange coefficient at x of the share at ids[i], among the
+// shares at ids: the secret of a polynomial of degree lower than len(ids) is the sum of its
+// shares times their coefficient at 0.
+func LagrangeCoefficient(q *big.Int, ids []*big.Int, i int, x *big.Int) (*big.Int, error) {
+	modQ := common.ModInt(q)
+	coef := big.NewInt(1)
+	for j, id := range ids {
+		if j == i {
+			continue
+		}
+		if id.Cmp(ids[i]) == 0 {
+			return nil, errors.New("index of two parties are equal")
+		}
+		coef = modQ.Mul(coef, modQ.Mul(modQ.Sub(x, id), modQ.ModInverse(modQ.Sub(ids[i], id))))
+	}
+	return coef, nil
+}
+
+// LagrangeParts returns random parts summing to the Lagrange term at x of the share at ids[i],
+// among the shares at ids, one for each of these shares, so that the holders of the shares
+// can compute the share at x by exchanging them without revealing their own.
+func LagrangeParts(rand io.Reader, q *big.Int, ids []*big.Int, i int, x, share *big.Int) ([]*big.Int, error) {
+	coef, err := LagrangeCoefficient(q, ids, i, x)
+	if err != nil {
+		return nil, err
+	}
+	modQ := common.ModInt(q)
+
+	// the part of the holder of the share itself is the remainder
+	parts := make([]*big.Int, len(ids))
+	parts[i] = modQ.Mul(coef, share)
+	for m := range ids {
+		if m == i {
+			continue
+		}
+		parts[m] = common.GetRandomPositiveInt(rand, q)
+		parts[i] = modQ.Sub(parts[i], parts[m])
+	}
+	return parts, nil
+}
+
+// ZeroSecretPublicShare returns f(id)·G for a polynomial f of secret 0, given the commitments
+// to its coefficients of degree 1 and above, as dealt to refresh shares without changing
+// their secret.
+func ZeroSecretPublicShare(commitments []*crypto.ECPoint, id *big.Int) (*crypto.ECPoint, error) {
+	// f(id) = id·g(id), where g has the coefficients of degree 1 and above of f
+	res, err := Vs(commitments).PublicShare(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.ScalarMult(id), nil
+}
+
+// InterpolatePublicShare returns f(x)·G, where bigXs holds f(id)·G for each of ids and f is
+// of degree lower than len(ids).
+func InterpolatePublicShare(ids []*big.Int, bigXs []*crypto.ECPoint, x *big.Int) (*crypto.ECPoint, error) {
+	if len(ids) != len(bigXs) || len(ids) == 0 {
+		return nil, fmt.Errorf("%d public shares for %d ids", len(bigXs), len(ids))
+	}
+	q := bigXs[0].Curve().Params().N
+	var res *crypto.ECPoint
+	for j := range ids {
+		coef, err := LagrangeCoefficient(q, ids, j, x)
+		if err != nil {
+			return nil, err
+		}
+		term := bigXs[j].ScalarMult(coef)
+		if res == nil {
+			res = term
+		} else if res, err = res.Add(term); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// PublicThreshold returns the threshold of a sharing of the secret of pub, given the public
+// shares bigXs at ids: the lowest degree of the polynomials interpolating pub from the first
+// shares, which is the degree of f when all of them are f(id)·G.
+func PublicThreshold(ids []*big.Int, bigXs []*crypto.ECPoint, pub *crypto.ECPoint) (int, error) {
+	if len(ids) != len(bigXs) {
+		return 0, fmt.Errorf("%d public shares for %d ids", len(bigXs), len(ids))
+	}
+	for t := range ids {
+		res, err := InterpolatePublicShare(ids[:t+1], bigXs[:t+1], zero)
+		if err != nil {
+			return 0, err
+		}
+		if res.Equals(pub) {
+			return t, nil
+		}
+	}
+	return 0, errors.New("the public shares do not interpolate the public key")
+}
+
 func samplePolynomial(ec elliptic.Curve, threshold int, secret *big.Int, rand io.Reader) []*big.Int {
 	q := ec.Params().N
 	v := make([]*big.Int, threshold+1)
@@ -12,8 +12,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
 	. "github.com/KarpelesLab/tss-lib/v2/crypto/vss"
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
@@ -115,3 +117,101 @@ func TestReconstruct(t *testing.T) {
 	assert.NoError(t, err4)
 	assert.NotZero(t, secret4)
 }
+
+func TestLagrangeCoefficient(t *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(5)}
+	vs, shares, err := Create(ec, 2, secret, ids, rand.Reader)
+	assert.NoError(t, err)
+
+	// the shares interpolate the secret at 0, and the share of another party at its id
+	other := big.NewInt(7)
+	want, err := vs.PublicShare(other, 0)
+	assert.NoError(t, err)
+	atZero, atOther := big.NewInt(0), big.NewInt(0)
+	for i, share := range shares {
+		coef, err := LagrangeCoefficient(q, ids, i, big.NewInt(0))
+		assert.NoError(t, err)
+		atZero = modQ.Add(atZero, modQ.Mul(coef, share.Share))
+		coef, err = LagrangeCoefficient(q, ids, i, other)
+		assert.NoError(t, err)
+		atOther = modQ.Add(atOther, modQ.Mul(coef, share.Share))
+	}
+	assert.Equal(t, secret, atZero)
+	assert.True(t, want.Equals(crypto.ScalarBaseMult(ec, atOther)))
+
+	_, err = LagrangeCoefficient(q, []*big.Int{big.NewInt(1), big.NewInt(1)}, 0, big.NewInt(0))
+	assert.Error(t, err)
+}
+
+func TestZeroSecretPublicShare(t *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// f(x) = a1·x + a2·x^2
+	a1 := common.GetRandomPositiveInt(rand.Reader, q)
+	a2 := common.GetRandomPositiveInt(rand.Reader, q)
+	commitments := []*crypto.ECPoint{crypto.ScalarBaseMult(ec, a1), crypto.ScalarBaseMult(ec, a2)}
+	for _, id := range []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)} {
+		got, err := ZeroSecretPublicShare(commitments, id)
+		assert.NoError(t, err)
+		f := modQ.Add(modQ.Mul(a1, id), modQ.Mul(a2, modQ.Mul(id, id)))
+		assert.True(t, got.Equals(crypto.ScalarBaseMult(ec, f)))
+	}
+}
+
+func TestInterpolatePublicShareAndThreshold(t *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	const threshold = 2
+
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4), big.NewInt(5)}
+	vs, shares, err := Create(ec, threshold, secret, ids, rand.Reader)
+	require.NoError(t, err)
+	bigXs := make([]*crypto.ECPoint, len(shares))
+	for j, share := range shares {
+		bigXs[j] = crypto.ScalarBaseMult(ec, share.Share)
+	}
+
+	// the public share of the last party is interpolated from the first t+1 ones
+	got, err := InterpolatePublicShare(ids[:threshold+1], bigXs[:threshold+1], ids[4])
+	require.NoError(t, err)
+	assert.True(t, got.Equals(bigXs[4]))
+	got, err = InterpolatePublicShare(ids[1:4], bigXs[1:4], big.NewInt(0))
+	require.NoError(t, err)
+	assert.True(t, got.Equals(vs[0]))
+
+	th, err := PublicThreshold(ids, bigXs, vs[0])
+	require.NoError(t, err)
+	assert.Equal(t, threshold, th)
+	_, err = PublicThreshold(ids, bigXs, crypto.ScalarBaseMult(ec, big.NewInt(1)))
+	assert.Error(t, err)
+}
+
+func TestLagrangeParts(t *testing.T) {
+	ec := tss.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// the parts of t+1 holders of shares sum to the share at x
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+	_, shares, err := Create(ec, 2, secret, ids, rand.Reader)
+	require.NoError(t, err)
+	holders := ids[:3]
+	sum := big.NewInt(0)
+	for i := range holders {
+		parts, err := LagrangeParts(rand.Reader, q, holders, i, ids[3], shares[i].Share)
+		require.NoError(t, err)
+		require.Len(t, parts, len(holders))
+		for _, part := range parts {
+			sum = modQ.Add(sum, part)
+		}
+	}
+	assert.Equal(t, 0, shares[3].Share.Cmp(sum))
+}
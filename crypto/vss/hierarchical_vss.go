@@ -0,0 +1,265 @@
+// Hierarchical threshold secret sharing, based on Tamir Tassa, 2007., Hierarchical Threshold
+// Secret Sharing. Journal of Cryptology 20, 237–264
+//
+
+package vss
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+)
+
+// ErrRanksNotSatisfied is returned when a set of hierarchical shares does not allow to recover
+// the secret.
+var ErrRanksNotSatisfied = errors.New("the ranks of the shares do not satisfy the access structure")
+
+// CreateHierarchical returns shares of secret like Create, except that the share at indexes[i]
+// is the value at indexes[i] of the ranks[i]-th derivative of the polynomial, instead of the
+// polynomial itself. The secret can be recovered from a set of shares when, sorted by rank,
+// the k-th of its first threshold+1 shares (from 0) has a rank of at most k: shares of rank 0
+// are needed, then shares of rank at most 1, and so on. Ranks are thus the levels of the
+// access structure: with ranks 0 for a compliance officer and 1 for the operators and a
+// threshold of 2, the officer and two operators are needed. It fails when the shares at all of
+// indexes do not allow to recover the secret.
+func CreateHierarchical(ec elliptic.Curve, threshold int, secret *big.Int, indexes []*big.Int, ranks []int, rand io.Reader) (Vs, Shares, error) {
+	if secret == nil || indexes == nil {
+		return nil, nil, fmt.Errorf("vss secret or indexes == nil: %v %v", secret, indexes)
+	}
+	if threshold < 1 {
+		return nil, nil, errors.New("vss threshold < 1")
+	}
+	if len(ranks) != len(indexes) {
+		return nil, nil, fmt.Errorf("len(ranks) != len(indexes) (%d != %d)", len(ranks), len(indexes))
+	}
+	ids, err := CheckIndexes(ec, indexes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := CheckRanks(ranks, threshold); err != nil {
+		return nil, nil, err
+	}
+
+	poly := samplePolynomial(ec, threshold, secret, rand)
+
+	v := make(Vs, len(poly))
+	for i, ai := range poly {
+		v[i] = crypto.ScalarBaseMult(ec, ai)
+	}
+
+	modQ := common.ModInt(ec.Params().N)
+	shares := make(Shares, len(ids))
+	for i, id := range ids {
+		share := big.NewInt(0)
+		for k, c := range derivativeRow(ec.Params().N, id, ranks[i], threshold+1) {
+			share = modQ.Add(share, modQ.Mul(c, poly[k]))
+		}
+		shares[i] = &Share{Threshold: threshold, ID: id, Share: share, Rank: ranks[i]}
+	}
+	return v, shares, nil
+}
+
+// CheckRanks returns ErrRanksNotSatisfied unless the shares of the given ranks, of a sharing
+// of the given threshold, allow to recover the secret.
+func CheckRanks(ranks []int, threshold int) error {
+	if len(ranks) < threshold+1 {
+		return ErrNumSharesBelowThreshold
+	}
+	sorted := slices.Sorted(slices.Values(ranks))
+	if sorted[0] < 0 {
+		return fmt.Errorf("negative rank %d", sorted[0])
+	}
+	for k, r := range sorted[:threshold+1] {
+		if r > k {
+			return ErrRanksNotSatisfied
+		}
+	}
+	if sorted[len(sorted)-1] > threshold {
+		return fmt.Errorf("rank %d above the threshold %d", sorted[len(sorted)-1], threshold)
+	}
+	return nil
+}
+
+// PublicShare returns the commitment to the share of the given rank at id of the polynomial
+// committed to by vs, that is the share times the generator.
+func (vs Vs) PublicShare(id *big.Int, rank int) (*crypto.ECPoint, error) {
+	if len(vs) == 0 || rank < 0 || rank >= len(vs) {
+		return nil, fmt.Errorf("rank %d out of the %d commitments", rank, len(vs))
+	}
+	var res *crypto.ECPoint
+	for k, c := range derivativeRow(vs[0].Curve().Params().N, id, rank, len(vs)) {
+		if k < rank {
+			continue
+		}
+		term := vs[k].ScalarMult(c)
+		if res == nil {
+			res = term
+			continue
+		}
+		var err error
+		if res, err = res.Add(term); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// BirkhoffCoefficients returns the coefficients which combine the shares at ids of the given
+// ranks, of a polynomial of degree lower than len(ids), into its constant term: the Lagrange
+// coefficients at 0 when all the ranks are 0. It returns ErrRanksNotSatisfied when the secret
+// cannot be recovered from these shares.
+func BirkhoffCoefficients(q *big.Int, ids []*big.Int, ranks []int) ([]*big.Int, error) {
+	if len(ids) != len(ranks) {
+		return nil, fmt.Errorf("len(ids) != len(ranks) (%d != %d)", len(ids), len(ranks))
+	}
+	if err := CheckRanks(ranks, len(ids)-1); err != nil {
+		return nil, err
+	}
+
+	// solve sum_j coefs[j] * rows[j][k] = (k == 0) for k < n, by Gauss-Jordan elimination of
+	// the transposed matrix augmented with the right-hand side
+	modQ := common.ModInt(q)
+	n := len(ids)
+	m := make([][]*big.Int, n)
+	for k := range m {
+		m[k] = make([]*big.Int, n+1)
+		m[k][n] = big.NewInt(0)
+	}
+	m[0][n] = big.NewInt(1)
+	for j, id := range ids {
+		for k, c := range derivativeRow(q, id, ranks[j], n) {
+			m[k][j] = c
+		}
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for row := col; row < n; row++ {
+			if m[row][col].Sign() != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, errors.New("the shares do not determine the secret (singular Birkhoff matrix)")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv := modQ.ModInverse(m[col][col])
+		for c := col; c <= n; c++ {
+			m[col][c] = modQ.Mul(m[col][c], inv)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col].Sign() == 0 {
+				continue
+			}
+			f := m[row][col]
+			for c := col; c <= n; c++ {
+				m[row][c] = modQ.Sub(m[row][c], modQ.Mul(f, m[col][c]))
+			}
+		}
+	}
+	coefs := make([]*big.Int, n)
+	for j := range coefs {
+		coefs[j] = m[j][n]
+	}
+	return coefs, nil
+}
+
+// HierarchicalCoefficients returns, for each party, the Birkhoff coefficient of its share
+// among the shares at ids of the given ranks, in the form of WeightedCoefficients. It fails
+// when these shares, of a sharing of the given threshold, do not allow to recover the secret.
+func HierarchicalCoefficients(q *big.Int, ids []*big.Int, ranks []int, threshold int) ([][]*big.Int, error) {
+	if len(ids) != len(ranks) {
+		return nil, fmt.Errorf("len(ids) != len(ranks) (%d != %d)", len(ids), len(ranks))
+	}
+	if err := CheckRanks(ranks, threshold); err != nil {
+		return nil, fmt.Errorf("the parties cannot sign together: %w", err)
+	}
+	lambdas, err := BirkhoffCoefficients(q, ids, ranks)
+	if err != nil {
+		return nil, err
+	}
+	coefs := make([][]*big.Int, len(lambdas))
+	for j, lambda := range lambdas {
+		coefs[j] = []*big.Int{lambda}
+	}
+	return coefs, nil
+}
+
+// HierarchicalRanks returns ranks, or nil when they are all 0 and the sharing is flat.
+func HierarchicalRanks(ranks []int) []int {
+	if !slices.ContainsFunc(ranks, func(r int) bool { return r != 0 }) {
+		return nil
+	}
+	return ranks
+}
+
+// AllRanks returns the rank of the share of each party, in the order of Ks: 0 for every party
+// unless the key is hierarchical.
+func (ks KeyShares) AllRanks() []int {
+	if ks.Ranks != nil {
+		return ks.Ranks
+	}
+	return make([]int, len(ks.Ks))
+}
+
+// DealShares returns the shares of secret for the parties of identifiers ids: at their other
+// points moreIDs too for a weighted sharing, or of the given ranks for a hierarchical one.
+func DealShares(ec elliptic.Curve, threshold int, secret *big.Int, ids []*big.Int, moreIDs [][]*big.Int, ranks []int, rand io.Reader) (Vs, Shares, error) {
+	if ranks != nil {
+		return CreateHierarchical(ec, threshold, secret, ids, ranks, rand)
+	}
+	return Create(ec, threshold, secret, AllPoints(ids, moreIDs), rand)
+}
+
+// derivativeRow returns the coefficients c[k], k < n, of the value at id of the rank-th
+// derivative of a polynomial a[0] + a[1] x + ... + a[n-1] x^(n-1), as sum of c[k] a[k]:
+// k!/(k-rank)! id^(k-rank), or 0 for k < rank.
+func derivativeRow(q, id *big.Int, rank, n int) []*big.Int {
+	modQ := common.ModInt(q)
+	row := make([]*big.Int, n)
+	pow := big.NewInt(1)
+	for k := range row {
+		if k < rank {
+			row[k] = big.NewInt(0)
+			continue
+		}
+		ff := big.NewInt(1)
+		for f := k - rank + 1; f <= k; f++ {
+			ff = modQ.Mul(ff, big.NewInt(int64(f)))
+		}
+		row[k] = modQ.Mul(ff, pow)
+		pow = modQ.Mul(pow, id)
+	}
+	return row
+}
+
+// hierarchical returns true if one of shares has a rank.
+func (shares Shares) hierarchical() bool {
+	return slices.ContainsFunc(shares, func(share *Share) bool { return share.Rank != 0 })
+}
+
+// reConstructHierarchical recovers the secret from hierarchical shares, by Birkhoff
+// interpolation.
+func (shares Shares) reConstructHierarchical(ec elliptic.Curve) (*big.Int, error) {
+	ids := make([]*big.Int, len(shares))
+	ranks := make([]int, len(shares))
+	for i, share := range shares {
+		ids[i], ranks[i] = share.ID, share.Rank
+	}
+	coefs, err := BirkhoffCoefficients(ec.Params().N, ids, ranks)
+	if err != nil {
+		return nil, err
+	}
+	modQ := common.ModInt(ec.Params().N)
+	secret := big.NewInt(0)
+	for i, share := range shares {
+		secret = modQ.Add(secret, modQ.Mul(coefs[i], share.Share))
+	}
+	return secret, nil
+}
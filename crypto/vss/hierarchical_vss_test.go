@@ -0,0 +1,114 @@
+package vss_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestCreateHierarchical(t *testing.T) {
+	// a compliance officer, of rank 0, and 4 operators, of rank 1: the officer and 2 operators
+	// recover the secret, the operators alone do not
+	const threshold = 2
+	ranks := []int{0, 1, 1, 1, 1}
+	q := tss.EC().Params().N
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := make([]*big.Int, len(ranks))
+	for i := range ids {
+		ids[i] = common.GetRandomPositiveInt(rand.Reader, q)
+	}
+
+	vs, shares, err := CreateHierarchical(tss.EC(), threshold, secret, ids, ranks, rand.Reader)
+	require.NoError(t, err)
+	require.Len(t, vs, threshold+1)
+	assert.True(t, crypto.ScalarBaseMult(tss.EC(), secret).Equals(vs[0]))
+	for i, share := range shares {
+		assert.Equal(t, ranks[i], share.Rank)
+		assert.True(t, share.Verify(tss.EC(), threshold, vs))
+		bigXi, err := vs.PublicShare(share.ID, share.Rank)
+		require.NoError(t, err)
+		assert.True(t, crypto.ScalarBaseMult(tss.EC(), share.Share).Equals(bigXi))
+
+		// a share does not verify with another rank
+		bad := *share
+		bad.Rank = 1 - share.Rank
+		assert.False(t, bad.Verify(tss.EC(), threshold, vs))
+	}
+
+	secret2, err := Shares{shares[0], shares[2], shares[4]}.ReConstruct(tss.EC())
+	require.NoError(t, err)
+	assert.Equal(t, 0, secret.Cmp(secret2))
+	secret2, err = shares.ReConstruct(tss.EC())
+	require.NoError(t, err)
+	assert.Equal(t, 0, secret.Cmp(secret2))
+
+	_, err = shares[1:].ReConstruct(tss.EC())
+	assert.ErrorIs(t, err, ErrRanksNotSatisfied)
+
+	// the parties holding the shares must be able to recover the secret
+	_, _, err = CreateHierarchical(tss.EC(), threshold, secret, ids, []int{1, 1, 1, 1, 1}, rand.Reader)
+	assert.ErrorIs(t, err, ErrRanksNotSatisfied)
+	_, _, err = CreateHierarchical(tss.EC(), threshold, secret, ids, []int{0, 1, 2, 3, 1}, rand.Reader)
+	assert.Error(t, err)
+}
+
+func TestBirkhoffCoefficientsFlat(t *testing.T) {
+	// with ranks 0, the coefficients are the Lagrange coefficients at 0
+	q := tss.EC().Params().N
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	coefs, err := BirkhoffCoefficients(q, ids, []int{0, 0, 0})
+	require.NoError(t, err)
+	modQ := common.ModInt(q)
+	expected := []*big.Int{big.NewInt(3), modQ.Sub(big.NewInt(0), big.NewInt(3)), big.NewInt(1)}
+	for j := range coefs {
+		assert.Equal(t, 0, expected[j].Cmp(coefs[j]))
+	}
+}
+
+func TestDealSharesHierarchical(t *testing.T) {
+	const threshold = 2
+	q := tss.EC().Params().N
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}
+	assert.Nil(t, HierarchicalRanks([]int{0, 0, 0, 0}))
+	ranks := HierarchicalRanks([]int{0, 1, 1, 1})
+	require.NotNil(t, ranks)
+
+	_, shares, err := DealShares(tss.EC(), threshold, secret, ids, nil, ranks, rand.Reader)
+	require.NoError(t, err)
+	for i, share := range shares {
+		assert.Equal(t, ranks[i], share.Rank)
+	}
+
+	// the officer and two operators sign together, the operators alone cannot
+	coefs, err := HierarchicalCoefficients(q, ids[:3], ranks[:3], threshold)
+	require.NoError(t, err)
+	modQ := common.ModInt(q)
+	sum := big.NewInt(0)
+	for j, jCoefs := range coefs {
+		require.Len(t, jCoefs, 1)
+		sum = modQ.Add(sum, modQ.Mul(jCoefs[0], shares[j].Share))
+	}
+	assert.Equal(t, 0, secret.Cmp(sum))
+	_, err = HierarchicalCoefficients(q, ids[1:], ranks[1:], threshold)
+	assert.ErrorIs(t, err, ErrRanksNotSatisfied)
+}
+
+func TestDealSharesFlat(t *testing.T) {
+	const threshold = 1
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	moreIDs := [][]*big.Int{{big.NewInt(3)}, nil}
+	vs, shares, err := DealShares(tss.EC(), threshold, big.NewInt(42), ids, moreIDs, nil, rand.Reader)
+	require.NoError(t, err)
+	require.Len(t, shares, 3)
+	assert.Equal(t, 0, shares[2].ID.Cmp(big.NewInt(3)))
+	assert.True(t, crypto.ScalarBaseMult(tss.EC(), big.NewInt(42)).Equals(vs[0]))
+}
@@ -0,0 +1,213 @@
+// Weighted threshold secret sharing, where a party holds several shares of the same
+// polynomial: the one at its identifier, and others at points derived from it.
+//
+
+package vss
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+)
+
+// WeightedPoints returns the evaluation points of the shares of each party of identifier
+// ids[j] besides ids[j] itself: weights[j]-1 points derived from ids[j]. It returns nil when
+// each party holds a single share.
+func WeightedPoints(q *big.Int, ids []*big.Int, weights []int) [][]*big.Int {
+	if !slices.ContainsFunc(weights, func(w int) bool { return w > 1 }) {
+		return nil
+	}
+	moreIDs := make([][]*big.Int, len(ids))
+	for j, id := range ids {
+		for m := 1; m < weights[j]; m++ {
+			moreIDs[j] = append(moreIDs[j], new(big.Int).Mod(common.SHA512_256i(id, big.NewInt(int64(m))), q))
+		}
+	}
+	return moreIDs
+}
+
+// AllPoints returns ids followed by the other evaluation points of each party, moreIDs[j].
+func AllPoints(ids []*big.Int, moreIDs [][]*big.Int) []*big.Int {
+	points := slices.Clone(ids)
+	for _, jIDs := range moreIDs {
+		points = append(points, jIDs...)
+	}
+	return points
+}
+
+// SplitShares returns the shares at the other evaluation points of each party, which follow
+// the shares at ids in shares, created at AllPoints(ids, moreIDs).
+func SplitShares(shares Shares, moreIDs [][]*big.Int) []Shares {
+	if moreIDs == nil {
+		return nil
+	}
+	res := make([]Shares, len(moreIDs))
+	off := len(moreIDs)
+	for j, jIDs := range moreIDs {
+		res[j] = shares[off : off+len(jIDs)]
+		off += len(jIDs)
+	}
+	return res
+}
+
+// WeightedCoefficients returns, for each party, the Lagrange coefficients at 0 of its
+// evaluation points, ids[j] then moreIDs[j], among the points of all the parties. moreIDs is
+// nil when each party holds a single share.
+func WeightedCoefficients(q *big.Int, ids []*big.Int, moreIDs [][]*big.Int) ([][]*big.Int, error) {
+	if moreIDs != nil && len(moreIDs) != len(ids) {
+		return nil, fmt.Errorf("len(ids) != len(moreIDs) (%d != %d)", len(ids), len(moreIDs))
+	}
+	points := AllPoints(ids, moreIDs)
+	zero := big.NewInt(0)
+	coefs := make([][]*big.Int, len(ids))
+	off := len(ids)
+	for j := range coefs {
+		idx := []int{j}
+		if moreIDs != nil {
+			for m := range moreIDs[j] {
+				idx = append(idx, off+m)
+			}
+			off += len(moreIDs[j])
+		}
+		for _, p := range idx {
+			coef, err := LagrangeCoefficient(q, points, p, zero)
+			if err != nil {
+				return nil, err
+			}
+			coefs[j] = append(coefs[j], coef)
+		}
+	}
+	return coefs, nil
+}
+
+// CombineShares returns the additive share of the secret of a party: its shares times coefs,
+// its coefficients returned by WeightedCoefficients.
+func CombineShares(q *big.Int, coefs, shares []*big.Int) (*big.Int, error) {
+	if len(coefs) != len(shares) || len(shares) == 0 {
+		return nil, fmt.Errorf("%d shares for %d coefficients", len(shares), len(coefs))
+	}
+	modQ := common.ModInt(q)
+	res := big.NewInt(0)
+	for m, share := range shares {
+		res = modQ.Add(res, modQ.Mul(coefs[m], share))
+	}
+	return res, nil
+}
+
+// CombinePublicShares returns the public additive share of a party: its public shares times
+// coefs, its coefficients returned by WeightedCoefficients.
+func CombinePublicShares(coefs []*big.Int, bigXs []*crypto.ECPoint) (*crypto.ECPoint, error) {
+	if len(coefs) != len(bigXs) || len(bigXs) == 0 {
+		return nil, fmt.Errorf("%d public shares for %d coefficients", len(bigXs), len(coefs))
+	}
+	res := bigXs[0].ScalarMult(coefs[0])
+	for m, coef := range coefs[1:] {
+		var err error
+		if res, err = res.Add(bigXs[m+1].ScalarMult(coef)); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// FlattenPublicShares returns the coordinates of the public shares at the other points of the
+// parties, as for an ssid.
+func FlattenPublicShares(moreBigXs [][]*crypto.ECPoint) ([]*big.Int, error) {
+	var flat []*big.Int
+	for _, bigXs := range moreBigXs {
+		if len(bigXs) == 0 {
+			continue
+		}
+		list, err := crypto.FlattenECPoints(bigXs)
+		if err != nil {
+			return nil, err
+		}
+		flat = append(flat, list...)
+	}
+	return flat, nil
+}
+
+// KeyShares is the share data of a key held by a party: the evaluation points of all the
+// parties, Ks then MoreKs[j] for a weighted key, their public shares at these points, BigXj
+// then MoreBigXj[j], the shares of the party, Xi then MoreXi, and the ranks of the parties of
+// a hierarchical key. MoreKs, MoreBigXj and MoreXi are nil unless the key is weighted, and
+// Ranks is nil unless it is hierarchical.
+type KeyShares struct {
+	Ks        []*big.Int
+	MoreKs    [][]*big.Int
+	BigXj     []*crypto.ECPoint
+	MoreBigXj [][]*crypto.ECPoint
+	Xi        *big.Int
+	MoreXi    []*big.Int
+	Ranks     []int
+}
+
+// Weights returns the number of shares held by each party, in the order of Ks.
+func (ks KeyShares) Weights() []int {
+	weights := make([]int, len(ks.Ks))
+	for j := range weights {
+		weights[j] = 1
+		if ks.MoreKs != nil {
+			weights[j] += len(ks.MoreKs[j])
+		}
+	}
+	return weights
+}
+
+// Coefficients returns, for each party, the Lagrange coefficients at 0 of its evaluation
+// points among the points of all the parties, which must already be reindexed for the
+// committee signing, or its Birkhoff coefficient for a hierarchical key. It fails when the
+// parties cannot sign together for threshold, or when weights or ranks, if not nil, are not
+// the ones of the key.
+func (ks KeyShares) Coefficients(q *big.Int, threshold int, weights, ranks []int) ([][]*big.Int, error) {
+	if len(ks.Ks) != len(ks.BigXj) {
+		return nil, fmt.Errorf("len(ks) != len(bigXs) (%d != %d)", len(ks.Ks), len(ks.BigXj))
+	}
+	if ks.MoreKs != nil {
+		if len(ks.MoreKs) != len(ks.Ks) || len(ks.MoreBigXj) != len(ks.Ks) {
+			return nil, errors.New("the other points of the parties do not match Ks")
+		}
+		for j, kjs := range ks.MoreKs {
+			if len(ks.MoreBigXj[j]) != len(kjs) {
+				return nil, fmt.Errorf("the public shares of party %d do not match its points", j)
+			}
+		}
+	}
+	if weights != nil && !slices.Equal(weights, ks.Weights()) {
+		return nil, errors.New("the weights of the parties are not the ones of the key")
+	}
+	if ranks != nil && !slices.Equal(ranks, ks.AllRanks()) {
+		return nil, errors.New("the ranks of the parties are not the ones of the key")
+	}
+	points := AllPoints(ks.Ks, ks.MoreKs)
+	if threshold+1 > len(points) {
+		return nil, fmt.Errorf("t+1=%d is not satisfied by the key count of %d", threshold+1, len(points))
+	}
+	if ks.Ranks != nil {
+		return HierarchicalCoefficients(q, ks.Ks, ks.Ranks, threshold)
+	}
+	return WeightedCoefficients(q, ks.Ks, ks.MoreKs)
+}
+
+// AdditiveShare returns the additive share of the secret of the party: its shares, Xi then
+// MoreXi, times coefs, its coefficients returned by Coefficients.
+func (ks KeyShares) AdditiveShare(q *big.Int, coefs []*big.Int) (*big.Int, error) {
+	if len(coefs) != 1+len(ks.MoreXi) {
+		return nil, fmt.Errorf("the key holds %d shares of this party, instead of %d", 1+len(ks.MoreXi), len(coefs))
+	}
+	return CombineShares(q, coefs, append([]*big.Int{ks.Xi}, ks.MoreXi...))
+}
+
+// PublicShare returns the public additive share of the party of index j, that is its public
+// shares, BigXj[j] then MoreBigXj[j], times coefs, its coefficients returned by Coefficients.
+func (ks KeyShares) PublicShare(j int, coefs []*big.Int) (*crypto.ECPoint, error) {
+	bigXs := []*crypto.ECPoint{ks.BigXj[j]}
+	if ks.MoreBigXj != nil {
+		bigXs = append(bigXs, ks.MoreBigXj[j]...)
+	}
+	return CombinePublicShares(coefs, bigXs)
+}
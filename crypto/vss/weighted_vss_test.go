@@ -0,0 +1,154 @@
+package vss_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	. "github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestWeightedSharing(t *testing.T) {
+	// 3 parties holding 2, 1 and 3 shares of a polynomial of degree 3: the first and the last
+	// parties recover the secret together, from their additive shares
+	const threshold = 3
+	weights := []int{2, 1, 3}
+	q := tss.EC().Params().N
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := make([]*big.Int, len(weights))
+	for j := range ids {
+		ids[j] = common.GetRandomPositiveInt(rand.Reader, q)
+	}
+
+	moreIDs := WeightedPoints(q, ids, weights)
+	require.Len(t, moreIDs, len(ids))
+	for j, jIDs := range moreIDs {
+		assert.Len(t, jIDs, weights[j]-1)
+	}
+	assert.Equal(t, moreIDs, WeightedPoints(q, ids, weights), "the points are derived from the ids")
+	assert.Nil(t, WeightedPoints(q, ids, []int{1, 1, 1}))
+
+	points := AllPoints(ids, moreIDs)
+	require.Len(t, points, 6)
+	vs, shares, err := Create(tss.EC(), threshold, secret, points, rand.Reader)
+	require.NoError(t, err)
+	moreShares := SplitShares(shares, moreIDs)
+	require.Len(t, moreShares, len(ids))
+	for j, jShares := range moreShares {
+		for m, share := range jShares {
+			assert.Equal(t, 0, moreIDs[j][m].Cmp(share.ID))
+		}
+	}
+	assert.Nil(t, SplitShares(shares, nil))
+
+	signers := []int{0, 2}
+	signerIDs := []*big.Int{ids[0], ids[2]}
+	coefs, err := WeightedCoefficients(q, signerIDs, [][]*big.Int{moreIDs[0], moreIDs[2]})
+	require.NoError(t, err)
+	sum := big.NewInt(0)
+	var bigW *crypto.ECPoint
+	for n, j := range signers {
+		values := []*big.Int{shares[j].Share}
+		bigXs := []*crypto.ECPoint{crypto.ScalarBaseMult(tss.EC(), shares[j].Share)}
+		for _, share := range moreShares[j] {
+			values = append(values, share.Share)
+			bigXs = append(bigXs, crypto.ScalarBaseMult(tss.EC(), share.Share))
+		}
+		w, err := CombineShares(q, coefs[n], values)
+		require.NoError(t, err)
+		sum.Add(sum, w)
+
+		bigWj, err := CombinePublicShares(coefs[n], bigXs)
+		require.NoError(t, err)
+		assert.True(t, crypto.ScalarBaseMult(tss.EC(), w).Equals(bigWj))
+		if bigW == nil {
+			bigW = bigWj
+		} else {
+			bigW, err = bigW.Add(bigWj)
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, 0, secret.Cmp(sum.Mod(sum, q)))
+	assert.True(t, vs[0].Equals(bigW))
+
+	_, err = CombineShares(q, coefs[0], []*big.Int{secret})
+	assert.Error(t, err)
+}
+
+func TestWeightedCoefficientsSingleShares(t *testing.T) {
+	q := tss.EC().Params().N
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+	coefs, err := WeightedCoefficients(q, ids, nil)
+	require.NoError(t, err)
+	for j, jCoefs := range coefs {
+		require.Len(t, jCoefs, 1)
+		lambda, err := LagrangeCoefficient(q, ids, j, big.NewInt(0))
+		require.NoError(t, err)
+		assert.Equal(t, 0, lambda.Cmp(jCoefs[0]))
+	}
+}
+
+func TestFlattenPublicShares(t *testing.T) {
+	p := crypto.ScalarBaseMult(tss.EC(), big.NewInt(7))
+	flat, err := FlattenPublicShares([][]*crypto.ECPoint{{p}, nil, {p, p}})
+	require.NoError(t, err)
+	require.Len(t, flat, 6)
+	assert.Equal(t, 0, p.X().Cmp(flat[4]))
+	assert.Equal(t, 0, p.Y().Cmp(flat[5]))
+}
+
+func TestKeyShares(t *testing.T) {
+	// 2 parties holding 2 and 1 shares of a polynomial of degree 2
+	const threshold = 2
+	weights := []int{2, 1}
+	q := tss.EC().Params().N
+	secret := common.GetRandomPositiveInt(rand.Reader, q)
+	ids := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	moreIDs := WeightedPoints(q, ids, weights)
+	_, shares, err := Create(tss.EC(), threshold, secret, AllPoints(ids, moreIDs), rand.Reader)
+	require.NoError(t, err)
+	moreShares := SplitShares(shares, moreIDs)
+
+	ks := KeyShares{Ks: ids, MoreKs: moreIDs, MoreBigXj: make([][]*crypto.ECPoint, len(ids))}
+	for j := range ids {
+		ks.BigXj = append(ks.BigXj, crypto.ScalarBaseMult(tss.EC(), shares[j].Share))
+		for _, share := range moreShares[j] {
+			ks.MoreBigXj[j] = append(ks.MoreBigXj[j], crypto.ScalarBaseMult(tss.EC(), share.Share))
+		}
+	}
+	assert.Equal(t, weights, ks.Weights())
+	assert.Equal(t, []int{0, 0}, ks.AllRanks())
+
+	coefs, err := ks.Coefficients(q, threshold, weights, nil)
+	require.NoError(t, err)
+	sum := big.NewInt(0)
+	for j := range ids {
+		ks.Xi = shares[j].Share
+		ks.MoreXi = nil
+		for _, share := range moreShares[j] {
+			ks.MoreXi = append(ks.MoreXi, share.Share)
+		}
+		wj, err := ks.AdditiveShare(q, coefs[j])
+		require.NoError(t, err)
+		bigWj, err := ks.PublicShare(j, coefs[j])
+		require.NoError(t, err)
+		assert.True(t, bigWj.Equals(crypto.ScalarBaseMult(tss.EC(), wj)))
+		sum.Add(sum, wj)
+	}
+	assert.Equal(t, 0, secret.Cmp(sum.Mod(sum, q)))
+
+	_, err = ks.Coefficients(q, threshold, []int{1, 2}, nil)
+	assert.ErrorContains(t, err, "weights")
+	_, err = ks.Coefficients(q, threshold, nil, []int{0, 1})
+	assert.ErrorContains(t, err, "ranks")
+	_, err = ks.Coefficients(q, 3, nil, nil)
+	assert.ErrorContains(t, err, "t+1=4")
+	_, err = ks.AdditiveShare(q, coefs[0]) // the shares of party 1
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package ecdsatss
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/facproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/schnorr"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskAnnouncement is the task name bound in the proofs of a PartyAnnouncement.
+const TaskAnnouncement = "ecdsa-announcement"
+
+// PartyAnnouncement carries the Paillier key and range proof parameters of a party, with the
+// same proofs as in keygen, to a party of the key which did not take part in the Repair of its
+// share or in its Enrollment. A Schnorr proof of the share of the party, bound to them, shows that they come from
+// it. It is made by Key.Announce for a single recipient, and imported with Key.ImportParty.
+type PartyAnnouncement struct {
+	ShareID []byte `json:"share_id"`
+	repairRound1msg2
+	FacProof    [][]byte `json:"fac_proof,omitempty"`
+	ProofAlphaX []byte   `json:"proof_alpha_x"`
+	ProofAlphaY []byte   `json:"proof_alpha_y"`
+	ProofT      []byte   `json:"proof_t"`
+}
+
+// Announce returns the announcement of the pre-params of this party to the party of the key
+// of identifier to. The proofs follow the settings of params, which must be the same for the
+// recipient.
+func (key *Key) Announce(params *tss.Parameters, to *big.Int) (*PartyAnnouncement, error) {
+	j := key.partyIndex(to)
+	if j < 0 || to.Cmp(key.ShareID) == 0 {
+		return nil, errors.New("the recipient must be another party of the key")
+	}
+	context := key.announcementContext(key.ShareID, to)
+	msg, err := newAuxInfoMsg(context, key.LocalPreParams, params)
+	if err != nil {
+		return nil, err
+	}
+	ann := &PartyAnnouncement{ShareID: key.ShareID.Bytes(), repairRound1msg2: *msg}
+	i := key.partyIndex(key.ShareID)
+	proof, err := schnorr.NewZKProof(ann.proofContext(context), key.Xi, key.BigXj[i], params.Rand())
+	if err != nil {
+		return nil, fmt.Errorf("NewZKProof(xi, bigXi): %w", err)
+	}
+	ann.ProofAlphaX, ann.ProofAlphaY, ann.ProofT = proof.Alpha.X().Bytes(), proof.Alpha.Y().Bytes(), proof.T.Bytes()
+	if !params.NoProofFac() {
+		sk := key.PaillierSK
+		fp, err := facproof.NewProof(context, params.EC(), sk.N, key.NTildej[j], key.H1j[j], key.H2j[j], sk.P, sk.Q, params.Rand())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate fac proof: %w", err)
+		}
+		bzs := fp.Bytes()
+		ann.FacProof = bzs[:]
+	}
+	return ann, nil
+}
+
+// ImportParty returns a copy of key with the Paillier key and range proof parameters of the
+// announced party, once their proofs are verified. When the announced party is a party of the
+// key, as after a Repair of its share, its public share is unchanged; otherwise, as after its
+// Enrollment, it is added as the last party of the key with its public share interpolated
+// from the public shares of the key.
+func (key *Key) ImportParty(params *tss.Parameters, ann *PartyAnnouncement) (*Key, error) {
+	if key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	shareID := new(big.Int).SetBytes(ann.ShareID)
+	if shareID.Cmp(key.ShareID) == 0 {
+		return nil, errors.New("a party cannot import its own announcement")
+	}
+	j := key.partyIndex(shareID)
+	var bigX *crypto.ECPoint
+	if j >= 0 {
+		bigX = key.BigXj[j]
+	} else {
+		var err error
+		if bigX, err = vss.InterpolatePublicShare(key.Ks, key.BigXj, shareID); err != nil {
+			return nil, err
+		}
+	}
+	context := key.announcementContext(shareID, key.ShareID)
+	alpha, err := crypto.NewECPoint(params.EC(), new(big.Int).SetBytes(ann.ProofAlphaX), new(big.Int).SetBytes(ann.ProofAlphaY))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct Schnorr proof alpha point: %w", err)
+	}
+	proof := &schnorr.ZKProof{Alpha: alpha, T: new(big.Int).SetBytes(ann.ProofT)}
+	if !proof.Verify(ann.proofContext(context), bigX) {
+		return nil, errors.New("Schnorr proof verification failed for the share of the announced party")
+	}
+	paillierPK, nTilde, h1, h2, err := parseAuxInfoMsg(context, &ann.repairRound1msg2, params)
+	if err != nil {
+		return nil, err
+	}
+	if !params.NoProofFac() {
+		fp, err := facproof.NewProofFromBytes(ann.FacProof)
+		if err != nil || !fp.Verify(context, params.EC(), paillierPK.N, key.NTildei, key.H1i, key.H2i) {
+			return nil, errors.New("fac proof verification failed")
+		}
+	}
+
+	if j < 0 {
+		return key.withParty(shareID, paillierPK, nTilde, h1, h2)
+	}
+	data := *key
+	data.PaillierPKs = append([]*paillier.PublicKey(nil), key.PaillierPKs...)
+	data.NTildej = append([]*big.Int(nil), key.NTildej...)
+	data.H1j = append([]*big.Int(nil), key.H1j...)
+	data.H2j = append([]*big.Int(nil), key.H2j...)
+	data.PaillierPKs[j] = paillierPK
+	data.NTildej[j], data.H1j[j], data.H2j[j] = nTilde, h1, h2
+	return &data, nil
+}
+
+// proofContext returns the context of the Schnorr proof of the announcement, binding the
+// announced Paillier key and range proof parameters.
+func (ann *PartyAnnouncement) proofContext(context []byte) []byte {
+	return common.SHA512_256(context, ann.PaillierN, ann.NTilde, ann.H1, ann.H2)
+}
+
+// partyIndex returns the index of the party of identifier k in Ks, or -1.
+func (key *Key) partyIndex(k *big.Int) int {
+	for j, kj := range key.Ks {
+		if kj.Cmp(k) == 0 {
+			return j
+		}
+	}
+	return -1
+}
+
+// announcementContext returns the context of the proofs of the announcement of the party from
+// to the party to, binding the public key.
+func (key *Key) announcementContext(from, to *big.Int) []byte {
+	ec := key.ECDSAPub.Curve()
+	return common.SHA512_256i(ec.Params().P, ec.Params().N, ec.Params().B, ec.Params().Gx, ec.Params().Gy,
+		key.ECDSAPub.X(), key.ECDSAPub.Y(), from, to, new(big.Int).SetBytes([]byte(TaskAnnouncement))).Bytes()
+}
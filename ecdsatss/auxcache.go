@@ -0,0 +1,150 @@
+package ecdsatss
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// ErrAuxInfoUnknown is returned when a party skipped sending its auxiliary info because it
+// expects it to be cached, while it is not found in the AuxInfoCache of this party. Removing
+// the party from the caches with Forget makes the next session send and verify it again.
+var ErrAuxInfoUnknown = errors.New("auxiliary info of party is not in the cache")
+
+// AuxInfo is the auxiliary info of a peer, its Paillier public key and range proof
+// parameters, as verified with their proofs in a keygen or resharing.
+type AuxInfo struct {
+	Fingerprint []byte   // fingerprint of the pre-params of the peer
+	PaillierN   *big.Int // Paillier modulus of the peer
+	NTilde      *big.Int
+	H1, H2      *big.Int
+
+	// Verifier is the fingerprint of the pre-params of this party in the session that
+	// verified the info. The peer proved its Paillier modulus against them, and verified them
+	// in turn.
+	Verifier []byte
+}
+
+// AuxInfoCache holds the auxiliary info of the peers of this party, verified in past keygens
+// and resharings. It can be persisted as json and shared by concurrent sessions.
+//
+// A session using the cache only sends the auxiliary info of this party when one of its peers
+// did not verify it yet, and does not verify again the auxiliary info of the peers found in
+// the cache, so that only the VSS part of the protocol is run when the same committee meets
+// again.
+type AuxInfoCache struct {
+	Peers map[string]*AuxInfo // by hex encoded PartyID.Key
+
+	lock sync.Mutex
+}
+
+// NewAuxInfoCache returns an empty AuxInfoCache.
+func NewAuxInfoCache() *AuxInfoCache {
+	return &AuxInfoCache{Peers: make(map[string]*AuxInfo)}
+}
+
+// Fingerprint returns the fingerprint of the public part of the pre-params: the Paillier
+// modulus, NTilde, h1 and h2.
+func (preParams LocalPreParams) Fingerprint() []byte {
+	return auxFingerprint(preParams.PaillierSK.N, preParams.NTildei, preParams.H1i, preParams.H2i)
+}
+
+func auxFingerprint(paillierN, NTilde, h1, h2 *big.Int) []byte {
+	return common.SHA512_256i(paillierN, NTilde, h1, h2).Bytes()
+}
+
+// Forget removes the auxiliary info of the party from the cache.
+func (c *AuxInfoCache) Forget(id *tss.PartyID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.Peers, hex.EncodeToString(id.Key))
+}
+
+// MarshalJSON returns the json encoding of the cache.
+func (c *AuxInfoCache) MarshalJSON() ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return json.Marshal(map[string]any{"Peers": c.Peers})
+}
+
+// UnmarshalJSON loads the cache from its json encoding.
+func (c *AuxInfoCache) UnmarshalJSON(data []byte) error {
+	var v struct{ Peers map[string]*AuxInfo }
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Peers == nil {
+		v.Peers = make(map[string]*AuxInfo)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Peers = v.Peers
+	return nil
+}
+
+// lookup returns the auxiliary info of the party with the given fingerprint, or nil if it is
+// not in the cache.
+func (c *AuxInfoCache) lookup(id *tss.PartyID, fingerprint []byte) *AuxInfo {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	info := c.Peers[hex.EncodeToString(id.Key)]
+	if info == nil || !bytes.Equal(info.Fingerprint, fingerprint) {
+		return nil
+	}
+	return info
+}
+
+// paired returns true if the auxiliary info of fingerprint of the party was verified, in a
+// session where this party had the pre-params of fingerprint own. The Paillier moduli of both
+// were then proven against the range proof parameters of the other.
+func (c *AuxInfoCache) paired(id *tss.PartyID, fingerprint, own []byte) bool {
+	info := c.lookup(id, fingerprint)
+	return info != nil && bytes.Equal(info.Verifier, own)
+}
+
+// known returns true if all the parties verified the pre-params of fingerprint own of this
+// party, so that sending them again can be skipped.
+func (c *AuxInfoCache) known(ids []*tss.PartyID, own []byte) bool {
+	if c == nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, id := range ids {
+		info := c.Peers[hex.EncodeToString(id.Key)]
+		if info == nil || !bytes.Equal(info.Verifier, own) {
+			return false
+		}
+	}
+	return true
+}
+
+// store saves the auxiliary info of the party, verified in a session where this party had
+// the pre-params of fingerprint own.
+func (c *AuxInfoCache) store(id *tss.PartyID, paillierN, NTilde, h1, h2 *big.Int, own []byte) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.Peers == nil {
+		c.Peers = make(map[string]*AuxInfo)
+	}
+	c.Peers[hex.EncodeToString(id.Key)] = &AuxInfo{
+		Fingerprint: auxFingerprint(paillierN, NTilde, h1, h2),
+		PaillierN:   paillierN,
+		NTilde:      NTilde,
+		H1:          h1,
+		H2:          h2,
+		Verifier:    own,
+	}
+}
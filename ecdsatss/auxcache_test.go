@@ -0,0 +1,176 @@
+package ecdsatss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// startCachedKeygens starts a keygen of the parties with the pre-params of the fixtures and
+// their caches.
+func startCachedKeygens(t *testing.T, ctx context.Context, fixtures []*Key, pIDs tss.SortedPartyIDs, caches []*AuxInfoCache) []*Keygen {
+	const threshold = 1
+	hub := newTestHub(len(pIDs))
+	p2pCtx := tss.NewPeerContext(pIDs)
+	keygens := make([]*Keygen, len(pIDs))
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, len(pIDs), threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygenWithAuxInfoCache(ctx, params, caches[i], fixtures[i].LocalPreParams)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	return keygens
+}
+
+func TestKeygenWithAuxInfoCache(t *testing.T) {
+	const partyCount = 3
+
+	fixtures, pIDs := loadTestKeys(t, partyCount)
+	caches := make([]*AuxInfoCache, partyCount)
+	for i := range caches {
+		caches[i] = NewAuxInfoCache()
+	}
+
+	for session := 0; session < 2; session++ {
+		keygens := startCachedKeygens(t, context.Background(), fixtures, pIDs, caches)
+		keys := make([]*Key, partyCount)
+		for i, kg := range keygens {
+			select {
+			case keys[i] = <-kg.Done:
+			case err := <-kg.Err:
+				t.Fatalf("Party %d keygen error: %v", i, err)
+			case <-time.After(5 * time.Minute):
+				t.Fatalf("Party %d keygen timed out", i)
+			}
+			// the auxiliary info is only sent in the first session
+			assert.Equal(t, session > 0, kg.auxLite)
+		}
+
+		shares := make(vss.Shares, partyCount)
+		for i, key := range keys {
+			assert.True(t, keys[0].ECDSAPub.Equals(key.ECDSAPub))
+			for j := range fixtures {
+				assert.Equal(t, 0, key.NTildej[j].Cmp(fixtures[j].NTildei))
+				assert.Equal(t, 0, key.PaillierPKs[j].N.Cmp(fixtures[j].PaillierSK.N))
+			}
+			shares[i] = &vss.Share{Threshold: 1, ID: key.ShareID, Share: key.Xi}
+		}
+		secret, err := shares.ReConstruct(tss.S256())
+		require.NoError(t, err)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), secret).Equals(keys[0].ECDSAPub))
+
+		// the caches are persisted between the sessions
+		for i, cache := range caches {
+			assert.Len(t, cache.Peers, partyCount-1)
+			bz, err := json.Marshal(cache)
+			require.NoError(t, err)
+			caches[i] = new(AuxInfoCache)
+			require.NoError(t, json.Unmarshal(bz, caches[i]))
+		}
+	}
+
+	// a party which lost its cache cannot use the auxiliary info omitted by the others
+	for _, Pj := range pIDs {
+		caches[0].Forget(Pj)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keygens := startCachedKeygens(t, ctx, fixtures, pIDs, caches)
+	select {
+	case <-keygens[0].Done:
+		t.Fatal("keygen should fail")
+	case err := <-keygens[0].Err:
+		assert.True(t, errors.Is(err, ErrAuxInfoUnknown), "unexpected error: %v", err)
+		// the first party whose auxiliary info is missing is blamed
+		var tssErr *tss.Error
+		require.True(t, errors.As(err, &tssErr))
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[1].Id, tssErr.Culprits()[0].Id)
+	case <-time.After(5 * time.Minute):
+		t.Fatal("keygen timed out")
+	}
+}
+
+func TestResharingWithAuxInfoCache(t *testing.T) {
+	const (
+		oldPartyCount = 3
+		oldThreshold  = 2
+		newPartyCount = 3
+		newThreshold  = 1
+	)
+
+	newPIDs := generateOffsetTestPartyIDs(newPartyCount, 5)
+	newP2pCtx := tss.NewPeerContext(newPIDs)
+	caches := make([]*AuxInfoCache, newPartyCount)
+	for i := range caches {
+		caches[i] = NewAuxInfoCache()
+	}
+
+	for session := 0; session < 2; session++ {
+		// resharing zeroes the shares of the old committee, which are loaded again
+		oldKeys, oldPIDs := loadTestKeys(t, oldPartyCount)
+		oldP2pCtx := tss.NewPeerContext(oldPIDs)
+		hub := newResharingHub(append(append([]*tss.PartyID{}, oldPIDs...), newPIDs...))
+
+		resharings := make([]*Resharing, 0, oldPartyCount+newPartyCount)
+		for i, p := range oldPIDs {
+			params := tss.NewReSharingParameters(tss.S256(), oldP2pCtx, newP2pCtx, p, oldPartyCount, oldThreshold, newPartyCount, newThreshold)
+			params.SetBroker(hub.brokerFor(p))
+
+			rs, err := NewResharing(context.Background(), params, oldKeys[i])
+			require.NoError(t, err)
+			resharings = append(resharings, rs)
+		}
+		for i, p := range newPIDs {
+			params := tss.NewReSharingParameters(tss.S256(), oldP2pCtx, newP2pCtx, p, oldPartyCount, oldThreshold, newPartyCount, newThreshold)
+			params.SetBroker(hub.brokerFor(p))
+
+			// the fixtures of the old committee provide the pre-params of the new one
+			rs, err := NewResharingWithAuxInfoCache(context.Background(), params, nil, caches[i], oldKeys[i].LocalPreParams)
+			require.NoError(t, err)
+			resharings = append(resharings, rs)
+		}
+
+		newKeys := make([]*Key, newPartyCount)
+		for i, rs := range resharings {
+			select {
+			case k := <-rs.Done:
+				if i >= oldPartyCount {
+					newKeys[i-oldPartyCount] = k
+					// the auxiliary info is only sent in the first session
+					assert.Equal(t, session > 0, rs.auxLite)
+				}
+			case err := <-rs.Err:
+				t.Fatalf("Party %d resharing error: %v", i, err)
+			case <-time.After(5 * time.Minute):
+				t.Fatalf("Party %d resharing timed out", i)
+			}
+		}
+
+		shares := make(vss.Shares, newPartyCount)
+		for i, key := range newKeys {
+			assert.True(t, oldKeys[0].ECDSAPub.Equals(key.ECDSAPub))
+			for j := range newKeys {
+				assert.Equal(t, 0, key.NTildej[j].Cmp(oldKeys[j].NTildei))
+			}
+			shares[i] = &vss.Share{Threshold: newThreshold, ID: key.ShareID, Share: key.Xi}
+		}
+		secret, err := shares.ReConstruct(tss.S256())
+		require.NoError(t, err)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), secret).Equals(oldKeys[0].ECDSAPub))
+		for _, cache := range caches {
+			assert.Len(t, cache.Peers, newPartyCount-1)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package ecdsatss
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// BatchKeygen tracks the generation of several independent keys in a single keygen session.
+type BatchKeygen struct {
+	Done chan []*Key
+	Err  chan error
+}
+
+// NewBatchKeygen runs a keygen producing count independent keys, sent on Done once all of
+// them are made. The rounds are those of NewKeygen: the Paillier keys and range proof
+// parameters of the parties, with their proofs, are sent and verified once and shared by all
+// the keys, while each message holds the VSS commitments and shares of every key.
+//
+// The keys only share the parameters used by the proofs of signing: their secrets are
+// unrelated, and each can be signed with, reshared or exported on its own.
+func NewBatchKeygen(ctx context.Context, params *tss.Parameters, count int, optionalPreParams ...LocalPreParams) (*BatchKeygen, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid number of keys %d", count)
+	}
+	kg := newKeygen(ctx, params, count, optionalPreParams...)
+	kg.batchDone = make(chan []*Key, 1)
+	if err := kg.start(); err != nil {
+		return nil, err
+	}
+	return &BatchKeygen{Done: kg.batchDone, Err: kg.Err}, nil
+}
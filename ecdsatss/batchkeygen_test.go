@@ -0,0 +1,95 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestBatchKeygen(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+		keyCount   = 3
+	)
+
+	// the pre-params of the fixtures avoid generating safe primes
+	fixtures, pIDs := loadTestKeys(t, partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*BatchKeygen, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewBatchKeygen(context.Background(), params, keyCount, fixtures[i].LocalPreParams)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([][]*Key, partyCount)
+	for i, kg := range keygens {
+		select {
+		case k := <-kg.Done:
+			keys[i] = k
+		case err := <-kg.Err:
+			t.Fatalf("Party %d batch keygen error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d batch keygen timed out", i)
+		}
+	}
+
+	for n := 0; n < keyCount; n++ {
+		shares := make(vss.Shares, partyCount)
+		for i := range keys {
+			require.Len(t, keys[i], keyCount)
+			assert.True(t, keys[0][n].ECDSAPub.Equals(keys[i][n].ECDSAPub))
+			assert.Equal(t, 0, keys[i][n].NTildej[i].Cmp(fixtures[i].NTildei))
+			shares[i] = &vss.Share{Threshold: threshold, ID: keys[i][n].ShareID, Share: keys[i][n].Xi}
+		}
+		secret, err := shares.ReConstruct(tss.S256())
+		require.NoError(t, err)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), secret).Equals(keys[0][n].ECDSAPub))
+		if n > 0 {
+			assert.False(t, keys[0][n-1].ECDSAPub.Equals(keys[0][n].ECDSAPub))
+		}
+	}
+
+	// a key of the batch signs on its own
+	msgHash := sha256.Sum256([]byte("hello world"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(signHub.brokers[i])
+
+		sg, err := keys[i][keyCount-1].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+	var sigData *SignatureData
+	for i, sg := range signings {
+		select {
+		case sigData = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+	pub := keys[0][keyCount-1].ECDSAPub
+	pk := ecdsa.PublicKey{Curve: tss.S256(), X: pub.X(), Y: pub.Y()}
+	assert.True(t, ecdsa.Verify(&pk, msgHash[:], new(big.Int).SetBytes(sigData.R), new(big.Int).SetBytes(sigData.S)))
+}
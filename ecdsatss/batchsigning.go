@@ -0,0 +1,412 @@
+package ecdsatss
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskBatchSigning is the task name reported in errors from BatchSigning.
+const TaskBatchSigning = "ecdsa-batch-signing"
+
+// BatchSigning tracks the threshold ECDSA signing of several messages with the same key and
+// committee. The rounds of Signing are run once, each message of a round holding the messages
+// of all the signatures, so that the number of messages does not depend on the batch size.
+type BatchSigning struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	sigs   []*Signing // state of the signing of each message
+
+	// synchronization for dual-message rounds
+	r1pending int32
+
+	// received message storage for round 1 (dual message)
+	r1msg1From []*tss.PartyID
+	r1msg1     []*signBatchMsg[signRound1msg1]
+	r1msg2From []*tss.PartyID
+	r1msg2     []*signBatchMsg[signRound1msg2]
+
+	Done chan []*SignatureData
+	Err  chan error
+}
+
+// NewBatchSigning creates a new BatchSigning of the hashed messages msgs and kicks off its
+// round 1. The signatures are sent on Done in the order of msgs once all of them are made.
+//
+// Each message is signed with its own nonces and MtA conversions, exactly as NewSigning
+// would, so that the computation grows linearly with the number of messages while the number
+// of messages exchanged per round stays the same. A failure of any signature fails the batch.
+func (key *Key) NewBatchSigning(ctx context.Context, msgs []*big.Int, params *tss.Parameters) (*BatchSigning, error) {
+	if len(msgs) == 0 {
+		return nil, errors.New("no message to sign")
+	}
+	subsetKey, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	b := &BatchSigning{
+		ctx:    ctx,
+		params: params,
+		sigs:   make([]*Signing, len(msgs)),
+		Done:   make(chan []*SignatureData, 1),
+		Err:    make(chan error, 1),
+	}
+	for n, msg := range msgs {
+		s := subsetKey.signingState(ctx, msg, params, TaskBatchSigning)
+		// distinct ssid for each signature, binding its proofs to it
+		s.ssidNonce = batchSSIDNonce(len(msgs), n)
+		// the signatures are computed concurrently, each from its own random stream
+		if s.rand, err = tss.RandStream(params.Rand()); err != nil {
+			return nil, err
+		}
+		b.sigs[n] = s
+	}
+	b.broker = tss.NewSessionBroker(params.Broker())
+	b.stop = context.AfterFunc(ctx, func() { b.fail(ctx.Err()) })
+	if err := b.round1(); err != nil {
+		b.release()
+		return nil, err
+	}
+	return b, nil
+}
+
+// batchSSIDNonce returns the ssid nonce of the signature of the n-th message of a batch of
+// size messages. It binds the batch domain, the batch size and n, so that the proofs of a
+// signature of a batch are valid neither in a plain Signing, whose nonce is 0, nor in another
+// batch.
+func batchSSIDNonce(size, n int) *big.Int {
+	return common.SHA512_256i(new(big.Int).SetBytes([]byte(TaskBatchSigning)), big.NewInt(int64(size)), big.NewInt(int64(n)))
+}
+
+func (b *BatchSigning) round1() error {
+	Pi := b.params.PartyID()
+	otherIds := b.params.Parties().IDs().Exclude(Pi)
+
+	r1m1s := make([][]*signRound1msg1, len(b.sigs))
+	r1m2 := &signBatchMsg[signRound1msg2]{Msgs: make([]*signRound1msg2, len(b.sigs))}
+	err := b.each(func(n int, s *Signing) error {
+		var err error
+		r1m1s[n], r1m2.Msgs[n], err = s.makeRound1msgs()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Send the P2P Alice init messages (round1-1)
+	for _, Pj := range otherIds {
+		r1m1 := &signBatchMsg[signRound1msg1]{Msgs: make([]*signRound1msg1, len(b.sigs))}
+		for n := range b.sigs {
+			r1m1.Msgs[n] = r1m1s[n][Pj.Index]
+		}
+		m := tss.JsonWrapPrivate(b.params.MsgType("ecdsa:batchsign:round1-1"), r1m1, Pi, Pj)
+		b.broker.Receive(m)
+	}
+
+	// Broadcast commitments (round1-2)
+	for _, Pj := range otherIds {
+		m := tss.JsonWrap(b.params.MsgType("ecdsa:batchsign:round1-2"), r1m2, Pi, Pj)
+		b.broker.Receive(m)
+	}
+
+	// Set pending counter for two incoming message types
+	atomic.StoreInt32(&b.r1pending, 2)
+
+	rcv1 := tss.NewJsonExpect[signBatchMsg[signRound1msg1]](b.params.MsgType("ecdsa:batchsign:round1-1"), otherIds, b.onR1msg1, b.timeout(1), tss.WithPrivate())
+	b.broker.Connect(b.params.MsgType("ecdsa:batchsign:round1-1"), rcv1)
+
+	rcv2 := tss.NewJsonExpect[signBatchMsg[signRound1msg2]](b.params.MsgType("ecdsa:batchsign:round1-2"), otherIds, b.onR1msg2, b.timeout(1), b.echo(1, "ecdsa:batchsign:round1-2", otherIds))
+	b.broker.Connect(b.params.MsgType("ecdsa:batchsign:round1-2"), rcv2)
+
+	return nil
+}
+
+func (b *BatchSigning) onR1msg1(from []*tss.PartyID, msgs []*signBatchMsg[signRound1msg1]) {
+	if b.ctx.Err() != nil {
+		b.fail(b.ctx.Err())
+		return
+	}
+	b.r1msg1From = from
+	b.r1msg1 = msgs
+	if atomic.AddInt32(&b.r1pending, -1) == 0 {
+		b.round2()
+	}
+}
+
+func (b *BatchSigning) onR1msg2(from []*tss.PartyID, msgs []*signBatchMsg[signRound1msg2]) {
+	if b.ctx.Err() != nil {
+		b.fail(b.ctx.Err())
+		return
+	}
+	b.r1msg2From = from
+	b.r1msg2 = msgs
+	if atomic.AddInt32(&b.r1pending, -1) == 0 {
+		b.round2()
+	}
+}
+
+func (b *BatchSigning) round2() {
+	if b.ctx.Err() != nil {
+		b.fail(b.ctx.Err())
+		return
+	}
+	r1m1s, err := splitBatch(b, 2, b.r1msg1From, b.r1msg1)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	r1m2s, err := splitBatch(b, 2, b.r1msg2From, b.r1msg2)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+
+	r2m := make([][]*signRound2msg, len(b.sigs))
+	err = b.each(func(n int, s *Signing) error {
+		s.r1msg1From, s.r1msg1 = b.r1msg1From, r1m1s[n]
+		s.r1msg2From, s.r1msg2 = b.r1msg2From, r1m2s[n]
+		var err error
+		r2m[n], err = s.makeRound2msgs()
+		return err
+	})
+	if err != nil {
+		b.fail(err)
+		return
+	}
+
+	// Send round 2 P2P messages
+	Pi := b.params.PartyID()
+	otherIds := b.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range otherIds {
+		r2msg := &signBatchMsg[signRound2msg]{Msgs: make([]*signRound2msg, len(b.sigs))}
+		for n := range b.sigs {
+			r2msg.Msgs[n] = r2m[n][Pj.Index]
+		}
+		m := tss.JsonWrapPrivate(b.params.MsgType("ecdsa:batchsign:round2"), r2msg, Pi, Pj)
+		b.broker.Receive(m)
+	}
+
+	rcv := tss.NewJsonExpect[signBatchMsg[signRound2msg]](b.params.MsgType("ecdsa:batchsign:round2"), otherIds, b.round3, b.timeout(2), tss.WithPrivate())
+	b.broker.Connect(b.params.MsgType("ecdsa:batchsign:round2"), rcv)
+}
+
+func (b *BatchSigning) round3(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound2msg]) {
+	r3msg, err := runBatchRound(b, 3, otherIds, msgs, (*Signing).makeRound3msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 3, r3msg, b.round4)
+}
+
+func (b *BatchSigning) round4(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound3msg]) {
+	r4msg, err := runBatchRound(b, 4, otherIds, msgs, (*Signing).makeRound4msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 4, r4msg, b.round5)
+}
+
+func (b *BatchSigning) round5(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound4msg]) {
+	r5msg, err := runBatchRound(b, 5, otherIds, msgs, func(s *Signing, otherIds []*tss.PartyID, r4msgs []*signRound4msg) (*signRound5msg, error) {
+		R, err := s.computeR(otherIds, r4msgs)
+		if err != nil {
+			return nil, err
+		}
+		return s.makeRound5msg(R)
+	})
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 5, r5msg, b.round6)
+}
+
+func (b *BatchSigning) round6(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound5msg]) {
+	r6msg, err := runBatchRound(b, 6, otherIds, msgs, (*Signing).makeRound6msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 6, r6msg, b.round7)
+}
+
+func (b *BatchSigning) round7(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound6msg]) {
+	r7msg, err := runBatchRound(b, 7, otherIds, msgs, (*Signing).makeRound7msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 7, r7msg, b.round8)
+}
+
+func (b *BatchSigning) round8(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound7msg]) {
+	r8msg, err := runBatchRound(b, 8, otherIds, msgs, (*Signing).makeRound8msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 8, r8msg, b.round9)
+}
+
+func (b *BatchSigning) round9(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound8msg]) {
+	r9msg, err := runBatchRound(b, 9, otherIds, msgs, (*Signing).makeRound9msg)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	broadcastBatch(b, 9, r9msg, b.finalize)
+}
+
+func (b *BatchSigning) finalize(otherIds []*tss.PartyID, msgs []*signBatchMsg[signRound9msg]) {
+	if b.ctx.Err() != nil {
+		b.fail(b.ctx.Err())
+		return
+	}
+	r9msgs, err := splitBatch(b, 10, otherIds, msgs)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	sigDatas := make([]*SignatureData, len(b.sigs))
+	err = b.each(func(n int, s *Signing) error {
+		sjs := make([]*big.Int, len(r9msgs[n]))
+		for k, r9msg := range r9msgs[n] {
+			sjs[k] = new(big.Int).SetBytes(r9msg.Si)
+		}
+		var err error
+		sigDatas[n], err = s.signature(10, sjs)
+		return err
+	})
+	if err != nil {
+		b.fail(err)
+		return
+	}
+
+	b.release()
+	b.Done <- sigDatas
+}
+
+// runBatchRound runs makeMsg for the signing of each message with its messages of the previous
+// round, and returns the batch of the resulting messages.
+func runBatchRound[In, Out any](b *BatchSigning, round int, otherIds []*tss.PartyID, msgs []*signBatchMsg[In], makeMsg func(*Signing, []*tss.PartyID, []*In) (*Out, error)) (*signBatchMsg[Out], error) {
+	if b.ctx.Err() != nil {
+		return nil, b.ctx.Err()
+	}
+	in, err := splitBatch(b, round, otherIds, msgs)
+	if err != nil {
+		return nil, err
+	}
+	out := &signBatchMsg[Out]{Msgs: make([]*Out, len(b.sigs))}
+	err = b.each(func(n int, s *Signing) error {
+		var err error
+		out.Msgs[n], err = makeMsg(s, otherIds, in[n])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// broadcastBatch broadcasts msg, the batch of the given round, and registers next as the
+// receiver of the batches of the other parties.
+func broadcastBatch[T any](b *BatchSigning, round int, msg *signBatchMsg[T], next func([]*tss.PartyID, []*signBatchMsg[T])) {
+	typ := "ecdsa:batchsign:round" + strconv.Itoa(round)
+	Pi := b.params.PartyID()
+	otherIds := b.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range otherIds {
+		m := tss.JsonWrap(b.params.MsgType(typ), msg, Pi, Pj)
+		b.broker.Receive(m)
+	}
+
+	rcv := tss.NewJsonExpect[signBatchMsg[T]](b.params.MsgType(typ), otherIds, next, b.timeout(round), b.echo(round, typ, otherIds))
+	b.broker.Connect(b.params.MsgType(typ), rcv)
+}
+
+// splitBatch returns the messages received from otherIds for the signing of each message, the
+// batches msgs holding one message per signing. The parties sending a batch of another size
+// are reported as culprits of the given round.
+func splitBatch[T any](b *BatchSigning, round int, otherIds []*tss.PartyID, msgs []*signBatchMsg[T]) ([][]*T, error) {
+	var culprits []*tss.PartyID
+	for k, msg := range msgs {
+		if len(msg.Msgs) != len(b.sigs) || slices.Contains(msg.Msgs, nil) {
+			culprits = append(culprits, otherIds[k])
+		}
+	}
+	if len(culprits) > 0 {
+		return nil, b.wrapError(round, errors.New("batch does not hold one message per signature"), culprits...)
+	}
+	split := make([][]*T, len(b.sigs))
+	for n := range b.sigs {
+		split[n] = make([]*T, len(msgs))
+		for k, msg := range msgs {
+			split[n][k] = msg.Msgs[n]
+		}
+	}
+	return split, nil
+}
+
+// each runs f concurrently for the signing of each message, and returns their errors joined.
+func (b *BatchSigning) each(f func(n int, s *Signing) error) error {
+	errs := make([]error, len(b.sigs))
+	var wg sync.WaitGroup
+	for n, s := range b.sigs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[n] = f(n, s)
+		}()
+	}
+	wg.Wait()
+	return tss.JoinErrors(errs...)
+}
+
+// fail reports err on Err and releases the receivers registered by this batch signing.
+func (b *BatchSigning) fail(err error) {
+	b.release()
+	select {
+	case b.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this batch signing from the broker.
+func (b *BatchSigning) release() {
+	b.stop()
+	b.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (b *BatchSigning) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(b.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		b.fail(b.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (b *BatchSigning) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !b.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(b.broker, b.params.MsgType(typ+":echo"), b.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		b.fail(b.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (b *BatchSigning) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskBatchSigning, round, b.params.PartyID(), culprits...)
+}
@@ -0,0 +1,115 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestBatchSigning(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+		batchSize   = 3
+	)
+
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	msgs := make([]*big.Int, batchSize)
+	for n := range msgs {
+		msgHash := sha256.Sum256([]byte(fmt.Sprintf("withdrawal %d", n)))
+		msgs[n] = new(big.Int).SetBytes(msgHash[:])
+	}
+
+	hub := newTestHub(signerCount)
+	batches := make([]*BatchSigning, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		b, err := keys[i].NewBatchSigning(context.Background(), msgs, params)
+		require.NoError(t, err)
+		batches[i] = b
+	}
+
+	sigDatas := make([][]*SignatureData, signerCount)
+	for i, b := range batches {
+		select {
+		case sds := <-b.Done:
+			sigDatas[i] = sds
+		case err := <-b.Err:
+			t.Fatalf("Party %d batch signing error: %v", i, err)
+		case <-time.After(10 * time.Minute):
+			t.Fatalf("Party %d batch signing timed out", i)
+		}
+	}
+
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     keys[0].ECDSAPub.X(),
+		Y:     keys[0].ECDSAPub.Y(),
+	}
+	for i := range sigDatas {
+		require.Len(t, sigDatas[i], batchSize)
+	}
+	for n, msg := range msgs {
+		for i := 1; i < signerCount; i++ {
+			assert.Equal(t, sigDatas[0][n].Signature, sigDatas[i][n].Signature)
+		}
+		assert.Equal(t, msg.Bytes(), sigDatas[0][n].M)
+		r := new(big.Int).SetBytes(sigDatas[0][n].R)
+		s := new(big.Int).SetBytes(sigDatas[0][n].S)
+		assert.True(t, ecdsa.Verify(&pk, msg.Bytes(), r, s), "signature %d should verify", n)
+		if n > 0 {
+			// each signature has its own nonce
+			assert.NotEqual(t, sigDatas[0][n-1].R, sigDatas[0][n].R)
+		}
+	}
+}
+
+func TestBatchSigningSSID(t *testing.T) {
+	keys, pIDs := loadTestKeys(t, 3)
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], 3, 2)
+	key, err := keys[0].SubsetForParties(pIDs)
+	require.NoError(t, err)
+
+	ssid := func(nonce *big.Int) string {
+		s := key.signingState(context.Background(), big.NewInt(42), params, TaskSigning)
+		if nonce != nil {
+			s.ssidNonce = nonce
+		}
+		ssid, err := s.getSSID()
+		require.NoError(t, err)
+		return string(ssid)
+	}
+
+	// the first signature of a batch, of batches of different sizes and a plain signing
+	ssids := []string{ssid(nil), ssid(batchSSIDNonce(1, 0)), ssid(batchSSIDNonce(2, 0)), ssid(batchSSIDNonce(2, 1))}
+	for n := range ssids {
+		for m := n + 1; m < len(ssids); m++ {
+			assert.NotEqual(t, ssids[n], ssids[m], "ssids %d and %d", n, m)
+		}
+	}
+}
+
+func TestBatchSigningInvalidMessages(t *testing.T) {
+	keys, pIDs := loadTestKeys(t, 3)
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], 3, 2)
+	params.SetBroker(newTestHub(3).brokers[0])
+
+	_, err := keys[0].NewBatchSigning(context.Background(), nil, params)
+	assert.Error(t, err)
+
+	_, err = keys[0].NewBatchSigning(context.Background(), []*big.Int{big.NewInt(1), tss.S256().Params().N}, params)
+	assert.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// signOnCurve signs msgHash over ec with the keys of the parties of ids, and returns the
+// signature.
+func signOnCurve(t *testing.T, ec elliptic.Curve, keys []*Key, ids tss.SortedPartyIDs, threshold int, msgHash []byte) *SignatureData {
+	msg := new(big.Int).SetBytes(msgHash)
+	signCtx := tss.NewPeerContext(ids)
+	hub := newTestHub(len(ids))
+	signings := make([]*Signing, len(ids))
+	for n, p := range ids {
+		params := tss.NewParameters(ec, signCtx, p, len(ids), threshold)
+		params.SetBroker(hub.brokers[n])
+
+		sg, err := keys[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	return sig
+}
+
+func TestKeygenSignAndImportOnCurves(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	// a curve known only by its parameters, computed by the generic implementation
+	generic := *elliptic.P256().Params()
+	generic.Name = "P-256 generic"
+	tss.RegisterCurve("p256-generic", &generic)
+	t.Cleanup(func() { tss.UnregisterCurve("p256-generic") })
+
+	fixtures, _ := loadTestKeys(t, partyCount)
+	for _, ec := range []elliptic.Curve{tss.P256(), elliptic.P384(), &generic} {
+		t.Run(ec.Params().Name, func(t *testing.T) {
+			pIDs := tss.GenerateTestPartyIDs(partyCount)
+			p2pCtx := tss.NewPeerContext(pIDs)
+			hub := newTestHub(partyCount)
+			keygens := make([]*Keygen, partyCount)
+			for n, p := range pIDs {
+				params := tss.NewParameters(ec, p2pCtx, p, partyCount, threshold)
+				params.SetBroker(hub.brokers[n])
+				params.SetNoProofMod()
+				params.SetNoProofFac()
+
+				kg, err := NewKeygen(context.Background(), params, fixtures[n].LocalPreParams)
+				require.NoError(t, err)
+				keygens[n] = kg
+			}
+			keys := make([]*Key, partyCount)
+			for n, kg := range keygens {
+				select {
+				case keys[n] = <-kg.Done:
+				case err := <-kg.Err:
+					t.Fatalf("Party %d keygen error: %v", n, err)
+				case <-time.After(5 * time.Minute):
+					t.Fatalf("Party %d keygen timed out", n)
+				}
+			}
+
+			// the key keeps its curve through JSON
+			buf, err := json.Marshal(keys[0])
+			require.NoError(t, err)
+			var decoded *Key
+			require.NoError(t, json.Unmarshal(buf, &decoded))
+			assert.Equal(t, ec, decoded.ECDSAPub.Curve())
+			assert.True(t, keys[0].ECDSAPub.Equals(decoded.ECDSAPub))
+
+			msgHash := sha256.Sum256([]byte("curves"))
+			sig := signOnCurve(t, ec, []*Key{keys[0], keys[2]}, subsetIDs(pIDs, 0, 2), threshold, msgHash[:])
+			pk := keys[0].ECDSAPub.ToECDSAPubKey()
+			assert.True(t, ecdsa.Verify(pk, msgHash[:], new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+			assert.Len(t, sig.R, (ec.Params().BitSize+7)/8)
+
+			// an existing key is imported, reshared to the committee and signed with
+			priv, err := ecdsa.GenerateKey(ec, rand.Reader)
+			require.NoError(t, err)
+			oldIDs := generateOffsetTestPartyIDs(1, partyCount)
+			oldKey, err := ImportKey(priv, oldIDs[0])
+			require.NoError(t, err)
+			oldCtx := tss.NewPeerContext(oldIDs)
+			allIDs := append(append([]*tss.PartyID{}, oldIDs...), pIDs...)
+			reshareHub := newResharingHub(allIDs)
+			resharings := make([]*Resharing, len(allIDs))
+			for n, p := range allIDs {
+				params := tss.NewReSharingParameters(ec, oldCtx, p2pCtx, p, 1, 0, partyCount, threshold)
+				params.SetNoProofMod()
+				params.SetNoProofFac()
+				params.SetBroker(reshareHub.brokerFor(p))
+
+				if n == 0 {
+					resharings[n], err = NewResharing(context.Background(), params, oldKey)
+				} else {
+					resharings[n], err = NewResharing(context.Background(), params, nil, fixtures[n-1].LocalPreParams)
+				}
+				require.NoError(t, err)
+			}
+			newKeys := make([]*Key, partyCount)
+			for n, rs := range resharings {
+				select {
+				case key := <-rs.Done:
+					if n > 0 {
+						newKeys[n-1] = key
+					}
+				case err := <-rs.Err:
+					t.Fatalf("Party %d resharing error: %v", n, err)
+				case <-time.After(5 * time.Minute):
+					t.Fatalf("Party %d resharing timed out", n)
+				}
+			}
+			sig = signOnCurve(t, ec, newKeys[1:], subsetIDs(pIDs, 1, 2), threshold, msgHash[:])
+			assert.True(t, ecdsa.Verify(&priv.PublicKey, msgHash[:], new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+		})
+	}
+}
+
+// TestSigningRejectsOffCurveDecommitment checks that a signer decommitting to a point that is
+// not on P-256 in round 8 is reported as a culprit, instead of making crypto/elliptic panic.
+func TestSigningRejectsOffCurveDecommitment(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	ec := tss.P256()
+
+	fixtures, _ := loadTestKeys(t, partyCount)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	keygens := make([]*Keygen, partyCount)
+	for n, p := range pIDs {
+		params := tss.NewParameters(ec, p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[n])
+		params.SetNoProofMod()
+		params.SetNoProofFac()
+
+		kg, err := NewKeygen(context.Background(), params, fixtures[n].LocalPreParams)
+		require.NoError(t, err)
+		keygens[n] = kg
+	}
+	keys := make([]*Key, partyCount)
+	for n, kg := range keygens {
+		select {
+		case keys[n] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("Party %d keygen error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d keygen timed out", n)
+		}
+	}
+
+	// party 1 commits in round 7 to Uj = Tj = (1, 1), and decommits to it in round 8
+	bad := cmts.NewHashCommitment(rand.Reader, big.NewInt(1), big.NewInt(1), big.NewInt(1), big.NewInt(1))
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for n, p := range pIDs {
+		params := tss.NewParameters(ec, p2pCtx, p, partyCount, threshold)
+		params.SetBroker(signHub.brokers[n])
+		if n == 1 {
+			params.SetBroker(&tamperBroker{hubBroker: signHub.brokers[n], typ: "ecdsa:sign:round", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				switch msg.Data.(type) {
+				case *signRound7msg:
+					return tss.JsonWrap(msg.Type, &signRound7msg{Commitment: bad.C.Bytes()}, msg.From, msg.To)
+				case *signRound8msg:
+					return tss.JsonWrap(msg.Type, &signRound8msg{DeCommitment: common.BigIntsToBytes(bad.D)}, msg.From, msg.To)
+				}
+				return msg
+			}})
+		}
+
+		sg, err := keys[n].NewSigning(context.Background(), testMessage("off curve"), params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	for _, i := range []int{0, 2} {
+		tssErr := waitSigningError(t, i, signings[i])
+		assert.Equal(t, 9, tssErr.Round())
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[1].Id, tssErr.Culprits()[0].Id)
+	}
+}
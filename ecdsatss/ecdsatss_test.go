@@ -141,6 +141,12 @@ func (b *hubBroker) Connect(typ string, dest tss.MessageReceiver) {
 	}
 }
 
+func (b *hubBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
 func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
 	if msg.From.Index == b.partyIdx {
 		if msg.To != nil {
@@ -670,6 +676,12 @@ func (rb *resharingBroker) Connect(typ string, dest tss.MessageReceiver) {
 	}
 }
 
+func (rb *resharingBroker) Disconnect(typ string) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	delete(rb.handlers, typ)
+}
+
 func (rb *resharingBroker) Receive(msg *tss.JsonMessage) error {
 	// If from self, route to destination
 	if msg.From.KeyInt().Cmp(rb.keyInt) == 0 {
@@ -0,0 +1,409 @@
+package ecdsatss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/facproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskEnrollment is the task name reported in errors from Enrollment.
+const TaskEnrollment = "ecdsa-enrollment"
+
+// Enrollment tracks the enrollment of a new party in a key. At least t+1 parties of the key,
+// the holders, give the newcomer a share at its ShareID on the polynomial of the key, as done
+// by Repair for a lost share, without changing their own shares, pre-params or the threshold.
+// The newcomer sends its Paillier key and range proof parameters with the same proofs as in
+// keygen.
+//
+// The holders add the newcomer to their key, its public share being interpolated from the
+// public shares of the key. The other parties of the key add it from a PartyAnnouncement made
+// by the newcomer, see Key.Announce and Key.ImportParty.
+type Enrollment struct {
+	ctx      context.Context
+	params   *tss.Parameters
+	broker   *tss.SessionBroker
+	stop     func() bool
+	ssid     []byte
+	key      *Key // key of the holder, nil for the newcomer
+	newcomer *tss.PartyID
+	holders  tss.SortedPartyIDs
+	part     *big.Int // part of the Lagrange term of this holder kept by it
+	data     *Key
+
+	// Paillier key and range proof parameters of the newcomer
+	paillierPK     *paillier.PublicKey
+	nTilde, h1, h2 *big.Int
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewEnrollment creates a new Enrollment of the party newcomer, and executes round 1. The
+// parties of params are the holders and the newcomer, whose ShareID is the key of its PartyID.
+// The holders give their key, and the newcomer a nil key and its pre-params; all of them
+// receive their key including the newcomer on Done.
+func NewEnrollment(ctx context.Context, params *tss.Parameters, key *Key, newcomer *tss.PartyID, optionalPreParams ...LocalPreParams) (*Enrollment, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key != nil && key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key != nil && key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	newcomer = params.Parties().IDs().FindByKey(newcomer.KeyInt())
+	if newcomer == nil {
+		return nil, errors.New("the newcomer must take part in the enrollment")
+	}
+	holders := params.Parties().IDs().Exclude(newcomer)
+	if len(holders) <= params.Threshold() {
+		return nil, fmt.Errorf("enrollment needs at least %d holders, got %d", params.Threshold()+1, len(holders))
+	}
+	isNew := params.PartyID().KeyInt().Cmp(newcomer.KeyInt()) == 0
+	if isNew != (key == nil) {
+		return nil, errors.New("the newcomer, and only it, must not give a key")
+	}
+	e := &Enrollment{
+		ctx:      ctx,
+		params:   params,
+		key:      key,
+		newcomer: newcomer,
+		holders:  holders,
+		Done:     make(chan *Key, 1),
+		Err:      make(chan error, 1),
+	}
+	if isNew {
+		if len(optionalPreParams) == 0 || !optionalPreParams[0].ValidateWithProof() {
+			return nil, errors.New("the newcomer must give its pre-params, including the values needed for the proofs")
+		}
+		e.data = &Key{LocalPreParams: optionalPreParams[0]}
+	}
+	e.ssid = e.getSSID()
+	e.broker = tss.NewSessionBroker(params.Broker())
+	e.stop = context.AfterFunc(ctx, func() { e.fail(ctx.Err()) })
+	var err error
+	if isNew {
+		err = e.round1New()
+	} else {
+		err = e.round1()
+	}
+	if err != nil {
+		e.release()
+		return nil, err
+	}
+	return e, nil
+}
+
+// getSSID returns the ssid of the enrollment, binding the curve and the parties.
+func (e *Enrollment) getSSID() []byte {
+	ec := e.params.EC()
+	ssidList := []*big.Int{ec.Params().P, ec.Params().N, ec.Params().B, ec.Params().Gx, ec.Params().Gy}
+	ssidList = append(ssidList, e.params.Parties().IDs().Keys()...)
+	ssidList = append(ssidList, e.newcomer.KeyInt())
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(TaskEnrollment)))
+	return common.SHA512_256i(ssidList...).Bytes()
+}
+
+// round1 splits the Lagrange term of this holder in random parts, and sends them to the
+// other holders.
+func (e *Enrollment) round1() error {
+	Pi := e.params.PartyID()
+	q := e.params.EC().Params().N
+
+	for _, kj := range e.key.Ks {
+		if kj.Cmp(e.newcomer.KeyInt()) == 0 {
+			return errors.New("the newcomer is already a party of the key")
+		}
+	}
+	holdersKey, err := e.key.SubsetForParties(e.holders)
+	if err != nil {
+		return err
+	}
+	n := -1
+	for m, Pj := range e.holders {
+		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
+			n = m
+		}
+	}
+	parts, err := vss.LagrangeParts(e.params.Rand(), q, holdersKey.Ks, n, e.newcomer.KeyInt(), e.key.Xi)
+	if err != nil {
+		return err
+	}
+	e.part = parts[n]
+	for m, Pj := range e.holders {
+		if m == n {
+			continue
+		}
+		msg := &repairRound1msg1{Part: parts[m].Bytes()}
+		e.broker.Receive(tss.JsonWrapPrivate(e.params.MsgType("ecdsa:enroll:round1-1"), msg, Pi, Pj))
+	}
+
+	otherIds := e.holders.Exclude(Pi)
+	var pending int32 = 2
+	var r1msgs1 []*repairRound1msg1
+	var r1msg2 *repairRound1msg2
+	check := func() {
+		if atomic.AddInt32(&pending, -1) == 0 {
+			e.round2(otherIds, r1msgs1, r1msg2)
+		}
+	}
+	rcv1 := tss.NewJsonExpect[repairRound1msg1](e.params.MsgType("ecdsa:enroll:round1-1"), otherIds, func(_ []*tss.PartyID, msgs []*repairRound1msg1) {
+		r1msgs1 = msgs
+		check()
+	}, e.timeout(1), tss.WithPrivate())
+	e.broker.Connect(e.params.MsgType("ecdsa:enroll:round1-1"), rcv1)
+	rcv2 := tss.NewJsonExpect[repairRound1msg2](e.params.MsgType("ecdsa:enroll:round1-2"), []*tss.PartyID{e.newcomer}, func(_ []*tss.PartyID, msgs []*repairRound1msg2) {
+		r1msg2 = msgs[0]
+		check()
+	}, e.timeout(1), e.echo(1, "ecdsa:enroll:round1-2", otherIds))
+	e.broker.Connect(e.params.MsgType("ecdsa:enroll:round1-2"), rcv2)
+	return nil
+}
+
+// round1New sends the pre-params of the newcomer to the holders, with their proofs.
+func (e *Enrollment) round1New() error {
+	Pi := e.params.PartyID()
+
+	ContextI := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(Pi.Index)))
+	msg, err := newAuxInfoMsg(ContextI, e.data.LocalPreParams, e.params)
+	if err != nil {
+		return err
+	}
+	for _, Pj := range e.holders {
+		e.broker.Receive(tss.JsonWrap(e.params.MsgType("ecdsa:enroll:round1-2"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[repairRound2msg](e.params.MsgType("ecdsa:enroll:round2"), e.holders, e.round3, e.timeout(2), tss.WithPrivate())
+	e.broker.Connect(e.params.MsgType("ecdsa:enroll:round2"), rcv)
+	return nil
+}
+
+// round2 verifies the pre-params of the newcomer, and sends it the sum of the parts received
+// by this holder with the public data of the key.
+func (e *Enrollment) round2(otherIds []*tss.PartyID, r1msgs1 []*repairRound1msg1, r1msg2 *repairRound1msg2) {
+	if e.ctx.Err() != nil {
+		e.fail(e.ctx.Err())
+		return
+	}
+	Pi := e.params.PartyID()
+	ec := e.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+
+	ContextJ := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(e.newcomer.Index)))
+	var err error
+	e.paillierPK, e.nTilde, e.h1, e.h2, err = parseAuxInfoMsg(ContextJ, r1msg2, e.params)
+	if err != nil {
+		e.fail(e.wrapError(2, err, e.newcomer))
+		return
+	}
+
+	sum := e.part
+	for _, r1msg := range r1msgs1 {
+		sum = modQ.Add(sum, new(big.Int).SetBytes(r1msg.Part))
+	}
+	msg, err := newRepairRound2msg(e.key, sum)
+	if err != nil {
+		e.fail(e.wrapError(2, err))
+		return
+	}
+	if !e.params.NoProofFac() {
+		ContextI := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(Pi.Index)))
+		sk := e.key.PaillierSK
+		fp, err := facproof.NewProof(ContextI, ec, sk.N, e.nTilde, e.h1, e.h2, sk.P, sk.Q, e.params.Rand())
+		if err != nil {
+			e.fail(e.wrapError(2, fmt.Errorf("failed to generate fac proof: %w", err)))
+			return
+		}
+		bzs := fp.Bytes()
+		msg.FacProof = bzs[:]
+	}
+	e.broker.Receive(tss.JsonWrapPrivate(e.params.MsgType("ecdsa:enroll:round2"), msg, Pi, e.newcomer))
+
+	rcv := tss.NewJsonExpect[repairRound3msg](e.params.MsgType("ecdsa:enroll:round3"), []*tss.PartyID{e.newcomer}, e.round4, e.timeout(3), tss.WithPrivate())
+	e.broker.Connect(e.params.MsgType("ecdsa:enroll:round3"), rcv)
+}
+
+// round3 checks that the holders sent the same public data and verifies their proofs,
+// computes the share of the newcomer, and sends the proofs of its Paillier key to the
+// holders.
+func (e *Enrollment) round3(holders []*tss.PartyID, r2msgs []*repairRound2msg) {
+	if e.ctx.Err() != nil {
+		e.fail(e.ctx.Err())
+		return
+	}
+	Pi := e.params.PartyID()
+	ec := e.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+	pp := e.data.LocalPreParams
+
+	candidates := make([]*Key, len(r2msgs))
+	xi := big.NewInt(0)
+	for n, r2msg := range r2msgs {
+		candidate, err := repairPublicData(ec, r2msg)
+		if err != nil {
+			e.fail(e.wrapError(3, err, holders[n]))
+			return
+		}
+		candidates[n] = candidate
+		xi = modQ.Add(xi, new(big.Int).SetBytes(r2msg.Sum))
+	}
+	for _, candidate := range candidates[1:] {
+		if !candidates[0].samePublicData(candidate) {
+			// the holders outvoted by the others are the culprits, if there is a majority
+			e.fail(e.wrapError(3, errors.New("different public data of the key"), tss.MinorityCulprits(holders, candidates, (*Key).samePublicData)...))
+			return
+		}
+	}
+	key := candidates[0]
+	key.LocalPreParams = pp
+	key.Xi = xi
+	key.ShareID = Pi.KeyInt()
+	e.paillierPK = &pp.PaillierSK.PublicKey
+	e.nTilde, e.h1, e.h2 = pp.NTildei, pp.H1i, pp.H2i
+	data, err := e.withNewcomer(key)
+	if err != nil {
+		e.fail(e.wrapError(3, err))
+		return
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(data.BigXj[len(data.BigXj)-1]) {
+		e.fail(e.wrapError(3, errors.New("enrolled share does not match its public point")))
+		return
+	}
+	holdersKey, err := key.SubsetForParties(holders)
+	if err != nil {
+		e.fail(e.wrapError(3, err))
+		return
+	}
+
+	if !e.params.NoProofFac() {
+		var culprits []*tss.PartyID
+		for n, Pj := range holders {
+			ContextJ := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(Pj.Index)))
+			fp, err := facproof.NewProofFromBytes(r2msgs[n].FacProof)
+			if err != nil || !fp.Verify(ContextJ, ec, holdersKey.PaillierPKs[n].N, pp.NTildei, pp.H1i, pp.H2i) {
+				culprits = append(culprits, Pj)
+			}
+		}
+		if len(culprits) > 0 {
+			e.fail(e.wrapError(3, errors.New("fac proof verification failed"), culprits...))
+			return
+		}
+	}
+
+	ContextI := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(Pi.Index)))
+	for n, Pj := range holders {
+		msg := &repairRound3msg{}
+		if !e.params.NoProofFac() {
+			fp, err := facproof.NewProof(ContextI, ec, pp.PaillierSK.N, holdersKey.NTildej[n], holdersKey.H1j[n], holdersKey.H2j[n], pp.PaillierSK.P, pp.PaillierSK.Q, e.params.Rand())
+			if err != nil {
+				e.fail(e.wrapError(3, fmt.Errorf("failed to generate fac proof for party %s: %w", Pj, err)))
+				return
+			}
+			bzs := fp.Bytes()
+			msg.FacProof = bzs[:]
+		}
+		e.broker.Receive(tss.JsonWrapPrivate(e.params.MsgType("ecdsa:enroll:round3"), msg, Pi, Pj))
+	}
+
+	e.release()
+	e.Done <- data
+}
+
+// round4 verifies the proof of the Paillier key of the newcomer, and adds the newcomer to the
+// key.
+func (e *Enrollment) round4(_ []*tss.PartyID, r3msgs []*repairRound3msg) {
+	if e.ctx.Err() != nil {
+		e.fail(e.ctx.Err())
+		return
+	}
+	if !e.params.NoProofFac() {
+		ContextJ := common.AppendBigIntToBytesSlice(e.ssid, big.NewInt(int64(e.newcomer.Index)))
+		fp, err := facproof.NewProofFromBytes(r3msgs[0].FacProof)
+		if err != nil || !fp.Verify(ContextJ, e.params.EC(), e.paillierPK.N, e.key.NTildei, e.key.H1i, e.key.H2i) {
+			e.fail(e.wrapError(4, errors.New("fac proof verification failed"), e.newcomer))
+			return
+		}
+	}
+	data, err := e.withNewcomer(e.key)
+	if err != nil {
+		e.fail(e.wrapError(4, err))
+		return
+	}
+
+	e.release()
+	e.Done <- data
+}
+
+// withNewcomer returns a copy of key with the newcomer added as its last party.
+func (e *Enrollment) withNewcomer(key *Key) (*Key, error) {
+	return key.withParty(e.newcomer.KeyInt(), e.paillierPK, e.nTilde, e.h1, e.h2)
+}
+
+// withParty returns a copy of key with the party of identifier shareID added as its last
+// party, its public share being interpolated from the public shares of the key.
+func (key *Key) withParty(shareID *big.Int, paillierPK *paillier.PublicKey, nTilde, h1, h2 *big.Int) (*Key, error) {
+	if key.partyIndex(shareID) >= 0 {
+		return nil, errors.New("the newcomer is already a party of the key")
+	}
+	BigX, err := vss.InterpolatePublicShare(key.Ks, key.BigXj, shareID)
+	if err != nil {
+		return nil, err
+	}
+	data := *key
+	data.Ks = append(append([]*big.Int(nil), key.Ks...), shareID)
+	data.BigXj = append(append([]*crypto.ECPoint(nil), key.BigXj...), BigX)
+	data.PaillierPKs = append(append([]*paillier.PublicKey(nil), key.PaillierPKs...), paillierPK)
+	data.NTildej = append(append([]*big.Int(nil), key.NTildej...), nTilde)
+	data.H1j = append(append([]*big.Int(nil), key.H1j...), h1)
+	data.H2j = append(append([]*big.Int(nil), key.H2j...), h2)
+	return &data, nil
+}
+
+// fail reports err on Err and releases the receivers registered by this enrollment.
+func (e *Enrollment) fail(err error) {
+	e.release()
+	select {
+	case e.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this enrollment from the broker.
+func (e *Enrollment) release() {
+	e.stop()
+	e.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (e *Enrollment) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(e.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		e.fail(e.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (e *Enrollment) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !e.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(e.broker, e.params.MsgType(typ+":echo"), e.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		e.fail(e.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (e *Enrollment) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskEnrollment, round, e.params.PartyID(), culprits...)
+}
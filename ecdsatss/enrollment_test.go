@@ -0,0 +1,140 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestEnrollmentAndSign(t *testing.T) {
+	const (
+		partyCount  = 3
+		holderCount = 2
+		threshold   = 1
+	)
+
+	keys, pIDs := runKeygen(t, partyCount, threshold)
+	fixtures, _ := loadTestKeys(t, partyCount+1)
+
+	// parties 0 and 1 are holders and party 2 does not take part, the newcomer uses the
+	// pre-params of another fixture
+	newcomerKey := big.NewInt(0)
+	for _, kj := range keys[0].Ks {
+		if kj.Cmp(newcomerKey) > 0 {
+			newcomerKey = kj
+		}
+	}
+	newcomerKey = new(big.Int).Add(newcomerKey, big.NewInt(1))
+	unsorted := tss.UnSortedPartyIDs{tss.NewPartyID("new", "P[new]", newcomerKey)}
+	for _, p := range pIDs[:holderCount] {
+		unsorted = append(unsorted, tss.NewPartyID(p.Id, p.Moniker, p.KeyInt()))
+	}
+	enrollIDs := tss.SortPartyIDs(unsorted)
+	require.Equal(t, 0, enrollIDs[holderCount].KeyInt().Cmp(newcomerKey))
+
+	enrollCtx := tss.NewPeerContext(enrollIDs)
+	hub := newTestHub(len(enrollIDs))
+	enrollments := make([]*Enrollment, len(enrollIDs))
+	for n, p := range enrollIDs {
+		params := tss.NewParameters(tss.S256(), enrollCtx, p, len(enrollIDs), threshold)
+		params.SetBroker(hub.brokers[n])
+
+		var e *Enrollment
+		var err error
+		if n == holderCount {
+			e, err = NewEnrollment(context.Background(), params, nil, p, fixtures[partyCount].LocalPreParams)
+		} else {
+			e, err = NewEnrollment(context.Background(), params, keys[n], enrollIDs[holderCount])
+		}
+		require.NoError(t, err)
+		enrollments[n] = e
+	}
+	newKeys := make([]*Key, partyCount+1)
+	for n, e := range enrollments {
+		select {
+		case newKeys[n] = <-e.Done:
+		case err := <-e.Err:
+			t.Fatalf("Party %d enrollment error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d enrollment timed out", n)
+		}
+	}
+	newcomer := newKeys[holderCount]
+
+	// party 2 adds the newcomer from its announcement
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], partyCount, threshold)
+	ann, err := newcomer.Announce(params, keys[2].ShareID)
+	require.NoError(t, err)
+	newKeys[partyCount], err = keys[2].ImportParty(params, ann)
+	require.NoError(t, err)
+	require.Len(t, keys[2].Ks, partyCount, "the imported key is a copy")
+
+	shares := make(vss.Shares, 0, threshold+1)
+	for n, key := range newKeys {
+		require.Len(t, key.Ks, partyCount+1)
+		assert.True(t, keys[0].ECDSAPub.Equals(key.ECDSAPub))
+		for j := 0; j <= partyCount; j++ {
+			assert.Equal(t, 0, newcomer.Ks[j].Cmp(key.Ks[j]))
+			assert.True(t, newcomer.BigXj[j].Equals(key.BigXj[j]))
+			assert.Equal(t, 0, newcomer.NTildej[j].Cmp(key.NTildej[j]))
+			assert.Equal(t, 0, newcomer.PaillierPKs[j].N.Cmp(key.PaillierPKs[j].N))
+		}
+		if n < threshold+1 {
+			shares = append(shares, &vss.Share{Threshold: threshold, ID: key.ShareID, Share: key.Xi})
+		}
+	}
+	assert.True(t, crypto.ScalarBaseMult(tss.S256(), newcomer.Xi).Equals(newcomer.BigXj[partyCount]))
+	assert.Equal(t, 0, newcomer.NTildei.Cmp(newcomer.NTildej[partyCount]))
+
+	// the share of the newcomer is on the polynomial of the key
+	shares[0] = &vss.Share{Threshold: threshold, ID: newcomer.ShareID, Share: newcomer.Xi}
+	secret, err := shares.ReConstruct(tss.S256())
+	require.NoError(t, err)
+	assert.True(t, crypto.ScalarBaseMult(tss.S256(), secret).Equals(keys[0].ECDSAPub))
+
+	// the newcomer signs with the party which was not a holder
+	msgHash := sha256.Sum256([]byte("hello world"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	signers := []*Key{newKeys[partyCount], newcomer}
+	signIDs := tss.SortPartyIDs(tss.UnSortedPartyIDs{
+		tss.NewPartyID(pIDs[2].Id, pIDs[2].Moniker, pIDs[2].KeyInt()),
+		tss.NewPartyID("new", "P[new]", newcomerKey),
+	})
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.S256(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		sg, err := signers[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     keys[0].ECDSAPub.X(),
+		Y:     keys[0].ECDSAPub.Y(),
+	}
+	assert.True(t, ecdsa.Verify(&pk, msgHash[:], new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+}
@@ -0,0 +1,148 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// signHierarchical signs msgHash with the keys of the parties of ids, of the given ranks, and
+// checks the signature against pub.
+func signHierarchical(t *testing.T, keys []*Key, ids tss.SortedPartyIDs, ranks []int, threshold int, msgHash []byte, pub *crypto.ECPoint) {
+	msg := new(big.Int).SetBytes(msgHash)
+	signCtx := tss.NewPeerContext(ids)
+	hub := newTestHub(len(ids))
+	signings := make([]*Signing, len(ids))
+	for n, p := range ids {
+		params := tss.NewHierarchicalParameters(tss.S256(), signCtx, p, ranks, threshold)
+		params.SetBroker(hub.brokers[n])
+
+		sg, err := keys[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := ecdsa.PublicKey{Curve: tss.S256(), X: pub.X(), Y: pub.Y()}
+	assert.True(t, ecdsa.Verify(&pk, msgHash, new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+}
+
+func TestHierarchicalKeygenSignAndReshare(t *testing.T) {
+	const threshold = 2
+
+	// the compliance officer, party 0, has rank 0 and the operators rank 1
+	fixtures, _ := loadTestKeys(t, 5)
+	ranks := []int{0, 1, 1, 1}
+	pIDs := tss.GenerateTestPartyIDs(len(ranks))
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(len(pIDs))
+	keygens := make([]*Keygen, len(pIDs))
+	for n, p := range pIDs {
+		params := tss.NewHierarchicalParameters(tss.S256(), p2pCtx, p, ranks, threshold)
+		params.SetBroker(hub.brokers[n])
+		params.SetNoProofMod()
+		params.SetNoProofFac()
+
+		kg, err := NewKeygen(context.Background(), params, fixtures[n].LocalPreParams)
+		require.NoError(t, err)
+		keygens[n] = kg
+	}
+	keys := make([]*Key, len(pIDs))
+	for n, kg := range keygens {
+		select {
+		case keys[n] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("Party %d keygen error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d keygen timed out", n)
+		}
+	}
+	pub := keys[0].ECDSAPub
+	for n, key := range keys {
+		assert.True(t, pub.Equals(key.ECDSAPub))
+		assert.Equal(t, ranks, key.Ranks)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[n]))
+	}
+
+	// the officer signs with two operators, the operators cannot sign without it
+	msgHash := sha256.Sum256([]byte("hierarchical"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	signHierarchical(t, []*Key{keys[0], keys[2], keys[3]}, subsetIDs(pIDs, 0, 2, 3), []int{0, 1, 1}, threshold, msgHash[:], pub)
+	operatorIDs := subsetIDs(pIDs, 1, 2, 3)
+	params := tss.NewHierarchicalParameters(tss.S256(), tss.NewPeerContext(operatorIDs), operatorIDs[0], []int{1, 1, 1}, threshold)
+	_, err := keys[1].NewSigning(context.Background(), msg, params)
+	assert.ErrorIs(t, err, vss.ErrRanksNotSatisfied)
+
+	// ranks which are not those of the key are rejected, as are the operations which do not
+	// support hierarchical keys
+	params = tss.NewHierarchicalParameters(tss.S256(), tss.NewPeerContext(operatorIDs), operatorIDs[0], []int{0, 1, 1}, threshold)
+	_, err = keys[1].NewSigning(context.Background(), msg, params)
+	assert.Error(t, err)
+	_, err = keys[0].NewRefresh(context.Background(), tss.NewParameters(tss.S256(), p2pCtx, pIDs[0], len(pIDs), threshold))
+	assert.ErrorIs(t, err, ErrHierarchicalKey)
+
+	// the officer and two operators reshare to three new parties, two of them of rank 0
+	oldIDs := subsetIDs(pIDs, 0, 1, 2)
+	oldKeys := []*Key{keys[0], keys[1], keys[2]}
+	oldRanks := []int{0, 1, 1}
+	newIDs := generateOffsetTestPartyIDs(3, len(pIDs))
+	newRanks := []int{0, 0, 1}
+	newPreParams := []LocalPreParams{fixtures[4].LocalPreParams, fixtures[0].LocalPreParams, fixtures[1].LocalPreParams}
+	oldCtx, newCtx := tss.NewPeerContext(oldIDs), tss.NewPeerContext(newIDs)
+	allIDs := append(append([]*tss.PartyID{}, oldIDs...), newIDs...)
+	reshareHub := newResharingHub(allIDs)
+	var resharings []*Resharing
+	for n, p := range allIDs {
+		params := tss.NewHierarchicalReSharingParameters(tss.S256(), oldCtx, newCtx, p, oldRanks, threshold, newRanks, threshold)
+		params.SetNoProofMod()
+		params.SetNoProofFac()
+		params.SetBroker(reshareHub.brokerFor(p))
+
+		var rs *Resharing
+		if n < len(oldIDs) {
+			rs, err = NewResharing(context.Background(), params, oldKeys[n])
+		} else {
+			rs, err = NewResharing(context.Background(), params, nil, newPreParams[n-len(oldIDs)])
+		}
+		require.NoError(t, err)
+		resharings = append(resharings, rs)
+	}
+	newKeys := make([]*Key, len(newIDs))
+	for n, rs := range resharings {
+		select {
+		case key := <-rs.Done:
+			if n >= len(oldIDs) {
+				newKeys[n-len(oldIDs)] = key
+			}
+		case err := <-rs.Err:
+			t.Fatalf("Party %d resharing error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d resharing timed out", n)
+		}
+	}
+	for n, key := range newKeys {
+		assert.True(t, pub.Equals(key.ECDSAPub))
+		assert.Equal(t, newRanks, key.Ranks)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[n]))
+	}
+
+	signHierarchical(t, newKeys, newIDs, newRanks, threshold, msgHash[:], pub)
+}
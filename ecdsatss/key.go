@@ -2,6 +2,7 @@ package ecdsatss
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -25,6 +26,14 @@ type Key struct {
 	PaillierPKs []*paillier.PublicKey // pkj
 	// used for test assertions (may be discarded)
 	ECDSAPub *crypto.ECPoint // y
+
+	// weighted keys, where the party of index j holds 1+len(MoreKs[j]) shares: the other
+	// evaluation points of each party and the public shares at these points
+	MoreKs    [][]*big.Int        `json:",omitempty"`
+	MoreBigXj [][]*crypto.ECPoint `json:",omitempty"`
+
+	// hierarchical keys: the rank of the share of each party, see tss.NewHierarchicalParameters
+	Ranks []int `json:",omitempty"`
 }
 
 // NewKey creates a new Key with all slice fields initialized for the given party count.
@@ -49,6 +58,9 @@ type LocalPreParams struct {
 type LocalSecrets struct {
 	// secret fields (not shared, but stored locally)
 	Xi, ShareID *big.Int // xi, kj
+
+	// shares of a party of a weighted key at its other points, MoreKs[i]
+	MoreXi []*big.Int `json:",omitempty"`
 }
 
 // Validate returns true if the essential pre-parameters (Paillier key, NTilde, H1, H2) are non-nil.
@@ -71,8 +83,9 @@ func (preParams LocalPreParams) ValidateWithProof() bool {
 }
 
 // SubsetForParties returns a new Key whose per-party slice fields (Ks, NTildej, H1j, H2j,
-// BigXj, PaillierPKs) are reordered to match the given sorted party IDs. Parties are matched
-// by their ShareID — i.e. the Ks value stored by keygen, compared to PartyID.Key.
+// BigXj, PaillierPKs, MoreKs and MoreBigXj of a weighted key, and Ranks of a hierarchical key)
+// are reordered to match the given sorted party IDs. Parties are matched by their ShareID —
+// i.e. the Ks value stored by keygen, compared to PartyID.Key.
 //
 // This reindexing is required whenever the current party set is a strict subset of the
 // parties that participated in keygen (for example, a t+1 signing committee picked out of
@@ -82,6 +95,12 @@ func (preParams LocalPreParams) ValidateWithProof() bool {
 // The returned Key shares LocalPreParams, LocalSecrets, and ECDSAPub with the receiver;
 // only the per-party slices are rebuilt.
 func (key *Key) SubsetForParties(sortedIDs tss.SortedPartyIDs) (*Key, error) {
+	if key.MoreKs != nil && (len(key.MoreKs) != len(key.Ks) || len(key.MoreBigXj) != len(key.Ks)) {
+		return nil, errors.New("SubsetForParties: the other points of the parties do not match Ks")
+	}
+	if key.Ranks != nil && len(key.Ranks) != len(key.Ks) {
+		return nil, errors.New("SubsetForParties: the ranks of the parties do not match Ks")
+	}
 	keysToIndices := make(map[string]int, len(key.Ks))
 	for j, kj := range key.Ks {
 		keysToIndices[hex.EncodeToString(kj.Bytes())] = j
@@ -101,6 +120,20 @@ func (key *Key) SubsetForParties(sortedIDs tss.SortedPartyIDs) (*Key, error) {
 		subset.H2j[j] = key.H2j[savedIdx]
 		subset.BigXj[j] = key.BigXj[savedIdx]
 		subset.PaillierPKs[j] = key.PaillierPKs[savedIdx]
+		if key.MoreKs != nil {
+			if subset.MoreKs == nil {
+				subset.MoreKs = make([][]*big.Int, len(sortedIDs))
+				subset.MoreBigXj = make([][]*crypto.ECPoint, len(sortedIDs))
+			}
+			subset.MoreKs[j] = key.MoreKs[savedIdx]
+			subset.MoreBigXj[j] = key.MoreBigXj[savedIdx]
+		}
+		if key.Ranks != nil {
+			if subset.Ranks == nil {
+				subset.Ranks = make([]int, len(sortedIDs))
+			}
+			subset.Ranks[j] = key.Ranks[savedIdx]
+		}
 	}
 	return subset, nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 	"sync"
 	"sync/atomic"
 
@@ -21,18 +22,33 @@ import (
 
 var zero = big.NewInt(0)
 
+// TaskKeygen is the task name reported in errors from Keygen.
+const TaskKeygen = "ecdsa-keygen"
+
 // Keygen is an object used to track a key currently being generated
 type Keygen struct {
 	ctx           context.Context
 	params        *tss.Parameters // contains curve, parties, etc
+	broker        *tss.SessionBroker
+	stop          func() bool
 	KGCs          []cmts.HashCommitment
-	vs            vss.Vs
-	ssid          []byte // ssid for current round/values
+	vs            []vss.Vs // polynomial commitments, for each key
+	ssid          []byte   // ssid for current round/values
 	ssidNonce     *big.Int
-	shares        vss.Shares
+	shares        []vss.Shares   // shares of the other parties, for each key
+	moreShares    [][]vss.Shares // shares at the other points of each party of a weighted key, for each key
 	deCommitPolyG cmts.HashDeCommitment
-	data          *Key // key data currently being generated
-	round         int  // current round
+	data          *Key   // key data currently being generated, the first key of a batch
+	keys          []*Key // all the keys being generated, keys[0] being data
+	round         int    // current round
+
+	// batchDone receives the keys of a batch keygen, instead of Done
+	batchDone chan []*Key
+
+	auxCache  *AuxInfoCache // verified auxiliary info of the peers, nil if not used
+	auxLite   bool          // this party omits its auxiliary info, all the peers have it
+	auxFps    [][]byte      // fingerprint of the pre-params of each party
+	auxCached []bool        // the party omitted its auxiliary info, found in auxCache
 
 	ui        *big.Int // keep around for potential use (ECDSA does clear it though)
 	r2pending int32    // atomic counter for dual-message round 2
@@ -50,31 +66,72 @@ type Keygen struct {
 
 // NewKeygen creates a new Keygen instance and executes round 1 of the key generation protocol.
 func NewKeygen(ctx context.Context, params *tss.Parameters, optionalPreParams ...LocalPreParams) (*Keygen, error) {
+	res := newKeygen(ctx, params, 1, optionalPreParams...)
+	if err := res.start(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// NewKeygenWithAuxInfoCache is NewKeygen, with the Paillier keys and range proof parameters
+// of the peers found in cache reused instead of being sent and verified again. This party
+// also omits its own when all the peers have them in their cache, the proofs about them being
+// skipped. The auxiliary info verified by the keygen is added to cache once it completes.
+//
+// optionalPreParams should be the same in all the keygens using cache: the peers did not
+// verify other ones, which are sent again with their proofs.
+func NewKeygenWithAuxInfoCache(ctx context.Context, params *tss.Parameters, cache *AuxInfoCache, optionalPreParams ...LocalPreParams) (*Keygen, error) {
+	res := newKeygen(ctx, params, 1, optionalPreParams...)
+	res.auxCache = cache
+	if err := res.start(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// newKeygen returns a Keygen of count keys, which is not started.
+func newKeygen(ctx context.Context, params *tss.Parameters, count int, optionalPreParams ...LocalPreParams) *Keygen {
 	partyCount := params.PartyCount()
 	res := &Keygen{
-		ctx:    ctx,
-		params: params,
-		KGCs:   make([]cmts.HashCommitment, partyCount),
-		data:   NewKey(partyCount),
-		round:  1,
-		Done:   make(chan *Key, 1),
-		Err:    make(chan error, 1),
-	}
+		ctx:       ctx,
+		params:    params,
+		KGCs:      make([]cmts.HashCommitment, partyCount),
+		keys:      make([]*Key, count),
+		round:     1,
+		auxFps:    make([][]byte, partyCount),
+		auxCached: make([]bool, partyCount),
+		Done:      make(chan *Key, 1),
+		Err:       make(chan error, 1),
+	}
+	for n := range res.keys {
+		res.keys[n] = NewKey(partyCount)
+	}
+	res.data = res.keys[0]
+	res.broker = tss.NewSessionBroker(params.Broker())
 	if len(optionalPreParams) > 0 {
 		res.data.LocalPreParams = optionalPreParams[0]
 	}
-	err := res.round1()
-	if err != nil {
-		return nil, err
-	}
+	return res
+}
 
-	return res, nil
+// start executes round 1.
+func (kg *Keygen) start() error {
+	kg.stop = context.AfterFunc(kg.ctx, func() { kg.fail(kg.ctx.Err()) })
+	if err := kg.round1(); err != nil {
+		kg.release()
+		return err
+	}
+	return nil
 }
 
 // getSSID returns ssid from local params
 func (kg *Keygen) getSSID(roundNum int) ([]byte, error) {
 	ssidList := []*big.Int{kg.params.EC().Params().P, kg.params.EC().Params().N, kg.params.EC().Params().Gx, kg.params.EC().Params().Gy} // ec curve
 	ssidList = append(ssidList, kg.params.Parties().IDs().Keys()...)
+	ssidList = append(ssidList, vss.AllPoints(nil, kg.data.MoreKs)...) // other points of a weighted key
+	for _, r := range kg.data.Ranks {
+		ssidList = append(ssidList, big.NewInt(int64(r))) // ranks of a hierarchical key
+	}
 	ssidList = append(ssidList, big.NewInt(int64(roundNum))) // round number
 	ssidList = append(ssidList, kg.ssidNonce)
 	ssid := common.SHA512_256i(ssidList...).Bytes()
@@ -85,32 +142,48 @@ func (kg *Keygen) getSSID(roundNum int) ([]byte, error) {
 func (kg *Keygen) round1() error {
 	Pi := kg.params.PartyID()
 	i := Pi.Index
-	// 1. calculate "partial" key share ui
-	ui := common.GetRandomPositiveInt(kg.params.PartialKeyRand(), kg.params.EC().Params().N)
-
-	// 2. compute the vss shares
 	ids := kg.params.Parties().IDs().Keys()
-	vs, shares, err := vss.Create(kg.params.EC(), kg.params.Threshold(), ui, ids, kg.params.Rand())
-	if err != nil {
-		return err
-	}
-	kg.data.Ks = ids
+	moreKs := vss.WeightedPoints(kg.params.EC().Params().N, ids, kg.params.Weights())
+	ranks := vss.HierarchicalRanks(kg.params.Ranks())
+	kg.vs = make([]vss.Vs, len(kg.keys))
+	kg.shares = make([]vss.Shares, len(kg.keys))
+	kg.moreShares = make([][]vss.Shares, len(kg.keys))
+	var pGFlat []*big.Int
+	for n := range kg.keys {
+		// 1. calculate "partial" key share ui
+		ui := common.GetRandomPositiveInt(kg.params.PartialKeyRand(), kg.params.EC().Params().N)
+
+		// 2. compute the vss shares, at the other points of the parties too for a weighted key
+		vs, shares, err := vss.DealShares(kg.params.EC(), kg.params.Threshold(), ui, ids, moreKs, ranks, kg.params.Rand())
+		if err != nil {
+			return err
+		}
+		kg.vs[n] = vs
+		kg.shares[n] = shares
+		kg.moreShares[n] = vss.SplitShares(shares, moreKs)
 
-	// security: the original u_i may be discarded
-	ui = zero // clears the secret data from memory
-	_ = ui    // silences a linter warning
+		// security: the original u_i may be discarded
+		ui = zero // clears the secret data from memory
+		_ = ui    // silences a linter warning
 
-	// make commitment -> (C, D)
-	pGFlat, err := crypto.FlattenECPoints(vs)
-	if err != nil {
-		return err
+		vsFlat, err := crypto.FlattenECPoints(vs)
+		if err != nil {
+			return err
+		}
+		pGFlat = append(pGFlat, vsFlat...)
 	}
+	kg.data.Ks = ids
+	kg.data.MoreKs = moreKs
+	kg.data.Ranks = ranks
+
+	// make commitment -> (C, D), to the polynomials of all the keys
 	cmt := cmts.NewHashCommitment(kg.params.Rand(), pGFlat...)
 
 	// 4. generate Paillier public key E_i, private key and proof
 	// 5-7. generate safe primes for ZKPs used later on
 	// 9-11. compute ntilde, h1, h2 (uses safe primes)
 	var preParams *LocalPreParams
+	var err error
 	if kg.data.LocalPreParams.Validate() && !kg.data.LocalPreParams.ValidateWithProof() {
 		return errors.New("`optionalPreParams` failed to validate; it might have been generated with an older version of tss-lib")
 	} else if kg.data.LocalPreParams.ValidateWithProof() {
@@ -126,11 +199,10 @@ func (kg *Keygen) round1() error {
 	kg.data.LocalPreParams = *preParams
 	kg.data.NTildej[i] = preParams.NTildei
 	kg.data.H1j[i], kg.data.H2j[i] = preParams.H1i, preParams.H2i
+	kg.auxFps[i] = preParams.Fingerprint()
 
-	// generate the dlnproofs for keygen
-	h1i, h2i, alpha, beta, p, q, NTildei := preParams.H1i, preParams.H2i, preParams.Alpha, preParams.Beta, preParams.P, preParams.Q, preParams.NTildei
-	dlnProof1 := dlnproof.NewDLNProof(h1i, h2i, alpha, p, q, NTildei, kg.params.Rand())
-	dlnProof2 := dlnproof.NewDLNProof(h2i, h1i, beta, p, q, NTildei, kg.params.Rand())
+	otherIds := kg.params.Parties().IDs().Exclude(Pi)
+	kg.auxLite = kg.auxCache.known(otherIds, kg.auxFps[i])
 
 	// for this P: SAVE
 	// - shareID
@@ -139,13 +211,11 @@ func (kg *Keygen) round1() error {
 	// - our set of Shamir shares
 	kg.ssidNonce = new(big.Int).SetUint64(0)
 	kg.data.ShareID = ids[i]
-	kg.vs = vs
 	ssid, err := kg.getSSID(kg.round) // for round 1
 	if err != nil {
 		return errors.New("failed to generate ssid")
 	}
 	kg.ssid = ssid
-	kg.shares = shares
 
 	// for this P: SAVE de-commitments, paillier keys for round 2
 	kg.data.PaillierSK = preParams.PaillierSK
@@ -153,37 +223,36 @@ func (kg *Keygen) round1() error {
 	kg.deCommitPolyG = cmt.D
 
 	// send commitments, paillier pk + proof; round 1 message
-	dlnProof1Bz, err := dlnProof1.Serialize()
-	if err != nil {
-		return err
-	}
-	dlnProof2Bz, err := dlnProof2.Serialize()
-	if err != nil {
-		return err
-	}
 	msg := &keygenRound1msg{
 		Commitment: cmt.C.Bytes(),
-		PaillierN:  preParams.PaillierSK.PublicKey.N.Bytes(),
-		NTilde:     preParams.NTildei.Bytes(),
-		H1:         preParams.H1i.Bytes(),
-		H2:         preParams.H2i.Bytes(),
-		Dlnproof_1: dlnProof1Bz,
-		Dlnproof_2: dlnProof2Bz,
 	}
-
-	var otherIds []*tss.PartyID
-	for n, p := range kg.params.Parties().IDs() {
-		if n == i {
-			// do not send to self
-			continue
+	if kg.auxCache != nil {
+		msg.AuxFingerprint = kg.auxFps[i]
+	}
+	if !kg.auxLite {
+		// generate the dlnproofs for keygen
+		h1i, h2i, alpha, beta, p, q, NTildei := preParams.H1i, preParams.H2i, preParams.Alpha, preParams.Beta, preParams.P, preParams.Q, preParams.NTildei
+		dlnProof1 := dlnproof.NewDLNProof(h1i, h2i, alpha, p, q, NTildei, kg.params.Rand())
+		dlnProof2 := dlnproof.NewDLNProof(h2i, h1i, beta, p, q, NTildei, kg.params.Rand())
+		if msg.Dlnproof_1, err = dlnProof1.Serialize(); err != nil {
+			return err
 		}
-		otherIds = append(otherIds, p)
-		m := tss.JsonWrap("ecdsa:keygen:round1", msg, Pi, p)
-		kg.params.Broker().Receive(m)
+		if msg.Dlnproof_2, err = dlnProof2.Serialize(); err != nil {
+			return err
+		}
+		msg.PaillierN = preParams.PaillierSK.PublicKey.N.Bytes()
+		msg.NTilde = preParams.NTildei.Bytes()
+		msg.H1 = preParams.H1i.Bytes()
+		msg.H2 = preParams.H2i.Bytes()
+	}
+
+	for _, p := range otherIds {
+		m := tss.JsonWrap(kg.params.MsgType("ecdsa:keygen:round1"), msg, Pi, p)
+		kg.broker.Receive(m)
 	}
 
-	kg.Receiver = tss.NewJsonExpect[keygenRound1msg]("ecdsa:keygen:round1", otherIds, kg.round2)
-	kg.params.Broker().Connect("ecdsa:keygen:round1", kg.Receiver)
+	kg.Receiver = tss.NewJsonExpect[keygenRound1msg](kg.params.MsgType("ecdsa:keygen:round1"), otherIds, kg.round2, kg.timeout(1), kg.echo(1, "ecdsa:keygen:round1", otherIds))
+	kg.broker.Connect(kg.params.MsgType("ecdsa:keygen:round1"), kg.Receiver)
 
 	return nil
 }
@@ -191,7 +260,7 @@ func (kg *Keygen) round1() error {
 // round2 processes round 1 messages from other parties and executes round 2.
 func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 	if kg.ctx.Err() != nil {
-		kg.Err <- kg.ctx.Err()
+		kg.fail(kg.ctx.Err())
 		return
 	}
 	kg.round = 2
@@ -219,24 +288,38 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 
 	for k, r1msg := range r1msgs {
 		jIdx := partyIdxMap[k]
-		_ = jIdx
+		if len(r1msg.PaillierN) == 0 && len(r1msg.NTilde) == 0 && kg.auxCache != nil {
+			// the party omitted its auxiliary info, which must be in the cache
+			info := kg.auxCache.lookup(otherIds[k], r1msg.AuxFingerprint)
+			if info == nil {
+				kg.fail(kg.wrapError(2, ErrAuxInfoUnknown, otherIds[k]))
+				return
+			}
+			kg.auxCached[jIdx] = true
+			kg.auxFps[jIdx] = info.Fingerprint
+			kg.data.PaillierPKs[jIdx] = &paillier.PublicKey{N: info.PaillierN}
+			kg.data.NTildej[jIdx] = info.NTilde
+			kg.data.H1j[jIdx] = info.H1
+			kg.data.H2j[jIdx] = info.H2
+			continue
+		}
 
 		paillierPK := &paillier.PublicKey{N: new(big.Int).SetBytes(r1msg.PaillierN)}
 		if paillierPK.N.BitLen() < 2048 {
-			kg.Err <- fmt.Errorf("party %s: paillier modulus bit length %d < 2048", otherIds[k], paillierPK.N.BitLen())
+			kg.fail(kg.wrapError(2, fmt.Errorf("paillier modulus bit length %d < 2048", paillierPK.N.BitLen()), otherIds[k]))
 			return
 		}
 
 		NTildej := new(big.Int).SetBytes(r1msg.NTilde)
 		if NTildej.BitLen() < 2048 {
-			kg.Err <- fmt.Errorf("party %s: NTilde bit length %d < 2048", otherIds[k], NTildej.BitLen())
+			kg.fail(kg.wrapError(2, fmt.Errorf("NTilde bit length %d < 2048", NTildej.BitLen()), otherIds[k]))
 			return
 		}
 
 		H1j := new(big.Int).SetBytes(r1msg.H1)
 		H2j := new(big.Int).SetBytes(r1msg.H2)
 		if H1j.Cmp(H2j) == 0 {
-			kg.Err <- fmt.Errorf("party %s: H1j == H2j", otherIds[k])
+			kg.fail(kg.wrapError(2, errors.New("H1j == H2j"), otherIds[k]))
 			return
 		}
 
@@ -259,16 +342,24 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 	}
 	wg.Wait()
 
+	var culprits []*tss.PartyID
 	for k := range otherIds {
 		if dlnProof1Fail[k] || dlnProof2Fail[k] {
-			kg.Err <- fmt.Errorf("party %s: DLN proof verification failed", otherIds[k])
-			return
+			culprits = append(culprits, otherIds[k])
 		}
 	}
+	if len(culprits) > 0 {
+		kg.fail(kg.wrapError(2, errors.New("DLN proof verification failed"), culprits...))
+		return
+	}
 
 	// Store verified values from R1 messages
 	for k, r1msg := range r1msgs {
 		jIdx := partyIdxMap[k]
+		kg.KGCs[jIdx] = cmts.HashCommitment(new(big.Int).SetBytes(r1msg.Commitment))
+		if kg.auxCached[jIdx] {
+			continue
+		}
 
 		paillierPK := &paillier.PublicKey{N: new(big.Int).SetBytes(r1msg.PaillierN)}
 		NTildej := new(big.Int).SetBytes(r1msg.NTilde)
@@ -279,7 +370,7 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 		kg.data.NTildej[jIdx] = NTildej
 		kg.data.H1j[jIdx] = H1j
 		kg.data.H2j[jIdx] = H2j
-		kg.KGCs[jIdx] = cmts.HashCommitment(new(big.Int).SetBytes(r1msg.Commitment))
+		kg.auxFps[jIdx] = auxFingerprint(paillierPK.N, NTildej, H1j, H2j)
 	}
 
 	// Generate ContextI for proofs
@@ -289,13 +380,14 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 	for k, oid := range otherIds {
 		jIdx := partyIdxMap[k]
 
+		// the party already verified the proof when both had the same pre-params
 		var facProofBzs [][]byte
-		if !kg.params.NoProofFac() {
+		if !kg.params.NoProofFac() && !kg.auxCache.paired(oid, kg.auxFps[jIdx], kg.auxFps[i]) {
 			fp, err := facproof.NewProof(ContextI, ec, kg.data.PaillierSK.N,
 				kg.data.NTildej[jIdx], kg.data.H1j[jIdx], kg.data.H2j[jIdx],
 				kg.data.PaillierSK.P, kg.data.PaillierSK.Q, kg.params.Rand())
 			if err != nil {
-				kg.Err <- fmt.Errorf("failed to generate fac proof for party %s: %w", oid, err)
+				kg.fail(kg.wrapError(2, fmt.Errorf("failed to generate fac proof for party %s: %w", oid, err)))
 				return
 			}
 			bzArr := fp.Bytes()
@@ -304,23 +396,37 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 
 		// Find the share for this party: shares are indexed by position in
 		// allParties, so the share at jIdx corresponds to party allParties[jIdx].
-		shareBytes := kg.shares[jIdx].Share.Bytes()
+		shareBytes := kg.shares[0][jIdx].Share.Bytes()
+		var keyShares, pointShares [][]byte
+		for _, shares := range kg.shares[1:] {
+			keyShares = append(keyShares, shares[jIdx].Share.Bytes())
+		}
+		for _, moreShares := range kg.moreShares {
+			if moreShares == nil {
+				continue
+			}
+			for _, share := range moreShares[jIdx] {
+				pointShares = append(pointShares, share.Share.Bytes())
+			}
+		}
 
 		r2m1 := &keygenRound2msg1{
-			Share:    shareBytes,
-			FacProof: facProofBzs,
+			Share:      shareBytes,
+			FacProof:   facProofBzs,
+			Shares:     keyShares,
+			MoreShares: pointShares,
 		}
-		m := tss.JsonWrap("ecdsa:keygen:round2-1", r2m1, Pi, oid)
-		kg.params.Broker().Receive(m)
+		m := tss.JsonWrapPrivate(kg.params.MsgType("ecdsa:keygen:round2-1"), r2m1, Pi, oid)
+		kg.broker.Receive(m)
 	}
 
 	// Generate ModProof and broadcast round2-2 message
 	var modProofBzs [][]byte
-	if !kg.params.NoProofMod() {
+	if !kg.params.NoProofMod() && !kg.auxLite {
 		mp, err := modproof.NewProof(ContextI, kg.data.PaillierSK.N,
 			kg.data.PaillierSK.P, kg.data.PaillierSK.Q, kg.params.Rand())
 		if err != nil {
-			kg.Err <- fmt.Errorf("failed to generate mod proof: %w", err)
+			kg.fail(kg.wrapError(2, fmt.Errorf("failed to generate mod proof: %w", err)))
 			return
 		}
 		bzArr := mp.Bytes()
@@ -332,19 +438,19 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 		ModProof:     modProofBzs,
 	}
 	for _, oid := range otherIds {
-		m := tss.JsonWrap("ecdsa:keygen:round2-2", r2m2, Pi, oid)
-		kg.params.Broker().Receive(m)
+		m := tss.JsonWrap(kg.params.MsgType("ecdsa:keygen:round2-2"), r2m2, Pi, oid)
+		kg.broker.Receive(m)
 	}
 
 	// Set pending counter for two incoming message types
 	atomic.StoreInt32(&kg.r2pending, 2)
 
 	// Register receivers for round 2 messages from other parties
-	rcv1 := tss.NewJsonExpect[keygenRound2msg1]("ecdsa:keygen:round2-1", otherIds, kg.onR2msg1)
-	kg.params.Broker().Connect("ecdsa:keygen:round2-1", rcv1)
+	rcv1 := tss.NewJsonExpect[keygenRound2msg1](kg.params.MsgType("ecdsa:keygen:round2-1"), otherIds, kg.onR2msg1, kg.timeout(2), tss.WithPrivate())
+	kg.broker.Connect(kg.params.MsgType("ecdsa:keygen:round2-1"), rcv1)
 
-	rcv2 := tss.NewJsonExpect[keygenRound2msg2]("ecdsa:keygen:round2-2", otherIds, kg.onR2msg2)
-	kg.params.Broker().Connect("ecdsa:keygen:round2-2", rcv2)
+	rcv2 := tss.NewJsonExpect[keygenRound2msg2](kg.params.MsgType("ecdsa:keygen:round2-2"), otherIds, kg.onR2msg2, kg.timeout(2), kg.echo(2, "ecdsa:keygen:round2-2", otherIds))
+	kg.broker.Connect(kg.params.MsgType("ecdsa:keygen:round2-2"), rcv2)
 }
 
 func (kg *Keygen) onR2msg1(from []*tss.PartyID, msgs []*keygenRound2msg1) {
@@ -366,7 +472,7 @@ func (kg *Keygen) onR2msg2(from []*tss.PartyID, msgs []*keygenRound2msg2) {
 // processRound3 verifies round 2 messages and executes round 3.
 func (kg *Keygen) processRound3() {
 	if kg.ctx.Err() != nil {
-		kg.Err <- kg.ctx.Err()
+		kg.fail(kg.ctx.Err())
 		return
 	}
 	kg.round = 3
@@ -408,7 +514,7 @@ func (kg *Keygen) processRound3() {
 	// Verify and process each other party's messages concurrently
 	type verifyResult struct {
 		err  error
-		pjVs vss.Vs
+		pjVs []vss.Vs // for each key
 	}
 	chs := make([]chan verifyResult, len(kg.r2msg1From))
 
@@ -429,58 +535,90 @@ func (kg *Keygen) processRound3() {
 			cmtDeCmt := cmts.HashCommitDecommit{C: KGCj, D: KGDj}
 			ok, flatPolyGs := cmtDeCmt.DeCommit()
 			if !ok || flatPolyGs == nil {
-				chs[k] <- verifyResult{err: fmt.Errorf("party %s: decommitment verification failed", allParties[jIdx])}
+				chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("decommitment verification failed"), allParties[jIdx])}
 				return
 			}
-
-			PjVs, err := crypto.UnFlattenECPoints(ec, flatPolyGs)
-			if err != nil {
-				chs[k] <- verifyResult{err: fmt.Errorf("party %s: unflatten EC points failed: %w", allParties[jIdx], err)}
+			polySize := 2 * (threshold + 1) // coordinates of the commitments to a polynomial
+			if len(flatPolyGs) != len(kg.keys)*polySize {
+				chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("decommitment holds the polynomials of another number of keys"), allParties[jIdx])}
+				return
+			}
+			if len(r2m1.Shares) != len(kg.keys)-1 {
+				chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("message holds the shares of another number of keys"), allParties[jIdx])}
+				return
+			}
+			if len(r2m1.MoreShares) != len(kg.keys)*len(kg.ownMoreKs()) {
+				chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("message holds the shares of another number of points"), allParties[jIdx])}
 				return
 			}
 
+			PjVs := make([]vss.Vs, len(kg.keys))
+			for n := range PjVs {
+				var err error
+				PjVs[n], err = crypto.UnFlattenECPoints(ec, flatPolyGs[n*polySize:(n+1)*polySize])
+				if err != nil {
+					chs[k] <- verifyResult{err: kg.wrapError(3, fmt.Errorf("unflatten EC points failed: %w", err), allParties[jIdx])}
+					return
+				}
+			}
+
 			// Verify ModProof
-			if !kg.params.NoProofMod() {
+			if !kg.params.NoProofMod() && !kg.auxCached[jIdx] {
 				if len(r2m2.ModProof) == 0 {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: mod proof missing", allParties[jIdx])}
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("mod proof missing"), allParties[jIdx])}
 					return
 				}
 				mp, err := modproof.NewProofFromBytes(r2m2.ModProof)
 				if err != nil {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: mod proof deserialization failed: %w", allParties[jIdx], err)}
+					chs[k] <- verifyResult{err: kg.wrapError(3, fmt.Errorf("mod proof deserialization failed: %w", err), allParties[jIdx])}
 					return
 				}
 				if ok := mp.Verify(ContextJ, kg.data.PaillierPKs[jIdx].N); !ok {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: mod proof verification failed", allParties[jIdx])}
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("mod proof verification failed"), allParties[jIdx])}
 					return
 				}
 			}
 
-			// Verify VSS share
-			share := vss.Share{
-				Threshold: threshold,
-				ID:        Pi.KeyInt(),
-				Share:     new(big.Int).SetBytes(r2m1.Share),
+			// Verify VSS shares
+			for n, shareBz := range append([][]byte{r2m1.Share}, r2m1.Shares...) {
+				share := vss.Share{
+					Threshold: threshold,
+					ID:        Pi.KeyInt(),
+					Share:     new(big.Int).SetBytes(shareBz),
+					Rank:      kg.data.ranks()[i],
+				}
+				if ok := share.Verify(ec, threshold, PjVs[n]); !ok {
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("VSS share verification failed"), allParties[jIdx])}
+					return
+				}
 			}
-			if ok := share.Verify(ec, threshold, PjVs); !ok {
-				chs[k] <- verifyResult{err: fmt.Errorf("party %s: VSS share verification failed", allParties[jIdx])}
-				return
+			moreKs := kg.ownMoreKs()
+			for c, shareBz := range r2m1.MoreShares {
+				share := vss.Share{
+					Threshold: threshold,
+					ID:        moreKs[c%len(moreKs)],
+					Share:     new(big.Int).SetBytes(shareBz),
+				}
+				if ok := share.Verify(ec, threshold, PjVs[c/len(moreKs)]); !ok {
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("VSS share verification failed"), allParties[jIdx])}
+					return
+				}
 			}
 
 			// Verify FacProof
-			if !kg.params.NoProofFac() {
+			if !kg.params.NoProofFac() && !kg.auxCache.paired(allParties[jIdx], kg.auxFps[jIdx], kg.auxFps[i]) {
 				if len(r2m1.FacProof) == 0 {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: fac proof missing", allParties[jIdx])}
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("fac proof missing"), allParties[jIdx])}
 					return
 				}
 				fp, err := facproof.NewProofFromBytes(r2m1.FacProof)
 				if err != nil {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: fac proof deserialization failed: %w", allParties[jIdx], err)}
+					chs[k] <- verifyResult{err: kg.wrapError(3, fmt.Errorf("fac proof deserialization failed: %w", err), allParties[jIdx])}
 					return
 				}
 				if ok := fp.Verify(ContextJ, ec, kg.data.PaillierPKs[jIdx].N,
 					kg.data.NTildei, kg.data.H1i, kg.data.H2i); !ok {
-					chs[k] <- verifyResult{err: fmt.Errorf("party %s: fac proof verification failed", allParties[jIdx])}
+					chs[k] <- verifyResult{err: kg.wrapError(3, errors.New("fac proof verification failed"), allParties[jIdx])}
 					return
 				}
 			}
@@ -490,47 +628,126 @@ func (kg *Keygen) processRound3() {
 	}
 
 	// Collect results
-	pjVsMap := make(map[int]vss.Vs) // allParties index -> PjVs
+	pjVsMap := make(map[int][]vss.Vs) // allParties index -> PjVs of each key
+	var errs []error
 	for k := range chs {
 		result := <-chs[k]
 		if result.err != nil {
-			kg.Err <- result.err
-			return
+			errs = append(errs, result.err)
+			continue
 		}
 		jIdx := partyIdxMap[k]
 		pjVsMap[jIdx] = result.pjVs
 	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		kg.fail(err)
+		return
+	}
+
+	// Compute the share and public keys of every key
+	for n := range kg.keys {
+		if err := kg.computeKey(n, pjVsMap); err != nil {
+			kg.fail(err)
+			return
+		}
+	}
+	ecdsaPubKey := kg.data.ECDSAPub
+
+	// Generate Paillier proof, which is about the Paillier key shared by all the keys of a
+	// batch: it is bound to the public key of the first one
+	r3msg := &keygenRound3msg{}
+	if !kg.auxLite {
+		ki := Pi.KeyInt()
+		proof, err := kg.data.PaillierSK.Proof(ki, ecdsaPubKey)
+		if err != nil {
+			kg.fail(kg.wrapError(3, fmt.Errorf("failed to generate Paillier proof: %w", err)))
+			return
+		}
+
+		// Serialize proof
+		r3msg.PaillierProof = make([][]byte, paillier.ProofIters)
+		for idx := 0; idx < paillier.ProofIters; idx++ {
+			if proof[idx] != nil {
+				r3msg.PaillierProof[idx] = proof[idx].Bytes()
+			}
+		}
+	}
+
+	// Broadcast round 3 message
+	var otherIds []*tss.PartyID
+	for n, p := range allParties {
+		if n == i {
+			continue
+		}
+		otherIds = append(otherIds, p)
+		m := tss.JsonWrap(kg.params.MsgType("ecdsa:keygen:round3"), r3msg, Pi, p)
+		kg.broker.Receive(m)
+	}
+
+	// Register receiver for round 3 -> round 4
+	rcv := tss.NewJsonExpect[keygenRound3msg](kg.params.MsgType("ecdsa:keygen:round3"), otherIds, kg.round4, kg.timeout(3), kg.echo(3, "ecdsa:keygen:round3", otherIds))
+	kg.broker.Connect(kg.params.MsgType("ecdsa:keygen:round3"), rcv)
+}
+
+// computeKey computes the share, the public shares and the public key of the key of index n,
+// from the shares received in round 2 and the polynomial commitments pjVsMap of the other
+// parties.
+func (kg *Keygen) computeKey(n int, pjVsMap map[int][]vss.Vs) error {
+	i := kg.params.PartyID().Index
+	ec := kg.params.EC()
+	threshold := kg.params.Threshold()
+	key := kg.keys[n]
 
 	// Compute xi = own share + sum(received shares) mod N
-	xi := new(big.Int).Set(kg.shares[i].Share)
+	xi := new(big.Int).Set(kg.shares[n][i].Share)
 	for k := range kg.r2msg1 {
-		share := new(big.Int).SetBytes(kg.r2msg1[k].Share)
-		xi = new(big.Int).Add(xi, share)
+		shareBz := kg.r2msg1[k].Share
+		if n > 0 {
+			shareBz = kg.r2msg1[k].Shares[n-1]
+		}
+		xi = new(big.Int).Add(xi, new(big.Int).SetBytes(shareBz))
+	}
+	key.Xi = new(big.Int).Mod(xi, ec.Params().N)
+
+	// and the shares at the other points of this party of a weighted key
+	modQ := common.ModInt(ec.Params().N)
+	moreKs := kg.ownMoreKs()
+	key.MoreXi = nil
+	for m := range moreKs {
+		xi := kg.moreShares[n][i][m].Share
+		for k := range kg.r2msg1 {
+			xi = modQ.Add(xi, new(big.Int).SetBytes(kg.r2msg1[k].MoreShares[n*len(moreKs)+m]))
+		}
+		key.MoreXi = append(key.MoreXi, xi)
 	}
-	kg.data.Xi = new(big.Int).Mod(xi, ec.Params().N)
 
 	// Aggregate Vc: start with our own vs
 	Vc := make(vss.Vs, threshold+1)
 	for c := range Vc {
-		Vc[c] = kg.vs[c]
+		Vc[c] = kg.vs[n][c]
 	}
-	for jIdx, PjVs := range pjVsMap {
-		_ = jIdx
+	for _, PjVs := range pjVsMap {
 		for c := 0; c <= threshold; c++ {
 			var err error
-			Vc[c], err = Vc[c].Add(PjVs[c])
+			Vc[c], err = Vc[c].Add(PjVs[n][c])
 			if err != nil {
-				kg.Err <- fmt.Errorf("failed to add PjVs[%d] to Vc[%d]: %w", c, c, err)
-				return
+				return kg.wrapError(3, fmt.Errorf("failed to add PjVs[%d] to Vc[%d]: %w", c, c, err))
 			}
 		}
 	}
 
 	// Compute BigXj for each party
-	modQ := common.ModInt(ec.Params().N)
 	ks := kg.data.Ks
 	for j := 0; j < kg.params.PartyCount(); j++ {
 		kj := ks[j]
+		if kg.data.Ranks != nil {
+			BigXj, err := Vc.PublicShare(kj, kg.data.Ranks[j])
+			if err != nil {
+				return kg.wrapError(3, fmt.Errorf("failed computing BigXj for party %d: %w", j, err))
+			}
+			key.BigXj[j] = BigXj
+			continue
+		}
 		BigXj := Vc[0]
 		z := new(big.Int).SetInt64(1)
 		for c := 1; c <= threshold; c++ {
@@ -538,61 +755,37 @@ func (kg *Keygen) processRound3() {
 			var err error
 			BigXj, err = BigXj.Add(Vc[c].ScalarMult(z))
 			if err != nil {
-				kg.Err <- fmt.Errorf("failed computing BigXj for party %d: %w", j, err)
-				return
+				return kg.wrapError(3, fmt.Errorf("failed computing BigXj for party %d: %w", j, err))
+			}
+		}
+		key.BigXj[j] = BigXj
+	}
+	if kg.data.MoreKs != nil {
+		key.MoreBigXj = make([][]*crypto.ECPoint, len(ks))
+		for j, kjs := range kg.data.MoreKs {
+			for _, kj := range kjs {
+				BigXj, err := Vc.PublicShare(kj, 0)
+				if err != nil {
+					return kg.wrapError(3, fmt.Errorf("failed computing BigXj for party %d: %w", j, err))
+				}
+				key.MoreBigXj[j] = append(key.MoreBigXj[j], BigXj)
 			}
 		}
-		kg.data.BigXj[j] = BigXj
 	}
 
 	// ECDSAPub = Vc[0]
 	ecdsaPubKey, err := crypto.NewECPoint(ec, Vc[0].X(), Vc[0].Y())
 	if err != nil {
-		kg.Err <- fmt.Errorf("public key is not on the curve: %w", err)
-		return
+		return kg.wrapError(3, fmt.Errorf("public key is not on the curve: %w", err))
 	}
-	kg.data.ECDSAPub = ecdsaPubKey
-
-	// Generate Paillier proof
-	ki := Pi.KeyInt()
-	proof, err := kg.data.PaillierSK.Proof(ki, ecdsaPubKey)
-	if err != nil {
-		kg.Err <- fmt.Errorf("failed to generate Paillier proof: %w", err)
-		return
-	}
-
-	// Serialize proof
-	proofBzs := make([][]byte, paillier.ProofIters)
-	for idx := 0; idx < paillier.ProofIters; idx++ {
-		if proof[idx] != nil {
-			proofBzs[idx] = proof[idx].Bytes()
-		}
-	}
-
-	r3msg := &keygenRound3msg{
-		PaillierProof: proofBzs,
-	}
-
-	// Broadcast round 3 message
-	var otherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		otherIds = append(otherIds, p)
-		m := tss.JsonWrap("ecdsa:keygen:round3", r3msg, Pi, p)
-		kg.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 3 -> round 4
-	rcv := tss.NewJsonExpect[keygenRound3msg]("ecdsa:keygen:round3", otherIds, kg.round4)
-	kg.params.Broker().Connect("ecdsa:keygen:round3", rcv)
+	key.ECDSAPub = ecdsaPubKey
+	return nil
 }
 
 // round4 verifies Paillier proofs from all other parties and completes keygen.
 func (kg *Keygen) round4(otherIds []*tss.PartyID, r3msgs []*keygenRound3msg) {
 	if kg.ctx.Err() != nil {
-		kg.Err <- kg.ctx.Err()
+		kg.fail(kg.ctx.Err())
 		return
 	}
 	kg.round = 4
@@ -620,6 +813,10 @@ func (kg *Keygen) round4(otherIds []*tss.PartyID, r3msgs []*keygenRound3msg) {
 
 	for k, r3msg := range r3msgs {
 		jIdx := partyIdxMap[k]
+		if kg.auxCached[jIdx] {
+			chs[k] <- true
+			continue
+		}
 		go func(k, jIdx int, r3msg *keygenRound3msg) {
 			// Deserialize Paillier proof
 			var proof paillier.Proof
@@ -642,18 +839,89 @@ func (kg *Keygen) round4(otherIds []*tss.PartyID, r3msgs []*keygenRound3msg) {
 	}
 
 	// Collect results
-	var culprits []string
+	var culprits []*tss.PartyID
 	for k := range chs {
 		if !<-chs[k] {
 			jIdx := partyIdxMap[k]
-			culprits = append(culprits, allParties[jIdx].String())
+			culprits = append(culprits, allParties[jIdx])
 		}
 	}
 
 	if len(culprits) > 0 {
-		kg.Err <- fmt.Errorf("paillier proof verification failed for parties: %v", culprits)
+		kg.fail(kg.wrapError(4, errors.New("paillier proof verification failed"), culprits...))
 		return
 	}
 
+	// Every peer verified the pre-params of this party before sending its round 3 message
+	for k, Pj := range otherIds {
+		jIdx := partyIdxMap[k]
+		kg.auxCache.store(Pj, kg.data.PaillierPKs[jIdx].N, kg.data.NTildej[jIdx], kg.data.H1j[jIdx], kg.data.H2j[jIdx], kg.auxFps[kg.params.PartyID().Index])
+	}
+
+	// The other keys of a batch share the Paillier keys and range proof parameters
+	for _, key := range kg.keys[1:] {
+		key.LocalPreParams = kg.data.LocalPreParams
+		key.ShareID = kg.data.ShareID
+		key.Ks = slices.Clone(kg.data.Ks)
+		key.MoreKs = kg.data.MoreKs
+		key.Ranks = kg.data.Ranks
+		key.NTildej = slices.Clone(kg.data.NTildej)
+		key.H1j = slices.Clone(kg.data.H1j)
+		key.H2j = slices.Clone(kg.data.H2j)
+		key.PaillierPKs = slices.Clone(kg.data.PaillierPKs)
+	}
+
+	kg.release()
+	if kg.batchDone != nil {
+		kg.batchDone <- kg.keys
+		return
+	}
 	kg.Done <- kg.data
 }
+
+// ownMoreKs returns the other evaluation points of this party of a weighted key.
+func (kg *Keygen) ownMoreKs() []*big.Int {
+	if kg.data.MoreKs == nil {
+		return nil
+	}
+	return kg.data.MoreKs[kg.params.PartyID().Index]
+}
+
+// fail reports err on Err and releases the receivers registered by this keygen.
+func (kg *Keygen) fail(err error) {
+	kg.release()
+	select {
+	case kg.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this keygen from the broker.
+func (kg *Keygen) release() {
+	kg.stop()
+	kg.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (kg *Keygen) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(kg.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (kg *Keygen) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !kg.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(kg.broker, kg.params.MsgType(typ+":echo"), kg.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (kg *Keygen) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskKeygen, round, kg.params.PartyID(), culprits...)
+}
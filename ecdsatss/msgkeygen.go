@@ -10,11 +10,19 @@ type keygenRound1msg struct {
 	H2         []byte
 	Dlnproof_1 [][]byte
 	Dlnproof_2 [][]byte
+
+	// fingerprint of the pre-params of the sender, which omits them and their proofs when all
+	// the parties have them in their AuxInfoCache
+	AuxFingerprint []byte `json:",omitempty"`
 }
 
 type keygenRound2msg1 struct {
 	Share    []byte
 	FacProof [][]byte
+	Shares   [][]byte `json:",omitempty"` // shares of the other keys of a batch keygen
+
+	// shares at the other points of the receiver of a weighted key, for each key
+	MoreShares [][]byte `json:",omitempty"`
 }
 
 type keygenRound2msg2 struct {
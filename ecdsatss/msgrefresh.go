@@ -0,0 +1,30 @@
+package ecdsatss
+
+// messages for refresh
+
+// refreshRound1msg is broadcast, with the commitment to the refresh polynomial of the sender,
+// and its new Paillier key and range proof parameters when it rotates its pre-params.
+type refreshRound1msg struct {
+	Commitment []byte   `json:"commitment"`
+	PaillierN  []byte   `json:"paillier_n,omitempty"`
+	NTilde     []byte   `json:"n_tilde,omitempty"`
+	H1         []byte   `json:"h1,omitempty"`
+	H2         []byte   `json:"h2,omitempty"`
+	Dlnproof_1 [][]byte `json:"dlnproof_1,omitempty"`
+	Dlnproof_2 [][]byte `json:"dlnproof_2,omitempty"`
+}
+
+// refreshRound2msg1 is a P2P message containing the refresh share of the recipient, and the
+// factorization proof of the Paillier modulus of the sender when either of them rotates its
+// pre-params.
+type refreshRound2msg1 struct {
+	Share    []byte   `json:"share"`
+	FacProof [][]byte `json:"fac_proof,omitempty"`
+}
+
+// refreshRound2msg2 is broadcast, with the de-commitment of round 1 and the modulus proof of
+// the new Paillier key of the sender.
+type refreshRound2msg2 struct {
+	DeCommitment [][]byte `json:"de_commitment"`
+	ModProof     [][]byte `json:"mod_proof,omitempty"`
+}
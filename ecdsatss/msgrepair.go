@@ -0,0 +1,43 @@
+package ecdsatss
+
+// messages for share repair and enrollment
+
+// repairRound1msg1 is a P2P message between helpers, containing a random part of the
+// Lagrange term of the sender for the lost share, or the share of the newcomer.
+type repairRound1msg1 struct {
+	Part []byte `json:"part"`
+}
+
+// repairRound1msg2 is broadcast from the lost party or the newcomer to the helpers, with its
+// new Paillier key and range proof parameters and their proofs.
+type repairRound1msg2 struct {
+	PaillierN  []byte   `json:"paillier_n"`
+	NTilde     []byte   `json:"n_tilde"`
+	H1         []byte   `json:"h1"`
+	H2         []byte   `json:"h2"`
+	Dlnproof_1 [][]byte `json:"dlnproof_1"`
+	Dlnproof_2 [][]byte `json:"dlnproof_2"`
+	ModProof   [][]byte `json:"mod_proof,omitempty"`
+}
+
+// repairRound2msg is a P2P message from each helper to the lost party or the newcomer,
+// containing the sum of the parts received by the helper, the public data of the key, and the
+// factorization proof of the Paillier modulus of the helper.
+type repairRound2msg struct {
+	Sum        []byte   `json:"sum"`
+	Ks         [][]byte `json:"ks"`
+	BigXj      [][]byte `json:"big_xj"`
+	ECDSAPubX  []byte   `json:"ecdsa_pub_x"`
+	ECDSAPubY  []byte   `json:"ecdsa_pub_y"`
+	PaillierNj [][]byte `json:"paillier_nj"`
+	NTildej    [][]byte `json:"n_tildej"`
+	H1j        [][]byte `json:"h1j"`
+	H2j        [][]byte `json:"h2j"`
+	FacProof   [][]byte `json:"fac_proof,omitempty"`
+}
+
+// repairRound3msg is a P2P message from the lost party or the newcomer to each helper, with
+// the factorization proof of its new Paillier modulus.
+type repairRound3msg struct {
+	FacProof [][]byte `json:"fac_proof,omitempty"`
+}
@@ -12,7 +12,8 @@ type resharingRound1msg struct {
 }
 
 // resharingRound2msg1 is broadcast from new committee to new committee.
-// Contains Paillier public key, modulus proof, and DLN proofs.
+// Contains Paillier public key, modulus proof, and DLN proofs, which are omitted when all the
+// other new parties have them in their AuxInfoCache.
 type resharingRound2msg1 struct {
 	PaillierN  []byte   `json:"paillier_n"`
 	ModProof   [][]byte `json:"mod_proof"`
@@ -21,15 +22,18 @@ type resharingRound2msg1 struct {
 	H2         []byte   `json:"h2"`
 	Dlnproof_1 [][]byte `json:"dlnproof_1"`
 	Dlnproof_2 [][]byte `json:"dlnproof_2"`
+
+	AuxFingerprint []byte `json:"aux_fingerprint,omitempty"`
 }
 
 // resharingRound2msg2 is broadcast from new committee to old committee (ACK).
 type resharingRound2msg2 struct{}
 
 // resharingRound3msg1 is a P2P message from old committee to each new party,
-// containing the VSS share.
+// containing the VSS share, and the shares at the other points of a weighted new party.
 type resharingRound3msg1 struct {
-	Share []byte `json:"share"`
+	Share      []byte   `json:"share"`
+	MoreShares [][]byte `json:"more_shares,omitempty"`
 }
 
 // resharingRound3msg2 is broadcast from old committee to new committee,
@@ -65,3 +65,25 @@ type signRound8msg struct {
 type signRound9msg struct {
 	Si []byte `json:"si"`
 }
+
+// presignRound5msg is the broadcast message of the last round of presigning, containing
+// ki·R and sigma_i·R.
+type presignRound5msg struct {
+	KX []byte `json:"k_x"`
+	KY []byte `json:"k_y"`
+	SX []byte `json:"s_x"`
+	SY []byte `json:"s_y"`
+}
+
+// signOnlineMsg is a broadcast message containing the partial signature si made with a
+// presignature.
+type signOnlineMsg struct {
+	ID []byte `json:"id"`
+	Si []byte `json:"si"`
+}
+
+// signBatchMsg is a message of a batch signing, holding the message of the same round of the
+// signing of each message of the batch, in order.
+type signBatchMsg[T any] struct {
+	Msgs []*T `json:"msgs"`
+}
@@ -0,0 +1,321 @@
+package ecdsatss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskPresigning is the task name reported in errors from Presigning.
+const TaskPresigning = "ecdsa-presigning"
+
+// ErrPresignatureUsed is returned by SignWithPresignature when the presignature was already
+// used to sign.
+var ErrPresignatureUsed = errors.New("presignature was already used")
+
+// PreSignature holds the result of the rounds of signing that do not depend on the message:
+// the nonce point R, the local shares of k and k·x, and the points used to check the partial
+// signature of each party. It can be persisted as json and used by SignWithPresignature to
+// produce exactly one signature, with the same committee.
+//
+// A PreSignature is as sensitive as a key share: signing two messages with the same
+// presignature reveals the key. SignWithPresignature clears it, and only signs once its
+// markUsed callback durably recorded that the presignature is used.
+type PreSignature struct {
+	ID    []byte            // identifies the presignature, the same for all the parties
+	Ks    []*big.Int        // keys of the parties of the committee, in order
+	K     *big.Int          // ki
+	Sigma *big.Int          // sigma_i, share of k·x
+	R     *crypto.ECPoint   // R = g^(1/k)
+	BigR  []*crypto.ECPoint // kj·R for each party
+	BigS  []*crypto.ECPoint // sigma_j·R for each party
+
+	lock sync.Mutex
+}
+
+// Presigning tracks the offline phase of a threshold ECDSA signing.
+type Presigning struct {
+	Done chan *PreSignature
+	Err  chan error
+}
+
+// NewPresigning runs the rounds of signing that do not depend on the message: the generation
+// of k and gamma, the MtA conversions and the computation of R. In a last round, each party
+// broadcasts ki·R and sigma_i·R, which must add up to G and to the public key, so that the
+// partial signature of each party can be checked when signing. The resulting PreSignature is
+// sent on Done, and can later be given to SignWithPresignature by each party of the same
+// committee.
+//
+// The last round is always followed by an echo round, whatever the setting of
+// params.EchoBroadcast, so that all the parties check the partial signatures against the same
+// points. When the points do not add up, presigning fails without culprits.
+func (key *Key) NewPresigning(ctx context.Context, params *tss.Parameters) (*Presigning, error) {
+	s, err := key.newSigning(ctx, nil, params, TaskPresigning)
+	if err != nil {
+		return nil, err
+	}
+	s.presigned = make(chan *PreSignature, 1)
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return &Presigning{Done: s.presigned, Err: s.Err}, nil
+}
+
+// presignRound5 broadcasts ki·R and sigma_i·R once R is known, instead of going on with
+// round 5 of signing.
+func (s *Signing) presignRound5(R *crypto.ECPoint) {
+	s.bigR = R
+	bigKi := R.ScalarMult(s.k)
+	bigSi := R.ScalarMult(s.sigma)
+
+	Pi := s.params.PartyID()
+	otherIds := s.params.Parties().IDs().Exclude(Pi)
+	r5msg := &presignRound5msg{
+		KX: bigKi.X().Bytes(),
+		KY: bigKi.Y().Bytes(),
+		SX: bigSi.X().Bytes(),
+		SY: bigSi.Y().Bytes(),
+	}
+	for _, Pj := range otherIds {
+		s.broker.Receive(tss.JsonWrap(s.params.MsgType("ecdsa:presign:round5"), r5msg, Pi, Pj))
+	}
+
+	onRound5 := func(from []*tss.PartyID, msgs []*presignRound5msg) {
+		s.finishPresigning(bigKi, bigSi, from, msgs)
+	}
+	echo := tss.WithEcho(s.broker, s.params.MsgType("ecdsa:presign:round5:echo"), Pi, otherIds, func(culprits []*tss.PartyID) {
+		s.fail(s.wrapError(5, tss.ErrEquivocation, culprits...))
+	})
+	rcv := tss.NewJsonExpect[presignRound5msg](s.params.MsgType("ecdsa:presign:round5"), otherIds, onRound5, s.timeout(5), echo)
+	s.broker.Connect(s.params.MsgType("ecdsa:presign:round5"), rcv)
+}
+
+// finishPresigning checks that the points kj·R add up to G and the points sigma_j·R to the
+// public key, and sends the presignature.
+func (s *Signing) finishPresigning(bigKi, bigSi *crypto.ECPoint, otherIds []*tss.PartyID, msgs []*presignRound5msg) {
+	if s.ctx.Err() != nil {
+		s.fail(s.ctx.Err())
+		return
+	}
+	ec := s.params.EC()
+	i := s.params.PartyID().Index
+	partyCount := s.params.PartyCount()
+	bigRs := make([]*crypto.ECPoint, partyCount)
+	bigSs := make([]*crypto.ECPoint, partyCount)
+	bigRs[i], bigSs[i] = bigKi, bigSi
+
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		bigKj, errK := crypto.NewECPoint(ec, new(big.Int).SetBytes(msgs[n].KX), new(big.Int).SetBytes(msgs[n].KY))
+		bigSj, errS := crypto.NewECPoint(ec, new(big.Int).SetBytes(msgs[n].SX), new(big.Int).SetBytes(msgs[n].SY))
+		if errK != nil || errS != nil {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		bigRs[j], bigSs[j] = bigKj, bigSj
+	}
+	if len(culprits) > 0 {
+		s.fail(s.wrapError(6, errors.New("invalid presignature point"), culprits...))
+		return
+	}
+
+	// sum(kj)·R = G and sum(sigma_j)·R = x·G, which no single party can be blamed for
+	sumK, errK := addPoints(bigRs...)
+	sumS, errS := addPoints(bigSs...)
+	if errK != nil || errS != nil || !pointsEqual(sumK, crypto.ScalarBaseMult(ec, big.NewInt(1))) || !pointsEqual(sumS, s.key.ECDSAPub) {
+		s.fail(s.wrapError(6, errors.New("presignature points are inconsistent")))
+		return
+	}
+
+	R := s.bigR
+	id := common.SHA512_256(s.ssid, R.X().Bytes(), R.Y().Bytes())
+	for j := range bigRs {
+		id = common.SHA512_256(id, bigRs[j].X().Bytes(), bigRs[j].Y().Bytes(), bigSs[j].X().Bytes(), bigSs[j].Y().Bytes())
+	}
+	presig := &PreSignature{
+		ID:    id,
+		Ks:    s.params.Parties().IDs().Keys(),
+		K:     s.k,
+		Sigma: s.sigma,
+		R:     R,
+		BigR:  bigRs,
+		BigS:  bigSs,
+	}
+
+	// Clear secret values
+	s.w = zero
+	s.k = zero
+	s.gamma = zero
+	s.sigma = zero
+
+	s.release()
+	s.presigned <- presig
+}
+
+// take returns ki and sigma_i once markUsed recorded that the presignature is used, and clears
+// them so that the presignature cannot be used again.
+func (p *PreSignature) take(markUsed func(id []byte) error) (k, sigma *big.Int, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.K == nil || p.Sigma == nil {
+		return nil, nil, ErrPresignatureUsed
+	}
+	if err := markUsed(p.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to mark the presignature as used: %w", err)
+	}
+	k, sigma = p.K, p.Sigma
+	p.K, p.Sigma = nil, nil
+	return k, sigma, nil
+}
+
+// SignWithPresignature signs msg with a presignature made by NewPresigning, in a single round
+// exchanging the partial signatures. The parties of params must be the committee of the
+// presignature.
+//
+// markUsed is called with the ID of the presignature before anything derived from it is
+// computed, and must durably record that it is used, for instance by deleting its stored
+// copies: a presignature restored from storage and used again would reveal the key. If
+// markUsed fails, nothing is sent and the presignature can be used again. Otherwise the
+// presignature is consumed even if signing fails afterwards.
+//
+// Each partial signature sj is checked against the points of the presignature, so that a
+// party sending an invalid one is reported as a culprit of round 2. Partial signatures made
+// with another presignature end the signing without culprits, as the presignature used is not
+// attributable.
+func (key *Key) SignWithPresignature(ctx context.Context, presig *PreSignature, msg *big.Int, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	ec := params.EC()
+	if msg == nil || msg.Cmp(ec.Params().N) >= 0 {
+		return nil, errors.New("hashed message is not valid")
+	}
+	if markUsed == nil {
+		return nil, errors.New("a presignature can only be used with a markUsed callback")
+	}
+	ids := params.Parties().IDs()
+	if len(ids) != len(presig.Ks) || len(presig.BigR) != len(ids) || len(presig.BigS) != len(ids) {
+		return nil, fmt.Errorf("presignature was made by %d parties, not %d", len(presig.Ks), len(ids))
+	}
+	for j, id := range ids {
+		if id.KeyInt().Cmp(presig.Ks[j]) != 0 {
+			return nil, fmt.Errorf("party %s is not part of the presignature committee", id)
+		}
+	}
+	if presig.R == nil {
+		return nil, errors.New("presignature has no R")
+	}
+	k, sigma, err := presig.take(markUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// si = m·ki + r·sigma_i
+	modN := common.ModInt(ec.Params().N)
+	s := key.signingState(ctx, msg, params, TaskSigning)
+	s.bigR = presig.R
+	s.rx = presig.R.X()
+	s.ry = presig.R.Y()
+	s.si = modN.Add(modN.Mul(msg, k), modN.Mul(s.rx, sigma))
+	s.broker = tss.NewSessionBroker(params.Broker())
+	s.stop = context.AfterFunc(ctx, func() { s.fail(ctx.Err()) })
+
+	Pi := params.PartyID()
+	otherIds := ids.Exclude(Pi)
+	onlineMsg := &signOnlineMsg{
+		ID: presig.ID,
+		Si: s.si.Bytes(),
+	}
+	for _, Pj := range otherIds {
+		s.broker.Receive(tss.JsonWrap(params.MsgType("ecdsa:sign:online"), onlineMsg, Pi, Pj))
+	}
+
+	onOnline := func(from []*tss.PartyID, msgs []*signOnlineMsg) {
+		s.finalizeOnline(presig, from, msgs)
+	}
+	rcv := tss.NewJsonExpect[signOnlineMsg](params.MsgType("ecdsa:sign:online"), otherIds, onOnline, s.timeout(1), s.echo(1, "ecdsa:sign:online", otherIds))
+	s.broker.Connect(params.MsgType("ecdsa:sign:online"), rcv)
+	return s, nil
+}
+
+// finalizeOnline checks the partial signatures of the other parties against the points of
+// the presignature, and combines them.
+func (s *Signing) finalizeOnline(presig *PreSignature, otherIds []*tss.PartyID, msgs []*signOnlineMsg) {
+	if s.ctx.Err() != nil {
+		s.fail(s.ctx.Err())
+		return
+	}
+	// the points of the presignature were echoed, so a partial signature sent with another ID
+	// was made with another presignature, which is not attributable to its sender
+	for _, msg := range msgs {
+		if !bytes.Equal(msg.ID, presig.ID) {
+			s.fail(s.wrapError(2, errors.New("partial signatures were made with different presignatures")))
+			return
+		}
+	}
+
+	// sj·R = m·(kj·R) + r·(sigma_j·R)
+	q := s.params.EC().Params().N
+	var culprits []*tss.PartyID
+	sjs := make([]*big.Int, len(msgs))
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		sjs[n] = new(big.Int).SetBytes(msgs[n].Si)
+		if sjs[n].Cmp(q) >= 0 {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		expected, err := addPoints(scalarMult(presig.BigR[j], s.m), scalarMult(presig.BigS[j], s.rx))
+		if err != nil || !pointsEqual(scalarMult(s.bigR, sjs[n]), expected) {
+			culprits = append(culprits, Pj)
+		}
+	}
+	if len(culprits) > 0 {
+		s.fail(s.wrapError(2, errors.New("partial signature verification failed"), culprits...))
+		return
+	}
+	s.combine(2, sjs)
+}
+
+// scalarMult returns k·p, or nil (the point at infinity) when k is 0 modulo the curve order.
+func scalarMult(p *crypto.ECPoint, k *big.Int) *crypto.ECPoint {
+	k = new(big.Int).Mod(k, p.Curve().Params().N)
+	if k.Sign() == 0 {
+		return nil
+	}
+	return p.ScalarMult(k)
+}
+
+// addPoints returns the sum of points, nil standing for the point at infinity.
+func addPoints(points ...*crypto.ECPoint) (*crypto.ECPoint, error) {
+	var sum *crypto.ECPoint
+	for _, p := range points {
+		switch {
+		case p == nil:
+		case sum == nil:
+			sum = p
+		case sum.X().Cmp(p.X()) == 0 && sum.Y().Cmp(p.Y()) != 0:
+			// p = -sum
+			sum = nil
+		default:
+			var err error
+			if sum, err = sum.Add(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sum, nil
+}
+
+// pointsEqual compares two points, nil standing for the point at infinity.
+func pointsEqual(p1, p2 *crypto.ECPoint) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == nil && p2 == nil
+	}
+	return p1.Equals(p2)
+}
@@ -0,0 +1,320 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// loadTestKeys loads the first qty keys of the 5 parties, threshold 2, keygen fixtures shared
+// with the legacy ecdsa packages, and the sorted ids of their parties.
+func loadTestKeys(t *testing.T, qty int) ([]*Key, tss.SortedPartyIDs) {
+	keys := make([]*Key, qty)
+	ids := make(tss.UnSortedPartyIDs, qty)
+	for i := range qty {
+		buf, err := os.ReadFile(fmt.Sprintf("../test/_ecdsa_fixtures/keygen_data_%d.json", i))
+		require.NoError(t, err)
+		var key *Key
+		require.NoError(t, json.Unmarshal(buf, &key))
+		for _, bigXj := range key.BigXj {
+			bigXj.SetCurve(tss.S256())
+		}
+		key.ECDSAPub.SetCurve(tss.S256())
+		keys[i] = key
+		ids[i] = tss.NewPartyID(fmt.Sprintf("%d", i+1), fmt.Sprintf("P[%d]", i+1), key.ShareID)
+	}
+	return keys, tss.SortPartyIDs(ids)
+}
+
+func TestPresignAndSign(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	keyFor := func(p *tss.PartyID) *Key {
+		for _, key := range keys {
+			if key.ShareID.Cmp(p.KeyInt()) == 0 {
+				return key
+			}
+		}
+		t.Fatalf("no key for party %s", p)
+		return nil
+	}
+
+	// --- offline phase ---
+	hub := newTestHub(signerCount)
+	presignings := make([]*Presigning, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		ps, err := keyFor(p).NewPresigning(context.Background(), params)
+		require.NoError(t, err)
+		presignings[i] = ps
+	}
+
+	presigs := make([]*PreSignature, signerCount)
+	for i, ps := range presignings {
+		select {
+		case presig := <-ps.Done:
+			// presignatures are persisted until the message is known
+			buf, err := json.Marshal(presig)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(buf, &presigs[i]))
+		case err := <-ps.Err:
+			t.Fatalf("Party %d presigning error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d presigning timed out", i)
+		}
+	}
+	for i := 1; i < signerCount; i++ {
+		assert.Equal(t, presigs[0].ID, presigs[i].ID)
+		assert.True(t, presigs[0].R.Equals(presigs[i].R))
+	}
+
+	// --- online phase ---
+	msgHash := sha256.Sum256([]byte("hello world"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+
+	signHub := newTestHub(signerCount)
+	signings := make([]*Signing, signerCount)
+	used := make([][]byte, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		params.SetBroker(signHub.brokers[i])
+
+		sg, err := keyFor(p).SignWithPresignature(context.Background(), presigs[i], msg, params, func(id []byte) error {
+			used[i] = id
+			return nil
+		})
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+	for i := range used {
+		assert.Equal(t, presigs[i].ID, used[i], "party %d should mark its presignature used", i)
+	}
+
+	sigDatas := make([]*SignatureData, signerCount)
+	for i, sg := range signings {
+		select {
+		case sd := <-sg.Done:
+			sigDatas[i] = sd
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+	for i := 1; i < signerCount; i++ {
+		assert.Equal(t, sigDatas[0].Signature, sigDatas[i].Signature)
+	}
+
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     keys[0].ECDSAPub.X(),
+		Y:     keys[0].ECDSAPub.Y(),
+	}
+	r := new(big.Int).SetBytes(sigDatas[0].R)
+	s := new(big.Int).SetBytes(sigDatas[0].S)
+	assert.True(t, ecdsa.Verify(&pk, msgHash[:], r, s))
+	assert.Equal(t, 0, r.Cmp(presigs[0].R.X()))
+
+	// a presignature signs a single message
+	params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[0], signerCount, threshold)
+	params.SetBroker(newTestHub(signerCount).brokers[0])
+	_, err := keyFor(pIDs[0]).SignWithPresignature(context.Background(), presigs[0], big.NewInt(1), params, markNothing)
+	assert.ErrorIs(t, err, ErrPresignatureUsed)
+}
+
+// markNothing is a markUsed callback for presignatures that are never stored.
+func markNothing([]byte) error { return nil }
+
+func TestSignWithPresignatureMarkUsedFails(t *testing.T) {
+	keys, pIDs := loadTestKeys(t, 3)
+
+	R := crypto.ScalarBaseMult(tss.S256(), big.NewInt(1))
+	presig := &PreSignature{
+		ID:    []byte("presig"),
+		Ks:    pIDs.Keys(),
+		K:     big.NewInt(1),
+		Sigma: big.NewInt(1),
+		R:     R,
+		BigR:  []*crypto.ECPoint{R, R, R},
+		BigS:  []*crypto.ECPoint{R, R, R},
+	}
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], 3, 2)
+	hub := newTestHub(3)
+	params.SetBroker(hub.brokers[0])
+
+	errStorage := errors.New("storage unavailable")
+	_, err := keys[0].SignWithPresignature(context.Background(), presig, big.NewInt(1), params, func([]byte) error {
+		return errStorage
+	})
+	assert.ErrorIs(t, err, errStorage)
+	// nothing was sent, and the presignature can still be used
+	hub.brokers[1].mu.Lock()
+	assert.Empty(t, hub.brokers[1].pending)
+	hub.brokers[1].mu.Unlock()
+	assert.NotNil(t, presig.K)
+
+	_, err = keys[0].SignWithPresignature(context.Background(), presig, big.NewInt(1), params, nil)
+	assert.Error(t, err)
+	assert.NotNil(t, presig.K)
+}
+
+func TestSignWithPresignatureOtherCommittee(t *testing.T) {
+	keys, pIDs := loadTestKeys(t, 4)
+
+	presig := &PreSignature{
+		Ks:    pIDs[:3].Keys(),
+		K:     big.NewInt(1),
+		Sigma: big.NewInt(1),
+	}
+	committee := tss.SortedPartyIDs{pIDs[0], pIDs[1], pIDs[3]}
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(committee), pIDs[0], 3, 2)
+	params.SetBroker(newTestHub(3).brokers[0])
+
+	_, err := keys[0].SignWithPresignature(context.Background(), presig, big.NewInt(1), params, markNothing)
+	assert.Error(t, err)
+	// the presignature can still be used with its committee
+	assert.NotNil(t, presig.K)
+}
+
+// startPresignings starts the presigning of the parties of pIDs over a test hub, each with
+// the broker returned by broker for its party.
+func startPresignings(t *testing.T, keys []*Key, pIDs tss.SortedPartyIDs, threshold int, broker func(i int, b *hubBroker) tss.MessageBroker) []*Presigning {
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(len(pIDs))
+	presignings := make([]*Presigning, len(pIDs))
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, len(pIDs), threshold)
+		params.SetBroker(broker(i, hub.brokers[i]))
+
+		ps, err := keys[i].NewPresigning(context.Background(), params)
+		require.NoError(t, err)
+		presignings[i] = ps
+	}
+	return presignings
+}
+
+// runSignWithPresignature signs msg with presigs over a test hub, and returns the signings.
+func runSignWithPresignature(t *testing.T, keys []*Key, pIDs tss.SortedPartyIDs, threshold int, presigs []*PreSignature, msg *big.Int) []*Signing {
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(len(pIDs))
+	signings := make([]*Signing, len(pIDs))
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, len(pIDs), threshold)
+		params.SetBroker(hub.brokers[i])
+
+		sg, err := keys[i].SignWithPresignature(context.Background(), presigs[i], msg, params, markNothing)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+	return signings
+}
+
+// TestSignWithPresignatureIdentifiesBadSi checks that a party signing with a corrupted
+// presignature is reported as the culprit of the online round, and that partial signatures
+// made with another presignature blame no one.
+func TestSignWithPresignatureIdentifiesBadSi(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	presignings := startPresignings(t, keys, pIDs, threshold, func(_ int, b *hubBroker) tss.MessageBroker { return b })
+	presigs := make([]*PreSignature, signerCount)
+	copies := make([]*PreSignature, signerCount)
+	for i, ps := range presignings {
+		select {
+		case presigs[i] = <-ps.Done:
+			buf, err := json.Marshal(presigs[i])
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(buf, &copies[i]))
+		case err := <-ps.Err:
+			t.Fatalf("Party %d presigning error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d presigning timed out", i)
+		}
+	}
+
+	presigs[2].Sigma = new(big.Int).Add(presigs[2].Sigma, big.NewInt(1))
+	signings := runSignWithPresignature(t, keys, pIDs, threshold, presigs, big.NewInt(42))
+	for i, sg := range signings[:2] {
+		tssErr := waitSigningError(t, i, sg)
+		assert.Equal(t, 2, tssErr.Round())
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	}
+
+	// party 2 signs with a presignature it believes comes from another presigning
+	copies[2].ID = common.SHA512_256(copies[2].ID)
+	signings = runSignWithPresignature(t, keys, pIDs, threshold, copies, big.NewInt(42))
+	for i, sg := range signings {
+		tssErr := waitSigningError(t, i, sg)
+		assert.Equal(t, 2, tssErr.Round())
+		assert.Empty(t, tssErr.Culprits())
+	}
+}
+
+// TestPresigningChecksPoints checks that presigning fails without culprits when the points
+// sigma_j·R do not add up to the public key.
+func TestPresigningChecksPoints(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	presignings := startPresignings(t, keys, pIDs, threshold, func(i int, b *hubBroker) tss.MessageBroker {
+		if i != 2 {
+			return b
+		}
+		// party 2 broadcasts sigma_2·R + G instead of sigma_2·R
+		return &tamperBroker{hubBroker: b, typ: "ecdsa:presign:round5", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+			r5msg, ok := msg.Data.(*presignRound5msg)
+			if !ok {
+				return msg
+			}
+			bigS, err := crypto.NewECPoint(tss.S256(), new(big.Int).SetBytes(r5msg.SX), new(big.Int).SetBytes(r5msg.SY))
+			require.NoError(t, err)
+			bigS, err = bigS.Add(crypto.ScalarBaseMult(tss.S256(), big.NewInt(1)))
+			require.NoError(t, err)
+			bad := *r5msg
+			bad.SX, bad.SY = bigS.X().Bytes(), bigS.Y().Bytes()
+			return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+		}}
+	})
+	for i, ps := range presignings[:2] {
+		select {
+		case <-ps.Done:
+			t.Fatalf("Party %d should reject the presignature points", i)
+		case err := <-ps.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskPresigning, tssErr.Task())
+			assert.Equal(t, 6, tssErr.Round())
+			assert.Empty(t, tssErr.Culprits())
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d presigning timed out", i)
+		}
+	}
+}
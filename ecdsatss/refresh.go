@@ -0,0 +1,467 @@
+package ecdsatss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/dlnproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/facproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/modproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskRefresh is the task name reported in errors from Refresh.
+const TaskRefresh = "ecdsa-refresh"
+
+// Refresh tracks the proactive refresh of the shares of a key. Every party shares a random
+// polynomial of constant term 0, committed to with Feldman VSS: adding these shares to the
+// current ones gives new shares of the same key, so that shares stolen before the refresh
+// cannot be combined with the new ones. A party can also replace its Paillier key and range
+// proof parameters, which are then sent with their proofs as in keygen.
+//
+// All the parties of the key must take part, with the same threshold as at keygen. The
+// public key, the parties and their ShareID are unchanged.
+type Refresh struct {
+	ctx      context.Context
+	params   *tss.Parameters
+	broker   *tss.SessionBroker
+	stop     func() bool
+	ssid     []byte
+	key      *Key              // current key, in the order of the parties of params
+	shares   []*big.Int        // refresh shares of each party
+	polyG    []*crypto.ECPoint // commitments to the coefficients of degree 1 to t
+	deCommit cmts.HashDeCommitment
+	kgcs     []cmts.HashCommitment
+	rotated  []bool // the party replaces its pre-params
+	data     *Key
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewRefresh creates a new Refresh of the shares of key and executes round 1. The refreshed
+// key is sent on Done, and the current one must then be deleted.
+//
+// The pre-params of the key are kept, unless new ones are given in optionalPreParams, for
+// example made by GeneratePreParams ahead of the refresh. They must include the values needed
+// for the proofs (see LocalPreParams.ValidateWithProof). The threshold of params must be the
+// one of the key, and a refresh retried after a failure should use a new session id.
+func (key *Key) NewRefresh(ctx context.Context, params *tss.Parameters, optionalPreParams ...LocalPreParams) (*Refresh, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	if len(key.Ks) != params.PartyCount() {
+		return nil, fmt.Errorf("all %d parties of the key must take part, got %d", len(key.Ks), params.PartyCount())
+	}
+	threshold, err := vss.PublicThreshold(key.Ks, key.BigXj, key.ECDSAPub)
+	if err != nil {
+		return nil, err
+	}
+	if threshold != params.Threshold() {
+		return nil, fmt.Errorf("the threshold of the key is %d, not %d", threshold, params.Threshold())
+	}
+	subsetKey, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	partyCount := params.PartyCount()
+	r := &Refresh{
+		ctx:     ctx,
+		params:  params,
+		key:     subsetKey,
+		kgcs:    make([]cmts.HashCommitment, partyCount),
+		rotated: make([]bool, partyCount),
+		data:    NewKey(partyCount),
+		Done:    make(chan *Key, 1),
+		Err:     make(chan error, 1),
+	}
+	r.data.LocalPreParams = subsetKey.LocalPreParams
+	if len(optionalPreParams) > 0 {
+		if !optionalPreParams[0].ValidateWithProof() {
+			return nil, errors.New("`optionalPreParams` failed to validate; it might have been generated with an older version of tss-lib")
+		}
+		r.data.LocalPreParams = optionalPreParams[0]
+		r.rotated[params.PartyID().Index] = true
+	}
+	r.broker = tss.NewSessionBroker(params.Broker())
+	r.stop = context.AfterFunc(ctx, func() { r.fail(ctx.Err()) })
+	if err := r.round1(); err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+// getSSID returns the ssid of the refresh, binding the curve, the parties, the public shares
+// of the key and the session id, so that a refresh retried with another session id does not
+// reuse the ssid of the previous attempt.
+func (r *Refresh) getSSID() ([]byte, error) {
+	ec := r.params.EC()
+	ssidList := []*big.Int{ec.Params().P, ec.Params().N, ec.Params().B, ec.Params().Gx, ec.Params().Gy}
+	ssidList = append(ssidList, r.params.Parties().IDs().Keys()...)
+	BigXjList, err := crypto.FlattenECPoints(r.key.BigXj)
+	if err != nil {
+		return nil, fmt.Errorf("read BigXj failed: %w", err)
+	}
+	ssidList = append(ssidList, BigXjList...)
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(TaskRefresh)))
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(r.params.SessionID())))
+	return common.SHA512_256i(ssidList...).Bytes(), nil
+}
+
+// round1 makes the refresh polynomial and broadcasts the commitment to it, with the new
+// pre-params of this party.
+func (r *Refresh) round1() error {
+	Pi := r.params.PartyID()
+	i := Pi.Index
+	ec := r.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	ssid, err := r.getSSID()
+	if err != nil {
+		return err
+	}
+	r.ssid = ssid
+
+	// random polynomial f of degree t with f(0) = 0, and the shares f(kj)
+	coefs := make([]*big.Int, r.params.Threshold())
+	r.polyG = make([]*crypto.ECPoint, len(coefs))
+	for c := range coefs {
+		coefs[c] = common.GetRandomPositiveInt(r.params.Rand(), q)
+		r.polyG[c] = crypto.ScalarBaseMult(ec, coefs[c])
+	}
+	r.shares = make([]*big.Int, len(r.key.Ks))
+	for j, kj := range r.key.Ks {
+		share, z := big.NewInt(0), big.NewInt(1)
+		for _, a := range coefs {
+			z = modQ.Mul(z, kj)
+			share = modQ.Add(share, modQ.Mul(a, z))
+		}
+		r.shares[j] = share
+	}
+
+	pGFlat, err := crypto.FlattenECPoints(r.polyG)
+	if err != nil {
+		return err
+	}
+	cmt := cmts.NewHashCommitment(r.params.Rand(), pGFlat...)
+	r.deCommit = cmt.D
+
+	// the other parties keep their pre-params unless they send new ones
+	r.data.Ks = r.key.Ks
+	r.data.ShareID = r.key.ShareID
+	r.data.ECDSAPub = r.key.ECDSAPub
+	copy(r.data.PaillierPKs, r.key.PaillierPKs)
+	copy(r.data.NTildej, r.key.NTildej)
+	copy(r.data.H1j, r.key.H1j)
+	copy(r.data.H2j, r.key.H2j)
+
+	msg := &refreshRound1msg{Commitment: cmt.C.Bytes()}
+	if r.rotated[i] {
+		pp := r.data.LocalPreParams
+		r.data.PaillierPKs[i] = &pp.PaillierSK.PublicKey
+		r.data.NTildej[i] = pp.NTildei
+		r.data.H1j[i], r.data.H2j[i] = pp.H1i, pp.H2i
+
+		dlnProof1 := dlnproof.NewDLNProof(pp.H1i, pp.H2i, pp.Alpha, pp.P, pp.Q, pp.NTildei, r.params.Rand())
+		dlnProof2 := dlnproof.NewDLNProof(pp.H2i, pp.H1i, pp.Beta, pp.P, pp.Q, pp.NTildei, r.params.Rand())
+		if msg.Dlnproof_1, err = dlnProof1.Serialize(); err != nil {
+			return err
+		}
+		if msg.Dlnproof_2, err = dlnProof2.Serialize(); err != nil {
+			return err
+		}
+		msg.PaillierN = pp.PaillierSK.N.Bytes()
+		msg.NTilde = pp.NTildei.Bytes()
+		msg.H1 = pp.H1i.Bytes()
+		msg.H2 = pp.H2i.Bytes()
+	}
+
+	otherIds := r.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range otherIds {
+		r.broker.Receive(tss.JsonWrap(r.params.MsgType("ecdsa:refresh:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[refreshRound1msg](r.params.MsgType("ecdsa:refresh:round1"), otherIds, r.round2, r.timeout(1), r.echo(1, "ecdsa:refresh:round1", otherIds))
+	r.broker.Connect(r.params.MsgType("ecdsa:refresh:round1"), rcv)
+	return nil
+}
+
+// round2 verifies the new pre-params of the other parties, and sends the refresh shares and
+// the de-commitment.
+func (r *Refresh) round2(otherIds []*tss.PartyID, r1msgs []*refreshRound1msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	Pi := r.params.PartyID()
+	i := Pi.Index
+	ec := r.params.EC()
+
+	var errs []error
+	failed := make([]bool, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, r1msg := range r1msgs {
+		Pj := otherIds[n]
+		r.kgcs[Pj.Index] = new(big.Int).SetBytes(r1msg.Commitment)
+		if len(r1msg.PaillierN) == 0 && len(r1msg.NTilde) == 0 {
+			if r.data.PaillierPKs[Pj.Index] == nil || r.data.NTildej[Pj.Index] == nil {
+				errs = append(errs, r.wrapError(2, errors.New("party has no pre-params and did not send new ones"), Pj))
+			}
+			continue
+		}
+
+		paillierPK := &paillier.PublicKey{N: new(big.Int).SetBytes(r1msg.PaillierN)}
+		if paillierPK.N.BitLen() < 2048 {
+			errs = append(errs, r.wrapError(2, fmt.Errorf("paillier modulus bit length %d < 2048", paillierPK.N.BitLen()), Pj))
+			continue
+		}
+		NTildej := new(big.Int).SetBytes(r1msg.NTilde)
+		if NTildej.BitLen() < 2048 {
+			errs = append(errs, r.wrapError(2, fmt.Errorf("NTilde bit length %d < 2048", NTildej.BitLen()), Pj))
+			continue
+		}
+		H1j, H2j := new(big.Int).SetBytes(r1msg.H1), new(big.Int).SetBytes(r1msg.H2)
+		if H1j.Cmp(H2j) == 0 {
+			errs = append(errs, r.wrapError(2, errors.New("H1j == H2j"), Pj))
+			continue
+		}
+
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			dlnPf1, err := dlnproof.UnmarshalDLNProof(r1msgs[n].Dlnproof_1)
+			if err != nil || !dlnPf1.Verify(H1j, H2j, NTildej) {
+				failed[n] = true
+				return
+			}
+			dlnPf2, err := dlnproof.UnmarshalDLNProof(r1msgs[n].Dlnproof_2)
+			if err != nil || !dlnPf2.Verify(H2j, H1j, NTildej) {
+				failed[n] = true
+			}
+		}(n)
+
+		r.rotated[Pj.Index] = true
+		r.data.PaillierPKs[Pj.Index] = paillierPK
+		r.data.NTildej[Pj.Index] = NTildej
+		r.data.H1j[Pj.Index], r.data.H2j[Pj.Index] = H1j, H2j
+	}
+	wg.Wait()
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		if failed[n] {
+			culprits = append(culprits, Pj)
+		}
+	}
+	if len(culprits) > 0 {
+		errs = append(errs, r.wrapError(2, errors.New("DLN proof verification failed"), culprits...))
+	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		r.fail(err)
+		return
+	}
+
+	// The Paillier modulus of this party is proven against the range proof parameters of
+	// each party, when one of them is new
+	ContextI := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(i)))
+	sk := r.data.PaillierSK
+	for _, Pj := range otherIds {
+		r2msg1 := &refreshRound2msg1{Share: r.shares[Pj.Index].Bytes()}
+		if !r.params.NoProofFac() && (r.rotated[i] || r.rotated[Pj.Index]) {
+			fp, err := facproof.NewProof(ContextI, ec, sk.N, r.data.NTildej[Pj.Index], r.data.H1j[Pj.Index], r.data.H2j[Pj.Index], sk.P, sk.Q, r.params.Rand())
+			if err != nil {
+				r.fail(r.wrapError(2, fmt.Errorf("failed to generate fac proof for party %s: %w", Pj, err)))
+				return
+			}
+			bzs := fp.Bytes()
+			r2msg1.FacProof = bzs[:]
+		}
+		r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("ecdsa:refresh:round2-1"), r2msg1, Pi, Pj))
+	}
+
+	r2msg2 := &refreshRound2msg2{DeCommitment: common.BigIntsToBytes(r.deCommit)}
+	if !r.params.NoProofMod() && r.rotated[i] {
+		mp, err := modproof.NewProof(ContextI, sk.N, sk.P, sk.Q, r.params.Rand())
+		if err != nil {
+			r.fail(r.wrapError(2, fmt.Errorf("failed to generate mod proof: %w", err)))
+			return
+		}
+		bzs := mp.Bytes()
+		r2msg2.ModProof = bzs[:]
+	}
+	for _, Pj := range otherIds {
+		r.broker.Receive(tss.JsonWrap(r.params.MsgType("ecdsa:refresh:round2-2"), r2msg2, Pi, Pj))
+	}
+
+	var pending int32 = 2
+	var r2msgs1 []*refreshRound2msg1
+	var r2msgs2 []*refreshRound2msg2
+	check := func() {
+		if atomic.AddInt32(&pending, -1) == 0 {
+			r.round3(otherIds, r2msgs1, r2msgs2)
+		}
+	}
+	rcv1 := tss.NewJsonExpect[refreshRound2msg1](r.params.MsgType("ecdsa:refresh:round2-1"), otherIds, func(_ []*tss.PartyID, msgs []*refreshRound2msg1) {
+		r2msgs1 = msgs
+		check()
+	}, r.timeout(2), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("ecdsa:refresh:round2-1"), rcv1)
+	rcv2 := tss.NewJsonExpect[refreshRound2msg2](r.params.MsgType("ecdsa:refresh:round2-2"), otherIds, func(_ []*tss.PartyID, msgs []*refreshRound2msg2) {
+		r2msgs2 = msgs
+		check()
+	}, r.timeout(2), r.echo(2, "ecdsa:refresh:round2-2", otherIds))
+	r.broker.Connect(r.params.MsgType("ecdsa:refresh:round2-2"), rcv2)
+}
+
+// round3 verifies the refresh shares and proofs of the other parties, and computes the new
+// shares.
+func (r *Refresh) round3(otherIds []*tss.PartyID, r2msgs1 []*refreshRound2msg1, r2msgs2 []*refreshRound2msg2) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	i := r.params.PartyID().Index
+	ec := r.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+	threshold := r.params.Threshold()
+
+	polyGs := make([][]*crypto.ECPoint, r.params.PartyCount())
+	polyGs[i] = r.polyG
+	shares := make([]*big.Int, len(otherIds))
+	errs := make([]error, len(otherIds))
+	wg := new(sync.WaitGroup)
+	for n, Pj := range otherIds {
+		wg.Add(1)
+		go func(n int, Pj *tss.PartyID) {
+			defer wg.Done()
+			j := Pj.Index
+			cmtDeCmt := cmts.HashCommitDecommit{C: r.kgcs[j], D: cmts.NewHashDeCommitmentFromBytes(r2msgs2[n].DeCommitment)}
+			ok, flatPolyG := cmtDeCmt.DeCommit()
+			if !ok || len(flatPolyG) != 2*threshold {
+				errs[n] = r.wrapError(3, errors.New("decommitment verification failed"), Pj)
+				return
+			}
+			polyG, err := crypto.UnFlattenECPoints(ec, flatPolyG)
+			if err != nil {
+				errs[n] = r.wrapError(3, fmt.Errorf("unflatten EC points failed: %w", err), Pj)
+				return
+			}
+			share := new(big.Int).SetBytes(r2msgs1[n].Share)
+			expected, err := vss.ZeroSecretPublicShare(polyG, r.key.Ks[i])
+			if err != nil || share.Cmp(q) >= 0 || !crypto.ScalarBaseMult(ec, share).Equals(expected) {
+				errs[n] = r.wrapError(3, errors.New("refresh share verification failed"), Pj)
+				return
+			}
+
+			ContextJ := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(j)))
+			Nj := r.data.PaillierPKs[j].N
+			if !r.params.NoProofMod() && r.rotated[j] {
+				mp, err := modproof.NewProofFromBytes(r2msgs2[n].ModProof)
+				if err != nil || !mp.Verify(ContextJ, Nj) {
+					errs[n] = r.wrapError(3, errors.New("mod proof verification failed"), Pj)
+					return
+				}
+			}
+			if !r.params.NoProofFac() && (r.rotated[i] || r.rotated[j]) {
+				fp, err := facproof.NewProofFromBytes(r2msgs1[n].FacProof)
+				if err != nil || !fp.Verify(ContextJ, ec, Nj, r.data.NTildei, r.data.H1i, r.data.H2i) {
+					errs[n] = r.wrapError(3, errors.New("fac proof verification failed"), Pj)
+					return
+				}
+			}
+			polyGs[j] = polyG
+			shares[n] = share
+		}(n, Pj)
+	}
+	wg.Wait()
+	if err := tss.JoinErrors(errs...); err != nil {
+		r.fail(err)
+		return
+	}
+
+	// new xi = xi + sum of the refresh shares
+	xi := modQ.Add(r.key.Xi, r.shares[i])
+	for _, share := range shares {
+		xi = modQ.Add(xi, share)
+	}
+	r.data.Xi = xi
+
+	// new Xj = Xj + sum of the refresh polynomials evaluated at kj
+	for j, kj := range r.key.Ks {
+		BigXj := r.key.BigXj[j]
+		for _, polyG := range polyGs {
+			Fj, err := vss.ZeroSecretPublicShare(polyG, kj)
+			if err == nil {
+				BigXj, err = BigXj.Add(Fj)
+			}
+			if err != nil {
+				r.fail(r.wrapError(3, fmt.Errorf("failed computing BigXj for party %d: %w", j, err)))
+				return
+			}
+		}
+		r.data.BigXj[j] = BigXj
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(r.data.BigXj[i]) {
+		r.fail(r.wrapError(3, errors.New("refreshed share does not match its public point")))
+		return
+	}
+
+	r.release()
+	r.Done <- r.data
+}
+
+// fail reports err on Err and releases the receivers registered by this refresh.
+func (r *Refresh) fail(err error) {
+	r.release()
+	select {
+	case r.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this refresh from the broker.
+func (r *Refresh) release() {
+	r.stop()
+	r.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (r *Refresh) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(r.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (r *Refresh) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !r.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(r.broker, r.params.MsgType(typ+":echo"), r.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (r *Refresh) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskRefresh, round, r.params.PartyID(), culprits...)
+}
@@ -0,0 +1,145 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestRefreshAndSign(t *testing.T) {
+	const (
+		partyCount = 5
+		threshold  = 2
+	)
+
+	fixtures, pIDs := loadTestKeys(t, partyCount)
+	keys := make([]*Key, partyCount)
+	for n, p := range pIDs {
+		for _, key := range fixtures {
+			if key.ShareID.Cmp(p.KeyInt()) == 0 {
+				keys[n] = key
+			}
+		}
+	}
+
+	// the first two parties rotate their pre-params, swapping them
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	refreshes := make([]*Refresh, partyCount)
+	for n, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[n])
+
+		var preParams []LocalPreParams
+		if n < 2 {
+			preParams = append(preParams, keys[1-n].LocalPreParams)
+		}
+		r, err := keys[n].NewRefresh(context.Background(), params, preParams...)
+		require.NoError(t, err)
+		refreshes[n] = r
+	}
+	newKeys := make([]*Key, partyCount)
+	for n, r := range refreshes {
+		select {
+		case newKeys[n] = <-r.Done:
+		case err := <-r.Err:
+			t.Fatalf("Party %d refresh error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d refresh timed out", n)
+		}
+	}
+
+	shares := make(vss.Shares, partyCount)
+	for n, key := range newKeys {
+		assert.True(t, keys[n].ECDSAPub.Equals(key.ECDSAPub))
+		assert.Equal(t, 0, keys[n].ShareID.Cmp(key.ShareID))
+		assert.NotEqual(t, 0, keys[n].Xi.Cmp(key.Xi), "share of party %d should change", n)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[n]))
+		for j := range newKeys {
+			assert.True(t, newKeys[0].BigXj[j].Equals(key.BigXj[j]))
+			assert.Equal(t, 0, newKeys[j].NTildei.Cmp(key.NTildej[j]))
+			assert.Equal(t, 0, newKeys[j].PaillierSK.N.Cmp(key.PaillierPKs[j].N))
+		}
+		shares[n] = &vss.Share{Threshold: threshold, ID: key.ShareID, Share: key.Xi}
+	}
+	assert.Equal(t, 0, newKeys[0].NTildei.Cmp(keys[1].NTildei))
+	assert.Equal(t, 0, newKeys[2].NTildei.Cmp(keys[2].NTildei))
+	secret, err := shares.ReConstruct(tss.S256())
+	require.NoError(t, err)
+	assert.True(t, crypto.ScalarBaseMult(tss.S256(), secret).Equals(keys[0].ECDSAPub))
+
+	// a committee of t+1 parties signs with the refreshed shares
+	msgHash := sha256.Sum256([]byte("hello world"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	var unsorted tss.UnSortedPartyIDs
+	for _, p := range pIDs[:threshold+1] {
+		unsorted = append(unsorted, tss.NewPartyID(p.Id, p.Moniker, p.KeyInt()))
+	}
+	signIDs := tss.SortPartyIDs(unsorted)
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.S256(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		sg, err := newKeys[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     keys[0].ECDSAPub.X(),
+		Y:     keys[0].ECDSAPub.Y(),
+	}
+	assert.True(t, ecdsa.Verify(&pk, msgHash[:], new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+}
+
+func TestRefreshThresholdAndSSID(t *testing.T) {
+	keys, pIDs := loadTestKeys(t, 5)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	var key *Key
+	for _, k := range keys {
+		if k.ShareID.Cmp(pIDs[0].KeyInt()) == 0 {
+			key = k
+		}
+	}
+
+	// a threshold which is not the one of the key is rejected
+	params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[0], len(pIDs), 3)
+	params.SetBroker(newTestHub(len(pIDs)).brokers[0])
+	_, err := key.NewRefresh(context.Background(), params)
+	assert.ErrorContains(t, err, "the threshold of the key is 2, not 3")
+
+	// the session id is bound in the ssid
+	params = tss.NewParameters(tss.S256(), p2pCtx, pIDs[0], len(pIDs), 2)
+	subsetKey, err := key.SubsetForParties(pIDs)
+	require.NoError(t, err)
+	r := &Refresh{params: params, key: subsetKey}
+	ssid1, err := r.getSSID()
+	require.NoError(t, err)
+	params.SetSessionID("retry")
+	ssid2, err := r.getSSID()
+	require.NoError(t, err)
+	assert.NotEqual(t, ssid1, ssid2)
+}
@@ -0,0 +1,530 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/dlnproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/facproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/modproof"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/paillier"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskRepair is the task name reported in errors from Repair.
+const TaskRepair = "ecdsa-repair"
+
+// Repair tracks the repair of the share of a party of a key that lost it. At least t+1 other
+// parties of the key, the helpers, compute the lost share at its ShareID without anyone
+// learning it: each helper splits its Lagrange term of the lost share, over the helpers, in
+// random parts sent to the other helpers, and only the sums of the parts received are sent to
+// the lost party.
+//
+// As its Paillier key was lost with its share, the lost party uses new pre-params, sent to
+// the helpers with the same proofs as in keygen. The shares of the helpers are unchanged, but
+// they record the new Paillier key and range proof parameters of the lost party. The other
+// parties of the key get them from a PartyAnnouncement made by the repaired party, see
+// Key.Announce and Key.ImportParty.
+type Repair struct {
+	ctx     context.Context
+	params  *tss.Parameters
+	broker  *tss.SessionBroker
+	stop    func() bool
+	ssid    []byte
+	key     *Key // key of the helper, nil for the lost party
+	lost    *tss.PartyID
+	helpers tss.SortedPartyIDs
+	part    *big.Int // part of the Lagrange term of this helper kept by it
+	data    *Key
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewRepair creates a new Repair of the share of the party lost, and executes round 1. The
+// parties of params are the helpers and the lost party. The helpers give their key, and
+// receive it on Done with the new pre-params of the lost party; the lost party gives a nil key
+// and its new pre-params, and receives its repaired key on Done.
+func NewRepair(ctx context.Context, params *tss.Parameters, key *Key, lost *tss.PartyID, optionalPreParams ...LocalPreParams) (*Repair, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key != nil && key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key != nil && key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	lost = params.Parties().IDs().FindByKey(lost.KeyInt())
+	if lost == nil {
+		return nil, errors.New("the lost party must take part in the repair")
+	}
+	helpers := params.Parties().IDs().Exclude(lost)
+	if len(helpers) <= params.Threshold() {
+		return nil, fmt.Errorf("repair needs at least %d helpers, got %d", params.Threshold()+1, len(helpers))
+	}
+	isLost := params.PartyID().KeyInt().Cmp(lost.KeyInt()) == 0
+	if isLost != (key == nil) {
+		return nil, errors.New("the lost party, and only it, must not give a key")
+	}
+	r := &Repair{
+		ctx:     ctx,
+		params:  params,
+		key:     key,
+		lost:    lost,
+		helpers: helpers,
+		Done:    make(chan *Key, 1),
+		Err:     make(chan error, 1),
+	}
+	if isLost {
+		if len(optionalPreParams) == 0 || !optionalPreParams[0].ValidateWithProof() {
+			return nil, errors.New("the lost party must give new pre-params, including the values needed for the proofs")
+		}
+		r.data = &Key{LocalPreParams: optionalPreParams[0]}
+	}
+	r.ssid = r.getSSID()
+	r.broker = tss.NewSessionBroker(params.Broker())
+	r.stop = context.AfterFunc(ctx, func() { r.fail(ctx.Err()) })
+	var err error
+	if isLost {
+		err = r.round1Lost()
+	} else {
+		err = r.round1()
+	}
+	if err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+// getSSID returns the ssid of the repair, binding the curve and the parties.
+func (r *Repair) getSSID() []byte {
+	ec := r.params.EC()
+	ssidList := []*big.Int{ec.Params().P, ec.Params().N, ec.Params().B, ec.Params().Gx, ec.Params().Gy}
+	ssidList = append(ssidList, r.params.Parties().IDs().Keys()...)
+	ssidList = append(ssidList, r.lost.KeyInt())
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(TaskRepair)))
+	return common.SHA512_256i(ssidList...).Bytes()
+}
+
+// round1 splits the Lagrange term of this helper in random parts, and sends them to the
+// other helpers.
+func (r *Repair) round1() error {
+	Pi := r.params.PartyID()
+	q := r.params.EC().Params().N
+
+	// the lost party must be a party of the key
+	if _, err := r.key.SubsetForParties(r.params.Parties().IDs()); err != nil {
+		return err
+	}
+	helpersKey, err := r.key.SubsetForParties(r.helpers)
+	if err != nil {
+		return err
+	}
+	n := -1
+	for m, Pj := range r.helpers {
+		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
+			n = m
+		}
+	}
+	parts, err := vss.LagrangeParts(r.params.Rand(), q, helpersKey.Ks, n, r.lost.KeyInt(), r.key.Xi)
+	if err != nil {
+		return err
+	}
+	r.part = parts[n]
+	for m, Pj := range r.helpers {
+		if m == n {
+			continue
+		}
+		msg := &repairRound1msg1{Part: parts[m].Bytes()}
+		r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("ecdsa:repair:round1-1"), msg, Pi, Pj))
+	}
+
+	otherIds := r.helpers.Exclude(Pi)
+	lostIds := []*tss.PartyID{r.lost}
+	var pending int32 = 2
+	var r1msgs1 []*repairRound1msg1
+	var r1msg2 *repairRound1msg2
+	check := func() {
+		if atomic.AddInt32(&pending, -1) == 0 {
+			r.round2(otherIds, r1msgs1, r1msg2)
+		}
+	}
+	rcv1 := tss.NewJsonExpect[repairRound1msg1](r.params.MsgType("ecdsa:repair:round1-1"), otherIds, func(_ []*tss.PartyID, msgs []*repairRound1msg1) {
+		r1msgs1 = msgs
+		check()
+	}, r.timeout(1), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("ecdsa:repair:round1-1"), rcv1)
+	rcv2 := tss.NewJsonExpect[repairRound1msg2](r.params.MsgType("ecdsa:repair:round1-2"), lostIds, func(_ []*tss.PartyID, msgs []*repairRound1msg2) {
+		r1msg2 = msgs[0]
+		check()
+	}, r.timeout(1), r.echo(1, "ecdsa:repair:round1-2", otherIds))
+	r.broker.Connect(r.params.MsgType("ecdsa:repair:round1-2"), rcv2)
+	return nil
+}
+
+// round1Lost sends the new pre-params of the lost party to the helpers, with their proofs.
+func (r *Repair) round1Lost() error {
+	Pi := r.params.PartyID()
+	pp := r.data.LocalPreParams
+
+	ContextI := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(Pi.Index)))
+	msg, err := newAuxInfoMsg(ContextI, pp, r.params)
+	if err != nil {
+		return err
+	}
+	for _, Pj := range r.helpers {
+		r.broker.Receive(tss.JsonWrap(r.params.MsgType("ecdsa:repair:round1-2"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[repairRound2msg](r.params.MsgType("ecdsa:repair:round2"), r.helpers, r.round3, r.timeout(2), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("ecdsa:repair:round2"), rcv)
+	return nil
+}
+
+// round2 verifies the new pre-params of the lost party, and sends it the sum of the parts
+// received by this helper with the public data of the key.
+func (r *Repair) round2(otherIds []*tss.PartyID, r1msgs1 []*repairRound1msg1, r1msg2 *repairRound1msg2) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	Pi := r.params.PartyID()
+	ec := r.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+
+	ContextJ := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(r.lost.Index)))
+	paillierPK, NTildej, H1j, H2j, err := parseAuxInfoMsg(ContextJ, r1msg2, r.params)
+	if err != nil {
+		r.fail(r.wrapError(2, err, r.lost))
+		return
+	}
+
+	// the helper records the new pre-params of the lost party
+	data := *r.key
+	data.PaillierPKs = append([]*paillier.PublicKey(nil), r.key.PaillierPKs...)
+	data.NTildej = append([]*big.Int(nil), r.key.NTildej...)
+	data.H1j = append([]*big.Int(nil), r.key.H1j...)
+	data.H2j = append([]*big.Int(nil), r.key.H2j...)
+	for j, kj := range data.Ks {
+		if kj.Cmp(r.lost.KeyInt()) == 0 {
+			data.PaillierPKs[j] = paillierPK
+			data.NTildej[j], data.H1j[j], data.H2j[j] = NTildej, H1j, H2j
+		}
+	}
+	r.data = &data
+
+	sum := r.part
+	for _, r1msg := range r1msgs1 {
+		sum = modQ.Add(sum, new(big.Int).SetBytes(r1msg.Part))
+	}
+	msg, err := newRepairRound2msg(r.key, sum)
+	if err != nil {
+		r.fail(r.wrapError(2, err))
+		return
+	}
+	if !r.params.NoProofFac() {
+		ContextI := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(Pi.Index)))
+		sk := r.key.PaillierSK
+		fp, err := facproof.NewProof(ContextI, ec, sk.N, NTildej, H1j, H2j, sk.P, sk.Q, r.params.Rand())
+		if err != nil {
+			r.fail(r.wrapError(2, fmt.Errorf("failed to generate fac proof: %w", err)))
+			return
+		}
+		bzs := fp.Bytes()
+		msg.FacProof = bzs[:]
+	}
+	r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("ecdsa:repair:round2"), msg, Pi, r.lost))
+
+	rcv := tss.NewJsonExpect[repairRound3msg](r.params.MsgType("ecdsa:repair:round3"), []*tss.PartyID{r.lost}, r.round4, r.timeout(3), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("ecdsa:repair:round3"), rcv)
+}
+
+// round3 checks that the helpers sent the same public data and verifies their proofs,
+// computes the lost share, and sends the proofs of the new Paillier key to the helpers.
+func (r *Repair) round3(helpers []*tss.PartyID, r2msgs []*repairRound2msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	Pi := r.params.PartyID()
+	ec := r.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+	pp := r.data.LocalPreParams
+
+	candidates := make([]*Key, len(r2msgs))
+	xi := big.NewInt(0)
+	for n, r2msg := range r2msgs {
+		candidate, err := repairPublicData(ec, r2msg)
+		if err != nil {
+			r.fail(r.wrapError(3, err, helpers[n]))
+			return
+		}
+		candidates[n] = candidate
+		xi = modQ.Add(xi, new(big.Int).SetBytes(r2msg.Sum))
+	}
+	for _, candidate := range candidates[1:] {
+		if !candidates[0].samePublicData(candidate) {
+			// the helpers outvoted by the others are the culprits, if there is a majority
+			r.fail(r.wrapError(3, errors.New("different public data of the key"), tss.MinorityCulprits(helpers, candidates, (*Key).samePublicData)...))
+			return
+		}
+	}
+	data := candidates[0]
+	data.LocalPreParams = pp
+	data.Xi = xi
+	data.ShareID = Pi.KeyInt()
+	i := -1
+	indexes := make(map[string]int, len(data.Ks))
+	for j, kj := range data.Ks {
+		if kj.Cmp(data.ShareID) == 0 {
+			i = j
+		}
+		indexes[string(kj.Bytes())] = j
+	}
+	if i < 0 {
+		r.fail(r.wrapError(3, errors.New("the lost party is not a party of the key")))
+		return
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(data.BigXj[i]) {
+		r.fail(r.wrapError(3, errors.New("repaired share does not match its public point")))
+		return
+	}
+
+	if !r.params.NoProofFac() {
+		var culprits []*tss.PartyID
+		for n, Pj := range helpers {
+			ContextJ := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(Pj.Index)))
+			Nj := data.PaillierPKs[indexes[string(Pj.KeyInt().Bytes())]].N
+			fp, err := facproof.NewProofFromBytes(r2msgs[n].FacProof)
+			if err != nil || !fp.Verify(ContextJ, ec, Nj, pp.NTildei, pp.H1i, pp.H2i) {
+				culprits = append(culprits, Pj)
+			}
+		}
+		if len(culprits) > 0 {
+			r.fail(r.wrapError(3, errors.New("fac proof verification failed"), culprits...))
+			return
+		}
+	}
+	data.PaillierPKs[i] = &pp.PaillierSK.PublicKey
+	data.NTildej[i], data.H1j[i], data.H2j[i] = pp.NTildei, pp.H1i, pp.H2i
+
+	ContextI := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(Pi.Index)))
+	for _, Pj := range helpers {
+		msg := &repairRound3msg{}
+		if !r.params.NoProofFac() {
+			j := indexes[string(Pj.KeyInt().Bytes())]
+			fp, err := facproof.NewProof(ContextI, ec, pp.PaillierSK.N, data.NTildej[j], data.H1j[j], data.H2j[j], pp.PaillierSK.P, pp.PaillierSK.Q, r.params.Rand())
+			if err != nil {
+				r.fail(r.wrapError(3, fmt.Errorf("failed to generate fac proof for party %s: %w", Pj, err)))
+				return
+			}
+			bzs := fp.Bytes()
+			msg.FacProof = bzs[:]
+		}
+		r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("ecdsa:repair:round3"), msg, Pi, Pj))
+	}
+
+	r.release()
+	r.Done <- data
+}
+
+// round4 verifies the proof of the new Paillier key of the lost party.
+func (r *Repair) round4(_ []*tss.PartyID, r3msgs []*repairRound3msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	if !r.params.NoProofFac() {
+		j := -1
+		for m, kj := range r.data.Ks {
+			if kj.Cmp(r.lost.KeyInt()) == 0 {
+				j = m
+			}
+		}
+		ContextJ := common.AppendBigIntToBytesSlice(r.ssid, big.NewInt(int64(r.lost.Index)))
+		fp, err := facproof.NewProofFromBytes(r3msgs[0].FacProof)
+		if err != nil || !fp.Verify(ContextJ, r.params.EC(), r.data.PaillierPKs[j].N, r.key.NTildei, r.key.H1i, r.key.H2i) {
+			r.fail(r.wrapError(4, errors.New("fac proof verification failed"), r.lost))
+			return
+		}
+	}
+
+	r.release()
+	r.Done <- r.data
+}
+
+// newAuxInfoMsg returns the message sending the Paillier key and range proof parameters of
+// pp, with their proofs.
+func newAuxInfoMsg(ContextI []byte, pp LocalPreParams, params *tss.Parameters) (*repairRound1msg2, error) {
+	dlnProof1 := dlnproof.NewDLNProof(pp.H1i, pp.H2i, pp.Alpha, pp.P, pp.Q, pp.NTildei, params.Rand())
+	dlnProof2 := dlnproof.NewDLNProof(pp.H2i, pp.H1i, pp.Beta, pp.P, pp.Q, pp.NTildei, params.Rand())
+	msg := &repairRound1msg2{
+		PaillierN: pp.PaillierSK.N.Bytes(),
+		NTilde:    pp.NTildei.Bytes(),
+		H1:        pp.H1i.Bytes(),
+		H2:        pp.H2i.Bytes(),
+	}
+	var err error
+	if msg.Dlnproof_1, err = dlnProof1.Serialize(); err != nil {
+		return nil, err
+	}
+	if msg.Dlnproof_2, err = dlnProof2.Serialize(); err != nil {
+		return nil, err
+	}
+	if !params.NoProofMod() {
+		mp, err := modproof.NewProof(ContextI, pp.PaillierSK.N, pp.PaillierSK.P, pp.PaillierSK.Q, params.Rand())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate mod proof: %w", err)
+		}
+		bzs := mp.Bytes()
+		msg.ModProof = bzs[:]
+	}
+	return msg, nil
+}
+
+// parseAuxInfoMsg returns the Paillier key and range proof parameters sent in msg, once their
+// proofs are verified.
+func parseAuxInfoMsg(ContextJ []byte, msg *repairRound1msg2, params *tss.Parameters) (*paillier.PublicKey, *big.Int, *big.Int, *big.Int, error) {
+	paillierPK := &paillier.PublicKey{N: new(big.Int).SetBytes(msg.PaillierN)}
+	if paillierPK.N.BitLen() < 2048 {
+		return nil, nil, nil, nil, fmt.Errorf("paillier modulus bit length %d < 2048", paillierPK.N.BitLen())
+	}
+	NTildej := new(big.Int).SetBytes(msg.NTilde)
+	if NTildej.BitLen() < 2048 {
+		return nil, nil, nil, nil, fmt.Errorf("NTilde bit length %d < 2048", NTildej.BitLen())
+	}
+	H1j, H2j := new(big.Int).SetBytes(msg.H1), new(big.Int).SetBytes(msg.H2)
+	if H1j.Cmp(H2j) == 0 {
+		return nil, nil, nil, nil, errors.New("H1j == H2j")
+	}
+	dlnPf1, err := dlnproof.UnmarshalDLNProof(msg.Dlnproof_1)
+	if err != nil || !dlnPf1.Verify(H1j, H2j, NTildej) {
+		return nil, nil, nil, nil, errors.New("DLN proof verification failed")
+	}
+	dlnPf2, err := dlnproof.UnmarshalDLNProof(msg.Dlnproof_2)
+	if err != nil || !dlnPf2.Verify(H2j, H1j, NTildej) {
+		return nil, nil, nil, nil, errors.New("DLN proof verification failed")
+	}
+	if !params.NoProofMod() {
+		mp, err := modproof.NewProofFromBytes(msg.ModProof)
+		if err != nil || !mp.Verify(ContextJ, paillierPK.N) {
+			return nil, nil, nil, nil, errors.New("mod proof verification failed")
+		}
+	}
+	return paillierPK, NTildej, H1j, H2j, nil
+}
+
+// newRepairRound2msg returns the message sending sum and the public data of key.
+func newRepairRound2msg(key *Key, sum *big.Int) (*repairRound2msg, error) {
+	flatBigXj, err := crypto.FlattenECPoints(key.BigXj)
+	if err != nil {
+		return nil, err
+	}
+	paillierNj := make([]*big.Int, len(key.PaillierPKs))
+	for j, pk := range key.PaillierPKs {
+		paillierNj[j] = pk.N
+	}
+	return &repairRound2msg{
+		Sum:        sum.Bytes(),
+		Ks:         common.BigIntsToBytes(key.Ks),
+		BigXj:      common.BigIntsToBytes(flatBigXj),
+		ECDSAPubX:  key.ECDSAPub.X().Bytes(),
+		ECDSAPubY:  key.ECDSAPub.Y().Bytes(),
+		PaillierNj: common.BigIntsToBytes(paillierNj),
+		NTildej:    common.BigIntsToBytes(key.NTildej),
+		H1j:        common.BigIntsToBytes(key.H1j),
+		H2j:        common.BigIntsToBytes(key.H2j),
+	}, nil
+}
+
+// repairPublicData returns the key of the public data sent by a helper to the lost party.
+func repairPublicData(ec elliptic.Curve, msg *repairRound2msg) (*Key, error) {
+	BigXj, err := crypto.UnFlattenECPoints(ec, common.MultiBytesToBigInts(msg.BigXj))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BigXj: %w", err)
+	}
+	partyCount := len(BigXj)
+	if len(msg.Ks) != partyCount || len(msg.PaillierNj) != partyCount || len(msg.NTildej) != partyCount ||
+		len(msg.H1j) != partyCount || len(msg.H2j) != partyCount {
+		return nil, fmt.Errorf("public data is not complete for %d parties", partyCount)
+	}
+	pub, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(msg.ECDSAPubX), new(big.Int).SetBytes(msg.ECDSAPubY))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECDSAPub: %w", err)
+	}
+	key := NewKey(partyCount)
+	key.Ks = common.MultiBytesToBigInts(msg.Ks)
+	key.BigXj = BigXj
+	key.ECDSAPub = pub
+	key.NTildej = common.MultiBytesToBigInts(msg.NTildej)
+	key.H1j = common.MultiBytesToBigInts(msg.H1j)
+	key.H2j = common.MultiBytesToBigInts(msg.H2j)
+	for j, N := range common.MultiBytesToBigInts(msg.PaillierNj) {
+		key.PaillierPKs[j] = &paillier.PublicKey{N: N}
+	}
+	return key, nil
+}
+
+// samePublicData returns whether key and other have the same parties, public shares, public
+// key and auxiliary info.
+func (key *Key) samePublicData(other *Key) bool {
+	if len(key.Ks) != len(other.Ks) || !key.ECDSAPub.Equals(other.ECDSAPub) {
+		return false
+	}
+	for j := range key.Ks {
+		if key.Ks[j].Cmp(other.Ks[j]) != 0 || !key.BigXj[j].Equals(other.BigXj[j]) ||
+			key.PaillierPKs[j].N.Cmp(other.PaillierPKs[j].N) != 0 || key.NTildej[j].Cmp(other.NTildej[j]) != 0 ||
+			key.H1j[j].Cmp(other.H1j[j]) != 0 || key.H2j[j].Cmp(other.H2j[j]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fail reports err on Err and releases the receivers registered by this repair.
+func (r *Repair) fail(err error) {
+	r.release()
+	select {
+	case r.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this repair from the broker.
+func (r *Repair) release() {
+	r.stop()
+	r.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (r *Repair) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(r.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (r *Repair) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !r.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(r.broker, r.params.MsgType(typ+":echo"), r.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (r *Repair) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskRepair, round, r.params.PartyID(), culprits...)
+}
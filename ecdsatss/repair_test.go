@@ -0,0 +1,133 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// subsetIDs returns sorted clones of the ids of the given parties, keeping the indexes of pIDs.
+func subsetIDs(pIDs tss.SortedPartyIDs, idx ...int) tss.SortedPartyIDs {
+	var unsorted tss.UnSortedPartyIDs
+	for _, k := range idx {
+		unsorted = append(unsorted, tss.NewPartyID(pIDs[k].Id, pIDs[k].Moniker, pIDs[k].KeyInt()))
+	}
+	return tss.SortPartyIDs(unsorted)
+}
+
+func TestRepairAndSign(t *testing.T) {
+	const (
+		partyCount = 4
+		threshold  = 1
+	)
+
+	keys, pIDs := runKeygen(t, partyCount, threshold)
+	fixtures, _ := loadTestKeys(t, partyCount+1)
+
+	// party 0 lost its key and uses the pre-params of another fixture, parties 1 and 2 help it
+	// and party 3 does not take part
+	repairIDs := subsetIDs(pIDs, 0, 1, 2)
+	repairCtx := tss.NewPeerContext(repairIDs)
+	hub := newTestHub(len(repairIDs))
+	repairs := make([]*Repair, len(repairIDs))
+	for n, p := range repairIDs {
+		params := tss.NewParameters(tss.S256(), repairCtx, p, len(repairIDs), threshold)
+		params.SetBroker(hub.brokers[n])
+
+		var r *Repair
+		var err error
+		if n == 0 {
+			r, err = NewRepair(context.Background(), params, nil, repairIDs[0], fixtures[partyCount].LocalPreParams)
+		} else {
+			r, err = NewRepair(context.Background(), params, keys[n], repairIDs[0])
+		}
+		require.NoError(t, err)
+		repairs[n] = r
+	}
+	repaired := make([]*Key, partyCount)
+	for n, r := range repairs {
+		select {
+		case repaired[n] = <-r.Done:
+		case err := <-r.Err:
+			t.Fatalf("Party %d repair error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d repair timed out", n)
+		}
+	}
+
+	key := repaired[0]
+	assert.Equal(t, 0, keys[0].Xi.Cmp(key.Xi))
+	assert.Equal(t, 0, keys[0].ShareID.Cmp(key.ShareID))
+	assert.True(t, keys[0].ECDSAPub.Equals(key.ECDSAPub))
+	assert.Equal(t, 0, fixtures[partyCount].NTildei.Cmp(key.NTildei))
+	// the keys given by the helpers are not modified
+	assert.Equal(t, 0, keys[1].NTildej[0].Cmp(keys[0].NTildei))
+
+	// party 3 imports the new pre-params of party 0 from its announcement
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], partyCount, threshold)
+	ann, err := key.Announce(params, keys[3].ShareID)
+	require.NoError(t, err)
+	repaired[3], err = keys[3].ImportParty(params, ann)
+	require.NoError(t, err)
+	assert.Equal(t, 0, keys[3].NTildej[0].Cmp(keys[0].NTildei), "the imported key is a copy")
+
+	// the announcement is bound to its recipient and to the share of the party
+	_, err = keys[2].ImportParty(params, ann)
+	assert.Error(t, err)
+	forged, err := keys[1].Announce(params, keys[3].ShareID)
+	require.NoError(t, err)
+	forged.ShareID = keys[0].ShareID.Bytes()
+	_, err = keys[3].ImportParty(params, forged)
+	assert.ErrorContains(t, err, "Schnorr proof")
+
+	for n, helperKey := range repaired[1:] {
+		assert.Equal(t, 0, keys[n+1].Xi.Cmp(helperKey.Xi))
+		for j := range keys {
+			assert.Equal(t, 0, keys[0].Ks[j].Cmp(helperKey.Ks[j]))
+			assert.True(t, key.BigXj[j].Equals(helperKey.BigXj[j]))
+			assert.Equal(t, 0, key.NTildej[j].Cmp(helperKey.NTildej[j]))
+			assert.Equal(t, 0, key.PaillierPKs[j].N.Cmp(helperKey.PaillierPKs[j].N))
+		}
+	}
+
+	// the repaired party signs with the party which did not help
+	msgHash := sha256.Sum256([]byte("hello world"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	signers := []int{0, 3}
+	signIDs := subsetIDs(pIDs, signers...)
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.S256(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		sg, err := repaired[signers[n]].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := ecdsa.PublicKey{
+		Curve: tss.S256(),
+		X:     keys[0].ECDSAPub.X(),
+		Y:     keys[0].ECDSAPub.Y(),
+	}
+	assert.True(t, ecdsa.Verify(&pk, msgHash[:], new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+}
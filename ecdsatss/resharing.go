@@ -21,25 +21,44 @@ import (
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
 
+// TaskResharing is the task name reported in errors from Resharing.
+const TaskResharing = "ecdsa-resharing"
+
 // Resharing tracks an ECDSA key resharing operation from an old committee to a new committee.
 type Resharing struct {
 	ctx    context.Context
 	params *tss.ReSharingParameters
+	broker *tss.SessionBroker
+	stop   func() bool
 	input  *Key // old committee key data (nil for pure new members)
 
 	// temp storage for old committee
-	vd        cmts.HashDeCommitment
-	newShares vss.Shares
+	vd            cmts.HashDeCommitment
+	newShares     vss.Shares
+	newMoreShares []vss.Shares // shares at the other points of each weighted new party
 
 	// temp storage for new committee
 	newXi     *big.Int
 	newKs     []*big.Int
 	newBigXjs []*crypto.ECPoint
 
+	// other points, shares and public shares of a weighted new committee
+	newMoreXi     []*big.Int
+	newMoreKs     [][]*big.Int
+	newMoreBigXjs [][]*crypto.ECPoint
+
+	// ranks of the shares of a hierarchical new committee
+	newRanks []int
+
 	// Paillier/proof data for new committee
 	preParams *LocalPreParams
 	newKey    *Key // key being built for new committee
 
+	auxCache  *AuxInfoCache // verified auxiliary info of the new parties, nil if not used
+	auxLite   bool          // this party omits its auxiliary info, all the new parties have it
+	auxFps    [][]byte      // fingerprint of the pre-params of each new party
+	auxCached []bool        // the new party omitted its auxiliary info, found in auxCache
+
 	// SSID
 	ssid      []byte
 	ssidNonce *big.Int
@@ -51,6 +70,9 @@ type Resharing struct {
 	// round synchronization for round 5 (new committee waits for FacProof P2P + ACK broadcast)
 	newR5pending int32
 
+	// number of committees this party is still active in, receivers are released at zero
+	roles int32
+
 	// saved round 1 messages from old committee (needed in round 4)
 	r1msgsFrom []*tss.PartyID
 	r1msgs     []*resharingRound1msg
@@ -79,15 +101,32 @@ type Resharing struct {
 // For old committee members, input is their existing key data.
 // For new committee members, input is nil and optionalPreParams provides the Paillier pre-parameters.
 func NewResharing(ctx context.Context, params *tss.ReSharingParameters, input *Key, optionalPreParams ...LocalPreParams) (*Resharing, error) {
+	return NewResharingWithAuxInfoCache(ctx, params, input, nil, optionalPreParams...)
+}
+
+// NewResharingWithAuxInfoCache is NewResharing, with the Paillier keys and range proof
+// parameters of the other new parties found in cache reused instead of being sent and
+// verified again, as done by NewKeygenWithAuxInfoCache. The cache is only used by the members
+// of the new committee.
+func NewResharingWithAuxInfoCache(ctx context.Context, params *tss.ReSharingParameters, input *Key, cache *AuxInfoCache, optionalPreParams ...LocalPreParams) (*Resharing, error) {
 	rs := &Resharing{
-		ctx:    ctx,
-		params: params,
-		input:  input,
-		Done:   make(chan *Key, 1),
-		Err:    make(chan error, 1),
+		ctx:       ctx,
+		params:    params,
+		input:     input,
+		auxCache:  cache,
+		auxFps:    make([][]byte, params.NewPartyCount()),
+		auxCached: make([]bool, params.NewPartyCount()),
+		Done:      make(chan *Key, 1),
+		Err:       make(chan error, 1),
 	}
+	rs.broker = tss.NewSessionBroker(params.Broker())
+	rs.stop = context.AfterFunc(ctx, func() { rs.fail(ctx.Err()) })
 
+	if params.IsOldCommittee() {
+		rs.roles++
+	}
 	if params.IsNewCommittee() {
+		rs.roles++
 		rs.newKey = NewKey(params.NewPartyCount())
 		if len(optionalPreParams) > 0 {
 			rs.newKey.LocalPreParams = optionalPreParams[0]
@@ -99,6 +138,7 @@ func NewResharing(ctx context.Context, params *tss.ReSharingParameters, input *K
 	if params.IsOldCommittee() {
 		err := rs.round1Old()
 		if err != nil {
+			rs.close()
 			return nil, err
 		}
 	}
@@ -119,6 +159,11 @@ func (rs *Resharing) getSSID() ([]byte, error) {
 		return nil, fmt.Errorf("read BigXj failed: %w", err)
 	}
 	ssidList = append(ssidList, BigXjList...)
+	moreBigXjList, err := vss.FlattenPublicShares(rs.input.MoreBigXj)
+	if err != nil {
+		return nil, fmt.Errorf("read MoreBigXj failed: %w", err)
+	}
+	ssidList = append(ssidList, moreBigXjList...)
 	ssidList = append(ssidList, rs.input.NTildej...)
 	ssidList = append(ssidList, rs.input.H1j...)
 	ssidList = append(ssidList, rs.input.H2j...)
@@ -128,40 +173,20 @@ func (rs *Resharing) getSSID() ([]byte, error) {
 	return ssid, nil
 }
 
-// prepareForSigning computes the Lagrange coefficient wi for the old committee member.
+// prepareForSigning computes the additive share wi of the old committee member, from its
+// shares and the Lagrange coefficients of their evaluation points.
 func (rs *Resharing) prepareForSigning(subsetKey *Key) (*big.Int, error) {
-	ec := rs.params.EC()
 	i := rs.params.PartyID().Index
-	xi := subsetKey.Xi
-	ks := subsetKey.Ks
-	bigXs := subsetKey.BigXj
-	pax := len(ks)
+	q := rs.params.EC().Params().N
 
-	if rs.params.Threshold()+1 > pax {
-		return nil, fmt.Errorf("t+1=%d is not satisfied by the key count of %d", rs.params.Threshold()+1, pax)
-	}
-	if len(ks) != len(bigXs) {
-		return nil, fmt.Errorf("len(ks) != len(bigXs) (%d != %d)", len(ks), len(bigXs))
-	}
-	if pax <= i {
-		return nil, fmt.Errorf("pax <= i (%d <= %d)", pax, i)
+	coefs, err := subsetKey.shareCoefs(rs.params.Parameters)
+	if err != nil {
+		return nil, err
 	}
-
-	modQ := common.ModInt(ec.Params().N)
-	wi := new(big.Int).Set(xi)
-	for j := 0; j < pax; j++ {
-		if j == i {
-			continue
-		}
-		ksj := ks[j]
-		ksi := ks[i]
-		if ksj.Cmp(ksi) == 0 {
-			return nil, fmt.Errorf("index of two parties are equal")
-		}
-		coef := modQ.Mul(ks[j], modQ.ModInverse(new(big.Int).Sub(ksj, ksi)))
-		wi = modQ.Mul(wi, coef)
+	if len(coefs) <= i {
+		return nil, fmt.Errorf("pax <= i (%d <= %d)", len(coefs), i)
 	}
-	return wi, nil
+	return subsetKey.additiveShare(q, coefs[i])
 }
 
 // ---- Round 1 (Old committee) ---- //
@@ -190,9 +215,11 @@ func (rs *Resharing) round1Old() error {
 		return fmt.Errorf("PrepareForSigning failed: %w", err)
 	}
 
-	// Create VSS shares for new committee
+	// Create VSS shares for new committee, at the other points of its weighted parties too, or
+	// of the ranks of its hierarchical parties
 	newKs := rs.params.NewParties().IDs().Keys()
-	vi, shares, err := vss.Create(rs.params.EC(), rs.params.NewThreshold(), wi, newKs, rs.params.Rand())
+	newMoreKs := vss.WeightedPoints(rs.params.EC().Params().N, newKs, rs.params.NewWeights())
+	vi, shares, err := vss.DealShares(rs.params.EC(), rs.params.NewThreshold(), wi, newKs, newMoreKs, vss.HierarchicalRanks(rs.params.NewRanks()), rs.params.Rand())
 	if err != nil {
 		return fmt.Errorf("VSS Create failed: %w", err)
 	}
@@ -207,6 +234,7 @@ func (rs *Resharing) round1Old() error {
 	// Store temp data
 	rs.vd = vCmt.D
 	rs.newShares = shares
+	rs.newMoreShares = vss.SplitShares(shares, newMoreKs)
 
 	// Broadcast R1 message to new committee
 	r1msg := &resharingRound1msg{
@@ -218,13 +246,13 @@ func (rs *Resharing) round1Old() error {
 
 	newIDs := rs.params.NewParties().IDs()
 	for _, Pj := range newIDs {
-		m := tss.JsonWrap("ecdsa:resharing:round1", r1msg, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round1"), r1msg, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Old committee now waits for ACK from new committee (round 2 msg2)
-	r2rcv := tss.NewJsonExpect[resharingRound2msg2]("ecdsa:resharing:round2-2", newIDs, rs.onR2msg2Old)
-	rs.params.Broker().Connect("ecdsa:resharing:round2-2", r2rcv)
+	r2rcv := tss.NewJsonExpect[resharingRound2msg2](rs.params.MsgType("ecdsa:resharing:round2-2"), newIDs, rs.onR2msg2Old, rs.timeout(2))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round2-2"), r2rcv)
 
 	return nil
 }
@@ -233,15 +261,15 @@ func (rs *Resharing) round1Old() error {
 
 func (rs *Resharing) round1New() {
 	oldIDs := rs.params.OldParties().IDs()
-	r1rcv := tss.NewJsonExpect[resharingRound1msg]("ecdsa:resharing:round1", oldIDs, rs.onR1New)
-	rs.params.Broker().Connect("ecdsa:resharing:round1", r1rcv)
+	r1rcv := tss.NewJsonExpect[resharingRound1msg](rs.params.MsgType("ecdsa:resharing:round1"), oldIDs, rs.onR1New, rs.timeout(1), rs.echo(1, "ecdsa:resharing:round1", rs.otherNewParties()))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round1"), r1rcv)
 }
 
 // ---- Round 2 (New committee receives R1, sends Paillier+proofs to new, ACK to old) ---- //
 
 func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -259,7 +287,7 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 		if SSID == nil {
 			SSID = msg.SSID
 		} else if !bytes.Equal(SSID, msg.SSID) {
-			rs.Err <- fmt.Errorf("SSID mismatch from old party %s", from[j])
+			rs.fail(rs.wrapError(2, errors.New("SSID mismatch from old party"), from[j]))
 			return
 		}
 	}
@@ -270,13 +298,13 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 	for j, msg := range msgs {
 		candidate, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(msg.ECDSAPubX), new(big.Int).SetBytes(msg.ECDSAPubY))
 		if err != nil {
-			rs.Err <- fmt.Errorf("unable to unmarshal ECDSA pub from party %s: %w", from[j], err)
+			rs.fail(rs.wrapError(2, fmt.Errorf("unable to unmarshal ECDSA pub: %w", err), from[j]))
 			return
 		}
 		if ecdsaPub == nil {
 			ecdsaPub = candidate
 		} else if !ecdsaPub.Equals(candidate) {
-			rs.Err <- fmt.Errorf("ECDSA pub key mismatch from party %s", from[j])
+			rs.fail(rs.wrapError(2, errors.New("ECDSA pub key mismatch"), from[j]))
 			return
 		}
 	}
@@ -285,7 +313,7 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 	// Generate or validate Paillier pre-params
 	var preParams *LocalPreParams
 	if rs.newKey.LocalPreParams.Validate() && !rs.newKey.LocalPreParams.ValidateWithProof() {
-		rs.Err <- errors.New("`optionalPreParams` failed to validate; it might have been generated with an older version of tss-lib")
+		rs.fail(rs.wrapError(2, errors.New("`optionalPreParams` failed to validate; it might have been generated with an older version of tss-lib")))
 		return
 	} else if rs.newKey.LocalPreParams.ValidateWithProof() {
 		preParams = &rs.newKey.LocalPreParams
@@ -295,7 +323,7 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 		var err error
 		preParams, err = (&LocalPreGenerator{Context: ctx, Rand: rs.params.Rand(), Concurrency: rs.params.Concurrency()}).Generate()
 		if err != nil {
-			rs.Err <- fmt.Errorf("pre-params generation failed: %w", err)
+			rs.fail(rs.wrapError(2, fmt.Errorf("pre-params generation failed: %w", err)))
 			return
 		}
 	}
@@ -306,61 +334,68 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 	rs.newKey.NTildej[i] = preParams.NTildei
 	rs.newKey.H1j[i] = preParams.H1i
 	rs.newKey.H2j[i] = preParams.H2i
+	rs.auxFps[i] = preParams.Fingerprint()
 
-	// Generate DLN proofs
-	dlnProof1 := dlnproof.NewDLNProof(preParams.H1i, preParams.H2i, preParams.Alpha, preParams.P, preParams.Q, preParams.NTildei, rs.params.Rand())
-	dlnProof2 := dlnproof.NewDLNProof(preParams.H2i, preParams.H1i, preParams.Beta, preParams.P, preParams.Q, preParams.NTildei, rs.params.Rand())
+	newIDs := rs.params.NewParties().IDs()
+	rs.auxLite = rs.auxCache.known(newIDs.Exclude(Pi), rs.auxFps[i])
 
-	// Generate ModProof
-	modProofObj := &modproof.ProofMod{W: zero, X: *new([80]*big.Int), A: zero, B: zero, Z: *new([80]*big.Int)}
-	ContextI := append(rs.ssid, big.NewInt(int64(i)).Bytes()...)
-	if !rs.params.NoProofMod() {
-		var err error
-		modProofObj, err = modproof.NewProof(ContextI, preParams.PaillierSK.N, preParams.PaillierSK.P, preParams.PaillierSK.Q, rs.params.Rand())
+	// Broadcast R2 msg1 (Paillier+proofs) to other new committee members
+	r2msg1 := &resharingRound2msg1{}
+	if rs.auxCache != nil {
+		r2msg1.AuxFingerprint = rs.auxFps[i]
+	}
+	if !rs.auxLite {
+		// Generate DLN proofs
+		dlnProof1 := dlnproof.NewDLNProof(preParams.H1i, preParams.H2i, preParams.Alpha, preParams.P, preParams.Q, preParams.NTildei, rs.params.Rand())
+		dlnProof2 := dlnproof.NewDLNProof(preParams.H2i, preParams.H1i, preParams.Beta, preParams.P, preParams.Q, preParams.NTildei, rs.params.Rand())
+
+		// Generate ModProof
+		modProofObj := &modproof.ProofMod{W: zero, X: *new([80]*big.Int), A: zero, B: zero, Z: *new([80]*big.Int)}
+		ContextI := append(rs.ssid, big.NewInt(int64(i)).Bytes()...)
+		if !rs.params.NoProofMod() {
+			var err error
+			modProofObj, err = modproof.NewProof(ContextI, preParams.PaillierSK.N, preParams.PaillierSK.P, preParams.PaillierSK.Q, rs.params.Rand())
+			if err != nil {
+				rs.fail(rs.wrapError(2, fmt.Errorf("ModProof generation failed: %w", err)))
+				return
+			}
+		}
+
+		modPfBzs := modProofObj.Bytes()
+		dlnProof1Bz, err := dlnProof1.Serialize()
 		if err != nil {
-			rs.Err <- fmt.Errorf("ModProof generation failed: %w", err)
+			rs.fail(rs.wrapError(2, fmt.Errorf("DLN proof 1 serialize failed: %w", err)))
+			return
+		}
+		dlnProof2Bz, err := dlnProof2.Serialize()
+		if err != nil {
+			rs.fail(rs.wrapError(2, fmt.Errorf("DLN proof 2 serialize failed: %w", err)))
 			return
 		}
-	}
-
-	modPfBzs := modProofObj.Bytes()
-	dlnProof1Bz, err := dlnProof1.Serialize()
-	if err != nil {
-		rs.Err <- fmt.Errorf("DLN proof 1 serialize failed: %w", err)
-		return
-	}
-	dlnProof2Bz, err := dlnProof2.Serialize()
-	if err != nil {
-		rs.Err <- fmt.Errorf("DLN proof 2 serialize failed: %w", err)
-		return
-	}
 
-	// Broadcast R2 msg1 (Paillier+proofs) to other new committee members
-	r2msg1 := &resharingRound2msg1{
-		PaillierN:  preParams.PaillierSK.PublicKey.N.Bytes(),
-		ModProof:   modPfBzs[:],
-		NTilde:     preParams.NTildei.Bytes(),
-		H1:         preParams.H1i.Bytes(),
-		H2:         preParams.H2i.Bytes(),
-		Dlnproof_1: dlnProof1Bz,
-		Dlnproof_2: dlnProof2Bz,
+		r2msg1.PaillierN = preParams.PaillierSK.PublicKey.N.Bytes()
+		r2msg1.ModProof = modPfBzs[:]
+		r2msg1.NTilde = preParams.NTildei.Bytes()
+		r2msg1.H1 = preParams.H1i.Bytes()
+		r2msg1.H2 = preParams.H2i.Bytes()
+		r2msg1.Dlnproof_1 = dlnProof1Bz
+		r2msg1.Dlnproof_2 = dlnProof2Bz
 	}
 
-	newIDs := rs.params.NewParties().IDs()
 	for _, Pj := range newIDs {
 		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
 			continue
 		}
-		m := tss.JsonWrap("ecdsa:resharing:round2-1", r2msg1, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round2-1"), r2msg1, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Broadcast R2 msg2 (ACK) to old committee
 	r2msg2 := &resharingRound2msg2{}
 	oldIDs := rs.params.OldParties().IDs()
 	for _, Pj := range oldIDs {
-		m := tss.JsonWrap("ecdsa:resharing:round2-2", r2msg2, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round2-2"), r2msg2, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// New committee now waits for 3 things:
@@ -377,19 +412,19 @@ func (rs *Resharing) onR1New(from []*tss.PartyID, msgs []*resharingRound1msg) {
 
 	atomic.StoreInt32(&rs.newR4pending, 3)
 
-	r2m1rcv := tss.NewJsonExpect[resharingRound2msg1]("ecdsa:resharing:round2-1", otherNewIDs, rs.onR2msg1New)
-	rs.params.Broker().Connect("ecdsa:resharing:round2-1", r2m1rcv)
+	r2m1rcv := tss.NewJsonExpect[resharingRound2msg1](rs.params.MsgType("ecdsa:resharing:round2-1"), otherNewIDs, rs.onR2msg1New, rs.timeout(2), rs.echo(2, "ecdsa:resharing:round2-1", otherNewIDs))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round2-1"), r2m1rcv)
 
-	r3m1rcv := tss.NewJsonExpect[resharingRound3msg1]("ecdsa:resharing:round3-1", oldIDs, rs.onR3msg1New)
-	rs.params.Broker().Connect("ecdsa:resharing:round3-1", r3m1rcv)
+	r3m1rcv := tss.NewJsonExpect[resharingRound3msg1](rs.params.MsgType("ecdsa:resharing:round3-1"), oldIDs, rs.onR3msg1New, rs.timeout(3), tss.WithPrivate())
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round3-1"), r3m1rcv)
 
-	r3m2rcv := tss.NewJsonExpect[resharingRound3msg2]("ecdsa:resharing:round3-2", oldIDs, rs.onR3msg2New)
-	rs.params.Broker().Connect("ecdsa:resharing:round3-2", r3m2rcv)
+	r3m2rcv := tss.NewJsonExpect[resharingRound3msg2](rs.params.MsgType("ecdsa:resharing:round3-2"), oldIDs, rs.onR3msg2New, rs.timeout(3), rs.echo(3, "ecdsa:resharing:round3-2", rs.otherNewParties()))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round3-2"), r3m2rcv)
 }
 
 func (rs *Resharing) onR2msg1New(from []*tss.PartyID, msgs []*resharingRound2msg1) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	rs.r2msg1From = from
@@ -401,7 +436,7 @@ func (rs *Resharing) onR2msg1New(from []*tss.PartyID, msgs []*resharingRound2msg
 
 func (rs *Resharing) onR3msg1New(from []*tss.PartyID, msgs []*resharingRound3msg1) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	rs.r3msg1From = from
@@ -413,7 +448,7 @@ func (rs *Resharing) onR3msg1New(from []*tss.PartyID, msgs []*resharingRound3msg
 
 func (rs *Resharing) onR3msg2New(from []*tss.PartyID, msgs []*resharingRound3msg2) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	rs.r3msg2From = from
@@ -427,7 +462,7 @@ func (rs *Resharing) onR3msg2New(from []*tss.PartyID, msgs []*resharingRound3msg
 
 func (rs *Resharing) onR2msg2Old(from []*tss.PartyID, msgs []*resharingRound2msg2) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	// Old committee proceeds to round 3
@@ -438,7 +473,7 @@ func (rs *Resharing) onR2msg2Old(from []*tss.PartyID, msgs []*resharingRound2msg
 
 func (rs *Resharing) round3Old() {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -450,8 +485,13 @@ func (rs *Resharing) round3Old() {
 		r3msg1 := &resharingRound3msg1{
 			Share: share.Share.Bytes(),
 		}
-		m := tss.JsonWrap("ecdsa:resharing:round3-1", r3msg1, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		if rs.newMoreShares != nil {
+			for _, share := range rs.newMoreShares[j] {
+				r3msg1.MoreShares = append(r3msg1.MoreShares, share.Share.Bytes())
+			}
+		}
+		m := tss.JsonWrapPrivate(rs.params.MsgType("ecdsa:resharing:round3-1"), r3msg1, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Broadcast decommitment to new committee
@@ -459,20 +499,20 @@ func (rs *Resharing) round3Old() {
 		VDecommitment: common.BigIntsToBytes(rs.vd),
 	}
 	for _, Pj := range newIDs {
-		m := tss.JsonWrap("ecdsa:resharing:round3-2", r3msg2, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round3-2"), r3msg2, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Old committee now waits for round 4 ACK from new committee
-	r4m2rcv := tss.NewJsonExpect[resharingRound4msg2]("ecdsa:resharing:round4-2", newIDs, rs.onR4msg2Old)
-	rs.params.Broker().Connect("ecdsa:resharing:round4-2", r4m2rcv)
+	r4m2rcv := tss.NewJsonExpect[resharingRound4msg2](rs.params.MsgType("ecdsa:resharing:round4-2"), newIDs, rs.onR4msg2Old, rs.timeout(4))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round4-2"), r4m2rcv)
 }
 
 // ---- Round 4 (New committee: verify proofs, compute new key shares, send FacProofs) ---- //
 
 func (rs *Resharing) round4New() {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -508,23 +548,41 @@ func (rs *Resharing) round4New() {
 		NTildej := new(big.Int).SetBytes(msg.NTilde)
 		H1j := new(big.Int).SetBytes(msg.H1)
 		H2j := new(big.Int).SetBytes(msg.H2)
+		paillierNj := new(big.Int).SetBytes(msg.PaillierN)
+		if len(msg.PaillierN) == 0 && len(msg.NTilde) == 0 && rs.auxCache != nil {
+			// the party omitted its auxiliary info, which must be in the cache
+			info := rs.auxCache.lookup(rs.r2msg1From[k], msg.AuxFingerprint)
+			if info == nil {
+				rs.fail(rs.wrapError(4, ErrAuxInfoUnknown, rs.r2msg1From[k]))
+				return
+			}
+			jIdx := r2msg1IdxMap[k]
+			rs.auxCached[jIdx] = true
+			NTildej, H1j, H2j, paillierNj = info.NTilde, info.H1, info.H2, info.PaillierN
+			rs.newKey.NTildej[jIdx], rs.newKey.H1j[jIdx], rs.newKey.H2j[jIdx] = NTildej, H1j, H2j
+			rs.newKey.PaillierPKs[jIdx] = &paillier.PublicKey{N: paillierNj}
+		}
+		rs.auxFps[r2msg1IdxMap[k]] = auxFingerprint(paillierNj, NTildej, H1j, H2j)
 
 		if H1j.Cmp(H2j) == 0 {
-			rs.Err <- fmt.Errorf("party %s: H1j == H2j", rs.r2msg1From[k])
+			rs.fail(rs.wrapError(4, errors.New("H1j == H2j"), rs.r2msg1From[k]))
 			return
 		}
 		h1JHex := hex.EncodeToString(H1j.Bytes())
 		h2JHex := hex.EncodeToString(H2j.Bytes())
 		if _, found := h1H2Map[h1JHex]; found {
-			rs.Err <- fmt.Errorf("party %s: h1j already used", rs.r2msg1From[k])
+			rs.fail(rs.wrapError(4, errors.New("h1j already used"), rs.r2msg1From[k]))
 			return
 		}
 		if _, found := h1H2Map[h2JHex]; found {
-			rs.Err <- fmt.Errorf("party %s: h2j already used", rs.r2msg1From[k])
+			rs.fail(rs.wrapError(4, errors.New("h2j already used"), rs.r2msg1From[k]))
 			return
 		}
 		h1H2Map[h1JHex] = struct{}{}
 		h1H2Map[h2JHex] = struct{}{}
+		if rs.auxCached[r2msg1IdxMap[k]] {
+			continue
+		}
 
 		kk := k
 		h1jCopy, h2jCopy, ntCopy := H1j, H2j, NTildej
@@ -562,16 +620,23 @@ func (rs *Resharing) round4New() {
 	}
 	wg.Wait()
 
+	var culprits []*tss.PartyID
 	for k := range rs.r2msg1 {
 		if paiProofFail[k] || dlnProof1Fail[k] || dlnProof2Fail[k] {
-			rs.Err <- fmt.Errorf("party %s: DLN/ModProof verification failed", rs.r2msg1From[k])
-			return
+			culprits = append(culprits, rs.r2msg1From[k])
 		}
 	}
+	if len(culprits) > 0 {
+		rs.fail(rs.wrapError(4, errors.New("DLN/ModProof verification failed"), culprits...))
+		return
+	}
 
 	// Save NTilde, H1, H2, PaillierPK from other new committee members
 	for k, msg := range rs.r2msg1 {
 		jIdx := r2msg1IdxMap[k]
+		if rs.auxCached[jIdx] {
+			continue
+		}
 		rs.newKey.NTildej[jIdx] = new(big.Int).SetBytes(msg.NTilde)
 		rs.newKey.H1j[jIdx] = new(big.Int).SetBytes(msg.H1)
 		rs.newKey.H2j[jIdx] = new(big.Int).SetBytes(msg.H2)
@@ -622,17 +687,35 @@ func (rs *Resharing) round4New() {
 	modQ := common.ModInt(ec.Params().N)
 	vjc := make([][]*crypto.ECPoint, len(oldIDs))
 
+	// a weighted new party also gets the shares at its other points
+	newMoreKs := vss.WeightedPoints(ec.Params().N, newIDs.Keys(), rs.params.NewWeights())
+	var ownMoreKs []*big.Int
+	if newMoreKs != nil {
+		ownMoreKs = newMoreKs[Pi.Index]
+	}
+	newMoreXi := make([]*big.Int, len(ownMoreKs))
+	for m := range newMoreXi {
+		newMoreXi[m] = big.NewInt(0)
+	}
+
+	// and a hierarchical one the share of its rank
+	newRanks := vss.HierarchicalRanks(rs.params.NewRanks())
+	ownRank := 0
+	if newRanks != nil {
+		ownRank = newRanks[Pi.Index]
+	}
+
 	for k, r3m1 := range rs.r3msg1 {
 		jOldIdx := r3m1IdxMap[k]
 
 		r1Pos, ok := r1ByOldIdx[jOldIdx]
 		if !ok {
-			rs.Err <- fmt.Errorf("missing R1 message for old party index %d", jOldIdx)
+			rs.fail(rs.wrapError(4, errors.New("missing R1 message from old party"), oldIDs[jOldIdx]))
 			return
 		}
 		r3m2Pos, ok := r3m2ByOldIdx[jOldIdx]
 		if !ok {
-			rs.Err <- fmt.Errorf("missing R3 decommitment for old party index %d", jOldIdx)
+			rs.fail(rs.wrapError(4, errors.New("missing R3 decommitment from old party"), oldIDs[jOldIdx]))
 			return
 		}
 
@@ -646,12 +729,12 @@ func (rs *Resharing) round4New() {
 		vCmtDeCmt := cmts.HashCommitDecommit{C: vCj, D: vDj}
 		ok2, flatVs := vCmtDeCmt.DeCommit()
 		if !ok2 || len(flatVs) != (rs.params.NewThreshold()+1)*2 {
-			rs.Err <- fmt.Errorf("de-commitment verification failed for old party %s", rs.r3msg1From[k])
+			rs.fail(rs.wrapError(4, errors.New("de-commitment verification failed"), rs.r3msg1From[k]))
 			return
 		}
 		vj, err := crypto.UnFlattenECPoints(ec, flatVs)
 		if err != nil {
-			rs.Err <- fmt.Errorf("UnFlattenECPoints failed for old party %s: %w", rs.r3msg1From[k], err)
+			rs.fail(rs.wrapError(4, fmt.Errorf("UnFlattenECPoints failed: %w", err), rs.r3msg1From[k]))
 			return
 		}
 		vjc[jOldIdx] = vj
@@ -661,13 +744,31 @@ func (rs *Resharing) round4New() {
 			Threshold: rs.params.NewThreshold(),
 			ID:        Pi.KeyInt(),
 			Share:     new(big.Int).SetBytes(r3m1.Share),
+			Rank:      ownRank,
 		}
 		if ok3 := sharej.Verify(ec, rs.params.NewThreshold(), vj); !ok3 {
-			rs.Err <- fmt.Errorf("VSS share verification failed for old party %s", rs.r3msg1From[k])
+			rs.fail(rs.wrapError(4, errors.New("VSS share verification failed"), rs.r3msg1From[k]))
 			return
 		}
 
 		newXi = new(big.Int).Add(newXi, sharej.Share)
+
+		if len(r3m1.MoreShares) != len(ownMoreKs) {
+			rs.fail(rs.wrapError(4, errors.New("message holds the shares of another number of points"), rs.r3msg1From[k]))
+			return
+		}
+		for m, shareBz := range r3m1.MoreShares {
+			sharej := &vss.Share{
+				Threshold: rs.params.NewThreshold(),
+				ID:        ownMoreKs[m],
+				Share:     new(big.Int).SetBytes(shareBz),
+			}
+			if !sharej.Verify(ec, rs.params.NewThreshold(), vj) {
+				rs.fail(rs.wrapError(4, errors.New("VSS share verification failed"), rs.r3msg1From[k]))
+				return
+			}
+			newMoreXi[m] = modQ.Add(newMoreXi[m], sharej.Share)
+		}
 	}
 
 	// Compute Vc (aggregated VSS coefficients)
@@ -684,7 +785,7 @@ func (rs *Resharing) round4New() {
 				var err error
 				first, err = first.Add(vjc[j][c])
 				if err != nil {
-					rs.Err <- fmt.Errorf("Vc[%d] aggregation failed: %w", c, err)
+					rs.fail(rs.wrapError(4, fmt.Errorf("Vc[%d] aggregation failed: %w", c, err)))
 					return
 				}
 			}
@@ -694,7 +795,7 @@ func (rs *Resharing) round4New() {
 
 	// Verify V_0 == ECDSAPub
 	if !Vc[0].Equals(rs.newKey.ECDSAPub) {
-		rs.Err <- errors.New("assertion failed: V_0 != ECDSAPub")
+		rs.fail(rs.wrapError(4, errors.New("assertion failed: V_0 != ECDSAPub")))
 		return
 	}
 
@@ -705,6 +806,15 @@ func (rs *Resharing) round4New() {
 		Pj := newIDs[j]
 		kj := Pj.KeyInt()
 		newKs = append(newKs, kj)
+		if newRanks != nil {
+			newBigXj, err := vss.Vs(Vc).PublicShare(kj, newRanks[j])
+			if err != nil {
+				rs.fail(rs.wrapError(4, fmt.Errorf("newBigXj computation failed: %w", err)))
+				return
+			}
+			newBigXjs[j] = newBigXj
+			continue
+		}
 		newBigXj := Vc[0]
 		z := new(big.Int).SetInt64(1)
 		for c := 1; c <= rs.params.NewThreshold(); c++ {
@@ -712,16 +822,36 @@ func (rs *Resharing) round4New() {
 			var err error
 			newBigXj, err = newBigXj.Add(Vc[c].ScalarMult(z))
 			if err != nil {
-				rs.Err <- fmt.Errorf("newBigXj computation failed: %w", err)
+				rs.fail(rs.wrapError(4, fmt.Errorf("newBigXj computation failed: %w", err)))
 				return
 			}
 		}
 		newBigXjs[j] = newBigXj
 	}
+	var newMoreBigXjs [][]*crypto.ECPoint
+	if newMoreKs != nil {
+		newMoreBigXjs = make([][]*crypto.ECPoint, len(newMoreKs))
+		for j, kjs := range newMoreKs {
+			for _, kj := range kjs {
+				newBigXj, err := vss.Vs(Vc).PublicShare(kj, 0)
+				if err != nil {
+					rs.fail(rs.wrapError(4, fmt.Errorf("newBigXj computation failed: %w", err)))
+					return
+				}
+				newMoreBigXjs[j] = append(newMoreBigXjs[j], newBigXj)
+			}
+		}
+	}
 
 	rs.newXi = newXi
 	rs.newKs = newKs
 	rs.newBigXjs = newBigXjs
+	rs.newMoreKs = newMoreKs
+	rs.newMoreBigXjs = newMoreBigXjs
+	rs.newRanks = newRanks
+	if len(newMoreXi) > 0 {
+		rs.newMoreXi = newMoreXi
+	}
 
 	// Send FacProof to each other new party (P2P)
 	for _, Pj := range newIDs {
@@ -741,22 +871,25 @@ func (rs *Resharing) round4New() {
 			P: zero, Q: zero, A: zero, B: zero, T: zero, Sigma: zero,
 			Z1: zero, Z2: zero, W1: zero, W2: zero, V: zero,
 		}
-		if !rs.params.NoProofFac() {
+		// the party already verified the proof when both had the same pre-params
+		paired := rs.auxCache.paired(Pj, rs.auxFps[jIdx], rs.auxFps[Pi.Index])
+		if !rs.params.NoProofFac() && !paired {
 			var err error
 			facProofObj, err = facproof.NewProof(ContextJ, ec, rs.newKey.PaillierSK.N,
 				rs.newKey.NTildej[jIdx], rs.newKey.H1j[jIdx], rs.newKey.H2j[jIdx],
 				rs.newKey.PaillierSK.P, rs.newKey.PaillierSK.Q, rs.params.Rand())
 			if err != nil {
-				rs.Err <- fmt.Errorf("FacProof generation failed: %w", err)
+				rs.fail(rs.wrapError(4, fmt.Errorf("FacProof generation failed: %w", err)))
 				return
 			}
 		}
-		pfBzs := facProofObj.Bytes()
-		r4msg1 := &resharingRound4msg1{
-			FacProof: pfBzs[:],
+		r4msg1 := &resharingRound4msg1{}
+		if !paired {
+			pfBzs := facProofObj.Bytes()
+			r4msg1.FacProof = pfBzs[:]
 		}
-		m := tss.JsonWrap("ecdsa:resharing:round4-1", r4msg1, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round4-1"), r4msg1, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Broadcast ACK to both old and new committees
@@ -766,8 +899,8 @@ func (rs *Resharing) round4New() {
 		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
 			continue // don't send to self
 		}
-		m := tss.JsonWrap("ecdsa:resharing:round4-2", r4msg2, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("ecdsa:resharing:round4-2"), r4msg2, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Wait for FacProofs from other new members (P2P) + ACKs from other new members (broadcast)
@@ -781,16 +914,16 @@ func (rs *Resharing) round4New() {
 
 	atomic.StoreInt32(&rs.newR5pending, 2)
 
-	r4m1rcv := tss.NewJsonExpect[resharingRound4msg1]("ecdsa:resharing:round4-1", otherNewIDs, rs.onR4msg1New)
-	rs.params.Broker().Connect("ecdsa:resharing:round4-1", r4m1rcv)
+	r4m1rcv := tss.NewJsonExpect[resharingRound4msg1](rs.params.MsgType("ecdsa:resharing:round4-1"), otherNewIDs, rs.onR4msg1New, rs.timeout(4))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round4-1"), r4m1rcv)
 
-	r4m2rcv := tss.NewJsonExpect[resharingRound4msg2]("ecdsa:resharing:round4-2", otherNewIDs, rs.onR4msg2New)
-	rs.params.Broker().Connect("ecdsa:resharing:round4-2", r4m2rcv)
+	r4m2rcv := tss.NewJsonExpect[resharingRound4msg2](rs.params.MsgType("ecdsa:resharing:round4-2"), otherNewIDs, rs.onR4msg2New, rs.timeout(4))
+	rs.broker.Connect(rs.params.MsgType("ecdsa:resharing:round4-2"), r4m2rcv)
 }
 
 func (rs *Resharing) onR4msg1New(from []*tss.PartyID, msgs []*resharingRound4msg1) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	rs.r4msg1From = from
@@ -802,7 +935,7 @@ func (rs *Resharing) onR4msg1New(from []*tss.PartyID, msgs []*resharingRound4msg
 
 func (rs *Resharing) onR4msg2New(from []*tss.PartyID, msgs []*resharingRound4msg2) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	rs.r4msg2From = from
@@ -816,11 +949,15 @@ func (rs *Resharing) onR4msg2New(from []*tss.PartyID, msgs []*resharingRound4msg
 
 func (rs *Resharing) onR4msg2Old(from []*tss.PartyID, msgs []*resharingRound4msg2) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	// Old committee: zero out Xi and finish
 	rs.input.Xi.SetInt64(0)
+	for _, xi := range rs.input.MoreXi {
+		xi.SetInt64(0)
+	}
+	rs.release()
 	rs.Done <- rs.input
 }
 
@@ -828,7 +965,7 @@ func (rs *Resharing) onR4msg2Old(from []*tss.PartyID, msgs []*resharingRound4msg
 
 func (rs *Resharing) round5New() {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	i := rs.params.PartyID().Index
@@ -840,6 +977,10 @@ func (rs *Resharing) round5New() {
 	rs.newKey.ShareID = rs.params.PartyID().KeyInt()
 	rs.newKey.Xi = rs.newXi
 	rs.newKey.Ks = rs.newKs
+	rs.newKey.MoreKs = rs.newMoreKs
+	rs.newKey.MoreBigXj = rs.newMoreBigXjs
+	rs.newKey.MoreXi = rs.newMoreXi
+	rs.newKey.Ranks = rs.newRanks
 
 	// Verify FacProofs from other new committee members
 	newIDs := rs.params.NewParties().IDs()
@@ -852,19 +993,81 @@ func (rs *Resharing) round5New() {
 			}
 		}
 
-		if !rs.params.NoProofFac() {
+		if !rs.params.NoProofFac() && !rs.auxCache.paired(rs.r4msg1From[k], rs.auxFps[jIdx], rs.auxFps[i]) {
 			proof, err := facproof.NewProofFromBytes(msg.FacProof)
 			if err != nil {
-				rs.Err <- fmt.Errorf("FacProof deserialization failed for party %s: %w", rs.r4msg1From[k], err)
+				rs.fail(rs.wrapError(5, fmt.Errorf("FacProof deserialization failed: %w", err), rs.r4msg1From[k]))
 				return
 			}
 			if ok := proof.Verify(ContextI, rs.params.EC(), rs.newKey.PaillierPKs[jIdx].N,
 				rs.newKey.NTildei, rs.newKey.H1i, rs.newKey.H2i); !ok {
-				rs.Err <- fmt.Errorf("FacProof verification failed for party %s", rs.r4msg1From[k])
+				rs.fail(rs.wrapError(5, errors.New("FacProof verification failed"), rs.r4msg1From[k]))
 				return
 			}
 		}
 	}
 
+	// Every other new party verified the pre-params of this party before sending its
+	// round 4 messages
+	for j, Pj := range newIDs {
+		if j != i {
+			rs.auxCache.store(Pj, rs.newKey.PaillierPKs[j].N, rs.newKey.NTildej[j], rs.newKey.H1j[j], rs.newKey.H2j[j], rs.auxFps[i])
+		}
+	}
+
+	rs.release()
 	rs.Done <- rs.newKey
 }
+
+// fail reports err on Err and releases the receivers registered by this resharing.
+func (rs *Resharing) fail(err error) {
+	rs.close()
+	select {
+	case rs.Err <- err:
+	default:
+	}
+}
+
+// release releases the receivers registered by this resharing once this party is done in
+// every committee it belongs to.
+func (rs *Resharing) release() {
+	if atomic.AddInt32(&rs.roles, -1) > 0 {
+		return
+	}
+	rs.close()
+}
+
+// close disconnects the receivers registered by this resharing from the broker.
+func (rs *Resharing) close() {
+	rs.stop()
+	rs.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (rs *Resharing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(rs.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		rs.fail(rs.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (rs *Resharing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !rs.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(rs.broker, rs.params.MsgType(typ+":echo"), rs.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		rs.fail(rs.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// otherNewParties returns the parties of the new committee, without this party.
+func (rs *Resharing) otherNewParties() []*tss.PartyID {
+	return rs.params.NewParties().IDs().Exclude(rs.params.PartyID())
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (rs *Resharing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskResharing, round, rs.params.PartyID(), culprits...)
+}
@@ -0,0 +1,598 @@
+package ecdsatss
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	mrand "math/rand/v2"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+	"github.com/KarpelesLab/tss-lib/v2/tss/simnet"
+)
+
+// loadSortedTestKeys is like loadTestKeys, with the keys in the order of the party ids.
+func loadSortedTestKeys(t *testing.T, qty int) ([]*Key, tss.SortedPartyIDs) {
+	keys, pIDs := loadTestKeys(t, qty)
+	sorted := make([]*Key, qty)
+	for n, p := range pIDs {
+		for _, key := range keys {
+			if key.ShareID.Cmp(p.KeyInt()) == 0 {
+				sorted[n] = key
+			}
+		}
+	}
+	return sorted, pIDs
+}
+
+// partyOption adjusts the parameters of party i before a test starts its session.
+type partyOption func(i int, params *tss.Parameters)
+
+// startKeygens starts keygen for n parties over a test hub, with the pre-params of the
+// fixtures. The options are applied to the parameters of all the parties before any of them
+// starts, and may replace the hub broker.
+func startKeygens(t *testing.T, n, threshold int, opts ...partyOption) ([]*Keygen, tss.SortedPartyIDs) {
+	t.Helper()
+	fixtures, _ := loadTestKeys(t, n)
+	pIDs := tss.GenerateTestPartyIDs(n)
+	hub := newTestHub(n)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	params := make([]*tss.Parameters, n)
+	for i := range params {
+		params[i] = tss.NewParameters(tss.S256(), p2pCtx, pIDs[i], n, threshold)
+		params[i].SetBroker(hub.brokers[i])
+		for _, opt := range opts {
+			opt(i, params[i])
+		}
+	}
+
+	keygens := make([]*Keygen, n)
+	for i := range keygens {
+		kg, err := NewKeygen(context.Background(), params[i], fixtures[i].LocalPreParams)
+		require.NoError(t, err, "NewKeygen should not fail for party %d", i)
+		keygens[i] = kg
+	}
+	return keygens, pIDs
+}
+
+// runKeygen runs keygen like startKeygens, and returns the keys of the parties once every
+// party completed.
+func runKeygen(t *testing.T, n, threshold int, opts ...partyOption) ([]*Key, tss.SortedPartyIDs) {
+	t.Helper()
+	keygens, pIDs := startKeygens(t, n, threshold, opts...)
+	keys := make([]*Key, n)
+	for i, kg := range keygens {
+		select {
+		case keys[i] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("Party %d keygen error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d keygen timed out", i)
+		}
+	}
+	for i := 1; i < n; i++ {
+		require.True(t, keys[0].ECDSAPub.Equals(keys[i].ECDSAPub),
+			"party 0 and party %d should have the same public key", i)
+	}
+	return keys, pIDs
+}
+
+// startSigning starts the signing of msg by the parties of pIDs over a test hub. The options
+// are applied to the parameters of all the parties before any of them starts, and may
+// replace the hub broker.
+func startSigning(t *testing.T, keys []*Key, pIDs tss.SortedPartyIDs, threshold int, msg *big.Int, opts ...partyOption) []*Signing {
+	t.Helper()
+	hub := newTestHub(len(pIDs))
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	params := make([]*tss.Parameters, len(pIDs))
+	for i, p := range pIDs {
+		params[i] = tss.NewParameters(tss.S256(), p2pCtx, p, len(pIDs), threshold)
+		params[i].SetBroker(hub.brokers[i])
+		for _, opt := range opts {
+			opt(i, params[i])
+		}
+	}
+
+	signings := make([]*Signing, len(pIDs))
+	for i := range signings {
+		sg, err := keys[i].NewSigning(context.Background(), msg, params[i])
+		require.NoError(t, err, "NewSigning should not fail for party %d", i)
+		signings[i] = sg
+	}
+	return signings
+}
+
+// waitSignatures waits for the signatures of all the signings, and checks them against pub.
+func waitSignatures(t *testing.T, signings []*Signing, pub *ecdsa.PublicKey, msg *big.Int) []*SignatureData {
+	t.Helper()
+	sigs := make([]*SignatureData, len(signings))
+	for i, sg := range signings {
+		select {
+		case sigs[i] = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+		r := new(big.Int).SetBytes(sigs[i].R)
+		s := new(big.Int).SetBytes(sigs[i].S)
+		assert.True(t, ecdsa.Verify(pub, msg.Bytes(), r, s), "party %d should produce a valid signature", i)
+	}
+	return sigs
+}
+
+// waitSigningError waits for the error of a signing that should fail.
+func waitSigningError(t *testing.T, i int, sg *Signing) *tss.Error {
+	t.Helper()
+	select {
+	case <-sg.Done:
+		t.Fatalf("party %d should not complete signing", i)
+	case err := <-sg.Err:
+		var tssErr *tss.Error
+		require.ErrorAs(t, err, &tssErr)
+		assert.Equal(t, TaskSigning, tssErr.Task())
+		return tssErr
+	case <-time.After(5 * time.Minute):
+		t.Fatalf("party %d signing did not fail", i)
+	}
+	return nil
+}
+
+// testMessage returns the hash of text as a message to sign.
+func testMessage(text string) *big.Int {
+	hash := sha256.Sum256([]byte(text))
+	return new(big.Int).SetBytes(hash[:])
+}
+
+// TestConcurrentSessions runs two signings over the same brokers at the same time, using
+// session ids to keep their receivers apart.
+func TestConcurrentSessions(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	pub := keys[0].ECDSAPub.ToECDSAPubKey()
+	hub := newTestHub(signerCount)
+
+	// start both sessions on every party before collecting any result
+	sessions := []string{"sign-a", "sign-b"}
+	msgs := []*big.Int{testMessage("a"), testMessage("b")}
+	signings := make([][]*Signing, len(sessions))
+	for s, sid := range sessions {
+		signings[s] = startSigning(t, keys, pIDs, threshold, msgs[s], func(i int, params *tss.Parameters) {
+			params.SetBroker(hub.brokers[i])
+			params.SetSessionID(sid)
+		})
+	}
+	for s := range sessions {
+		waitSignatures(t, signings[s], pub, msgs[s])
+	}
+}
+
+// TestSigningReplay records the signing session of a party, and replays it with the same
+// random source.
+func TestSigningReplay(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	seed := [32]byte{1, 2, 3}
+	msg := big.NewInt(42)
+
+	var transcript bytes.Buffer
+	hub := newTestHub(signerCount)
+	signings := make([]*Signing, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		if i == 0 {
+			params.SetRand(mrand.NewChaCha8(seed))
+			params.SetBroker(tss.NewRecordingBroker(hub.brokers[i], p, &transcript))
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+
+		sg, err := keys[i].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	sigs := make([]*SignatureData, signerCount)
+	for i, sg := range signings {
+		select {
+		case sigs[i] = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+
+	// replay the session of party 0 without its peers, the MtA proofs included
+	entries, err := tss.ReadTranscript(&transcript)
+	require.NoError(t, err)
+	replay := tss.NewReplayBroker(entries)
+
+	params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[0], signerCount, threshold)
+	params.SetRand(mrand.NewChaCha8(seed))
+	params.SetBroker(replay)
+	sg, err := keys[0].NewSigning(context.Background(), msg, params)
+	require.NoError(t, err)
+
+	select {
+	case sig := <-sg.Done:
+		assert.Equal(t, sigs[0].Signature, sig.Signature)
+	case err := <-sg.Err:
+		t.Fatalf("Replayed signing error: %v", err)
+	case <-time.After(time.Minute):
+		t.Fatal("Replayed signing timed out")
+	}
+	assert.NoError(t, replay.Diverged())
+}
+
+type transportFunc func(msg *tss.JsonMessage) error
+
+func (f transportFunc) Receive(msg *tss.JsonMessage) error { return f(msg) }
+
+// TestKeygenAndSignBufferedBroker runs keygen and signing over tss.BufferedBroker, with
+// messages serialized as they would be on a real network.
+func TestKeygenAndSignBufferedBroker(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	brokers := make([]*tss.BufferedBroker, partyCount)
+	buffered := func(i int, params *tss.Parameters) {
+		brokers[i] = tss.NewBufferedBroker(params.PartyID(), transportFunc(func(msg *tss.JsonMessage) error {
+			buf, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			var in *tss.JsonMessage
+			if err := json.Unmarshal(buf, &in); err != nil {
+				return err
+			}
+			return brokers[msg.To.Index].Receive(in)
+		}))
+		params.SetBroker(brokers[i])
+		params.SetSessionID("keygen")
+	}
+	keys, pIDs := runKeygen(t, partyCount, threshold, buffered)
+
+	msg := testMessage("buffered")
+	signings := startSigning(t, keys, pIDs, threshold, msg, func(i int, params *tss.Parameters) {
+		params.SetBroker(brokers[i])
+		params.SetSessionID("sign")
+	})
+	waitSignatures(t, signings, keys[0].ECDSAPub.ToECDSAPubKey(), msg)
+}
+
+// TestReleaseReceivers checks that handlers are disconnected from the broker once a session
+// completes, or once its context is cancelled.
+func TestReleaseReceivers(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	hub := newTestHub(signerCount)
+	msg := testMessage("release")
+	signings := startSigning(t, keys, pIDs, threshold, msg, func(i int, params *tss.Parameters) {
+		params.SetBroker(hub.brokers[i])
+	})
+	waitSignatures(t, signings, keys[0].ECDSAPub.ToECDSAPubKey(), msg)
+	for i := 0; i < signerCount; i++ {
+		hub.brokers[i].mu.Lock()
+		assert.Empty(t, hub.brokers[i].handlers, "party %d should have released its signing handlers", i)
+		hub.brokers[i].mu.Unlock()
+	}
+
+	// only party 0 starts signing, then gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	params := tss.NewParameters(tss.S256(), tss.NewPeerContext(pIDs), pIDs[0], signerCount, threshold)
+	params.SetBroker(hub.brokers[0])
+	sg, err := keys[0].NewSigning(ctx, msg, params)
+	require.NoError(t, err)
+
+	hub.brokers[0].mu.Lock()
+	assert.NotEmpty(t, hub.brokers[0].handlers)
+	hub.brokers[0].mu.Unlock()
+
+	cancel()
+	select {
+	case err := <-sg.Err:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled signing did not report an error")
+	}
+
+	hub.brokers[0].mu.Lock()
+	assert.Empty(t, hub.brokers[0].handlers, "cancelled signing should release its handlers")
+	hub.brokers[0].mu.Unlock()
+}
+
+// TestSigningRoundTimeout checks that a party which never shows up is reported as culprit
+// once the round timeout expires.
+func TestSigningRoundTimeout(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	hub := newTestHub(signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	// party 2 never starts signing
+	signings := make([]*Signing, 2)
+	for i := range signings {
+		params := tss.NewParameters(tss.S256(), p2pCtx, pIDs[i], signerCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		params.SetRoundTimeout(5 * time.Second)
+
+		sg, err := keys[i].NewSigning(context.Background(), testMessage("timeout"), params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	for i, sg := range signings {
+		tssErr := waitSigningError(t, i, sg)
+		assert.ErrorIs(t, tssErr, tss.ErrRoundTimeout)
+		assert.Equal(t, 1, tssErr.Round())
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	}
+}
+
+// tamperBroker lets a test corrupt the messages of the given type sent by a party.
+type tamperBroker struct {
+	*hubBroker
+	typ    string
+	tamper func(msg *tss.JsonMessage) *tss.JsonMessage
+}
+
+func (b *tamperBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx && strings.HasPrefix(msg.Type, b.typ) {
+		msg = b.tamper(msg)
+	}
+	return b.hubBroker.Receive(msg)
+}
+
+func TestKeygenIdentifiesBadShare(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	// party 2 sends an invalid VSS share to party 0
+	keygens, pIDs := startKeygens(t, partyCount, threshold, func(i int, params *tss.Parameters) {
+		if i != 2 {
+			return
+		}
+		params.SetBroker(&tamperBroker{hubBroker: params.Broker().(*hubBroker), typ: "ecdsa:keygen:round2-1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+			if msg.To.Index != 0 {
+				return msg
+			}
+			bad := *msg.Data.(*keygenRound2msg1)
+			bad.Share = new(big.Int).Add(new(big.Int).SetBytes(bad.Share), big.NewInt(1)).Bytes()
+			return tss.JsonWrapPrivate(msg.Type, &bad, msg.From, msg.To)
+		}})
+	})
+
+	select {
+	case <-keygens[0].Done:
+		t.Fatal("party 0 should reject the share of party 2")
+	case err := <-keygens[0].Err:
+		var tssErr *tss.Error
+		require.ErrorAs(t, err, &tssErr)
+		assert.Equal(t, TaskKeygen, tssErr.Task())
+		assert.Equal(t, 3, tssErr.Round())
+		assert.Equal(t, pIDs[0].Id, tssErr.Victim().Id)
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	case <-time.After(5 * time.Minute):
+		t.Fatal("Keygen timed out for party 0")
+	}
+}
+
+// withIdentityKeys gives each party the public key of privs as identity key, and signs its
+// messages with its private key on top of the broker set so far.
+func withIdentityKeys(privs []ed25519.PrivateKey) partyOption {
+	return func(i int, params *tss.Parameters) {
+		params.PartyID().IdentityKey = privs[i].Public().(ed25519.PublicKey)
+		params.SetBroker(tss.NewSigningBroker(params.Broker(), params.PartyID(), privs[i]))
+	}
+}
+
+// newIdentityKeys generates an ed25519 identity key for each of n parties.
+func newIdentityKeys(t *testing.T, n int) []ed25519.PrivateKey {
+	privs := make([]ed25519.PrivateKey, n)
+	for i := range privs {
+		_, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		privs[i] = priv
+	}
+	return privs
+}
+
+// TestSigningIdentityKeys signs with messages authenticated by identity keys, then checks
+// that the messages of a party signed with another key are dropped, and the party reported
+// once the round times out.
+func TestSigningIdentityKeys(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	privs := newIdentityKeys(t, signerCount)
+	msg := testMessage("identity")
+	signings := startSigning(t, keys, pIDs, threshold, msg, withIdentityKeys(privs))
+	waitSignatures(t, signings, keys[0].ECDSAPub.ToECDSAPubKey(), msg)
+
+	// party 2 signs its messages with a key its peers do not know, before the signing broker
+	// of its identity key sees them
+	forged := newIdentityKeys(t, 1)[0]
+	signings = startSigning(t, keys, pIDs, threshold, msg, withIdentityKeys(privs), func(i int, params *tss.Parameters) {
+		params.SetRoundTimeout(5 * time.Second)
+		if i == 2 {
+			params.SetBroker(tss.NewSigningBroker(params.Broker(), params.PartyID(), forged))
+		}
+	})
+	for i, sg := range signings[:2] {
+		tssErr := waitSigningError(t, i, sg)
+		assert.ErrorIs(t, tssErr, tss.ErrRoundTimeout)
+		assert.Equal(t, 1, tssErr.Round())
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	}
+}
+
+// TestSigningEncryptedMessages signs with signed and encrypted messages, checking that the
+// private MtA messages never cross the hub in the clear.
+func TestSigningEncryptedMessages(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	privs := newIdentityKeys(t, signerCount)
+	var lock sync.Mutex
+	var private int
+	var clear []string
+	encrypt := func(i int, params *tss.Parameters) {
+		spy := &tamperBroker{hubBroker: params.Broker().(*hubBroker), typ: "ecdsa:sign:", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case msg.Encrypted:
+				private++
+			case msg.Type == "ecdsa:sign:round1-1", msg.Type == "ecdsa:sign:round2":
+				clear = append(clear, msg.Type)
+			}
+			return msg
+		}}
+		enc, err := tss.NewEncryptingBroker(spy, params.PartyID(), privs[i])
+		require.NoError(t, err)
+		params.SetBroker(enc)
+	}
+
+	msg := testMessage("encrypted")
+	signings := startSigning(t, keys, pIDs, threshold, msg, encrypt, withIdentityKeys(privs))
+	waitSignatures(t, signings, keys[0].ECDSAPub.ToECDSAPubKey(), msg)
+	// round1-1 and round2 are sent to each peer
+	assert.Equal(t, 2*signerCount*(signerCount-1), private)
+	assert.Empty(t, clear)
+}
+
+// TestSigningEchoBroadcast checks that a party signing different commitments for different
+// peers is proven to equivocate by the echoes.
+func TestSigningEchoBroadcast(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	privs := newIdentityKeys(t, signerCount)
+	equivocate := func(i int, params *tss.Parameters) {
+		params.SetEchoBroadcast(true)
+		// the signatures only prove an equivocation within a session
+		params.SetSessionID("echo")
+		if i != 2 {
+			return
+		}
+		// party 2 signs a different commitment for party 0
+		params.SetBroker(&tamperBroker{hubBroker: params.Broker().(*hubBroker), typ: "ecdsa:sign:round1-2", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+			r1msg, ok := msg.Data.(*signRound1msg2)
+			if !ok || msg.To.Index != 0 {
+				return msg
+			}
+			bad := *r1msg
+			bad.Commitment = big.NewInt(1).Bytes()
+			m := tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			require.NoError(t, m.Sign(privs[2]))
+			return m
+		}})
+	}
+
+	signings := startSigning(t, keys, pIDs, threshold, testMessage("echo"), equivocate, withIdentityKeys(privs))
+	for i, sg := range signings[:2] {
+		tssErr := waitSigningError(t, i, sg)
+		assert.ErrorIs(t, tssErr, tss.ErrEquivocation)
+		assert.Equal(t, 1, tssErr.Round())
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	}
+}
+
+// TestSimulatedNetwork signs over a network that delays, reorders and duplicates messages,
+// then checks that a partitioned party is reported once rounds time out.
+func TestSimulatedNetwork(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadSortedTestKeys(t, signerCount)
+	net := simnet.New(pIDs, simnet.Config{
+		Latency:       time.Millisecond,
+		Jitter:        10 * time.Millisecond,
+		DuplicateRate: 0.5,
+		Seed:          1,
+	})
+	defer net.Close()
+	simulated := func(session string) partyOption {
+		return func(i int, params *tss.Parameters) {
+			params.SetBroker(net.Broker(pIDs[i]))
+			params.SetSessionID(session)
+			params.SetRoundTimeout(10 * time.Second)
+		}
+	}
+
+	msg := testMessage("simnet")
+	signings := startSigning(t, keys, pIDs, threshold, msg, simulated("sign1"))
+	sigs := waitSignatures(t, signings, keys[0].ECDSAPub.ToECDSAPubKey(), msg)
+	for i := 1; i < signerCount; i++ {
+		assert.Equal(t, sigs[0].Signature, sigs[i].Signature)
+	}
+
+	stats := net.Stats("sign1")
+	assert.Positive(t, stats.Messages)
+	assert.Positive(t, stats.Bytes)
+	assert.Positive(t, stats.Duplicated)
+	assert.Zero(t, stats.Dropped)
+
+	// party 2 is cut off from the others
+	net.Partition(pIDs[:2], pIDs[2:])
+	for i, sg := range startSigning(t, keys, pIDs, threshold, msg, simulated("sign2")) {
+		tssErr := waitSigningError(t, i, sg)
+		assert.ErrorIs(t, tssErr, tss.ErrRoundTimeout)
+		assert.Equal(t, 1, tssErr.Round())
+		if i < 2 {
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		} else {
+			assert.Len(t, tssErr.Culprits(), 2)
+		}
+	}
+}
@@ -1,9 +1,165 @@
 package ecdsatss
 
+import (
+	"crypto/ecdsa"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
 // SignatureData holds the output of a threshold ECDSA signing operation.
 type SignatureData struct {
-	R, S      []byte // R and S components
-	Signature []byte // R || S
-	Recovery  byte   // recovery byte for public key recovery
-	M         []byte // original message hash that was signed
+	R, S      []byte        // R and S components
+	Signature []byte        // R || S
+	Recovery  byte          // recovery byte for public key recovery
+	M         []byte        // original message hash that was signed
+	Curve     tss.CurveName // name of the curve of the key, empty if it is not registered
+}
+
+// DER returns the signature encoded as an ASN.1 DER SEQUENCE of the integers R and S, as used
+// by Bitcoin scripts, X.509 and TLS.
+func (sig *SignatureData) DER() ([]byte, error) {
+	return asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)})
+}
+
+// Compact returns the 65 bytes compact signature of Bitcoin message signing: a header byte of
+// 27 plus the recovery byte, plus 4 when the public key is serialized compressed, then R and S.
+// It fails on other curves than secp256k1.
+func (sig *SignatureData) Compact(compressed bool) ([]byte, error) {
+	if sig.Curve != tss.Secp256k1 {
+		return nil, fmt.Errorf("compact signatures are only defined over %s, not %q", tss.Secp256k1, sig.Curve)
+	}
+	if len(sig.R) != 32 || len(sig.S) != 32 {
+		return nil, fmt.Errorf("compact signatures need 32 bytes R and S, not %d and %d", len(sig.R), len(sig.S))
+	}
+	if sig.Recovery > 3 {
+		return nil, fmt.Errorf("invalid recovery byte %d", sig.Recovery)
+	}
+	header := 27 + sig.Recovery
+	if compressed {
+		header += 4
+	}
+	return append(append([]byte{header}, sig.R...), sig.S...), nil
+}
+
+// EthereumV returns the v of the signature in an Ethereum transaction: 27 or 28 for a legacy
+// one when chainID is nil, or chainID*2+35 plus the recovery byte as defined by EIP-155. It
+// fails on other curves than secp256k1, and when R is not the x coordinate of the nonce
+// point, which Ethereum cannot encode.
+func (sig *SignatureData) EthereumV(chainID *big.Int) (*big.Int, error) {
+	if sig.Curve != tss.Secp256k1 {
+		return nil, fmt.Errorf("ethereum signatures are only defined over %s, not %q", tss.Secp256k1, sig.Curve)
+	}
+	if sig.Recovery > 1 {
+		return nil, fmt.Errorf("recovery byte %d cannot be encoded in v", sig.Recovery)
+	}
+	if chainID == nil {
+		return big.NewInt(27 + int64(sig.Recovery)), nil
+	}
+	v := new(big.Int).Lsh(chainID, 1)
+	return v.Add(v, big.NewInt(35+int64(sig.Recovery))), nil
+}
+
+// Ethereum returns the 65 bytes R || S || v signature checked by the ecrecover of Ethereum and
+// returned by eth_sign, with a v of 27 or 28. It fails on other curves than secp256k1.
+func (sig *SignatureData) Ethereum() ([]byte, error) {
+	if sig.Curve != tss.Secp256k1 {
+		return nil, fmt.Errorf("ethereum signatures are only defined over %s, not %q", tss.Secp256k1, sig.Curve)
+	}
+	if len(sig.R) != 32 || len(sig.S) != 32 {
+		return nil, fmt.Errorf("ethereum signatures need 32 bytes R and S, not %d and %d", len(sig.R), len(sig.S))
+	}
+	v, err := sig.EthereumV(nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(append([]byte{}, sig.R...), sig.S...), byte(v.Int64())), nil
+}
+
+// VerifyDER reports whether der, a signature encoded by SignatureData.DER, is a valid
+// signature of hash by pub.
+func VerifyDER(pub *ecdsa.PublicKey, hash, der []byte) bool {
+	return ecdsa.VerifyASN1(pub, hash, der)
+}
+
+// VerifyCompact reports whether sig, a signature encoded by SignatureData.Compact, is a valid
+// signature of hash by pub, whose recovery byte recovers pub. pub must be over secp256k1.
+func VerifyCompact(pub *ecdsa.PublicKey, hash, sig []byte) bool {
+	if len(sig) != 65 || sig[0] < 27 || sig[0] > 34 {
+		return false
+	}
+	return verifyWithRecovery(pub, hash, sig[1:33], sig[33:], (sig[0]-27)&3)
+}
+
+// VerifyEthereum reports whether rs, the R || S of a signature, with the given v is a valid
+// signature of hash by pub, whose recovery byte recovers pub. v is 27 or 28, or 0 or 1 as
+// returned by some signers, when chainID is nil, and an EIP-155 v for chainID otherwise. pub
+// must be over secp256k1.
+func VerifyEthereum(pub *ecdsa.PublicKey, hash, rs []byte, v, chainID *big.Int) bool {
+	if len(rs) == 0 || len(rs)%2 != 0 || v == nil {
+		return false
+	}
+	recovery := new(big.Int).Set(v)
+	if chainID != nil {
+		recovery.Sub(recovery, new(big.Int).Lsh(chainID, 1))
+		recovery.Sub(recovery, big.NewInt(35))
+	} else if recovery.Cmp(big.NewInt(27)) >= 0 {
+		recovery.Sub(recovery, big.NewInt(27))
+	}
+	if !recovery.IsInt64() || recovery.Int64() < 0 || recovery.Int64() > 1 {
+		return false
+	}
+	return verifyWithRecovery(pub, hash, rs[:len(rs)/2], rs[len(rs)/2:], byte(recovery.Int64()))
+}
+
+// verifyWithRecovery reports whether (r, s) is a valid signature of hash by pub, like
+// ecdsa.Verify, made with the nonce point described by recovery: the parity of its y, and
+// whether its x is above the order. The recovery byte is only defined over secp256k1.
+func verifyWithRecovery(pub *ecdsa.PublicKey, hash, rBytes, sBytes []byte, recovery byte) bool {
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil || !tss.SameCurve(pub.Curve, tss.S256()) {
+		return false
+	}
+	ec := pub.Curve
+	N := ec.Params().N
+	r, s := new(big.Int).SetBytes(rBytes), new(big.Int).SetBytes(sBytes)
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(N) >= 0 || s.Cmp(N) >= 0 {
+		return false
+	}
+
+	// the nonce point is R = (e G + r pub) / s
+	w := new(big.Int).ModInverse(s, N)
+	if w == nil {
+		return false
+	}
+	u1 := new(big.Int).Mul(hashToInt(hash, N), w)
+	u1.Mod(u1, N)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, N)
+	x1, y1 := ec.ScalarBaseMult(u1.Bytes())
+	x2, y2 := ec.ScalarMult(pub.X, pub.Y, u2.Bytes())
+	x, y := ec.Add(x1, y1, x2, y2)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+	if new(big.Int).Mod(x, N).Cmp(r) != 0 {
+		return false
+	}
+	return y.Bit(0) == uint(recovery&1) && (x.Cmp(N) >= 0) == (recovery&2 != 0)
+}
+
+// hashToInt converts hash to an integer modulo the order N the way crypto/ecdsa does, keeping
+// its leftmost bits when it is longer than N.
+func hashToInt(hash []byte, N *big.Int) *big.Int {
+	orderBits := N.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(hash) > orderBytes {
+		hash = hash[:orderBytes]
+	}
+	ret := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
 }
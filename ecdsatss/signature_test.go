@@ -0,0 +1,130 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KarpelesLab/secp256k1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestSignatureEncodingsAndKeepHighS(t *testing.T) {
+	const (
+		signerCount = 3
+		threshold   = 2
+	)
+
+	keys, pIDs := loadTestKeys(t, signerCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	keyFor := func(p *tss.PartyID) *Key {
+		for _, key := range keys {
+			if key.ShareID.Cmp(p.KeyInt()) == 0 {
+				return key
+			}
+		}
+		t.Fatalf("no key for party %s", p)
+		return nil
+	}
+
+	// the first party keeps the S computed by the signers, the others normalize it
+	msgHash := sha256.Sum256([]byte("encodings"))
+	msg := new(big.Int).SetBytes(msgHash[:])
+	hub := newTestHub(signerCount)
+	signings := make([]*Signing, signerCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, signerCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		params.SetKeepHighS(i == 0)
+
+		sg, err := keyFor(p).NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+	sigs := make([]*SignatureData, signerCount)
+	for i, sg := range signings {
+		select {
+		case sigs[i] = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+
+	N := tss.S256().Params().N
+	halfN := new(big.Int).Rsh(N, 1)
+	kept, low := sigs[0], sigs[1]
+	assert.LessOrEqual(t, new(big.Int).SetBytes(low.S).Cmp(halfN), 0)
+	if new(big.Int).SetBytes(kept.S).Cmp(halfN) > 0 {
+		assert.Equal(t, 0, new(big.Int).Sub(N, new(big.Int).SetBytes(kept.S)).Cmp(new(big.Int).SetBytes(low.S)))
+		assert.Equal(t, low.Recovery^1, kept.Recovery)
+	} else {
+		assert.Equal(t, low.Signature, kept.Signature)
+	}
+
+	pub := keys[0].ECDSAPub.ToECDSAPubKey()
+	otherHash := sha256.Sum256([]byte("other"))
+	for _, sig := range []*SignatureData{kept, low} {
+		assert.Equal(t, tss.Secp256k1, sig.Curve)
+		der, err := sig.DER()
+		require.NoError(t, err)
+		assert.True(t, VerifyDER(pub, msgHash[:], der))
+		assert.False(t, VerifyDER(pub, otherHash[:], der))
+
+		compact, err := sig.Compact(true)
+		require.NoError(t, err)
+		assert.True(t, VerifyCompact(pub, msgHash[:], compact))
+		assert.False(t, VerifyCompact(pub, otherHash[:], compact))
+		recovered, compressed, err := secp256k1.RecoverCompact(compact, msgHash[:])
+		require.NoError(t, err)
+		assert.True(t, compressed)
+		assert.True(t, recovered.IsEqual(keys[0].ECDSAPub.ToSecp256k1PubKey()))
+		compact[0] ^= 1 // the other recovery byte recovers another key
+		assert.False(t, VerifyCompact(pub, msgHash[:], compact))
+
+		v, err := sig.EthereumV(nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(27+sig.Recovery), v.Int64())
+		assert.True(t, VerifyEthereum(pub, msgHash[:], sig.Signature, v, nil))
+		assert.True(t, VerifyEthereum(pub, msgHash[:], sig.Signature, big.NewInt(int64(sig.Recovery)), nil))
+		assert.False(t, VerifyEthereum(pub, msgHash[:], sig.Signature, big.NewInt(int64(27+(sig.Recovery^1))), nil))
+		rsv, err := sig.Ethereum()
+		require.NoError(t, err)
+		assert.Equal(t, append(append([]byte{}, sig.Signature...), byte(v.Int64())), rsv)
+
+		chainID := big.NewInt(1)
+		v, err = sig.EthereumV(chainID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(37+sig.Recovery), v.Int64())
+		assert.True(t, VerifyEthereum(pub, msgHash[:], sig.Signature, v, chainID))
+		assert.False(t, VerifyEthereum(pub, msgHash[:], sig.Signature, v, big.NewInt(5)))
+	}
+
+	// the encodings fail on the signatures they cannot hold
+	_, err := (&SignatureData{R: make([]byte, 48), S: make([]byte, 48), Curve: tss.Secp256k1}).Compact(false)
+	assert.Error(t, err)
+	_, err = (&SignatureData{R: make([]byte, 32), S: make([]byte, 32), Recovery: 2, Curve: tss.Secp256k1}).EthereumV(nil)
+	assert.Error(t, err)
+	assert.False(t, VerifyCompact(&ecdsa.PublicKey{}, msgHash[:], make([]byte, 65)))
+
+	// and on the signatures over other curves, even with 32 bytes R and S
+	p256 := &SignatureData{R: make([]byte, 32), S: make([]byte, 32), Curve: tss.Secp256r1}
+	_, err = p256.Compact(true)
+	assert.Error(t, err)
+	_, err = p256.EthereumV(nil)
+	assert.Error(t, err)
+	_, err = p256.Ethereum()
+	assert.Error(t, err)
+	p256Pub := &ecdsa.PublicKey{Curve: tss.P256(), X: tss.P256().Params().Gx, Y: tss.P256().Params().Gy}
+	compact, err := low.Compact(true)
+	require.NoError(t, err)
+	assert.False(t, VerifyCompact(p256Pub, msgHash[:], compact))
+	assert.False(t, VerifyEthereum(p256Pub, msgHash[:], low.Signature, big.NewInt(27), nil))
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"slices"
 	"sync"
@@ -15,14 +16,26 @@ import (
 	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
 	"github.com/KarpelesLab/tss-lib/v2/crypto/mta"
 	"github.com/KarpelesLab/tss-lib/v2/crypto/schnorr"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
 
+// TaskSigning is the task name reported in errors from Signing.
+const TaskSigning = "ecdsa-signing"
+
 // Signing tracks a threshold ECDSA signing operation.
 type Signing struct {
 	ctx    context.Context
 	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
 	key    *Key
+	task   string
+	rand   io.Reader // random source, a stream of its own in a batch
+
+	// presigned receives the result of a presigning, which broadcasts ki·R and sigma_i·R
+	// once R is known
+	presigned chan *PreSignature
 
 	// round 1
 	w, m, k, gamma *big.Int
@@ -89,16 +102,39 @@ type Signing struct {
 // NTildej, H1j, H2j, BigXj, PaillierPKs) to match params.Parties().IDs() via
 // SubsetForParties, so callers can pass the full keygen key as-is.
 func (key *Key) NewSigning(ctx context.Context, msg *big.Int, params *tss.Parameters) (*Signing, error) {
+	s, err := key.newSigning(ctx, msg, params, TaskSigning)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// newSigning returns a Signing of msg, which is nil when presigning.
+func (key *Key) newSigning(ctx context.Context, msg *big.Int, params *tss.Parameters, task string) (*Signing, error) {
 	subsetKey, err := key.SubsetForParties(params.Parties().IDs())
 	if err != nil {
 		return nil, err
 	}
+	s := subsetKey.signingState(ctx, msg, params, task)
+	s.broker = tss.NewSessionBroker(params.Broker())
+	return s, nil
+}
+
+// signingState returns the state of a signing of msg by key, which must already be reindexed
+// for the parties of params, with no broker. Its rounds are run by the caller.
+func (key *Key) signingState(ctx context.Context, msg *big.Int, params *tss.Parameters, task string) *Signing {
 	partyCount := params.PartyCount()
-	s := &Signing{
+	return &Signing{
 		ctx:           ctx,
 		params:        params,
-		key:           subsetKey,
+		key:           key,
+		task:          task,
+		rand:          params.Rand(),
 		m:             msg,
+		ssidNonce:     big.NewInt(0),
 		cis:           make([]*big.Int, partyCount),
 		bigWs:         make([]*crypto.ECPoint, partyCount),
 		betas:         make([]*big.Int, partyCount),
@@ -111,10 +147,16 @@ func (key *Key) NewSigning(ctx context.Context, msg *big.Int, params *tss.Parame
 		Done:          make(chan *SignatureData, 1),
 		Err:           make(chan error, 1),
 	}
+}
+
+// start kicks off round 1.
+func (s *Signing) start() error {
+	s.stop = context.AfterFunc(s.ctx, func() { s.fail(s.ctx.Err()) })
 	if err := s.round1(); err != nil {
-		return nil, err
+		s.release()
+		return err
 	}
-	return s, nil
+	return nil
 }
 
 // NewSigningWithKDD is a drop-in replacement for NewSigning that signs under a BIP32-derived
@@ -150,8 +192,26 @@ func (key *Key) NewSigningWithKDD(ctx context.Context, msg *big.Int, params *tss
 		keyClone.BigXj[j] = shifted
 	}
 
+	// the other shares of a party of a weighted key are shifted the same way
+	if key.MoreBigXj != nil {
+		keyClone.MoreBigXj = make([][]*crypto.ECPoint, len(key.MoreBigXj))
+		for j, bigXs := range key.MoreBigXj {
+			for m, bigX := range bigXs {
+				shifted, err := bigX.Add(deltaG)
+				if err != nil {
+					return nil, fmt.Errorf("failed to shift MoreBigXj[%d][%d]: %w", j, m, err)
+				}
+				keyClone.MoreBigXj[j] = append(keyClone.MoreBigXj[j], shifted)
+			}
+		}
+	}
+
 	modQ := common.ModInt(params.EC().Params().N)
 	keyClone.Xi = modQ.Add(keyDerivationDelta, key.Xi)
+	keyClone.MoreXi = nil
+	for _, xi := range key.MoreXi {
+		keyClone.MoreXi = append(keyClone.MoreXi, modQ.Add(keyDerivationDelta, xi))
+	}
 
 	return (&keyClone).NewSigning(ctx, msg, params)
 }
@@ -166,6 +226,11 @@ func (s *Signing) getSSID() ([]byte, error) {
 		return nil, fmt.Errorf("read BigXj failed: %w", err)
 	}
 	ssidList = append(ssidList, BigXjList...)
+	moreBigXjList, err := vss.FlattenPublicShares(s.key.MoreBigXj)
+	if err != nil {
+		return nil, fmt.Errorf("read MoreBigXj failed: %w", err)
+	}
+	ssidList = append(ssidList, moreBigXjList...)
 	ssidList = append(ssidList, s.key.NTildej...)
 	ssidList = append(ssidList, s.key.H1j...)
 	ssidList = append(ssidList, s.key.H2j...)
@@ -175,62 +240,29 @@ func (s *Signing) getSSID() ([]byte, error) {
 	return ssid, nil
 }
 
-// prepareForSigning computes the Lagrange coefficient wi and the transformed bigWs.
+// prepareForSigning computes the additive share wi and the transformed bigWs, from the shares
+// of the parties and the Lagrange coefficients of their evaluation points.
 func (s *Signing) prepareForSigning() error {
 	i := s.params.PartyID().Index
-	ec := s.params.EC()
+	q := s.params.EC().Params().N
 
-	xi := s.key.Xi
-	ks := s.key.Ks
-	bigXs := s.key.BigXj
-
-	if s.params.Threshold()+1 > len(ks) {
-		return fmt.Errorf("t+1=%d is not satisfied by the key count of %d", s.params.Threshold()+1, len(ks))
-	}
-
-	// Use the PrepareForSigning from the ecdsa/signing package pattern
-	modQ := common.ModInt(ec.Params().N)
-	pax := len(ks)
-
-	if len(ks) != len(bigXs) {
-		return fmt.Errorf("PrepareForSigning: len(ks) != len(bigXs) (%d != %d)", len(ks), len(bigXs))
+	coefs, err := s.key.shareCoefs(s.params)
+	if err != nil {
+		return fmt.Errorf("PrepareForSigning: %w", err)
 	}
-	if pax <= i {
-		return fmt.Errorf("PrepareForSigning: pax <= i (%d <= %d)", pax, i)
+	if len(coefs) <= i {
+		return fmt.Errorf("PrepareForSigning: pax <= i (%d <= %d)", len(coefs), i)
 	}
-
-	// Compute wi
-	wi := new(big.Int).Set(xi)
-	for j := 0; j < pax; j++ {
-		if j == i {
-			continue
-		}
-		ksj := ks[j]
-		ksi := ks[i]
-		if ksj.Cmp(ksi) == 0 {
-			return fmt.Errorf("index of two parties are equal")
-		}
-		coef := modQ.Mul(ks[j], modQ.ModInverse(new(big.Int).Sub(ksj, ksi)))
-		wi = modQ.Mul(wi, coef)
+	wi, err := s.key.additiveShare(q, coefs[i])
+	if err != nil {
+		return fmt.Errorf("PrepareForSigning: %w", err)
 	}
 
-	// Compute bigWs
-	bigWs := make([]*crypto.ECPoint, pax)
-	for j := 0; j < pax; j++ {
-		bigWj := bigXs[j]
-		for c := 0; c < pax; c++ {
-			if j == c {
-				continue
-			}
-			ksc := ks[c]
-			ksj := ks[j]
-			if ksj.Cmp(ksc) == 0 {
-				return fmt.Errorf("index of two parties are equal")
-			}
-			iota := modQ.Mul(ksc, modQ.ModInverse(new(big.Int).Sub(ksc, ksj)))
-			bigWj = bigWj.ScalarMult(iota)
+	bigWs := make([]*crypto.ECPoint, len(coefs))
+	for j := range bigWs {
+		if bigWs[j], err = s.key.publicShare(j, coefs[j]); err != nil {
+			return fmt.Errorf("PrepareForSigning: %w", err)
 		}
-		bigWs[j] = bigWj
 	}
 
 	s.w = wi
@@ -239,37 +271,71 @@ func (s *Signing) prepareForSigning() error {
 }
 
 func (s *Signing) round1() error {
+	r1m1s, r1m2, err := s.makeRound1msgs()
+	if err != nil {
+		return err
+	}
+	Pi := s.params.PartyID()
+	otherIds := s.params.Parties().IDs().Exclude(Pi)
+
+	// Send the P2P Alice init messages (round1-1)
+	for _, Pj := range otherIds {
+		m := tss.JsonWrapPrivate(s.params.MsgType("ecdsa:sign:round1-1"), r1m1s[Pj.Index], Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Broadcast commitment (round1-2)
+	for _, Pj := range otherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round1-2"), r1m2, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Set pending counter for two incoming message types
+	atomic.StoreInt32(&s.r1pending, 2)
+
+	// Register receivers for both round 1 message types
+	rcv1 := tss.NewJsonExpect[signRound1msg1](s.params.MsgType("ecdsa:sign:round1-1"), otherIds, s.onR1msg1, s.timeout(1), tss.WithPrivate())
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round1-1"), rcv1)
+
+	rcv2 := tss.NewJsonExpect[signRound1msg2](s.params.MsgType("ecdsa:sign:round1-2"), otherIds, s.onR1msg2, s.timeout(1), s.echo(1, "ecdsa:sign:round1-2", otherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round1-2"), rcv2)
+
+	return nil
+}
+
+// makeRound1msgs generates k and gamma, and returns the P2P Alice init message for each other
+// party, indexed by party, and the broadcast commitment to pointGamma.
+func (s *Signing) makeRound1msgs() ([]*signRound1msg1, *signRound1msg2, error) {
 	Pi := s.params.PartyID()
 	i := Pi.Index
 	ec := s.params.EC()
 
-	// Validate message
-	if s.m.Cmp(ec.Params().N) >= 0 {
-		return errors.New("hashed message is not valid")
+	// Validate message, unless presigning
+	if s.presigned == nil && (s.m == nil || s.m.Cmp(ec.Params().N) >= 0) {
+		return nil, nil, errors.New("hashed message is not valid")
 	}
 
 	// Initialize ssid
-	s.ssidNonce = new(big.Int).SetUint64(0)
 	var err error
 	s.ssid, err = s.getSSID()
 	if err != nil {
-		return fmt.Errorf("failed to generate ssid: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate ssid: %w", err)
 	}
 
 	// Prepare for signing (Lagrange coefficients)
 	if err := s.prepareForSigning(); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Generate random k, gamma
-	k := common.GetRandomPositiveInt(s.params.Rand(), ec.Params().N)
-	gamma := common.GetRandomPositiveInt(s.params.Rand(), ec.Params().N)
+	k := common.GetRandomPositiveInt(s.rand, ec.Params().N)
+	gamma := common.GetRandomPositiveInt(s.rand, ec.Params().N)
 
 	// Compute pointGamma = gamma * G
 	pointGamma := crypto.ScalarBaseMult(ec, gamma)
 
 	// Create commitment to pointGamma
-	cmt := cmts.NewHashCommitment(s.params.Rand(), pointGamma.X(), pointGamma.Y())
+	cmt := cmts.NewHashCommitment(s.rand, pointGamma.X(), pointGamma.Y())
 
 	s.k = k
 	s.gamma = gamma
@@ -286,6 +352,7 @@ func (s *Signing) round1() error {
 	}
 
 	// For each other party j: AliceInit to create Paillier ciphertext and range proof
+	r1m1s := make([]*signRound1msg1, len(s.params.Parties().IDs()))
 	for _, Pj := range otherIds {
 		j := Pj.Index
 		cA, piA, err := mta.AliceInit(
@@ -295,47 +362,29 @@ func (s *Signing) round1() error {
 			s.key.NTildej[j],
 			s.key.H1j[j],
 			s.key.H2j[j],
-			s.params.Rand(),
+			s.rand,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to init mta: %w", err)
+			return nil, nil, fmt.Errorf("failed to init mta: %w", err)
 		}
 		s.cis[j] = cA
 
 		pfBz := piA.Bytes()
-		r1m1 := &signRound1msg1{
+		r1m1s[j] = &signRound1msg1{
 			C:               cA.Bytes(),
 			RangeProofAlice: pfBz[:],
 		}
-		m := tss.JsonWrap("ecdsa:sign:round1-1", r1m1, Pi, Pj)
-		s.params.Broker().Receive(m)
 	}
 
-	// Broadcast commitment (round1-2)
 	r1m2 := &signRound1msg2{
 		Commitment: cmt.C.Bytes(),
 	}
-	for _, Pj := range otherIds {
-		m := tss.JsonWrap("ecdsa:sign:round1-2", r1m2, Pi, Pj)
-		s.params.Broker().Receive(m)
-	}
-
-	// Set pending counter for two incoming message types
-	atomic.StoreInt32(&s.r1pending, 2)
-
-	// Register receivers for both round 1 message types
-	rcv1 := tss.NewJsonExpect[signRound1msg1]("ecdsa:sign:round1-1", otherIds, s.onR1msg1)
-	s.params.Broker().Connect("ecdsa:sign:round1-1", rcv1)
-
-	rcv2 := tss.NewJsonExpect[signRound1msg2]("ecdsa:sign:round1-2", otherIds, s.onR1msg2)
-	s.params.Broker().Connect("ecdsa:sign:round1-2", rcv2)
-
-	return nil
+	return r1m1s, r1m2, nil
 }
 
 func (s *Signing) onR1msg1(from []*tss.PartyID, msgs []*signRound1msg1) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
 	s.r1msg1From = from
@@ -347,7 +396,7 @@ func (s *Signing) onR1msg1(from []*tss.PartyID, msgs []*signRound1msg1) {
 
 func (s *Signing) onR1msg2(from []*tss.PartyID, msgs []*signRound1msg2) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
 	s.r1msg2From = from
@@ -359,9 +408,31 @@ func (s *Signing) onR1msg2(from []*tss.PartyID, msgs []*signRound1msg2) {
 
 func (s *Signing) round2() {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	r2msgs, err := s.makeRound2msgs()
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Send round 2 P2P messages
+	Pi := s.params.PartyID()
+	otherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range otherIds {
+		m := tss.JsonWrapPrivate(s.params.MsgType("ecdsa:sign:round2"), r2msgs[Pj.Index], Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 2 messages -> triggers round 3
+	rcv := tss.NewJsonExpect[signRound2msg](s.params.MsgType("ecdsa:sign:round2"), otherIds, s.round3, s.timeout(2), tss.WithPrivate())
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round2"), rcv)
+}
+
+// makeRound2msgs checks the round 1 messages stored in s, runs the MtA conversions as Bob and
+// returns the P2P message for each other party, indexed by party.
+func (s *Signing) makeRound2msgs() ([]*signRound2msg, error) {
 	Pi := s.params.PartyID()
 	i := Pi.Index
 	ec := s.params.EC()
@@ -401,8 +472,18 @@ func (s *Signing) round2() {
 	results := make([]bobResult, len(s.r1msg1From))
 	errChs := make(chan error, len(s.r1msg1From)*2)
 	wg := sync.WaitGroup{}
-	wg.Add(len(s.r1msg1From) * 2)
 
+	// each conversion draws from its own stream, so that they make the same proofs whatever
+	// order they run in
+	streams := make([]io.Reader, 2*len(s.r1msg1From))
+	for n := range streams {
+		var err error
+		if streams[n], err = tss.RandStream(s.rand); err != nil {
+			return nil, s.wrapError(2, err)
+		}
+	}
+
+	wg.Add(len(s.r1msg1From) * 2)
 	for idx := range s.r1msg1From {
 		j := msg1IdxMap[idx]
 		results[idx].j = j
@@ -412,7 +493,7 @@ func (s *Signing) round2() {
 			defer wg.Done()
 			rangeProof, err := mta.RangeProofAliceFromBytes(s.r1msg1[idx].RangeProofAlice)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: RangeProofAliceFromBytes failed: %w", j, err)
+				errChs <- s.wrapError(2, fmt.Errorf("RangeProofAliceFromBytes failed: %w", err), allParties[j])
 				return
 			}
 			cA := new(big.Int).SetBytes(s.r1msg1[idx].C)
@@ -429,10 +510,10 @@ func (s *Signing) round2() {
 				s.key.NTildej[i],
 				s.key.H1j[i],
 				s.key.H2j[i],
-				s.params.Rand(),
+				streams[2*idx],
 			)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: BobMid failed: %w", j, err)
+				errChs <- s.wrapError(2, fmt.Errorf("BobMid failed: %w", err), allParties[j])
 				return
 			}
 			s.betas[j] = beta
@@ -446,7 +527,7 @@ func (s *Signing) round2() {
 			defer wg.Done()
 			rangeProof, err := mta.RangeProofAliceFromBytes(s.r1msg1[idx].RangeProofAlice)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: RangeProofAliceFromBytes (WC) failed: %w", j, err)
+				errChs <- s.wrapError(2, fmt.Errorf("RangeProofAliceFromBytes (WC) failed: %w", err), allParties[j])
 				return
 			}
 			cA := new(big.Int).SetBytes(s.r1msg1[idx].C)
@@ -464,10 +545,10 @@ func (s *Signing) round2() {
 				s.key.H1j[i],
 				s.key.H2j[i],
 				s.bigWs[i],
-				s.params.Rand(),
+				streams[2*idx+1],
 			)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: BobMidWC failed: %w", j, err)
+				errChs <- s.wrapError(2, fmt.Errorf("BobMidWC failed: %w", err), allParties[j])
 				return
 			}
 			s.vs[j] = v
@@ -479,46 +560,54 @@ func (s *Signing) round2() {
 
 	wg.Wait()
 	close(errChs)
+	var errs []error
 	for err := range errChs {
-		if err != nil {
-			s.Err <- err
-			return
-		}
+		errs = append(errs, err)
 	}
-
-	// Send round 2 P2P messages
-	var otherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		otherIds = append(otherIds, p)
+	if err := tss.JoinErrors(errs...); err != nil {
+		return nil, err
 	}
 
+	r2msgs := make([]*signRound2msg, len(allParties))
 	for _, res := range results {
 		j := res.j
-		Pj := allParties[j]
-
-		r2m := &signRound2msg{
+		r2msgs[j] = &signRound2msg{
 			C1:         s.c1jis[j].Bytes(),
 			ProofBob:   res.proofBob,
 			C2:         s.c2jis[j].Bytes(),
 			ProofBobWC: res.proofBobWC,
 		}
-		m := tss.JsonWrap("ecdsa:sign:round2", r2m, Pi, Pj)
-		s.params.Broker().Receive(m)
 	}
-
-	// Register receiver for round 2 messages -> triggers round 3
-	rcv := tss.NewJsonExpect[signRound2msg]("ecdsa:sign:round2", otherIds, s.round3)
-	s.params.Broker().Connect("ecdsa:sign:round2", rcv)
+	return r2msgs, nil
 }
 
 func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	r3msg, err := s.makeRound3msg(otherIds, r2msgs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Broadcast theta
+	Pi := s.params.PartyID()
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round3"), r3msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 3 messages -> triggers round 4
+	rcv := tss.NewJsonExpect[signRound3msg](s.params.MsgType("ecdsa:sign:round3"), nextOtherIds, s.round4, s.timeout(3), s.echo(3, "ecdsa:sign:round3", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round3"), rcv)
+}
+
+// makeRound3msg completes the MtA conversions as Alice with the round 2 messages, and returns
+// the broadcast share of theta.
+func (s *Signing) makeRound3msg(otherIds []*tss.PartyID, r2msgs []*signRound2msg) (*signRound3msg, error) {
 	Pi := s.params.PartyID()
 	i := Pi.Index
 	ec := s.params.EC()
@@ -551,7 +640,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 			defer wg.Done()
 			proofBob, err := mta.ProofBobFromBytes(r2msgs[k].ProofBob)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: ProofBobFromBytes failed: %w", j, err)
+				errChs <- s.wrapError(3, fmt.Errorf("ProofBobFromBytes failed: %w", err), allParties[j])
 				return
 			}
 			alphaIj, err := mta.AliceEnd(
@@ -567,7 +656,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 				s.key.PaillierSK,
 			)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: AliceEnd failed: %w", j, err)
+				errChs <- s.wrapError(3, fmt.Errorf("AliceEnd failed: %w", err), allParties[j])
 				return
 			}
 			alphas[j] = alphaIj
@@ -578,7 +667,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 			defer wg.Done()
 			proofBobWC, err := mta.ProofBobWCFromBytes(ec, r2msgs[k].ProofBobWC)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: ProofBobWCFromBytes failed: %w", j, err)
+				errChs <- s.wrapError(3, fmt.Errorf("ProofBobWCFromBytes failed: %w", err), allParties[j])
 				return
 			}
 			uIj, err := mta.AliceEndWC(
@@ -595,7 +684,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 				s.key.PaillierSK,
 			)
 			if err != nil {
-				errChs <- fmt.Errorf("party %d: AliceEndWC failed: %w", j, err)
+				errChs <- s.wrapError(3, fmt.Errorf("AliceEndWC failed: %w", err), allParties[j])
 				return
 			}
 			us[j] = uIj
@@ -604,11 +693,12 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 
 	wg.Wait()
 	close(errChs)
+	var errs []error
 	for err := range errChs {
-		if err != nil {
-			s.Err <- err
-			return
-		}
+		errs = append(errs, err)
+	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		return nil, err
 	}
 
 	// Compute theta and sigma
@@ -627,34 +717,42 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 	s.theta = theta
 	s.sigma = sigma
 
-	// Broadcast theta
 	r3msg := &signRound3msg{
 		Theta: theta.Bytes(),
 	}
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round3", r3msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 3 messages -> triggers round 4
-	rcv := tss.NewJsonExpect[signRound3msg]("ecdsa:sign:round3", nextOtherIds, s.round4)
-	s.params.Broker().Connect("ecdsa:sign:round3", rcv)
+	return r3msg, nil
 }
 
 func (s *Signing) round4(otherIds []*tss.PartyID, r3msgs []*signRound3msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	r4msg, err := s.makeRound4msg(otherIds, r3msgs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Broadcast decommitment + proof
+	Pi := s.params.PartyID()
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round4"), r4msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 4 messages -> triggers round 5
+	rcv := tss.NewJsonExpect[signRound4msg](s.params.MsgType("ecdsa:sign:round4"), nextOtherIds, s.round5, s.timeout(4), s.echo(4, "ecdsa:sign:round4", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round4"), rcv)
+}
+
+// makeRound4msg computes the inverse of theta from the round 3 messages, and returns the
+// broadcast decommitment to pointGamma with its Schnorr proof.
+func (s *Signing) makeRound4msg(otherIds []*tss.PartyID, r3msgs []*signRound3msg) (*signRound4msg, error) {
 	Pi := s.params.PartyID()
 	i := Pi.Index
 	ec := s.params.EC()
-	allParties := s.params.Parties().IDs()
 
 	modN := common.ModInt(ec.Params().N)
 
@@ -671,13 +769,11 @@ func (s *Signing) round4(otherIds []*tss.PartyID, r3msgs []*signRound3msg) {
 
 	// Schnorr proof for gamma
 	ContextI := append(s.ssid, new(big.Int).SetUint64(uint64(i)).Bytes()...)
-	piGamma, err := schnorr.NewZKProof(ContextI, s.gamma, s.pointGamma, s.params.Rand())
+	piGamma, err := schnorr.NewZKProof(ContextI, s.gamma, s.pointGamma, s.rand)
 	if err != nil {
-		s.Err <- fmt.Errorf("NewZKProof(gamma, pointGamma): %w", err)
-		return
+		return nil, s.wrapError(4, fmt.Errorf("NewZKProof(gamma, pointGamma): %w", err))
 	}
 
-	// Broadcast decommitment + proof
 	dcBzs := common.BigIntsToBytes(s.deCommit)
 	r4msg := &signRound4msg{
 		DeCommitment: dcBzs,
@@ -685,29 +781,45 @@ func (s *Signing) round4(otherIds []*tss.PartyID, r3msgs []*signRound3msg) {
 		ProofAlphaY:  piGamma.Alpha.Y().Bytes(),
 		ProofT:       piGamma.T.Bytes(),
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round4", r4msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 4 messages -> triggers round 5
-	rcv := tss.NewJsonExpect[signRound4msg]("ecdsa:sign:round4", nextOtherIds, s.round5)
-	s.params.Broker().Connect("ecdsa:sign:round4", rcv)
+	return r4msg, nil
 }
 
 func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
+		return
+	}
+	R, err := s.computeR(otherIds, r4msgs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	if s.presigned != nil {
+		s.presignRound5(R)
 		return
 	}
+	r5msg, err := s.makeRound5msg(R)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Broadcast commitment
 	Pi := s.params.PartyID()
-	i := Pi.Index
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round5"), r5msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 5 messages -> triggers round 6
+	rcv := tss.NewJsonExpect[signRound5msg](s.params.MsgType("ecdsa:sign:round5"), nextOtherIds, s.round6, s.timeout(5), s.echo(5, "ecdsa:sign:round5", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round5"), rcv)
+}
+
+// computeR checks the decommitments to the pointGamma of the other parties and returns the
+// nonce point R.
+func (s *Signing) computeR(otherIds []*tss.PartyID, r4msgs []*signRound4msg) (*crypto.ECPoint, error) {
 	ec := s.params.EC()
 	allParties := s.params.Parties().IDs()
 
@@ -736,14 +848,12 @@ func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 		cmtDeCmt := cmts.HashCommitDecommit{C: SCj, D: SDj}
 		ok, bigGammaJ := cmtDeCmt.DeCommit()
 		if !ok || len(bigGammaJ) != 2 {
-			s.Err <- fmt.Errorf("party %d: commitment verification failed", j)
-			return
+			return nil, s.wrapError(5, errors.New("commitment verification failed"), allParties[j])
 		}
 
 		bigGammaJPoint, err := crypto.NewECPoint(ec, bigGammaJ[0], bigGammaJ[1])
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: NewECPoint(bigGammaJ): %w", j, err)
-			return
+			return nil, s.wrapError(5, fmt.Errorf("NewECPoint(bigGammaJ): %w", err), allParties[j])
 		}
 
 		// Verify Schnorr proof
@@ -751,27 +861,30 @@ func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 		alphaY := new(big.Int).SetBytes(r4msgs[k].ProofAlphaY)
 		alpha, err := crypto.NewECPoint(ec, alphaX, alphaY)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: failed to reconstruct Schnorr proof alpha point: %w", j, err)
-			return
+			return nil, s.wrapError(5, fmt.Errorf("failed to reconstruct Schnorr proof alpha point: %w", err), allParties[j])
 		}
 		proof := &schnorr.ZKProof{
 			Alpha: alpha,
 			T:     new(big.Int).SetBytes(r4msgs[k].ProofT),
 		}
 		if !proof.Verify(ContextJ, bigGammaJPoint) {
-			s.Err <- fmt.Errorf("party %d: Schnorr proof verification failed for bigGamma", j)
-			return
+			return nil, s.wrapError(5, errors.New("Schnorr proof verification failed for bigGamma"), allParties[j])
 		}
 
 		R, err = R.Add(bigGammaJPoint)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: R.Add(bigGammaJ): %w", j, err)
-			return
+			return nil, s.wrapError(5, fmt.Errorf("R.Add(bigGammaJ): %w", err), allParties[j])
 		}
 	}
 
 	// R = bigGamma * thetaInverse
-	R = R.ScalarMult(s.thetaInverse)
+	return R.ScalarMult(s.thetaInverse), nil
+}
+
+// makeRound5msg computes si with the nonce point R and returns the broadcast commitment to
+// (Vi, Ai).
+func (s *Signing) makeRound5msg(R *crypto.ECPoint) (*signRound5msg, error) {
+	ec := s.params.EC()
 
 	N := ec.Params().N
 	modN := common.ModInt(N)
@@ -785,8 +898,8 @@ func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 	s.gamma = zero
 
 	// Generate random li, roi
-	li := common.GetRandomPositiveInt(s.params.Rand(), N)
-	roI := common.GetRandomPositiveInt(s.params.Rand(), N)
+	li := common.GetRandomPositiveInt(s.rand, N)
+	roI := common.GetRandomPositiveInt(s.rand, N)
 
 	// Compute bigVi = R^si + G^li
 	rToSi := R.ScalarMult(si)
@@ -794,12 +907,11 @@ func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 	bigAi := crypto.ScalarBaseMult(ec, roI)
 	bigVi, err := rToSi.Add(liPoint)
 	if err != nil {
-		s.Err <- fmt.Errorf("rToSi.Add(liPoint): %w", err)
-		return
+		return nil, s.wrapError(5, fmt.Errorf("rToSi.Add(liPoint): %w", err))
 	}
 
 	// Commit to (bigVi.X, bigVi.Y, bigAi.X, bigAi.Y)
-	cmt := cmts.NewHashCommitment(s.params.Rand(), bigVi.X(), bigVi.Y(), bigAi.X(), bigAi.Y())
+	cmt := cmts.NewHashCommitment(s.rand, bigVi.X(), bigVi.Y(), bigAi.X(), bigAi.Y())
 
 	s.li = li
 	s.roi = roI
@@ -811,31 +923,39 @@ func (s *Signing) round5(otherIds []*tss.PartyID, r4msgs []*signRound4msg) {
 	s.bigR = R
 	s.r5DeCommit = cmt.D
 
-	// Broadcast commitment
 	r5msg := &signRound5msg{
 		Commitment: cmt.C.Bytes(),
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round5", r5msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 5 messages -> triggers round 6
-	rcv := tss.NewJsonExpect[signRound5msg]("ecdsa:sign:round5", nextOtherIds, s.round6)
-	s.params.Broker().Connect("ecdsa:sign:round5", rcv)
+	return r5msg, nil
 }
 
 func (s *Signing) round6(otherIds []*tss.PartyID, r5msgs []*signRound5msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
+		return
+	}
+	r6msg, err := s.makeRound6msg(otherIds, r5msgs)
+	if err != nil {
+		s.fail(err)
 		return
 	}
+
+	// Broadcast decommitment + proofs
+	Pi := s.params.PartyID()
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round6"), r6msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 6 messages -> triggers round 7
+	rcv := tss.NewJsonExpect[signRound6msg](s.params.MsgType("ecdsa:sign:round6"), nextOtherIds, s.round7, s.timeout(6), s.echo(6, "ecdsa:sign:round6", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round6"), rcv)
+}
+
+// makeRound6msg stores the round 5 commitments, and returns the broadcast decommitment to
+// (Vi, Ai) with the proofs of their knowledge.
+func (s *Signing) makeRound6msg(otherIds []*tss.PartyID, r5msgs []*signRound5msg) (*signRound6msg, error) {
 	Pi := s.params.PartyID()
 	i := Pi.Index
 	allParties := s.params.Parties().IDs()
@@ -852,18 +972,15 @@ func (s *Signing) round6(otherIds []*tss.PartyID, r5msgs []*signRound5msg) {
 
 	// Create Schnorr proofs
 	ContextI := append(s.ssid, new(big.Int).SetUint64(uint64(i)).Bytes()...)
-	piAi, err := schnorr.NewZKProof(ContextI, s.roi, s.bigAi, s.params.Rand())
+	piAi, err := schnorr.NewZKProof(ContextI, s.roi, s.bigAi, s.rand)
 	if err != nil {
-		s.Err <- fmt.Errorf("NewZKProof(roi, bigAi): %w", err)
-		return
+		return nil, s.wrapError(6, fmt.Errorf("NewZKProof(roi, bigAi): %w", err))
 	}
-	piV, err := schnorr.NewZKVProof(ContextI, s.bigVi, s.bigR, s.si, s.li, s.params.Rand())
+	piV, err := schnorr.NewZKVProof(ContextI, s.bigVi, s.bigR, s.si, s.li, s.rand)
 	if err != nil {
-		s.Err <- fmt.Errorf("NewZKVProof(bigVi, bigR, si, li): %w", err)
-		return
+		return nil, s.wrapError(6, fmt.Errorf("NewZKVProof(bigVi, bigR, si, li): %w", err))
 	}
 
-	// Broadcast decommitment + proofs
 	dcBzs := common.BigIntsToBytes(s.r5DeCommit)
 	r6msg := &signRound6msg{
 		DeCommitment: dcBzs,
@@ -875,29 +992,36 @@ func (s *Signing) round6(otherIds []*tss.PartyID, r5msgs []*signRound5msg) {
 		VProofT:      piV.T.Bytes(),
 		VProofU:      piV.U.Bytes(),
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round6", r6msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 6 messages -> triggers round 7
-	rcv := tss.NewJsonExpect[signRound6msg]("ecdsa:sign:round6", nextOtherIds, s.round7)
-	s.params.Broker().Connect("ecdsa:sign:round6", rcv)
+	return r6msg, nil
 }
 
 func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	r7msg, err := s.makeRound7msg(otherIds, r6msgs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Broadcast commitment
 	Pi := s.params.PartyID()
-	i := Pi.Index
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round7"), r7msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 7 messages -> triggers round 8
+	rcv := tss.NewJsonExpect[signRound7msg](s.params.MsgType("ecdsa:sign:round7"), nextOtherIds, s.round8, s.timeout(7), s.echo(7, "ecdsa:sign:round7", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round7"), rcv)
+}
+
+// makeRound7msg checks the round 6 decommitments and proofs, and returns the broadcast
+// commitment to (Ui, Ti).
+func (s *Signing) makeRound7msg(otherIds []*tss.PartyID, r6msgs []*signRound6msg) (*signRound7msg, error) {
 	ec := s.params.EC()
 	allParties := s.params.Parties().IDs()
 
@@ -925,22 +1049,19 @@ func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 		cmtDeCmt := cmts.HashCommitDecommit{C: cj, D: dj}
 		ok, values := cmtDeCmt.DeCommit()
 		if !ok || len(values) != 4 {
-			s.Err <- fmt.Errorf("party %d: de-commitment for bigVj and bigAj failed", j)
-			return
+			return nil, s.wrapError(7, errors.New("de-commitment for bigVj and bigAj failed"), allParties[j])
 		}
 
 		bigVjX, bigVjY, bigAjX, bigAjY := values[0], values[1], values[2], values[3]
 		bigVj, err := crypto.NewECPoint(ec, bigVjX, bigVjY)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: NewECPoint(bigVj): %w", j, err)
-			return
+			return nil, s.wrapError(7, fmt.Errorf("NewECPoint(bigVj): %w", err), allParties[j])
 		}
 		bigVjs[j] = bigVj
 
 		bigAj, err := crypto.NewECPoint(ec, bigAjX, bigAjY)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: NewECPoint(bigAj): %w", j, err)
-			return
+			return nil, s.wrapError(7, fmt.Errorf("NewECPoint(bigAj): %w", err), allParties[j])
 		}
 		bigAjs[j] = bigAj
 
@@ -949,16 +1070,14 @@ func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 		pAlphaY := new(big.Int).SetBytes(r6msgs[k].ProofAlphaY)
 		pAlpha, err := crypto.NewECPoint(ec, pAlphaX, pAlphaY)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: failed to reconstruct Schnorr proof alpha for Aj: %w", j, err)
-			return
+			return nil, s.wrapError(7, fmt.Errorf("failed to reconstruct Schnorr proof alpha for Aj: %w", err), allParties[j])
 		}
 		pijA := &schnorr.ZKProof{
 			Alpha: pAlpha,
 			T:     new(big.Int).SetBytes(r6msgs[k].ProofT),
 		}
 		if !pijA.Verify(ContextJ, bigAj) {
-			s.Err <- fmt.Errorf("party %d: Schnorr verify for Aj failed", j)
-			return
+			return nil, s.wrapError(7, errors.New("Schnorr verify for Aj failed"), allParties[j])
 		}
 
 		// Verify ZKV proof for Vj
@@ -966,8 +1085,7 @@ func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 		vAlphaY := new(big.Int).SetBytes(r6msgs[k].VProofAlphaY)
 		vAlpha, err := crypto.NewECPoint(ec, vAlphaX, vAlphaY)
 		if err != nil {
-			s.Err <- fmt.Errorf("party %d: failed to reconstruct ZKV proof alpha for Vj: %w", j, err)
-			return
+			return nil, s.wrapError(7, fmt.Errorf("failed to reconstruct ZKV proof alpha for Vj: %w", err), allParties[j])
 		}
 		pijV := &schnorr.ZKVProof{
 			Alpha: vAlpha,
@@ -975,8 +1093,7 @@ func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 			U:     new(big.Int).SetBytes(r6msgs[k].VProofU),
 		}
 		if !pijV.Verify(ContextJ, bigVj, s.bigR) {
-			s.Err <- fmt.Errorf("party %d: ZKV proof verify for Vj failed", j)
-			return
+			return nil, s.wrapError(7, errors.New("ZKV proof verify for Vj failed"), allParties[j])
 		}
 	}
 
@@ -1006,36 +1123,42 @@ func (s *Signing) round7(otherIds []*tss.PartyID, r6msgs []*signRound6msg) {
 	s.Ti = crypto.NewECPointNoCurveCheck(ec, TiX, TiY)
 
 	// Commit to (Ui.X, Ui.Y, Ti.X, Ti.Y)
-	cmt := cmts.NewHashCommitment(s.params.Rand(), UiX, UiY, TiX, TiY)
+	cmt := cmts.NewHashCommitment(s.rand, UiX, UiY, TiX, TiY)
 	s.r7DeCommit = cmt.D
 
-	// Broadcast commitment
 	r7msg := &signRound7msg{
 		Commitment: cmt.C.Bytes(),
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round7", r7msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 7 messages -> triggers round 8
-	rcv := tss.NewJsonExpect[signRound7msg]("ecdsa:sign:round7", nextOtherIds, s.round8)
-	s.params.Broker().Connect("ecdsa:sign:round7", rcv)
+	return r7msg, nil
 }
 
 func (s *Signing) round8(otherIds []*tss.PartyID, r7msgs []*signRound7msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
+		return
+	}
+	r8msg, err := s.makeRound8msg(otherIds, r7msgs)
+	if err != nil {
+		s.fail(err)
 		return
 	}
+
+	// Broadcast decommitment
 	Pi := s.params.PartyID()
-	i := Pi.Index
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round8"), r8msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 8 messages -> triggers round 9
+	rcv := tss.NewJsonExpect[signRound8msg](s.params.MsgType("ecdsa:sign:round8"), nextOtherIds, s.round9, s.timeout(8), s.echo(8, "ecdsa:sign:round8", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round8"), rcv)
+}
+
+// makeRound8msg stores the round 7 commitments, and returns the broadcast decommitment to
+// (Ui, Ti).
+func (s *Signing) makeRound8msg(otherIds []*tss.PartyID, r7msgs []*signRound7msg) (*signRound8msg, error) {
 	allParties := s.params.Parties().IDs()
 
 	// Store commitments from round 7
@@ -1048,34 +1171,40 @@ func (s *Signing) round8(otherIds []*tss.PartyID, r7msgs []*signRound7msg) {
 		}
 	}
 
-	// Broadcast decommitment
 	dcBzs := common.BigIntsToBytes(s.r7DeCommit)
 	r8msg := &signRound8msg{
 		DeCommitment: dcBzs,
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round8", r8msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 8 messages -> triggers round 9
-	rcv := tss.NewJsonExpect[signRound8msg]("ecdsa:sign:round8", nextOtherIds, s.round9)
-	s.params.Broker().Connect("ecdsa:sign:round8", rcv)
+	return r8msg, nil
 }
 
 func (s *Signing) round9(otherIds []*tss.PartyID, r8msgs []*signRound8msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	r9msg, err := s.makeRound9msg(otherIds, r8msgs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	// Broadcast si
 	Pi := s.params.PartyID()
-	i := Pi.Index
+	nextOtherIds := s.params.Parties().IDs().Exclude(Pi)
+	for _, Pj := range nextOtherIds {
+		m := tss.JsonWrap(s.params.MsgType("ecdsa:sign:round9"), r9msg, Pi, Pj)
+		s.broker.Receive(m)
+	}
+
+	// Register receiver for round 9 messages -> triggers finalize
+	rcv := tss.NewJsonExpect[signRound9msg](s.params.MsgType("ecdsa:sign:round9"), nextOtherIds, s.finalize, s.timeout(9), s.echo(9, "ecdsa:sign:round9", nextOtherIds))
+	s.broker.Connect(s.params.MsgType("ecdsa:sign:round9"), rcv)
+}
+
+// makeRound9msg checks the round 8 decommitments and that U equals T, and returns the
+// broadcast partial signature si.
+func (s *Signing) makeRound9msg(otherIds []*tss.PartyID, r8msgs []*signRound8msg) (*signRound9msg, error) {
 	ec := s.params.EC()
 	allParties := s.params.Parties().IDs()
 
@@ -1102,82 +1231,101 @@ func (s *Signing) round9(otherIds []*tss.PartyID, r8msgs []*signRound8msg) {
 		cmtObj := cmts.HashCommitDecommit{C: cj, D: dj}
 		ok, values := cmtObj.DeCommit()
 		if !ok || len(values) != 4 {
-			s.Err <- fmt.Errorf("party %d: de-commitment for Uj and Tj failed", j)
-			return
+			return nil, s.wrapError(9, errors.New("de-commitment for Uj and Tj failed"), allParties[j])
+		}
+		// the curves of crypto/elliptic panic on points that are not on the curve
+		Uj, err := crypto.NewECPoint(ec, values[0], values[1])
+		if err != nil {
+			return nil, s.wrapError(9, fmt.Errorf("NewECPoint(Uj): %w", err), allParties[j])
+		}
+		Tj, err := crypto.NewECPoint(ec, values[2], values[3])
+		if err != nil {
+			return nil, s.wrapError(9, fmt.Errorf("NewECPoint(Tj): %w", err), allParties[j])
 		}
-		UjX, UjY, TjX, TjY := values[0], values[1], values[2], values[3]
-		UX, UY = ec.Add(UX, UY, UjX, UjY)
-		TX, TY = ec.Add(TX, TY, TjX, TjY)
+		UX, UY = ec.Add(UX, UY, Uj.X(), Uj.Y())
+		TX, TY = ec.Add(TX, TY, Tj.X(), Tj.Y())
 	}
 
 	// Check U == T
 	if UX.Cmp(TX) != 0 || UY.Cmp(TY) != 0 {
-		s.Err <- errors.New("U doesn't equal T")
-		return
+		return nil, s.wrapError(9, errors.New("U doesn't equal T"))
 	}
 
-	// Broadcast si
 	r9msg := &signRound9msg{
 		Si: s.si.Bytes(),
 	}
-
-	var nextOtherIds []*tss.PartyID
-	for n, p := range allParties {
-		if n == i {
-			continue
-		}
-		nextOtherIds = append(nextOtherIds, p)
-		m := tss.JsonWrap("ecdsa:sign:round9", r9msg, Pi, p)
-		s.params.Broker().Receive(m)
-	}
-
-	// Register receiver for round 9 messages -> triggers finalize
-	rcv := tss.NewJsonExpect[signRound9msg]("ecdsa:sign:round9", nextOtherIds, s.finalize)
-	s.params.Broker().Connect("ecdsa:sign:round9", rcv)
+	return r9msg, nil
 }
 
 func (s *Signing) finalize(otherIds []*tss.PartyID, r9msgs []*signRound9msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
+	sjs := make([]*big.Int, len(r9msgs))
+	for n, r9msg := range r9msgs {
+		sjs[n] = new(big.Int).SetBytes(r9msg.Si)
+	}
+	s.combine(10, sjs)
+}
+
+// combine sums si with the sjs of the other parties, and sends the signature on Done once
+// verified. A failure is reported as the given round.
+func (s *Signing) combine(round int, sjs []*big.Int) {
+	sigData, err := s.signature(round, sjs)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+	s.release()
+	s.Done <- sigData
+}
+
+// signature returns the signature made of si and the sjs of the other parties, once verified.
+// A failure is reported as the given round.
+func (s *Signing) signature(round int, sjs []*big.Int) (*SignatureData, error) {
 	ec := s.params.EC()
 	modN := common.ModInt(ec.Params().N)
 
 	// Sum all si values
 	sumS := new(big.Int).Set(s.si)
-	for _, r9msg := range r9msgs {
-		sj := new(big.Int).SetBytes(r9msg.Si)
+	for _, sj := range sjs {
 		sumS = modN.Add(sumS, sj)
 	}
 
+	// r is the x coordinate of R reduced modulo N, which changes it on curves whose field is
+	// larger than N, such as P-256
+	r := new(big.Int).Mod(s.rx, ec.Params().N)
+
 	// Compute recovery byte
 	recid := 0
-	if s.rx.Cmp(ec.Params().N) > 0 {
+	if s.rx.Cmp(ec.Params().N) >= 0 {
 		recid = 2
 	}
 	if s.ry.Bit(0) != 0 {
 		recid |= 1
 	}
 
-	// Low-S normalization (BIP-62)
+	// Low-S normalization (BIP-62), unless disabled for this session
 	halfN := new(big.Int).Rsh(ec.Params().N, 1)
-	if sumS.Cmp(halfN) > 0 {
+	if !s.params.KeepHighS() && sumS.Cmp(halfN) > 0 {
 		sumS.Sub(ec.Params().N, sumS)
 		recid ^= 1
 	}
 
 	// Build signature data
-	bitSizeInBytes := ec.Params().BitSize / 8
-	rBytes := padToLengthBytesInPlace(s.rx.Bytes(), bitSizeInBytes)
+	bitSizeInBytes := (ec.Params().BitSize + 7) / 8
+	rBytes := padToLengthBytesInPlace(r.Bytes(), bitSizeInBytes)
 	sBytes := padToLengthBytesInPlace(sumS.Bytes(), bitSizeInBytes)
 
+	curve, _ := tss.GetCurveName(ec)
 	sigData := &SignatureData{
 		R:         rBytes,
 		S:         sBytes,
 		Signature: append(rBytes, sBytes...),
 		Recovery:  byte(recid),
 		M:         s.m.Bytes(),
+		Curve:     curve,
 	}
 
 	// Verify signature
@@ -1187,13 +1335,11 @@ func (s *Signing) finalize(otherIds []*tss.PartyID, r9msgs []*signRound9msg) {
 		Y:     s.key.ECDSAPub.Y(),
 	}
 
-	ok := ecdsa.Verify(&pk, sigData.M, s.rx, sumS)
+	ok := ecdsa.Verify(&pk, sigData.M, r, sumS)
 	if !ok {
-		s.Err <- fmt.Errorf("signature verification failed")
-		return
+		return nil, s.wrapError(round, errors.New("signature verification failed"))
 	}
-
-	s.Done <- sigData
+	return sigData, nil
 }
 
 func padToLengthBytesInPlace(src []byte, length int) []byte {
@@ -1204,3 +1350,42 @@ func padToLengthBytesInPlace(src []byte, length int) []byte {
 	copy(padded[length-len(src):], src)
 	return padded
 }
+
+// fail reports err on Err and releases the receivers registered by this signing.
+func (s *Signing) fail(err error) {
+	s.release()
+	select {
+	case s.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this signing from the broker.
+func (s *Signing) release() {
+	s.stop()
+	s.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (s *Signing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(s.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (s *Signing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !s.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(s.broker, s.params.MsgType(typ+":echo"), s.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (s *Signing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, s.task, round, s.params.PartyID(), culprits...)
+}
@@ -0,0 +1,52 @@
+package ecdsatss
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// ErrWeightedKey is returned by the operations which do not support the keys generated with
+// tss.NewWeightedParameters, where a party holds several shares.
+var ErrWeightedKey = errors.New("operation not supported on weighted keys")
+
+// shares returns the share data of the key.
+func (key *Key) shares() vss.KeyShares {
+	return vss.KeyShares{
+		Ks:        key.Ks,
+		MoreKs:    key.MoreKs,
+		BigXj:     key.BigXj,
+		MoreBigXj: key.MoreBigXj,
+		Xi:        key.Xi,
+		MoreXi:    key.MoreXi,
+		Ranks:     key.Ranks,
+	}
+}
+
+// Weights returns the number of shares held by each party of the key, in the order of Ks:
+// 1 for every party unless the key was generated with tss.NewWeightedParameters.
+func (key *Key) Weights() []int {
+	return key.shares().Weights()
+}
+
+// shareCoefs returns the coefficients of the shares of each party of the key, which must
+// already be reindexed for the committee of params, as vss.KeyShares.Coefficients does for
+// the threshold, weights and ranks of params.
+func (key *Key) shareCoefs(params *tss.Parameters) ([][]*big.Int, error) {
+	return key.shares().Coefficients(params.EC().Params().N, params.Threshold(), params.Weights(), params.Ranks())
+}
+
+// additiveShare returns the additive share of the secret of this party for coefs, its
+// coefficients returned by shareCoefs.
+func (key *Key) additiveShare(q *big.Int, coefs []*big.Int) (*big.Int, error) {
+	return key.shares().AdditiveShare(q, coefs)
+}
+
+// publicShare returns the public additive share of the party of index j for coefs, its
+// coefficients returned by shareCoefs.
+func (key *Key) publicShare(j int, coefs []*big.Int) (*crypto.ECPoint, error) {
+	return key.shares().PublicShare(j, coefs)
+}
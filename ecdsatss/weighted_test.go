@@ -0,0 +1,151 @@
+package ecdsatss
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// signWeighted signs msgHash with the keys of the parties of ids, of the given weights, and
+// checks the signature against pub.
+func signWeighted(t *testing.T, keys []*Key, ids tss.SortedPartyIDs, weights []int, threshold int, msgHash []byte, pub *crypto.ECPoint) {
+	msg := new(big.Int).SetBytes(msgHash)
+	signCtx := tss.NewPeerContext(ids)
+	hub := newTestHub(len(ids))
+	signings := make([]*Signing, len(ids))
+	for n, p := range ids {
+		params := tss.NewWeightedParameters(tss.S256(), signCtx, p, weights, threshold)
+		params.SetBroker(hub.brokers[n])
+
+		sg, err := keys[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(time.Minute):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := ecdsa.PublicKey{Curve: tss.S256(), X: pub.X(), Y: pub.Y()}
+	assert.True(t, ecdsa.Verify(&pk, msgHash, new(big.Int).SetBytes(sig.R), new(big.Int).SetBytes(sig.S)))
+}
+
+func TestWeightedKeygenSignAndReshare(t *testing.T) {
+	const threshold = 2
+
+	// the treasury, party 0, holds 2 shares and each operator 1
+	fixtures, _ := loadTestKeys(t, 5)
+	weights := []int{2, 1, 1}
+	pIDs := tss.GenerateTestPartyIDs(len(weights))
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(len(pIDs))
+	keygens := make([]*Keygen, len(pIDs))
+	for n, p := range pIDs {
+		params := tss.NewWeightedParameters(tss.S256(), p2pCtx, p, weights, threshold)
+		params.SetBroker(hub.brokers[n])
+		params.SetNoProofMod()
+		params.SetNoProofFac()
+
+		kg, err := NewKeygen(context.Background(), params, fixtures[n].LocalPreParams)
+		require.NoError(t, err)
+		keygens[n] = kg
+	}
+	keys := make([]*Key, len(pIDs))
+	for n, kg := range keygens {
+		select {
+		case keys[n] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("Party %d keygen error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d keygen timed out", n)
+		}
+	}
+	pub := keys[0].ECDSAPub
+	for n, key := range keys {
+		assert.True(t, pub.Equals(key.ECDSAPub))
+		assert.Equal(t, weights, key.Weights())
+		require.Len(t, key.MoreXi, weights[n]-1)
+		assert.True(t, crypto.ScalarBaseMult(tss.S256(), key.Xi).Equals(key.BigXj[n]))
+		for m, xi := range key.MoreXi {
+			assert.True(t, crypto.ScalarBaseMult(tss.S256(), xi).Equals(key.MoreBigXj[n][m]))
+		}
+	}
+
+	// the treasury signs with either operator, the operators cannot sign without it
+	msgHash := sha256.Sum256([]byte("weighted"))
+	signWeighted(t, []*Key{keys[0], keys[2]}, subsetIDs(pIDs, 0, 2), []int{2, 1}, threshold, msgHash[:], pub)
+	operatorIDs := subsetIDs(pIDs, 1, 2)
+	assert.Panics(t, func() {
+		tss.NewWeightedParameters(tss.S256(), tss.NewPeerContext(operatorIDs), operatorIDs[0], []int{1, 1}, threshold)
+	})
+
+	// weights which are not those of the key are rejected
+	mixedIDs := subsetIDs(pIDs, 0, 1)
+	params := tss.NewWeightedParameters(tss.S256(), tss.NewPeerContext(mixedIDs), mixedIDs[0], []int{1, 1}, 1)
+	_, err := keys[0].NewSigning(context.Background(), new(big.Int).SetBytes(msgHash[:]), params)
+	assert.Error(t, err)
+
+	// the treasury and an operator reshare to three new parties, the last one holding 2 shares
+	oldIDs := subsetIDs(pIDs, 0, 1)
+	oldKeys := []*Key{keys[0], keys[1]}
+	oldWeights := []int{2, 1}
+	newIDs := generateOffsetTestPartyIDs(3, len(pIDs))
+	newWeights := []int{1, 1, 2}
+	newPreParams := []LocalPreParams{fixtures[3].LocalPreParams, fixtures[4].LocalPreParams, fixtures[0].LocalPreParams}
+	oldCtx, newCtx := tss.NewPeerContext(oldIDs), tss.NewPeerContext(newIDs)
+	reshareHub := newResharingHub(append(append([]*tss.PartyID{}, oldIDs...), newIDs...))
+	var resharings []*Resharing
+	for n, p := range append(append([]*tss.PartyID{}, oldIDs...), newIDs...) {
+		params := tss.NewWeightedReSharingParameters(tss.S256(), oldCtx, newCtx, p, oldWeights, threshold, newWeights, threshold)
+		params.SetNoProofMod()
+		params.SetNoProofFac()
+		params.SetBroker(reshareHub.brokerFor(p))
+
+		var rs *Resharing
+		if n < len(oldIDs) {
+			rs, err = NewResharing(context.Background(), params, oldKeys[n])
+		} else {
+			rs, err = NewResharing(context.Background(), params, nil, newPreParams[n-len(oldIDs)])
+		}
+		require.NoError(t, err)
+		resharings = append(resharings, rs)
+	}
+	newKeys := make([]*Key, len(newIDs))
+	for n, rs := range resharings {
+		select {
+		case key := <-rs.Done:
+			if n >= len(oldIDs) {
+				newKeys[n-len(oldIDs)] = key
+			}
+		case err := <-rs.Err:
+			t.Fatalf("Party %d resharing error: %v", n, err)
+		case <-time.After(5 * time.Minute):
+			t.Fatalf("Party %d resharing timed out", n)
+		}
+	}
+	for n, key := range newKeys {
+		assert.True(t, pub.Equals(key.ECDSAPub))
+		assert.Equal(t, newWeights, key.Weights())
+		require.Len(t, key.MoreXi, newWeights[n]-1)
+		for m, xi := range key.MoreXi {
+			assert.True(t, crypto.ScalarBaseMult(tss.S256(), xi).Equals(key.MoreBigXj[n][m]))
+		}
+	}
+
+	// the new party holding 2 shares signs with another one
+	signWeighted(t, []*Key{newKeys[0], newKeys[2]}, subsetIDs(newIDs, 0, 2), []int{1, 2}, threshold, msgHash[:], pub)
+}
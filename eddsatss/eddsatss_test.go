@@ -3,8 +3,12 @@ package eddsatss
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"encoding/json"
 	"fmt"
 	"math/big"
+	mrand "math/rand/v2"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,6 +18,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/KarpelesLab/tss-lib/v2/tss"
+	"github.com/KarpelesLab/tss-lib/v2/tss/simnet"
 )
 
 // hubBroker routes messages between parties in a test network.
@@ -65,6 +70,12 @@ func (b *hubBroker) Connect(typ string, dest tss.MessageReceiver) {
 	}
 }
 
+func (b *hubBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
 func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
 	if msg.From.Index == b.partyIdx {
 		// outbound from this party: route to destination
@@ -97,6 +108,36 @@ func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
 	return handler.Receive(msg)
 }
 
+// runKeygen runs keygen for n parties over a test hub and returns their keys.
+func runKeygen(t *testing.T, n, threshold int) ([]*Key, tss.SortedPartyIDs) {
+	t.Helper()
+	pIDs := tss.GenerateTestPartyIDs(n)
+	hub := newTestHub(n)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, n)
+	for i := range keygens {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], n, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, n)
+	for i := range keys {
+		select {
+		case keys[i] = <-keygens[i].Done:
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+	return keys, pIDs
+}
+
 func TestKeygenFull(t *testing.T) {
 	const (
 		partyCount = 3
@@ -148,6 +189,169 @@ func TestKeygenFull(t *testing.T) {
 	t.Log("All parties completed keygen with matching public keys")
 }
 
+func TestKeygenIdentityKeys(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	privs := make([]ed25519.PrivateKey, partyCount)
+	for i, p := range pIDs {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		p.IdentityKey = pub
+		privs[i] = priv
+	}
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(tss.NewSigningBroker(hub.brokers[i], pIDs[i], privs[i]))
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Party %d keygen error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d keygen timed out", i)
+		}
+	}
+	for i := 1; i < partyCount; i++ {
+		assert.True(t, keys[0].EDDSAPub.Equals(keys[i].EDDSAPub))
+	}
+}
+
+// TestKeygenEncryptedShares runs keygen with signed and encrypted messages, checking that
+// the secret shares never cross the hub in the clear.
+func TestKeygenEncryptedShares(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	privs := make([]ed25519.PrivateKey, partyCount)
+	for i, p := range pIDs {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		p.IdentityKey = pub
+		privs[i] = priv
+	}
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	var lock sync.Mutex
+	var seen int
+	var clear []string
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		spy := &tamperBroker{hubBroker: hub.brokers[i], typ: "eddsa:keygen:round2-1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+			lock.Lock()
+			defer lock.Unlock()
+			seen++
+			if !msg.Encrypted {
+				clear = append(clear, msg.Type)
+			}
+			return msg
+		}}
+		enc, err := tss.NewEncryptingBroker(spy, pIDs[i], privs[i])
+		require.NoError(t, err)
+
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(tss.NewSigningBroker(enc, pIDs[i], privs[i]))
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Party %d keygen error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d keygen timed out", i)
+		}
+	}
+	for i := 1; i < partyCount; i++ {
+		assert.True(t, keys[0].EDDSAPub.Equals(keys[i].EDDSAPub))
+	}
+	assert.Equal(t, partyCount*(partyCount-1), seen)
+	assert.Empty(t, clear)
+}
+
+type transportFunc func(msg *tss.JsonMessage) error
+
+func (f transportFunc) Receive(msg *tss.JsonMessage) error { return f(msg) }
+
+// TestKeygenBufferedBroker runs keygen over tss.BufferedBroker, with messages serialized
+// as they would be on a real network.
+func TestKeygenBufferedBroker(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	brokers := make([]*tss.BufferedBroker, partyCount)
+	for i := 0; i < partyCount; i++ {
+		brokers[i] = tss.NewBufferedBroker(pIDs[i], transportFunc(func(msg *tss.JsonMessage) error {
+			buf, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			var in *tss.JsonMessage
+			if err := json.Unmarshal(buf, &in); err != nil {
+				return err
+			}
+			return brokers[msg.To.Index].Receive(in)
+		}))
+	}
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Party %d keygen error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d keygen timed out", i)
+		}
+	}
+
+	for i := 1; i < partyCount; i++ {
+		assert.True(t, keys[0].EDDSAPub.Equals(keys[i].EDDSAPub),
+			"party 0 and party %d should have the same public key", i)
+	}
+}
+
 // resharingHub routes messages between parties across old and new committees.
 // Unlike testHub which routes by party index, this routes by PartyID key
 // since old and new committees have different index spaces.
@@ -198,6 +402,12 @@ func (b *resharingBroker) Connect(typ string, dest tss.MessageReceiver) {
 	}
 }
 
+func (b *resharingBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
 func (b *resharingBroker) Receive(msg *tss.JsonMessage) error {
 	fromKey := msg.From.KeyInt().String()
 	if fromKey == b.partyKey {
@@ -309,6 +519,691 @@ func TestKeygenAndSign(t *testing.T) {
 	t.Logf("All parties completed signing, signature: %x", sigs[0].Signature)
 }
 
+// TestConcurrentSessions runs two signings over the same brokers at the same time, using
+// session ids to keep their receivers apart.
+func TestConcurrentSessions(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		params.SetSessionID("keygen")
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	// start both sessions on every party before collecting any result
+	sessions := []string{"sign-a", "sign-b"}
+	msgs := []*big.Int{big.NewInt(42), big.NewInt(43)}
+	signings := make([][]*Signing, len(sessions))
+	for s, sid := range sessions {
+		signings[s] = make([]*Signing, partyCount)
+		for i := 0; i < partyCount; i++ {
+			params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+			params.SetBroker(hub.brokers[i])
+			params.SetSessionID(sid)
+
+			sg, err := keys[i].NewSigning(context.Background(), msgs[s], params)
+			require.NoError(t, err)
+			signings[s][i] = sg
+		}
+	}
+
+	pk := edwards25519.PublicKey{
+		Curve: tss.Edwards(),
+		X:     keys[0].EDDSAPub.X(),
+		Y:     keys[0].EDDSAPub.Y(),
+	}
+	for s := range sessions {
+		for i := 0; i < partyCount; i++ {
+			select {
+			case sig := <-signings[s][i].Done:
+				parsed, err := edwards25519.ParseSignature(sig.Signature)
+				require.NoError(t, err)
+				assert.True(t, edwards25519.VerifyRS(&pk, msgs[s].Bytes(), parsed.R, parsed.S),
+					"session %s party %d should produce a valid signature", sessions[s], i)
+			case err := <-signings[s][i].Err:
+				t.Fatalf("session %s party %d signing error: %v", sessions[s], i, err)
+			case <-time.After(30 * time.Second):
+				t.Fatalf("session %s party %d signing timed out", sessions[s], i)
+			}
+		}
+	}
+}
+
+// TestReleaseReceivers checks that handlers are disconnected from the broker once a session
+// completes, or once its context is cancelled.
+func TestReleaseReceivers(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+	for i := 0; i < partyCount; i++ {
+		hub.brokers[i].mu.Lock()
+		assert.Empty(t, hub.brokers[i].handlers, "party %d should have released its keygen handlers", i)
+		hub.brokers[i].mu.Unlock()
+	}
+
+	// only party 0 starts signing, then gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[0], partyCount, threshold)
+	params.SetBroker(hub.brokers[0])
+	sg, err := keys[0].NewSigning(ctx, big.NewInt(42), params)
+	require.NoError(t, err)
+
+	hub.brokers[0].mu.Lock()
+	assert.NotEmpty(t, hub.brokers[0].handlers)
+	hub.brokers[0].mu.Unlock()
+
+	cancel()
+	select {
+	case err := <-sg.Err:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled signing did not report an error")
+	}
+
+	hub.brokers[0].mu.Lock()
+	assert.Empty(t, hub.brokers[0].handlers, "cancelled signing should release its handlers")
+	hub.brokers[0].mu.Unlock()
+}
+
+// TestSigningRoundTimeout checks that a party which never shows up is reported as culprit
+// once the round timeout expires.
+func TestSigningRoundTimeout(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	// party 2 never starts signing
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, 2)
+	for i := 0; i < 2; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(signHub.brokers[i])
+		params.SetRoundTimeout(200 * time.Millisecond)
+
+		sg, err := keys[i].NewSigning(context.Background(), big.NewInt(42), params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	for i, sg := range signings {
+		select {
+		case <-sg.Done:
+			t.Fatalf("party %d should not complete signing", i)
+		case err := <-sg.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.ErrorIs(t, err, tss.ErrRoundTimeout)
+			assert.Equal(t, TaskSigning, tssErr.Task())
+			assert.Equal(t, 1, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("party %d did not time out", i)
+		}
+	}
+}
+
+// TestSimulatedNetwork runs keygen and signing over a network that delays, reorders and
+// duplicates messages, then checks that a partitioned party is reported once rounds time out.
+func TestSimulatedNetwork(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	net := simnet.New(pIDs, simnet.Config{
+		Latency:       time.Millisecond,
+		Jitter:        10 * time.Millisecond,
+		DuplicateRate: 0.5,
+		Seed:          1,
+	})
+	defer net.Close()
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(net.Broker(pIDs[i]))
+		params.SetSessionID("keygen")
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	sign := func(session string) []*Signing {
+		signings := make([]*Signing, partyCount)
+		for i := 0; i < partyCount; i++ {
+			params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+			params.SetBroker(net.Broker(pIDs[i]))
+			params.SetSessionID(session)
+			params.SetRoundTimeout(time.Second)
+
+			sg, err := keys[i].NewSigning(context.Background(), big.NewInt(42), params)
+			require.NoError(t, err)
+			signings[i] = sg
+		}
+		return signings
+	}
+
+	sigs := make([]*SignatureData, partyCount)
+	for i, sg := range sign("sign1") {
+		select {
+		case sig := <-sg.Done:
+			sigs[i] = sig
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+	for i := 1; i < partyCount; i++ {
+		assert.Equal(t, sigs[0].Signature, sigs[i].Signature)
+	}
+
+	stats := net.Stats("sign1")
+	assert.Positive(t, stats.Messages)
+	assert.Positive(t, stats.Bytes)
+	assert.Positive(t, stats.Duplicated)
+	assert.Zero(t, stats.Dropped)
+
+	// party 2 is cut off from the others
+	net.Partition(pIDs[:2], pIDs[2:])
+	for i, sg := range sign("sign2") {
+		select {
+		case <-sg.Done:
+			t.Fatalf("party %d should not complete signing", i)
+		case err := <-sg.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.ErrorIs(t, err, tss.ErrRoundTimeout)
+			assert.Equal(t, 1, tssErr.Round())
+			if i < 2 {
+				require.Len(t, tssErr.Culprits(), 1)
+				assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+			} else {
+				assert.Len(t, tssErr.Culprits(), 2)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("party %d did not time out", i)
+		}
+	}
+}
+
+// TestKeygenAndSignThroughRelay runs keygen and signing with parties that only talk to a
+// relay, signing their messages and checking broadcasts with echoes.
+func TestKeygenAndSignThroughRelay(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	privs := make([]ed25519.PrivateKey, partyCount)
+	for i, p := range pIDs {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		p.IdentityKey = pub
+		privs[i] = priv
+	}
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	parties := make([]*tss.RelayParty, partyCount)
+	relay := tss.NewRelay(pIDs, func(bundle *tss.RelayBundle) error {
+		go parties[bundle.To.Index].ReceiveBundle(bundle)
+		return nil
+	})
+	for i, p := range pIDs {
+		parties[i] = tss.NewRelayParty(p, relay)
+	}
+	newParams := func(i int, session string) *tss.Parameters {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(tss.NewSigningBroker(parties[i], pIDs[i], privs[i]))
+		params.SetSessionID(session)
+		params.SetEchoBroadcast(true)
+		return params
+	}
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		kg, err := NewKeygen(context.Background(), newParams(i, "keygen"))
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+	relay.DropSession("keygen")
+
+	signings := make([]*Signing, partyCount)
+	for i := 0; i < partyCount; i++ {
+		sg, err := keys[i].NewSigning(context.Background(), big.NewInt(42), newParams(i, "sign"))
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	sigs := make([]*SignatureData, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case sig := <-signings[i].Done:
+			sigs[i] = sig
+		case err := <-signings[i].Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+	for i := 1; i < partyCount; i++ {
+		assert.Equal(t, sigs[0].Signature, sigs[i].Signature)
+	}
+}
+
+// tamperBroker lets a test corrupt the messages of the given type sent by a party.
+type tamperBroker struct {
+	*hubBroker
+	typ    string
+	tamper func(msg *tss.JsonMessage) *tss.JsonMessage
+}
+
+func (b *tamperBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx && strings.HasPrefix(msg.Type, b.typ) {
+		msg = b.tamper(msg)
+	}
+	return b.hubBroker.Receive(msg)
+}
+
+func TestKeygenIdentifiesBadShare(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		if i == 2 {
+			// party 2 sends an invalid VSS share to party 0
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "eddsa:keygen:round2-1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				if msg.To.Index != 0 {
+					return msg
+				}
+				bad := *msg.Data.(*keygenRound2msg1)
+				bad.Share = new(big.Int).Add(new(big.Int).SetBytes(bad.Share), big.NewInt(1)).Bytes()
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	select {
+	case <-keygens[0].Done:
+		t.Fatal("party 0 should reject the share of party 2")
+	case err := <-keygens[0].Err:
+		var tssErr *tss.Error
+		require.ErrorAs(t, err, &tssErr)
+		assert.Equal(t, TaskKeygen, tssErr.Task())
+		assert.Equal(t, 3, tssErr.Round())
+		assert.Equal(t, pIDs[0].Id, tssErr.Victim().Id)
+		require.Len(t, tssErr.Culprits(), 1)
+		assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+	case <-time.After(30 * time.Second):
+		t.Fatal("Keygen timed out for party 0")
+	}
+}
+
+func TestSigningIdentifiesBadShare(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		if i == 2 {
+			// party 2 broadcasts a wrong si
+			params.SetBroker(&tamperBroker{hubBroker: signHub.brokers[i], typ: "eddsa:sign:round3", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				bad := *msg.Data.(*signRound3msg)
+				bad.Si = append([]byte{}, bad.Si...)
+				bad.Si[0] ^= 1
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(signHub.brokers[i])
+		}
+
+		sg, err := keys[i].NewSigning(context.Background(), big.NewInt(42), params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	for i, sg := range signings[:2] {
+		select {
+		case <-sg.Done:
+			t.Fatalf("party %d should reject the signature", i)
+		case err := <-sg.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskSigning, tssErr.Task())
+			assert.Equal(t, 4, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Signing timed out for party %d", i)
+		}
+	}
+}
+
+func TestSigningEchoBroadcast(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		params.SetEchoBroadcast(true)
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	// party 2 signs a different commitment for party 0, which the echoes of party 1 prove
+	privs := make([]ed25519.PrivateKey, partyCount)
+	for i, p := range pIDs {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		p.IdentityKey = pub
+		privs[i] = priv
+	}
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetEchoBroadcast(true)
+		// the signatures only prove an equivocation within a session
+		params.SetSessionID("echo")
+		var broker tss.MessageBroker = signHub.brokers[i]
+		if i == 2 {
+			broker = &tamperBroker{hubBroker: signHub.brokers[i], typ: "eddsa:sign:round1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				r1msg, ok := msg.Data.(*signRound1msg)
+				if !ok || msg.To.Index != 0 {
+					return msg
+				}
+				bad := *r1msg
+				bad.Commitment = big.NewInt(1).Bytes()
+				m := tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+				require.NoError(t, m.Sign(privs[2]))
+				return m
+			}}
+		}
+		params.SetBroker(tss.NewSigningBroker(broker, pIDs[i], privs[i]))
+
+		sg, err := keys[i].NewSigning(context.Background(), big.NewInt(42), params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	for i, sg := range signings[:2] {
+		select {
+		case <-sg.Done:
+			t.Fatalf("party %d should detect the equivocation", i)
+		case err := <-sg.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.ErrorIs(t, err, tss.ErrEquivocation)
+			assert.Equal(t, 1, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Signing timed out for party %d", i)
+		}
+	}
+}
+
+// TestSigningReplay records the signing session of a party, and replays it with the same
+// random source.
+func TestSigningReplay(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case k := <-keygens[i].Done:
+			keys[i] = k
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	seed := [32]byte{1, 2, 3}
+	msg := big.NewInt(42)
+
+	var transcript bytes.Buffer
+	signHub := newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		if i == 0 {
+			params.SetRand(mrand.NewChaCha8(seed))
+			params.SetBroker(tss.NewRecordingBroker(signHub.brokers[i], pIDs[i], &transcript))
+		} else {
+			params.SetBroker(signHub.brokers[i])
+		}
+
+		sg, err := keys[i].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[i] = sg
+	}
+
+	sigs := make([]*SignatureData, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case sig := <-signings[i].Done:
+			sigs[i] = sig
+		case err := <-signings[i].Err:
+			t.Fatalf("Party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", i)
+		}
+	}
+
+	// replay the session of party 0 without its peers
+	entries, err := tss.ReadTranscript(&transcript)
+	require.NoError(t, err)
+	replay := tss.NewReplayBroker(entries)
+
+	params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[0], partyCount, threshold)
+	params.SetRand(mrand.NewChaCha8(seed))
+	params.SetBroker(replay)
+	sg, err := keys[0].NewSigning(context.Background(), msg, params)
+	require.NoError(t, err)
+
+	select {
+	case sig := <-sg.Done:
+		assert.Equal(t, sigs[0].Signature, sig.Signature)
+	case err := <-sg.Err:
+		t.Fatalf("Replayed signing error: %v", err)
+	case <-time.After(30 * time.Second):
+		t.Fatal("Replayed signing timed out")
+	}
+	assert.NoError(t, replay.Diverged())
+}
+
 // TestKeygenAndSignStrictSubset runs EdDSA keygen with 5 parties at threshold 2, then signs
 // with a non-contiguous 3-party subset {0, 2, 4}. Without SubsetForParties, Ks and BigXj
 // would stay keygen-indexed and Lagrange interpolation over the subset would use wrong x
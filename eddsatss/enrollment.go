@@ -0,0 +1,262 @@
+package eddsatss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskEnrollment is the task name reported in errors from Enrollment.
+const TaskEnrollment = "eddsa-enrollment"
+
+// Enrollment tracks the enrollment of a new party in a key. At least t+1 parties of the key,
+// the holders, give the newcomer a share at its ShareID on the polynomial of the key, as done
+// by Repair for a lost share, without changing their own shares or the threshold.
+//
+// The holders add the newcomer to their key, its public share being interpolated from the
+// public shares of the key. The other parties of the key add it with Key.AddParty.
+type Enrollment struct {
+	ctx      context.Context
+	params   *tss.Parameters
+	broker   *tss.SessionBroker
+	stop     func() bool
+	key      *Key // key of the holder, nil for the newcomer
+	newcomer *tss.PartyID
+	holders  tss.SortedPartyIDs
+	part     *big.Int // part of the Lagrange term of this holder kept by it
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewEnrollment creates a new Enrollment of the party newcomer, and executes round 1. The
+// parties of params are the holders and the newcomer, whose ShareID is the key of its PartyID.
+// The holders give their key, and the newcomer a nil key; all of them receive their key
+// including the newcomer on Done.
+func NewEnrollment(ctx context.Context, params *tss.Parameters, key *Key, newcomer *tss.PartyID) (*Enrollment, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key != nil && key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key != nil && key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	newcomer = params.Parties().IDs().FindByKey(newcomer.KeyInt())
+	if newcomer == nil {
+		return nil, errors.New("the newcomer must take part in the enrollment")
+	}
+	holders := params.Parties().IDs().Exclude(newcomer)
+	if len(holders) <= params.Threshold() {
+		return nil, fmt.Errorf("enrollment needs at least %d holders, got %d", params.Threshold()+1, len(holders))
+	}
+	isNew := params.PartyID().KeyInt().Cmp(newcomer.KeyInt()) == 0
+	if isNew != (key == nil) {
+		return nil, errors.New("the newcomer, and only it, must not give a key")
+	}
+	e := &Enrollment{
+		ctx:      ctx,
+		params:   params,
+		key:      key,
+		newcomer: newcomer,
+		holders:  holders,
+		Done:     make(chan *Key, 1),
+		Err:      make(chan error, 1),
+	}
+	e.broker = tss.NewSessionBroker(params.Broker())
+	e.stop = context.AfterFunc(ctx, func() { e.fail(ctx.Err()) })
+	if isNew {
+		e.round1New()
+		return e, nil
+	}
+	if err := e.round1(); err != nil {
+		e.release()
+		return nil, err
+	}
+	return e, nil
+}
+
+// round1 splits the Lagrange term of this holder in random parts, and sends them to the
+// other holders.
+func (e *Enrollment) round1() error {
+	Pi := e.params.PartyID()
+	q := e.params.EC().Params().N
+
+	for _, kj := range e.key.Ks {
+		if kj.Cmp(e.newcomer.KeyInt()) == 0 {
+			return errors.New("the newcomer is already a party of the key")
+		}
+	}
+	holdersKey, err := e.key.SubsetForParties(e.holders)
+	if err != nil {
+		return err
+	}
+	n := -1
+	for m, Pj := range e.holders {
+		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
+			n = m
+		}
+	}
+	parts, err := vss.LagrangeParts(e.params.Rand(), q, holdersKey.Ks, n, e.newcomer.KeyInt(), e.key.Xi)
+	if err != nil {
+		return err
+	}
+	e.part = parts[n]
+
+	otherIds := e.holders.Exclude(Pi)
+	for m, Pj := range e.holders {
+		if m == n {
+			continue
+		}
+		msg := &repairRound1msg{Part: parts[m].Bytes()}
+		e.broker.Receive(tss.JsonWrapPrivate(e.params.MsgType("eddsa:enroll:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[repairRound1msg](e.params.MsgType("eddsa:enroll:round1"), otherIds, e.round2, e.timeout(1), tss.WithPrivate())
+	e.broker.Connect(e.params.MsgType("eddsa:enroll:round1"), rcv)
+	return nil
+}
+
+// round1New waits for the sums of the holders.
+func (e *Enrollment) round1New() {
+	rcv := tss.NewJsonExpect[repairRound2msg](e.params.MsgType("eddsa:enroll:round2"), e.holders, e.round3, e.timeout(2), tss.WithPrivate())
+	e.broker.Connect(e.params.MsgType("eddsa:enroll:round2"), rcv)
+}
+
+// round2 sends the sum of the parts received by this holder to the newcomer, with the public
+// data of the key, and adds the newcomer to the key.
+func (e *Enrollment) round2(otherIds []*tss.PartyID, r1msgs []*repairRound1msg) {
+	if e.ctx.Err() != nil {
+		e.fail(e.ctx.Err())
+		return
+	}
+	modQ := common.ModInt(e.params.EC().Params().N)
+
+	sum := e.part
+	for _, r1msg := range r1msgs {
+		sum = modQ.Add(sum, new(big.Int).SetBytes(r1msg.Part))
+	}
+	msg, err := newRepairRound2msg(e.key, sum)
+	if err != nil {
+		e.fail(e.wrapError(2, err))
+		return
+	}
+	e.broker.Receive(tss.JsonWrapPrivate(e.params.MsgType("eddsa:enroll:round2"), msg, e.params.PartyID(), e.newcomer))
+
+	data, err := e.key.AddParty(e.newcomer.KeyInt())
+	if err != nil {
+		e.fail(e.wrapError(2, err))
+		return
+	}
+	e.release()
+	e.Done <- data
+}
+
+// round3 checks that the holders sent the same public data, and computes the share of the
+// newcomer.
+func (e *Enrollment) round3(holders []*tss.PartyID, r2msgs []*repairRound2msg) {
+	if e.ctx.Err() != nil {
+		e.fail(e.ctx.Err())
+		return
+	}
+	ec := e.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+
+	candidates := make([]*Key, len(r2msgs))
+	xi := big.NewInt(0)
+	for n, r2msg := range r2msgs {
+		candidate, err := repairPublicData(ec, r2msg)
+		if err != nil {
+			e.fail(e.wrapError(3, err, holders[n]))
+			return
+		}
+		candidates[n] = candidate
+		xi = modQ.Add(xi, new(big.Int).SetBytes(r2msg.Sum))
+	}
+	for _, candidate := range candidates[1:] {
+		if !candidates[0].samePublicData(candidate) {
+			// the holders outvoted by the others are the culprits, if there is a majority
+			e.fail(e.wrapError(3, errors.New("different public data of the key"), tss.MinorityCulprits(holders, candidates, (*Key).samePublicData)...))
+			return
+		}
+	}
+	key := candidates[0]
+	key.Xi = xi
+	key.ShareID = e.newcomer.KeyInt()
+
+	data, err := key.AddParty(e.newcomer.KeyInt())
+	if err != nil {
+		e.fail(e.wrapError(3, err))
+		return
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(data.BigXj[len(data.BigXj)-1]) {
+		e.fail(e.wrapError(3, errors.New("enrolled share does not match its public point")))
+		return
+	}
+
+	e.release()
+	e.Done <- data
+}
+
+// AddParty returns a copy of key with the party of identifier shareID added as its last party,
+// its public share being interpolated from the public shares of the key. The parties of the
+// key which were not holders of the Enrollment of a newcomer add it with AddParty.
+func (key *Key) AddParty(shareID *big.Int) (*Key, error) {
+	if key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	for _, kj := range key.Ks {
+		if kj.Cmp(shareID) == 0 {
+			return nil, errors.New("the newcomer is already a party of the key")
+		}
+	}
+	BigX, err := vss.InterpolatePublicShare(key.Ks, key.BigXj, shareID)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		Xi:       key.Xi,
+		ShareID:  key.ShareID,
+		Ks:       append(append([]*big.Int(nil), key.Ks...), shareID),
+		BigXj:    append(append([]*crypto.ECPoint(nil), key.BigXj...), BigX),
+		EDDSAPub: key.EDDSAPub,
+	}, nil
+}
+
+// fail reports err on Err and releases the receivers registered by this enrollment.
+func (e *Enrollment) fail(err error) {
+	e.release()
+	select {
+	case e.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this enrollment from the broker.
+func (e *Enrollment) release() {
+	e.stop()
+	e.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (e *Enrollment) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(e.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		e.fail(e.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (e *Enrollment) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskEnrollment, round, e.params.PartyID(), culprits...)
+}
@@ -0,0 +1,138 @@
+package eddsatss
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KarpelesLab/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestEnrollmentAndSign(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	allIDs := tss.GenerateTestPartyIDs(partyCount + 1)
+	pIDs := subsetIDs(allIDs, 0, 1, 2)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case keys[i] = <-keygens[i].Done:
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	// the 2-of-3 key becomes 2-of-4, parties 0 and 1 are holders and party 2 does not take part
+	enrollIDs := subsetIDs(allIDs, 0, 1, 3)
+	enrollCtx := tss.NewPeerContext(enrollIDs)
+	enrollHub := newTestHub(len(enrollIDs))
+	enrollments := make([]*Enrollment, len(enrollIDs))
+	for n, p := range enrollIDs {
+		params := tss.NewParameters(tss.Edwards(), enrollCtx, p, len(enrollIDs), threshold)
+		params.SetBroker(enrollHub.brokers[n])
+
+		var key *Key
+		if n < len(enrollIDs)-1 {
+			key = keys[n]
+		}
+		e, err := NewEnrollment(context.Background(), params, key, enrollIDs[len(enrollIDs)-1])
+		require.NoError(t, err)
+		enrollments[n] = e
+	}
+	newKeys := make([]*Key, partyCount+1)
+	for n, e := range enrollments {
+		if n == len(enrollIDs)-1 {
+			n = partyCount
+		}
+		select {
+		case newKeys[n] = <-e.Done:
+		case err := <-e.Err:
+			t.Fatalf("Party %d enrollment error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d enrollment timed out", n)
+		}
+	}
+
+	// party 2 adds the newcomer
+	var err error
+	newKeys[2], err = keys[2].AddParty(allIDs[3].KeyInt())
+	require.NoError(t, err)
+	require.Len(t, keys[2].Ks, partyCount, "the key with the newcomer is a copy")
+	_, err = newKeys[2].AddParty(allIDs[3].KeyInt())
+	assert.Error(t, err, "the newcomer is already a party of the key")
+
+	shares := make(vss.Shares, len(newKeys))
+	for n, key := range newKeys {
+		require.Len(t, key.Ks, partyCount+1)
+		assert.True(t, keys[0].EDDSAPub.Equals(key.EDDSAPub))
+		if n < partyCount {
+			assert.Equal(t, 0, keys[n].Xi.Cmp(key.Xi))
+		}
+		for j := range newKeys {
+			assert.Equal(t, 0, newKeys[0].Ks[j].Cmp(key.Ks[j]))
+			assert.True(t, newKeys[0].BigXj[j].Equals(key.BigXj[j]))
+		}
+		assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), key.Xi).Equals(key.BigXj[n]))
+		shares[n] = &vss.Share{Threshold: threshold, ID: key.ShareID, Share: key.Xi}
+	}
+	// the share of the newcomer is on the polynomial of the key
+	secret, err := shares[partyCount-1:].ReConstruct(tss.Edwards())
+	require.NoError(t, err)
+	assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), secret).Equals(keys[0].EDDSAPub))
+
+	// the newcomer signs with the party which was not a holder
+	msg := big.NewInt(42)
+	signIDs := subsetIDs(allIDs, 2, 3)
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.Edwards(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		sg, err := newKeys[2+n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := edwards25519.PublicKey{
+		Curve: tss.Edwards(),
+		X:     keys[0].EDDSAPub.X(),
+		Y:     keys[0].EDDSAPub.Y(),
+	}
+	parsed, err := edwards25519.ParseSignature(sig.Signature)
+	require.NoError(t, err)
+	assert.True(t, edwards25519.VerifyRS(&pk, msg.Bytes(), parsed.R, parsed.S))
+}
@@ -0,0 +1,13 @@
+package eddsatss
+
+import "errors"
+
+// ErrHierarchicalKey is returned by the operations which do not support the keys generated
+// with tss.NewHierarchicalParameters, whose shares have ranks.
+var ErrHierarchicalKey = errors.New("operation not supported on hierarchical keys")
+
+// ranks returns the rank of the share of each party of the key, in the order of Ks: 0 for
+// every party unless the key is hierarchical.
+func (key *Key) ranks() []int {
+	return key.shares().AllRanks()
+}
@@ -2,6 +2,7 @@ package eddsatss
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -18,6 +19,16 @@ type Key struct {
 	Ks          []*big.Int
 	BigXj       []*crypto.ECPoint
 	EDDSAPub    *crypto.ECPoint
+
+	// weighted keys, where the party of index j holds 1+len(MoreKs[j]) shares: the shares of
+	// this party at its other points, the other evaluation points of each party and the
+	// public shares at these points
+	MoreXi    []*big.Int          `json:",omitempty"`
+	MoreKs    [][]*big.Int        `json:",omitempty"`
+	MoreBigXj [][]*crypto.ECPoint `json:",omitempty"`
+
+	// hierarchical keys: the rank of the share of each party, see tss.NewHierarchicalParameters
+	Ranks []int `json:",omitempty"`
 }
 
 // NewKey initializes a Key with slices pre-allocated for the given party count.
@@ -28,8 +39,9 @@ func NewKey(partyCount int) *Key {
 	}
 }
 
-// SubsetForParties returns a new Key whose Ks and BigXj slices are reordered to match the
-// given sorted party IDs. Parties are matched by their ShareID — i.e. the Ks value stored
+// SubsetForParties returns a new Key whose Ks and BigXj slices, MoreKs and MoreBigXj of a
+// weighted key, and Ranks of a hierarchical key, are reordered to match the given sorted party
+// IDs. Parties are matched by their ShareID — i.e. the Ks value stored
 // by keygen, compared to PartyID.Key.
 //
 // This reindexing is required whenever the current party set is a strict subset of the
@@ -37,9 +49,15 @@ func NewKey(partyCount int) *Key {
 // an n-party keygen, or resharing's old committee). The signing and resharing rounds index
 // these slices by the current-party index, so the slices must be in current-party order.
 //
-// The returned Key shares Xi, ShareID, and EDDSAPub with the receiver; only Ks and BigXj
-// are rebuilt.
+// The returned Key shares Xi, ShareID, MoreXi and EDDSAPub with the receiver; only the
+// per-party slices are rebuilt.
 func (key *Key) SubsetForParties(sortedIDs tss.SortedPartyIDs) (*Key, error) {
+	if key.MoreKs != nil && (len(key.MoreKs) != len(key.Ks) || len(key.MoreBigXj) != len(key.Ks)) {
+		return nil, errors.New("SubsetForParties: the other points of the parties do not match Ks")
+	}
+	if key.Ranks != nil && len(key.Ranks) != len(key.Ks) {
+		return nil, errors.New("SubsetForParties: the ranks of the parties do not match Ks")
+	}
 	keysToIndices := make(map[string]int, len(key.Ks))
 	for j, kj := range key.Ks {
 		keysToIndices[hex.EncodeToString(kj.Bytes())] = j
@@ -50,6 +68,14 @@ func (key *Key) SubsetForParties(sortedIDs tss.SortedPartyIDs) (*Key, error) {
 		Ks:       make([]*big.Int, len(sortedIDs)),
 		BigXj:    make([]*crypto.ECPoint, len(sortedIDs)),
 		EDDSAPub: key.EDDSAPub,
+		MoreXi:   key.MoreXi,
+	}
+	if key.MoreKs != nil {
+		subset.MoreKs = make([][]*big.Int, len(sortedIDs))
+		subset.MoreBigXj = make([][]*crypto.ECPoint, len(sortedIDs))
+	}
+	if key.Ranks != nil {
+		subset.Ranks = make([]int, len(sortedIDs))
 	}
 	for j, id := range sortedIDs {
 		savedIdx, ok := keysToIndices[hex.EncodeToString(id.Key)]
@@ -58,6 +84,13 @@ func (key *Key) SubsetForParties(sortedIDs tss.SortedPartyIDs) (*Key, error) {
 		}
 		subset.Ks[j] = key.Ks[savedIdx]
 		subset.BigXj[j] = key.BigXj[savedIdx]
+		if key.MoreKs != nil {
+			subset.MoreKs[j] = key.MoreKs[savedIdx]
+			subset.MoreBigXj[j] = key.MoreBigXj[savedIdx]
+		}
+		if key.Ranks != nil {
+			subset.Ranks[j] = key.Ranks[savedIdx]
+		}
 	}
 	return subset, nil
 }
@@ -15,13 +15,19 @@ import (
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
 
+// TaskKeygen is the task name reported in errors from Keygen.
+const TaskKeygen = "eddsa-keygen"
+
 // Keygen tracks a key currently being generated via the EdDSA TSS protocol.
 type Keygen struct {
 	ctx           context.Context
 	params        *tss.Parameters
+	broker        *tss.SessionBroker
+	stop          func() bool
 	KGCs          []cmts.HashCommitment
 	vs            vss.Vs
 	shares        vss.Shares
+	moreShares    []vss.Shares // shares at the other points of each party of a weighted key
 	deCommitPolyG cmts.HashDeCommitment
 	ssid          []byte
 	ssidNonce     *big.Int
@@ -43,8 +49,11 @@ func NewKeygen(ctx context.Context, params *tss.Parameters) (*Keygen, error) {
 		Done:   make(chan *Key, 1),
 		Err:    make(chan error, 1),
 	}
+	kg.broker = tss.NewSessionBroker(params.Broker())
+	kg.stop = context.AfterFunc(ctx, func() { kg.fail(ctx.Err()) })
 	err := kg.round1()
 	if err != nil {
+		kg.release()
 		return nil, err
 	}
 	return kg, nil
@@ -59,6 +68,10 @@ func (kg *Keygen) getSSID(roundNum int) ([]byte, error) {
 		kg.params.EC().Params().Gy,
 	}
 	ssidList = append(ssidList, kg.params.Parties().IDs().Keys()...)
+	ssidList = append(ssidList, vss.AllPoints(nil, kg.data.MoreKs)...)
+	for _, r := range kg.data.Ranks {
+		ssidList = append(ssidList, big.NewInt(int64(r)))
+	}
 	ssidList = append(ssidList, big.NewInt(int64(roundNum)))
 	ssidList = append(ssidList, kg.ssidNonce)
 	ssid := common.SHA512_256i(ssidList...).Bytes()
@@ -72,13 +85,19 @@ func (kg *Keygen) round1() error {
 	// 1. calculate "partial" key share ui
 	ui := common.GetRandomPositiveInt(kg.params.PartialKeyRand(), kg.params.EC().Params().N)
 
-	// 2. compute the vss shares
+	// 2. compute the vss shares, at the other points of the parties too for a weighted key, or
+	// of the ranks of the parties for a hierarchical one
 	ids := kg.params.Parties().IDs().Keys()
-	vs, shares, err := vss.Create(kg.params.EC(), kg.params.Threshold(), ui, ids, kg.params.Rand())
+	moreKs := vss.WeightedPoints(kg.params.EC().Params().N, ids, kg.params.Weights())
+	ranks := vss.HierarchicalRanks(kg.params.Ranks())
+	vs, shares, err := vss.DealShares(kg.params.EC(), kg.params.Threshold(), ui, ids, moreKs, ranks, kg.params.Rand())
 	if err != nil {
 		return err
 	}
 	kg.data.Ks = ids
+	kg.data.MoreKs = moreKs
+	kg.data.Ranks = ranks
+	kg.moreShares = vss.SplitShares(shares, moreKs)
 
 	// NOTE: In EdDSA we keep ui for the Schnorr proof in round 2 (unlike ECDSA which discards it).
 	kg.ui = ui
@@ -116,20 +135,20 @@ func (kg *Keygen) round1() error {
 		Commitment: cmt.C.Bytes(),
 	}
 	for _, p := range otherIds {
-		m := tss.JsonWrap("eddsa:keygen:round1", msg, Pi, p)
-		kg.params.Broker().Receive(m)
+		m := tss.JsonWrap(kg.params.MsgType("eddsa:keygen:round1"), msg, Pi, p)
+		kg.broker.Receive(m)
 	}
 
 	// register receiver for round 1 messages from others -> triggers round 2
-	rcv := tss.NewJsonExpect[keygenRound1msg]("eddsa:keygen:round1", otherIds, kg.round2)
-	kg.params.Broker().Connect("eddsa:keygen:round1", rcv)
+	rcv := tss.NewJsonExpect[keygenRound1msg](kg.params.MsgType("eddsa:keygen:round1"), otherIds, kg.round2, kg.timeout(1), kg.echo(1, "eddsa:keygen:round1", otherIds))
+	kg.broker.Connect(kg.params.MsgType("eddsa:keygen:round1"), rcv)
 
 	return nil
 }
 
 func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 	if kg.ctx.Err() != nil {
-		kg.Err <- kg.ctx.Err()
+		kg.fail(kg.ctx.Err())
 		return
 	}
 	Pi := kg.params.PartyID()
@@ -157,21 +176,26 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 			}
 		}
 		if shareForPj == nil {
-			kg.Err <- fmt.Errorf("could not find share for party %s", Pj)
+			kg.fail(kg.wrapError(2, fmt.Errorf("could not find share for party %s", Pj)))
 			return
 		}
 		r2msg1 := &keygenRound2msg1{
 			Share: shareForPj.Bytes(),
 		}
-		m := tss.JsonWrap("eddsa:keygen:round2-1", r2msg1, Pi, Pj)
-		kg.params.Broker().Receive(m)
+		if kg.moreShares != nil {
+			for _, share := range kg.moreShares[Pj.Index] {
+				r2msg1.MoreShares = append(r2msg1.MoreShares, share.Share.Bytes())
+			}
+		}
+		m := tss.JsonWrapPrivate(kg.params.MsgType("eddsa:keygen:round2-1"), r2msg1, Pi, Pj)
+		kg.broker.Receive(m)
 	}
 
 	// compute Schnorr proof: prove knowledge of ui such that vs[0] = ui*G
 	ContextI := append(kg.ssid, new(big.Int).SetUint64(uint64(i)).Bytes()...)
 	pii, err := schnorr.NewZKProof(ContextI, kg.ui, kg.vs[0], kg.params.Rand())
 	if err != nil {
-		kg.Err <- fmt.Errorf("NewZKProof(ui, vi0): %w", err)
+		kg.fail(kg.wrapError(2, fmt.Errorf("NewZKProof(ui, vi0): %w", err)))
 		return
 	}
 
@@ -183,8 +207,8 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 		SchnorrProofT:      pii.T.Bytes(),
 	}
 	for _, p := range otherIds {
-		m := tss.JsonWrap("eddsa:keygen:round2-2", r2msg2, Pi, p)
-		kg.params.Broker().Receive(m)
+		m := tss.JsonWrap(kg.params.MsgType("eddsa:keygen:round2-2"), r2msg2, Pi, p)
+		kg.broker.Receive(m)
 	}
 
 	// security: now we can discard ui
@@ -202,22 +226,22 @@ func (kg *Keygen) round2(otherIds []*tss.PartyID, r1msgs []*keygenRound1msg) {
 		}
 	}
 
-	rcv1 := tss.NewJsonExpect[keygenRound2msg1]("eddsa:keygen:round2-1", otherIds, func(ids []*tss.PartyID, msgs []*keygenRound2msg1) {
+	rcv1 := tss.NewJsonExpect[keygenRound2msg1](kg.params.MsgType("eddsa:keygen:round2-1"), otherIds, func(ids []*tss.PartyID, msgs []*keygenRound2msg1) {
 		r2msg1s = msgs
 		check()
-	})
-	kg.params.Broker().Connect("eddsa:keygen:round2-1", rcv1)
+	}, kg.timeout(2), tss.WithPrivate())
+	kg.broker.Connect(kg.params.MsgType("eddsa:keygen:round2-1"), rcv1)
 
-	rcv2 := tss.NewJsonExpect[keygenRound2msg2]("eddsa:keygen:round2-2", otherIds, func(ids []*tss.PartyID, msgs []*keygenRound2msg2) {
+	rcv2 := tss.NewJsonExpect[keygenRound2msg2](kg.params.MsgType("eddsa:keygen:round2-2"), otherIds, func(ids []*tss.PartyID, msgs []*keygenRound2msg2) {
 		r2msg2s = msgs
 		check()
-	})
-	kg.params.Broker().Connect("eddsa:keygen:round2-2", rcv2)
+	}, kg.timeout(2), kg.echo(2, "eddsa:keygen:round2-2", otherIds))
+	kg.broker.Connect(kg.params.MsgType("eddsa:keygen:round2-2"), rcv2)
 }
 
 func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2msg1, r2msg2s []*keygenRound2msg2) {
 	if kg.ctx.Err() != nil {
-		kg.Err <- kg.ctx.Err()
+		kg.fail(kg.ctx.Err())
 		return
 	}
 	ec := kg.params.EC()
@@ -245,7 +269,7 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 				}
 			}
 			if j == -1 {
-				chs[n] <- vssOut{errors.New("party not found"), nil}
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("party not found"), pid), nil}
 				return
 			}
 
@@ -257,14 +281,14 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 			cmtDeCmt := cmts.HashCommitDecommit{C: KGCj, D: KGDj}
 			ok, flatPolyGs := cmtDeCmt.DeCommit()
 			if !ok || flatPolyGs == nil {
-				chs[n] <- vssOut{errors.New("de-commitment verify failed"), nil}
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("de-commitment verify failed"), pid), nil}
 				return
 			}
 
 			// 2. unflatten EC points
 			PjVs, err := crypto.UnFlattenECPoints(ec, flatPolyGs)
 			if err != nil {
-				chs[n] <- vssOut{err, nil}
+				chs[n] <- vssOut{kg.wrapError(3, err, pid), nil}
 				return
 			}
 
@@ -278,7 +302,7 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 			alphaY := new(big.Int).SetBytes(r2msg2s[n].SchnorrProofAlphaY)
 			alpha, err := crypto.NewECPoint(ec, alphaX, alphaY)
 			if err != nil {
-				chs[n] <- vssOut{errors.New("failed to reconstruct Schnorr proof alpha point"), nil}
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("failed to reconstruct Schnorr proof alpha point"), pid), nil}
 				return
 			}
 			proof := &schnorr.ZKProof{
@@ -286,7 +310,7 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 				T:     new(big.Int).SetBytes(r2msg2s[n].SchnorrProofT),
 			}
 			if !proof.Verify(ContextJ, PjVs[0]) {
-				chs[n] <- vssOut{errors.New("Schnorr proof verification failed"), nil}
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("Schnorr proof verification failed"), pid), nil}
 				return
 			}
 
@@ -296,11 +320,28 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 				Threshold: kg.params.Threshold(),
 				ID:        kg.data.ShareID,
 				Share:     shareFromJ,
+				Rank:      kg.data.ranks()[PIdx],
 			}
 			if !PjShare.Verify(ec, kg.params.Threshold(), PjVs) {
-				chs[n] <- vssOut{errors.New("VSS share verification failed"), nil}
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("VSS share verification failed"), pid), nil}
 				return
 			}
+			moreKs := kg.ownMoreKs()
+			if len(r2msg1s[n].MoreShares) != len(moreKs) {
+				chs[n] <- vssOut{kg.wrapError(3, errors.New("message holds the shares of another number of points"), pid), nil}
+				return
+			}
+			for m, shareBz := range r2msg1s[n].MoreShares {
+				share := vss.Share{
+					Threshold: kg.params.Threshold(),
+					ID:        moreKs[m],
+					Share:     new(big.Int).SetBytes(shareBz),
+				}
+				if !share.Verify(ec, kg.params.Threshold(), PjVs) {
+					chs[n] <- vssOut{kg.wrapError(3, errors.New("VSS share verification failed"), pid), nil}
+					return
+				}
+			}
 
 			chs[n] <- vssOut{nil, PjVs}
 		}(n, pid)
@@ -308,12 +349,14 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 
 	// collect results
 	vssResults := make([]vssOut, len(otherIds))
+	var errs []error
 	for n := range otherIds {
 		vssResults[n] = <-chs[n]
-		if vssResults[n].err != nil {
-			kg.Err <- vssResults[n].err
-			return
-		}
+		errs = append(errs, vssResults[n].err)
+	}
+	if err := tss.JoinErrors(errs...); err != nil {
+		kg.fail(err)
+		return
 	}
 
 	// compute xi = ownShare + sum(receivedShares) mod N
@@ -324,6 +367,16 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 	}
 	kg.data.Xi = new(big.Int).Mod(xi, ec.Params().N)
 
+	// and the shares at the other points of this party of a weighted key
+	modQ := common.ModInt(ec.Params().N)
+	for m := range kg.ownMoreKs() {
+		xi := kg.moreShares[PIdx][m].Share
+		for n := range otherIds {
+			xi = modQ.Add(xi, new(big.Int).SetBytes(r2msg1s[n].MoreShares[m]))
+		}
+		kg.data.MoreXi = append(kg.data.MoreXi, xi)
+	}
+
 	// aggregate Vc: Vc[c] = vs[c] + sum(PjVs[c])
 	Vc := make(vss.Vs, kg.params.Threshold()+1)
 	for c := range Vc {
@@ -335,16 +388,24 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 			var err error
 			Vc[c], err = Vc[c].Add(PjVs[c])
 			if err != nil {
-				kg.Err <- fmt.Errorf("adding PjVs[c] to Vc[c] failed: %w", err)
+				kg.fail(kg.wrapError(3, fmt.Errorf("adding PjVs[c] to Vc[c] failed: %w", err)))
 				return
 			}
 		}
 	}
 
 	// compute BigXj for each party: evaluate polynomial at each party's key
-	modQ := common.ModInt(ec.Params().N)
 	for j := 0; j < kg.params.PartyCount(); j++ {
 		kj := kg.params.Parties().IDs()[j].KeyInt()
+		if kg.data.Ranks != nil {
+			BigXj, err := Vc.PublicShare(kj, kg.data.Ranks[j])
+			if err != nil {
+				kg.fail(kg.wrapError(3, fmt.Errorf("computing BigXj failed: %w", err)))
+				return
+			}
+			kg.data.BigXj[j] = BigXj
+			continue
+		}
 		BigXj := Vc[0]
 		z := new(big.Int).SetInt64(1)
 		for c := 1; c <= kg.params.Threshold(); c++ {
@@ -352,20 +413,81 @@ func (kg *Keygen) processRound3(otherIds []*tss.PartyID, r2msg1s []*keygenRound2
 			var err error
 			BigXj, err = BigXj.Add(Vc[c].ScalarMult(z))
 			if err != nil {
-				kg.Err <- fmt.Errorf("computing BigXj failed: %w", err)
+				kg.fail(kg.wrapError(3, fmt.Errorf("computing BigXj failed: %w", err)))
 				return
 			}
 		}
 		kg.data.BigXj[j] = BigXj
 	}
+	if kg.data.MoreKs != nil {
+		kg.data.MoreBigXj = make([][]*crypto.ECPoint, len(kg.data.Ks))
+		for j, kjs := range kg.data.MoreKs {
+			for _, kj := range kjs {
+				BigXj, err := Vc.PublicShare(kj, 0)
+				if err != nil {
+					kg.fail(kg.wrapError(3, fmt.Errorf("computing BigXj failed: %w", err)))
+					return
+				}
+				kg.data.MoreBigXj[j] = append(kg.data.MoreBigXj[j], BigXj)
+			}
+		}
+	}
 
 	// EDDSAPub = Vc[0]
 	eddsaPubKey, err := crypto.NewECPoint(ec, Vc[0].X(), Vc[0].Y())
 	if err != nil {
-		kg.Err <- fmt.Errorf("public key is not on the curve: %w", err)
+		kg.fail(kg.wrapError(3, fmt.Errorf("public key is not on the curve: %w", err)))
 		return
 	}
 	kg.data.EDDSAPub = eddsaPubKey
 
+	kg.release()
 	kg.Done <- kg.data
 }
+
+// ownMoreKs returns the other evaluation points of this party of a weighted key.
+func (kg *Keygen) ownMoreKs() []*big.Int {
+	if kg.data.MoreKs == nil {
+		return nil
+	}
+	return kg.data.MoreKs[kg.params.PartyID().Index]
+}
+
+// fail reports err on Err and releases the receivers registered by this keygen.
+func (kg *Keygen) fail(err error) {
+	kg.release()
+	select {
+	case kg.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this keygen from the broker.
+func (kg *Keygen) release() {
+	kg.stop()
+	kg.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (kg *Keygen) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(kg.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (kg *Keygen) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !kg.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(kg.broker, kg.params.MsgType(typ+":echo"), kg.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		kg.fail(kg.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (kg *Keygen) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskKeygen, round, kg.params.PartyID(), culprits...)
+}
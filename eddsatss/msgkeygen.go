@@ -8,6 +8,9 @@ type keygenRound1msg struct {
 
 type keygenRound2msg1 struct {
 	Share []byte `json:"share"`
+
+	// shares at the other points of the receiver of a weighted key
+	MoreShares [][]byte `json:"more_shares,omitempty"`
 }
 
 type keygenRound2msg2 struct {
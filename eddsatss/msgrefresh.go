@@ -0,0 +1,18 @@
+package eddsatss
+
+// messages for EdDSA refresh
+
+// refreshRound1msg is broadcast, with the commitment to the refresh polynomial of the sender.
+type refreshRound1msg struct {
+	Commitment []byte `json:"commitment"`
+}
+
+// refreshRound2msg1 is a P2P message containing the refresh share of the recipient.
+type refreshRound2msg1 struct {
+	Share []byte `json:"share"`
+}
+
+// refreshRound2msg2 is broadcast, with the de-commitment of round 1.
+type refreshRound2msg2 struct {
+	DeCommitment [][]byte `json:"de_commitment"`
+}
@@ -0,0 +1,19 @@
+package eddsatss
+
+// messages for EdDSA share repair and enrollment
+
+// repairRound1msg is a P2P message between helpers, containing a random part of the Lagrange
+// term of the sender for the lost share, or the share of the newcomer.
+type repairRound1msg struct {
+	Part []byte `json:"part"`
+}
+
+// repairRound2msg is a P2P message from each helper to the lost party or the newcomer,
+// containing the sum of the parts received by the helper, and the public data of the key.
+type repairRound2msg struct {
+	Sum       []byte   `json:"sum"`
+	Ks        [][]byte `json:"ks"`
+	BigXj     [][]byte `json:"big_xj"`
+	EDDSAPubX []byte   `json:"eddsa_pub_x"`
+	EDDSAPubY []byte   `json:"eddsa_pub_y"`
+}
@@ -17,6 +17,9 @@ type resharingRound2msg struct{}
 // containing the VSS share for that party.
 type resharingRound3msg1 struct {
 	Share []byte `json:"share"`
+
+	// shares at the other points of the receiver of a weighted key
+	MoreShares [][]byte `json:"more_shares,omitempty"`
 }
 
 // resharingRound3msg2 is broadcast from old committee to new committee,
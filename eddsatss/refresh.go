@@ -0,0 +1,279 @@
+package eddsatss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskRefresh is the task name reported in errors from Refresh.
+const TaskRefresh = "eddsa-refresh"
+
+// Refresh tracks the proactive refresh of the shares of a key. Every party shares a random
+// polynomial of constant term 0, committed to with Feldman VSS: adding these shares to the
+// current ones gives new shares of the same key, so that shares stolen before the refresh
+// cannot be combined with the new ones.
+//
+// All the parties of the key must take part, with the same threshold as at keygen. The
+// public key, the parties and their ShareID are unchanged.
+type Refresh struct {
+	ctx      context.Context
+	params   *tss.Parameters
+	broker   *tss.SessionBroker
+	stop     func() bool
+	key      *Key              // current key, in the order of the parties of params
+	shares   []*big.Int        // refresh shares of each party
+	polyG    []*crypto.ECPoint // commitments to the coefficients of degree 1 to t
+	deCommit cmts.HashDeCommitment
+	kgcs     []cmts.HashCommitment
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewRefresh creates a new Refresh of the shares of key and executes round 1. The refreshed
+// key is sent on Done, and the current one must then be deleted. The threshold of params must
+// be the one of the key.
+func (key *Key) NewRefresh(ctx context.Context, params *tss.Parameters) (*Refresh, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	if len(key.Ks) != params.PartyCount() {
+		return nil, fmt.Errorf("all %d parties of the key must take part, got %d", len(key.Ks), params.PartyCount())
+	}
+	threshold, err := vss.PublicThreshold(key.Ks, key.BigXj, key.EDDSAPub)
+	if err != nil {
+		return nil, err
+	}
+	if threshold != params.Threshold() {
+		return nil, fmt.Errorf("the threshold of the key is %d, not %d", threshold, params.Threshold())
+	}
+	subsetKey, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	r := &Refresh{
+		ctx:    ctx,
+		params: params,
+		key:    subsetKey,
+		kgcs:   make([]cmts.HashCommitment, params.PartyCount()),
+		Done:   make(chan *Key, 1),
+		Err:    make(chan error, 1),
+	}
+	r.broker = tss.NewSessionBroker(params.Broker())
+	r.stop = context.AfterFunc(ctx, func() { r.fail(ctx.Err()) })
+	if err := r.round1(); err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+// round1 makes the refresh polynomial and broadcasts the commitment to it.
+func (r *Refresh) round1() error {
+	Pi := r.params.PartyID()
+	ec := r.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// random polynomial f of degree t with f(0) = 0, and the shares f(kj)
+	coefs := make([]*big.Int, r.params.Threshold())
+	r.polyG = make([]*crypto.ECPoint, len(coefs))
+	for c := range coefs {
+		coefs[c] = common.GetRandomPositiveInt(r.params.Rand(), q)
+		r.polyG[c] = crypto.ScalarBaseMult(ec, coefs[c])
+	}
+	r.shares = make([]*big.Int, len(r.key.Ks))
+	for j, kj := range r.key.Ks {
+		share, z := big.NewInt(0), big.NewInt(1)
+		for _, a := range coefs {
+			z = modQ.Mul(z, kj)
+			share = modQ.Add(share, modQ.Mul(a, z))
+		}
+		r.shares[j] = share
+	}
+
+	pGFlat, err := crypto.FlattenECPoints(r.polyG)
+	if err != nil {
+		return err
+	}
+	cmt := cmts.NewHashCommitment(r.params.Rand(), pGFlat...)
+	r.deCommit = cmt.D
+
+	otherIds := r.params.Parties().IDs().Exclude(Pi)
+	msg := &refreshRound1msg{Commitment: cmt.C.Bytes()}
+	for _, Pj := range otherIds {
+		r.broker.Receive(tss.JsonWrap(r.params.MsgType("eddsa:refresh:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[refreshRound1msg](r.params.MsgType("eddsa:refresh:round1"), otherIds, r.round2, r.timeout(1), r.echo(1, "eddsa:refresh:round1", otherIds))
+	r.broker.Connect(r.params.MsgType("eddsa:refresh:round1"), rcv)
+	return nil
+}
+
+// round2 sends the refresh shares and the de-commitment.
+func (r *Refresh) round2(otherIds []*tss.PartyID, r1msgs []*refreshRound1msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	Pi := r.params.PartyID()
+
+	for n, Pj := range otherIds {
+		r.kgcs[Pj.Index] = new(big.Int).SetBytes(r1msgs[n].Commitment)
+		r2msg1 := &refreshRound2msg1{Share: r.shares[Pj.Index].Bytes()}
+		r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("eddsa:refresh:round2-1"), r2msg1, Pi, Pj))
+	}
+	r2msg2 := &refreshRound2msg2{DeCommitment: common.BigIntsToBytes(r.deCommit)}
+	for _, Pj := range otherIds {
+		r.broker.Receive(tss.JsonWrap(r.params.MsgType("eddsa:refresh:round2-2"), r2msg2, Pi, Pj))
+	}
+
+	var pending int32 = 2
+	var r2msgs1 []*refreshRound2msg1
+	var r2msgs2 []*refreshRound2msg2
+	check := func() {
+		if atomic.AddInt32(&pending, -1) == 0 {
+			r.round3(otherIds, r2msgs1, r2msgs2)
+		}
+	}
+	rcv1 := tss.NewJsonExpect[refreshRound2msg1](r.params.MsgType("eddsa:refresh:round2-1"), otherIds, func(_ []*tss.PartyID, msgs []*refreshRound2msg1) {
+		r2msgs1 = msgs
+		check()
+	}, r.timeout(2), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("eddsa:refresh:round2-1"), rcv1)
+	rcv2 := tss.NewJsonExpect[refreshRound2msg2](r.params.MsgType("eddsa:refresh:round2-2"), otherIds, func(_ []*tss.PartyID, msgs []*refreshRound2msg2) {
+		r2msgs2 = msgs
+		check()
+	}, r.timeout(2), r.echo(2, "eddsa:refresh:round2-2", otherIds))
+	r.broker.Connect(r.params.MsgType("eddsa:refresh:round2-2"), rcv2)
+}
+
+// round3 verifies the refresh shares of the other parties, and computes the new shares.
+func (r *Refresh) round3(otherIds []*tss.PartyID, r2msgs1 []*refreshRound2msg1, r2msgs2 []*refreshRound2msg2) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	i := r.params.PartyID().Index
+	ec := r.params.EC()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+	threshold := r.params.Threshold()
+
+	polyGs := make([][]*crypto.ECPoint, r.params.PartyCount())
+	polyGs[i] = r.polyG
+	xi := modQ.Add(r.key.Xi, r.shares[i])
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		cmtDeCmt := cmts.HashCommitDecommit{C: r.kgcs[Pj.Index], D: cmts.NewHashDeCommitmentFromBytes(r2msgs2[n].DeCommitment)}
+		ok, flatPolyG := cmtDeCmt.DeCommit()
+		if !ok || len(flatPolyG) != 2*threshold {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		polyG, err := crypto.UnFlattenECPoints(ec, flatPolyG)
+		if err != nil {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		for c := range polyG {
+			polyG[c] = polyG[c].EightInvEight()
+		}
+		share := new(big.Int).SetBytes(r2msgs1[n].Share)
+		expected, err := vss.ZeroSecretPublicShare(polyG, r.key.Ks[i])
+		if err != nil || share.Cmp(q) >= 0 || !crypto.ScalarBaseMult(ec, share).Equals(expected) {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		polyGs[Pj.Index] = polyG
+		xi = modQ.Add(xi, share)
+	}
+	if len(culprits) > 0 {
+		r.fail(r.wrapError(3, errors.New("refresh share verification failed"), culprits...))
+		return
+	}
+
+	// new Xj = Xj + sum of the refresh polynomials evaluated at kj
+	data := &Key{
+		Xi:       xi,
+		ShareID:  r.key.ShareID,
+		Ks:       r.key.Ks,
+		BigXj:    make([]*crypto.ECPoint, len(r.key.Ks)),
+		EDDSAPub: r.key.EDDSAPub,
+	}
+	for j, kj := range r.key.Ks {
+		BigXj := r.key.BigXj[j]
+		for _, polyG := range polyGs {
+			Fj, err := vss.ZeroSecretPublicShare(polyG, kj)
+			if err == nil {
+				BigXj, err = BigXj.Add(Fj)
+			}
+			if err != nil {
+				r.fail(r.wrapError(3, fmt.Errorf("computing BigXj failed: %w", err)))
+				return
+			}
+		}
+		data.BigXj[j] = BigXj
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(data.BigXj[i]) {
+		r.fail(r.wrapError(3, errors.New("refreshed share does not match its public point")))
+		return
+	}
+
+	r.release()
+	r.Done <- data
+}
+
+// fail reports err on Err and releases the receivers registered by this refresh.
+func (r *Refresh) fail(err error) {
+	r.release()
+	select {
+	case r.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this refresh from the broker.
+func (r *Refresh) release() {
+	r.stop()
+	r.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (r *Refresh) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(r.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (r *Refresh) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !r.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(r.broker, r.params.MsgType(typ+":echo"), r.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (r *Refresh) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskRefresh, round, r.params.PartyID(), culprits...)
+}
@@ -0,0 +1,135 @@
+package eddsatss
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KarpelesLab/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+func TestRefreshAndSign(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case keys[i] = <-keygens[i].Done:
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	refreshHub := newTestHub(partyCount)
+	refreshes := make([]*Refresh, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(refreshHub.brokers[i])
+
+		r, err := keys[i].NewRefresh(context.Background(), params)
+		require.NoError(t, err)
+		refreshes[i] = r
+	}
+	newKeys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case newKeys[i] = <-refreshes[i].Done:
+		case err := <-refreshes[i].Err:
+			t.Fatalf("Refresh error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Refresh timed out for party %d", i)
+		}
+	}
+
+	shares := make(vss.Shares, partyCount)
+	for i, key := range newKeys {
+		assert.True(t, keys[i].EDDSAPub.Equals(key.EDDSAPub))
+		assert.Equal(t, 0, keys[i].ShareID.Cmp(key.ShareID))
+		assert.NotEqual(t, 0, keys[i].Xi.Cmp(key.Xi), "share of party %d should change", i)
+		assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), key.Xi).Equals(key.BigXj[i]))
+		for j := range newKeys {
+			assert.True(t, newKeys[0].BigXj[j].Equals(key.BigXj[j]))
+		}
+		shares[i] = &vss.Share{Threshold: threshold, ID: key.ShareID, Share: key.Xi}
+	}
+	secret, err := shares.ReConstruct(tss.Edwards())
+	require.NoError(t, err)
+	assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), secret).Equals(keys[0].EDDSAPub))
+
+	// an old share mixed with the new ones does not give the key
+	shares[0] = &vss.Share{Threshold: threshold, ID: keys[0].ShareID, Share: keys[0].Xi}
+	secret, err = shares[:threshold+1].ReConstruct(tss.Edwards())
+	require.NoError(t, err)
+	assert.False(t, crypto.ScalarBaseMult(tss.Edwards(), secret).Equals(keys[0].EDDSAPub))
+
+	// a committee of t+1 parties signs with the refreshed shares
+	msg := big.NewInt(42)
+	var unsorted tss.UnSortedPartyIDs
+	for _, p := range pIDs[1:] {
+		unsorted = append(unsorted, tss.NewPartyID(p.Id, p.Moniker, p.KeyInt()))
+	}
+	signIDs := tss.SortPartyIDs(unsorted)
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.Edwards(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		sg, err := newKeys[n+1].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := edwards25519.PublicKey{
+		Curve: tss.Edwards(),
+		X:     keys[0].EDDSAPub.X(),
+		Y:     keys[0].EDDSAPub.Y(),
+	}
+	parsed, err := edwards25519.ParseSignature(sig.Signature)
+	require.NoError(t, err)
+	assert.True(t, edwards25519.VerifyRS(&pk, msg.Bytes(), parsed.R, parsed.S))
+}
+
+func TestRefreshThreshold(t *testing.T) {
+	keys, pIDs := runKeygen(t, 3, 1)
+
+	// a threshold which is not the one of the key is rejected
+	params := tss.NewParameters(tss.Edwards(), tss.NewPeerContext(pIDs), pIDs[0], len(pIDs), 2)
+	params.SetBroker(newTestHub(len(pIDs)).brokers[0])
+	_, err := keys[0].NewRefresh(context.Background(), params)
+	assert.ErrorContains(t, err, "the threshold of the key is 1, not 2")
+}
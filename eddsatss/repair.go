@@ -0,0 +1,283 @@
+package eddsatss
+
+import (
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskRepair is the task name reported in errors from Repair.
+const TaskRepair = "eddsa-repair"
+
+// Repair tracks the repair of the share of a party of a key that lost it. At least t+1 other
+// parties of the key, the helpers, compute the lost share at its ShareID without anyone
+// learning it: each helper splits its Lagrange term of the lost share in random parts sent
+// to the other helpers, and only the sums of the parts received are sent to the lost party.
+//
+// The shares of the helpers are unchanged. The lost party gets the public data of the key
+// from the helpers, and checks its share against its public point.
+type Repair struct {
+	ctx     context.Context
+	params  *tss.Parameters
+	broker  *tss.SessionBroker
+	stop    func() bool
+	key     *Key // key of the helper, nil for the lost party
+	lost    *tss.PartyID
+	helpers tss.SortedPartyIDs
+	part    *big.Int // part of the Lagrange term of this helper kept by it
+
+	Done chan *Key
+	Err  chan error
+}
+
+// NewRepair creates a new Repair of the share of the party lost, and executes round 1. The
+// parties of params are the helpers and the lost party. The helpers give their key, and
+// receive it unchanged on Done; the lost party gives a nil key, and receives its repaired key
+// on Done.
+func NewRepair(ctx context.Context, params *tss.Parameters, key *Key, lost *tss.PartyID) (*Repair, error) {
+	if params.Threshold() < 1 {
+		return nil, fmt.Errorf("invalid threshold %d", params.Threshold())
+	}
+	if key != nil && key.MoreKs != nil {
+		return nil, ErrWeightedKey
+	}
+	if key != nil && key.Ranks != nil {
+		return nil, ErrHierarchicalKey
+	}
+	lost = params.Parties().IDs().FindByKey(lost.KeyInt())
+	if lost == nil {
+		return nil, errors.New("the lost party must take part in the repair")
+	}
+	helpers := params.Parties().IDs().Exclude(lost)
+	if len(helpers) <= params.Threshold() {
+		return nil, fmt.Errorf("repair needs at least %d helpers, got %d", params.Threshold()+1, len(helpers))
+	}
+	isLost := params.PartyID().KeyInt().Cmp(lost.KeyInt()) == 0
+	if isLost != (key == nil) {
+		return nil, errors.New("the lost party, and only it, must not give a key")
+	}
+	r := &Repair{
+		ctx:     ctx,
+		params:  params,
+		key:     key,
+		lost:    lost,
+		helpers: helpers,
+		Done:    make(chan *Key, 1),
+		Err:     make(chan error, 1),
+	}
+	r.broker = tss.NewSessionBroker(params.Broker())
+	r.stop = context.AfterFunc(ctx, func() { r.fail(ctx.Err()) })
+	if isLost {
+		r.round1Lost()
+		return r, nil
+	}
+	if err := r.round1(); err != nil {
+		r.release()
+		return nil, err
+	}
+	return r, nil
+}
+
+// round1 splits the Lagrange term of this helper in random parts, and sends them to the
+// other helpers.
+func (r *Repair) round1() error {
+	Pi := r.params.PartyID()
+	q := r.params.EC().Params().N
+
+	// the lost party must be a party of the key
+	if _, err := r.key.SubsetForParties(r.params.Parties().IDs()); err != nil {
+		return err
+	}
+	helpersKey, err := r.key.SubsetForParties(r.helpers)
+	if err != nil {
+		return err
+	}
+	n := -1
+	for m, Pj := range r.helpers {
+		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
+			n = m
+		}
+	}
+	parts, err := vss.LagrangeParts(r.params.Rand(), q, helpersKey.Ks, n, r.lost.KeyInt(), r.key.Xi)
+	if err != nil {
+		return err
+	}
+	r.part = parts[n]
+
+	otherIds := r.helpers.Exclude(Pi)
+	for m, Pj := range r.helpers {
+		if m == n {
+			continue
+		}
+		msg := &repairRound1msg{Part: parts[m].Bytes()}
+		r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("eddsa:repair:round1"), msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[repairRound1msg](r.params.MsgType("eddsa:repair:round1"), otherIds, r.round2, r.timeout(1), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("eddsa:repair:round1"), rcv)
+	return nil
+}
+
+// round1Lost waits for the sums of the helpers.
+func (r *Repair) round1Lost() {
+	rcv := tss.NewJsonExpect[repairRound2msg](r.params.MsgType("eddsa:repair:round2"), r.helpers, r.round3, r.timeout(2), tss.WithPrivate())
+	r.broker.Connect(r.params.MsgType("eddsa:repair:round2"), rcv)
+}
+
+// round2 sends the sum of the parts received by this helper to the lost party, with the
+// public data of the key.
+func (r *Repair) round2(otherIds []*tss.PartyID, r1msgs []*repairRound1msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	modQ := common.ModInt(r.params.EC().Params().N)
+
+	sum := r.part
+	for _, r1msg := range r1msgs {
+		sum = modQ.Add(sum, new(big.Int).SetBytes(r1msg.Part))
+	}
+	msg, err := newRepairRound2msg(r.key, sum)
+	if err != nil {
+		r.fail(r.wrapError(2, err))
+		return
+	}
+	r.broker.Receive(tss.JsonWrapPrivate(r.params.MsgType("eddsa:repair:round2"), msg, r.params.PartyID(), r.lost))
+
+	r.release()
+	r.Done <- r.key
+}
+
+// round3 checks that the helpers sent the same public data, and computes the lost share.
+func (r *Repair) round3(helpers []*tss.PartyID, r2msgs []*repairRound2msg) {
+	if r.ctx.Err() != nil {
+		r.fail(r.ctx.Err())
+		return
+	}
+	ec := r.params.EC()
+	modQ := common.ModInt(ec.Params().N)
+
+	candidates := make([]*Key, len(r2msgs))
+	xi := big.NewInt(0)
+	for n, r2msg := range r2msgs {
+		candidate, err := repairPublicData(ec, r2msg)
+		if err != nil {
+			r.fail(r.wrapError(3, err, helpers[n]))
+			return
+		}
+		candidates[n] = candidate
+		xi = modQ.Add(xi, new(big.Int).SetBytes(r2msg.Sum))
+	}
+	for _, candidate := range candidates[1:] {
+		if !candidates[0].samePublicData(candidate) {
+			// the helpers outvoted by the others are the culprits, if there is a majority
+			r.fail(r.wrapError(3, errors.New("different public data of the key"), tss.MinorityCulprits(helpers, candidates, (*Key).samePublicData)...))
+			return
+		}
+	}
+	data := candidates[0]
+
+	data.Xi = xi
+	data.ShareID = r.lost.KeyInt()
+	i := -1
+	for j, kj := range data.Ks {
+		if kj.Cmp(data.ShareID) == 0 {
+			i = j
+		}
+	}
+	if i < 0 {
+		r.fail(r.wrapError(3, errors.New("the lost party is not a party of the key")))
+		return
+	}
+	if !crypto.ScalarBaseMult(ec, xi).Equals(data.BigXj[i]) {
+		r.fail(r.wrapError(3, errors.New("repaired share does not match its public point")))
+		return
+	}
+
+	r.release()
+	r.Done <- data
+}
+
+// newRepairRound2msg returns the message sending sum and the public data of key.
+func newRepairRound2msg(key *Key, sum *big.Int) (*repairRound2msg, error) {
+	flatBigXj, err := crypto.FlattenECPoints(key.BigXj)
+	if err != nil {
+		return nil, err
+	}
+	return &repairRound2msg{
+		Sum:       sum.Bytes(),
+		Ks:        common.BigIntsToBytes(key.Ks),
+		BigXj:     common.BigIntsToBytes(flatBigXj),
+		EDDSAPubX: key.EDDSAPub.X().Bytes(),
+		EDDSAPubY: key.EDDSAPub.Y().Bytes(),
+	}, nil
+}
+
+// repairPublicData returns the key of the public data sent by a helper to the lost party.
+func repairPublicData(ec elliptic.Curve, msg *repairRound2msg) (*Key, error) {
+	BigXj, err := crypto.UnFlattenECPoints(ec, common.MultiBytesToBigInts(msg.BigXj))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BigXj: %w", err)
+	}
+	if len(msg.Ks) != len(BigXj) {
+		return nil, fmt.Errorf("got %d Ks for %d BigXj", len(msg.Ks), len(BigXj))
+	}
+	pub, err := crypto.NewECPoint(ec, new(big.Int).SetBytes(msg.EDDSAPubX), new(big.Int).SetBytes(msg.EDDSAPubY))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EDDSAPub: %w", err)
+	}
+	return &Key{
+		Ks:       common.MultiBytesToBigInts(msg.Ks),
+		BigXj:    BigXj,
+		EDDSAPub: pub,
+	}, nil
+}
+
+// samePublicData returns whether key and other have the same parties, public shares and
+// public key.
+func (key *Key) samePublicData(other *Key) bool {
+	if len(key.Ks) != len(other.Ks) || !key.EDDSAPub.Equals(other.EDDSAPub) {
+		return false
+	}
+	for j := range key.Ks {
+		if key.Ks[j].Cmp(other.Ks[j]) != 0 || !key.BigXj[j].Equals(other.BigXj[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// fail reports err on Err and releases the receivers registered by this repair.
+func (r *Repair) fail(err error) {
+	r.release()
+	select {
+	case r.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this repair from the broker.
+func (r *Repair) release() {
+	r.stop()
+	r.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (r *Repair) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(r.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		r.fail(r.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (r *Repair) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskRepair, round, r.params.PartyID(), culprits...)
+}
@@ -0,0 +1,128 @@
+package eddsatss
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KarpelesLab/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// subsetIDs returns sorted clones of the ids of the given parties, keeping the indexes of pIDs.
+func subsetIDs(pIDs tss.SortedPartyIDs, idx ...int) tss.SortedPartyIDs {
+	var unsorted tss.UnSortedPartyIDs
+	for _, k := range idx {
+		unsorted = append(unsorted, tss.NewPartyID(pIDs[k].Id, pIDs[k].Moniker, pIDs[k].KeyInt()))
+	}
+	return tss.SortPartyIDs(unsorted)
+}
+
+func TestRepairAndSign(t *testing.T) {
+	const (
+		partyCount = 4
+		threshold  = 1
+	)
+
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	keygens := make([]*Keygen, partyCount)
+	for i := 0; i < partyCount; i++ {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	keys := make([]*Key, partyCount)
+	for i := 0; i < partyCount; i++ {
+		select {
+		case keys[i] = <-keygens[i].Done:
+		case err := <-keygens[i].Err:
+			t.Fatalf("Keygen error for party %d: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Keygen timed out for party %d", i)
+		}
+	}
+
+	// party 0 lost its key, parties 2 and 3 help it
+	repairIDs := subsetIDs(pIDs, 0, 2, 3)
+	repairCtx := tss.NewPeerContext(repairIDs)
+	repairHub := newTestHub(len(repairIDs))
+	repairs := make([]*Repair, len(repairIDs))
+	for n, p := range repairIDs {
+		params := tss.NewParameters(tss.Edwards(), repairCtx, p, len(repairIDs), threshold)
+		params.SetBroker(repairHub.brokers[n])
+
+		var key *Key
+		if n > 0 {
+			key = keys[n+1]
+		}
+		r, err := NewRepair(context.Background(), params, key, repairIDs[0])
+		require.NoError(t, err)
+		repairs[n] = r
+	}
+	repaired := make([]*Key, len(repairIDs))
+	for n, r := range repairs {
+		select {
+		case repaired[n] = <-r.Done:
+		case err := <-r.Err:
+			t.Fatalf("Party %d repair error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d repair timed out", n)
+		}
+	}
+	assert.Same(t, keys[2], repaired[1])
+	assert.Same(t, keys[3], repaired[2])
+	key := repaired[0]
+	assert.Equal(t, 0, keys[0].Xi.Cmp(key.Xi))
+	assert.Equal(t, 0, keys[0].ShareID.Cmp(key.ShareID))
+	assert.True(t, keys[0].EDDSAPub.Equals(key.EDDSAPub))
+	for j := range keys {
+		assert.Equal(t, 0, keys[0].Ks[j].Cmp(key.Ks[j]))
+		assert.True(t, keys[0].BigXj[j].Equals(key.BigXj[j]))
+	}
+
+	// the repaired party signs with a party that did not help
+	msg := big.NewInt(42)
+	signIDs := subsetIDs(pIDs, 0, 1)
+	signCtx := tss.NewPeerContext(signIDs)
+	signHub := newTestHub(len(signIDs))
+	signings := make([]*Signing, len(signIDs))
+	for n, p := range signIDs {
+		params := tss.NewParameters(tss.Edwards(), signCtx, p, len(signIDs), threshold)
+		params.SetBroker(signHub.brokers[n])
+
+		signKey := keys[1]
+		if n == 0 {
+			signKey = key
+		}
+		sg, err := signKey.NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := edwards25519.PublicKey{
+		Curve: tss.Edwards(),
+		X:     keys[0].EDDSAPub.X(),
+		Y:     keys[0].EDDSAPub.Y(),
+	}
+	parsed, err := edwards25519.ParseSignature(sig.Signature)
+	require.NoError(t, err)
+	assert.True(t, edwards25519.VerifyRS(&pk, msg.Bytes(), parsed.R, parsed.S))
+}
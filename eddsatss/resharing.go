@@ -2,6 +2,7 @@ package eddsatss
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"sync/atomic"
@@ -13,16 +14,22 @@ import (
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
 
+// TaskResharing is the task name reported in errors from Resharing.
+const TaskResharing = "eddsa-resharing"
+
 // Resharing tracks a key resharing operation between old and new committees.
 type Resharing struct {
 	ctx    context.Context
 	params *tss.ReSharingParameters
+	broker *tss.SessionBroker
+	stop   func() bool
 	input  *Key // old committee's key data (nil for pure new members)
 
 	// Round 1 temp (old committee)
-	newVs     vss.Vs
-	newShares vss.Shares
-	vD        cmts.HashDeCommitment
+	newVs         vss.Vs
+	newShares     vss.Shares
+	newMoreShares []vss.Shares // shares at the other points of each new party of a weighted key
+	vD            cmts.HashDeCommitment
 
 	// Round 4 temp (new committee)
 	eddsaPub     *crypto.ECPoint // received from old committee
@@ -43,9 +50,12 @@ func NewResharing(ctx context.Context, params *tss.ReSharingParameters, input *K
 		Done:   make(chan *Key, 1),
 		Err:    make(chan error, 1),
 	}
+	rs.broker = tss.NewSessionBroker(params.Broker())
+	rs.stop = context.AfterFunc(ctx, func() { rs.fail(ctx.Err()) })
 
 	if params.IsOldCommittee() {
 		if err := rs.round1Old(); err != nil {
+			rs.release()
 			return nil, err
 		}
 	}
@@ -72,17 +82,21 @@ func (rs *Resharing) round1Old() error {
 	}
 	rs.input = subset
 
-	// 1. PrepareForSigning() -> wi
-	xi := rs.input.Xi
-	ks := rs.input.Ks
-	if rs.params.Threshold()+1 > len(ks) {
-		return fmt.Errorf("t+1=%d is not satisfied by the key count of %d", rs.params.Threshold()+1, len(ks))
+	// 1. wi, from the Lagrange coefficients of the shares of this party
+	coefs, err := rs.input.shareCoefs(rs.params.Parameters)
+	if err != nil {
+		return err
+	}
+	wi, err := rs.input.additiveShare(ec.Params().N, coefs[i])
+	if err != nil {
+		return err
 	}
-	wi := PrepareForSigning(ec, i, len(rs.params.OldParties().IDs()), xi, ks)
 
-	// 2. VSS-share wi for new committee using new threshold and new party keys
+	// 2. VSS-share wi for new committee using new threshold and new party keys, and the other
+	// points of the new parties of a weighted key, or the ranks of those of a hierarchical one
 	newKs := rs.params.NewParties().IDs().Keys()
-	vi, shares, err := vss.Create(ec, rs.params.NewThreshold(), wi, newKs, rs.params.Rand())
+	newMoreKs := vss.WeightedPoints(ec.Params().N, newKs, rs.params.NewWeights())
+	vi, shares, err := vss.DealShares(ec, rs.params.NewThreshold(), wi, newKs, newMoreKs, vss.HierarchicalRanks(rs.params.NewRanks()), rs.params.Rand())
 	if err != nil {
 		return fmt.Errorf("vss.Create: %w", err)
 	}
@@ -97,6 +111,7 @@ func (rs *Resharing) round1Old() error {
 	// 4. Save temp data
 	rs.newVs = vi
 	rs.newShares = shares
+	rs.newMoreShares = vss.SplitShares(shares, newMoreKs)
 	rs.vD = vCmt.D
 
 	// 5. Broadcast commitment + EDDSAPub to all new parties (excluding self if in both)
@@ -111,14 +126,14 @@ func (rs *Resharing) round1Old() error {
 		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
 			continue // skip self
 		}
-		m := tss.JsonWrap("eddsa:reshare:round1", r1msg, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("eddsa:reshare:round1"), r1msg, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// If this party is also in the new committee, deliver round1 msg to self
 	if rs.params.IsNewCommittee() {
-		selfMsg := tss.JsonWrap("eddsa:reshare:round1", r1msg, Pi, Pi)
-		rs.params.Broker().Receive(selfMsg)
+		selfMsg := tss.JsonWrap(rs.params.MsgType("eddsa:reshare:round1"), r1msg, Pi, Pi)
+		rs.broker.Receive(selfMsg)
 	}
 
 	// Register receiver for round 2 ACKs from new committee
@@ -136,10 +151,10 @@ func (rs *Resharing) round1Old() error {
 		// (This happens if old and new committees are identical single-party)
 		go rs.round3Old()
 	} else {
-		rcv := tss.NewJsonExpect[resharingRound2msg]("eddsa:reshare:round2", newOtherIds, func(ids []*tss.PartyID, msgs []*resharingRound2msg) {
+		rcv := tss.NewJsonExpect[resharingRound2msg](rs.params.MsgType("eddsa:reshare:round2"), newOtherIds, func(ids []*tss.PartyID, msgs []*resharingRound2msg) {
 			rs.round3Old()
-		})
-		rs.params.Broker().Connect("eddsa:reshare:round2", rcv)
+		}, rs.timeout(2))
+		rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round2"), rcv)
 	}
 
 	return nil
@@ -153,16 +168,16 @@ func (rs *Resharing) setupNewRound1Receiver() {
 	allOldIds := make([]*tss.PartyID, len(rs.params.OldParties().IDs()))
 	copy(allOldIds, rs.params.OldParties().IDs())
 
-	rcv := tss.NewJsonExpect[resharingRound1msg]("eddsa:reshare:round1", allOldIds, func(ids []*tss.PartyID, msgs []*resharingRound1msg) {
+	rcv := tss.NewJsonExpect[resharingRound1msg](rs.params.MsgType("eddsa:reshare:round1"), allOldIds, func(ids []*tss.PartyID, msgs []*resharingRound1msg) {
 		rs.round2New(ids, msgs)
-	})
-	rs.params.Broker().Connect("eddsa:reshare:round1", rcv)
+	}, rs.timeout(1), rs.echo(1, "eddsa:reshare:round1", rs.otherNewParties()))
+	rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round1"), rcv)
 }
 
 // round2New: new committee receives round1 messages, verifies EDDSAPub consistency, sends ACK.
 func (rs *Resharing) round2New(oldIds []*tss.PartyID, r1msgs []*resharingRound1msg) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -175,13 +190,13 @@ func (rs *Resharing) round2New(oldIds []*tss.PartyID, r1msgs []*resharingRound1m
 		pubY := new(big.Int).SetBytes(msg.EDDSAPubY)
 		candidate, err := crypto.NewECPoint(ec, pubX, pubY)
 		if err != nil {
-			rs.Err <- fmt.Errorf("party %s sent invalid EDDSAPub: %w", oldIds[n], err)
+			rs.fail(rs.wrapError(2, fmt.Errorf("invalid EDDSAPub: %w", err), oldIds[n]))
 			return
 		}
 		if eddsaPub == nil {
 			eddsaPub = candidate
 		} else if !eddsaPub.Equals(candidate) {
-			rs.Err <- fmt.Errorf("party %s sent different EDDSAPub", oldIds[n])
+			rs.fail(rs.wrapError(2, errors.New("different EDDSAPub"), oldIds[n]))
 			return
 		}
 	}
@@ -193,8 +208,8 @@ func (rs *Resharing) round2New(oldIds []*tss.PartyID, r1msgs []*resharingRound1m
 		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
 			continue
 		}
-		m := tss.JsonWrap("eddsa:reshare:round2", r2msg, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("eddsa:reshare:round2"), r2msg, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Register receivers for round 3 messages from old committee
@@ -220,29 +235,29 @@ func (rs *Resharing) setupNewRound3Receiver(oldIds []*tss.PartyID, r1msgs []*res
 	allOldIds := make([]*tss.PartyID, len(rs.params.OldParties().IDs()))
 	copy(allOldIds, rs.params.OldParties().IDs())
 
-	rcv1 := tss.NewJsonExpect[resharingRound3msg1]("eddsa:reshare:round3-1", allOldIds, func(ids []*tss.PartyID, msgs []*resharingRound3msg1) {
+	rcv1 := tss.NewJsonExpect[resharingRound3msg1](rs.params.MsgType("eddsa:reshare:round3-1"), allOldIds, func(ids []*tss.PartyID, msgs []*resharingRound3msg1) {
 		r3msg1s = msgs
 		r3msg1Ids = ids
 		check()
-	})
-	rs.params.Broker().Connect("eddsa:reshare:round3-1", rcv1)
+	}, rs.timeout(3), tss.WithPrivate())
+	rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round3-1"), rcv1)
 
 	// For round3-2 (broadcast decommitment), all old parties broadcast.
 	allOldIds2 := make([]*tss.PartyID, len(rs.params.OldParties().IDs()))
 	copy(allOldIds2, rs.params.OldParties().IDs())
 
-	rcv2 := tss.NewJsonExpect[resharingRound3msg2]("eddsa:reshare:round3-2", allOldIds2, func(ids []*tss.PartyID, msgs []*resharingRound3msg2) {
+	rcv2 := tss.NewJsonExpect[resharingRound3msg2](rs.params.MsgType("eddsa:reshare:round3-2"), allOldIds2, func(ids []*tss.PartyID, msgs []*resharingRound3msg2) {
 		r3msg2s = msgs
 		r3msg2Ids = ids
 		check()
-	})
-	rs.params.Broker().Connect("eddsa:reshare:round3-2", rcv2)
+	}, rs.timeout(3), rs.echo(3, "eddsa:reshare:round3-2", rs.otherNewParties()))
+	rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round3-2"), rcv2)
 }
 
 // round3Old: old committee sends P2P VSS shares to each new party and broadcasts decommitment.
 func (rs *Resharing) round3Old() {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -254,8 +269,13 @@ func (rs *Resharing) round3Old() {
 		r3msg1 := &resharingRound3msg1{
 			Share: share.Share.Bytes(),
 		}
-		m := tss.JsonWrap("eddsa:reshare:round3-1", r3msg1, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		if rs.newMoreShares != nil {
+			for _, share := range rs.newMoreShares[j] {
+				r3msg1.MoreShares = append(r3msg1.MoreShares, share.Share.Bytes())
+			}
+		}
+		m := tss.JsonWrapPrivate(rs.params.MsgType("eddsa:reshare:round3-1"), r3msg1, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Broadcast decommitment to all new parties
@@ -264,8 +284,8 @@ func (rs *Resharing) round3Old() {
 		VDecommitment: vDBytes,
 	}
 	for _, Pj := range newParties {
-		m := tss.JsonWrap("eddsa:reshare:round3-2", r3msg2, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("eddsa:reshare:round3-2"), r3msg2, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	// Register receiver for round 4 ACKs from new committee
@@ -293,10 +313,10 @@ func (rs *Resharing) setupOldRound4Receiver() {
 		return
 	}
 
-	rcv := tss.NewJsonExpect[resharingRound4msg]("eddsa:reshare:round4", otherNewIds, func(ids []*tss.PartyID, msgs []*resharingRound4msg) {
+	rcv := tss.NewJsonExpect[resharingRound4msg](rs.params.MsgType("eddsa:reshare:round4"), otherNewIds, func(ids []*tss.PartyID, msgs []*resharingRound4msg) {
 		rs.round5Old()
-	})
-	rs.params.Broker().Connect("eddsa:reshare:round4", rcv)
+	}, rs.timeout(4))
+	rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round4"), rcv)
 }
 
 // round4New: new committee verifies decommitments, VSS shares, computes new key data.
@@ -309,7 +329,7 @@ func (rs *Resharing) round4New(
 	r3msg2s []*resharingRound3msg2,
 ) {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	Pi := rs.params.PartyID()
@@ -349,22 +369,45 @@ func (rs *Resharing) round4New(
 	newXi := big.NewInt(0)
 	modQ := common.ModInt(ec.Params().N)
 
+	// the other points of the new parties of a weighted key, or the ranks of those of a
+	// hierarchical one, and those of this party
+	newKs := rs.params.NewParties().IDs().Keys()
+	newMoreKs := vss.WeightedPoints(ec.Params().N, newKs, rs.params.NewWeights())
+	newRanks := vss.HierarchicalRanks(rs.params.NewRanks())
+	var ownMoreKs []*big.Int
+	ownRank := 0
+	for j, kj := range newKs {
+		if kj.Cmp(Pi.KeyInt()) != 0 {
+			continue
+		}
+		if newMoreKs != nil {
+			ownMoreKs = newMoreKs[j]
+		}
+		if newRanks != nil {
+			ownRank = newRanks[j]
+		}
+	}
+	newMoreXi := make([]*big.Int, len(ownMoreKs))
+	for m := range newMoreXi {
+		newMoreXi[m] = big.NewInt(0)
+	}
+
 	vjc := make([][]*crypto.ECPoint, len(allOldIds))
 
 	for j := 0; j < len(allOldIds); j++ {
 		r1msg, ok := r1ByOldIdx[j]
 		if !ok {
-			rs.Err <- fmt.Errorf("missing round1 message from old party %d", j)
+			rs.fail(rs.wrapError(4, errors.New("missing round1 message from old party"), allOldIds[j]))
 			return
 		}
 		r3msg1, ok := r3m1ByOldIdx[j]
 		if !ok {
-			rs.Err <- fmt.Errorf("missing round3-1 message from old party %d", j)
+			rs.fail(rs.wrapError(4, errors.New("missing round3-1 message from old party"), allOldIds[j]))
 			return
 		}
 		r3msg2, ok := r3m2ByOldIdx[j]
 		if !ok {
-			rs.Err <- fmt.Errorf("missing round3-2 message from old party %d", j)
+			rs.fail(rs.wrapError(4, errors.New("missing round3-2 message from old party"), allOldIds[j]))
 			return
 		}
 
@@ -374,13 +417,13 @@ func (rs *Resharing) round4New(
 		cmtDeCmt := cmts.HashCommitDecommit{C: vCj, D: vDj}
 		ok2, flatVs := cmtDeCmt.DeCommit()
 		if !ok2 || len(flatVs) != (rs.params.NewThreshold()+1)*2 {
-			rs.Err <- fmt.Errorf("de-commitment verify failed for old party %d", j)
+			rs.fail(rs.wrapError(4, errors.New("de-commitment verify failed"), allOldIds[j]))
 			return
 		}
 
 		vj, err := crypto.UnFlattenECPoints(ec, flatVs)
 		if err != nil {
-			rs.Err <- fmt.Errorf("UnFlattenECPoints for old party %d: %w", j, err)
+			rs.fail(rs.wrapError(4, fmt.Errorf("UnFlattenECPoints: %w", err), allOldIds[j]))
 			return
 		}
 
@@ -396,13 +439,31 @@ func (rs *Resharing) round4New(
 			Threshold: rs.params.NewThreshold(),
 			ID:        Pi.KeyInt(),
 			Share:     new(big.Int).SetBytes(r3msg1.Share),
+			Rank:      ownRank,
 		}
 		if !sharej.Verify(ec, rs.params.NewThreshold(), vj) {
-			rs.Err <- fmt.Errorf("VSS share verification failed for old party %d", j)
+			rs.fail(rs.wrapError(4, errors.New("VSS share verification failed"), allOldIds[j]))
 			return
 		}
 
 		newXi = new(big.Int).Add(newXi, sharej.Share)
+
+		if len(r3msg1.MoreShares) != len(ownMoreKs) {
+			rs.fail(rs.wrapError(4, errors.New("message holds the shares of another number of points"), allOldIds[j]))
+			return
+		}
+		for m, shareBz := range r3msg1.MoreShares {
+			share := &vss.Share{
+				Threshold: rs.params.NewThreshold(),
+				ID:        ownMoreKs[m],
+				Share:     new(big.Int).SetBytes(shareBz),
+			}
+			if !share.Verify(ec, rs.params.NewThreshold(), vj) {
+				rs.fail(rs.wrapError(4, errors.New("VSS share verification failed"), allOldIds[j]))
+				return
+			}
+			newMoreXi[m] = modQ.Add(newMoreXi[m], share.Share)
+		}
 	}
 
 	// Compute Vc: aggregate polynomial commitments
@@ -413,7 +474,7 @@ func (rs *Resharing) round4New(
 		for j := 1; j < len(vjc); j++ {
 			Vc[c], err = Vc[c].Add(vjc[j][c])
 			if err != nil {
-				rs.Err <- fmt.Errorf("Vc[%d].Add(vjc[%d][%d]): %w", c, j, c, err)
+				rs.fail(rs.wrapError(4, fmt.Errorf("Vc[%d].Add(vjc[%d][%d]): %w", c, j, c, err)))
 				return
 			}
 		}
@@ -421,29 +482,49 @@ func (rs *Resharing) round4New(
 
 	// Verify Vc[0] == EDDSAPub
 	if !Vc[0].Equals(rs.eddsaPub) {
-		rs.Err <- fmt.Errorf("assertion failed: V_0 != EDDSAPub")
+		rs.fail(rs.wrapError(4, errors.New("assertion failed: V_0 != EDDSAPub")))
 		return
 	}
 
 	// Compute newBigXj for each new party
-	newKs := make([]*big.Int, 0, rs.params.NewPartyCount())
 	newBigXjs := make([]*crypto.ECPoint, rs.params.NewPartyCount())
 	for j := 0; j < rs.params.NewPartyCount(); j++ {
-		Pj := rs.params.NewParties().IDs()[j]
-		kj := Pj.KeyInt()
-		newKs = append(newKs, kj)
+		kj := newKs[j]
+		if newRanks != nil {
+			newBigXj, err := vss.Vs(Vc).PublicShare(kj, newRanks[j])
+			if err != nil {
+				rs.fail(rs.wrapError(4, fmt.Errorf("computing newBigXj: %w", err)))
+				return
+			}
+			newBigXjs[j] = newBigXj
+			continue
+		}
 		newBigXj := Vc[0]
 		z := new(big.Int).SetInt64(1)
 		for c := 1; c <= rs.params.NewThreshold(); c++ {
 			z = modQ.Mul(z, kj)
 			newBigXj, err = newBigXj.Add(Vc[c].ScalarMult(z))
 			if err != nil {
-				rs.Err <- fmt.Errorf("computing newBigXj: %w", err)
+				rs.fail(rs.wrapError(4, fmt.Errorf("computing newBigXj: %w", err)))
 				return
 			}
 		}
 		newBigXjs[j] = newBigXj
 	}
+	var newMoreBigXjs [][]*crypto.ECPoint
+	if newMoreKs != nil {
+		newMoreBigXjs = make([][]*crypto.ECPoint, len(newKs))
+		for j, kjs := range newMoreKs {
+			for _, kj := range kjs {
+				newBigXj, err := vss.Vs(Vc).PublicShare(kj, 0)
+				if err != nil {
+					rs.fail(rs.wrapError(4, fmt.Errorf("computing newBigXj: %w", err)))
+					return
+				}
+				newMoreBigXjs[j] = append(newMoreBigXjs[j], newBigXj)
+			}
+		}
+	}
 
 	newXi = new(big.Int).Mod(newXi, ec.Params().N)
 
@@ -454,6 +535,12 @@ func (rs *Resharing) round4New(
 	newKey.Ks = newKs
 	newKey.BigXj = newBigXjs
 	newKey.EDDSAPub = rs.eddsaPub
+	newKey.Ranks = newRanks
+	if newMoreKs != nil {
+		newKey.MoreXi = newMoreXi
+		newKey.MoreKs = newMoreKs
+		newKey.MoreBigXj = newMoreBigXjs
+	}
 
 	// Store for round5
 	rs.round5NewKey = newKey
@@ -464,8 +551,8 @@ func (rs *Resharing) round4New(
 		if Pj.KeyInt().Cmp(Pi.KeyInt()) == 0 {
 			continue
 		}
-		m := tss.JsonWrap("eddsa:reshare:round4", r4msg, Pi, Pj)
-		rs.params.Broker().Receive(m)
+		m := tss.JsonWrap(rs.params.MsgType("eddsa:reshare:round4"), r4msg, Pi, Pj)
+		rs.broker.Receive(m)
 	}
 
 	if rs.params.IsOldCommittee() {
@@ -485,31 +572,82 @@ func (rs *Resharing) round4New(
 
 	if len(otherNewIds) == 0 {
 		// Only new party is self; save directly
+		rs.release()
 		rs.Done <- newKey
 		return
 	}
 
-	rcv := tss.NewJsonExpect[resharingRound4msg]("eddsa:reshare:round4", otherNewIds, func(ids []*tss.PartyID, msgs []*resharingRound4msg) {
+	rcv := tss.NewJsonExpect[resharingRound4msg](rs.params.MsgType("eddsa:reshare:round4"), otherNewIds, func(ids []*tss.PartyID, msgs []*resharingRound4msg) {
+		rs.release()
 		rs.Done <- newKey
-	})
-	rs.params.Broker().Connect("eddsa:reshare:round4", rcv)
+	}, rs.timeout(4))
+	rs.broker.Connect(rs.params.MsgType("eddsa:reshare:round4"), rcv)
 }
 
 // round5Old: old committee zeros Xi and signals done.
 func (rs *Resharing) round5Old() {
 	if rs.ctx.Err() != nil {
-		rs.Err <- rs.ctx.Err()
+		rs.fail(rs.ctx.Err())
 		return
 	}
 	if rs.input != nil {
 		rs.input.Xi.SetInt64(0)
+		for _, xi := range rs.input.MoreXi {
+			xi.SetInt64(0)
+		}
 	}
 
 	if rs.params.IsNewCommittee() && rs.round5NewKey != nil {
 		// Dual party: deliver the new key
+		rs.release()
 		rs.Done <- rs.round5NewKey
 	} else {
 		// Pure old party: done with nil key (Xi zeroed)
+		rs.release()
 		rs.Done <- nil
 	}
 }
+
+// fail reports err on Err and releases the receivers registered by this resharing.
+func (rs *Resharing) fail(err error) {
+	rs.release()
+	select {
+	case rs.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this resharing from the broker.
+func (rs *Resharing) release() {
+	rs.stop()
+	rs.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (rs *Resharing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(rs.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		rs.fail(rs.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (rs *Resharing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !rs.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(rs.broker, rs.params.MsgType(typ+":echo"), rs.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		rs.fail(rs.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// otherNewParties returns the parties of the new committee, without this party.
+func (rs *Resharing) otherNewParties() []*tss.PartyID {
+	return rs.params.NewParties().IDs().Exclude(rs.params.PartyID())
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (rs *Resharing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskResharing, round, rs.params.PartyID(), culprits...)
+}
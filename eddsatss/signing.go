@@ -12,20 +12,29 @@ import (
 	"github.com/KarpelesLab/tss-lib/v2/crypto"
 	cmts "github.com/KarpelesLab/tss-lib/v2/crypto/commitments"
 	"github.com/KarpelesLab/tss-lib/v2/crypto/schnorr"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
 	"github.com/KarpelesLab/tss-lib/v2/tss"
 )
 
+// TaskSigning is the task name reported in errors from Signing.
+const TaskSigning = "eddsa-signing"
+
 // Signing tracks a threshold EdDSA signing operation.
 type Signing struct {
 	ctx       context.Context
 	params    *tss.Parameters
+	broker    *tss.SessionBroker
+	stop      func() bool
 	key       *Key
 	msg       *big.Int
 	wi        *big.Int
+	coefs     [][]*big.Int // Lagrange coefficients of the shares of each party
 	ri        *big.Int
 	pointRi   *crypto.ECPoint
 	deCommit  cmts.HashDeCommitment
 	cjs       []*big.Int
+	bigRjs    []*crypto.ECPoint
+	lambda    *big.Int
 	ssid      []byte
 	ssidNonce *big.Int
 
@@ -50,10 +59,14 @@ func (key *Key) NewSigning(ctx context.Context, msg *big.Int, params *tss.Parame
 		key:    subsetKey,
 		msg:    msg,
 		cjs:    make([]*big.Int, partyCount),
+		bigRjs: make([]*crypto.ECPoint, partyCount),
 		Done:   make(chan *SignatureData, 1),
 		Err:    make(chan error, 1),
 	}
+	s.broker = tss.NewSessionBroker(params.Broker())
+	s.stop = context.AfterFunc(ctx, func() { s.fail(ctx.Err()) })
 	if err := s.round1(); err != nil {
+		s.release()
 		return nil, err
 	}
 	return s, nil
@@ -73,6 +86,11 @@ func (s *Signing) getSSID(roundNum int) ([]byte, error) {
 		return nil, fmt.Errorf("read BigXj failed: %w", err)
 	}
 	ssidList = append(ssidList, BigXjList...)
+	MoreBigXjList, err := vss.FlattenPublicShares(s.key.MoreBigXj)
+	if err != nil {
+		return nil, fmt.Errorf("read MoreBigXj failed: %w", err)
+	}
+	ssidList = append(ssidList, MoreBigXjList...)
 	ssidList = append(ssidList, big.NewInt(int64(roundNum)))
 	ssidList = append(ssidList, s.ssidNonce)
 	ssid := common.SHA512_256i(ssidList...).Bytes()
@@ -84,13 +102,16 @@ func (s *Signing) round1() error {
 	i := Pi.Index
 	ec := s.params.EC()
 
-	// prepare wi (Lagrange coefficient)
-	xi := s.key.Xi
-	ks := s.key.Ks
-	if s.params.Threshold()+1 > len(ks) {
-		return fmt.Errorf("t+1=%d is not satisfied by the key count of %d", s.params.Threshold()+1, len(ks))
+	// prepare wi (Lagrange coefficients of the shares of this party)
+	coefs, err := s.key.shareCoefs(s.params)
+	if err != nil {
+		return err
+	}
+	s.coefs = coefs
+	s.wi, err = s.key.additiveShare(ec.Params().N, coefs[i])
+	if err != nil {
+		return err
 	}
-	s.wi = PrepareForSigning(ec, i, len(ks), xi, ks)
 
 	// select random ri
 	ri := common.GetRandomPositiveInt(s.params.Rand(), ec.Params().N)
@@ -106,7 +127,6 @@ func (s *Signing) round1() error {
 
 	// compute ssid
 	s.ssidNonce = new(big.Int).SetUint64(0)
-	var err error
 	s.ssid, err = s.getSSID(1)
 	if err != nil {
 		return fmt.Errorf("failed to generate ssid: %w", err)
@@ -126,20 +146,20 @@ func (s *Signing) round1() error {
 		Commitment: cmt.C.Bytes(),
 	}
 	for _, p := range otherIds {
-		m := tss.JsonWrap("eddsa:sign:round1", msg, Pi, p)
-		s.params.Broker().Receive(m)
+		m := tss.JsonWrap(s.params.MsgType("eddsa:sign:round1"), msg, Pi, p)
+		s.broker.Receive(m)
 	}
 
 	// register receiver for round 1 messages from others -> triggers round 2
-	rcv := tss.NewJsonExpect[signRound1msg]("eddsa:sign:round1", otherIds, s.round2)
-	s.params.Broker().Connect("eddsa:sign:round1", rcv)
+	rcv := tss.NewJsonExpect[signRound1msg](s.params.MsgType("eddsa:sign:round1"), otherIds, s.round2, s.timeout(1), s.echo(1, "eddsa:sign:round1", otherIds))
+	s.broker.Connect(s.params.MsgType("eddsa:sign:round1"), rcv)
 
 	return nil
 }
 
 func (s *Signing) round2(otherIds []*tss.PartyID, r1msgs []*signRound1msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
 	Pi := s.params.PartyID()
@@ -159,7 +179,7 @@ func (s *Signing) round2(otherIds []*tss.PartyID, r1msgs []*signRound1msg) {
 	ContextI := append(s.ssid, new(big.Int).SetUint64(uint64(i)).Bytes()...)
 	pir, err := schnorr.NewZKProof(ContextI, s.ri, s.pointRi, s.params.Rand())
 	if err != nil {
-		s.Err <- fmt.Errorf("NewZKProof(ri, pointRi): %w", err)
+		s.fail(s.wrapError(2, fmt.Errorf("NewZKProof(ri, pointRi): %w", err)))
 		return
 	}
 
@@ -171,18 +191,18 @@ func (s *Signing) round2(otherIds []*tss.PartyID, r1msgs []*signRound1msg) {
 		SchnorrProofT:      pir.T.Bytes(),
 	}
 	for _, p := range otherIds {
-		m := tss.JsonWrap("eddsa:sign:round2", r2msg, Pi, p)
-		s.params.Broker().Receive(m)
+		m := tss.JsonWrap(s.params.MsgType("eddsa:sign:round2"), r2msg, Pi, p)
+		s.broker.Receive(m)
 	}
 
 	// register receiver for round 2 messages from others -> triggers round 3
-	rcv := tss.NewJsonExpect[signRound2msg]("eddsa:sign:round2", otherIds, s.round3)
-	s.params.Broker().Connect("eddsa:sign:round2", rcv)
+	rcv := tss.NewJsonExpect[signRound2msg](s.params.MsgType("eddsa:sign:round2"), otherIds, s.round3, s.timeout(2), s.echo(2, "eddsa:sign:round2", otherIds))
+	s.broker.Connect(s.params.MsgType("eddsa:sign:round2"), rcv)
 }
 
 func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
 	Pi := s.params.PartyID()
@@ -205,7 +225,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 			}
 		}
 		if j == -1 {
-			s.Err <- errors.New("party not found")
+			s.fail(s.wrapError(3, errors.New("party not found"), pid))
 			return
 		}
 
@@ -216,17 +236,17 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 		cmtDeCmt := cmts.HashCommitDecommit{C: s.cjs[j], D: KGDj}
 		ok, coordinates := cmtDeCmt.DeCommit()
 		if !ok {
-			s.Err <- errors.New("de-commitment verify failed")
+			s.fail(s.wrapError(3, errors.New("de-commitment verify failed"), pid))
 			return
 		}
 		if len(coordinates) != 2 {
-			s.Err <- errors.New("length of de-commitment should be 2")
+			s.fail(s.wrapError(3, errors.New("length of de-commitment should be 2"), pid))
 			return
 		}
 
 		Rj, err := crypto.NewECPoint(ec, coordinates[0], coordinates[1])
 		if err != nil {
-			s.Err <- fmt.Errorf("NewECPoint(Rj): %w", err)
+			s.fail(s.wrapError(3, fmt.Errorf("NewECPoint(Rj): %w", err), pid))
 			return
 		}
 		Rj = Rj.EightInvEight()
@@ -236,7 +256,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 		alphaY := new(big.Int).SetBytes(r2msgs[n].SchnorrProofAlphaY)
 		alpha, err := crypto.NewECPoint(ec, alphaX, alphaY)
 		if err != nil {
-			s.Err <- errors.New("failed to reconstruct Schnorr proof alpha point")
+			s.fail(s.wrapError(3, errors.New("failed to reconstruct Schnorr proof alpha point"), pid))
 			return
 		}
 		proof := &schnorr.ZKProof{
@@ -244,9 +264,10 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 			T:     new(big.Int).SetBytes(r2msgs[n].SchnorrProofT),
 		}
 		if !proof.Verify(ContextJ, Rj) {
-			s.Err <- errors.New("Schnorr proof verification failed for Rj")
+			s.fail(s.wrapError(3, errors.New("Schnorr proof verification failed for Rj"), pid))
 			return
 		}
+		s.bigRjs[j] = Rj
 
 		// add to running total R
 		extendedRj := ecPointToExtendedElement(ec, Rj.X(), Rj.Y(), s.params.Rand())
@@ -270,6 +291,7 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 	h.Sum(lambda[:0])
 	var lambdaReduced [32]byte
 	edwards25519.ScReduce(&lambdaReduced, &lambda)
+	s.lambda = encodedBytesToBigInt(&lambdaReduced)
 
 	// compute si = lambdaReduced * wi + ri
 	var localS [32]byte
@@ -283,23 +305,23 @@ func (s *Signing) round3(otherIds []*tss.PartyID, r2msgs []*signRound2msg) {
 		Si: localS[:],
 	}
 	for _, p := range otherIds {
-		m := tss.JsonWrap("eddsa:sign:round3", r3msg, Pi, p)
-		s.params.Broker().Receive(m)
+		m := tss.JsonWrap(s.params.MsgType("eddsa:sign:round3"), r3msg, Pi, p)
+		s.broker.Receive(m)
 	}
 
 	// register receiver for round 3 messages from others -> triggers finalize
-	rcv := tss.NewJsonExpect[signRound3msg]("eddsa:sign:round3", otherIds, func(ids []*tss.PartyID, msgs []*signRound3msg) {
-		s.finalize(r, &localS, &encodedR, msgs)
-	})
-	s.params.Broker().Connect("eddsa:sign:round3", rcv)
+	rcv := tss.NewJsonExpect[signRound3msg](s.params.MsgType("eddsa:sign:round3"), otherIds, func(ids []*tss.PartyID, msgs []*signRound3msg) {
+		s.finalize(r, &localS, &encodedR, ids, msgs)
+	}, s.timeout(3), s.echo(3, "eddsa:sign:round3", otherIds))
+	s.broker.Connect(s.params.MsgType("eddsa:sign:round3"), rcv)
 
 	// suppress unused variable warning
 	_ = i
 }
 
-func (s *Signing) finalize(r *big.Int, localS *[32]byte, encodedR *[32]byte, r3msgs []*signRound3msg) {
+func (s *Signing) finalize(r *big.Int, localS *[32]byte, encodedR *[32]byte, otherIds []*tss.PartyID, r3msgs []*signRound3msg) {
 	if s.ctx.Err() != nil {
-		s.Err <- s.ctx.Err()
+		s.fail(s.ctx.Err())
 		return
 	}
 	// sum all sj: start with our own si
@@ -333,9 +355,81 @@ func (s *Signing) finalize(r *big.Int, localS *[32]byte, encodedR *[32]byte, r3m
 
 	ok := edwards25519.VerifyRS(&pk, sigData.M, r, sInt)
 	if !ok {
-		s.Err <- fmt.Errorf("signature verification failed")
+		s.fail(s.wrapError(4, errors.New("signature verification failed"), s.badShares(otherIds, r3msgs)...))
 		return
 	}
 
+	s.release()
 	s.Done <- sigData
 }
+
+// fail reports err on Err and releases the receivers registered by this signing.
+func (s *Signing) fail(err error) {
+	s.release()
+	select {
+	case s.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this signing from the broker.
+func (s *Signing) release() {
+	s.stop()
+	s.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (s *Signing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(s.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (s *Signing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !s.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(s.broker, s.params.MsgType(typ+":echo"), s.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (s *Signing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskSigning, round, s.params.PartyID(), culprits...)
+}
+
+// badShares returns the parties whose si does not match their Rj and public key share, that
+// is for which si*G != Rj + lambda*wj*G.
+func (s *Signing) badShares(otherIds []*tss.PartyID, r3msgs []*signRound3msg) []*tss.PartyID {
+	ec := s.params.EC()
+
+	var culprits []*tss.PartyID
+	for n, pid := range otherIds {
+		j := -1
+		for idx, Pj := range s.params.Parties().IDs() {
+			if Pj.KeyInt().Cmp(pid.KeyInt()) == 0 {
+				j = idx
+				break
+			}
+		}
+		if j == -1 || s.bigRjs[j] == nil {
+			culprits = append(culprits, pid)
+			continue
+		}
+		bigWj, err := s.key.publicShare(j, s.coefs[j])
+		if err != nil {
+			culprits = append(culprits, pid)
+			continue
+		}
+		sj := encodedBytesToBigInt(copyBytes(r3msgs[n].Si))
+		expected, err := s.bigRjs[j].Add(bigWj.ScalarMult(s.lambda))
+		if err != nil || !crypto.ScalarBaseMult(ec, sj).Equals(expected) {
+			culprits = append(culprits, pid)
+		}
+	}
+	return culprits
+}
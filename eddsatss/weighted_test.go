@@ -0,0 +1,151 @@
+package eddsatss
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/KarpelesLab/edwards25519"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// signWeighted signs msg with the keys of the parties of ids, of the given weights, and checks
+// the signature against pub.
+func signWeighted(t *testing.T, keys []*Key, ids tss.SortedPartyIDs, weights []int, threshold int, msg *big.Int, pub *crypto.ECPoint) {
+	signCtx := tss.NewPeerContext(ids)
+	hub := newTestHub(len(ids))
+	signings := make([]*Signing, len(ids))
+	for n, p := range ids {
+		params := tss.NewWeightedParameters(tss.Edwards(), signCtx, p, weights, threshold)
+		params.SetBroker(hub.brokers[n])
+
+		sg, err := keys[n].NewSigning(context.Background(), msg, params)
+		require.NoError(t, err)
+		signings[n] = sg
+	}
+	var sig *SignatureData
+	for n, sg := range signings {
+		select {
+		case sig = <-sg.Done:
+		case err := <-sg.Err:
+			t.Fatalf("Party %d signing error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d signing timed out", n)
+		}
+	}
+	pk := edwards25519.PublicKey{Curve: tss.Edwards(), X: pub.X(), Y: pub.Y()}
+	parsed, err := edwards25519.ParseSignature(sig.Signature)
+	require.NoError(t, err)
+	assert.True(t, edwards25519.VerifyRS(&pk, msg.Bytes(), parsed.R, parsed.S))
+}
+
+func TestWeightedKeygenSignAndReshare(t *testing.T) {
+	const threshold = 2
+
+	// the treasury, party 0, holds 2 shares and each operator 1
+	weights := []int{2, 1, 1}
+	pIDs := tss.GenerateTestPartyIDs(len(weights))
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(len(pIDs))
+	keygens := make([]*Keygen, len(pIDs))
+	for n, p := range pIDs {
+		params := tss.NewWeightedParameters(tss.Edwards(), p2pCtx, p, weights, threshold)
+		params.SetBroker(hub.brokers[n])
+
+		kg, err := NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[n] = kg
+	}
+	keys := make([]*Key, len(pIDs))
+	for n, kg := range keygens {
+		select {
+		case keys[n] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("Party %d keygen error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d keygen timed out", n)
+		}
+	}
+	pub := keys[0].EDDSAPub
+	for n, key := range keys {
+		assert.True(t, pub.Equals(key.EDDSAPub))
+		assert.Equal(t, weights, key.Weights())
+		require.Len(t, key.MoreXi, weights[n]-1)
+		assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), key.Xi).Equals(key.BigXj[n]))
+		for m, xi := range key.MoreXi {
+			assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), xi).Equals(key.MoreBigXj[n][m]))
+		}
+	}
+
+	// the treasury signs with either operator, the operators cannot sign without it
+	msg := big.NewInt(42)
+	signWeighted(t, []*Key{keys[0], keys[2]}, subsetIDs(pIDs, 0, 2), []int{2, 1}, threshold, msg, pub)
+	operatorIDs := subsetIDs(pIDs, 1, 2)
+	assert.Panics(t, func() {
+		tss.NewWeightedParameters(tss.Edwards(), tss.NewPeerContext(operatorIDs), operatorIDs[0], []int{1, 1}, threshold)
+	})
+
+	// weights which are not those of the key are rejected, as are the operations which do not
+	// support weighted keys
+	mixedIDs := subsetIDs(pIDs, 0, 1)
+	params := tss.NewWeightedParameters(tss.Edwards(), tss.NewPeerContext(mixedIDs), mixedIDs[0], []int{1, 1}, 1)
+	_, err := keys[0].NewSigning(context.Background(), msg, params)
+	assert.Error(t, err)
+	_, err = keys[0].NewRefresh(context.Background(), tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[0], len(pIDs), 1))
+	assert.ErrorIs(t, err, ErrWeightedKey)
+
+	// the treasury and an operator reshare to three new parties, the last one holding 2 shares
+	oldIDs := subsetIDs(pIDs, 0, 1)
+	oldKeys := []*Key{keys[0], keys[1]}
+	oldWeights := []int{2, 1}
+	newIDs := tss.GenerateTestPartyIDs(3)
+	newWeights := []int{1, 1, 2}
+	oldCtx, newCtx := tss.NewPeerContext(oldIDs), tss.NewPeerContext(newIDs)
+	allIDs := append(append([]*tss.PartyID{}, oldIDs...), newIDs...)
+	rsHub := newResharingHub()
+	for _, p := range allIDs {
+		rsHub.addParty(p)
+	}
+	var resharings []*Resharing
+	for n, p := range allIDs {
+		params := tss.NewWeightedReSharingParameters(tss.Edwards(), oldCtx, newCtx, p, oldWeights, threshold, newWeights, threshold)
+		params.SetBroker(rsHub.addParty(p))
+
+		var input *Key
+		if n < len(oldIDs) {
+			input = oldKeys[n]
+		}
+		rs, err := NewResharing(context.Background(), params, input)
+		require.NoError(t, err)
+		resharings = append(resharings, rs)
+	}
+	newKeys := make([]*Key, len(newIDs))
+	for n, rs := range resharings {
+		select {
+		case key := <-rs.Done:
+			if n >= len(oldIDs) {
+				newKeys[n-len(oldIDs)] = key
+			}
+		case err := <-rs.Err:
+			t.Fatalf("Party %d resharing error: %v", n, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("Party %d resharing timed out", n)
+		}
+	}
+	for n, key := range newKeys {
+		assert.True(t, pub.Equals(key.EDDSAPub))
+		assert.Equal(t, newWeights, key.Weights())
+		require.Len(t, key.MoreXi, newWeights[n]-1)
+		for m, xi := range key.MoreXi {
+			assert.True(t, crypto.ScalarBaseMult(tss.Edwards(), xi).Equals(key.MoreBigXj[n][m]))
+		}
+	}
+
+	// the new party holding 2 shares signs with another one
+	signWeighted(t, []*Key{newKeys[0], newKeys[2]}, subsetIDs(newIDs, 0, 2), []int{1, 2}, threshold, msg, pub)
+}
@@ -0,0 +1,259 @@
+package frosttss
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// bip340ContextString is the context string of FROST(secp256k1, SHA-256) producing BIP-340
+// signatures.
+const bip340ContextString = "FROST-secp256k1-SHA256-TR-v1"
+
+// bip340Suite is the FROST(secp256k1, SHA-256) ciphersuite of RFC 9591, section 6.5, with the
+// challenge and the even y of BIP-340, so that the signatures are valid BIP-340 signatures
+// by the x coordinate of the group public key.
+type bip340Suite struct{}
+
+func (bip340Suite) curve() elliptic.Curve {
+	return tss.S256()
+}
+
+// serializePoint returns the 33-byte compressed SEC1 encoding of p.
+func (bip340Suite) serializePoint(p *crypto.ECPoint) []byte {
+	return append([]byte{byte(2 + p.Y().Bit(0))}, xOnly(p)...)
+}
+
+func (cs bip340Suite) serializeScalar(k *big.Int) []byte {
+	return common.PadToLengthBytesInPlace(new(big.Int).Mod(k, cs.curve().Params().N).Bytes(), 32)
+}
+
+func (cs bip340Suite) validPoint(p *crypto.ECPoint) bool {
+	return p.ValidateBasic() && p.Curve() == cs.curve()
+}
+
+func (cs bip340Suite) h1(m []byte) *big.Int {
+	return cs.hashToScalar(m, "rho")
+}
+
+func (cs bip340Suite) h3(m []byte) *big.Int {
+	return cs.hashToScalar(m, "nonce")
+}
+
+func (cs bip340Suite) h4(m []byte) []byte {
+	return cs.hash("msg", m)
+}
+
+func (cs bip340Suite) h5(m []byte) []byte {
+	return cs.hash("com", m)
+}
+
+// challenge is the one of BIP-340: the tagged hash of x(R) || x(pub) || msg.
+func (cs bip340Suite) challenge(R, pub *crypto.ECPoint, msg []byte) *big.Int {
+	e := taggedHash("BIP0340/challenge", xOnly(R), xOnly(pub), msg)
+	return new(big.Int).Mod(new(big.Int).SetBytes(e), cs.curve().Params().N)
+}
+
+// negatesNonces returns true if R has an odd y, BIP-340 signatures using the point of x(R)
+// with an even y.
+func (bip340Suite) negatesNonces(R *crypto.ECPoint) bool {
+	return R.Y().Bit(0) == 1
+}
+
+// signature returns the BIP-340 signature x(R) || z, R and S holding its two halves.
+func (cs bip340Suite) signature(R *crypto.ECPoint, z *big.Int, msg []byte) *eddsatss.SignatureData {
+	r, s := xOnly(R), cs.serializeScalar(z)
+	return &eddsatss.SignatureData{
+		R:         r,
+		S:         s,
+		Signature: append(append([]byte{}, r...), s...),
+		M:         msg,
+	}
+}
+
+func (bip340Suite) verify(pub *crypto.ECPoint, sig *eddsatss.SignatureData) bool {
+	return VerifyBIP340(xOnly(pub), sig.M, sig.Signature)
+}
+
+// hash returns SHA-256(contextString || tag || m).
+func (bip340Suite) hash(tag string, m []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(bip340ContextString))
+	h.Write([]byte(tag))
+	h.Write(m)
+	return h.Sum(nil)
+}
+
+// hashToScalar returns hash_to_field(m) of RFC 9380, with expand_message_xmd using SHA-256,
+// the domain separation tag contextString || tag and L = 48.
+func (cs bip340Suite) hashToScalar(m []byte, tag string) *big.Int {
+	uniform := expandMessageXMD(m, []byte(bip340ContextString+tag), 48)
+	return new(big.Int).Mod(new(big.Int).SetBytes(uniform), cs.curve().Params().N)
+}
+
+// expandMessageXMD is expand_message_xmd of RFC 9380, section 5.3.1, with SHA-256. size
+// must be at most 255·32.
+func expandMessageXMD(msg, dst []byte, size int) []byte {
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+	h := sha256.New()
+	h.Write(make([]byte, h.BlockSize()))
+	h.Write(msg)
+	h.Write([]byte{byte(size >> 8), byte(size), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	var uniform, bi []byte
+	for i := 1; len(uniform) < size; i++ {
+		h.Reset()
+		if i == 1 {
+			h.Write(b0)
+		} else {
+			for k := range bi {
+				bi[k] ^= b0[k]
+			}
+			h.Write(bi)
+		}
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		uniform = append(uniform, bi...)
+	}
+	return uniform[:size]
+}
+
+// taggedHash returns the tagged hash of BIP-340: SHA-256(SHA-256(tag) || SHA-256(tag) || m).
+func taggedHash(tag string, m ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, bz := range m {
+		h.Write(bz)
+	}
+	return h.Sum(nil)
+}
+
+// xOnly returns the 32-byte x coordinate of p.
+func xOnly(p *crypto.ECPoint) []byte {
+	return common.PadToLengthBytesInPlace(p.X().Bytes(), 32)
+}
+
+// liftX returns the point of x coordinate x with an even y, as done by BIP-340.
+func liftX(x *big.Int) (*crypto.ECPoint, error) {
+	ec := tss.S256()
+	p := ec.Params().P
+	if x.Cmp(p) >= 0 {
+		return nil, errors.New("x is not a field element")
+	}
+	// y² = x³ + 7, and y = (y²)^((p+1)/4) as p = 3 mod 4
+	y2 := new(big.Int).Exp(x, big.NewInt(3), p)
+	y2.Add(y2, big.NewInt(7)).Mod(y2, p)
+	y := new(big.Int).Exp(y2, new(big.Int).Rsh(new(big.Int).Add(p, big.NewInt(1)), 2), p)
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+	return crypto.NewECPoint(ec, x, y)
+}
+
+// VerifyBIP340 returns true if sig is a valid 64-byte BIP-340 signature of msg by the x-only
+// public key pubKey.
+func VerifyBIP340(pubKey, msg, sig []byte) bool {
+	if len(pubKey) != 32 || len(sig) != 64 {
+		return false
+	}
+	ec := tss.S256()
+	n := ec.Params().N
+	P, err := liftX(new(big.Int).SetBytes(pubKey))
+	if err != nil {
+		return false
+	}
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(ec.Params().P) >= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	e := new(big.Int).Mod(new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pubKey, msg)), n)
+
+	// R = s·G - e·P must have an even y and x(R) = r
+	sx, sy := ec.ScalarBaseMult(s.Bytes())
+	ex, ey := ec.ScalarMult(P.X(), P.Y(), new(big.Int).Sub(n, e).Bytes())
+	Rx, Ry := ec.Add(sx, sy, ex, ey)
+	if !ec.IsOnCurve(Rx, Ry) || Ry.Bit(0) == 1 {
+		return false
+	}
+	return Rx.Cmp(r) == 0
+}
+
+// taprootTweak returns the output key Q = P + t·G of BIP-341 for the internal key P, the
+// point of x(pub) with an even y, and the script tree merkleRoot, nil if there is none, along
+// with the tweak t.
+func taprootTweak(pub *crypto.ECPoint, merkleRoot []byte) (*crypto.ECPoint, *big.Int, error) {
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, nil, errors.New("merkle root must be 32 bytes")
+	}
+	ec := tss.S256()
+	P := pub
+	if P.Y().Bit(0) == 1 {
+		P = negatePoint(P)
+	}
+	t := new(big.Int).SetBytes(taggedHash("TapTweak", xOnly(P), merkleRoot))
+	if t.Cmp(ec.Params().N) >= 0 {
+		return nil, nil, errors.New("taproot tweak is not a valid scalar")
+	}
+	Q, err := P.Add(crypto.ScalarBaseMult(ec, t))
+	if err != nil {
+		return nil, nil, err
+	}
+	return Q, t, nil
+}
+
+// TaprootOutputKey returns the 32-byte x-only output key of BIP-341 for the internal key pub,
+// such as the ECDSAPub of an ecdsatss.Key, and the script tree merkleRoot, nil for a key with
+// no script path. Signatures made by SignTaprootWithNonces are valid for this key.
+func TaprootOutputKey(pub *crypto.ECPoint, merkleRoot []byte) ([]byte, error) {
+	Q, _, err := taprootTweak(pub, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return xOnly(Q), nil
+}
+
+// bip340Key returns the share, the public shares, the public key and the tweak to sign with
+// key for BIP-340, with the key of key.ECDSAPub with an even y, or its taproot output key when
+// taproot is true. The shares are negated when needed for the signing key to have an even y.
+func bip340Key(key *ecdsatss.Key, taproot bool, merkleRoot []byte) (*big.Int, []*crypto.ECPoint, *crypto.ECPoint, *big.Int, error) {
+	q := tss.S256().Params().N
+	modQ := common.ModInt(q)
+	pub := key.ECDSAPub
+	negate := pub.Y().Bit(0) == 1
+	if negate {
+		pub = negatePoint(pub)
+	}
+	var tweak *big.Int
+	if taproot {
+		Q, t, err := taprootTweak(pub, merkleRoot)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		pub, tweak = Q, t
+		if Q.Y().Bit(0) == 1 {
+			// -Q = -(P + t·G): negate the shares again, and the tweak
+			pub, tweak = negatePoint(Q), modQ.Sub(zero, t)
+			negate = !negate
+		}
+	}
+	if !negate {
+		return key.Xi, key.BigXj, pub, tweak, nil
+	}
+	bigXj := make([]*crypto.ECPoint, len(key.BigXj))
+	for j, Xj := range key.BigXj {
+		bigXj[j] = negatePoint(Xj)
+	}
+	return modQ.Sub(zero, key.Xi), bigXj, pub, tweak, nil
+}
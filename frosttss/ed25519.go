@@ -0,0 +1,102 @@
+package frosttss
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"math/big"
+
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// ed25519ContextString is the context string of FROST(Ed25519, SHA-512).
+const ed25519ContextString = "FROST-ED25519-SHA512-v1"
+
+// ed25519Suite is the FROST(Ed25519, SHA-512) ciphersuite (RFC 9591, section 6.1).
+type ed25519Suite struct{}
+
+func (ed25519Suite) curve() elliptic.Curve {
+	return tss.Edwards()
+}
+
+// serializePoint returns the 32-byte encoding of RFC 8032: y in little-endian order, with
+// the sign of x in the top bit.
+func (ed25519Suite) serializePoint(p *crypto.ECPoint) []byte {
+	bz := littleEndian(p.Y(), 32)
+	bz[31] |= byte(p.X().Bit(0)) << 7
+	return bz
+}
+
+func (cs ed25519Suite) serializeScalar(k *big.Int) []byte {
+	return littleEndian(new(big.Int).Mod(k, cs.curve().Params().N), 32)
+}
+
+// validPoint returns true if p is a point of the prime-order subgroup other than the
+// identity, as required of the elements received from other parties.
+func (cs ed25519Suite) validPoint(p *crypto.ECPoint) bool {
+	if !p.ValidateBasic() || p.Curve() != cs.curve() || (p.X().Sign() == 0 && p.Y().Cmp(big.NewInt(1)) == 0) {
+		return false
+	}
+	return p.EightInvEight().Equals(p)
+}
+
+func (cs ed25519Suite) h1(m []byte) *big.Int {
+	return cs.scalar(cs.hash("rho", m))
+}
+
+func (cs ed25519Suite) h3(m []byte) *big.Int {
+	return cs.scalar(cs.hash("nonce", m))
+}
+
+func (cs ed25519Suite) h4(m []byte) []byte {
+	return cs.hash("msg", m)
+}
+
+func (cs ed25519Suite) h5(m []byte) []byte {
+	return cs.hash("com", m)
+}
+
+// challenge is the one of Ed25519: SHA-512(R || pub || msg), without context string.
+func (cs ed25519Suite) challenge(R, pub *crypto.ECPoint, msg []byte) *big.Int {
+	h := sha512.New()
+	h.Write(cs.serializePoint(R))
+	h.Write(cs.serializePoint(pub))
+	h.Write(msg)
+	return cs.scalar(h.Sum(nil))
+}
+
+func (ed25519Suite) negatesNonces(*crypto.ECPoint) bool {
+	return false
+}
+
+// signature returns the Ed25519 signature R || z, R and S holding the big-endian values of
+// its two halves as done by eddsatss.
+func (cs ed25519Suite) signature(R *crypto.ECPoint, z *big.Int, msg []byte) *eddsatss.SignatureData {
+	encodedR := cs.serializePoint(R)
+	return &eddsatss.SignatureData{
+		R:         fromLittleEndian(encodedR).Bytes(),
+		S:         z.Bytes(),
+		Signature: append(encodedR, cs.serializeScalar(z)...),
+		M:         msg,
+	}
+}
+
+func (cs ed25519Suite) verify(pub *crypto.ECPoint, sig *eddsatss.SignatureData) bool {
+	return ed25519.Verify(cs.serializePoint(pub), sig.M, sig.Signature)
+}
+
+// hash returns SHA-512(contextString || tag || m).
+func (ed25519Suite) hash(tag string, m []byte) []byte {
+	h := sha512.New()
+	h.Write([]byte(ed25519ContextString))
+	h.Write([]byte(tag))
+	h.Write(m)
+	return h.Sum(nil)
+}
+
+// scalar returns the little-endian integer digest modulo the group order.
+func (cs ed25519Suite) scalar(digest []byte) *big.Int {
+	return new(big.Int).Mod(fromLittleEndian(digest), cs.curve().Params().N)
+}
@@ -0,0 +1,180 @@
+// Package frosttss implements FROST, the Flexible Round-Optimized Schnorr Threshold signature
+// scheme of RFC 9591, with the key shares made by the other packages of the library.
+//
+// Signing is split in two steps:
+//
+//   - preprocessing runs the round committing to the nonces of a signature, which does not
+//     depend on the message and can be run ahead of time;
+//   - the signing functions sign a message in a single round with the nonces of a
+//     preprocessing.
+//
+// Two ciphersuites are available:
+//
+//   - FROST(Ed25519, SHA-512) works on the shares of eddsatss.Key and produces standard 64-byte
+//     Ed25519 signatures, with NewPreprocessing and SignWithNonces;
+//   - FROST(secp256k1, SHA-256) works on the shares of ecdsatss.Key, so that the key of an
+//     ECDSA keygen also signs for Bitcoin, and produces BIP-340 signatures by the x-only key,
+//     or by its BIP-341 taproot output key, with NewBIP340Preprocessing, SignBIP340WithNonces
+//     and SignTaprootWithNonces.
+//
+// Every signature share is checked against the public share of its sender, so that a party
+// sending an invalid one is reported as a culprit of the tss.Error ending the signing.
+package frosttss
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"slices"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+var zero = big.NewInt(0)
+
+// ciphersuite holds the encodings and hash functions of a FROST ciphersuite (RFC 9591,
+// section 6).
+type ciphersuite interface {
+	curve() elliptic.Curve
+	serializePoint(p *crypto.ECPoint) []byte
+	serializeScalar(k *big.Int) []byte
+	validPoint(p *crypto.ECPoint) bool
+	h1(m []byte) *big.Int // binding factors
+	h3(m []byte) *big.Int // nonces
+	h4(m []byte) []byte   // message
+	h5(m []byte) []byte   // commitment list
+
+	// challenge returns the challenge of the signature of msg with the group commitment R
+	// and the group public key pub (RFC 9591, section 4.6).
+	challenge(R, pub *crypto.ECPoint, msg []byte) *big.Int
+	// negatesNonces returns true if the nonces must be negated for R to be the nonce of the
+	// signature, as done by BIP-340 when R has an odd y.
+	negatesNonces(R *crypto.ECPoint) bool
+	// signature returns the signature of msg made of R and z.
+	signature(R *crypto.ECPoint, z *big.Int, msg []byte) *eddsatss.SignatureData
+	// verify returns true if sig is a valid signature by pub.
+	verify(pub *crypto.ECPoint, sig *eddsatss.SignatureData) bool
+}
+
+// littleEndian returns k as size bytes in little-endian order.
+func littleEndian(k *big.Int, size int) []byte {
+	bz := common.PadToLengthBytesInPlace(k.Bytes(), size)
+	slices.Reverse(bz)
+	return bz
+}
+
+// fromLittleEndian returns the integer of the little-endian bytes bz.
+func fromLittleEndian(bz []byte) *big.Int {
+	be := slices.Clone(bz)
+	slices.Reverse(be)
+	return new(big.Int).SetBytes(be)
+}
+
+// getSSID returns the identifier of a FROST session, binding the curve, the parties, the
+// public shares of the key and the session id of params.
+func getSSID(params *tss.Parameters, task string, bigXj []*crypto.ECPoint) ([]byte, error) {
+	ec := params.EC()
+	ssidList := []*big.Int{ec.Params().P, ec.Params().N, ec.Params().Gx, ec.Params().Gy}
+	ssidList = append(ssidList, params.Parties().IDs().Keys()...)
+	values, err := crypto.FlattenECPoints(bigXj)
+	if err != nil {
+		return nil, err
+	}
+	ssidList = append(ssidList, values...)
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(task)))
+	ssidList = append(ssidList, new(big.Int).SetBytes([]byte(params.SessionID())))
+	return common.SHA512_256i(ssidList...).Bytes(), nil
+}
+
+// identifiers returns the FROST identifiers of the parties of keys ks, that is their keys
+// modulo the group order.
+func identifiers(q *big.Int, ks []*big.Int) ([]*big.Int, error) {
+	ids := make([]*big.Int, len(ks))
+	for j, kj := range ks {
+		ids[j] = new(big.Int).Mod(kj, q)
+		if ids[j].Sign() == 0 {
+			return nil, errors.New("party key is zero modulo the group order")
+		}
+	}
+	return ids, nil
+}
+
+// nonceGenerate returns the nonce derived from 32 random bytes and the secret share, so that
+// a bad source of randomness alone does not reveal the nonce (RFC 9591, section 4.1).
+func nonceGenerate(cs ciphersuite, random []byte, secret *big.Int) *big.Int {
+	return cs.h3(append(slices.Clone(random), cs.serializeScalar(secret)...))
+}
+
+// encodeCommitments returns the encoding of the commitment list of the parties of
+// identifiers ids, with hiding commitments ds and binding commitments es, in the ascending
+// order of the identifiers (RFC 9591, section 4.3).
+func encodeCommitments(cs ciphersuite, ids []*big.Int, ds, es []*crypto.ECPoint) []byte {
+	var enc []byte
+	for _, j := range sortedOrder(ids) {
+		enc = append(enc, cs.serializeScalar(ids[j])...)
+		enc = append(enc, cs.serializePoint(ds[j])...)
+		enc = append(enc, cs.serializePoint(es[j])...)
+	}
+	return enc
+}
+
+// sortedOrder returns the indexes of ids in the ascending order of their values.
+func sortedOrder(ids []*big.Int) []int {
+	order := make([]int, len(ids))
+	for j := range order {
+		order[j] = j
+	}
+	slices.SortFunc(order, func(a, b int) int { return ids[a].Cmp(ids[b]) })
+	return order
+}
+
+// bindingFactors returns the binding factor of each party for the signature of msg by the
+// group public key pub (RFC 9591, section 4.4).
+func bindingFactors(cs ciphersuite, pub *crypto.ECPoint, ids []*big.Int, ds, es []*crypto.ECPoint, msg []byte) []*big.Int {
+	prefix := cs.serializePoint(pub)
+	prefix = append(prefix, cs.h4(msg)...)
+	prefix = append(prefix, cs.h5(encodeCommitments(cs, ids, ds, es))...)
+	rhos := make([]*big.Int, len(ids))
+	for j, id := range ids {
+		rhos[j] = cs.h1(append(slices.Clone(prefix), cs.serializeScalar(id)...))
+	}
+	return rhos
+}
+
+// groupCommitment returns R, the sum of Dj + rho_j·Ej for all the parties (RFC 9591,
+// section 4.5).
+func groupCommitment(ds, es []*crypto.ECPoint, rhos []*big.Int) (*crypto.ECPoint, error) {
+	var R *crypto.ECPoint
+	for j := range ds {
+		Rj, err := ds[j].Add(es[j].ScalarMult(rhos[j]))
+		if err != nil {
+			return nil, err
+		}
+		if R == nil {
+			R = Rj
+		} else if R, err = R.Add(Rj); err != nil {
+			return nil, err
+		}
+	}
+	return R, nil
+}
+
+// negatePoint returns -p.
+func negatePoint(p *crypto.ECPoint) *crypto.ECPoint {
+	return p.ScalarMult(new(big.Int).Sub(p.Curve().Params().N, big.NewInt(1)))
+}
+
+// pointFromBytes returns the point of coordinates x and y, if it is valid for cs.
+func pointFromBytes(cs ciphersuite, x, y []byte) (*crypto.ECPoint, error) {
+	p, err := crypto.NewECPoint(cs.curve(), new(big.Int).SetBytes(x), new(big.Int).SetBytes(y))
+	if err != nil {
+		return nil, err
+	}
+	if !cs.validPoint(p) {
+		return nil, errors.New("point is not a valid group element")
+	}
+	return p, nil
+}
@@ -0,0 +1,783 @@
+package frosttss
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/test"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// hubBroker routes messages between parties in a test network.
+// When the protocol calls broker.Receive(msg), it routes the message:
+//   - If msg.From matches this party (outbound): route to destination party's broker
+//   - If msg.From does NOT match this party (inbound): dispatch to local handler
+//
+// If no handler is registered yet for an inbound message, it is queued and
+// delivered as soon as Connect() registers a handler for that type.
+type hubBroker struct {
+	partyIdx int
+	hub      *testHub
+	handlers map[string]tss.MessageReceiver
+	pending  map[string][]*tss.JsonMessage // messages buffered before handler registered
+	mu       sync.Mutex
+}
+
+type testHub struct {
+	brokers []*hubBroker
+}
+
+func newTestHub(n int) *testHub {
+	h := &testHub{
+		brokers: make([]*hubBroker, n),
+	}
+	for i := 0; i < n; i++ {
+		h.brokers[i] = &hubBroker{
+			partyIdx: i,
+			hub:      h,
+			handlers: make(map[string]tss.MessageReceiver),
+			pending:  make(map[string][]*tss.JsonMessage),
+		}
+	}
+	return h
+}
+
+func (b *hubBroker) Connect(typ string, dest tss.MessageReceiver) {
+	b.mu.Lock()
+	b.handlers[typ] = dest
+	// drain any pending messages for this type
+	queued := b.pending[typ]
+	delete(b.pending, typ)
+	b.mu.Unlock()
+
+	for _, msg := range queued {
+		if err := dest.Receive(msg); err != nil {
+			fmt.Printf("hubBroker: error delivering queued message type %s to party %d: %v\n", typ, b.partyIdx, err)
+		}
+	}
+}
+
+func (b *hubBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
+func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx {
+		// outbound from this party: route to destination
+		if msg.To != nil {
+			// P2P: route to specific party
+			return b.hub.brokers[msg.To.Index].Receive(msg)
+		}
+		// broadcast to all other parties
+		for j, broker := range b.hub.brokers {
+			if j == b.partyIdx {
+				continue
+			}
+			if err := broker.Receive(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// inbound to this party: dispatch to handler or queue
+	b.mu.Lock()
+	handler, ok := b.handlers[msg.Type]
+	if !ok {
+		// no handler yet; buffer the message
+		b.pending[msg.Type] = append(b.pending[msg.Type], msg)
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+	return handler.Receive(msg)
+}
+
+// tamperBroker lets a test corrupt the messages of the given type sent by a party.
+type tamperBroker struct {
+	*hubBroker
+	typ    string
+	tamper func(msg *tss.JsonMessage) *tss.JsonMessage
+}
+
+func (b *tamperBroker) Receive(msg *tss.JsonMessage) error {
+	if msg.From.Index == b.partyIdx && strings.HasPrefix(msg.Type, b.typ) && !strings.HasPrefix(msg.Type, b.typ+":echo") {
+		msg = b.tamper(msg)
+	}
+	return b.hubBroker.Receive(msg)
+}
+
+// keyFor returns the key of keys held by the party p.
+func keyFor(t *testing.T, keys []*eddsatss.Key, p *tss.PartyID) *eddsatss.Key {
+	for _, key := range keys {
+		if key.ShareID.Cmp(p.KeyInt()) == 0 {
+			return key
+		}
+	}
+	t.Fatalf("no key for party %s", p)
+	return nil
+}
+
+// subsetIDs returns clones of the parties of pIDs at the given indexes, sorted and reindexed.
+func subsetIDs(pIDs tss.SortedPartyIDs, idx ...int) tss.SortedPartyIDs {
+	var ids tss.UnSortedPartyIDs
+	for _, k := range idx {
+		ids = append(ids, tss.NewPartyID(pIDs[k].Id, pIDs[k].Moniker, pIDs[k].KeyInt()))
+	}
+	return tss.SortPartyIDs(ids)
+}
+
+func runKeygen(t *testing.T, pIDs tss.SortedPartyIDs, threshold int) []*eddsatss.Key {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	keygens := make([]*eddsatss.Keygen, partyCount)
+	for i := range partyCount {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, pIDs[i], partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		kg, err := eddsatss.NewKeygen(context.Background(), params)
+		require.NoError(t, err)
+		keygens[i] = kg
+	}
+	keys := make([]*eddsatss.Key, partyCount)
+	for i, kg := range keygens {
+		select {
+		case keys[i] = <-kg.Done:
+		case err := <-kg.Err:
+			t.Fatalf("party %d keygen error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d keygen timed out", i)
+		}
+	}
+	return keys
+}
+
+func runPreprocessing(t *testing.T, keys []*eddsatss.Key, pIDs tss.SortedPartyIDs, threshold int) []*Nonces {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	preprocessings := make([]*Preprocessing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		pp, err := NewPreprocessing(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		preprocessings[i] = pp
+	}
+	nonces := make([]*Nonces, partyCount)
+	for i, pp := range preprocessings {
+		select {
+		case nonces[i] = <-pp.Done:
+		case err := <-pp.Err:
+			t.Fatalf("party %d preprocessing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d preprocessing timed out", i)
+		}
+	}
+	return nonces
+}
+
+func runSigning(t *testing.T, keys []*eddsatss.Key, nonces []*Nonces, msg []byte, pIDs tss.SortedPartyIDs, threshold int) []*eddsatss.SignatureData {
+	partyCount := len(pIDs)
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	signings := make([]*Signing, partyCount)
+	used := make([][]byte, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		s, err := SignWithNonces(context.Background(), keyFor(t, keys, p), nonces[i], msg, params, func(id []byte) error {
+			used[i] = id
+			return nil
+		})
+		require.NoError(t, err)
+		signings[i] = s
+	}
+	for i := range used {
+		assert.Equal(t, nonces[i].ID, used[i], "party %d should mark its nonces used", i)
+	}
+	sigs := make([]*eddsatss.SignatureData, partyCount)
+	for i, s := range signings {
+		select {
+		case sigs[i] = <-s.Done:
+		case err := <-s.Err:
+			t.Fatalf("party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+	return sigs
+}
+
+// TestRFC9591Vectors checks the ciphersuite and the signing steps against the
+// FROST(Ed25519, SHA-512) test vectors of RFC 9591, appendix E.1.
+func TestRFC9591Vectors(t *testing.T) {
+	cs := ed25519Suite{}
+	ec := cs.curve()
+	modQ := common.ModInt(ec.Params().N)
+	decode := func(s string) []byte {
+		bz, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return bz
+	}
+	scalar := func(s string) *big.Int {
+		return fromLittleEndian(decode(s))
+	}
+
+	secret := scalar("7b1c33d3f5291d85de664833beb1ad469f7fb6025a0ec78b3a790c6e13a98304")
+	coef := scalar("178199860edd8c62f5212ee91eff1295d0d670ab4ed4506866bae57e7030b204")
+	pub := crypto.ScalarBaseMult(ec, secret)
+	assert.Equal(t, "15d21ccd7ee42959562fc8aa63224c8851fb3ec85a3faf66040d380fb9738673", hex.EncodeToString(cs.serializePoint(pub)))
+
+	// shares of participants 1 to 3, f(j) = secret + coef·j
+	shares := []string{
+		"929dcc590407aae7d388761cddb0c0db6f5627aea8e217f4a033f2ec83d93509",
+		"a91e66e012e4364ac9aaa405fcafd370402d9859f7b6685c07eed76bf409e80d",
+		"d3cb090a075eb154e82fdb4b3cb507f110040905468bb9c46da8bdea643a9a02",
+	}
+	for j, share := range shares {
+		expected := modQ.Add(secret, modQ.Mul(coef, big.NewInt(int64(j+1))))
+		assert.Equal(t, share, hex.EncodeToString(cs.serializeScalar(expected)))
+	}
+
+	// participants 1 and 3 sign
+	ids := []*big.Int{big.NewInt(1), big.NewInt(3)}
+	lambda1, err := vss.LagrangeCoefficient(ec.Params().N, ids, 0, big.NewInt(0))
+	require.NoError(t, err)
+	lambda3, err := vss.LagrangeCoefficient(ec.Params().N, ids, 1, big.NewInt(0))
+	require.NoError(t, err)
+	recovered := modQ.Add(modQ.Mul(lambda1, scalar(shares[0])), modQ.Mul(lambda3, scalar(shares[2])))
+	assert.Equal(t, 0, secret.Cmp(recovered))
+
+	// round one of participants 1 and 3
+	hidingRandom := []string{
+		"0fd2e39e111cdc266f6c0f4d0fd45c947761f1f5d3cb583dfcb9bbaf8d4c9fec",
+		"86d64a260059e495d0fb4fcc17ea3da7452391baa494d4b00321098ed2a0062f",
+	}
+	bindingRandom := []string{
+		"69cd85f631d5f7f2721ed5e40519b1366f340a87c2f6856363dbdcda348a7501",
+		"13e6b25afb2eba51716a9a7d44130c0dbae0004a9ef8d7b5550c8a0e07c61775",
+	}
+	hidingNonces := []string{
+		"812d6104142944d5a55924de6d49940956206909f2acaeedecda2b726e630407",
+		"c256de65476204095ebdc01bd11dc10e57b36bc96284595b8215222374f99c0e",
+	}
+	bindingNonces := []string{
+		"b1110165fc2334149750b28dd813a39244f315cff14d4e89e6142f262ed83301",
+		"243d71944d929063bc51205714ae3c2218bd3451d0214dfb5aeec2a90c35180d",
+	}
+	hidingCommitments := []string{
+		"b5aa8ab305882a6fc69cbee9327e5a45e54c08af61ae77cb8207be3d2ce13de3",
+		"cfbdb165bd8aad6eb79deb8d287bcc0ab6658ae57fdcc98ed12c0669e90aec91",
+	}
+	bindingCommitments := []string{
+		"67e98ab55aa310c3120418e5050c9cf76cf387cb20ac9e4b6fdb6f82a469f932",
+		"7487bc41a6e712eea2f2af24681b58b1cf1da278ea11fe4e8b78398965f13552",
+	}
+	signers := []*big.Int{scalar(shares[0]), scalar(shares[2])}
+	hidings, bindings := make([]*big.Int, 2), make([]*big.Int, 2)
+	ds, es := make([]*crypto.ECPoint, 2), make([]*crypto.ECPoint, 2)
+	for n, share := range signers {
+		hidings[n] = nonceGenerate(cs, decode(hidingRandom[n]), share)
+		bindings[n] = nonceGenerate(cs, decode(bindingRandom[n]), share)
+		ds[n] = crypto.ScalarBaseMult(ec, hidings[n])
+		es[n] = crypto.ScalarBaseMult(ec, bindings[n])
+		assert.Equal(t, hidingNonces[n], hex.EncodeToString(cs.serializeScalar(hidings[n])))
+		assert.Equal(t, bindingNonces[n], hex.EncodeToString(cs.serializeScalar(bindings[n])))
+		assert.Equal(t, hidingCommitments[n], hex.EncodeToString(cs.serializePoint(ds[n])))
+		assert.Equal(t, bindingCommitments[n], hex.EncodeToString(cs.serializePoint(es[n])))
+	}
+
+	// round two: binding factors, group commitment and signature shares
+	msg := decode("74657374")
+	sig := decode("36282629c383bb820a88b71cae937d41f2f2adfcc3d02e55507e2fb9e2dd3cbebd9d2b0844e49ae0f3fa935161e1419aab7b47d21a37ebeae1f17d4987b3160b")
+	rhos := bindingFactors(cs, pub, ids, ds, es, msg)
+	assert.Equal(t, "f2cb9d7dd9beff688da6fcc83fa89046b3479417f47f55600b106760eb3b5603", hex.EncodeToString(cs.serializeScalar(rhos[0])))
+	assert.Equal(t, "b087686bf35a13f3dc78e780a34b0fe8a77fef1b9938c563f5573d71d8d7890f", hex.EncodeToString(cs.serializeScalar(rhos[1])))
+	R, err := groupCommitment(ds, es, rhos)
+	require.NoError(t, err)
+	assert.Equal(t, sig[:32], cs.serializePoint(R))
+	c := cs.challenge(R, pub, msg)
+
+	s := &Signing{
+		cs:     cs,
+		bigXj:  []*crypto.ECPoint{crypto.ScalarBaseMult(ec, signers[0]), crypto.ScalarBaseMult(ec, signers[1])},
+		nonces: &Nonces{D: ds, E: es},
+		ids:    ids,
+		rhos:   rhos,
+		R:      R,
+		c:      c,
+	}
+	sigShares := []string{
+		"001719ab5a53ee1a12095cd088fd149702c0720ce5fd2f29dbecf24b7281b603",
+		"bd86125de990acc5e1f13781d8e32c03a9bbd4c53539bbc106058bfd14326007",
+	}
+	z := big.NewInt(0)
+	for n, lambda := range []*big.Int{lambda1, lambda3} {
+		zn := modQ.Add(modQ.Add(hidings[n], modQ.Mul(bindings[n], rhos[n])), modQ.Mul(modQ.Mul(lambda, signers[n]), c))
+		assert.Equal(t, sigShares[n], hex.EncodeToString(cs.serializeScalar(zn)))
+		assert.True(t, s.verifyShare(n, zn))
+		z = modQ.Add(z, zn)
+	}
+	assert.Equal(t, sig, cs.signature(R, z, msg).Signature)
+	assert.True(t, ed25519.Verify(cs.serializePoint(pub), msg, sig))
+}
+
+func TestPreprocessAndSign(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+	pub := ed25519Suite{}.serializePoint(keys[0].EDDSAPub)
+
+	for _, committee := range [][]int{{0, 1, 2}, {0, 2}} {
+		ids := subsetIDs(pIDs, committee...)
+		nonces := runPreprocessing(t, keys, ids, threshold)
+		for i := 1; i < len(ids); i++ {
+			assert.Equal(t, nonces[0].ID, nonces[i].ID)
+		}
+
+		// the nonces survive a json round trip
+		bz, err := json.Marshal(nonces[0])
+		require.NoError(t, err)
+		nonces[0] = new(Nonces)
+		require.NoError(t, json.Unmarshal(bz, nonces[0]))
+
+		msg := []byte(fmt.Sprintf("message for %v", committee))
+		sigs := runSigning(t, keys, nonces, msg, ids, threshold)
+		for i := range sigs {
+			assert.Equal(t, sigs[0].Signature, sigs[i].Signature)
+		}
+		require.Len(t, sigs[0].Signature, 64)
+		assert.True(t, ed25519.Verify(pub, msg, sigs[0].Signature))
+		assert.Equal(t, msg, sigs[0].M)
+
+		// nonces cannot be used twice
+		params := tss.NewParameters(tss.Edwards(), tss.NewPeerContext(ids), ids[0], len(ids), threshold)
+		params.SetBroker(newTestHub(len(ids)).brokers[0])
+		_, err = SignWithNonces(context.Background(), keyFor(t, keys, ids[0]), nonces[0], msg, params, markNothing)
+		assert.ErrorIs(t, err, ErrNoncesUsed)
+	}
+}
+
+// markNothing is a markUsed callback for nonces that are never stored.
+func markNothing([]byte) error { return nil }
+
+func TestSignWithNoncesMarkUsedFails(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+	nonces := runPreprocessing(t, keys, pIDs, threshold)
+
+	params := tss.NewParameters(tss.Edwards(), tss.NewPeerContext(pIDs), pIDs[0], partyCount, threshold)
+	hub := newTestHub(partyCount)
+	params.SetBroker(hub.brokers[0])
+
+	errStorage := errors.New("storage unavailable")
+	_, err := SignWithNonces(context.Background(), keyFor(t, keys, pIDs[0]), nonces[0], []byte("hello"), params, func([]byte) error {
+		return errStorage
+	})
+	assert.ErrorIs(t, err, errStorage)
+	// nothing was sent, and the nonces can still be used
+	hub.brokers[1].mu.Lock()
+	assert.Empty(t, hub.brokers[1].pending)
+	hub.brokers[1].mu.Unlock()
+	assert.NotNil(t, nonces[0].Hiding)
+
+	_, err = SignWithNonces(context.Background(), keyFor(t, keys, pIDs[0]), nonces[0], []byte("hello"), params, nil)
+	assert.Error(t, err)
+	assert.NotNil(t, nonces[0].Hiding)
+}
+
+func TestPreprocessingIdentifiesBadCommitment(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	preprocessings := make([]*Preprocessing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		if i == 1 {
+			// party 1 commits to the identity as binding nonce
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "frost:preprocess:round1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				bad := *msg.Data.(*preprocessMsg)
+				bad.EX, bad.EY = []byte{}, []byte{1}
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		pp, err := NewPreprocessing(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		preprocessings[i] = pp
+	}
+
+	for _, i := range []int{0, 2} {
+		select {
+		case <-preprocessings[i].Done:
+			t.Fatalf("party %d should reject the commitment", i)
+		case err := <-preprocessings[i].Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskPreprocessing, tssErr.Task())
+			assert.Equal(t, 2, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[1].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d preprocessing timed out", i)
+		}
+	}
+}
+
+func TestPreprocessingAlwaysEchoes(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	preprocessings := make([]*Preprocessing, partyCount)
+	for i, p := range pIDs {
+		// echo broadcast is not enabled in params
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		if i == 1 {
+			// party 1 sends another valid hiding commitment to party 2 only
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "frost:preprocess:round1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				if msg.To.Index != 2 {
+					return msg
+				}
+				bad := *msg.Data.(*preprocessMsg)
+				D, err := pointFromBytes(ed25519Suite{}, bad.DX, bad.DY)
+				require.NoError(t, err)
+				D, err = D.Add(crypto.ScalarBaseMult(tss.Edwards(), big.NewInt(1)))
+				require.NoError(t, err)
+				bad.DX, bad.DY = D.X().Bytes(), D.Y().Bytes()
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		pp, err := NewPreprocessing(context.Background(), keyFor(t, keys, p), params)
+		require.NoError(t, err)
+		preprocessings[i] = pp
+	}
+
+	for _, i := range []int{0, 2} {
+		select {
+		case <-preprocessings[i].Done:
+			t.Fatalf("party %d should detect the equivocation", i)
+		case err := <-preprocessings[i].Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.ErrorIs(t, err, tss.ErrEquivocation)
+			assert.Equal(t, 1, tssErr.Round())
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d preprocessing timed out", i)
+		}
+	}
+}
+
+func TestSigningWithOtherNoncesBlamesNoOne(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+	nonces := runPreprocessing(t, keys, pIDs, threshold)
+	// party 2 signs with nonces it believes come from another preprocessing
+	nonces[2].ID = common.SHA512_256(nonces[2].ID)
+
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	signings := make([]*Signing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		s, err := SignWithNonces(context.Background(), keyFor(t, keys, p), nonces[i], []byte("hello"), params, markNothing)
+		require.NoError(t, err)
+		signings[i] = s
+	}
+
+	for i, s := range signings {
+		select {
+		case <-s.Done:
+			t.Fatalf("party %d should reject the signature shares", i)
+		case err := <-s.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, 2, tssErr.Round())
+			assert.Empty(t, tssErr.Culprits())
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+}
+
+func TestSigningIdentifiesBadShare(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	keys := runKeygen(t, pIDs, threshold)
+	nonces := runPreprocessing(t, keys, pIDs, threshold)
+
+	hub := newTestHub(partyCount)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	signings := make([]*Signing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.Edwards(), p2pCtx, p, partyCount, threshold)
+		if i == 2 {
+			// party 2 broadcasts a wrong z
+			params.SetBroker(&tamperBroker{hubBroker: hub.brokers[i], typ: "frost:sign:round1", tamper: func(msg *tss.JsonMessage) *tss.JsonMessage {
+				bad := *msg.Data.(*signMsg)
+				bad.Z = new(big.Int).Add(new(big.Int).SetBytes(bad.Z), big.NewInt(1)).Bytes()
+				return tss.JsonWrap(msg.Type, &bad, msg.From, msg.To)
+			}})
+		} else {
+			params.SetBroker(hub.brokers[i])
+		}
+		s, err := SignWithNonces(context.Background(), keyFor(t, keys, p), nonces[i], []byte("hello"), params, markNothing)
+		require.NoError(t, err)
+		signings[i] = s
+	}
+
+	for i, s := range signings[:2] {
+		select {
+		case <-s.Done:
+			t.Fatalf("party %d should reject the signature share", i)
+		case err := <-s.Err:
+			var tssErr *tss.Error
+			require.ErrorAs(t, err, &tssErr)
+			assert.Equal(t, TaskSigning, tssErr.Task())
+			assert.Equal(t, 2, tssErr.Round())
+			require.Len(t, tssErr.Culprits(), 1)
+			assert.Equal(t, pIDs[2].Id, tssErr.Culprits()[0].Id)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+}
+
+// dealKeys returns the ecdsatss keys of the shares of secret for the parties of pIDs, as made by
+// a trusted dealer.
+func dealKeys(t *testing.T, secret *big.Int, pIDs tss.SortedPartyIDs, threshold int) []*ecdsatss.Key {
+	ec := tss.S256()
+	_, shares, err := vss.Create(ec, threshold, secret, pIDs.Keys(), rand.Reader)
+	require.NoError(t, err)
+	keys := make([]*ecdsatss.Key, len(pIDs))
+	for i := range pIDs {
+		keys[i] = ecdsatss.NewKey(len(pIDs))
+		keys[i].Xi, keys[i].ShareID = shares[i].Share, shares[i].ID
+		keys[i].ECDSAPub = crypto.ScalarBaseMult(ec, secret)
+		for j := range pIDs {
+			keys[i].Ks[j] = shares[j].ID
+			keys[i].BigXj[j] = crypto.ScalarBaseMult(ec, shares[j].Share)
+		}
+	}
+	return keys
+}
+
+func runBIP340(t *testing.T, keys []*ecdsatss.Key, pIDs tss.SortedPartyIDs, threshold int, sign func(key *ecdsatss.Key, nonces *Nonces, params *tss.Parameters) (*Signing, error)) []*eddsatss.SignatureData {
+	partyCount := len(pIDs)
+	p2pCtx := tss.NewPeerContext(pIDs)
+	hub := newTestHub(partyCount)
+	preprocessings := make([]*Preprocessing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		pp, err := NewBIP340Preprocessing(context.Background(), ecdsaKeyFor(t, keys, p), params)
+		require.NoError(t, err)
+		preprocessings[i] = pp
+	}
+	nonces := make([]*Nonces, partyCount)
+	for i, pp := range preprocessings {
+		select {
+		case nonces[i] = <-pp.Done:
+		case err := <-pp.Err:
+			t.Fatalf("party %d preprocessing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d preprocessing timed out", i)
+		}
+	}
+
+	hub = newTestHub(partyCount)
+	signings := make([]*Signing, partyCount)
+	for i, p := range pIDs {
+		params := tss.NewParameters(tss.S256(), p2pCtx, p, partyCount, threshold)
+		params.SetBroker(hub.brokers[i])
+		s, err := sign(ecdsaKeyFor(t, keys, p), nonces[i], params)
+		require.NoError(t, err)
+		signings[i] = s
+	}
+	sigs := make([]*eddsatss.SignatureData, partyCount)
+	for i, s := range signings {
+		select {
+		case sigs[i] = <-s.Done:
+		case err := <-s.Err:
+			t.Fatalf("party %d signing error: %v", i, err)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("party %d signing timed out", i)
+		}
+	}
+	for i := range sigs {
+		assert.Equal(t, sigs[0].Signature, sigs[i].Signature)
+	}
+	return sigs
+}
+
+// ecdsaKeyFor returns the key of keys held by the party p.
+func ecdsaKeyFor(t *testing.T, keys []*ecdsatss.Key, p *tss.PartyID) *ecdsatss.Key {
+	for _, key := range keys {
+		if key.ShareID.Cmp(p.KeyInt()) == 0 {
+			return key
+		}
+	}
+	t.Fatalf("no key for party %s", p)
+	return nil
+}
+
+// TestBIP340Vectors checks VerifyBIP340 with test vectors of BIP-340.
+func TestBIP340Vectors(t *testing.T) {
+	for _, v := range []struct {
+		secret, pub, msg, sig string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca821525f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0",
+		},
+		{
+			"b7e151628aed2a6abf7158809cf4f3c762e7160f38b4da56a784d9045190cfef",
+			"dff1d77f2a671c5f36183726db2341be58feae1da2deced843240f7b502ba659",
+			"243f6a8885a308d313198a2e03707344a4093822299f31d0082efa98ec4e6c89",
+			"6896bd60eeae296db48a229ff71dfe071bde413e6d43f917dc8dcf8c78de33418906d11ac976abccb20b091292bff4ea897efcb639ea871cfa95f6de339e4b0a",
+		},
+	} {
+		secret, _ := new(big.Int).SetString(v.secret, 16)
+		pub, msg, sig := mustDecode(t, v.pub), mustDecode(t, v.msg), mustDecode(t, v.sig)
+		assert.Equal(t, pub, xOnly(crypto.ScalarBaseMult(tss.S256(), secret)))
+		assert.True(t, VerifyBIP340(pub, msg, sig))
+		sig[63] ^= 1
+		assert.False(t, VerifyBIP340(pub, msg, sig))
+	}
+}
+
+// TestExpandMessageXMD checks expandMessageXMD with test vectors of RFC 9380, appendix K.1.
+func TestExpandMessageXMD(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	assert.Equal(t, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235", hex.EncodeToString(expandMessageXMD(nil, dst, 0x20)))
+	assert.Equal(t, "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615", hex.EncodeToString(expandMessageXMD([]byte("abc"), dst, 0x20)))
+	assert.Equal(t, "af84c27ccfd45d41914fdff5df25293e221afc53d8ad2ac06d5e3e29485dadbee0d121587713a3e0dd4d5e69e93eb7cd4f5df4cd103e188cf60cb02edc3edf18eda8576c412b18ffb658e3dd6ec849469b979d444cf7b26911a08e63cf31f9dcc541708d3491184472c2c29bb749d4286b004ceb5ee6b9a7fa5b646c993f0ced", hex.EncodeToString(expandMessageXMD(nil, dst, 0x80)))
+}
+
+// TestTaprootOutputKey checks TaprootOutputKey with the first receiving address of the test
+// vectors of BIP-86, a key with no script path.
+func TestTaprootOutputKey(t *testing.T) {
+	P, err := liftX(new(big.Int).SetBytes(mustDecode(t, "cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115")))
+	require.NoError(t, err)
+	Q, err := TaprootOutputKey(P, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c", hex.EncodeToString(Q))
+}
+
+func TestBIP340Sign(t *testing.T) {
+	const (
+		partyCount = 3
+		threshold  = 1
+	)
+	pIDs := tss.GenerateTestPartyIDs(partyCount)
+	ec := tss.S256()
+	merkleRoot := mustDecode(t, "53a1f6e454df1aa2776a2814a721372d6258050de330b3c6d10ee8f4e0dda343")
+	msg := []byte("taproot sighash")
+
+	// public keys with an even and an odd y
+	secret := common.GetRandomPositiveInt(rand.Reader, ec.Params().N)
+	for _, odd := range []uint{0, 1} {
+		if crypto.ScalarBaseMult(ec, secret).Y().Bit(0) != odd {
+			secret = new(big.Int).Sub(ec.Params().N, secret)
+		}
+		keys := dealKeys(t, secret, pIDs, threshold)
+		committee := subsetIDs(pIDs, 0, 2)
+
+		sigs := runBIP340(t, keys, committee, threshold, func(key *ecdsatss.Key, nonces *Nonces, params *tss.Parameters) (*Signing, error) {
+			return SignBIP340WithNonces(context.Background(), key, nonces, msg, params, markNothing)
+		})
+		require.Len(t, sigs[0].Signature, 64)
+		assert.True(t, VerifyBIP340(xOnly(keys[0].ECDSAPub), msg, sigs[0].Signature), "odd=%d", odd)
+
+		for _, root := range [][]byte{nil, merkleRoot} {
+			sigs = runBIP340(t, keys, committee, threshold, func(key *ecdsatss.Key, nonces *Nonces, params *tss.Parameters) (*Signing, error) {
+				return SignTaprootWithNonces(context.Background(), key, root, nonces, msg, params, markNothing)
+			})
+			outputKey, err := TaprootOutputKey(keys[0].ECDSAPub, root)
+			require.NoError(t, err)
+			assert.True(t, VerifyBIP340(outputKey, msg, sigs[0].Signature), "odd=%d root=%x", odd, root)
+		}
+	}
+}
+
+// TestBIP340SignEcdsatssKeys signs with the keys of the ecdsatss fixtures, so that the key of an
+// ECDSA keygen also makes Schnorr signatures.
+func TestBIP340SignEcdsatssKeys(t *testing.T) {
+	const threshold = test.TestThreshold
+	keys := make([]*ecdsatss.Key, threshold+1)
+	ids := make(tss.UnSortedPartyIDs, len(keys))
+	for i := range keys {
+		buf, err := os.ReadFile(fmt.Sprintf("../test/_ecdsa_fixtures/keygen_data_%d.json", i))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(buf, &keys[i]))
+		ids[i] = tss.NewPartyID(fmt.Sprintf("%d", i+1), fmt.Sprintf("P[%d]", i+1), keys[i].ShareID)
+	}
+	pIDs := tss.SortPartyIDs(ids)
+
+	msg := []byte("schnorr with an ecdsa key")
+	sigs := runBIP340(t, keys, pIDs, threshold, func(key *ecdsatss.Key, nonces *Nonces, params *tss.Parameters) (*Signing, error) {
+		return SignTaprootWithNonces(context.Background(), key, nil, nonces, msg, params, markNothing)
+	})
+	outputKey, err := TaprootOutputKey(keys[0].ECDSAPub, nil)
+	require.NoError(t, err)
+	assert.True(t, VerifyBIP340(outputKey, msg, sigs[0].Signature))
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	bz, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return bz
+}
@@ -0,0 +1,19 @@
+package frosttss
+
+// messages for preprocessing and signing
+
+// preprocessMsg is a broadcast message containing the commitments D_i and E_i to the hiding
+// and binding nonces of the sender.
+type preprocessMsg struct {
+	DX []byte `json:"d_x"`
+	DY []byte `json:"d_y"`
+	EX []byte `json:"e_x"`
+	EY []byte `json:"e_y"`
+}
+
+// signMsg is a broadcast message containing the signature share made with the nonces of a
+// preprocessing.
+type signMsg struct {
+	ID []byte `json:"id"`
+	Z  []byte `json:"z"`
+}
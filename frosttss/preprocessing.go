@@ -0,0 +1,260 @@
+package frosttss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskPreprocessing is the task name reported in errors from Preprocessing.
+const TaskPreprocessing = "frost-preprocessing"
+
+// ErrNoncesUsed is returned when signing with nonces that were already used to sign.
+var ErrNoncesUsed = errors.New("nonces were already used")
+
+// Nonces holds the result of preprocessing: the hiding and binding nonces of this party and
+// the commitments to the nonces of every party of the committee. It can be persisted as json
+// and used to produce exactly one signature, with the same committee.
+//
+// Nonces are as sensitive as a key share: signing two messages with the same nonces reveals
+// the share. Signing clears them before use, once its markUsed callback recorded the use, for
+// instance by deleting the stored copies.
+type Nonces struct {
+	ID      []byte            // identifies the nonces, the same for all the parties
+	Ks      []*big.Int        // keys of the parties of the committee, in order
+	Hiding  *big.Int          // hiding nonce d_i
+	Binding *big.Int          // binding nonce e_i
+	D       []*crypto.ECPoint // commitment Dj = dj·G to the hiding nonce of each party
+	E       []*crypto.ECPoint // commitment Ej = ej·G to the binding nonce of each party
+
+	lock sync.Mutex
+}
+
+// Preprocessing tracks the preprocessing round of FROST (RFC 9591, section 5.1), in which
+// every party of the committee commits to a pair of nonces.
+//
+// The commitments are always followed by an echo round, whatever the setting of
+// params.EchoBroadcast: the ID of the nonces and the binding factors depend on the whole
+// commitment list, so that a party sending different commitments to different peers would
+// otherwise make the signature shares of honest parties fail to verify.
+type Preprocessing struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	cs     ciphersuite
+	ssid   []byte
+	ks     []*big.Int
+
+	hiding, binding *big.Int
+	ds, es          []*crypto.ECPoint
+
+	Done chan *Nonces
+	Err  chan error
+}
+
+// NewPreprocessing creates a new Preprocessing for the eddsatss key, with the committee of the
+// parties of params, and executes its round. The resulting Nonces are used by SignWithNonces
+// to sign a message with the same committee.
+func NewPreprocessing(ctx context.Context, key *eddsatss.Key, params *tss.Parameters) (*Preprocessing, error) {
+	if key.MoreKs != nil {
+		return nil, eddsatss.ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, eddsatss.ErrHierarchicalKey
+	}
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	return newPreprocessing(ctx, ed25519Suite{}, key.Xi, key.Ks, key.BigXj, params)
+}
+
+// NewBIP340Preprocessing creates a new Preprocessing for the secp256k1 key of ecdsatss or
+// cggmptss, with the committee of the parties of params, and executes its round. The resulting
+// Nonces are used by SignBIP340WithNonces or SignTaprootWithNonces.
+func NewBIP340Preprocessing(ctx context.Context, key *ecdsatss.Key, params *tss.Parameters) (*Preprocessing, error) {
+	if key.MoreKs != nil {
+		return nil, ecdsatss.ErrWeightedKey
+	}
+	if key.Ranks != nil {
+		return nil, ecdsatss.ErrHierarchicalKey
+	}
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	return newPreprocessing(ctx, bip340Suite{}, key.Xi, key.Ks, key.BigXj, params)
+}
+
+func newPreprocessing(ctx context.Context, cs ciphersuite, xi *big.Int, ks []*big.Int, bigXj []*crypto.ECPoint, params *tss.Parameters) (*Preprocessing, error) {
+	if params.EC() != cs.curve() {
+		return nil, errors.New("curve of params does not match the ciphersuite")
+	}
+	if params.PartyCount() <= params.Threshold() {
+		return nil, fmt.Errorf("signing needs more than %d parties, got %d", params.Threshold(), params.PartyCount())
+	}
+	if _, err := identifiers(cs.curve().Params().N, ks); err != nil {
+		return nil, err
+	}
+	ssid, err := getSSID(params, TaskPreprocessing, bigXj)
+	if err != nil {
+		return nil, err
+	}
+	partyCount := params.PartyCount()
+	p := &Preprocessing{
+		ctx:    ctx,
+		params: params,
+		cs:     cs,
+		ssid:   ssid,
+		ks:     ks,
+		ds:     make([]*crypto.ECPoint, partyCount),
+		es:     make([]*crypto.ECPoint, partyCount),
+		Done:   make(chan *Nonces, 1),
+		Err:    make(chan error, 1),
+	}
+	p.broker = tss.NewSessionBroker(params.Broker())
+	p.stop = context.AfterFunc(ctx, func() { p.fail(ctx.Err()) })
+	if err := p.round1(xi); err != nil {
+		p.release()
+		return nil, err
+	}
+	return p, nil
+}
+
+// round1 generates the nonces of this party and broadcasts their commitments.
+func (p *Preprocessing) round1(xi *big.Int) error {
+	Pi := p.params.PartyID()
+	i := Pi.Index
+	ec := p.cs.curve()
+
+	random := make([]byte, 32)
+	if _, err := io.ReadFull(p.params.Rand(), random); err != nil {
+		return err
+	}
+	p.hiding = nonceGenerate(p.cs, random, xi)
+	if _, err := io.ReadFull(p.params.Rand(), random); err != nil {
+		return err
+	}
+	p.binding = nonceGenerate(p.cs, random, xi)
+	p.ds[i] = crypto.ScalarBaseMult(ec, p.hiding)
+	p.es[i] = crypto.ScalarBaseMult(ec, p.binding)
+
+	otherIds := p.params.Parties().IDs().Exclude(Pi)
+	r1msg := &preprocessMsg{
+		DX: p.ds[i].X().Bytes(),
+		DY: p.ds[i].Y().Bytes(),
+		EX: p.es[i].X().Bytes(),
+		EY: p.es[i].Y().Bytes(),
+	}
+	for _, Pj := range otherIds {
+		p.broker.Receive(tss.JsonWrap(p.params.MsgType("frost:preprocess:round1"), r1msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[preprocessMsg](p.params.MsgType("frost:preprocess:round1"), otherIds, p.round2, p.timeout(1), p.echo(1, "frost:preprocess:round1", otherIds))
+	p.broker.Connect(p.params.MsgType("frost:preprocess:round1"), rcv)
+	return nil
+}
+
+// round2 checks the commitments of the other parties and outputs the nonces.
+func (p *Preprocessing) round2(otherIds []*tss.PartyID, msgs []*preprocessMsg) {
+	if p.ctx.Err() != nil {
+		p.fail(p.ctx.Err())
+		return
+	}
+
+	var culprits []*tss.PartyID
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		D, errD := pointFromBytes(p.cs, msgs[n].DX, msgs[n].DY)
+		E, errE := pointFromBytes(p.cs, msgs[n].EX, msgs[n].EY)
+		if errD != nil || errE != nil {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		p.ds[j], p.es[j] = D, E
+	}
+	if len(culprits) > 0 {
+		p.fail(p.wrapError(2, errors.New("invalid nonce commitment"), culprits...))
+		return
+	}
+
+	ids, err := identifiers(p.cs.curve().Params().N, p.ks)
+	if err != nil {
+		p.fail(p.wrapError(2, err))
+		return
+	}
+	nonces := &Nonces{
+		ID:      common.SHA512_256(p.ssid, encodeCommitments(p.cs, ids, p.ds, p.es)),
+		Ks:      p.ks,
+		Hiding:  p.hiding,
+		Binding: p.binding,
+		D:       p.ds,
+		E:       p.es,
+	}
+	p.hiding, p.binding = nil, nil
+
+	p.release()
+	p.Done <- nonces
+}
+
+// take returns the hiding and binding nonces once markUsed recorded that the nonces are used,
+// and clears them so that the nonces cannot be used again.
+func (nonces *Nonces) take(markUsed func(id []byte) error) (d, e *big.Int, err error) {
+	nonces.lock.Lock()
+	defer nonces.lock.Unlock()
+	if nonces.Hiding == nil || nonces.Binding == nil {
+		return nil, nil, ErrNoncesUsed
+	}
+	if err := markUsed(nonces.ID); err != nil {
+		return nil, nil, fmt.Errorf("failed to mark the nonces as used: %w", err)
+	}
+	d, e = nonces.Hiding, nonces.Binding
+	nonces.Hiding, nonces.Binding = nil, nil
+	return d, e, nil
+}
+
+// fail reports err on Err and releases the receivers registered by this preprocessing.
+func (p *Preprocessing) fail(err error) {
+	p.release()
+	select {
+	case p.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this preprocessing from the broker.
+func (p *Preprocessing) release() {
+	p.stop()
+	p.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (p *Preprocessing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(p.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		p.fail(p.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking that the broadcast messages of the given type were the
+// same for all of peers. It is used whether echo broadcast is enabled or not.
+func (p *Preprocessing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	return tss.WithEcho(p.broker, p.params.MsgType(typ+":echo"), p.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		p.fail(p.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (p *Preprocessing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskPreprocessing, round, p.params.PartyID(), culprits...)
+}
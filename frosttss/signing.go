@@ -0,0 +1,291 @@
+package frosttss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/KarpelesLab/tss-lib/v2/common"
+	"github.com/KarpelesLab/tss-lib/v2/crypto"
+	"github.com/KarpelesLab/tss-lib/v2/crypto/vss"
+	"github.com/KarpelesLab/tss-lib/v2/ecdsatss"
+	"github.com/KarpelesLab/tss-lib/v2/eddsatss"
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// TaskSigning is the task name reported in errors from Signing.
+const TaskSigning = "frost-signing"
+
+// Signing tracks the signing of a message with the nonces of a preprocessing (RFC 9591,
+// section 5.2 and 5.3).
+type Signing struct {
+	ctx    context.Context
+	params *tss.Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
+	cs     ciphersuite
+	bigXj  []*crypto.ECPoint
+	pub    *crypto.ECPoint
+	nonces *Nonces
+	msg    []byte
+
+	ids   []*big.Int // identifiers of the parties
+	rhos  []*big.Int // binding factors of the parties
+	R     *crypto.ECPoint
+	negR  bool // whether the nonces were negated to use R
+	c     *big.Int
+	tweak *big.Int // added to the key, nil if none
+	z     *big.Int
+
+	Done chan *eddsatss.SignatureData
+	Err  chan error
+}
+
+// SignWithNonces signs msg with the eddsatss key and nonces made by NewPreprocessing, in a
+// single round exchanging the signature shares. The parties of params must be the committee
+// of the nonces.
+//
+// markUsed is called with the ID of the nonces before the signature share is computed, and
+// must durably record that they are used, for instance by deleting their stored copies: nonces
+// restored from storage and used again would reveal the share. If markUsed fails, nothing is
+// sent and the nonces can be used again. Otherwise the nonces are consumed even if signing
+// fails afterwards.
+//
+// The result is a standard Ed25519 signature of msg by key.EDDSAPub. Each signature share is
+// checked against the commitments and the public share of its sender, so that a party sending
+// an invalid one is reported as a culprit of round 2. Shares made with nonces of another
+// preprocessing end the signing without culprits, as the nonces used are not attributable.
+func SignWithNonces(ctx context.Context, key *eddsatss.Key, nonces *Nonces, msg []byte, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	return newSigning(ctx, ed25519Suite{}, key.Xi, key.BigXj, key.EDDSAPub, nil, nonces, msg, params, markUsed)
+}
+
+// SignBIP340WithNonces signs msg with the secp256k1 key of ecdsatss or cggmptss and nonces made
+// by NewBIP340Preprocessing, in a single round exchanging the signature shares. The parties of
+// params must be the committee of the nonces, and markUsed must durably record their use as
+// for SignWithNonces.
+//
+// The result is a BIP-340 signature by the x-only public key x(key.ECDSAPub): when
+// key.ECDSAPub has an odd y, the shares are negated so that the signing key has an even y.
+func SignBIP340WithNonces(ctx context.Context, key *ecdsatss.Key, nonces *Nonces, msg []byte, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	return signBIP340(ctx, key, false, nil, nonces, msg, params, markUsed)
+}
+
+// SignTaprootWithNonces is like SignBIP340WithNonces, but signs for a key path spend of the
+// taproot output of BIP-341 made of the internal key key.ECDSAPub and the script tree
+// merkleRoot, nil if there is none. The signature is valid for the output key returned by
+// TaprootOutputKey.
+func SignTaprootWithNonces(ctx context.Context, key *ecdsatss.Key, merkleRoot []byte, nonces *Nonces, msg []byte, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	return signBIP340(ctx, key, true, merkleRoot, nonces, msg, params, markUsed)
+}
+
+func signBIP340(ctx context.Context, key *ecdsatss.Key, taproot bool, merkleRoot []byte, nonces *Nonces, msg []byte, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	key, err := key.SubsetForParties(params.Parties().IDs())
+	if err != nil {
+		return nil, err
+	}
+	xi, bigXj, pub, tweak, err := bip340Key(key, taproot, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+	return newSigning(ctx, bip340Suite{}, xi, bigXj, pub, tweak, nonces, msg, params, markUsed)
+}
+
+// newSigning starts the signing of msg with the share xi of the key pub, and the public shares
+// bigXj of the committee. When tweak is not nil, the signature is made for pub, which must be
+// the key of the shares plus tweak·G.
+func newSigning(ctx context.Context, cs ciphersuite, xi *big.Int, bigXj []*crypto.ECPoint, pub *crypto.ECPoint, tweak *big.Int, nonces *Nonces, msg []byte, params *tss.Parameters, markUsed func(id []byte) error) (*Signing, error) {
+	ec := cs.curve()
+	q := ec.Params().N
+	if params.EC() != ec {
+		return nil, errors.New("curve of params does not match the ciphersuite")
+	}
+	if markUsed == nil {
+		return nil, errors.New("nonces can only be used with a markUsed callback")
+	}
+	ids := params.Parties().IDs()
+	if len(ids) != len(nonces.Ks) || len(nonces.D) != len(ids) || len(nonces.E) != len(ids) {
+		return nil, fmt.Errorf("nonces were made by %d parties, not %d", len(nonces.Ks), len(ids))
+	}
+	for j, id := range ids {
+		if id.KeyInt().Cmp(nonces.Ks[j]) != 0 {
+			return nil, fmt.Errorf("party %s is not part of the nonces committee", id)
+		}
+		if !cs.validPoint(nonces.D[j]) || !cs.validPoint(nonces.E[j]) {
+			return nil, fmt.Errorf("nonce commitment of party %s is invalid", id)
+		}
+	}
+	idents, err := identifiers(q, nonces.Ks)
+	if err != nil {
+		return nil, err
+	}
+	i := params.PartyID().Index
+	lambda, err := vss.LagrangeCoefficient(q, idents, i, big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+	d, e, err := nonces.take(markUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// z_i = d_i + e_i·rho_i + lambda_i·s_i·c
+	modQ := common.ModInt(q)
+	rhos := bindingFactors(cs, pub, idents, nonces.D, nonces.E, msg)
+	R, err := groupCommitment(nonces.D, nonces.E, rhos)
+	if err != nil {
+		return nil, err
+	}
+	negR := cs.negatesNonces(R)
+	if negR {
+		d, e = modQ.Sub(zero, d), modQ.Sub(zero, e)
+	}
+	c := cs.challenge(R, pub, msg)
+	s := &Signing{
+		ctx:    ctx,
+		params: params,
+		cs:     cs,
+		bigXj:  bigXj,
+		pub:    pub,
+		nonces: nonces,
+		msg:    slices.Clone(msg),
+		ids:    idents,
+		rhos:   rhos,
+		R:      R,
+		negR:   negR,
+		c:      c,
+		tweak:  tweak,
+		z:      modQ.Add(modQ.Add(d, modQ.Mul(e, rhos[i])), modQ.Mul(modQ.Mul(lambda, xi), c)),
+		Done:   make(chan *eddsatss.SignatureData, 1),
+		Err:    make(chan error, 1),
+	}
+	s.broker = tss.NewSessionBroker(params.Broker())
+	s.stop = context.AfterFunc(ctx, func() { s.fail(ctx.Err()) })
+
+	Pi := params.PartyID()
+	otherIds := ids.Exclude(Pi)
+	r1msg := &signMsg{ID: nonces.ID, Z: s.z.Bytes()}
+	for _, Pj := range otherIds {
+		s.broker.Receive(tss.JsonWrap(params.MsgType("frost:sign:round1"), r1msg, Pi, Pj))
+	}
+
+	rcv := tss.NewJsonExpect[signMsg](params.MsgType("frost:sign:round1"), otherIds, s.round2, s.timeout(1), s.echo(1, "frost:sign:round1", otherIds))
+	s.broker.Connect(params.MsgType("frost:sign:round1"), rcv)
+	return s, nil
+}
+
+// round2 checks the signature shares of the other parties and aggregates them.
+func (s *Signing) round2(otherIds []*tss.PartyID, msgs []*signMsg) {
+	if s.ctx.Err() != nil {
+		s.fail(s.ctx.Err())
+		return
+	}
+	ec := s.cs.curve()
+	q := ec.Params().N
+	modQ := common.ModInt(q)
+
+	// the commitments were echoed in preprocessing, so a share sent with another ID was made
+	// with the nonces of another preprocessing, which is not attributable to its sender
+	for n := range otherIds {
+		if !bytes.Equal(msgs[n].ID, s.nonces.ID) {
+			s.fail(s.wrapError(2, errors.New("signature shares were made with different nonces")))
+			return
+		}
+	}
+
+	// z_j·G = Dj + rho_j·Ej + (c·lambda_j)·Xj (RFC 9591, section 5.4)
+	var culprits []*tss.PartyID
+	z := new(big.Int).Set(s.z)
+	for n, Pj := range otherIds {
+		j := Pj.Index
+		zj := new(big.Int).SetBytes(msgs[n].Z)
+		if zj.Cmp(q) >= 0 || !s.verifyShare(j, zj) {
+			culprits = append(culprits, Pj)
+			continue
+		}
+		z = modQ.Add(z, zj)
+	}
+	if len(culprits) > 0 {
+		s.fail(s.wrapError(2, errors.New("signature share verification failed"), culprits...))
+		return
+	}
+
+	if s.tweak != nil {
+		z = modQ.Add(z, modQ.Mul(s.c, s.tweak))
+	}
+	sigData := s.cs.signature(s.R, z, s.msg)
+	if !s.cs.verify(s.pub, sigData) {
+		s.fail(s.wrapError(2, errors.New("signature verification failed")))
+		return
+	}
+
+	s.release()
+	s.Done <- sigData
+}
+
+// verifyShare returns true if zj is the signature share expected from the party of index j.
+func (s *Signing) verifyShare(j int, zj *big.Int) bool {
+	ec := s.cs.curve()
+	q := ec.Params().N
+	lambda, err := vss.LagrangeCoefficient(q, s.ids, j, big.NewInt(0))
+	if err != nil {
+		return false
+	}
+	commShare, err := s.nonces.D[j].Add(s.nonces.E[j].ScalarMult(s.rhos[j]))
+	if err != nil {
+		return false
+	}
+	if s.negR {
+		commShare = negatePoint(commShare)
+	}
+	expected, err := commShare.Add(s.bigXj[j].ScalarMult(common.ModInt(q).Mul(s.c, lambda)))
+	if err != nil {
+		return false
+	}
+	return crypto.ScalarBaseMult(ec, zj).Equals(expected)
+}
+
+// fail reports err on Err and releases the receivers registered by this signing.
+func (s *Signing) fail(err error) {
+	s.release()
+	select {
+	case s.Err <- err:
+	default:
+	}
+}
+
+// release disconnects the receivers registered by this signing from the broker.
+func (s *Signing) release() {
+	s.stop()
+	s.broker.Close()
+}
+
+// timeout returns the option reporting the parties that did not send their message for the
+// given round in time.
+func (s *Signing) timeout(round int) tss.ExpectOption {
+	return tss.WithTimeout(s.params.RoundTimeout(), func(missing []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrRoundTimeout, missing...))
+	})
+}
+
+// echo returns the option checking, when echo broadcast is enabled, that the broadcast
+// messages of the given type were the same for all of peers.
+func (s *Signing) echo(round int, typ string, peers []*tss.PartyID) tss.ExpectOption {
+	if !s.params.EchoBroadcast() {
+		return nil
+	}
+	return tss.WithEcho(s.broker, s.params.MsgType(typ+":echo"), s.params.PartyID(), peers, func(culprits []*tss.PartyID) {
+		s.fail(s.wrapError(round, tss.ErrEquivocation, culprits...))
+	})
+}
+
+// wrapError returns err as a failure of the given round, blaming culprits.
+func (s *Signing) wrapError(round int, err error, culprits ...*tss.PartyID) error {
+	return tss.NewError(err, TaskSigning, round, s.params.PartyID(), culprits...)
+}
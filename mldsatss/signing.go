@@ -79,6 +79,8 @@ func (p *Parameters) msgType(base string) string {
 type Signing44 struct {
 	ctx    context.Context
 	params *Parameters
+	broker *tss.SessionBroker
+	stop   func() bool
 	key    *Key44
 	msg    []byte
 	msgCtx []byte
@@ -153,10 +155,13 @@ func NewSigning44(ctx context.Context, params *Parameters, key *Key44, msg, msgC
 		Done:      make(chan *SignatureData, 1),
 		Err:       make(chan error, 1),
 	}
+	s.broker = tss.NewSessionBroker(params.broker)
+	s.stop = context.AfterFunc(ctx, func() { s.fail(ctx.Err()) })
 
 	// Round 1 both broadcasts and installs its own receiver (the latter after
 	// broadcast, so other parties' messages can be queued in the interim).
 	if err := s.round1(); err != nil {
+		s.release()
 		return nil, err
 	}
 	return s, nil
@@ -239,7 +244,7 @@ func (s *Signing44) round1() error {
 	msg := &signRound1msg44{Commit: commit}
 	others := s.otherPartyIDs()
 	for _, pj := range others {
-		if err := s.params.broker.Receive(tss.JsonWrap(
+		if err := s.broker.Receive(tss.JsonWrap(
 			s.params.msgType(MsgTypeR1_44), msg, s.params.partyID, pj,
 		)); err != nil {
 			return fmt.Errorf("mldsatss: round1 broadcast failed: %w", err)
@@ -249,7 +254,7 @@ func (s *Signing44) round1() error {
 	// Register the Round 1 receiver after broadcast. Queued messages from
 	// parties that already ran round1 will be replayed immediately.
 	atomic.StoreInt32(&s.pending2, 1)
-	s.params.broker.Connect(s.params.msgType(MsgTypeR1_44),
+	s.broker.Connect(s.params.msgType(MsgTypeR1_44),
 		tss.NewJsonExpect[signRound1msg44](s.params.msgType(MsgTypeR1_44), others, s.onR1))
 	return nil
 }
@@ -316,7 +321,7 @@ func (s *Signing44) round2() {
 	msg := &signRound2msg44{Wbuf: s.wbuf}
 	others := s.otherPartyIDs()
 	for _, pj := range others {
-		if err := s.params.broker.Receive(tss.JsonWrap(
+		if err := s.broker.Receive(tss.JsonWrap(
 			s.params.msgType(MsgTypeR2_44), msg, s.params.partyID, pj,
 		)); err != nil {
 			s.fail(fmt.Errorf("mldsatss: round2 broadcast failed: %w", err))
@@ -325,7 +330,7 @@ func (s *Signing44) round2() {
 	}
 
 	atomic.StoreInt32(&s.pending3, 1)
-	s.params.broker.Connect(s.params.msgType(MsgTypeR2_44),
+	s.broker.Connect(s.params.msgType(MsgTypeR2_44),
 		tss.NewJsonExpect[signRound2msg44](s.params.msgType(MsgTypeR2_44), others, s.onR2))
 }
 
@@ -433,7 +438,7 @@ func (s *Signing44) round3() {
 	msg := &signRound3msg44{Resp: respBuf}
 	others := s.otherPartyIDs()
 	for _, pj := range others {
-		if err := s.params.broker.Receive(tss.JsonWrap(
+		if err := s.broker.Receive(tss.JsonWrap(
 			s.params.msgType(MsgTypeR3_44), msg, s.params.partyID, pj,
 		)); err != nil {
 			s.fail(fmt.Errorf("mldsatss: round3 broadcast failed: %w", err))
@@ -441,7 +446,7 @@ func (s *Signing44) round3() {
 		}
 	}
 
-	s.params.broker.Connect(s.params.msgType(MsgTypeR3_44),
+	s.broker.Connect(s.params.msgType(MsgTypeR3_44),
 		tss.NewJsonExpect[signRound3msg44](s.params.msgType(MsgTypeR3_44), others, s.onR3))
 }
 
@@ -606,6 +611,7 @@ func (s *Signing44) combine() {
 		copy(sigBuf[off:], mldsa.PackHint44(hints[:]))
 
 		// Emit signature and stop.
+		s.release()
 		s.Done <- &SignatureData{Signature: append([]byte(nil), sigBuf...)}
 		return
 	}
@@ -667,23 +673,27 @@ func makeHintLowBits44(z0, r1 uint32) uint32 {
 // bail returns true if the context is cancelled; in that case it also sends to Err.
 func (s *Signing44) bail() bool {
 	if err := s.ctx.Err(); err != nil {
-		select {
-		case s.Err <- err:
-		default:
-		}
+		s.fail(err)
 		return true
 	}
 	return false
 }
 
-// fail sends an error to Err (non-blocking).
+// fail releases the broker receivers and sends an error to Err (non-blocking).
 func (s *Signing44) fail(err error) {
+	s.release()
 	select {
 	case s.Err <- err:
 	default:
 	}
 }
 
+// release disconnects the receivers registered by this attempt from the broker.
+func (s *Signing44) release() {
+	s.stop()
+	s.broker.Close()
+}
+
 // bytesEqual is a simple constant-time comparison wrapper (public-data here,
 // so constant-time is not a security requirement).
 func bytesEqual(a, b []byte) bool {
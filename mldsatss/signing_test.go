@@ -1,9 +1,11 @@
 package mldsatss
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/big"
+	mrand "math/rand/v2"
 	"sync"
 	"testing"
 	"time"
@@ -56,6 +58,12 @@ func (b *hubBroker) Connect(typ string, dest tss.MessageReceiver) {
 	}
 }
 
+func (b *hubBroker) Disconnect(typ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, typ)
+}
+
 func (b *hubBroker) Receive(msg *tss.JsonMessage) error {
 	if msg.From.Index == b.partyIdx {
 		if msg.To != nil {
@@ -252,3 +260,67 @@ func TestSigning44_BadContextMismatch(t *testing.T) {
 	require.False(t, pk.Verify(sig, msg, []byte("ctx-B")))
 	require.False(t, pk.Verify(sig, []byte("other msg"), []byte("ctx-A")))
 }
+
+// TestSigning44_Replay records the signing attempt of a party, and replays it with the same
+// random source: the replayed party must send the same messages and reach the same outcome.
+func TestSigning44_Replay(t *testing.T) {
+	var seed [32]byte
+	seed[0] = 0x9
+	tParams, err := GetThresholdParams44(2, 3)
+	require.NoError(t, err)
+	_, keys, err := TrustedDealerKeygen44(seed, tParams)
+	require.NoError(t, err)
+
+	signers, p2pCtx, keyIds := buildCommittee(3, 2)
+	msg := []byte("replayed message")
+	randSeed := [32]byte{1, 2, 3}
+
+	result := func(s *Signing44) ([]byte, error) {
+		select {
+		case sd := <-s.Done:
+			return sd.Signature, nil
+		case err := <-s.Err:
+			return nil, err
+		case <-time.After(30 * time.Second):
+			t.Fatal("signing timed out")
+			return nil, nil
+		}
+	}
+
+	var transcript bytes.Buffer
+	hub := newTestHub(len(signers))
+	sigs := make([]*Signing44, len(signers))
+	for i, pid := range signers {
+		var broker tss.MessageBroker = hub.brokers[i]
+		if i == 0 {
+			broker = tss.NewRecordingBroker(broker, pid, &transcript)
+		}
+		params, err := NewParameters(pid, p2pCtx, tParams, keyIds, broker)
+		require.NoError(t, err)
+		if i == 0 {
+			params.SetRand(mrand.NewChaCha8(randSeed))
+		}
+		s, err := NewSigning44(context.Background(), params, keys[keyIds[i]], msg, nil)
+		require.NoError(t, err)
+		sigs[i] = s
+	}
+	want, wantErr := result(sigs[0])
+	for _, s := range sigs[1:] {
+		_, _ = result(s)
+	}
+
+	// replay the attempt of party 0 without its peers
+	entries, err := tss.ReadTranscript(&transcript)
+	require.NoError(t, err)
+	replay := tss.NewReplayBroker(entries)
+	params, err := NewParameters(signers[0], p2pCtx, tParams, keyIds, replay)
+	require.NoError(t, err)
+	params.SetRand(mrand.NewChaCha8(randSeed))
+	s, err := NewSigning44(context.Background(), params, keys[keyIds[0]], msg, nil)
+	require.NoError(t, err)
+
+	got, gotErr := result(s)
+	require.Equal(t, wantErr, gotErr)
+	require.Equal(t, want, got)
+	require.NoError(t, replay.Diverged())
+}
@@ -1,18 +1,65 @@
 package tss
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // MessageReceiver defines an interface for receiving JSON messages.
 type MessageReceiver interface {
 	Receive(msg *JsonMessage) error
 }
 
+// ExpectingReceiver is implemented by receivers that know which parties they expect a message
+// from, such as the ones returned by NewJsonExpect. Receivers wrapping another one should
+// implement it too, so that brokers such as RelayParty can find out what a round waits for.
+type ExpectingReceiver interface {
+	MessageReceiver
+	Expected() []*PartyID
+}
+
+// expectedParties returns the parties r expects a message from, or nil if unknown.
+func expectedParties(r MessageReceiver) []*PartyID {
+	if e, ok := r.(ExpectingReceiver); ok {
+		return e.Expected()
+	}
+	return nil
+}
+
+// PrivateReceiver is implemented by receivers of private messages, such as the ones returned
+// by NewJsonExpect with WithPrivate. EncryptingBroker rejects the messages passed to them in
+// the clear. Receivers wrapping another one should implement it too.
+type PrivateReceiver interface {
+	MessageReceiver
+	Private() bool
+}
+
+// isPrivate reports whether r receives private messages.
+func isPrivate(r MessageReceiver) bool {
+	p, ok := r.(PrivateReceiver)
+	return ok && p.Private()
+}
+
 // MessageBroker extends MessageReceiver with the ability to connect message handlers by type.
 type MessageBroker interface {
 	MessageReceiver
 	Connect(typ string, dest MessageReceiver)
 }
 
+// Disconnecter is implemented by brokers able to release the handler connected for a type.
+// Protocols call Disconnect once their session has ended. Brokers that do not implement it
+// keep the handlers of finished sessions until they are replaced by a later Connect.
+type Disconnecter interface {
+	Disconnect(typ string)
+}
+
+// disconnect releases the handler for typ on b, if b supports it.
+func disconnect(b MessageBroker, typ string) {
+	if d, ok := b.(Disconnecter); ok {
+		d.Disconnect(typ)
+	}
+}
+
 type testBroker struct {
 	rcv map[string]MessageReceiver
 }
@@ -33,3 +80,89 @@ func (b *testBroker) Receive(msg *JsonMessage) error {
 func (b *testBroker) Connect(typ string, dest MessageReceiver) {
 	b.rcv[typ] = dest
 }
+
+func (b *testBroker) Disconnect(typ string) {
+	delete(b.rcv, typ)
+}
+
+// SessionBroker wraps a MessageBroker and keeps track of the types connected through it, so
+// a protocol run can release all its handlers at once when it ends.
+type SessionBroker struct {
+	broker MessageBroker
+	lock   sync.Mutex
+	types  map[string]MessageReceiver
+	closed bool
+}
+
+// canceler is implemented by receivers holding resources, such as timers, that must be
+// released when they are disconnected.
+type canceler interface {
+	Cancel()
+}
+
+// NewSessionBroker returns a SessionBroker connecting handlers on b.
+func NewSessionBroker(b MessageBroker) *SessionBroker {
+	return &SessionBroker{broker: b, types: make(map[string]MessageReceiver)}
+}
+
+// Receive passes msg to the underlying broker.
+func (s *SessionBroker) Receive(msg *JsonMessage) error {
+	return s.broker.Receive(msg)
+}
+
+// Connect registers dest on the underlying broker, unless the session was closed.
+func (s *SessionBroker) Connect(typ string, dest MessageReceiver) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return
+	}
+	s.types[typ] = dest
+	s.lock.Unlock()
+
+	// the underlying broker may deliver queued messages to dest right away, which can lead
+	// the protocol into its next round and back here, so this must run without the lock
+	s.broker.Connect(typ, dest)
+
+	s.lock.Lock()
+	closed := s.closed
+	s.lock.Unlock()
+	if closed {
+		// session was closed while connecting
+		disconnect(s.broker, typ)
+		cancelReceiver(dest)
+	}
+}
+
+// Disconnect unregisters the handler for typ from the underlying broker.
+func (s *SessionBroker) Disconnect(typ string) {
+	s.lock.Lock()
+	dest, ok := s.types[typ]
+	delete(s.types, typ)
+	s.lock.Unlock()
+	if ok {
+		disconnect(s.broker, typ)
+		cancelReceiver(dest)
+	}
+}
+
+// Close disconnects every handler registered through this session. Any later Connect is
+// ignored. Close can be called multiple times.
+func (s *SessionBroker) Close() {
+	s.lock.Lock()
+	types := s.types
+	s.types = nil
+	s.closed = true
+	s.lock.Unlock()
+
+	for typ, dest := range types {
+		disconnect(s.broker, typ)
+		cancelReceiver(dest)
+	}
+}
+
+func cancelReceiver(r MessageReceiver) {
+	if c, ok := r.(canceler); ok {
+		c.Cancel()
+	}
+}
@@ -0,0 +1,129 @@
+package tss
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultMaxQueuedPerType = 64
+	defaultMaxQueuedTypes   = 256
+)
+
+// ErrQueueFull is returned by BufferedBroker when an early message cannot be queued because
+// the configured limits have been reached.
+var ErrQueueFull = errors.New("broker queue is full")
+
+// BufferedBroker is a MessageBroker suitable for real networks, where faster peers may send
+// messages for a round this party has not reached yet. Inbound messages that arrive before a
+// receiver was connected for their type are queued, and replayed into the receiver as soon
+// as Connect is called.
+//
+// Messages passed to Receive that were sent by the local party are handed to the transport,
+// unless they are addressed to the local party itself. Any other message is considered
+// inbound, so the transport should pass received messages to Receive as well.
+type BufferedBroker struct {
+	self      *PartyID
+	transport MessageReceiver
+	maxQueue  int // per type
+	maxTypes  int
+
+	lock    sync.Mutex
+	rcv     map[string]MessageReceiver
+	pending map[string][]*JsonMessage
+}
+
+// NewBufferedBroker returns a BufferedBroker for the given local party, sending outbound
+// messages to transport.
+func NewBufferedBroker(self *PartyID, transport MessageReceiver) *BufferedBroker {
+	return &BufferedBroker{
+		self:      self,
+		transport: transport,
+		maxQueue:  defaultMaxQueuedPerType,
+		maxTypes:  defaultMaxQueuedTypes,
+		rcv:       make(map[string]MessageReceiver),
+		pending:   make(map[string][]*JsonMessage),
+	}
+}
+
+// SetQueueLimits sets how many early messages can be queued for a single type, and for how
+// many distinct types messages can be queued at the same time.
+func (b *BufferedBroker) SetQueueLimits(perType, types int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.maxQueue = perType
+	b.maxTypes = types
+}
+
+// Receive sends outbound messages to the transport, and dispatches or queues inbound ones.
+func (b *BufferedBroker) Receive(msg *JsonMessage) error {
+	if b.isSelf(msg.From) && !b.isSelf(msg.To) {
+		return b.transport.Receive(msg)
+	}
+
+	b.lock.Lock()
+	tgt, ok := b.rcv[msg.Type]
+	if !ok {
+		defer b.lock.Unlock()
+		queue, found := b.pending[msg.Type]
+		if (!found && len(b.pending) >= b.maxTypes) || len(queue) >= b.maxQueue {
+			return ErrQueueFull
+		}
+		b.pending[msg.Type] = append(queue, msg)
+		return nil
+	}
+	b.lock.Unlock()
+	return tgt.Receive(msg)
+}
+
+// Connect registers dest for the given type, and replays any message queued for it.
+func (b *BufferedBroker) Connect(typ string, dest MessageReceiver) {
+	b.lock.Lock()
+	b.rcv[typ] = dest
+	queued := b.pending[typ]
+	delete(b.pending, typ)
+	b.lock.Unlock()
+
+	for _, msg := range queued {
+		// invalid messages are rejected by the receiver, there is nobody to report it to
+		_ = dest.Receive(msg)
+	}
+}
+
+// Disconnect unregisters the receiver for the given type, dropping any message queued for it.
+func (b *BufferedBroker) Disconnect(typ string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.rcv, typ)
+	delete(b.pending, typ)
+}
+
+// Pending returns the number of queued messages for the given type.
+func (b *BufferedBroker) Pending(typ string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.pending[typ])
+}
+
+// DropSession drops all the messages queued for the given session id, as set with
+// Parameters.SetSessionID. It should be called once a session ends, as peers may still be
+// sending messages for it.
+func (b *BufferedBroker) DropSession(sessionID string) {
+	suffix := "#" + sessionID
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for typ := range b.pending {
+		if strings.HasSuffix(typ, suffix) {
+			delete(b.pending, typ)
+		}
+	}
+}
+
+func (b *BufferedBroker) isSelf(p *PartyID) bool {
+	if p == nil || b.self == nil {
+		return false
+	}
+	return p.KeyInt().Cmp(b.self.KeyInt()) == 0
+}
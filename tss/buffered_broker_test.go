@@ -0,0 +1,77 @@
+package tss
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedBrokerQueuesEarlyMessages(t *testing.T) {
+	self := NewPartyID("1", "P1", big.NewInt(1))
+	peer := NewPartyID("2", "P2", big.NewInt(2))
+
+	var sent []*JsonMessage
+	b := NewBufferedBroker(self, receiverFunc(func(msg *JsonMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}))
+
+	// inbound message before any receiver: queued
+	require.NoError(t, b.Receive(JsonWrap("round2", "early", peer, self)))
+	assert.Equal(t, 1, b.Pending("round2"))
+
+	var got []*JsonMessage
+	b.Connect("round2", receiverFunc(func(msg *JsonMessage) error {
+		got = append(got, msg)
+		return nil
+	}))
+	assert.Equal(t, 0, b.Pending("round2"))
+	require.Len(t, got, 1)
+	assert.Equal(t, "early", got[0].Data)
+
+	// once connected, messages are dispatched directly
+	require.NoError(t, b.Receive(JsonWrap("round2", "late", peer, self)))
+	assert.Len(t, got, 2)
+
+	// outbound messages go to the transport
+	require.NoError(t, b.Receive(JsonWrap("round2", "out", self, peer)))
+	require.Len(t, sent, 1)
+	assert.Equal(t, "out", sent[0].Data)
+
+	// messages to self are looped back
+	require.NoError(t, b.Receive(JsonWrap("round2", "loop", self, self)))
+	assert.Len(t, sent, 1)
+	assert.Len(t, got, 3)
+}
+
+func TestBufferedBrokerLimits(t *testing.T) {
+	self := NewPartyID("1", "P1", big.NewInt(1))
+	peer := NewPartyID("2", "P2", big.NewInt(2))
+
+	b := NewBufferedBroker(self, NewTestBroker())
+	b.SetQueueLimits(2, 2)
+
+	require.NoError(t, b.Receive(JsonWrap("a", 1, peer, self)))
+	require.NoError(t, b.Receive(JsonWrap("a", 2, peer, self)))
+	assert.ErrorIs(t, b.Receive(JsonWrap("a", 3, peer, self)), ErrQueueFull)
+
+	require.NoError(t, b.Receive(JsonWrap("b", 1, peer, self)))
+	assert.ErrorIs(t, b.Receive(JsonWrap("c", 1, peer, self)), ErrQueueFull)
+}
+
+func TestBufferedBrokerDropSession(t *testing.T) {
+	self := NewPartyID("1", "P1", big.NewInt(1))
+	peer := NewPartyID("2", "P2", big.NewInt(2))
+
+	b := NewBufferedBroker(self, NewTestBroker())
+	require.NoError(t, b.Receive(JsonWrap("round1#s1", 1, peer, self)))
+	require.NoError(t, b.Receive(JsonWrap("round2#s1", 1, peer, self)))
+	require.NoError(t, b.Receive(JsonWrap("round1#s2", 1, peer, self)))
+
+	b.DropSession("s1")
+	assert.Equal(t, 0, b.Pending("round1#s1"))
+	assert.Equal(t, 0, b.Pending("round2#s1"))
+	assert.Equal(t, 1, b.Pending("round1#s2"))
+}
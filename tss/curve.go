@@ -23,6 +23,12 @@ const (
 	Secp256k1 CurveName = "secp256k1"
 	// Ed25519 is the curve name for the Ed25519 twisted Edwards curve.
 	Ed25519 CurveName = "ed25519"
+	// Secp256r1 is the curve name for the NIST P-256 elliptic curve.
+	Secp256r1 CurveName = "secp256r1"
+	// Secp384r1 is the curve name for the NIST P-384 elliptic curve.
+	Secp384r1 CurveName = "secp384r1"
+	// Secp521r1 is the curve name for the NIST P-521 elliptic curve.
+	Secp521r1 CurveName = "secp521r1"
 )
 
 var (
@@ -37,13 +43,24 @@ func init() {
 	registry = make(map[CurveName]elliptic.Curve)
 	registry[Secp256k1] = secp256k1.S256()
 	registry[Ed25519] = edwards25519.Edwards()
+	registry[Secp256r1] = elliptic.P256()
+	registry[Secp384r1] = elliptic.P384()
+	registry[Secp521r1] = elliptic.P521()
 }
 
 // RegisterCurve registers an elliptic curve under the given name in the global curve registry.
+// Keys over a curve must be registered to be marshalled to JSON, and ecdsatss supports any
+// short Weierstrass curve of prime order, such as an *elliptic.CurveParams.
 func RegisterCurve(name CurveName, curve elliptic.Curve) {
 	registry[name] = curve
 }
 
+// UnregisterCurve removes the curve registered under the given name from the global curve
+// registry, such as a curve registered for the time of a test.
+func UnregisterCurve(name CurveName) {
+	delete(registry, name)
+}
+
 // return curve, exist(bool)
 func GetCurveByName(name CurveName) (elliptic.Curve, bool) {
 	if val, exist := registry[name]; exist {
@@ -55,6 +72,16 @@ func GetCurveByName(name CurveName) (elliptic.Curve, bool) {
 
 // return name, exist(bool)
 func GetCurveName(curve elliptic.Curve) (CurveName, bool) {
+	// the same instance first, then the same type unless it is *elliptic.CurveParams, shared
+	// by the curves built from their parameters
+	for name, e := range registry {
+		if e == curve {
+			return name, true
+		}
+	}
+	if _, ok := curve.(*elliptic.CurveParams); ok {
+		return "", false
+	}
 	for name, e := range registry {
 		if reflect.TypeOf(curve) == reflect.TypeOf(e) {
 			return name, true
@@ -94,6 +121,11 @@ func S256() elliptic.Curve {
 	return secp256k1.S256()
 }
 
+// P256 returns the NIST P-256 elliptic curve, also known as secp256r1 or prime256v1.
+func P256() elliptic.Curve {
+	return elliptic.P256()
+}
+
 // Edwards returns the Ed25519 twisted Edwards elliptic curve.
 func Edwards() elliptic.Curve {
 	return edwards25519.Edwards()
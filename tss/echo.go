@@ -0,0 +1,197 @@
+package tss
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrEquivocation is the cause of the error reported when a party sent different messages to
+// different peers in a broadcast round, or when a peer echoed a broadcast differently.
+var ErrEquivocation = errors.New("party sent different broadcast messages")
+
+// echoMsg is sent to the other receivers of a broadcast, with the digests of the messages
+// received from each sender.
+type echoMsg struct {
+	Digests []echoDigest `json:"digests"`
+}
+
+// echoDigest is the digest of the data of the message received from Party. When the message
+// was signed by its sender, the data and signature are echoed too, so that a sender that
+// signed different messages for different peers can be proven to have equivocated.
+type echoDigest struct {
+	Party     []byte          `json:"party"`
+	Digest    []byte          `json:"digest"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Signature []byte          `json:"sig,omitempty"`
+}
+
+type echoOptions struct {
+	broker         MessageBroker
+	typ            string
+	self           *PartyID
+	peers          []*PartyID
+	onEquivocation func([]*PartyID)
+}
+
+// WithEcho turns a broadcast round into an echo broadcast. Once all the messages have been
+// received, their digests are sent to peers, the other parties receiving the broadcast, in a
+// message of the given type. The callback of the receiver is only called once every peer
+// reported the same digests; otherwise onEquivocation is called with the culprits. When the
+// broadcast messages are signed by the identity key of their sender, the echoes carry them,
+// so the culprits are the senders that signed different messages for different peers, or
+// the peers that echoed a message their sender did not sign. The signatures only prove an
+// equivocation when the broadcast type holds a session id, as set with
+// Parameters.SetSessionID, since a message signed in another session with the same type
+// could be echoed otherwise. Without identity keys or session id, a peer echoing a different
+// digest cannot be told from a sender that equivocated, and the culprits are the peers whose
+// echoes differ from the messages received. A peer that does not echo
+// the digest of every sender is a culprit too. The receiver timeout also applies to the
+// echoes.
+func WithEcho(b MessageBroker, typ string, self *PartyID, peers []*PartyID, onEquivocation func(culprits []*PartyID)) ExpectOption {
+	return func(o *expectOptions) {
+		o.echo = &echoOptions{
+			broker:         b,
+			typ:            typ,
+			self:           self,
+			peers:          peers,
+			onEquivocation: onEquivocation,
+		}
+	}
+}
+
+// echo tracks the echo sub-round of a broadcast round.
+type echo struct {
+	*echoOptions
+	msgType   string // type of the broadcast messages
+	lock      sync.Mutex
+	senders   []*PartyID
+	own       []echoDigest // digests of the messages we received, once complete
+	done      func()
+	echoes    []*echoMsg
+	hasEchoes bool
+	fired     bool
+}
+
+func newEcho(msgType string, o *expectOptions) *echo {
+	ec := &echo{echoOptions: o.echo, msgType: msgType}
+	if len(ec.peers) == 0 {
+		ec.hasEchoes = true
+		return ec
+	}
+	var opts []ExpectOption
+	if o.timeout > 0 {
+		opts = append(opts, WithTimeout(o.timeout, o.onTimeout))
+	}
+	ec.broker.Connect(ec.typ, NewJsonExpect[echoMsg](ec.typ, ec.peers, ec.onEchoes, opts...))
+	return ec
+}
+
+// complete is called once all the broadcast messages have been received, with their json
+// encoded data and signatures, and calls done once the echoes have been checked.
+func (ec *echo) complete(from []*PartyID, data, sigs [][]byte, done func()) {
+	msg := &echoMsg{Digests: make([]echoDigest, len(from))}
+	for n, p := range from {
+		sum := sha256.Sum256(data[n])
+		msg.Digests[n] = echoDigest{Party: p.Key, Digest: sum[:]}
+		if sigs[n] != nil {
+			msg.Digests[n].Data = data[n]
+			msg.Digests[n].Signature = sigs[n]
+		}
+	}
+	for _, p := range ec.peers {
+		// delivery errors surface as a timeout of the echo round
+		_ = ec.broker.Receive(JsonWrap(ec.typ, msg, ec.self, p))
+	}
+
+	ec.lock.Lock()
+	ec.senders = from
+	ec.own = msg.Digests
+	ec.done = done
+	ec.lock.Unlock()
+	ec.check()
+}
+
+func (ec *echo) onEchoes(from []*PartyID, msgs []*echoMsg) {
+	ec.lock.Lock()
+	ec.echoes = msgs
+	ec.hasEchoes = true
+	ec.lock.Unlock()
+	ec.check()
+}
+
+// check compares the echoes with our own digests once both are available.
+func (ec *echo) check() {
+	ec.lock.Lock()
+	if ec.own == nil || !ec.hasEchoes || ec.fired {
+		ec.lock.Unlock()
+		return
+	}
+	ec.fired = true
+	ec.lock.Unlock()
+
+	var culprits []*PartyID
+	blame := func(p *PartyID) {
+		for _, c := range culprits {
+			if c == p {
+				return
+			}
+		}
+		culprits = append(culprits, p)
+	}
+	for n, own := range ec.own {
+		sender := ec.senders[n]
+		for i, msg := range ec.echoes {
+			peer := ec.peers[i]
+			if bytes.Equal(peer.Key, sender.Key) {
+				// the sender does not echo its own message
+				continue
+			}
+			d := findDigest(msg, own.Party)
+			switch {
+			case d == nil:
+				blame(peer)
+			case bytes.Equal(d.Digest, own.Digest):
+			case ec.signedBy(sender, peer, d):
+				blame(sender)
+			default:
+				blame(peer)
+			}
+		}
+	}
+	if len(culprits) > 0 {
+		ec.onEquivocation(culprits)
+		return
+	}
+	ec.done()
+}
+
+// signedBy reports whether d, echoed by peer, holds a message signed by sender for peer in
+// this session, which proves that sender equivocated when d differs from the message we
+// received.
+func (ec *echo) signedBy(sender, peer *PartyID, d *echoDigest) bool {
+	if sender.IdentityKey == nil || d.Data == nil || !strings.Contains(ec.msgType, "#") {
+		return false
+	}
+	if sum := sha256.Sum256(d.Data); !bytes.Equal(sum[:], d.Digest) {
+		return false
+	}
+	msg := &JsonMessage{Type: ec.msgType, From: sender, To: peer, Data: d.Data, Signature: d.Signature}
+	return msg.Verify(sender.IdentityKey) == nil
+}
+
+// findDigest returns the digest msg holds for party, or nil if there is none.
+func findDigest(msg *echoMsg, party []byte) *echoDigest {
+	if msg == nil {
+		return nil
+	}
+	for n, d := range msg.Digests {
+		if bytes.Equal(d.Party, party) {
+			return &msg.Digests[n]
+		}
+	}
+	return nil
+}
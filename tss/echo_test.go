@@ -0,0 +1,144 @@
+package tss
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEchoBroadcast(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+	encode := func(v int) []byte {
+		buf, err := json.Marshal(&Payload{Value: v})
+		require.NoError(t, err)
+		return buf
+	}
+	digest := func(v int) []byte {
+		sum := sha256.Sum256(encode(v))
+		return sum[:]
+	}
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+	p3 := NewPartyID("3", "P3", big.NewInt(3))
+	others := []*PartyID{p2, p3}
+
+	// echoOfP3 builds the entry for the message of p3 in the echo of p2
+	type echoOfP3 func() *echoDigest
+	// session is the type of the broadcast messages, which holds a session id once set
+	session := "round1"
+	run := func(t *testing.T, key ed25519.PrivateKey, p3Echo echoOfP3) (bool, []*PartyID) {
+		b := NewTestBroker()
+		var called bool
+		var culprits []*PartyID
+		rcv := NewJsonExpect[Payload](session, others, func([]*PartyID, []*Payload) {
+			called = true
+		}, WithEcho(b, "round1:echo", p1, others, func(c []*PartyID) {
+			culprits = c
+		}))
+		b.Connect(session, rcv)
+
+		for _, m := range []*JsonMessage{
+			JsonWrap(session, &Payload{Value: 2}, p2, p1),
+			JsonWrap(session, &Payload{Value: 3}, p3, p1),
+		} {
+			if key != nil && m.From == p3 {
+				require.NoError(t, m.Sign(key))
+			}
+			require.NoError(t, b.Receive(m))
+		}
+		assert.False(t, called, "callback must wait for the echoes")
+
+		digests := []echoDigest{{Party: p1.Key, Digest: digest(1)}}
+		if d := p3Echo(); d != nil {
+			digests = append(digests, *d)
+		}
+		require.NoError(t, b.Receive(JsonWrap("round1:echo", &echoMsg{Digests: digests}, p2, p1)))
+		m := JsonWrap("round1:echo", &echoMsg{Digests: []echoDigest{
+			{Party: p1.Key, Digest: digest(1)},
+			{Party: p2.Key, Digest: digest(2)},
+		}}, p3, p1)
+		if key != nil {
+			require.NoError(t, m.Sign(key))
+		}
+		require.NoError(t, b.Receive(m))
+		return called, culprits
+	}
+	unsigned := func(v int) echoOfP3 {
+		return func() *echoDigest { return &echoDigest{Party: p3.Key, Digest: digest(v)} }
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+		called, culprits := run(t, nil, unsigned(3))
+		assert.True(t, called)
+		assert.Empty(t, culprits)
+	})
+
+	t.Run("unsigned mismatch blames the echoer", func(t *testing.T) {
+		// p3 may have sent a different message to p2, or p2 may be lying
+		called, culprits := run(t, nil, unsigned(4))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+	})
+
+	t.Run("missing digest blames the echoer", func(t *testing.T) {
+		called, culprits := run(t, nil, func() *echoDigest { return nil })
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+	})
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	p3.IdentityKey = pub
+	defer func() { p3.IdentityKey = nil }()
+	signedIn := func(typ string, v int, to *PartyID, key ed25519.PrivateKey) echoOfP3 {
+		return func() *echoDigest {
+			m := JsonWrap(typ, json.RawMessage(encode(v)), p3, to)
+			require.NoError(t, m.Sign(key))
+			return &echoDigest{Party: p3.Key, Digest: digest(v), Data: encode(v), Signature: m.Signature}
+		}
+	}
+	signedFor := func(v int, to *PartyID, key ed25519.PrivateKey) echoOfP3 {
+		return signedIn(session, v, to, key)
+	}
+
+	t.Run("signed mismatch without session blames the echoer", func(t *testing.T) {
+		// p2 may echo a message p3 signed in an earlier session
+		called, culprits := run(t, priv, signedFor(4, p2, priv))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+	})
+
+	session = "round1#s2"
+
+	t.Run("signed equivocation blames the sender", func(t *testing.T) {
+		called, culprits := run(t, priv, signedFor(4, p2, priv))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p3}, culprits)
+	})
+
+	t.Run("forged echo blames the echoer", func(t *testing.T) {
+		_, other, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+		called, culprits := run(t, priv, signedFor(4, p2, other))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+
+		// a message p3 signed for another recipient proves nothing either
+		called, culprits = run(t, priv, signedFor(4, p1, priv))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+	})
+	t.Run("replay from an earlier session blames the echoer", func(t *testing.T) {
+		called, culprits := run(t, priv, signedIn("round1#s1", 4, p2, priv))
+		assert.False(t, called)
+		assert.Equal(t, []*PartyID{p2}, culprits)
+	})
+}
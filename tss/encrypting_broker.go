@@ -0,0 +1,221 @@
+package tss
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// ErrDecryption is returned when an encrypted message cannot be decrypted.
+var ErrDecryption = errors.New("message decryption failed")
+
+// ErrNotEncrypted is returned when a private message arrives in the clear.
+var ErrNotEncrypted = errors.New("private message is not encrypted")
+
+const encryptionContext = "tss-lib/p2p/v1"
+
+// EncryptingBroker wraps a MessageBroker and encrypts the private messages sent by the local
+// party, such as secret shares, so that only their recipient can read them. Other messages
+// are passed in the clear. Received messages are decrypted before they reach their receiver,
+// and the private messages that arrive in the clear, which a relay stripping the encryption
+// would pass on, are rejected with ErrNotEncrypted. Receivers mark the types they expect to be
+// private with WithPrivate.
+//
+// Keys are derived with X25519 from the Ed25519 IdentityKey of the sender and recipient, so
+// every party must have one, and messages are encrypted with AES-GCM. The sender is not
+// authenticated by the encryption, so messages should also be signed: the SigningBroker must
+// wrap the EncryptingBroker so that the signature covers the plaintext.
+type EncryptingBroker struct {
+	broker MessageBroker
+	self   *PartyID
+	key    *ecdh.PrivateKey
+}
+
+// NewEncryptingBroker returns an EncryptingBroker for the local party self, whose identity
+// private key is key, passing messages to b.
+func NewEncryptingBroker(b MessageBroker, self *PartyID, key ed25519.PrivateKey) (*EncryptingBroker, error) {
+	h := sha512.Sum512(key.Seed())
+	xkey, err := ecdh.X25519().NewPrivateKey(h[:32])
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptingBroker{broker: b, self: self, key: xkey}, nil
+}
+
+// Receive encrypts the message if it is a private message sent by the local party, and
+// passes it to the underlying broker.
+func (b *EncryptingBroker) Receive(msg *JsonMessage) error {
+	if msg.Private && !msg.Encrypted && msg.From != nil && msg.From.KeyInt().Cmp(b.self.KeyInt()) == 0 {
+		enc, err := b.seal(msg)
+		if err != nil {
+			return err
+		}
+		msg = enc
+	}
+	return b.broker.Receive(msg)
+}
+
+// Connect registers dest for the given type on the underlying broker, decrypting the
+// messages passed to it.
+func (b *EncryptingBroker) Connect(typ string, dest MessageReceiver) {
+	b.broker.Connect(typ, &decryptingReceiver{broker: b, dest: dest})
+}
+
+// Disconnect unregisters the receiver for the given type on the underlying broker.
+func (b *EncryptingBroker) Disconnect(typ string) {
+	disconnect(b.broker, typ)
+}
+
+func (b *EncryptingBroker) seal(msg *JsonMessage) (*JsonMessage, error) {
+	if msg.To == nil {
+		return nil, errors.New("private message has no recipient")
+	}
+	aead, err := b.aead(msg, msg.To)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	res := *msg
+	res.Data = aead.Seal(nonce, nonce, data, nil)
+	res.Encrypted = true
+	return &res, nil
+}
+
+func (b *EncryptingBroker) open(msg *JsonMessage) (*JsonMessage, error) {
+	if msg.From == nil || msg.To == nil {
+		return nil, ErrDecryption
+	}
+	aead, err := b.aead(msg, msg.From)
+	if err != nil {
+		return nil, err
+	}
+	var sealed []byte
+	switch d := msg.Data.(type) {
+	case []byte:
+		sealed = d
+	case json.RawMessage:
+		if err := json.Unmarshal(d, &sealed); err != nil {
+			return nil, ErrDecryption
+		}
+	default:
+		return nil, ErrDecryption
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryption
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	res := *msg
+	res.Data = json.RawMessage(data)
+	res.Encrypted = false
+	return &res, nil
+}
+
+// aead returns the cipher for messages of the given type between the sender and recipient
+// of msg, peer being the party that is not the local party.
+func (b *EncryptingBroker) aead(msg *JsonMessage, peer *PartyID) (cipher.AEAD, error) {
+	if peer.IdentityKey == nil {
+		return nil, errors.New("peer has no identity key")
+	}
+	pub, err := x25519PublicKey(peer.IdentityKey)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := b.key.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	info := []byte(encryptionContext)
+	for _, v := range [][]byte{[]byte(msg.Type), msg.From.Key, msg.To.Key} {
+		info = binary.BigEndian.AppendUint32(info, uint32(len(v)))
+		info = append(info, v...)
+	}
+	key, err := hkdf.Key(sha256.New, shared, nil, string(info), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// x25519PublicKey converts an Ed25519 public key to the X25519 public key of the same secret,
+// mapping the Edwards y coordinate to the Montgomery u = (1+y)/(1-y).
+func x25519PublicKey(pub ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid identity key")
+	}
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+	buf := make([]byte, 32)
+	for i, v := range pub {
+		buf[31-i] = v
+	}
+	buf[0] &= 0x7f // drop the sign of x
+	y := new(big.Int).SetBytes(buf)
+
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, p)
+	if den.ModInverse(den, p) == nil {
+		return nil, errors.New("invalid identity key")
+	}
+	u := new(big.Int).Add(big.NewInt(1), y)
+	u.Mul(u, den)
+	u.Mod(u, p)
+
+	u.FillBytes(buf)
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+	return ecdh.X25519().NewPublicKey(buf)
+}
+
+// decryptingReceiver decrypts the encrypted messages passed to its receiver.
+type decryptingReceiver struct {
+	broker *EncryptingBroker
+	dest   MessageReceiver
+}
+
+func (r *decryptingReceiver) Expected() []*PartyID {
+	return expectedParties(r.dest)
+}
+
+func (r *decryptingReceiver) Private() bool {
+	return isPrivate(r.dest)
+}
+
+func (r *decryptingReceiver) Receive(msg *JsonMessage) error {
+	if msg.Encrypted {
+		dec, err := r.broker.open(msg)
+		if err != nil {
+			return err
+		}
+		msg = dec
+	} else if isPrivate(r.dest) {
+		return ErrNotEncrypted
+	}
+	return r.dest.Receive(msg)
+}
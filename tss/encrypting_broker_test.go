@@ -0,0 +1,102 @@
+package tss
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX25519PublicKey(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		h := sha512.Sum512(priv.Seed())
+		xpriv, err := ecdh.X25519().NewPrivateKey(h[:32])
+		require.NoError(t, err)
+
+		xpub, err := x25519PublicKey(pub)
+		require.NoError(t, err)
+		assert.True(t, xpriv.PublicKey().Equal(xpub))
+	}
+}
+
+func TestEncryptingBroker(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	pub1, priv1, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	pub2, priv2, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p1.IdentityKey = pub1
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+	p2.IdentityKey = pub2
+
+	// p1 signs and encrypts, everything goes on the wire as json
+	var wire [][]byte
+	enc, err := NewEncryptingBroker(receiverFuncBroker(func(msg *JsonMessage) error {
+		buf, err := json.Marshal(msg)
+		wire = append(wire, buf)
+		return err
+	}), p1, priv1)
+	require.NoError(t, err)
+	b1 := NewSigningBroker(enc, p1, priv1)
+
+	require.NoError(t, b1.Receive(JsonWrapPrivate("share", &Payload{Value: 42}, p1, p2)))
+	require.NoError(t, b1.Receive(JsonWrap("commit", &Payload{Value: 7}, p1, p2)))
+	require.Len(t, wire, 2)
+	assert.NotContains(t, string(wire[0]), `"value"`)
+	assert.Contains(t, string(wire[1]), `"value":7`)
+
+	// p2 receives them
+	var got []*Payload
+	tb := NewTestBroker()
+	b2, err := NewEncryptingBroker(tb, p2, priv2)
+	require.NoError(t, err)
+	collect := func(from []*PartyID, packets []*Payload) {
+		got = append(got, packets[0])
+	}
+	b2.Connect("share", NewJsonExpect[Payload]("share", []*PartyID{p1}, collect, WithPrivate()))
+	b2.Connect("commit", NewJsonExpect[Payload]("commit", []*PartyID{p1}, collect))
+	for _, buf := range wire {
+		var msg *JsonMessage
+		require.NoError(t, json.Unmarshal(buf, &msg))
+		require.NoError(t, tb.Receive(msg))
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, 42, got[0].Value)
+	assert.Equal(t, 7, got[1].Value)
+
+	// a tampered ciphertext is rejected
+	var msg *JsonMessage
+	require.NoError(t, json.Unmarshal(wire[0], &msg))
+	var sealed []byte
+	require.NoError(t, json.Unmarshal(msg.Data.(json.RawMessage), &sealed))
+	sealed[len(sealed)-1] ^= 1
+	msg.Data = sealed
+	b2.Connect("share", NewJsonExpect[Payload]("share", []*PartyID{p1}, func([]*PartyID, []*Payload) {}, WithPrivate()))
+	assert.ErrorIs(t, tb.Receive(msg), ErrDecryption)
+
+	// so is a private message stripped of its encryption
+	buf, err := json.Marshal(JsonWrap("share", &Payload{Value: 42}, p1, p2))
+	require.NoError(t, err)
+	var plain *JsonMessage
+	require.NoError(t, json.Unmarshal(buf, &plain))
+	assert.ErrorIs(t, tb.Receive(plain), ErrNotEncrypted)
+}
+
+// receiverFuncBroker is a MessageBroker passing every message to a function.
+type receiverFuncBroker func(msg *JsonMessage) error
+
+func (f receiverFuncBroker) Receive(msg *JsonMessage) error           { return f(msg) }
+func (f receiverFuncBroker) Connect(typ string, dest MessageReceiver) {}
@@ -7,6 +7,7 @@
 package tss
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -54,3 +55,64 @@ func (err *Error) Error() string {
 	return fmt.Sprintf("task %s, party %v, round %d: %s",
 		err.task, err.victim, err.round, err.cause.Error())
 }
+
+// JoinErrors merges the errors reported by concurrent checks of the same round into a single
+// Error, whose culprits are the culprits of all the given errors. The task, round, victim and
+// cause are taken from the first Error. Errors that are not an Error are only returned if
+// there is no Error to merge, and nil is returned if errs holds no error at all.
+func JoinErrors(errs ...error) error {
+	var first *Error
+	var other error
+	var culprits []*PartyID
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var e *Error
+		if !errors.As(err, &e) {
+			if other == nil {
+				other = err
+			}
+			continue
+		}
+		if first == nil {
+			first = e
+		}
+		for _, c := range e.culprits {
+			if !containsParty(culprits, c) {
+				culprits = append(culprits, c)
+			}
+		}
+	}
+	if first == nil {
+		return other
+	}
+	return NewError(first.cause, first.task, first.round, first.victim, culprits...)
+}
+
+// MinorityCulprits returns the parties of ids whose value, values holding the value of each,
+// differs from the value of a strict majority of them, as compared with equal. It returns nil
+// when no value has a strict majority, as the culprits cannot be told apart then.
+func MinorityCulprits[T any](ids []*PartyID, values []T, equal func(a, b T) bool) []*PartyID {
+	for _, v := range values {
+		var culprits []*PartyID
+		for n, other := range values {
+			if !equal(v, other) {
+				culprits = append(culprits, ids[n])
+			}
+		}
+		if 2*len(culprits) < len(values) {
+			return culprits
+		}
+	}
+	return nil
+}
+
+func containsParty(ids []*PartyID, p *PartyID) bool {
+	for _, id := range ids {
+		if id.KeyInt().Cmp(p.KeyInt()) == 0 {
+			return true
+		}
+	}
+	return false
+}
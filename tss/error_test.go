@@ -24,3 +24,20 @@ func TestNewError(t *testing.T) {
 	assert.Equal(t, culprit, tssErr.Culprits()[0])
 	assert.Contains(t, tssErr.Error(), "test cause")
 }
+
+func TestMinorityCulprits(t *testing.T) {
+	ids := []*PartyID{
+		NewPartyID("1", "P1", big.NewInt(1)),
+		NewPartyID("2", "P2", big.NewInt(2)),
+		NewPartyID("3", "P3", big.NewInt(3)),
+		NewPartyID("4", "P4", big.NewInt(4)),
+	}
+	equal := func(a, b int) bool { return a == b }
+
+	assert.Empty(t, MinorityCulprits(ids, []int{1, 1, 1, 1}, equal))
+	assert.Equal(t, []*PartyID{ids[2]}, MinorityCulprits(ids, []int{1, 1, 2, 1}, equal))
+	assert.Equal(t, []*PartyID{ids[0]}, MinorityCulprits(ids[:3], []int{2, 1, 1}, equal))
+	// no strict majority
+	assert.Nil(t, MinorityCulprits(ids, []int{1, 1, 2, 2}, equal))
+	assert.Nil(t, MinorityCulprits(ids[:2], []int{1, 2}, equal))
+}
@@ -5,8 +5,13 @@ import (
 	"errors"
 	"fmt"
 	sync "sync"
+	"time"
 )
 
+// ErrRoundTimeout is the cause of the error reported when some parties did not send their
+// message for a round before the round timeout expired.
+var ErrRoundTimeout = errors.New("timed out waiting for round messages")
+
 // jsonExpect is an object used to collect messages from peers and trigger a callback once
 // enough messages have been collected
 type jsonExpect[T any] struct {
@@ -16,21 +21,64 @@ type jsonExpect[T any] struct {
 	missing int
 	lock    sync.Mutex
 	cb      func([]*PartyID, []*T)
+
+	timer     *time.Timer
+	onTimeout func([]*PartyID)
+	echo      *echo
+	data      [][]byte // json encoded data of the messages, for the echo
+	sigs      [][]byte // signatures of the messages, for the echo
+	private   bool
 }
 
-// JsonMessage is an object storing any kind of object for json transmission
+// ExpectOption configures optional behavior of a receiver created by NewJsonExpect.
+type ExpectOption func(*expectOptions)
+
+type expectOptions struct {
+	timeout   time.Duration
+	onTimeout func([]*PartyID)
+	echo      *echoOptions
+	private   bool
+}
+
+// WithTimeout makes the receiver call onTimeout with the parties that have not sent their
+// message if it is still incomplete after d. A zero duration disables the timeout. Messages
+// arriving after the timeout expired are rejected.
+func WithTimeout(d time.Duration, onTimeout func(missing []*PartyID)) ExpectOption {
+	return func(o *expectOptions) {
+		o.timeout = d
+		o.onTimeout = onTimeout
+	}
+}
+
+// WithPrivate marks the messages collected by the receiver as private to the local party, as
+// sent with JsonWrapPrivate, so that an EncryptingBroker rejects them when they arrive in the
+// clear.
+func WithPrivate() ExpectOption {
+	return func(o *expectOptions) {
+		o.private = true
+	}
+}
+
+// JsonMessage is an object storing any kind of object for json transmission. Signature is
+// set by SigningBroker when the sender has an identity key. Private marks messages holding
+// secrets for their recipient, which EncryptingBroker encrypts and flags as Encrypted.
 type JsonMessage struct {
-	Type string   `json:"type"`
-	From *PartyID `json:"from"`
-	To   *PartyID `json:"to"`
-	Data any      `json:"data"`
+	Type      string   `json:"type"`
+	From      *PartyID `json:"from"`
+	To        *PartyID `json:"to"`
+	Data      any      `json:"data"`
+	Signature []byte   `json:"sig,omitempty"`
+	Private   bool     `json:"-"`
+	Encrypted bool     `json:"encrypted,omitempty"`
 }
 
 type rawJsonMsg struct {
-	Type string          `json:"type"`
-	From *PartyID        `json:"from"`
-	To   *PartyID        `json:"to"`
-	Data json.RawMessage `json:"data"`
+	Type      string          `json:"type"`
+	From      *PartyID        `json:"from"`
+	To        *PartyID        `json:"to"`
+	Data      json.RawMessage `json:"data"`
+	Signature []byte          `json:"sig,omitempty"`
+	Encrypted bool            `json:"encrypted,omitempty"`
 }
 
 // UnmarshalJSON will set Type to the right value, and Data to a json.RawMessage of the actual data
@@ -45,6 +93,8 @@ func (j *JsonMessage) UnmarshalJSON(data []byte) error {
 	j.Data = v.Data
 	j.From = v.From
 	j.To = v.To
+	j.Signature = v.Signature
+	j.Encrypted = v.Encrypted
 	return nil
 }
 
@@ -68,9 +118,16 @@ func JsonWrap(typ string, o any, from *PartyID, to *PartyID) *JsonMessage {
 	return &JsonMessage{Type: typ, From: from, To: to, Data: o}
 }
 
+// JsonWrapPrivate is like JsonWrap, but marks the message as private to its recipient so it
+// is encrypted by EncryptingBroker.
+func JsonWrapPrivate(typ string, o any, from *PartyID, to *PartyID) *JsonMessage {
+	return &JsonMessage{Type: typ, From: from, To: to, Data: o, Private: true}
+}
+
 // NewJsonExpect returns a new MessageReceiver of the given type that can be used to collect
-// packets from multiple parties, and trigger a callback once everything has been collected
-func NewJsonExpect[T any](typ string, parties []*PartyID, cb func([]*PartyID, []*T)) MessageReceiver {
+// packets from multiple parties, and trigger a callback once everything has been collected.
+// Nil options are ignored.
+func NewJsonExpect[T any](typ string, parties []*PartyID, cb func([]*PartyID, []*T), opts ...ExpectOption) MessageReceiver {
 	res := &jsonExpect[T]{
 		Type:    typ,
 		From:    parties,
@@ -78,9 +135,63 @@ func NewJsonExpect[T any](typ string, parties []*PartyID, cb func([]*PartyID, []
 		missing: len(parties), // nothing received yet
 		cb:      cb,
 	}
+	var o expectOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	res.private = o.private
+	if o.timeout > 0 && o.onTimeout != nil && res.missing > 0 {
+		res.onTimeout = o.onTimeout
+		res.timer = time.AfterFunc(o.timeout, res.expire)
+	}
+	if o.echo != nil {
+		res.echo = newEcho(typ, &o)
+		res.data = make([][]byte, len(parties))
+		res.sigs = make([][]byte, len(parties))
+	}
 	return res
 }
 
+// expire is called when the timeout expires before all messages were received
+func (e *jsonExpect[T]) expire() {
+	e.lock.Lock()
+	if e.missing == 0 {
+		e.lock.Unlock()
+		return
+	}
+	var missing []*PartyID
+	for n, p := range e.From {
+		if e.Packet[n] == nil {
+			missing = append(missing, p)
+		}
+	}
+	// reject anything that would arrive later
+	e.missing = 0
+	e.Packet = nil
+	e.lock.Unlock()
+
+	e.onTimeout(missing)
+}
+
+// Expected returns the parties the receiver expects a message from.
+func (e *jsonExpect[T]) Expected() []*PartyID {
+	return e.From
+}
+
+func (e *jsonExpect[T]) Private() bool {
+	return e.private
+}
+
+// Cancel stops the timeout of the receiver, if any. It is called by SessionBroker when the
+// session is closed.
+func (e *jsonExpect[T]) Cancel() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+}
+
 func (e *jsonExpect[T]) Receive(msg *JsonMessage) error {
 	if msg.Type != e.Type {
 		// ignore
@@ -94,12 +205,6 @@ func (e *jsonExpect[T]) Receive(msg *JsonMessage) error {
 		return errors.New("json expect has completed")
 	}
 
-	// parse object if needed
-	obj, err := JsonGet[T](msg)
-	if err != nil {
-		return err
-	}
-
 	// locate the party
 	ki := msg.From.KeyInt()
 	for n, p := range e.From {
@@ -107,15 +212,44 @@ func (e *jsonExpect[T]) Receive(msg *JsonMessage) error {
 			// do not need to run expensive big.Int.Cmp() if we already got the packet
 			continue
 		}
-		if p.KeyInt().Cmp(ki) == 0 {
-			e.Packet[n] = obj
-			e.missing -= 1
-			if e.missing == 0 {
-				// complete!
-				e.cb(e.From, e.Packet)
+		if p.KeyInt().Cmp(ki) != 0 {
+			continue
+		}
+
+		// check the message against the identity key we know, not the one it claims
+		if p.IdentityKey != nil {
+			if err := msg.Verify(p.IdentityKey); err != nil {
+				return err
+			}
+		}
+
+		// parse object if needed
+		obj, err := JsonGet[T](msg)
+		if err != nil {
+			return err
+		}
+
+		if e.echo != nil {
+			if e.data[n], err = json.Marshal(msg.Data); err != nil {
+				return err
+			}
+			e.sigs[n] = msg.Signature
+		}
+
+		e.Packet[n] = obj
+		e.missing -= 1
+		if e.missing == 0 {
+			// complete!
+			if e.timer != nil {
+				e.timer.Stop()
+			}
+			if e.echo != nil {
+				e.echo.complete(e.From, e.data, e.sigs, func() { e.cb(e.From, e.Packet) })
+				return nil
 			}
-			return nil
+			e.cb(e.From, e.Packet)
 		}
+		return nil
 	}
 
 	// no party found
@@ -10,7 +10,9 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"io"
+	mrand "math/rand/v2"
 	"runtime"
+	"slices"
 	"time"
 )
 
@@ -32,6 +34,18 @@ type (
 		// random sources
 		partialKeyRand, rand io.Reader
 		broker               MessageBroker
+		// appended to message types so sessions can share a broker
+		sessionID string
+		// maximum time to wait for the messages of a round, 0 for no limit
+		roundTimeout time.Duration
+		// check broadcast messages were the same for every receiver
+		echoBroadcast bool
+		// for signing: do not normalize ECDSA signatures to low-S
+		keepHighS bool
+		// number of shares of each party of a weighted key, nil for one share each
+		weights []int
+		// rank of the share of each party of a hierarchical key, nil for a flat sharing
+		ranks []int
 	}
 
 	// ReSharingParameters extends Parameters with additional configuration for key re-sharing between old and new committees.
@@ -40,6 +54,8 @@ type (
 		newParties    *PeerContext
 		newPartyCount int
 		newThreshold  int
+		newWeights    []int
+		newRanks      []int
 	}
 )
 
@@ -72,6 +88,66 @@ func NewParameters(ec elliptic.Curve, ctx *PeerContext, partyID *PartyID, partyC
 	}
 }
 
+// NewWeightedParameters returns the Parameters of a session where the party of index j in
+// ctx holds weights[j] shares of the key, the threshold being counted in shares: any set of
+// parties whose weights sum to more than threshold can sign. The keygen deals weights[j]
+// Shamir shares to the party of index j, which it combines locally when signing.
+func NewWeightedParameters(ec elliptic.Curve, ctx *PeerContext, partyID *PartyID, weights []int, threshold int) *Parameters {
+	total := weightSum("NewWeightedParameters", weights)
+	if threshold < 0 || threshold >= total {
+		panic("NewWeightedParameters: threshold must satisfy 0 <= threshold < sum of the weights")
+	}
+	params := NewParameters(ec, ctx, partyID, len(weights), 0)
+	params.threshold = threshold
+	params.weights = slices.Clone(weights)
+	return params
+}
+
+// NewHierarchicalParameters returns the Parameters of a session with a hierarchical access
+// structure, following Tassa's hierarchical threshold secret sharing: the party of index j in
+// ctx holds the ranks[j]-th derivative at its key of the polynomial of degree threshold,
+// instead of its value. A set of parties can sign when, sorted by rank, the k-th of its first
+// threshold+1 parties (from 0) has a rank of at most k. For example with ranks 0 for a
+// compliance node and 1 for 3 operators and a threshold of 2, the compliance node and any 2
+// operators can sign, but not the operators alone; with ranks 0 for 2 HSMs and 1 for 5 humans
+// and a threshold of 2, an HSM and 2 other parties can sign. Ranks must not exceed the
+// threshold.
+func NewHierarchicalParameters(ec elliptic.Curve, ctx *PeerContext, partyID *PartyID, ranks []int, threshold int) *Parameters {
+	checkRanks("NewHierarchicalParameters", ranks, threshold)
+	params := NewParameters(ec, ctx, partyID, len(ranks), threshold)
+	params.ranks = slices.Clone(ranks)
+	return params
+}
+
+// checkRanks panics with a message prefixed by fn when ranks is empty or one of them is not
+// between 0 and threshold.
+func checkRanks(fn string, ranks []int, threshold int) {
+	if len(ranks) < 1 {
+		panic(fn + ": ranks must not be empty")
+	}
+	for _, r := range ranks {
+		if r < 0 || r > threshold {
+			panic(fn + ": ranks must satisfy 0 <= rank <= threshold")
+		}
+	}
+}
+
+// weightSum returns the sum of weights, panicking with a message prefixed by fn when one of
+// them is not positive.
+func weightSum(fn string, weights []int) int {
+	if len(weights) < 1 {
+		panic(fn + ": weights must not be empty")
+	}
+	total := 0
+	for _, w := range weights {
+		if w < 1 {
+			panic(fn + ": weights must be positive")
+		}
+		total += w
+	}
+	return total
+}
+
 // EC returns the elliptic curve used by this set of parameters.
 func (params *Parameters) EC() elliptic.Curve {
 	return params.ec
@@ -92,11 +168,24 @@ func (params *Parameters) PartyCount() int {
 	return params.partyCount
 }
 
-// Threshold returns the threshold value t, where t+1 parties are needed to sign.
+// Threshold returns the threshold value t, where t+1 parties are needed to sign. With
+// weighted parameters, t+1 is the number of shares the signing parties must hold together.
 func (params *Parameters) Threshold() int {
 	return params.threshold
 }
 
+// Weights returns the number of shares held by each party, indexed like Parties().IDs(), or
+// nil when each party holds a single share.
+func (params *Parameters) Weights() []int {
+	return params.weights
+}
+
+// Ranks returns the rank of the share of each party, indexed like Parties().IDs(), or nil
+// when the parameters are not hierarchical.
+func (params *Parameters) Ranks() []int {
+	return params.ranks
+}
+
 // Concurrency returns the concurrency level used for parallelizable operations.
 func (params *Parameters) Concurrency() int {
 	return params.concurrency
@@ -117,6 +206,45 @@ func (params *Parameters) SetSafePrimeGenTimeout(timeout time.Duration) {
 	params.safePrimeGenTimeout = timeout
 }
 
+// RoundTimeout returns how long a party waits for the messages of a round before failing.
+func (params *Parameters) RoundTimeout() time.Duration {
+	return params.roundTimeout
+}
+
+// SetRoundTimeout sets how long a party waits for the messages of a round. Once expired, the
+// protocol fails with a *Error listing the parties that did not send their message as
+// culprits. A zero duration, the default, waits forever.
+func (params *Parameters) SetRoundTimeout(timeout time.Duration) {
+	params.roundTimeout = timeout
+}
+
+// EchoBroadcast returns whether broadcast rounds are followed by an echo round.
+func (params *Parameters) EchoBroadcast() bool {
+	return params.echoBroadcast
+}
+
+// SetEchoBroadcast enables an echo round after each broadcast round, where receivers compare
+// the digests of the messages they got, so that a party sending different messages to
+// different peers is detected, and the round fails with a *Error caused by ErrEquivocation.
+// The sender is named as culprit when its identity key proves it signed both messages;
+// without identity keys, the culprits are the peers whose echoes disagree (see WithEcho).
+// All parties of a session must use the same setting.
+func (params *Parameters) SetEchoBroadcast(echo bool) {
+	params.echoBroadcast = echo
+}
+
+// KeepHighS returns whether ECDSA signatures are left with an S above N/2.
+func (params *Parameters) KeepHighS() bool {
+	return params.keepHighS
+}
+
+// SetKeepHighS disables the normalization of ECDSA signatures to an S of at most N/2 (BIP-62),
+// for the chains which require the S computed by the signers. All parties of a session must
+// use the same setting, or they output signatures with different S.
+func (params *Parameters) SetKeepHighS(keep bool) {
+	params.keepHighS = keep
+}
+
 // NoProofMod returns whether the modular proof is disabled for key generation.
 func (params *Parameters) NoProofMod() bool {
 	return params.noProofMod
@@ -147,6 +275,26 @@ func (params *Parameters) Broker() MessageBroker {
 	return params.broker
 }
 
+// SetSessionID sets a per-session id that is appended to every message type, allowing
+// multiple sessions to share the same broker. The id must be identical for all parties.
+func (params *Parameters) SetSessionID(id string) {
+	params.sessionID = id
+}
+
+// SessionID returns the session id set by SetSessionID, or an empty string.
+func (params *Parameters) SessionID() string {
+	return params.sessionID
+}
+
+// MsgType returns the message type used on the wire for the given base type, namespaced
+// by the session id if one was set.
+func (params *Parameters) MsgType(base string) string {
+	if params.sessionID == "" {
+		return base
+	}
+	return base + "#" + params.sessionID
+}
+
 // PartialKeyRand returns the random source used for partial key generation.
 func (params *Parameters) PartialKeyRand() io.Reader {
 	return params.partialKeyRand
@@ -157,6 +305,23 @@ func (params *Parameters) Rand() io.Reader {
 	return params.rand
 }
 
+// RandStream returns a random source derived from r for a task run concurrently with others,
+// such as the proofs made for each peer. When r is crypto/rand.Reader, it is returned as is.
+// Otherwise the stream is a ChaCha8 generator seeded from r, so that concurrent tasks draw
+// the same values whatever order they run in, and a session started with the same seeded
+// source makes the same messages (see ReplayBroker). Streams must be derived in the same order
+// in every run.
+func RandStream(r io.Reader) (io.Reader, error) {
+	if r == rand.Reader {
+		return r, nil
+	}
+	var seed [32]byte
+	if _, err := io.ReadFull(r, seed[:]); err != nil {
+		return nil, err
+	}
+	return mrand.NewChaCha8(seed), nil
+}
+
 // SetPartialKeyRand sets the random source used for partial key generation.
 func (params *Parameters) SetPartialKeyRand(rand io.Reader) {
 	params.partialKeyRand = rand
@@ -180,6 +345,44 @@ func NewReSharingParameters(ec elliptic.Curve, ctx, newCtx *PeerContext, partyID
 	}
 }
 
+// NewWeightedReSharingParameters returns the ReSharingParameters of a resharing between
+// weighted committees, as described by NewWeightedParameters: weights and threshold are those
+// of the old committee, newWeights and newThreshold those of the new one. The weights of a
+// committee holding one share per party are all 1.
+func NewWeightedReSharingParameters(ec elliptic.Curve, ctx, newCtx *PeerContext, partyID *PartyID, weights []int, threshold int, newWeights []int, newThreshold int) *ReSharingParameters {
+	params := NewWeightedParameters(ec, ctx, partyID, weights, threshold)
+	newTotal := weightSum("NewWeightedReSharingParameters", newWeights)
+	if newThreshold < 0 || newThreshold >= newTotal {
+		panic("NewWeightedReSharingParameters: newThreshold must satisfy 0 <= newThreshold < sum of the new weights")
+	}
+	return &ReSharingParameters{
+		Parameters:    params,
+		newParties:    newCtx,
+		newPartyCount: len(newWeights),
+		newThreshold:  newThreshold,
+		newWeights:    slices.Clone(newWeights),
+	}
+}
+
+// NewHierarchicalReSharingParameters returns the ReSharingParameters of a resharing between
+// hierarchical committees, as described by NewHierarchicalParameters: ranks and threshold are
+// those of the old committee, newRanks and newThreshold those of the new one. The ranks of a
+// committee with a flat sharing are all 0.
+func NewHierarchicalReSharingParameters(ec elliptic.Curve, ctx, newCtx *PeerContext, partyID *PartyID, ranks []int, threshold int, newRanks []int, newThreshold int) *ReSharingParameters {
+	params := NewHierarchicalParameters(ec, ctx, partyID, ranks, threshold)
+	checkRanks("NewHierarchicalReSharingParameters", newRanks, newThreshold)
+	if newThreshold >= len(newRanks) {
+		panic("NewHierarchicalReSharingParameters: newThreshold must be lower than the new party count")
+	}
+	return &ReSharingParameters{
+		Parameters:    params,
+		newParties:    newCtx,
+		newPartyCount: len(newRanks),
+		newThreshold:  newThreshold,
+		newRanks:      slices.Clone(newRanks),
+	}
+}
+
 // OldParties returns the PeerContext for the old committee in a re-sharing session.
 func (rgParams *ReSharingParameters) OldParties() *PeerContext {
 	return rgParams.Parties() // wr use the original method for old parties
@@ -205,6 +408,18 @@ func (rgParams *ReSharingParameters) NewThreshold() int {
 	return rgParams.newThreshold
 }
 
+// NewWeights returns the number of shares held by each party of the new committee, indexed
+// like NewParties().IDs(), or nil when each of them holds a single share.
+func (rgParams *ReSharingParameters) NewWeights() []int {
+	return rgParams.newWeights
+}
+
+// NewRanks returns the rank of the share of each party of the new committee, indexed like
+// NewParties().IDs(), or nil when the new committee has a flat sharing.
+func (rgParams *ReSharingParameters) NewRanks() []int {
+	return rgParams.newRanks
+}
+
 // OldAndNewParties returns the combined list of party IDs from both old and new committees.
 func (rgParams *ReSharingParameters) OldAndNewParties() []*PartyID {
 	return append(rgParams.OldParties().IDs(), rgParams.NewParties().IDs()...)
@@ -43,3 +43,79 @@ func TestNewParametersValidation(t *testing.T) {
 		NewParameters(ec, nil, nil, 1, 0)
 	}, "threshold=0, partyCount=1 should be valid")
 }
+
+// TestNewWeightedParametersValidation verifies that the threshold of weighted parameters is
+// counted in shares.
+func TestNewWeightedParametersValidation(t *testing.T) {
+	ec := EC()
+
+	assert.Panics(t, func() {
+		NewWeightedParameters(ec, nil, nil, nil, 0)
+	}, "no weights should panic")
+
+	assert.Panics(t, func() {
+		NewWeightedParameters(ec, nil, nil, []int{2, 0, 1}, 1)
+	}, "zero weight should panic")
+
+	assert.Panics(t, func() {
+		NewWeightedParameters(ec, nil, nil, []int{2, 1}, 3)
+	}, "threshold=sum of the weights should panic")
+
+	assert.Panics(t, func() {
+		NewWeightedReSharingParameters(ec, nil, nil, nil, []int{2, 1}, 2, []int{1, 1}, 2)
+	}, "newThreshold=sum of the new weights should panic")
+
+	// the treasury holding 2 shares signs with any operator
+	var params *Parameters
+	assert.NotPanics(t, func() {
+		params = NewWeightedParameters(ec, nil, nil, []int{2, 1, 1}, 2)
+	}, "threshold above the party count should be valid")
+	assert.Equal(t, 3, params.PartyCount())
+	assert.Equal(t, 2, params.Threshold())
+	assert.Equal(t, []int{2, 1, 1}, params.Weights())
+
+	var rgParams *ReSharingParameters
+	assert.NotPanics(t, func() {
+		rgParams = NewWeightedReSharingParameters(ec, nil, nil, nil, []int{2, 1, 1}, 2, []int{1, 1, 1, 1}, 2)
+	})
+	assert.Equal(t, 4, rgParams.NewPartyCount())
+	assert.Equal(t, []int{1, 1, 1, 1}, rgParams.NewWeights())
+}
+
+// TestNewHierarchicalParametersValidation verifies that the ranks of hierarchical parameters
+// do not exceed the threshold.
+func TestNewHierarchicalParametersValidation(t *testing.T) {
+	ec := EC()
+
+	assert.Panics(t, func() {
+		NewHierarchicalParameters(ec, nil, nil, nil, 0)
+	}, "no ranks should panic")
+
+	assert.Panics(t, func() {
+		NewHierarchicalParameters(ec, nil, nil, []int{0, -1, 1}, 2)
+	}, "negative rank should panic")
+
+	assert.Panics(t, func() {
+		NewHierarchicalParameters(ec, nil, nil, []int{0, 3, 1, 1}, 2)
+	}, "rank above the threshold should panic")
+
+	assert.Panics(t, func() {
+		NewHierarchicalReSharingParameters(ec, nil, nil, nil, []int{0, 1, 1}, 2, []int{0, 1}, 2)
+	}, "newThreshold=new party count should panic")
+
+	// the compliance node signs with any 2 operators
+	var params *Parameters
+	assert.NotPanics(t, func() {
+		params = NewHierarchicalParameters(ec, nil, nil, []int{0, 1, 1, 1}, 2)
+	})
+	assert.Equal(t, 4, params.PartyCount())
+	assert.Equal(t, 2, params.Threshold())
+	assert.Equal(t, []int{0, 1, 1, 1}, params.Ranks())
+
+	var rgParams *ReSharingParameters
+	assert.NotPanics(t, func() {
+		rgParams = NewHierarchicalReSharingParameters(ec, nil, nil, nil, []int{0, 1, 1, 1}, 2, []int{0, 0, 2, 2, 2}, 3)
+	})
+	assert.Equal(t, 5, rgParams.NewPartyCount())
+	assert.Equal(t, []int{0, 0, 2, 2, 2}, rgParams.NewRanks())
+}
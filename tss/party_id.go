@@ -7,6 +7,7 @@
 package tss
 
 import (
+	"crypto/ed25519"
 	"crypto/rand"
 	"fmt"
 	"math/big"
@@ -19,9 +20,12 @@ type (
 	// PartyID represents a participant in the TSS protocol rounds.
 	// Note: The `id` and `moniker` are provided for convenience to allow you to track participants easier.
 	// The `id` is intended to be a unique string representation of `key` and `moniker` can be anything (even left blank).
+	// When IdentityKey is set, messages claiming to come from this party are only accepted if
+	// they are signed with the matching private key (see SigningBroker).
 	PartyID struct {
 		*MessageWrapper_PartyID
-		Index int `json:"index"`
+		Index       int               `json:"index"`
+		IdentityKey ed25519.PublicKey `json:"identity_key,omitempty"`
 	}
 
 	// UnSortedPartyIDs is an unsorted slice of PartyID pointers.
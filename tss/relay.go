@@ -0,0 +1,305 @@
+package tss
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidBundle is returned by RelayParty when a bundle forwarded by the relay is
+// incomplete or holds messages that do not belong to it.
+var ErrInvalidBundle = errors.New("invalid relay bundle")
+
+// RelayBundle holds the messages of one type sent to a party, forwarded at once by a Relay.
+type RelayBundle struct {
+	Type     string         `json:"type"`
+	To       *PartyID       `json:"to"`
+	Messages []*JsonMessage `json:"messages"`
+}
+
+// RelaySubscription tells a Relay that a party waits for the messages of the given type from
+// the parties in From. An empty From means all the other parties of the relay.
+type RelaySubscription struct {
+	Type string     `json:"type"`
+	To   *PartyID   `json:"to"`
+	From []*PartyID `json:"from,omitempty"`
+}
+
+// RelayTransport carries the messages and subscriptions of a RelayParty to the relay. Relay
+// implements it, and network transports should pass what they carry to a Relay.
+type RelayTransport interface {
+	Send(msg *JsonMessage) error
+	Subscribe(sub *RelaySubscription) error
+}
+
+// Relay collects the messages of all parties, and forwards them to each recipient in a single
+// bundle per round, once all the messages it waits for have arrived. Parties then only need
+// to reach the relay, instead of each other.
+//
+// The relay is not trusted: parties check the bundles they receive with RelayParty, and should
+// sign their messages with a SigningBroker so that the relay cannot forge them, encrypt them
+// with an EncryptingBroker so that it cannot read private messages, and enable echo broadcast
+// so that it cannot send different broadcast messages to different parties.
+type Relay struct {
+	parties []*PartyID
+	deliver func(bundle *RelayBundle) error
+
+	lock    sync.Mutex
+	subs    map[string]*RelaySubscription
+	pending map[string][]*JsonMessage
+	flushed map[string]bool
+}
+
+// NewRelay returns a Relay for the given parties, calling deliver with the bundles to send
+// to each of them.
+func NewRelay(parties []*PartyID, deliver func(bundle *RelayBundle) error) *Relay {
+	return &Relay{
+		parties: parties,
+		deliver: deliver,
+		subs:    make(map[string]*RelaySubscription),
+		pending: make(map[string][]*JsonMessage),
+		flushed: make(map[string]bool),
+	}
+}
+
+// Send queues a message sent by a party, and forwards the bundle of its recipient if it is
+// complete.
+func (r *Relay) Send(msg *JsonMessage) error {
+	if msg.From == nil || msg.To == nil {
+		return errors.New("relayed messages must have a sender and a recipient")
+	}
+	if r.party(msg.From) == nil || r.party(msg.To) == nil {
+		return errors.New("message between unknown parties")
+	}
+	key := relayKey(msg.Type, msg.To)
+
+	r.lock.Lock()
+	if r.flushed[key] {
+		r.lock.Unlock()
+		return fmt.Errorf("round %s of %s is over", msg.Type, msg.To)
+	}
+	for _, other := range r.pending[key] {
+		if other.From.KeyInt().Cmp(msg.From.KeyInt()) == 0 {
+			r.lock.Unlock()
+			return fmt.Errorf("duplicate message %s from %s", msg.Type, msg.From)
+		}
+	}
+	r.pending[key] = append(r.pending[key], msg)
+	bundle := r.bundle(key)
+	r.lock.Unlock()
+
+	if bundle == nil {
+		return nil
+	}
+	return r.deliver(bundle)
+}
+
+// Subscribe records what a party waits for, and forwards its bundle if it is complete.
+func (r *Relay) Subscribe(sub *RelaySubscription) error {
+	if sub.To == nil || r.party(sub.To) == nil {
+		return errors.New("subscription from an unknown party")
+	}
+	if len(sub.From) == 0 {
+		var from []*PartyID
+		for _, p := range r.parties {
+			if p.KeyInt().Cmp(sub.To.KeyInt()) != 0 {
+				from = append(from, p)
+			}
+		}
+		sub = &RelaySubscription{Type: sub.Type, To: sub.To, From: from}
+	}
+	key := relayKey(sub.Type, sub.To)
+
+	r.lock.Lock()
+	if r.flushed[key] {
+		r.lock.Unlock()
+		return nil
+	}
+	r.subs[key] = sub
+	bundle := r.bundle(key)
+	r.lock.Unlock()
+
+	if bundle == nil {
+		return nil
+	}
+	return r.deliver(bundle)
+}
+
+// DropSession forgets the rounds of the given session id, as set with
+// Parameters.SetSessionID. It should be called once a session ends.
+func (r *Relay) DropSession(sessionID string) {
+	suffix := "#" + sessionID
+	inSession := func(key string) bool {
+		_, typ, _ := strings.Cut(key, "/")
+		return strings.HasSuffix(typ, suffix)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for key := range r.subs {
+		if inSession(key) {
+			delete(r.subs, key)
+		}
+	}
+	for key := range r.pending {
+		if inSession(key) {
+			delete(r.pending, key)
+		}
+	}
+	for key := range r.flushed {
+		if inSession(key) {
+			delete(r.flushed, key)
+		}
+	}
+}
+
+// bundle returns the bundle for key if all its messages have arrived, and marks it as sent.
+// The lock must be held.
+func (r *Relay) bundle(key string) *RelayBundle {
+	sub, ok := r.subs[key]
+	if !ok {
+		return nil
+	}
+	msgs := make([]*JsonMessage, 0, len(sub.From))
+	for _, p := range sub.From {
+		var found *JsonMessage
+		for _, msg := range r.pending[key] {
+			if msg.From.KeyInt().Cmp(p.KeyInt()) == 0 {
+				found = msg
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		msgs = append(msgs, found)
+	}
+
+	delete(r.subs, key)
+	delete(r.pending, key)
+	r.flushed[key] = true
+	return &RelayBundle{Type: sub.Type, To: sub.To, Messages: msgs}
+}
+
+func (r *Relay) party(p *PartyID) *PartyID {
+	for _, other := range r.parties {
+		if other.KeyInt().Cmp(p.KeyInt()) == 0 {
+			return other
+		}
+	}
+	return nil
+}
+
+func relayKey(typ string, to *PartyID) string {
+	return fmt.Sprintf("%x/%s", to.Key, typ)
+}
+
+// RelayParty is the MessageBroker of a party talking to the others through a Relay. Messages
+// sent by the party are passed to the relay, and receivers are subscribed to the relay when
+// connected. Bundles received from the relay must be passed to ReceiveBundle, which checks
+// that they hold exactly one message from each expected party before passing them on.
+type RelayParty struct {
+	self  *PartyID
+	relay RelayTransport
+
+	lock     sync.Mutex
+	rcv      map[string]MessageReceiver
+	expected map[string][]*PartyID
+}
+
+// NewRelayParty returns the broker of the local party self, talking to relay.
+func NewRelayParty(self *PartyID, relay RelayTransport) *RelayParty {
+	return &RelayParty{
+		self:     self,
+		relay:    relay,
+		rcv:      make(map[string]MessageReceiver),
+		expected: make(map[string][]*PartyID),
+	}
+}
+
+// Receive passes a message sent by the local party to the relay. Messages from other parties
+// are only accepted through ReceiveBundle.
+func (b *RelayParty) Receive(msg *JsonMessage) error {
+	if msg.From == nil || msg.From.KeyInt().Cmp(b.self.KeyInt()) != 0 {
+		return errors.New("messages from other parties must come in a relay bundle")
+	}
+	return b.relay.Send(msg)
+}
+
+// Connect registers dest for the given type, and subscribes to the messages it expects.
+func (b *RelayParty) Connect(typ string, dest MessageReceiver) {
+	expected := expectedParties(dest)
+
+	b.lock.Lock()
+	b.rcv[typ] = dest
+	b.expected[typ] = expected
+	b.lock.Unlock()
+
+	// a failed subscription surfaces as a timeout of the round
+	_ = b.relay.Subscribe(&RelaySubscription{Type: typ, To: b.self, From: expected})
+}
+
+// Disconnect unregisters the receiver for the given type.
+func (b *RelayParty) Disconnect(typ string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.rcv, typ)
+	delete(b.expected, typ)
+}
+
+// ReceiveBundle checks a bundle forwarded by the relay and passes its messages to the
+// receiver of their type. The bundle must hold exactly one message of its type from each
+// party the receiver expects, all addressed to the local party.
+func (b *RelayParty) ReceiveBundle(bundle *RelayBundle) error {
+	b.lock.Lock()
+	dest, ok := b.rcv[bundle.Type]
+	expected := b.expected[bundle.Type]
+	b.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("no handler for message type %s", bundle.Type)
+	}
+
+	if err := b.checkBundle(bundle, expected); err != nil {
+		return err
+	}
+	var errs []error
+	for _, msg := range bundle.Messages {
+		if err := dest.Receive(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *RelayParty) checkBundle(bundle *RelayBundle, expected []*PartyID) error {
+	if bundle.To == nil || bundle.To.KeyInt().Cmp(b.self.KeyInt()) != 0 {
+		return fmt.Errorf("%w: sent to another party", ErrInvalidBundle)
+	}
+	seen := make(map[string]bool, len(bundle.Messages))
+	for _, msg := range bundle.Messages {
+		switch {
+		case msg.Type != bundle.Type:
+			return fmt.Errorf("%w: message of type %s", ErrInvalidBundle, msg.Type)
+		case msg.To == nil || msg.To.KeyInt().Cmp(b.self.KeyInt()) != 0:
+			return fmt.Errorf("%w: message sent to another party", ErrInvalidBundle)
+		case msg.From == nil:
+			return fmt.Errorf("%w: message without sender", ErrInvalidBundle)
+		case seen[string(msg.From.Key)]:
+			return fmt.Errorf("%w: several messages from %s", ErrInvalidBundle, msg.From)
+		}
+		seen[string(msg.From.Key)] = true
+	}
+	if expected == nil {
+		return nil
+	}
+	if len(bundle.Messages) != len(expected) {
+		return fmt.Errorf("%w: %d messages instead of %d", ErrInvalidBundle, len(bundle.Messages), len(expected))
+	}
+	for _, p := range expected {
+		if !seen[string(p.Key)] {
+			return fmt.Errorf("%w: missing message from %s", ErrInvalidBundle, p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package tss
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	pIDs := SortPartyIDs(UnSortedPartyIDs{
+		NewPartyID("1", "P1", big.NewInt(1)),
+		NewPartyID("2", "P2", big.NewInt(2)),
+		NewPartyID("3", "P3", big.NewInt(3)),
+	})
+
+	var bundles []*RelayBundle
+	parties := make([]*RelayParty, len(pIDs))
+	relay := NewRelay(pIDs, func(bundle *RelayBundle) error {
+		bundles = append(bundles, bundle)
+		return parties[bundle.To.Index].ReceiveBundle(bundle)
+	})
+	for i, p := range pIDs {
+		parties[i] = NewRelayParty(p, relay)
+	}
+
+	// every party broadcasts, party 1 is late to connect its receiver
+	got := make([][]*Payload, len(pIDs))
+	connect := func(i int) {
+		others := pIDs.Exclude(pIDs[i])
+		parties[i].Connect("round1", NewJsonExpect[Payload]("round1", others, func(from []*PartyID, packets []*Payload) {
+			got[i] = packets
+		}))
+	}
+	connect(0)
+	connect(2)
+	for i, p := range pIDs {
+		for _, q := range pIDs.Exclude(p) {
+			require.NoError(t, parties[i].Receive(JsonWrap("round1", &Payload{Value: i}, p, q)))
+		}
+	}
+	assert.Len(t, bundles, 2)
+	assert.Nil(t, got[1])
+	connect(1)
+
+	require.Len(t, bundles, 3)
+	for i, packets := range got {
+		require.Len(t, packets, 2, "party %d", i)
+	}
+	assert.Equal(t, 1, got[0][0].Value)
+	assert.Equal(t, 2, got[0][1].Value)
+
+	// parties cannot be sent messages directly, nor twice
+	assert.Error(t, parties[0].Receive(JsonWrap("round1", &Payload{}, pIDs[1], pIDs[0])))
+	assert.Error(t, relay.Send(JsonWrap("round1", &Payload{}, pIDs[1], pIDs[0])))
+}
+
+func TestRelayPartyRejectsInvalidBundles(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	pIDs := SortPartyIDs(UnSortedPartyIDs{
+		NewPartyID("1", "P1", big.NewInt(1)),
+		NewPartyID("2", "P2", big.NewInt(2)),
+		NewPartyID("3", "P3", big.NewInt(3)),
+	})
+	p0 := NewRelayParty(pIDs[0], NewRelay(pIDs, func(*RelayBundle) error { return nil }))
+	var got []*Payload
+	p0.Connect("round1", NewJsonExpect[Payload]("round1", pIDs[1:], func(from []*PartyID, packets []*Payload) {
+		got = packets
+	}))
+
+	msg := func(typ string, from, to *PartyID) *JsonMessage {
+		return JsonWrap(typ, &Payload{}, from, to)
+	}
+	for name, bundle := range map[string]*RelayBundle{
+		"incomplete":      {Type: "round1", To: pIDs[0], Messages: []*JsonMessage{msg("round1", pIDs[1], pIDs[0])}},
+		"duplicate":       {Type: "round1", To: pIDs[0], Messages: []*JsonMessage{msg("round1", pIDs[1], pIDs[0]), msg("round1", pIDs[1], pIDs[0])}},
+		"other type":      {Type: "round1", To: pIDs[0], Messages: []*JsonMessage{msg("round1", pIDs[1], pIDs[0]), msg("round2", pIDs[2], pIDs[0])}},
+		"other recipient": {Type: "round1", To: pIDs[0], Messages: []*JsonMessage{msg("round1", pIDs[1], pIDs[0]), msg("round1", pIDs[2], pIDs[1])}},
+		"other party":     {Type: "round1", To: pIDs[1], Messages: []*JsonMessage{msg("round1", pIDs[0], pIDs[1]), msg("round1", pIDs[2], pIDs[1])}},
+	} {
+		assert.ErrorIs(t, p0.ReceiveBundle(bundle), ErrInvalidBundle, name)
+	}
+	assert.Nil(t, got)
+
+	require.NoError(t, p0.ReceiveBundle(&RelayBundle{Type: "round1", To: pIDs[0], Messages: []*JsonMessage{
+		msg("round1", pIDs[2], pIDs[0]),
+		msg("round1", pIDs[1], pIDs[0]),
+	}}))
+	assert.Len(t, got, 2)
+}
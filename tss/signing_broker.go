@@ -0,0 +1,104 @@
+package tss
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidSignature is returned when a message is not signed by the identity key of the
+// party it claims to come from.
+var ErrInvalidSignature = errors.New("invalid message signature")
+
+const signatureContext = "tss-lib/message/v1"
+
+// SigningBroker wraps a MessageBroker and signs every message sent by the local party with its
+// identity key. Receivers created with NewJsonExpect check these signatures against the
+// IdentityKey of the expected parties, so all parties should know the identity key of their
+// peers and use a SigningBroker.
+//
+// The signatures cover the message type, which holds the session id set with
+// Parameters.SetSessionID. Sessions should have distinct session ids: otherwise a message
+// signed in an earlier session is valid in the next one.
+type SigningBroker struct {
+	broker MessageBroker
+	self   *PartyID
+	key    ed25519.PrivateKey
+}
+
+// NewSigningBroker returns a SigningBroker signing the messages sent by self with key, and
+// passing them to b.
+func NewSigningBroker(b MessageBroker, self *PartyID, key ed25519.PrivateKey) *SigningBroker {
+	return &SigningBroker{broker: b, self: self, key: key}
+}
+
+// Receive signs the message if it was sent by the local party, and passes it to the
+// underlying broker.
+func (b *SigningBroker) Receive(msg *JsonMessage) error {
+	if msg.From != nil && msg.Signature == nil && msg.From.KeyInt().Cmp(b.self.KeyInt()) == 0 {
+		if err := msg.Sign(b.key); err != nil {
+			return err
+		}
+	}
+	return b.broker.Receive(msg)
+}
+
+// Connect registers dest for the given type on the underlying broker.
+func (b *SigningBroker) Connect(typ string, dest MessageReceiver) {
+	b.broker.Connect(typ, dest)
+}
+
+// Disconnect unregisters the receiver for the given type on the underlying broker.
+func (b *SigningBroker) Disconnect(typ string) {
+	disconnect(b.broker, typ)
+}
+
+// Sign sets the signature of the message, made with the identity key of its sender.
+func (j *JsonMessage) Sign(key ed25519.PrivateKey) error {
+	buf, err := j.signedData()
+	if err != nil {
+		return err
+	}
+	j.Signature = ed25519.Sign(key, buf)
+	return nil
+}
+
+// Verify checks that the message was signed with the private key matching pub.
+func (j *JsonMessage) Verify(pub ed25519.PublicKey) error {
+	if len(j.Signature) != ed25519.SignatureSize {
+		return ErrInvalidSignature
+	}
+	buf, err := j.signedData()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, buf, j.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signedData returns the bytes covered by the signature: the type, with the session id it
+// holds, sender, recipient and json encoded data of the message, each prefixed with its
+// length.
+func (j *JsonMessage) signedData() ([]byte, error) {
+	data, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, err
+	}
+	var from, to []byte
+	if j.From != nil {
+		from = j.From.Key
+	}
+	if j.To != nil {
+		to = j.To.Key
+	}
+
+	buf := []byte(signatureContext)
+	for _, v := range [][]byte{[]byte(j.Type), from, to, data} {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(v)))
+		buf = append(buf, v...)
+	}
+	return buf, nil
+}
@@ -0,0 +1,86 @@
+package tss
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonMessageSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+
+	msg := JsonWrap("round1", map[string]int{"value": 1}, p1, p2)
+	assert.ErrorIs(t, msg.Verify(pub), ErrInvalidSignature)
+
+	require.NoError(t, msg.Sign(priv))
+	assert.NoError(t, msg.Verify(pub))
+	assert.ErrorIs(t, msg.Verify(otherPub), ErrInvalidSignature)
+
+	// the signature survives a round trip through json
+	buf, err := json.Marshal(msg)
+	require.NoError(t, err)
+	var decoded *JsonMessage
+	require.NoError(t, json.Unmarshal(buf, &decoded))
+	assert.NoError(t, decoded.Verify(pub))
+
+	// and covers the type, recipient and data
+	decoded.Type = "round2"
+	assert.ErrorIs(t, decoded.Verify(pub), ErrInvalidSignature)
+	decoded.Type = "round1"
+	decoded.To = p1
+	assert.ErrorIs(t, decoded.Verify(pub), ErrInvalidSignature)
+	decoded.To = p2
+	decoded.Data = json.RawMessage(`{"value":2}`)
+	assert.ErrorIs(t, decoded.Verify(pub), ErrInvalidSignature)
+}
+
+func TestSigningBroker(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	pub1, priv1, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, priv2, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p1.IdentityKey = pub1
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+
+	var got []*Payload
+	rcv := NewJsonExpect[Payload]("round1", []*PartyID{p1}, func(from []*PartyID, packets []*Payload) {
+		got = packets
+	})
+
+	b := NewTestBroker()
+	b.Connect("round1", rcv)
+
+	// unsigned, or signed with another key: rejected
+	assert.ErrorIs(t, b.Receive(JsonWrap("round1", &Payload{Value: 1}, p1, p2)), ErrInvalidSignature)
+	forged := JsonWrap("round1", &Payload{Value: 1}, p1, p2)
+	require.NoError(t, forged.Sign(priv2))
+	assert.ErrorIs(t, b.Receive(forged), ErrInvalidSignature)
+
+	// a message claiming another identity key is still checked against the known one
+	impostor := NewPartyID("1", "P1", big.NewInt(1))
+	impostor.IdentityKey = priv2.Public().(ed25519.PublicKey)
+	signer := NewSigningBroker(b, impostor, priv2)
+	assert.ErrorIs(t, signer.Receive(JsonWrap("round1", &Payload{Value: 1}, impostor, p2)), ErrInvalidSignature)
+	assert.Nil(t, got)
+
+	signer = NewSigningBroker(b, p1, priv1)
+	require.NoError(t, signer.Receive(JsonWrap("round1", &Payload{Value: 2}, p1, p2)))
+	require.Len(t, got, 1)
+	assert.Equal(t, 2, got[0].Value)
+}
@@ -0,0 +1,223 @@
+// Package simnet simulates the network between the parties of a protocol, for tests.
+//
+// Messages are serialized as they would be on a real transport and delivered asynchronously,
+// with configurable latency, reordering, duplication and loss. The network can also be split
+// in partitions, and keeps per-session statistics of the traffic.
+package simnet
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+// Config describes the behaviour of a simulated network.
+type Config struct {
+	Latency       time.Duration // delay of every message
+	Jitter        time.Duration // random extra delay of up to Jitter, reordering messages
+	DuplicateRate float64       // probability that a message is delivered twice
+	DropRate      float64       // probability that a message is lost
+	Seed          uint64        // seed of the random decisions of the network
+}
+
+// Stats holds the traffic of a session.
+type Stats struct {
+	Messages   int // messages sent
+	Bytes      int // bytes sent, as serialized json
+	Delivered  int // messages delivered, including duplicates
+	Duplicated int // messages delivered twice
+	Dropped    int // messages lost or blocked by a partition
+}
+
+// Network connects a set of parties. Each party gets a tss.BufferedBroker, so messages that
+// arrive before their round has started are queued.
+type Network struct {
+	cfg Config
+
+	lock      sync.Mutex
+	rnd       *rand.Rand
+	brokers   map[string]*tss.BufferedBroker // by party key
+	partition map[string]int                 // group of each party, nil when not partitioned
+	stats     map[string]*Stats
+	inflight  sync.WaitGroup
+	closed    bool
+}
+
+// New returns a network connecting the given parties.
+func New(parties []*tss.PartyID, cfg Config) *Network {
+	n := &Network{
+		cfg:     cfg,
+		rnd:     rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		brokers: make(map[string]*tss.BufferedBroker),
+		stats:   make(map[string]*Stats),
+	}
+	for _, p := range parties {
+		n.brokers[string(p.Key)] = tss.NewBufferedBroker(p, &transport{net: n})
+	}
+	return n
+}
+
+// Broker returns the broker of the given party, or nil if it is not part of the network.
+func (n *Network) Broker(p *tss.PartyID) tss.MessageBroker {
+	b, ok := n.brokers[string(p.Key)]
+	if !ok {
+		return nil
+	}
+	return b
+}
+
+// Partition splits the network in the given groups: messages between parties of different
+// groups are dropped. Parties that are not part of any group cannot reach anyone.
+func (n *Network) Partition(groups ...[]*tss.PartyID) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.partition = make(map[string]int)
+	for g, group := range groups {
+		for _, p := range group {
+			n.partition[string(p.Key)] = g + 1
+		}
+	}
+}
+
+// Heal removes the partitions of the network.
+func (n *Network) Heal() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.partition = nil
+}
+
+// Stats returns the traffic of the given session, as set with Parameters.SetSessionID.
+// Messages of sessions without an id are counted in the session "".
+func (n *Network) Stats(session string) Stats {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if s, ok := n.stats[session]; ok {
+		return *s
+	}
+	return Stats{}
+}
+
+// Wait blocks until the messages in flight have been delivered or dropped.
+func (n *Network) Wait() {
+	n.inflight.Wait()
+}
+
+// Close stops the delivery of messages, including the ones in flight.
+func (n *Network) Close() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.closed = true
+}
+
+// send is called with the messages leaving a party.
+func (n *Network) send(msg *tss.JsonMessage) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	stats := n.session(msg.Type)
+	stats.Messages++
+	stats.Bytes += len(buf)
+	if n.closed {
+		stats.Dropped++
+		return nil
+	}
+
+	var dests []string
+	if msg.To != nil {
+		dests = append(dests, string(msg.To.Key))
+	} else {
+		for key := range n.brokers {
+			if key != string(msg.From.Key) {
+				dests = append(dests, key)
+			}
+		}
+	}
+
+	for _, dest := range dests {
+		b, ok := n.brokers[dest]
+		if !ok || n.blocked(string(msg.From.Key), dest) || n.rnd.Float64() < n.cfg.DropRate {
+			stats.Dropped++
+			continue
+		}
+		copies := 1
+		if n.rnd.Float64() < n.cfg.DuplicateRate {
+			copies = 2
+			stats.Duplicated++
+		}
+		for range copies {
+			n.deliverLater(b, buf, stats)
+		}
+	}
+	return nil
+}
+
+// deliverLater delivers a copy of buf to b after the latency of the network. The lock must be
+// held.
+func (n *Network) deliverLater(b *tss.BufferedBroker, buf []byte, stats *Stats) {
+	delay := n.cfg.Latency
+	if n.cfg.Jitter > 0 {
+		delay += time.Duration(n.rnd.Int64N(int64(n.cfg.Jitter)))
+	}
+
+	n.inflight.Add(1)
+	time.AfterFunc(delay, func() {
+		defer n.inflight.Done()
+
+		var msg *tss.JsonMessage
+		if err := json.Unmarshal(buf, &msg); err != nil {
+			return
+		}
+		n.lock.Lock()
+		closed := n.closed
+		if !closed {
+			stats.Delivered++
+		}
+		n.lock.Unlock()
+		if closed {
+			return
+		}
+		// receivers reject duplicates and invalid messages, like they would on a real network
+		_ = b.Receive(msg)
+	})
+}
+
+// blocked reports whether a partition prevents messages between the parties with the given
+// keys. The lock must be held.
+func (n *Network) blocked(from, to string) bool {
+	if n.partition == nil {
+		return false
+	}
+	g := n.partition[from]
+	return g == 0 || g != n.partition[to]
+}
+
+// session returns the stats of the session of the given message type. The lock must be held.
+func (n *Network) session(typ string) *Stats {
+	var id string
+	if i := strings.LastIndexByte(typ, '#'); i >= 0 {
+		id = typ[i+1:]
+	}
+	s, ok := n.stats[id]
+	if !ok {
+		s = &Stats{}
+		n.stats[id] = s
+	}
+	return s
+}
+
+// transport hands the messages sent by a party to the network.
+type transport struct {
+	net *Network
+}
+
+func (t *transport) Receive(msg *tss.JsonMessage) error {
+	return t.net.send(msg)
+}
@@ -0,0 +1,120 @@
+package simnet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/KarpelesLab/tss-lib/v2/tss"
+)
+
+type payload struct {
+	Value int `json:"value"`
+}
+
+// collector records the messages it is passed.
+type collector struct {
+	lock sync.Mutex
+	msgs []*tss.JsonMessage
+}
+
+func (c *collector) Receive(msg *tss.JsonMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.msgs = append(c.msgs, msg)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.msgs)
+}
+
+func TestDelivery(t *testing.T) {
+	pIDs := tss.GenerateTestPartyIDs(3)
+	net := New(pIDs, Config{Latency: 5 * time.Millisecond, Jitter: 20 * time.Millisecond, Seed: 1})
+
+	cs := make([]*collector, len(pIDs))
+	for i, p := range pIDs {
+		cs[i] = &collector{}
+		net.Broker(p).Connect("round1#s1", cs[i])
+	}
+
+	const count = 50
+	for v := range count {
+		require.NoError(t, net.Broker(pIDs[0]).Receive(tss.JsonWrap("round1#s1", &payload{Value: v}, pIDs[0], pIDs[1])))
+	}
+	// broadcast
+	require.NoError(t, net.Broker(pIDs[0]).Receive(tss.JsonWrap("round1#s1", &payload{Value: count}, pIDs[0], nil)))
+	net.Wait()
+
+	assert.Equal(t, 0, cs[0].count())
+	require.Equal(t, count+1, cs[1].count())
+	assert.Equal(t, 1, cs[2].count())
+
+	// messages are delivered serialized, and reordered by the jitter
+	reordered := false
+	for n, msg := range cs[1].msgs {
+		p, err := tss.JsonGet[payload](msg)
+		require.NoError(t, err)
+		if p.Value != n {
+			reordered = true
+		}
+	}
+	assert.True(t, reordered)
+
+	stats := net.Stats("s1")
+	assert.Equal(t, count+1, stats.Messages)
+	assert.Equal(t, count+2, stats.Delivered)
+	assert.Positive(t, stats.Bytes)
+	assert.Equal(t, Stats{}, net.Stats(""))
+}
+
+func TestFaults(t *testing.T) {
+	pIDs := tss.GenerateTestPartyIDs(3)
+	net := New(pIDs, Config{DuplicateRate: 0.5, DropRate: 0.2, Seed: 2})
+
+	c := &collector{}
+	net.Broker(pIDs[1]).Connect("round1", c)
+
+	const count = 200
+	for v := range count {
+		require.NoError(t, net.Broker(pIDs[0]).Receive(tss.JsonWrap("round1", &payload{Value: v}, pIDs[0], pIDs[1])))
+	}
+	net.Wait()
+
+	stats := net.Stats("")
+	assert.Positive(t, stats.Duplicated)
+	assert.Positive(t, stats.Dropped)
+	assert.Equal(t, count-stats.Dropped+stats.Duplicated, stats.Delivered)
+	assert.Equal(t, stats.Delivered, c.count())
+}
+
+func TestPartition(t *testing.T) {
+	pIDs := tss.GenerateTestPartyIDs(4)
+	net := New(pIDs, Config{})
+
+	cs := make([]*collector, len(pIDs))
+	for i, p := range pIDs {
+		cs[i] = &collector{}
+		net.Broker(p).Connect("round1", cs[i])
+	}
+
+	// party 3 is in no group
+	net.Partition([]*tss.PartyID{pIDs[0], pIDs[1]}, []*tss.PartyID{pIDs[2]})
+	for _, p := range pIDs {
+		require.NoError(t, net.Broker(p).Receive(tss.JsonWrap("round1", &payload{}, p, nil)))
+	}
+	net.Wait()
+	assert.Equal(t, []int{1, 1, 0, 0}, []int{cs[0].count(), cs[1].count(), cs[2].count(), cs[3].count()})
+	assert.Equal(t, 10, net.Stats("").Dropped)
+
+	net.Heal()
+	require.NoError(t, net.Broker(pIDs[3]).Receive(tss.JsonWrap("round1", &payload{}, pIDs[3], pIDs[2])))
+	net.Wait()
+	assert.Equal(t, 1, cs[2].count())
+}
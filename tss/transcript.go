@@ -0,0 +1,235 @@
+package tss
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Directions of the messages recorded in a transcript.
+const (
+	TranscriptSent     = "sent"
+	TranscriptReceived = "received"
+)
+
+// ErrReplayDiverged is the error reported by ReplayBroker when the replayed session sends a
+// message that differs from the recorded one.
+var ErrReplayDiverged = errors.New("replayed session diverged from the transcript")
+
+// TranscriptEntry is a message recorded by a RecordingBroker.
+type TranscriptEntry struct {
+	Time    time.Time    `json:"time"`
+	Dir     string       `json:"dir"`
+	Message *JsonMessage `json:"msg"`
+}
+
+// RecordingBroker wraps a MessageBroker and writes every message sent by the local party, and
+// every message passed to the receivers of the local party, to a transcript. The transcript
+// has one json encoded TranscriptEntry per line, and can be replayed with a ReplayBroker.
+//
+// The RecordingBroker should wrap any SigningBroker or EncryptingBroker, so that received
+// messages are recorded once decrypted. The transcript then holds the secret shares sent to
+// the local party, and must be protected accordingly.
+type RecordingBroker struct {
+	broker MessageBroker
+	self   *PartyID
+
+	lock sync.Mutex
+	enc  *json.Encoder
+	err  error
+}
+
+// NewRecordingBroker returns a RecordingBroker for the local party self, passing messages to
+// b and writing the transcript to w.
+func NewRecordingBroker(b MessageBroker, self *PartyID, w io.Writer) *RecordingBroker {
+	return &RecordingBroker{broker: b, self: self, enc: json.NewEncoder(w)}
+}
+
+// Receive records the message if it was sent by the local party, and passes it to the
+// underlying broker. Other messages are recorded when they reach their receiver.
+func (b *RecordingBroker) Receive(msg *JsonMessage) error {
+	if msg.From != nil && msg.From.KeyInt().Cmp(b.self.KeyInt()) == 0 {
+		b.record(TranscriptSent, msg)
+	}
+	return b.broker.Receive(msg)
+}
+
+// Connect registers dest for the given type on the underlying broker, recording the messages
+// passed to it.
+func (b *RecordingBroker) Connect(typ string, dest MessageReceiver) {
+	b.broker.Connect(typ, &recordingReceiver{broker: b, dest: dest})
+}
+
+// Disconnect unregisters the receiver for the given type on the underlying broker.
+func (b *RecordingBroker) Disconnect(typ string) {
+	disconnect(b.broker, typ)
+}
+
+// Err returns the first error that happened while writing the transcript, if any.
+func (b *RecordingBroker) Err() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.err
+}
+
+func (b *RecordingBroker) record(dir string, msg *JsonMessage) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.err != nil {
+		return
+	}
+	b.err = b.enc.Encode(&TranscriptEntry{Time: time.Now(), Dir: dir, Message: msg})
+}
+
+// recordingReceiver records the messages passed to its receiver.
+type recordingReceiver struct {
+	broker *RecordingBroker
+	dest   MessageReceiver
+}
+
+func (r *recordingReceiver) Expected() []*PartyID {
+	return expectedParties(r.dest)
+}
+
+func (r *recordingReceiver) Private() bool {
+	return isPrivate(r.dest)
+}
+
+func (r *recordingReceiver) Receive(msg *JsonMessage) error {
+	r.broker.record(TranscriptReceived, msg)
+	return r.dest.Receive(msg)
+}
+
+// ReadTranscript reads the entries of a transcript written by a RecordingBroker.
+func ReadTranscript(r io.Reader) ([]*TranscriptEntry, error) {
+	var res []*TranscriptEntry
+	dec := json.NewDecoder(r)
+	for {
+		var entry *TranscriptEntry
+		if err := dec.Decode(&entry); err != nil {
+			if err == io.EOF {
+				return res, nil
+			}
+			return nil, err
+		}
+		if entry.Message == nil {
+			return nil, errors.New("transcript entry has no message")
+		}
+		res = append(res, entry)
+	}
+}
+
+// ReplayBroker is a MessageBroker that feeds the messages received in a transcript to a new
+// session, in place of the network. Each receiver is given the recorded messages of its type
+// as soon as it is connected, in the order they were received.
+//
+// To reproduce a session, the new session must use the same parameters, key and random
+// sources (see Parameters.SetRand and Parameters.SetPartialKeyRand) as the recorded one. The
+// messages it sends are compared with the recorded ones, and the first difference is
+// reported by Diverged. Protocols computing values concurrently, such as the MtA proofs of
+// ecdsatss, give each task its own stream derived with RandStream, so that they draw the same
+// random values as in the recorded session.
+type ReplayBroker struct {
+	lock     sync.Mutex
+	recv     map[string][]*JsonMessage
+	sent     map[string][]*JsonMessage // by type and recipient
+	diverged error
+}
+
+// NewReplayBroker returns a ReplayBroker replaying the given transcript entries.
+func NewReplayBroker(entries []*TranscriptEntry) *ReplayBroker {
+	b := &ReplayBroker{
+		recv: make(map[string][]*JsonMessage),
+		sent: make(map[string][]*JsonMessage),
+	}
+	for _, entry := range entries {
+		msg := entry.Message
+		switch entry.Dir {
+		case TranscriptReceived:
+			b.recv[msg.Type] = append(b.recv[msg.Type], msg)
+		case TranscriptSent:
+			key := replayKey(msg)
+			b.sent[key] = append(b.sent[key], msg)
+		}
+	}
+	return b
+}
+
+// Receive compares a message sent by the replayed session with the recorded one. Messages are
+// not delivered anywhere.
+func (b *ReplayBroker) Receive(msg *JsonMessage) error {
+	key := replayKey(msg)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	queue := b.sent[key]
+	if len(queue) == 0 {
+		return b.diverge(fmt.Errorf("%w: unexpected message %s to %v", ErrReplayDiverged, msg.Type, msg.To))
+	}
+	b.sent[key] = queue[1:]
+
+	same, err := sameData(msg, queue[0])
+	if err != nil {
+		return err
+	}
+	if !same {
+		return b.diverge(fmt.Errorf("%w: message %s to %v differs", ErrReplayDiverged, msg.Type, msg.To))
+	}
+	return nil
+}
+
+// Connect registers dest for the given type, and passes it the recorded messages of that type.
+func (b *ReplayBroker) Connect(typ string, dest MessageReceiver) {
+	b.lock.Lock()
+	queued := b.recv[typ]
+	delete(b.recv, typ)
+	b.lock.Unlock()
+
+	for _, msg := range queued {
+		// the receiver rejects the same messages as in the recorded session
+		_ = dest.Receive(msg)
+	}
+}
+
+// Disconnect does nothing, as all the recorded messages were passed on Connect.
+func (b *ReplayBroker) Disconnect(typ string) {}
+
+// Diverged returns an error wrapping ErrReplayDiverged if the replayed session sent a message
+// that differs from the transcript, describing the first difference.
+func (b *ReplayBroker) Diverged() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.diverged
+}
+
+func (b *ReplayBroker) diverge(err error) error {
+	if b.diverged == nil {
+		b.diverged = err
+	}
+	return err
+}
+
+func replayKey(msg *JsonMessage) string {
+	var to []byte
+	if msg.To != nil {
+		to = msg.To.Key
+	}
+	return fmt.Sprintf("%s/%x", msg.Type, to)
+}
+
+// sameData reports whether two messages hold the same json encoded data.
+func sameData(a, b *JsonMessage) (bool, error) {
+	bufA, err := json.Marshal(a.Data)
+	if err != nil {
+		return false, err
+	}
+	bufB, err := json.Marshal(b.Data)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(bufA, bufB), nil
+}
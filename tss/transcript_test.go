@@ -0,0 +1,59 @@
+package tss
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+
+	// p1 receives a message from p2 and answers it
+	var buf bytes.Buffer
+	var got *Payload
+	run := func(b MessageBroker, answer int) {
+		got = nil
+		b.Connect("round1", NewJsonExpect[Payload]("round1", []*PartyID{p2}, func(from []*PartyID, packets []*Payload) {
+			got = packets[0]
+			b.Receive(JsonWrap("round2", &Payload{Value: answer}, p1, p2))
+		}))
+	}
+
+	tb := NewTestBroker()
+	tb.Connect("round2", receiverFuncBroker(func(msg *JsonMessage) error { return nil }))
+	rec := NewRecordingBroker(tb, p1, &buf)
+	run(rec, 2)
+	require.NoError(t, tb.Receive(JsonWrap("round1", &Payload{Value: 1}, p2, p1)))
+	require.NoError(t, rec.Err())
+	require.NotNil(t, got)
+
+	entries, err := ReadTranscript(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, TranscriptReceived, entries[0].Dir)
+	assert.Equal(t, "round1", entries[0].Message.Type)
+	assert.Equal(t, TranscriptSent, entries[1].Dir)
+	assert.Equal(t, "round2", entries[1].Message.Type)
+	assert.False(t, entries[1].Time.Before(entries[0].Time))
+
+	// the replayed session receives the recorded message and sends the same answer
+	replay := NewReplayBroker(entries)
+	run(replay, 2)
+	require.NotNil(t, got)
+	assert.Equal(t, 1, got.Value)
+	assert.NoError(t, replay.Diverged())
+
+	// unless it behaves differently
+	replay = NewReplayBroker(entries)
+	run(replay, 3)
+	assert.ErrorIs(t, replay.Diverged(), ErrReplayDiverged)
+}
@@ -51,6 +51,33 @@ func TestErrorStringNil(t *testing.T) {
 	assert.Equal(t, "Error is nil", e2.Error())
 }
 
+func TestJoinErrors(t *testing.T) {
+	victim := NewPartyID("1", "P1", big.NewInt(1))
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+	p3 := NewPartyID("3", "P3", big.NewInt(3))
+	cause := errors.New("bad proof")
+
+	assert.NoError(t, JoinErrors())
+	assert.NoError(t, JoinErrors(nil, nil))
+
+	plain := errors.New("plain")
+	assert.Equal(t, plain, JoinErrors(nil, plain))
+
+	err := JoinErrors(
+		nil,
+		NewError(cause, "keygen", 3, victim, p2),
+		plain,
+		NewError(errors.New("other"), "keygen", 3, victim, p3, p2),
+	)
+	var tssErr *Error
+	require.ErrorAs(t, err, &tssErr)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "keygen", tssErr.Task())
+	assert.Equal(t, 3, tssErr.Round())
+	assert.Equal(t, victim, tssErr.Victim())
+	assert.Equal(t, []*PartyID{p2, p3}, tssErr.Culprits())
+}
+
 // -----
 // ErrorParty tests
 // -----
@@ -150,6 +177,7 @@ func TestGenerateTestPartyIDsStartAt(t *testing.T) {
 func TestCurves(t *testing.T) {
 	assert.NotNil(t, S256())
 	assert.NotNil(t, Edwards())
+	assert.NotNil(t, P256())
 	assert.NotNil(t, EC())
 	assert.Equal(t, S256(), EC())
 }
@@ -176,8 +204,22 @@ func TestGetCurveName(t *testing.T) {
 	assert.True(t, ok2)
 	assert.Equal(t, Ed25519, name2)
 
-	_, ok3 := GetCurveName(elliptic.P256())
-	assert.False(t, ok3)
+	name3, ok3 := GetCurveName(elliptic.P256())
+	assert.True(t, ok3)
+	assert.Equal(t, Secp256r1, name3)
+
+	// curves built from their parameters share a type, and are told apart by instance
+	other := *elliptic.P384().Params()
+	RegisterCurve("p384-params", &other)
+	defer delete(registry, "p384-params")
+	params := *elliptic.P256().Params()
+	_, ok4 := GetCurveName(&params)
+	assert.False(t, ok4)
+	RegisterCurve("p256-params", &params)
+	defer delete(registry, "p256-params")
+	name4, ok4 := GetCurveName(&params)
+	assert.True(t, ok4)
+	assert.Equal(t, CurveName("p256-params"), name4)
 }
 
 func TestSameCurve(t *testing.T) {
@@ -185,6 +227,7 @@ func TestSameCurve(t *testing.T) {
 	assert.True(t, SameCurve(Edwards(), Edwards()))
 	assert.False(t, SameCurve(S256(), Edwards()))
 	assert.False(t, SameCurve(S256(), elliptic.P256()))
+	assert.True(t, SameCurve(P256(), elliptic.P256()))
 }
 
 func TestRegisterCurve(t *testing.T) {
@@ -193,7 +236,9 @@ func TestRegisterCurve(t *testing.T) {
 	c, ok := GetCurveByName("p256")
 	assert.True(t, ok)
 	assert.Equal(t, p256, c)
-	delete(registry, "p256")
+	UnregisterCurve("p256")
+	_, ok = GetCurveByName("p256")
+	assert.False(t, ok)
 }
 
 func TestSetCurve(t *testing.T) {
@@ -269,6 +314,22 @@ func TestParametersSetters(t *testing.T) {
 
 	params.SetRand(nil)
 	assert.Nil(t, params.Rand())
+
+	assert.Equal(t, time.Duration(0), params.RoundTimeout())
+	params.SetRoundTimeout(time.Minute)
+	assert.Equal(t, time.Minute, params.RoundTimeout())
+}
+
+func TestParametersSessionID(t *testing.T) {
+	ids := GenerateTestPartyIDs(3)
+	params := NewParameters(S256(), NewPeerContext(ids), ids[0], 3, 1)
+
+	assert.Equal(t, "", params.SessionID())
+	assert.Equal(t, "ecdsa:sign:round1", params.MsgType("ecdsa:sign:round1"))
+
+	params.SetSessionID("abc")
+	assert.Equal(t, "abc", params.SessionID())
+	assert.Equal(t, "ecdsa:sign:round1#abc", params.MsgType("ecdsa:sign:round1"))
 }
 
 // -----
@@ -393,6 +454,49 @@ func TestTestBroker(t *testing.T) {
 	assert.Contains(t, err.Error(), "no handler")
 }
 
+func TestSessionBroker(t *testing.T) {
+	broker := NewTestBroker()
+	session := NewSessionBroker(broker)
+
+	var handler MessageReceiver = receiverFunc(func(msg *JsonMessage) error { return nil })
+	session.Connect("a", handler)
+	session.Connect("b", handler)
+	broker.Connect("other", handler)
+	assert.Len(t, broker.rcv, 3)
+
+	session.Disconnect("a")
+	assert.Len(t, broker.rcv, 2)
+
+	session.Close()
+	assert.Len(t, broker.rcv, 1)
+	assert.Contains(t, broker.rcv, "other")
+
+	// connecting after close is a no-op
+	session.Connect("c", handler)
+	assert.Len(t, broker.rcv, 1)
+}
+
+// connectOnlyBroker is a MessageBroker that does not implement Disconnecter.
+type connectOnlyBroker struct {
+	rcv map[string]MessageReceiver
+}
+
+func (b *connectOnlyBroker) Receive(msg *JsonMessage) error           { return nil }
+func (b *connectOnlyBroker) Connect(typ string, dest MessageReceiver) { b.rcv[typ] = dest }
+
+func TestSessionBrokerWithoutDisconnect(t *testing.T) {
+	broker := &connectOnlyBroker{rcv: make(map[string]MessageReceiver)}
+	session := NewSessionBroker(broker)
+
+	var handler MessageReceiver = receiverFunc(func(msg *JsonMessage) error { return nil })
+	session.Connect("a", handler)
+	session.Disconnect("a")
+	session.Close()
+
+	// the handlers stay on a broker that cannot release them
+	assert.Contains(t, broker.rcv, "a")
+}
+
 type receiverFunc func(msg *JsonMessage) error
 
 func (f receiverFunc) Receive(msg *JsonMessage) error { return f(msg) }
@@ -437,6 +541,54 @@ func TestNewJsonExpect(t *testing.T) {
 	assert.Contains(t, err.Error(), "completed")
 }
 
+func TestNewJsonExpectTimeout(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	p2 := NewPartyID("2", "P2", big.NewInt(2))
+	p3 := NewPartyID("3", "P3", big.NewInt(3))
+	parties := []*PartyID{p1, p2, p3}
+
+	missing := make(chan []*PartyID, 1)
+	rcv := NewJsonExpect[Payload]("round1", parties, func(from []*PartyID, packets []*Payload) {
+		t.Error("callback should not be called")
+	}, WithTimeout(50*time.Millisecond, func(m []*PartyID) {
+		missing <- m
+	}))
+
+	require.NoError(t, rcv.Receive(&JsonMessage{Type: "round1", From: p2, Data: &Payload{Value: 1}}))
+
+	select {
+	case m := <-missing:
+		assert.Equal(t, []*PartyID{p1, p3}, m)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout was not reported")
+	}
+
+	// late messages are rejected
+	err := rcv.Receive(&JsonMessage{Type: "round1", From: p1, Data: &Payload{Value: 1}})
+	assert.Error(t, err)
+}
+
+func TestNewJsonExpectTimeoutCompleted(t *testing.T) {
+	type Payload struct {
+		Value int `json:"value"`
+	}
+
+	p1 := NewPartyID("1", "P1", big.NewInt(1))
+	done := false
+	rcv := NewJsonExpect[Payload]("round1", []*PartyID{p1}, func(from []*PartyID, packets []*Payload) {
+		done = true
+	}, WithTimeout(20*time.Millisecond, func(m []*PartyID) {
+		t.Error("timeout should not fire once complete")
+	}))
+	require.NoError(t, rcv.Receive(&JsonMessage{Type: "round1", From: p1, Data: &Payload{Value: 1}}))
+	assert.True(t, done)
+	time.Sleep(50 * time.Millisecond)
+}
+
 func TestNewJsonExpectUnknownPeer(t *testing.T) {
 	type Payload struct {
 		Value int `json:"value"`